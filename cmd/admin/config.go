@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ceesaxp/cocktail-bot/internal/cli"
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+)
+
+func configCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Document the configuration",
+	}
+	cmd.AddCommand(configSchemaCommand())
+	return cmd
+}
+
+func configSchemaCommand() *cobra.Command {
+	var env bool
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print an annotated configuration file with every setting at its default",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			if len(args) != 0 {
+				return cli.Usagef("expected no arguments")
+			}
+
+			// JSON lists every setting, the table form is the file itself
+			settings := config.Settings()
+			if !env && c.Output != cli.OutputJSON {
+				return config.WriteExample(c.Stdout)
+			}
+			if env {
+				overridden := settings[:0]
+				for _, s := range settings {
+					if s.Env != "" {
+						overridden = append(overridden, s)
+					}
+				}
+				settings = overridden
+			}
+
+			return c.Render(settings, func() cli.Table {
+				table := cli.Table{Header: []string{"ENV", "SETTING", "TYPE", "DEFAULT"}}
+				for _, s := range settings {
+					table.Rows = append(table.Rows, []string{s.Env, s.Path, s.Type, fmt.Sprint(s.Default)})
+				}
+				return table
+			})
+		}),
+	}
+	cmd.Flags().BoolVar(&env, "env", false, "list the environment variables overriding settings instead")
+	return cmd
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ceesaxp/cocktail-bot/internal/analytics"
+	"github.com/ceesaxp/cocktail-bot/internal/cli"
+)
+
+// revealedGuest describes a reversible dataset token in command output
+type revealedGuest struct {
+	Token string `json:"token"`
+	Email string `json:"email,omitempty"` // Empty if the token does not open with the salt
+}
+
+// revealGuestsCommand turns the guest tokens of a reversible dataset back
+// into emails, for following up on what an analysis found
+func revealGuestsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reveal-guests <token>...",
+		Short: "Print the emails of guest tokens from a reversible dataset",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			if len(args) == 0 {
+				return cli.Usagef("expected at least one guest token")
+			}
+			cfg, err := loadConfig(c)
+			if err != nil {
+				return err
+			}
+			anonymizer, err := analytics.NewAnonymizer(cfg.Reports.Dataset.Salt, cfg.Reports.Dataset.Bucket.Duration())
+			if errors.Is(err, analytics.ErrNoSalt) {
+				return cli.Exit(cli.ExitConfig, err)
+			}
+			if err != nil {
+				return err
+			}
+
+			guests := make([]revealedGuest, 0, len(args))
+			failed := 0
+			for _, token := range args {
+				email, err := anonymizer.Reveal(token)
+				if err != nil {
+					failed++
+				}
+				guests = append(guests, revealedGuest{Token: token, Email: email})
+			}
+
+			if err := c.Render(guests, func() cli.Table {
+				table := cli.Table{Header: []string{"TOKEN", "EMAIL"}}
+				for _, g := range guests {
+					email := g.Email
+					if email == "" {
+						email = "(invalid)"
+					}
+					table.Rows = append(table.Rows, []string{g.Token, email})
+				}
+				return table
+			}); err != nil {
+				return err
+			}
+			if failed > 0 {
+				return errors.New("some tokens are invalid or come from another salt")
+			}
+			return nil
+		}),
+	}
+}
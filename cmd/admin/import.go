@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ceesaxp/cocktail-bot/internal/cli"
+	"github.com/ceesaxp/cocktail-bot/internal/importer"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+)
+
+// importResult summarizes an import run
+type importResult struct {
+	File string `json:"file"`
+	importer.Result
+}
+
+func importCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import guests into the configured database",
+	}
+	cmd.AddCommand(importCSVCommand())
+	return cmd
+}
+
+func importCSVCommand() *cobra.Command {
+	var (
+		column     int
+		nameColumn int
+		tagsColumn int
+		hasHeader  bool
+		dedupe     string
+		existing   string
+		indexDir   string
+		progress   int
+	)
+	cmd := &cobra.Command{
+		Use:   "csv <file>",
+		Short: "Import guest emails from a CSV file",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			if len(args) != 1 {
+				return cli.Usagef("expected the CSV file to import")
+			}
+			opts := importer.Options{
+				Column:        column,
+				NameColumn:    nameColumn,
+				TagsColumn:    tagsColumn,
+				Header:        hasHeader,
+				Dedupe:        dedupe,
+				Existing:      existing,
+				IndexDir:      indexDir,
+				CreatedBy:     "admin_import",
+				ProgressEvery: progress,
+				Progress: func(r importer.Result) {
+					c.Printf("Processed %d rows: %d imported, %d updated, %d duplicate, %d invalid\n",
+						r.Rows, r.Imported, r.Updated, r.Duplicate, r.Invalid)
+				},
+				OnInvalid: func(row int, email string) {
+					c.Printf("Invalid email or tags at row %d: %s\n", row, email)
+				},
+			}
+			if err := opts.Validate(); err != nil {
+				return cli.Usagef("%v", err)
+			}
+
+			input, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("error opening input file: %w", err)
+			}
+			defer input.Close()
+
+			svc, err := openService(c)
+			if err != nil {
+				return err
+			}
+			defer svc.Close()
+
+			result, err := importer.Run(context.Background(), input, svc, opts)
+			var rowErr *importer.RowError
+			if errors.As(err, &rowErr) && rowErr.Lookup {
+				return cli.Exit(cli.ExitUnavailable, err)
+			}
+			if err != nil {
+				return err
+			}
+
+			summary := importResult{File: args[0], Result: result}
+			return c.Render(summary, func() cli.Table {
+				return cli.Table{
+					Header: []string{"FILE", "ROWS", "IMPORTED", "UPDATED", "DUPLICATE", "INVALID"},
+					Rows: [][]string{{summary.File, strconv.Itoa(result.Rows), strconv.Itoa(result.Imported),
+						strconv.Itoa(result.Updated), strconv.Itoa(result.Duplicate), strconv.Itoa(result.Invalid)}},
+				}
+			})
+		}),
+	}
+	cmd.Flags().IntVar(&column, "column", 1, "column number containing emails (1-based)")
+	cmd.Flags().IntVar(&nameColumn, "name-column", 0, "column number containing full names, split into first and last name (0 for none)")
+	cmd.Flags().IntVar(&tagsColumn, "tags-column", 0, "column number containing tags separated by commas (0 for none)")
+	cmd.Flags().BoolVar(&hasHeader, "header", true, "input file has a header row")
+	cmd.Flags().StringVar(&dedupe, "dedupe", importer.DedupeMemory, "how to detect emails repeated in the file: memory, disk or none")
+	cmd.Flags().StringVar(&existing, "existing", importer.ExistingSkip, "what to do with guests already on the list: skip, or update to add the tags and name of the file")
+	cmd.Flags().StringVar(&indexDir, "index-dir", "", "directory for the disk dedupe index, defaults to the system temporary directory")
+	cmd.Flags().IntVar(&progress, "progress", 10000, "report progress every N rows, 0 to disable")
+	return cmd
+}
+
+// normalizeEmailsCommand rewrites all stored emails to lowercase without
+// surrounding spaces. Run it once after upgrading a database that was
+// filled before emails were normalized on write.
+func normalizeEmailsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "normalize-emails",
+		Short: "Rewrite stored emails to their normalized form",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			cfg, err := loadConfig(c)
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			repo, err := repository.New(ctx, cfg.Database, newLogger(c, cfg))
+			if err != nil {
+				return cli.Exit(cli.ExitUnavailable, fmt.Errorf("failed to open database: %w", err))
+			}
+			defer repo.Close()
+
+			normalizer, ok := repo.(repository.EmailNormalizer)
+			if !ok {
+				return fmt.Errorf("database type %q does not support email normalization", cfg.Database.Type)
+			}
+
+			changed, err := normalizer.NormalizeEmails(ctx)
+			if errors.Is(err, repository.ErrDuplicateEmails) {
+				return fmt.Errorf("cannot normalize emails, merge these duplicates first: %w", err)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to normalize emails: %w", err)
+			}
+
+			result := struct {
+				Database   string `json:"database"`
+				Normalized int    `json:"normalized"`
+			}{cfg.Database.Type, changed}
+			return c.Render(result, func() cli.Table {
+				return cli.Table{
+					Header: []string{"DATABASE", "NORMALIZED"},
+					Rows:   [][]string{{result.Database, strconv.Itoa(result.Normalized)}},
+				}
+			})
+		}),
+	}
+}
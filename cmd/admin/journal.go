@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/cli"
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+)
+
+// journalInfo describes an exported or verified journal in command output
+type journalInfo struct {
+	File string `json:"file"`
+	audit.JournalSummary
+}
+
+// journalKey returns the key signing journals, set by event.journal_key
+func journalKey(cfg *config.Config) ([]byte, error) {
+	if cfg.Event.JournalKey == "" {
+		return nil, cli.Exit(cli.ExitConfig, errors.New("journals need event.journal_key to be set"))
+	}
+	return []byte(cfg.Event.JournalKey), nil
+}
+
+// renderJournal prints the record count and head hash of a journal
+func renderJournal(c *cli.Context, info journalInfo) error {
+	return c.Render(info, func() cli.Table {
+		return cli.Table{
+			Header: []string{"FILE", "RECORDS", "HEAD"},
+			Rows:   [][]string{{info.File, strconv.Itoa(info.Records), info.Head}},
+		}
+	})
+}
+
+// exportJournalCommand writes the audit log as a hash-chained journal
+// that sponsors can check with verify-journal
+func exportJournalCommand() *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "export-journal",
+		Short: "Export the audit log as a signed, hash-chained journal",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			cfg, err := loadConfig(c)
+			if err != nil {
+				return err
+			}
+			key, err := journalKey(cfg)
+			if err != nil {
+				return err
+			}
+			if cfg.Event.AuditFile == "" {
+				return cli.Exit(cli.ExitConfig, errors.New("the audit log is only kept in memory, set event.audit_file"))
+			}
+			log, err := audit.Open(cfg.Event.AuditFile)
+			if err != nil {
+				return err
+			}
+
+			// Without a file, stdout carries the journal and the summary goes to stderr
+			if file == "" {
+				summary, err := log.ExportJournal(c.Stdout, key)
+				if err != nil {
+					return err
+				}
+				c.Printf("Exported %d records, head %s\n", summary.Records, summary.Head)
+				return nil
+			}
+
+			output, err := os.Create(file)
+			if err != nil {
+				return fmt.Errorf("error creating journal file: %w", err)
+			}
+			summary, err := log.ExportJournal(output, key)
+			if closeErr := output.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+			return renderJournal(c, journalInfo{File: file, JournalSummary: summary})
+		}),
+	}
+	cmd.Flags().StringVar(&file, "file", "", "file to write the journal to, instead of stdout")
+	return cmd
+}
+
+// verifyJournalCommand checks the chain and signatures of an exported journal
+func verifyJournalCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-journal <file>",
+		Short: "Check that an exported journal is complete and unchanged",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			if len(args) != 1 {
+				return cli.Usagef("expected the journal file to verify, or - for stdin")
+			}
+			cfg, err := loadConfig(c)
+			if err != nil {
+				return err
+			}
+			key, err := journalKey(cfg)
+			if err != nil {
+				return err
+			}
+
+			var input io.Reader = c.Stdin
+			if args[0] != "-" {
+				file, err := os.Open(args[0])
+				if err != nil {
+					return fmt.Errorf("error opening journal file: %w", err)
+				}
+				defer file.Close()
+				input = file
+			}
+
+			summary, err := audit.VerifyJournal(input, key)
+			if err != nil {
+				return fmt.Errorf("journal verification failed after %d valid records: %w", summary.Records, err)
+			}
+			return renderJournal(c, journalInfo{File: args[0], JournalSummary: summary})
+		}),
+	}
+}
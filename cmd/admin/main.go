@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ceesaxp/cocktail-bot/internal/cli"
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+func main() {
+	root := cli.NewRoot("cocktail-admin", "Operational tasks for Cocktail Bot: tokens, imports and database maintenance")
+	root.AddCommand(
+		tokensCommand(),
+		importCommand(),
+		normalizeEmailsCommand(),
+		usersCommand(),
+		dbCommand(),
+		vouchersCommand(),
+		exportJournalCommand(),
+		verifyJournalCommand(),
+		revealGuestsCommand(),
+		configCommand(),
+		cli.VersionCommand(version),
+	)
+	os.Exit(cli.Execute(root, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// loadConfig loads the configuration named by --config
+func loadConfig(c *cli.Context) (*config.Config, error) {
+	cfg, err := config.Load(c.Config)
+	if err != nil {
+		return nil, cli.Exit(cli.ExitConfig, fmt.Errorf("failed to load configuration: %w", err))
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, cli.Exit(cli.ExitConfig, fmt.Errorf("invalid configuration: %w", err))
+	}
+	return cfg, nil
+}
+
+// newLogger returns a logger writing to stderr, keeping stdout for results
+func newLogger(c *cli.Context, cfg *config.Config) *logger.Logger {
+	return logger.NewWithWriter(cfg.LogLevel, c.Stderr)
+}
+
+// openService loads the configuration and opens the service on the
+// configured database. The caller must close the service.
+func openService(c *cli.Context) (*service.Service, error) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	svc, err := service.New(context.Background(), cfg, newLogger(c, cfg))
+	if err != nil {
+		return nil, cli.Exit(cli.ExitUnavailable, fmt.Errorf("failed to open database: %w", err))
+	}
+	return svc, nil
+}
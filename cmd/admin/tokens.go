@@ -0,0 +1,212 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ceesaxp/cocktail-bot/internal/api"
+	"github.com/ceesaxp/cocktail-bot/internal/cli"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Default token length in bytes (resulting in longer base64 string)
+	defaultTokenLength = 24
+	// Default output file
+	defaultTokensFile = "api_tokens.yaml"
+)
+
+type tokensFile struct {
+	AuthTokens []string `yaml:"auth_tokens"`
+}
+
+// tokenInfo describes a token in command output
+type tokenInfo struct {
+	Token       string `json:"token,omitempty"` // Only shown when generated
+	Hash        string `json:"hash,omitempty"`  // Stored in the tokens file instead of the token
+	Fingerprint string `json:"fingerprint"`
+	Hashed      bool   `json:"hashed"`
+}
+
+func tokensCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tokens",
+		Short: "Manage REST API tokens",
+	}
+	cmd.AddCommand(
+		tokensGenerateCommand(),
+		tokensListCommand(),
+	)
+	return cmd
+}
+
+func tokensGenerateCommand() *cobra.Command {
+	var (
+		count, length             int
+		file                      string
+		appendTokens, displayOnly bool
+		force, plaintext          bool
+	)
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate API tokens and save them to a tokens file",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			if count <= 0 {
+				return cli.Usagef("number of tokens must be greater than 0")
+			}
+			if length <= 0 {
+				return cli.Usagef("token length must be greater than 0")
+			}
+
+			tokens, err := generateTokens(count, length)
+			if err != nil {
+				return err
+			}
+
+			infos := make([]tokenInfo, len(tokens))
+			for i, token := range tokens {
+				infos[i] = tokenInfo{Token: token, Hash: api.HashToken(token), Fingerprint: api.TokenFingerprint(token), Hashed: !plaintext}
+			}
+
+			if !displayOnly {
+				outputTokens := make([]string, len(infos))
+				for i, info := range infos {
+					outputTokens[i] = info.Hash
+					if plaintext {
+						outputTokens[i] = info.Token
+					}
+				}
+				if appendTokens {
+					existing, err := readExistingTokens(file)
+					if err != nil && !errors.Is(err, os.ErrNotExist) {
+						return fmt.Errorf("error reading existing tokens: %w", err)
+					}
+					outputTokens = append(existing, outputTokens...)
+				} else if _, err := os.Stat(file); err == nil && !force {
+					return cli.Usagef("file %s already exists, use --append or --force", file)
+				}
+
+				if err := writeTokensToFile(file, outputTokens); err != nil {
+					return err
+				}
+				c.Printf("Wrote %d token(s) to %s\n", len(outputTokens), file)
+				c.Printf("Set api.tokens_file to %q and send tokens as \"Authorization: Bearer <token>\"\n", file)
+				if !plaintext {
+					c.Printf("The file only holds hashes: copy the tokens now, they are not shown again\n")
+				}
+			}
+
+			return c.Render(infos, func() cli.Table {
+				return tokenTable(infos, true)
+			})
+		}),
+	}
+	cmd.Flags().IntVar(&count, "count", 1, "number of tokens to generate")
+	cmd.Flags().IntVar(&length, "length", defaultTokenLength, "length of generated tokens in bytes (before encoding)")
+	cmd.Flags().StringVar(&file, "file", defaultTokensFile, "tokens file to write")
+	cmd.Flags().BoolVar(&appendTokens, "append", false, "append to an existing tokens file instead of overwriting")
+	cmd.Flags().BoolVar(&displayOnly, "display-only", false, "only display tokens, don't write to file")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite an existing tokens file")
+	cmd.Flags().BoolVar(&plaintext, "plaintext", false, "write the tokens themselves to the file instead of their hashes")
+	return cmd
+}
+
+func tokensListCommand() *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the fingerprints of the tokens in a tokens file",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			tokens, err := readExistingTokens(file)
+			if err != nil {
+				return fmt.Errorf("error reading tokens: %w", err)
+			}
+
+			infos := make([]tokenInfo, len(tokens))
+			for i, token := range tokens {
+				infos[i] = tokenInfo{Fingerprint: api.EntryFingerprint(token), Hashed: api.IsTokenHash(token)}
+			}
+			return c.Render(infos, func() cli.Table {
+				return tokenTable(infos, false)
+			})
+		}),
+	}
+	cmd.Flags().StringVar(&file, "file", defaultTokensFile, "tokens file to read")
+	return cmd
+}
+
+// tokenTable lists tokens, including their secret value and hash only
+// when asked
+func tokenTable(infos []tokenInfo, secret bool) cli.Table {
+	table := cli.Table{Header: []string{"FINGERPRINT", "STORED"}}
+	if secret {
+		table.Header = append(table.Header, "TOKEN", "HASH")
+	}
+	for _, info := range infos {
+		stored := "plain"
+		if info.Hashed {
+			stored = "hashed"
+		}
+		row := []string{info.Fingerprint, stored}
+		if secret {
+			row = append(row, info.Token, info.Hash)
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// generateTokens generates the specified number of random tokens
+func generateTokens(count, length int) ([]string, error) {
+	tokens := make([]string, count)
+	for i := range tokens {
+		randomBytes := make([]byte, length)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return nil, fmt.Errorf("failed to generate random bytes: %w", err)
+		}
+		tokens[i] = base64.RawURLEncoding.EncodeToString(randomBytes)
+	}
+	return tokens, nil
+}
+
+// readExistingTokens reads tokens from an existing file
+func readExistingTokens(filename string) ([]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens tokensFile
+	if err := yaml.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("error parsing tokens file: %w", err)
+	}
+	return tokens.AuthTokens, nil
+}
+
+// writeTokensToFile writes tokens to a YAML file
+func writeTokensToFile(filename string, tokens []string) error {
+	// Create directory if it doesn't exist
+	dir := filepath.Dir(filename)
+	if dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+
+	data, err := yaml.Marshal(tokensFile{AuthTokens: tokens})
+	if err != nil {
+		return fmt.Errorf("error marshaling tokens: %w", err)
+	}
+
+	// Restrictive permissions for security
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+	return nil
+}
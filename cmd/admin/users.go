@@ -0,0 +1,424 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ceesaxp/cocktail-bot/internal/api"
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/cli"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+)
+
+// userTable lists users with one row each
+func userTable(users []*api.User) cli.Table {
+	table := cli.Table{Header: []string{"ID", "EMAIL", "NAME", "ADDED", "REDEEMED", "BAR", "CONSENT", "ADDED BY", "TAGS"}}
+	for _, user := range users {
+		table.Rows = append(table.Rows, []string{user.ID, user.Email,
+			strings.TrimSpace(user.FirstName + " " + user.LastName), user.DateAdded.Format(time.RFC3339),
+			formatOptionalTime(user.Redeemed), user.Bar, formatOptionalTime(user.MarketingConsent), user.CreatedBy,
+			strings.Join(user.Tags, ",")})
+	}
+	return table
+}
+
+// formatOptionalTime formats a timestamp, or "-" if it is not set
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func usersCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "users",
+		Short: "Look up and correct guests",
+	}
+	cmd.AddCommand(
+		usersFindCommand(),
+		usersReportCommand(),
+		usersUpdateCommand(),
+		usersMergeCommand(),
+	)
+	return cmd
+}
+
+func usersFindCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "find <email>",
+		Short: "Show the guest with an email",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			if len(args) != 1 {
+				return cli.Usagef("expected the email to look up")
+			}
+
+			svc, err := openService(c)
+			if err != nil {
+				return err
+			}
+			defer svc.Close()
+
+			user, err := svc.FindUser(context.Background(), args[0])
+			if errors.Is(err, domain.ErrUserNotFound) {
+				return err
+			}
+			if err != nil {
+				return cli.Exit(cli.ExitUnavailable, err)
+			}
+
+			record := api.NewUser(user)
+			return c.Render(record, func() cli.Table {
+				return userTable([]*api.User{record})
+			})
+		}),
+	}
+}
+
+func usersReportCommand() *cobra.Command {
+	var reportType, from, to string
+	var filter domain.ReportFilter
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "List guests added, redeemed or consented in a date range",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			if _, err := domain.ValidateReportType(reportType); err != nil {
+				return cli.Usagef("%v", err)
+			}
+			if _, err := domain.NormalizeReportFilter(filter); err != nil {
+				return cli.Usagef("%v", err)
+			}
+			fromDate, err := parseDate(from)
+			if err != nil {
+				return cli.Usagef("invalid --from date: %v", err)
+			}
+			toDate, err := parseDate(to)
+			if err != nil {
+				return cli.Usagef("invalid --to date: %v", err)
+			}
+			if !toDate.IsZero() {
+				// Include the whole end day
+				toDate = toDate.Add(24*time.Hour - time.Nanosecond)
+			}
+
+			svc, err := openService(c)
+			if err != nil {
+				return err
+			}
+			defer svc.Close()
+
+			users, err := svc.GenerateReport(context.Background(), reportType, fromDate, toDate, filter)
+			if err != nil {
+				return cli.Exit(cli.ExitUnavailable, err)
+			}
+
+			records := api.NewUsers(users)
+			return c.Render(records, func() cli.Table {
+				return userTable(records)
+			})
+		}),
+	}
+	cmd.Flags().StringVar(&reportType, "type", string(domain.ReportTypeAll), "report type: redeemed, added, consented, changed or all")
+	cmd.Flags().StringVar(&from, "from", "", "start date as YYYY-MM-DD, defaults to 7 days ago")
+	cmd.Flags().StringVar(&to, "to", "", "end date as YYYY-MM-DD, defaults to now")
+	cmd.Flags().StringVar(&filter.Domain, "domain", "", "only guests with emails at this domain")
+	cmd.Flags().StringVar(&filter.Source, "source", "", "only guests added by this source, such as token or rsvp_import")
+	cmd.Flags().StringVar(&filter.Bar, "bar", "", "only guests served by this bar")
+	return cmd
+}
+
+func usersUpdateCommand() *cobra.Command {
+	var patch domain.UserPatch
+	var yes bool
+	cmd := &cobra.Command{
+		Use:   "update <id or email>",
+		Short: "Change fields of a guest, such as a mistyped email, names, notes or tags",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			if len(args) != 1 {
+				return cli.Usagef("expected the ID or email of the guest")
+			}
+			normalized, err := domain.NormalizeUserPatch(patch)
+			if err != nil {
+				return cli.Usagef("%v", err)
+			}
+
+			svc, err := openService(c)
+			if err != nil {
+				return err
+			}
+			defer svc.Close()
+
+			if normalized.Unredeem && !yes && !c.Confirm("Undo the redemption of %s?", args[0]) {
+				return errors.New("no changes made")
+			}
+
+			user, err := svc.PatchUser(audit.NewContext(context.Background(), "admin_cli"), args[0], normalized)
+			switch {
+			case domain.IsValidationError(err):
+				return cli.Usagef("%v", err)
+			case errors.Is(err, domain.ErrUserNotFound), domain.IsDuplicateUser(err),
+				errors.Is(err, domain.ErrEmailDenied), errors.Is(err, domain.ErrEventArchived):
+				return err
+			case err != nil:
+				return cli.Exit(cli.ExitUnavailable, err)
+			}
+
+			record := api.NewUser(user)
+			return c.Render(record, func() cli.Table {
+				return userTable([]*api.User{record})
+			})
+		}),
+	}
+	cmd.Flags().Func("email", "corrected email of the guest", func(value string) error {
+		patch.Email = &value
+		return nil
+	})
+	cmd.Flags().Func("first-name", "first name of the guest, empty to clear it", func(value string) error {
+		patch.FirstName = &value
+		return nil
+	})
+	cmd.Flags().Func("last-name", "last name of the guest, empty to clear it", func(value string) error {
+		patch.LastName = &value
+		return nil
+	})
+	cmd.Flags().Func("notes", "notes on the guest, empty to clear them", func(value string) error {
+		patch.Notes = &value
+		return nil
+	})
+	cmd.Flags().Func("tags", "comma-separated tags replacing the current ones, empty to clear them", func(value string) error {
+		tags := domain.SplitTags(value)
+		patch.Tags = &tags
+		return nil
+	})
+	cmd.Flags().BoolVar(&patch.Unredeem, "unredeem", false, "undo the redemption, so the guest can redeem again")
+	cmd.Flags().StringVar(&patch.Reason, "reason", "", "why the guest is changed, recorded in the audit log and required with --unredeem")
+	cmd.Flags().BoolVar(&yes, "yes", false, "undo a redemption without asking")
+	return cmd
+}
+
+func usersMergeCommand() *cobra.Command {
+	var reason string
+	var yes bool
+	cmd := &cobra.Command{
+		Use:   "merge <keep id or email> <duplicate id or email>",
+		Short: "Merge the duplicate record of a guest into the record that is kept",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			if len(args) != 2 {
+				return cli.Usagef("expected the guest to keep and its duplicate")
+			}
+
+			svc, err := openService(c)
+			if err != nil {
+				return err
+			}
+			defer svc.Close()
+
+			if !yes && !c.Confirm("Merge %s into %s? The duplicate can no longer redeem.", args[1], args[0]) {
+				return errors.New("no changes made")
+			}
+
+			keep, duplicate, err := svc.MergeUsers(audit.NewContext(context.Background(), "admin_cli"), args[0], args[1], reason)
+			switch {
+			case domain.IsValidationError(err):
+				return cli.Usagef("%v", err)
+			case errors.Is(err, domain.ErrUserNotFound), errors.Is(err, domain.ErrEventArchived):
+				return err
+			case err != nil:
+				return cli.Exit(cli.ExitUnavailable, err)
+			}
+
+			records := []*api.User{api.NewUser(keep), api.NewUser(duplicate)}
+			return c.Render(api.MergeUsersResponse{User: records[0], Duplicate: records[1]}, func() cli.Table {
+				return userTable(records)
+			})
+		}),
+	}
+	cmd.Flags().StringVar(&reason, "reason", "", "why the records are merged, recorded in the audit log")
+	cmd.Flags().BoolVar(&yes, "yes", false, "merge without asking")
+	return cmd
+}
+
+// parseDate parses a YYYY-MM-DD date, returning the zero time for an empty string
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func dbCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Inspect the configured database",
+	}
+	cmd.AddCommand(
+		dbStatusCommand(),
+		dbDoctorCommand(),
+		dbDemoteCommand(),
+	)
+	return cmd
+}
+
+// dbStatusCommand checks that the database is reachable and shows its
+// record counts
+func dbStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Check that the database is reachable and show its record counts",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			svc, err := openService(c)
+			if err != nil {
+				return err
+			}
+			defer svc.Close()
+
+			ctx := context.Background()
+			if err := svc.DatabaseHealth(ctx); err != nil {
+				return cli.Exit(cli.ExitUnavailable, fmt.Errorf("database is unreachable: %w", err))
+			}
+			stats, err := svc.DatabaseStats(ctx)
+			if err != nil {
+				return cli.Exit(cli.ExitUnavailable, err)
+			}
+
+			return c.Render(stats, func() cli.Table {
+				table := cli.Table{Rows: [][]string{
+					{"Backend:", stats.Backend},
+					{"Users:", strconv.Itoa(stats.Users)},
+					{"Redeemed:", strconv.Itoa(stats.Redeemed)},
+					{"Consented:", strconv.Itoa(stats.Consented)},
+					{"Last write:", formatOptionalTime(stats.LastWrite)},
+				}}
+				keys := make([]string, 0, len(stats.Details))
+				for key := range stats.Details {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				for _, key := range keys {
+					table.Rows = append(table.Rows, []string{key + ":", stats.Details[key]})
+				}
+				return table
+			})
+		}),
+	}
+}
+
+// dbDoctorCommand checks the configured database for missing columns,
+// outdated headers and missing indexes, and repairs them with --fix
+func dbDoctorCommand() *cobra.Command {
+	var fix, yes bool
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the database schema and optionally repair it",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			cfg, err := loadConfig(c)
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			issues, err := repository.Diagnose(ctx, cfg.Database)
+			if err != nil {
+				return cli.Exit(cli.ExitUnavailable, fmt.Errorf("failed to inspect database: %w", err))
+			}
+			if err := renderIssues(c, issues); err != nil {
+				return err
+			}
+			if len(issues) == 0 {
+				c.Printf("No issues found\n")
+				return nil
+			}
+
+			fixable := 0
+			for _, issue := range issues {
+				if issue.Fix != "" {
+					fixable++
+				}
+			}
+			if !fix || fixable == 0 {
+				return fmt.Errorf("%d issues found, %d can be fixed with --fix", len(issues), fixable)
+			}
+			if !yes && !c.Confirm("Apply %d fixes to the %s database?", fixable, cfg.Database.Type) {
+				return errors.New("no changes made")
+			}
+
+			if err := repository.Repair(ctx, cfg.Database, newLogger(c, cfg)); err != nil {
+				return cli.Exit(cli.ExitUnavailable, fmt.Errorf("failed to repair database: %w", err))
+			}
+			remaining, err := repository.Diagnose(ctx, cfg.Database)
+			if err != nil {
+				return cli.Exit(cli.ExitUnavailable, fmt.Errorf("failed to inspect database: %w", err))
+			}
+			if len(remaining) > 0 {
+				c.Printf("Remaining issues:\n")
+				if err := renderIssues(c, remaining); err != nil {
+					return err
+				}
+				return fmt.Errorf("%d issues remain after repair", len(remaining))
+			}
+			c.Printf("Applied %d fixes\n", fixable)
+			return nil
+		}),
+	}
+	cmd.Flags().BoolVar(&fix, "fix", false, "apply the fixes after confirmation")
+	cmd.Flags().BoolVar(&yes, "yes", false, "apply the fixes without asking, with --fix")
+	return cmd
+}
+
+// dbDemoteCommand reverses the promotion of a CSV file to SQLite, writing
+// the guests of the SQLite database back to the CSV file
+func dbDemoteCommand() *cobra.Command {
+	var yes bool
+	cmd := &cobra.Command{
+		Use:   "demote",
+		Short: "Move a CSV guest list promoted to SQLite back to the CSV file",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			cfg, err := loadConfig(c)
+			if err != nil {
+				return err
+			}
+
+			path := cfg.Database.ConnectionString
+			if !yes && !c.Confirm("Write the guests of %s back to %s? The bot must be stopped.", repository.PromotedPath(path), path) {
+				return errors.New("no changes made")
+			}
+			rows, err := repository.Demote(context.Background(), cfg.Database, newLogger(c, cfg))
+			if errors.Is(err, repository.ErrNotPromoted) {
+				return fmt.Errorf("%s was not promoted: %s does not exist", path, repository.PromotedPath(path))
+			}
+			if err != nil {
+				return fmt.Errorf("failed to demote: %w", err)
+			}
+			c.Printf("Wrote %d guests to %s, the SQLite database is kept as %s\n", rows, path, repository.BackupPath(repository.PromotedPath(path)))
+			return nil
+		}),
+	}
+	cmd.Flags().BoolVar(&yes, "yes", false, "demote without asking")
+	return cmd
+}
+
+// renderIssues lists schema issues found by the doctor
+func renderIssues(c *cli.Context, issues []repository.SchemaIssue) error {
+	if issues == nil {
+		issues = []repository.SchemaIssue{}
+	}
+	return c.Render(issues, func() cli.Table {
+		table := cli.Table{Header: []string{"BACKEND", "PROBLEM", "FIX"}}
+		for _, issue := range issues {
+			fix := issue.Fix
+			if fix == "" {
+				fix = "(manual)"
+			}
+			table.Rows = append(table.Rows, []string{issue.Backend, issue.Problem, fix})
+		}
+		return table
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ceesaxp/cocktail-bot/internal/cli"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// telegramPayloadLimit is the longest /start payload Telegram deep links carry
+const telegramPayloadLimit = 64
+
+// voucherInfo describes a voucher in command output
+type voucherInfo struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+	Link  string `json:"link,omitempty"` // Telegram deep link, empty if the code is too long for one
+}
+
+func vouchersCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "vouchers",
+		Short: "Create voucher codes and signed links for guests",
+	}
+	cmd.AddCommand(vouchersGenerateCommand())
+	return cmd
+}
+
+func vouchersGenerateCommand() *cobra.Command {
+	var all bool
+	cmd := &cobra.Command{
+		Use:   "generate [<email>...]",
+		Short: "Print the voucher code and Telegram link of guests",
+		RunE: cli.Run(func(c *cli.Context, args []string) error {
+			if all == (len(args) > 0) {
+				return cli.Usagef("name guests by email or use --all")
+			}
+
+			cfg, err := loadConfig(c)
+			if err != nil {
+				return err
+			}
+			svc, err := openService(c)
+			if err != nil {
+				return err
+			}
+			defer svc.Close()
+
+			emails := args
+			if all {
+				// Guests added since the Unix epoch, as a zero start date means the last week
+				users, err := svc.GenerateReport(context.Background(), string(domain.ReportTypeAll), time.Unix(0, 0), time.Now(), domain.ReportFilter{})
+				if err != nil {
+					return cli.Exit(cli.ExitUnavailable, err)
+				}
+				for _, user := range users {
+					emails = append(emails, user.Email)
+				}
+			}
+
+			bot := strings.TrimPrefix(cfg.Telegram.User, "@")
+			vouchers := make([]voucherInfo, 0, len(emails))
+			for _, email := range emails {
+				code, err := svc.VoucherCode(email)
+				if errors.Is(err, domain.ErrVouchersDisabled) {
+					return cli.Exit(cli.ExitConfig, errors.New("vouchers need event.access.signing_key to be set"))
+				}
+				if err != nil {
+					return fmt.Errorf("failed to create voucher for %s: %w", email, err)
+				}
+
+				voucher := voucherInfo{Email: utils.NormalizeEmail(email), Code: code}
+				if bot != "" && len(code) <= telegramPayloadLimit {
+					voucher.Link = "https://t.me/" + bot + "?start=" + code
+				}
+				vouchers = append(vouchers, voucher)
+			}
+
+			return c.Render(vouchers, func() cli.Table {
+				table := cli.Table{Header: []string{"EMAIL", "CODE", "LINK"}}
+				for _, v := range vouchers {
+					table.Rows = append(table.Rows, []string{v.Email, v.Code, v.Link})
+				}
+				return table
+			})
+		}),
+	}
+	cmd.Flags().BoolVar(&all, "all", false, "generate vouchers for every guest in the database")
+	return cmd
+}
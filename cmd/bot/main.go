@@ -2,89 +2,280 @@ package main
 
 import (
 	"context"
-	"flag"
-	"log"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
 
 	"github.com/ceesaxp/cocktail-bot/internal/api"
+	"github.com/ceesaxp/cocktail-bot/internal/cli"
 	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/crashreport"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/httpclient"
+	"github.com/ceesaxp/cocktail-bot/internal/i18n"
+	"github.com/ceesaxp/cocktail-bot/internal/integrations/eventbrite"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/messenger"
+	"github.com/ceesaxp/cocktail-bot/internal/mirror"
+	"github.com/ceesaxp/cocktail-bot/internal/notify"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+	"github.com/ceesaxp/cocktail-bot/internal/rsvp"
 	"github.com/ceesaxp/cocktail-bot/internal/service"
-	"github.com/ceesaxp/cocktail-bot/internal/telegram"
 	"github.com/ceesaxp/cocktail-bot/webui"
 )
 
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
 func main() {
-	// Parse command line flags
-	configPath := flag.String("config", "config.yaml", "path to configuration file")
-	flag.Parse()
+	// Running without a command starts the bot, as "run" does
+	root := cli.NewRoot("cocktail-bot", "Cocktail Bot serves guest check-ins over chat, the REST API and the WebUI")
+	root.RunE = cli.Run(runBot)
+
+	configCmd := &cobra.Command{Use: "config", Short: "Inspect the configuration"}
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Load and check the configuration",
+		RunE:  cli.Run(validateConfig),
+	})
+	root.AddCommand(
+		&cobra.Command{Use: "run", Short: "Start the bot and its servers", RunE: cli.Run(runBot)},
+		configCmd,
+		cli.VersionCommand(version),
+	)
+	os.Exit(cli.Execute(root, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// loadConfig loads and checks the configuration named by --config
+func loadConfig(c *cli.Context) (*config.Config, error) {
+	cfg, err := config.Load(c.Config)
+	if err != nil {
+		return nil, cli.Exit(cli.ExitConfig, fmt.Errorf("failed to load configuration: %w", err))
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, cli.Exit(cli.ExitConfig, fmt.Errorf("invalid configuration: %w", err))
+	}
+	return cfg, nil
+}
+
+// validateConfig loads the configuration and prints a summary of what it enables
+func validateConfig(c *cli.Context, args []string) error {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+
+	summary := struct {
+		Config   string `json:"config"`
+		Valid    bool   `json:"valid"`
+		Channel  string `json:"channel"`
+		Database string `json:"database"`
+		API      bool   `json:"api"`
+		WebUI    bool   `json:"webui"`
+	}{c.Config, true, cfg.Channel, cfg.Database.Type, cfg.API.Enabled, cfg.WebUI.Enabled}
+
+	return c.Render(summary, func() cli.Table {
+		return cli.Table{Rows: [][]string{
+			{"Config:", summary.Config},
+			{"Channel:", summary.Channel},
+			{"Database:", summary.Database},
+			{"API:", fmt.Sprint(summary.API)},
+			{"WebUI:", fmt.Sprint(summary.WebUI)},
+		}}
+	})
+}
+
+// runBot starts the bot and blocks until it receives a termination signal
+func runBot(c *cli.Context, args []string) error {
+	if len(args) > 0 {
+		return cli.Usagef("unexpected arguments %v", args)
+	}
 
 	// Initialize context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Load configuration
-	cfg, err := config.Load(*configPath)
+	cfg, err := loadConfig(c)
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		return err
 	}
 
 	// Initialize logger
-	l := logger.New(cfg.LogLevel)
+	var l *logger.Logger
+	if cfg.LogFile.Path != "" {
+		l, err = logger.NewWithFile(cfg.LogLevel, cfg.LogFile.Path, logger.RotationConfig{
+			MaxSizeMB:  cfg.LogFile.MaxSizeMB,
+			MaxAgeDays: cfg.LogFile.MaxAgeDays,
+			MaxBackups: cfg.LogFile.MaxBackups,
+			Compress:   cfg.LogFile.Compress,
+			Daily:      cfg.LogFile.Daily,
+		})
+	} else {
+		l, err = logger.NewWithOutput(cfg.LogLevel, cfg.LogOutput)
+	}
+	if err != nil {
+		return cli.Exit(cli.ExitConfig, fmt.Errorf("failed to initialize logger: %w", err))
+	}
+	defer l.Close()
+	l.SetSampling(cfg.LogSampling.First, time.Duration(cfg.LogSampling.IntervalSeconds)*time.Second)
+	l.SetMaskEmails(cfg.Privacy.MaskEmails)
 	l.Info("Starting Cocktail Bot")
 
+	// Load translation overrides from the translations file and messages config
+	if count, err := i18n.LoadOverrides(cfg); err != nil {
+		return cli.Exit(cli.ExitConfig, err)
+	} else if count > 0 {
+		l.Info("Translation overrides loaded", "texts", count)
+	}
+
 	// Initialize service
 	svc, err := service.New(ctx, cfg, l)
 	if err != nil {
-		l.Fatal("Failed to initialize service", "error", err)
+		l.Error("Failed to initialize service", "error", err)
+		return cli.Exit(cli.ExitUnavailable, fmt.Errorf("failed to initialize service: %w", err))
 	}
 
-	// Initialize bot
-	bot, err := telegram.NewFromToken(cfg.Telegram.Token, svc, l, cfg)
-	if err != nil {
-		l.Fatal("Failed to initialize Telegram bot", "error", err)
+	// A startup failure stops what was already started, newest first, and
+	// exits with a code telling supervisors what went wrong
+	stops := []func(){func() { svc.Close() }}
+	fail := func(code int, msg string, err error) error {
+		l.Error(msg, "error", err)
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+		return cli.Exit(code, fmt.Errorf("%s: %w", strings.ToLower(msg), err))
 	}
 
-	// Start bot in a separate goroutine
-	if err := bot.Start(); err != nil {
-		l.Fatal("Failed to start bot", "error", err)
+	// Report panics recovered by the API and the bot to Sentry
+	if cfg.Notify.SentryDSN != "" {
+		reporter, err := crashreport.NewSentry(cfg.Notify.SentryDSN, cfg.Notify.SentryEnv, httpclient.New(httpclient.FromConfig(cfg, "sentry")))
+		if err != nil {
+			return fail(cli.ExitConfig, "Invalid configuration", err)
+		}
+		crashreport.SetReporter(reporter)
 	}
 
-	// Initialize and start API server if enabled
+	// WebUI talks to the bot through the API
+	if cfg.WebUI.Enabled && !cfg.API.Enabled {
+		return fail(cli.ExitConfig, "Invalid configuration", errors.New("WebUI requires API to be enabled"))
+	}
+
+	// Start the API server before the bot, so probes are answered while
+	// the chat channel connects. The readiness probe fails until it has.
 	var apiServer *api.Server
 	if cfg.API.Enabled {
 		apiServer, err = api.New(cfg, svc, l)
 		if err != nil {
-			l.Fatal("Failed to initialize API server", "error", err)
+			return fail(cli.ExitConfig, "Failed to initialize API server", err)
 		}
+		apiServer.SetReady("messenger", false)
 
 		if err := apiServer.Start(); err != nil {
-			l.Fatal("Failed to start API server", "error", err)
+			return fail(cli.ExitError, "Failed to start API server", err)
 		}
+		stops = append(stops, func() { apiServer.Stop() })
 		l.Info("API server started", "port", cfg.API.Port)
 	}
 
+	// Initialize bot on the configured messaging channel
+	bot, err := messenger.New(cfg, svc, l)
+	if err != nil {
+		return fail(cli.ExitConfig, "Failed to initialize bot", err)
+	}
+
+	// Alert admins about failed redemptions
+	if alerter, ok := bot.(notify.Alerter); ok {
+		svc.AddAlerter(alerter)
+	}
+
+	// Start bot in a separate goroutine
+	if err := bot.Start(); err != nil {
+		return fail(cli.ExitError, "Failed to start bot", err)
+	}
+	stops = append(stops, bot.Stop)
+	if apiServer != nil {
+		apiServer.SetReady("messenger", true)
+	}
+
 	// Initialize and start WebUI if enabled
 	var webUIServer *webui.Server
 	if cfg.WebUI.Enabled {
-		// Ensure API is also enabled when WebUI is enabled
-		if !cfg.API.Enabled {
-			l.Fatal("WebUI requires API to be enabled")
-		}
-
 		webUIServer, err = webui.New(cfg, l)
 		if err != nil {
-			l.Fatal("Failed to initialize WebUI server", "error", err)
+			return fail(cli.ExitConfig, "Failed to initialize WebUI server", err)
 		}
 
 		if err := webUIServer.Start(); err != nil {
-			l.Fatal("Failed to start WebUI server", "error", err)
+			return fail(cli.ExitError, "Failed to start WebUI server", err)
 		}
+		stops = append(stops, func() { webUIServer.Stop() })
 		l.Info("WebUI server started", "port", cfg.WebUI.Port)
 	}
 
+	// Start importing guests from the RSVP sheet if configured
+	var rsvpSyncer *rsvp.Syncer
+	if cfg.RSVPImport.SpreadsheetID != "" {
+		source, err := rsvp.NewSheetSource(ctx, cfg.RSVPImport)
+		if err != nil {
+			return fail(cli.ExitUnavailable, "Failed to connect to RSVP sheet", err)
+		}
+
+		rsvpSyncer, err = rsvp.NewSyncer(cfg.RSVPImport, source, svc, l)
+		if err != nil {
+			return fail(cli.ExitConfig, "Failed to initialize RSVP import", err)
+		}
+		rsvpSyncer.Start()
+		stops = append(stops, rsvpSyncer.Stop)
+	}
+
+	// Start pulling Eventbrite attendees if configured
+	var eventbriteSyncer *eventbrite.Syncer
+	if cfg.Integrations.Eventbrite.EventID != "" {
+		client, err := eventbrite.NewClient(cfg.Integrations.Eventbrite.BaseURL, cfg.Integrations.Eventbrite.Token, httpclient.New(httpclient.FromConfig(cfg, "eventbrite")))
+		if err != nil {
+			return fail(cli.ExitConfig, "Failed to initialize Eventbrite client", err)
+		}
+
+		eventbriteSyncer, err = eventbrite.NewSyncer(cfg.Integrations.Eventbrite, client, svc, l)
+		if err != nil {
+			return fail(cli.ExitConfig, "Failed to initialize Eventbrite sync", err)
+		}
+		eventbriteSyncer.Start()
+	}
+
+	// Start mirroring the guest list to a reporting sheet if configured
+	var sheetMirror *mirror.Syncer
+	if cfg.SheetsMirror.SpreadsheetID != "" {
+		if cfg.Database.Type == "googlesheet" {
+			l.Warn("Sheets mirror enabled while the database is already a Google Sheet")
+		}
+		connection := strings.Join([]string{cfg.SheetsMirror.CredentialsFile, cfg.SheetsMirror.SpreadsheetID, cfg.SheetsMirror.Sheet}, "|")
+		target, err := repository.NewGoogleSheetRepository(ctx, connection, l)
+		if err != nil {
+			return fail(cli.ExitUnavailable, "Failed to connect to mirror sheet", err)
+		}
+
+		sheetMirror, err = mirror.NewSyncer(cfg.SheetsMirror, svc, target, l)
+		if err != nil {
+			return fail(cli.ExitConfig, "Failed to initialize Sheets mirror", err)
+		}
+		sheetMirror.Start()
+		stops = append(stops, sheetMirror.Stop)
+	}
+
+	// Reload translation overrides and the command menu on SIGHUP
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	defer signal.Stop(hupCh)
+	go reloadMessages(c, hupCh, bot, l)
+
 	// Wait for termination signal
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
@@ -96,10 +287,24 @@ func main() {
 	<-sigCh
 	l.Info("Received termination signal")
 
-	// Graceful shutdown
+	// Graceful shutdown, taking the bot out of rotation first
+	if apiServer != nil {
+		apiServer.SetReady("messenger", false)
+	}
 	l.Info("Shutting down bot")
 	bot.Stop()
 
+	// Stop guest list imports if running
+	if rsvpSyncer != nil {
+		rsvpSyncer.Stop()
+	}
+	if eventbriteSyncer != nil {
+		eventbriteSyncer.Stop()
+	}
+	if sheetMirror != nil {
+		sheetMirror.Stop()
+	}
+
 	// Stop API server if running
 	if apiServer != nil {
 		l.Info("Shutting down API server")
@@ -118,10 +323,60 @@ func main() {
 		l.Info("WebUI server stopped")
 	}
 
+	// Summarize the run before closing the service, so supervisors see
+	// what was processed and what is left to retry
+	status := svc.Status()
+	logStatus(l, status)
+
 	// Close service
 	if err := svc.Close(); err != nil {
 		l.Error("Error closing service", "error", err)
+		return cli.Exit(cli.ExitUnavailable, fmt.Errorf("failed to close repository: %w", err))
 	}
 
 	l.Info("Bot stopped")
+	return nil
+}
+
+// logStatus logs the shutdown summary of a run
+func logStatus(l *logger.Logger, status domain.RuntimeStatus) {
+	l.Info("Shutdown summary",
+		"uptime", (time.Duration(status.UptimeSeconds) * time.Second).String(),
+		"interactions", status.Interactions,
+		"checks", status.Checks,
+		"redemptions", status.Redemptions,
+		"pending_redemptions", status.PendingRedemptions,
+		"errors", len(status.LastErrors))
+	for _, record := range status.LastErrors {
+		l.Info("Recent error", "time", record.Time.Format(time.RFC3339), "message", record.Message)
+	}
+	if status.PendingRedemptions > 0 {
+		l.Warn("Failed redemptions are waiting for a retry", "count", status.PendingRedemptions)
+	}
+}
+
+// reloadMessages reloads the translation overrides each time a signal
+// arrives, and passes the configuration on to a messenger that applies it,
+// which publishes its command menu again. A configuration that fails to
+// load keeps the current texts.
+func reloadMessages(c *cli.Context, signals <-chan os.Signal, bot messenger.Messenger, l *logger.Logger) {
+	for range signals {
+		cfg, err := config.Load(c.Config)
+		if err != nil {
+			l.Error("Failed to reload configuration, keeping current messages", "error", err)
+			continue
+		}
+		count, err := i18n.LoadOverrides(cfg)
+		if err != nil {
+			l.Error("Failed to reload translation overrides, keeping current messages", "error", err)
+			continue
+		}
+		l.Info("Translation overrides reloaded", "texts", count)
+
+		if reloader, ok := bot.(messenger.Reloader); ok {
+			if err := reloader.Reload(cfg); err != nil {
+				l.Error("Failed to publish the reloaded command menu", "error", err)
+			}
+		}
+	}
 }
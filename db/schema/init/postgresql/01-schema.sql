@@ -5,7 +5,8 @@ CREATE TABLE IF NOT EXISTS users (
     id VARCHAR(100) PRIMARY KEY,
     email VARCHAR(255) NOT NULL,
     date_added TIMESTAMP WITH TIME ZONE NOT NULL,
-    redeemed TIMESTAMP WITH TIME ZONE NULL
+    redeemed TIMESTAMP WITH TIME ZONE NULL,
+    marketing_consent TIMESTAMP WITH TIME ZONE NULL
 );
 
 -- Add unique constraint for email
@@ -6,6 +6,7 @@ CREATE TABLE IF NOT EXISTS users (
     email VARCHAR(255) NOT NULL,
     date_added DATETIME NOT NULL,
     redeemed DATETIME NULL,
+    marketing_consent DATETIME NULL,
     UNIQUE INDEX idx_email (email)
 );
 
@@ -0,0 +1,209 @@
+package analytics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Engagement is a snapshot of bot usage statistics
+type Engagement struct {
+	Since          time.Time      `json:"since"`
+	Interactions   int            `json:"interactions"`
+	Languages      map[string]int `json:"languages"`
+	Commands       map[string]int `json:"commands"`
+	Hours          [24]int        `json:"hours"`
+	BusiestHour    int            `json:"busiest_hour"`
+	Checks         int            `json:"checks"`
+	EligibleChecks int            `json:"eligible_checks"`
+	ButtonsShown   int            `json:"buttons_shown"`
+	Redemptions    int            `json:"redemptions"`
+	ConversionRate float64        `json:"conversion_rate"` // Redemptions per email check
+}
+
+// Stages of the redemption funnel, as labeled in metrics
+const (
+	StageCheck       = "check"        // An email was checked
+	StageEligible    = "eligible"     // The email may redeem a cocktail
+	StageButtonShown = "button_shown" // The guest was offered a redeem button
+	StageRedeemed    = "redeemed"     // The cocktail was redeemed
+)
+
+// Stages lists the funnel stages in order
+var Stages = []string{StageCheck, StageEligible, StageButtonShown, StageRedeemed}
+
+// FunnelDay holds the funnel counts of one day
+type FunnelDay struct {
+	Date         string `json:"date,omitempty"` // YYYY-MM-DD, empty for totals
+	Checks       int    `json:"checks"`
+	Eligible     int    `json:"eligible"`
+	ButtonsShown int    `json:"buttons_shown"`
+	Redeemed     int    `json:"redeemed"`
+	Unclaimed    int    `json:"unclaimed"` // Eligible checks beyond the redemptions, such as guests who never claimed
+}
+
+// Stage returns the count of a funnel stage
+func (d FunnelDay) Stage(stage string) int {
+	switch stage {
+	case StageCheck:
+		return d.Checks
+	case StageEligible:
+		return d.Eligible
+	case StageButtonShown:
+		return d.ButtonsShown
+	case StageRedeemed:
+		return d.Redeemed
+	}
+	return 0
+}
+
+// Funnel holds the funnel counts per day since the bot started
+type Funnel struct {
+	Since time.Time   `json:"since"`
+	Days  []FunnelDay `json:"days"` // Oldest first
+	Total FunnelDay   `json:"total"`
+}
+
+// Tracker collects in-memory usage statistics. Counters start at zero
+// whenever the bot is restarted.
+type Tracker struct {
+	mu             sync.Mutex
+	since          time.Time
+	interactions   int
+	languages      map[string]int
+	commands       map[string]int
+	hours          [24]int
+	checks         int
+	eligibleChecks int
+	buttonsShown   int
+	redemptions    int
+	days           map[string]*FunnelDay // Funnel counts by date
+}
+
+// New creates an empty tracker
+func New() *Tracker {
+	return &Tracker{
+		since:     time.Now(),
+		languages: make(map[string]int),
+		commands:  make(map[string]int),
+		days:      make(map[string]*FunnelDay),
+	}
+}
+
+// today returns the funnel counts of the current day. The caller must
+// hold mu.
+func (t *Tracker) today() *FunnelDay {
+	date := time.Now().Format("2006-01-02")
+	day := t.days[date]
+	if day == nil {
+		day = &FunnelDay{Date: date}
+		t.days[date] = day
+	}
+	return day
+}
+
+// RecordInteraction counts a message from a user in the given language.
+// command is empty for messages that are not bot commands.
+func (t *Tracker) RecordInteraction(lang, command string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.interactions++
+	t.hours[time.Now().Hour()]++
+	if lang != "" {
+		t.languages[lang]++
+	}
+	if command != "" {
+		t.commands[command]++
+	}
+}
+
+// RecordCheck counts an email lookup
+func (t *Tracker) RecordCheck(eligible bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.checks++
+	day := t.today()
+	day.Checks++
+	if eligible {
+		t.eligibleChecks++
+		day.Eligible++
+	}
+}
+
+// RecordButtonShown counts a redeem button offered to a guest
+func (t *Tracker) RecordButtonShown() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buttonsShown++
+	t.today().ButtonsShown++
+}
+
+// RecordRedemption counts a successful redemption
+func (t *Tracker) RecordRedemption() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.redemptions++
+	t.today().Redeemed++
+}
+
+// Funnel returns the funnel counts of every day with activity
+func (t *Tracker) Funnel() Funnel {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f := Funnel{Since: t.since, Days: make([]FunnelDay, 0, len(t.days))}
+	for _, day := range t.days {
+		d := *day
+		d.Unclaimed = max(d.Eligible-d.Redeemed, 0)
+		f.Days = append(f.Days, d)
+
+		f.Total.Checks += d.Checks
+		f.Total.Eligible += d.Eligible
+		f.Total.ButtonsShown += d.ButtonsShown
+		f.Total.Redeemed += d.Redeemed
+	}
+	sort.Slice(f.Days, func(i, j int) bool { return f.Days[i].Date < f.Days[j].Date })
+	f.Total.Unclaimed = max(f.Total.Eligible-f.Total.Redeemed, 0)
+	return f
+}
+
+// Snapshot returns a copy of the current statistics
+func (t *Tracker) Snapshot() Engagement {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e := Engagement{
+		Since:          t.since,
+		Interactions:   t.interactions,
+		Languages:      make(map[string]int, len(t.languages)),
+		Commands:       make(map[string]int, len(t.commands)),
+		Hours:          t.hours,
+		Checks:         t.checks,
+		EligibleChecks: t.eligibleChecks,
+		ButtonsShown:   t.buttonsShown,
+		Redemptions:    t.redemptions,
+	}
+	for lang, count := range t.languages {
+		e.Languages[lang] = count
+	}
+	for cmd, count := range t.commands {
+		e.Commands[cmd] = count
+	}
+
+	// Find the busiest hour of the day
+	for hour, count := range t.hours {
+		if count > t.hours[e.BusiestHour] {
+			e.BusiestHour = hour
+		}
+	}
+
+	if t.checks > 0 {
+		e.ConversionRate = float64(t.redemptions) / float64(t.checks)
+	}
+
+	return e
+}
@@ -0,0 +1,82 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/analytics"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := analytics.New()
+
+	tracker.RecordInteraction("en", "start")
+	tracker.RecordInteraction("en", "")
+	tracker.RecordInteraction("de", "help")
+	tracker.RecordCheck(true)
+	tracker.RecordCheck(false)
+	tracker.RecordCheck(true)
+	tracker.RecordCheck(true)
+	tracker.RecordButtonShown()
+	tracker.RecordButtonShown()
+	tracker.RecordRedemption()
+
+	stats := tracker.Snapshot()
+
+	if stats.Interactions != 3 {
+		t.Errorf("Expected 3 interactions, got %d", stats.Interactions)
+	}
+	if stats.Languages["en"] != 2 || stats.Languages["de"] != 1 {
+		t.Errorf("Unexpected language split: %v", stats.Languages)
+	}
+	if stats.Commands["start"] != 1 || stats.Commands["help"] != 1 || len(stats.Commands) != 2 {
+		t.Errorf("Unexpected command counts: %v", stats.Commands)
+	}
+	total := 0
+	for _, count := range stats.Hours {
+		total += count
+	}
+	if total != 3 || stats.Hours[stats.BusiestHour] == 0 {
+		t.Errorf("Unexpected hourly counts: %v (busiest %d)", stats.Hours, stats.BusiestHour)
+	}
+	if stats.Checks != 4 || stats.EligibleChecks != 3 || stats.ButtonsShown != 2 || stats.Redemptions != 1 {
+		t.Errorf("Unexpected funnel counts: %+v", stats)
+	}
+	if stats.ConversionRate != 0.25 {
+		t.Errorf("Expected conversion rate 0.25, got %f", stats.ConversionRate)
+	}
+
+	// Snapshots are independent copies
+	stats.Languages["en"] = 100
+	if tracker.Snapshot().Languages["en"] != 2 {
+		t.Errorf("Snapshot should not share state with the tracker")
+	}
+}
+
+func TestFunnel(t *testing.T) {
+	tracker := analytics.New()
+	if funnel := tracker.Funnel(); len(funnel.Days) != 0 || funnel.Total.Checks != 0 {
+		t.Errorf("Expected an empty funnel, got %+v", funnel)
+	}
+
+	tracker.RecordCheck(true)
+	tracker.RecordCheck(true)
+	tracker.RecordCheck(false)
+	tracker.RecordButtonShown()
+	tracker.RecordButtonShown()
+	tracker.RecordRedemption()
+
+	funnel := tracker.Funnel()
+	if len(funnel.Days) != 1 || funnel.Days[0].Date != time.Now().Format("2006-01-02") {
+		t.Fatalf("Expected the counts of today, got %+v", funnel.Days)
+	}
+	want := analytics.FunnelDay{Checks: 3, Eligible: 2, ButtonsShown: 2, Redeemed: 1, Unclaimed: 1}
+	if funnel.Total != want {
+		t.Errorf("Expected totals %+v, got %+v", want, funnel.Total)
+	}
+	for _, stage := range analytics.Stages {
+		if funnel.Days[0].Stage(stage) != funnel.Total.Stage(stage) {
+			t.Errorf("Expected the day to hold all %s counts", stage)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package analytics
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// Dataset modes
+const (
+	ModeHashed     = "hashed"     // Guests are one-way pseudonyms
+	ModeReversible = "reversible" // Guests are tokens that the salt turns back into emails
+)
+
+// reversiblePrefix starts the guest tokens of reversible datasets
+const reversiblePrefix = "r1."
+
+// Errors of anonymized datasets
+var (
+	ErrNoSalt       = errors.New("anonymized datasets need reports.dataset.salt to be set")
+	ErrInvalidToken = errors.New("invalid guest token")
+)
+
+// DatasetRecord is a guest with the email replaced and the times bucketed
+type DatasetRecord struct {
+	Guest     string     `json:"guest"`  // Pseudonym, or token in reversible datasets
+	Source    string     `json:"source"` // Kind of creator, such as token, telegram or import
+	Added     time.Time  `json:"added"`
+	Redeemed  *time.Time `json:"redeemed,omitempty"`
+	Bar       string     `json:"bar,omitempty"`
+	Consented bool       `json:"consented"`
+}
+
+// Anonymizer turns guest records into a dataset that can be shared. The
+// same salt always gives a guest the same pseudonym, so datasets exported
+// at different times can be joined.
+type Anonymizer struct {
+	key    []byte
+	bucket time.Duration
+	aead   cipher.AEAD
+}
+
+// NewAnonymizer creates an anonymizer with the secret salt, rounding times
+// down to the bucket
+func NewAnonymizer(salt string, bucket time.Duration) (*Anonymizer, error) {
+	if salt == "" {
+		return nil, ErrNoSalt
+	}
+	if bucket <= 0 {
+		bucket = time.Hour
+	}
+
+	// Tokens are encrypted with a key derived from the salt
+	key := sha256.Sum256([]byte("dataset-token|" + salt))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Anonymizer{key: []byte(salt), bucket: bucket, aead: aead}, nil
+}
+
+// Bucket returns the length that times are rounded down to
+func (a *Anonymizer) Bucket() time.Duration {
+	return a.bucket
+}
+
+// Pseudonym returns the one-way pseudonym of an email
+func (a *Anonymizer) Pseudonym(email string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte("guest|" + email))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Token returns the reversible token of an email. Tokens are deterministic,
+// so they can be joined like pseudonyms, and only the salt opens them.
+func (a *Anonymizer) Token(email string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte("nonce|" + email))
+	nonce := mac.Sum(nil)[:a.aead.NonceSize()]
+	sealed := a.aead.Seal(append([]byte(nil), nonce...), nonce, []byte(email), nil)
+	return reversiblePrefix + base64.RawURLEncoding.EncodeToString(sealed)
+}
+
+// Reveal returns the email of a reversible token
+func (a *Anonymizer) Reveal(token string) (string, error) {
+	encoded, ok := strings.CutPrefix(token, reversiblePrefix)
+	if !ok {
+		return "", ErrInvalidToken
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < a.aead.NonceSize() {
+		return "", ErrInvalidToken
+	}
+	nonce, ciphertext := sealed[:a.aead.NonceSize()], sealed[a.aead.NonceSize():]
+	email, err := a.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	return string(email), nil
+}
+
+// Dataset anonymizes the users in the given mode, hashed by default
+func (a *Anonymizer) Dataset(users []*domain.User, mode string) []DatasetRecord {
+	records := make([]DatasetRecord, 0, len(users))
+	for _, user := range users {
+		record := DatasetRecord{
+			Guest:     a.Pseudonym(user.Email),
+			Added:     a.round(user.DateAdded),
+			Bar:       user.RedeemedAt,
+			Consented: user.HasMarketingConsent(),
+		}
+		if mode == ModeReversible {
+			record.Guest = a.Token(user.Email)
+		}
+		// User IDs and token fingerprints after the colon would identify guests
+		record.Source, _, _ = strings.Cut(user.CreatedBy, ":")
+		if user.Redeemed != nil {
+			redeemed := a.round(*user.Redeemed)
+			record.Redeemed = &redeemed
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// round returns the start of the bucket of a time, in UTC
+func (a *Anonymizer) round(t time.Time) time.Time {
+	return t.UTC().Truncate(a.bucket)
+}
+
+// WriteDatasetCSV writes the records as CSV with a header row
+func WriteDatasetCSV(w io.Writer, records []DatasetRecord) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Guest", "Source", "Added", "Redeemed", "Bar", "Consented"})
+	for _, record := range records {
+		redeemed := ""
+		if record.Redeemed != nil {
+			redeemed = record.Redeemed.Format(time.RFC3339)
+		}
+		writer.Write([]string{
+			record.Guest,
+			record.Source,
+			record.Added.Format(time.RFC3339),
+			redeemed,
+			record.Bar,
+			strconv.FormatBool(record.Consented),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
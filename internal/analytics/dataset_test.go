@@ -0,0 +1,73 @@
+package analytics_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/analytics"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+func TestDataset(t *testing.T) {
+	if _, err := analytics.NewAnonymizer("", time.Hour); !errors.Is(err, analytics.ErrNoSalt) {
+		t.Fatalf("Expected ErrNoSalt, got %v", err)
+	}
+	anonymizer, err := analytics.NewAnonymizer("pepper", time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	added := time.Date(2025, 6, 14, 18, 42, 7, 0, time.UTC)
+	redeemed := added.Add(3 * time.Hour)
+	users := []*domain.User{
+		{Email: "guest@example.com", DateAdded: added, Redeemed: &redeemed, RedeemedAt: "Rooftop", CreatedBy: "telegram:12345"},
+		{Email: "other@example.com", DateAdded: added, CreatedBy: "rsvp_import"},
+	}
+
+	records := anonymizer.Dataset(users, analytics.ModeHashed)
+	first := records[0]
+	if first.Guest == "" || strings.Contains(first.Guest, "guest") || first.Guest == records[1].Guest {
+		t.Errorf("Unexpected pseudonyms %q and %q", first.Guest, records[1].Guest)
+	}
+	if first.Guest != anonymizer.Pseudonym("guest@example.com") {
+		t.Error("Expected the same pseudonym for every export")
+	}
+	if !first.Added.Equal(time.Date(2025, 6, 14, 18, 0, 0, 0, time.UTC)) || first.Redeemed == nil || first.Redeemed.Minute() != 0 {
+		t.Errorf("Expected times rounded to the hour, got %v and %v", first.Added, first.Redeemed)
+	}
+	if first.Source != "telegram" || records[1].Source != "rsvp_import" || first.Bar != "Rooftop" {
+		t.Errorf("Unexpected record: %+v", first)
+	}
+
+	// Another salt gives other pseudonyms
+	other, _ := analytics.NewAnonymizer("salt", time.Hour)
+	if other.Pseudonym("guest@example.com") == first.Guest {
+		t.Error("Expected pseudonyms to depend on the salt")
+	}
+
+	// Reversible tokens open with the same salt only
+	token := anonymizer.Dataset(users, analytics.ModeReversible)[0].Guest
+	if token != anonymizer.Token("guest@example.com") {
+		t.Error("Expected tokens to be deterministic")
+	}
+	if email, err := anonymizer.Reveal(token); err != nil || email != "guest@example.com" {
+		t.Errorf("Expected the token to reveal the email, got %q, %v", email, err)
+	}
+	if _, err := other.Reveal(token); !errors.Is(err, analytics.ErrInvalidToken) {
+		t.Errorf("Expected another salt to fail, got %v", err)
+	}
+	if _, err := anonymizer.Reveal(first.Guest); !errors.Is(err, analytics.ErrInvalidToken) {
+		t.Errorf("Expected a pseudonym not to reveal anything, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := analytics.WriteDatasetCSV(&buf, records); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 || strings.Contains(buf.String(), "@") {
+		t.Errorf("Unexpected CSV:\n%s", buf.String())
+	}
+}
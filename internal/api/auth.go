@@ -1,71 +1,206 @@
 package api
 
 import (
+	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
 	"sync"
 )
 
-// AuthProvider handles API authentication
+// tokenHashPrefix marks configured tokens given as their SHA-256 hash
+// rather than in plain text
+const tokenHashPrefix = "sha256:"
+
+// HashToken returns the hashed form of a token, as stored in the tokens
+// file so the file does not reveal the token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return tokenHashPrefix + hex.EncodeToString(sum[:])
+}
+
+// IsTokenHash reports whether a configured token is a hash made by HashToken
+func IsTokenHash(entry string) bool {
+	return strings.HasPrefix(entry, tokenHashPrefix)
+}
+
+// tokenHash returns the SHA-256 hash of a configured token, given in plain
+// text or hashed
+func tokenHash(entry string) ([]byte, error) {
+	if !IsTokenHash(entry) {
+		sum := sha256.Sum256([]byte(entry))
+		return sum[:], nil
+	}
+	hash, err := hex.DecodeString(strings.TrimPrefix(entry, tokenHashPrefix))
+	if err != nil || len(hash) != sha256.Size {
+		return nil, fmt.Errorf("invalid token hash %q, expected %s and 64 hex digits", entry, tokenHashPrefix)
+	}
+	return hash, nil
+}
+
+// CheckTokens returns an error for the first configured token that is an
+// invalid hash. Such tokens are ignored.
+func CheckTokens(entries ...[]string) error {
+	for _, list := range entries {
+		for _, entry := range list {
+			if _, err := tokenHash(entry); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AuthProvider handles API authentication. It keeps only the SHA-256
+// hashes of tokens and compares them in constant time.
 type AuthProvider struct {
-	tokens map[string]bool
-	mu     sync.RWMutex
+	tokens      map[string]bool // Keyed by the hash of the token
+	adminTokens map[string]bool
+	mu          sync.RWMutex
 }
 
-// NewAuthProvider creates a new authentication provider with the given tokens
+// NewAuthProvider creates a new authentication provider with the given
+// tokens, in plain text or hashed with HashToken
 func NewAuthProvider(tokens []string) *AuthProvider {
-	// Create a map for O(1) lookups
-	tokenMap := make(map[string]bool, len(tokens))
+	a := &AuthProvider{
+		tokens:      make(map[string]bool, len(tokens)),
+		adminTokens: make(map[string]bool),
+	}
 	for _, token := range tokens {
-		if token != "" {
-			tokenMap[token] = true
-		}
+		a.add(token, false)
+	}
+	return a
+}
+
+// NewAuthProviderWithAdmins creates a new authentication provider with regular
+// and admin-scoped tokens. Admin tokens are also valid for regular endpoints.
+func NewAuthProviderWithAdmins(tokens, adminTokens []string) *AuthProvider {
+	a := NewAuthProvider(tokens)
+	for _, token := range adminTokens {
+		a.add(token, true)
 	}
+	return a
+}
+
+// add adds a configured token. Empty tokens and invalid hashes are
+// ignored. The caller must hold mu or own a.
+func (a *AuthProvider) add(entry string, admin bool) {
+	if entry == "" {
+		return
+	}
+	hash, err := tokenHash(entry)
+	if err != nil {
+		return
+	}
+	a.tokens[string(hash)] = true
+	if admin {
+		a.adminTokens[string(hash)] = true
+	}
+}
 
-	return &AuthProvider{
-		tokens: tokenMap,
+// matches reports whether the hash of token is in hashes. Every hash is
+// compared in constant time, so the time taken does not tell how much of
+// a token matched.
+func matches(hashes map[string]bool, token string) bool {
+	sum := sha256.Sum256([]byte(token))
+	found := 0
+	for hash := range hashes {
+		found |= subtle.ConstantTimeCompare(sum[:], []byte(hash))
 	}
+	return found == 1
 }
 
 // Authenticate validates the provided token
 // Returns true if the token is valid
 func (a *AuthProvider) Authenticate(token string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	// If we have no tokens, reject all requests
+	if token == "" || len(a.tokens) == 0 {
+		return false
+	}
+	return matches(a.tokens, token)
+}
+
+// IsAdmin validates the provided token against the admin-scoped tokens
+// Returns true if the token grants admin access
+func (a *AuthProvider) IsAdmin(token string) bool {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
-	if len(a.tokens) == 0 {
+	if token == "" {
 		return false
 	}
+	return matches(a.adminTokens, token)
+}
 
-	// Check for token in the map
-	// Note: We use a constant-time comparison approach to 
-	// prevent timing attacks, even though that's overkill 
-	// for a simple API key check like this
-	for validToken := range a.tokens {
-		if subtle.ConstantTimeCompare([]byte(token), []byte(validToken)) == 1 {
-			return true
-		}
+// Scopes granted by API tokens
+const (
+	ScopeAPI   = "api"   // Guest lookups, redemptions, imports and reports
+	ScopeAdmin = "admin" // Admin endpoints, full emails and reversible datasets
+)
+
+// Scopes returns the scopes the provided token grants, nil if it is invalid
+func (a *AuthProvider) Scopes(token string) []string {
+	if !a.Authenticate(token) {
+		return nil
+	}
+	if a.IsAdmin(token) {
+		return []string{ScopeAPI, ScopeAdmin}
 	}
+	return []string{ScopeAPI}
+}
+
+// TokenFingerprint returns a short, non-reversible identifier for a token
+// suitable for audit logs
+func TokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:4])
+}
+
+// EntryFingerprint returns the fingerprint of a configured token, in plain
+// text or hashed. It equals TokenFingerprint of the token itself.
+func EntryFingerprint(entry string) string {
+	hash, err := tokenHash(entry)
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(hash[:4])
+}
 
-	return false
+// Fingerprints returns the fingerprints of all configured tokens
+func (a *AuthProvider) Fingerprints() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	fingerprints := make([]string, 0, len(a.tokens))
+	for hash := range a.tokens {
+		fingerprints = append(fingerprints, hex.EncodeToString([]byte(hash[:4])))
+	}
+	return fingerprints
 }
 
-// AddToken adds a new token to the provider
+// AddToken adds a new token to the provider, in plain text or hashed
 func (a *AuthProvider) AddToken(token string) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	if token != "" {
-		a.tokens[token] = true
-	}
+	a.add(token, false)
 }
 
-// RemoveToken removes a token from the provider
+// RemoveToken removes a token from the provider, in plain text or hashed
 func (a *AuthProvider) RemoveToken(token string) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	delete(a.tokens, token)
+	hash, err := tokenHash(token)
+	if err != nil {
+		return
+	}
+	delete(a.tokens, string(hash))
+	delete(a.adminTokens, string(hash))
 }
 
 // HasTokens returns true if there are tokens configured
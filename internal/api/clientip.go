@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/proxyproto"
+)
+
+// trustedProxies holds the networks whose forwarding headers are believed
+var trustedProxies atomic.Pointer[[]netip.Prefix]
+
+// SetTrustedProxies sets the networks, such as 10.0.0.0/8, of the proxies
+// whose X-Forwarded-For and X-Real-IP headers name the client. Clients of
+// other peers are their own address, whatever headers they send. The API
+// and the Web UI both set them from api.trusted_proxies.
+func SetTrustedProxies(networks []string) error {
+	prefixes := make([]netip.Prefix, 0, len(networks))
+	for _, network := range networks {
+		prefix, err := config.ParseNetwork(network)
+		if err != nil {
+			return err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	trustedProxies.Store(&prefixes)
+	return nil
+}
+
+// isTrustedProxy reports whether the peer address is a trusted proxy
+func isTrustedProxy(ip string) bool {
+	prefixes := trustedProxies.Load()
+	if prefixes == nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	for _, prefix := range *prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP extracts the client IP address from the request, in canonical
+// form: IPv4 addresses mapped into IPv6 are plain IPv4, IPv6 addresses are
+// lowercase and compressed, and ports and zones are dropped. Forwarding
+// headers are only read from trusted proxies, and never on connections
+// whose address came from the PROXY protocol. X-Forwarded-For gives the
+// right-most address that is not a trusted proxy. Headers that do not hold
+// an address are skipped.
+func ClientIP(r *http.Request) string {
+	remote := normalizeIP(r.RemoteAddr)
+	if remote == "" {
+		return r.RemoteAddr // Return as is if it is not an address
+	}
+	if proxyproto.FromContext(r.Context()) || !isTrustedProxy(remote) {
+		return remote
+	}
+
+	if ip := forwardedFor(r.Header.Values("X-Forwarded-For")); ip != "" {
+		return ip
+	}
+
+	if ip := normalizeIP(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+	return remote
+}
+
+// forwardedFor returns the client named by X-Forwarded-For headers, or ""
+// if they name none. Each proxy appends the peer it got the request from,
+// so only the entries from the right up to the first untrusted address
+// were written by trusted proxies; anything left of it came from the
+// client and may be made up. The walk stops at an entry that is not an
+// address. When every entry is a trusted proxy, the left-most one is the
+// client.
+func forwardedFor(headers []string) string {
+	entries := strings.Split(strings.Join(headers, ","), ",")
+	client := ""
+	for i := len(entries) - 1; i >= 0; i-- {
+		ip := normalizeIP(entries[i])
+		if ip == "" {
+			break
+		}
+		client = ip
+		if !isTrustedProxy(ip) {
+			break
+		}
+	}
+	return client
+}
+
+// normalizeIP returns the canonical form of an address, which may carry a
+// port and brackets such as [2001:db8::1]:443, or "" if it is none
+func normalizeIP(value string) string {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(value, "[]"))
+	if err != nil {
+		return ""
+	}
+	return addr.Unmap().WithZone("").String()
+}
+
+// ClientPrefix returns what rate limits count a client IP under. Clients
+// usually get a whole IPv6 network, such as a /64, so with prefix between
+// 1 and 127 IPv6 addresses count as their network, like 2001:db8:1:2::/64.
+// IPv4 addresses, and anything else, count as themselves.
+func ClientPrefix(ip string, prefix int) string {
+	if prefix <= 0 || prefix >= 128 {
+		return ip
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !addr.Is6() || addr.Is4In6() {
+		return ip
+	}
+	network, err := addr.WithZone("").Prefix(prefix)
+	if err != nil {
+		return ip
+	}
+	return network.String()
+}
+
+// clientLimitKey returns the key of the API limiter for the client of a
+// request under a token
+func (s *Server) clientLimitKey(r *http.Request, fingerprint string) string {
+	return clientKey(fingerprint, ClientPrefix(ClientIP(r), s.config.API.IPv6Prefix))
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/period"
+)
+
+// counterStreamPath streams the redemption count of the night to venue
+// screens. It needs a token unless api.counter.public is set.
+const counterStreamPath = "/api/v1/public/counter/stream"
+
+// Defaults of the counter stream when they are not configured
+const (
+	defaultCounterInterval   = 5 * time.Second
+	defaultCounterMaxStreams = 50
+	counterKeepAlive         = 30 * time.Second // Comment lines keeping idle proxies from closing the stream
+)
+
+// CounterEvent is the data of a count event in the counter stream
+type CounterEvent struct {
+	Count   int       `json:"count"`  // Cocktails served today
+	Period  string    `json:"period"` // Always "today"
+	Updated time.Time `json:"updated"`
+}
+
+// counterHub reads the count once for all connected screens. It polls
+// the database only while at least one screen is connected.
+type counterHub struct {
+	server     *Server
+	interval   time.Duration
+	maxStreams int
+
+	mu      sync.Mutex
+	streams map[chan CounterEvent]struct{}
+	last    *CounterEvent // Last count read, nil until the first read
+	stop    chan struct{} // Stops polling, nil while not polling
+	closed  chan struct{} // Closed when the server shuts down
+}
+
+// newCounterHub creates the hub of the counter stream
+func newCounterHub(s *Server) *counterHub {
+	h := &counterHub{
+		server:     s,
+		interval:   s.config.API.Counter.Interval.Duration(),
+		maxStreams: s.config.API.Counter.MaxStreams,
+		streams:    make(map[chan CounterEvent]struct{}),
+		closed:     make(chan struct{}),
+	}
+	if h.interval <= 0 {
+		h.interval = defaultCounterInterval
+	}
+	if h.maxStreams <= 0 {
+		h.maxStreams = defaultCounterMaxStreams
+	}
+	return h
+}
+
+// subscribe registers a screen. It returns false if too many screens are
+// connected. The last count, if any, is ready on the channel at once.
+func (h *counterHub) subscribe() (chan CounterEvent, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.streams) >= h.maxStreams {
+		return nil, false
+	}
+	ch := make(chan CounterEvent, 1)
+	if h.last != nil {
+		ch <- *h.last
+	}
+	h.streams[ch] = struct{}{}
+	if h.stop == nil {
+		h.stop = make(chan struct{})
+		go h.poll(h.stop)
+	}
+	return ch, true
+}
+
+// unsubscribe removes a screen, and stops polling after the last one
+func (h *counterHub) unsubscribe(ch chan CounterEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.streams, ch)
+	if len(h.streams) == 0 && h.stop != nil {
+		close(h.stop)
+		h.stop = nil
+		h.last = nil // Screens connecting later get a fresh count
+	}
+}
+
+// close ends every stream, so the server can shut down
+func (h *counterHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	select {
+	case <-h.closed:
+	default:
+		close(h.closed)
+	}
+}
+
+// poll reads the count every interval until stop is closed, and sends it
+// to the screens when it changed
+func (h *counterHub) poll(stop chan struct{}) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		h.refresh()
+		select {
+		case <-stop:
+			return
+		case <-h.closed:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh reads the count and sends it to the screens if it changed.
+// Errors are only logged, screens keep showing the last count.
+func (h *counterHub) refresh() {
+	count, err := h.server.redeemedToday()
+	if err != nil {
+		h.server.logger.Warn("Error reading the redemption count", "error", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.last != nil && h.last.Count == count {
+		return
+	}
+	event := CounterEvent{Count: count, Period: period.Today, Updated: time.Now()}
+	h.last = &event
+	for ch := range h.streams {
+		// Screens that did not take the previous count get the newer one
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- event
+	}
+}
+
+// redeemedToday counts the cocktails served since midnight in the event's
+// timezone
+func (s *Server) redeemedToday() (int, error) {
+	from, to, err := s.calendar.Range(period.Today, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	users, err := s.service.GenerateReport(context.Background(), "redeemed", from, to, domain.ReportFilter{})
+	if err != nil {
+		return 0, err
+	}
+	return len(users), nil
+}
+
+// handleCounterStream streams the redemption count of the night as
+// server-sent events. Only the count is sent, never guest data.
+func (s *Server) handleCounterStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	ch, ok := s.counter.subscribe()
+	if !ok {
+		setRetryAfter(w, s.counter.interval)
+		s.writeErrorResponse(w, r, "Service unavailable", http.StatusServiceUnavailable, "Too many counter streams are open")
+		return
+	}
+	defer s.counter.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", s.counter.interval.Milliseconds())
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(counterKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event := <-ch:
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "event: count\ndata: %s\n\n", data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		case <-s.counter.closed:
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/analytics"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// DatasetResponse represents the anonymized dataset of a report
+type DatasetResponse struct {
+	Type      string                    `json:"type"`
+	Mode      string                    `json:"mode"`   // hashed or reversible
+	Bucket    string                    `json:"bucket"` // Times are rounded down to this, in UTC
+	From      string                    `json:"from"`
+	To        string                    `json:"to"`
+	Count     int                       `json:"count"`
+	Records   []analytics.DatasetRecord `json:"records"`
+	Generated time.Time                 `json:"generated"`
+}
+
+// handleReportDataset serves the guests of a report as an anonymized
+// dataset: emails replaced by pseudonyms and times bucketed. Reversible
+// datasets, whose tokens the salt turns back into emails, need an admin
+// token.
+func (s *Server) handleReportDataset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	anonymizer, err := analytics.NewAnonymizer(s.config.Reports.Dataset.Salt, s.config.Reports.Dataset.Bucket.Duration())
+	if err != nil {
+		s.writeErrorResponse(w, r, "Not Found", http.StatusNotFound, "Datasets are not configured, set reports.dataset.salt")
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	switch mode {
+	case "":
+		mode = analytics.ModeHashed
+	case analytics.ModeHashed:
+	case analytics.ModeReversible:
+		token := tokenFromContext(r.Context())
+		if s.authProvider == nil || !s.authProvider.IsAdmin(token) {
+			s.writeErrorResponse(w, r, "Forbidden", http.StatusForbidden, "Reversible datasets need an admin token")
+			return
+		}
+		s.log(r).Info("Reversible dataset exported", "token", TokenFingerprint(token))
+	default:
+		s.writeErrorResponse(w, r, "Invalid mode", http.StatusBadRequest, "mode must be hashed or reversible")
+		return
+	}
+
+	reportType := r.URL.Query().Get("type")
+	if reportType == "" {
+		reportType = string(domain.ReportTypeAll)
+	}
+	if _, err := domain.ValidateReportType(reportType); err != nil {
+		s.writeErrorResponse(w, r, "Invalid report type", http.StatusBadRequest, err.Error())
+		return
+	}
+	fromDate, toDate, err := s.parseDateParams(r)
+	if err != nil {
+		s.writeErrorResponse(w, r, "Invalid date format", http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, err := reportFilter(r)
+	if err != nil {
+		s.writeErrorResponse(w, r, "Invalid filter", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, err := s.service.GenerateReport(serviceContext(r), reportType, fromDate, toDate, filter)
+	if err != nil {
+		s.log(r).Error("Error generating dataset", "type", reportType, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error generating report")
+		return
+	}
+	records := anonymizer.Dataset(users, mode)
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"dataset-%s-%s.csv\"",
+			reportType, time.Now().Format("2006-01-02")))
+		if err := analytics.WriteDatasetCSV(w, records); err != nil {
+			s.log(r).Error("Error writing CSV dataset", "error", err)
+		}
+		return
+	}
+
+	s.writeJSONResponse(w, DatasetResponse{
+		Type:      reportType,
+		Mode:      mode,
+		Bucket:    anonymizer.Bucket().String(),
+		From:      fromDate.Format(time.RFC3339),
+		To:        toDate.Format(time.RFC3339),
+		Count:     len(records),
+		Records:   records,
+		Generated: time.Now(),
+	}, http.StatusOK)
+}
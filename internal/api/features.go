@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ceesaxp/cocktail-bot/internal/featureflags"
+)
+
+// featuresPathPrefix is the admin path toggling a feature flag, followed by
+// the flag name
+const featuresPathPrefix = "/api/v1/admin/features/"
+
+// FeaturesResponse represents the JSON response listing the feature flags
+type FeaturesResponse struct {
+	Features      []featureflags.Flag `json:"features"`
+	RuntimeToggle bool                `json:"runtime_toggle"` // Whether admin tokens may toggle flags
+}
+
+// FeatureToggleRequest represents the JSON request toggling a feature flag
+type FeatureToggleRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// handleFeatures lists the feature flags and whether each is on
+func (s *Server) handleFeatures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	s.writeJSONResponse(w, FeaturesResponse{
+		Features:      s.service.Features(),
+		RuntimeToggle: s.config.Features.RuntimeToggle,
+	}, http.StatusOK)
+}
+
+// handleFeatureToggle handles the admin endpoint turning a feature flag on
+// or off with PUT, or back to its configured state with DELETE, until the
+// next restart
+func (s *Server) handleFeatureToggle(w http.ResponseWriter, r *http.Request) {
+	var enabled *bool
+	switch r.Method {
+	case http.MethodPut:
+		var req FeatureToggleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+			s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "A JSON payload with enabled is required")
+			return
+		}
+		enabled = req.Enabled
+	case http.MethodDelete:
+	default:
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only PUT and DELETE methods are allowed")
+		return
+	}
+
+	name := featureflags.Name(strings.TrimPrefix(r.URL.Path, featuresPathPrefix))
+	flag, err := s.service.ToggleFeature(serviceContext(r), name, enabled, tokenActor(r.Context()))
+	switch {
+	case err == nil:
+		s.writeJSONResponse(w, flag, http.StatusOK)
+	case errors.Is(err, featureflags.ErrUnknownFlag):
+		s.writeErrorResponse(w, r, "Not Found", http.StatusNotFound, err.Error())
+	case errors.Is(err, featureflags.ErrToggleDisabled):
+		s.writeErrorResponse(w, r, "Forbidden", http.StatusForbidden, "Set features.runtime_toggle to toggle feature flags")
+	default:
+		s.log(r).Error("Error toggling feature flag", "flag", name, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error toggling feature flag")
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// readinessTimeout bounds the checks run for a single readiness probe
+const readinessTimeout = 2 * time.Second
+
+// ReadinessResponse represents the JSON response of the readiness probe
+type ReadinessResponse struct {
+	Status string            `json:"status"` // ready or not_ready
+	Checks map[string]string `json:"checks"` // ok, pending or unavailable for each check
+}
+
+// readiness tracks whether the bot can take traffic: startup steps that
+// must have completed, and checks run on every probe
+type readiness struct {
+	mu     sync.Mutex
+	steps  map[string]bool // Startup steps and whether they have completed
+	checks map[string]func(ctx context.Context) error
+}
+
+func newReadiness() *readiness {
+	return &readiness{
+		steps:  make(map[string]bool),
+		checks: make(map[string]func(ctx context.Context) error),
+	}
+}
+
+// SetReady records whether a startup step, such as connecting the chat
+// channel, has completed. Steps set to false, including before shutdown,
+// make the readiness probe fail until they are set to true.
+func (s *Server) SetReady(step string, ready bool) {
+	s.readiness.mu.Lock()
+	defer s.readiness.mu.Unlock()
+	s.readiness.steps[step] = ready
+}
+
+// AddReadinessCheck adds a check run on every readiness probe, such as
+// pinging a dependency
+func (s *Server) AddReadinessCheck(name string, check func(ctx context.Context) error) {
+	s.readiness.mu.Lock()
+	defer s.readiness.mu.Unlock()
+	s.readiness.checks[name] = check
+}
+
+// handleLiveness handles the liveness probe. It only reports that the
+// process is serving requests, so a slow dependency never gets it restarted.
+func (s *Server) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+	s.writeJSONResponse(w, map[string]string{"status": "ok"}, http.StatusOK)
+}
+
+// handleReadiness handles the readiness probe. It fails while a startup
+// step is pending or a dependency such as the repository is unreachable,
+// so no traffic is routed to the bot before it can serve it.
+func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	s.readiness.mu.Lock()
+	resp := ReadinessResponse{Status: "ready", Checks: make(map[string]string)}
+	for step, ready := range s.readiness.steps {
+		resp.Checks[step] = "ok"
+		if !ready {
+			resp.Checks[step] = "pending"
+			resp.Status = "not_ready"
+		}
+	}
+	checks := make(map[string]func(ctx context.Context) error, len(s.readiness.checks))
+	names := make([]string, 0, len(s.readiness.checks))
+	for name, check := range s.readiness.checks {
+		checks[name] = check
+		names = append(names, name)
+	}
+	s.readiness.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	sort.Strings(names)
+	for _, name := range names {
+		resp.Checks[name] = "ok"
+		if err := checks[name](ctx); err != nil {
+			// Errors are only logged, the probe is public
+			s.log(r).Warn("Readiness check failed", "check", name, "error", err)
+			resp.Checks[name] = "unavailable"
+			resp.Status = "not_ready"
+		}
+	}
+
+	status := http.StatusOK
+	if resp.Status != "ready" {
+		status = http.StatusServiceUnavailable
+	}
+	s.writeJSONResponse(w, resp, status)
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+)
+
+// IntrospectRequest names the token to describe, when it is not the one
+// the request is made with
+type IntrospectRequest struct {
+	Token string `json:"token"`
+}
+
+// IntrospectResponse describes a token, so clients can adapt to its
+// permissions without trying endpoints
+type IntrospectResponse struct {
+	Valid       bool       `json:"valid"`
+	Active      bool       `json:"active"`                // Same as valid, as named by RFC 7662
+	Fingerprint string     `json:"fingerprint,omitempty"` // As recorded in the audit log
+	Scopes      []string   `json:"scopes"`
+	Expires     *time.Time `json:"expires"`            // API tokens do not expire, so always null
+	Events      []string   `json:"events"`             // Events the token works for: the one this instance serves
+	Operator    string     `json:"operator,omitempty"` // From the X-Operator header, as recorded
+}
+
+// handleIntrospect describes the token of the request with GET, or the
+// token posted in the body with POST. The caller is authenticated like on
+// any other endpoint, so a missing or invalid caller token gets 401, and
+// only admin tokens may post a token other than their own. A posted token
+// that is invalid is described as not valid.
+func (s *Server) handleIntrospect(w http.ResponseWriter, r *http.Request) {
+	// Public endpoints get no token, and do not check one either
+	caller := tokenFromContext(r.Context())
+	token := caller
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req IntrospectRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+			s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "A JSON payload with token is required")
+			return
+		}
+		token = req.Token
+
+		// Only admins may check other tokens, so API tokens are no oracle
+		// for guessed or leaked ones
+		if token != caller && !s.authProvider.IsAdmin(caller) {
+			s.writeErrorResponse(w, r, "Forbidden", http.StatusForbidden, "Admin token required")
+			return
+		}
+	default:
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and POST methods are allowed")
+		return
+	}
+
+	scopes := s.authProvider.Scopes(token)
+	if scopes == nil {
+		s.writeJSONResponse(w, IntrospectResponse{Scopes: []string{}, Events: []string{}}, http.StatusOK)
+		return
+	}
+
+	events := []string{}
+	if s.config.Event.Name != "" {
+		events = append(events, s.config.Event.Name)
+	}
+	info := IntrospectResponse{
+		Valid:       true,
+		Active:      true,
+		Fingerprint: TokenFingerprint(token),
+		Scopes:      scopes,
+		Events:      events,
+	}
+	if token == caller {
+		info.Operator = audit.OperatorFromContext(r.Context())
+	}
+	s.writeJSONResponse(w, info, http.StatusOK)
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/jobs"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// Paths of long-running operations
+const (
+	jobsPathPrefix       = "/api/v1/jobs/"             // Followed by the job ID, and /cancel to stop it
+	migrationsPathPrefix = "/api/v1/admin/migrations/" // Followed by the migration name
+)
+
+// JobsResponse represents the JSON response listing jobs
+type JobsResponse struct {
+	Jobs  []jobs.Job `json:"jobs"`
+	Count int        `json:"count"`
+}
+
+// ownsJob returns whether the token of the request may see and cancel the
+// job: admin tokens may see every job, other tokens the jobs they started
+func (s *Server) ownsJob(r *http.Request, job jobs.Job) bool {
+	token := tokenFromContext(r.Context())
+	if s.authProvider != nil && s.authProvider.IsAdmin(token) {
+		return true
+	}
+	return job.Actor == tokenActor(r.Context())
+}
+
+// jobView returns the job as the request may see it, with guest emails
+// in the errors masked in privacy mode
+func (s *Server) jobView(r *http.Request, job jobs.Job) jobs.Job {
+	if s.revealEmails(r) {
+		return job
+	}
+	for i, msg := range job.Errors {
+		if email, reason, ok := strings.Cut(msg, ": "); ok && strings.Contains(email, "@") {
+			job.Errors[i] = utils.MaskEmail(email) + ": " + reason
+		}
+	}
+	return job
+}
+
+// handleJobs lists the recent jobs the token may see, newest first
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	response := JobsResponse{Jobs: []jobs.Job{}}
+	for _, job := range s.service.Jobs() {
+		if s.ownsJob(r, job) {
+			response.Jobs = append(response.Jobs, s.jobView(r, job))
+		}
+	}
+	response.Count = len(response.Jobs)
+	s.writeJSONResponse(w, response, http.StatusOK)
+}
+
+// handleJob returns the progress of a job, or cancels it on POST to
+// /api/v1/jobs/{id}/cancel
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, jobsPathPrefix), "/")
+	if id == "" || (action != "" && action != "cancel") {
+		s.writeErrorResponse(w, r, "Not Found", http.StatusNotFound, "Use /api/v1/jobs/{id} or /api/v1/jobs/{id}/cancel")
+		return
+	}
+	if action == "" && r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+	if action == "cancel" && r.Method != http.MethodPost {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	// Jobs of other tokens are reported as missing
+	job, err := s.service.Job(id)
+	if err != nil || !s.ownsJob(r, job) {
+		s.writeErrorResponse(w, r, "Not Found", http.StatusNotFound, "No such job")
+		return
+	}
+	if action == "" {
+		s.writeJSONResponse(w, s.jobView(r, job), http.StatusOK)
+		return
+	}
+
+	job, err = s.service.CancelJob(serviceContext(r), tokenActor(r.Context()), id)
+	switch {
+	case err == nil:
+		s.writeJSONResponse(w, s.jobView(r, job), http.StatusOK)
+	case errors.Is(err, jobs.ErrFinished):
+		s.writeErrorResponse(w, r, "Conflict", http.StatusConflict, "Job already finished as "+string(job.Status))
+	case errors.Is(err, jobs.ErrNotFound):
+		s.writeErrorResponse(w, r, "Not Found", http.StatusNotFound, "No such job")
+	default:
+		s.log(r).Error("Error canceling job", "job", id, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error canceling job")
+	}
+}
+
+// handleMigration handles the admin endpoint starting a migration of the
+// stored records as a job
+func (s *Server) handleMigration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, migrationsPathPrefix)
+	job, err := s.service.StartMigration(serviceContext(r), tokenActor(r.Context()), name)
+	switch {
+	case err == nil:
+		w.Header().Set("Location", jobsPathPrefix+job.ID)
+		s.writeJSONResponse(w, job, http.StatusAccepted)
+	case errors.Is(err, domain.ErrMigrationUnknown):
+		s.writeErrorResponse(w, r, "Not Found", http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrMigrationUnsupported):
+		s.writeErrorResponse(w, r, "Not Implemented", http.StatusNotImplemented, err.Error())
+	default:
+		s.log(r).Error("Error starting migration", "migration", name, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error starting migration")
+	}
+}
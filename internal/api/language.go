@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// errorDetailKeys are the translation keys of the error details clients
+// commonly show to people. Other details are sent in English.
+var errorDetailKeys = map[string]string{
+	"Only GET method is allowed":                     "api_get_only",
+	"Only POST method is allowed":                    "api_post_only",
+	"Invalid JSON payload":                           "api_invalid_json",
+	"Content-Type must be application/json":          "api_json_required",
+	"Rate limit exceeded":                            "api_rate_limited",
+	"Invalid or missing authentication token":        "api_unauthorized",
+	"Admin token required":                           "api_admin_required",
+	"Database is temporarily unavailable":            "api_unavailable",
+	"Error processing request":                       "api_processing_error",
+	"The provided email address is not valid":        "api_invalid_email",
+	"Email address is not allowed":                   "api_email_denied",
+	"Email is not in the database":                   "api_email_not_found",
+	"Email address is not verified":                  "api_email_not_verified",
+	"Email lookups are disabled, use a voucher code": "api_voucher_required",
+	"The provided voucher code is not valid":         "api_invalid_voucher",
+	"Cocktail already redeemed":                      "api_already_redeemed",
+	"Event is archived":                              "api_event_archived",
+	"No such guest":                                  "api_no_such_guest",
+	"Ticket not found":                               "api_ticket_not_found",
+	"Ticket link has expired":                        "api_ticket_expired",
+	"Invalid ticket link":                            "api_ticket_invalid",
+}
+
+// localizeDetails translates the details of an error response to the
+// language the client asks for, and names it in Content-Language. Details
+// without a translation, and clients asking for no enabled language, get
+// English.
+func (s *Server) localizeDetails(w http.ResponseWriter, r *http.Request, details string) string {
+	key, ok := errorDetailKeys[details]
+	if !ok {
+		return details
+	}
+	lang := s.requestLanguage(r)
+	if lang == "" {
+		return details
+	}
+	w.Header().Set("Content-Language", lang)
+	return s.translator.T(lang, key)
+}
+
+// requestLanguage returns the first enabled language of the lang parameter
+// or the Accept-Language header, "" if the client asks for none of them.
+// Locales and aliases are matched as for the bot, so pt-BR gets pt.
+func (s *Server) requestLanguage(r *http.Request) string {
+	for _, tag := range acceptedLanguages(r) {
+		if lang, ok := s.translator.MatchLanguage(tag); ok {
+			return lang
+		}
+	}
+	return ""
+}
+
+// acceptedLanguages returns the language tags a request asks for, most
+// preferred first: the lang parameter, then Accept-Language by weight
+func acceptedLanguages(r *http.Request) []string {
+	type weighted struct {
+		tag    string
+		weight float64
+	}
+	var accepted []weighted
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		weight := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				weight = parsed
+			}
+		}
+		if tag = strings.TrimSpace(tag); tag != "" && tag != "*" && weight > 0 {
+			accepted = append(accepted, weighted{tag, weight})
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].weight > accepted[j].weight })
+
+	var tags []string
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		tags = append(tags, lang)
+	}
+	for _, a := range accepted {
+		tags = append(tags, a.tag)
+	}
+	return tags
+}
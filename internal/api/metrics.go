@@ -0,0 +1,183 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/analytics"
+	"github.com/ceesaxp/cocktail-bot/internal/crashreport"
+	"github.com/ceesaxp/cocktail-bot/internal/httpclient"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+)
+
+// Number of top consumers returned by the rate limiter endpoint
+const (
+	defaultRateLimitTop = 10
+	maxRateLimitTop     = 100
+)
+
+// RateLimitStatsResponse represents the JSON response of the rate limiter endpoint
+type RateLimitStatsResponse struct {
+	Limiters  map[string]ratelimit.Stats `json:"limiters"` // service, api_client and api_token
+	Generated time.Time                  `json:"generated"`
+}
+
+// rateLimitStats returns the state of every rate limiter by name. The
+// service limiter counts bot users and API clients, the API limiters
+// count clients per IP and token and tokens across IPs.
+func (s *Server) rateLimitStats(top int) map[string]ratelimit.Stats {
+	return map[string]ratelimit.Stats{
+		"service":    s.service.RateLimitStats(top),
+		"api_client": s.limiter.Stats(top),
+		"api_token":  s.tokenLimiter.Stats(top),
+	}
+}
+
+// handleRateLimitStats handles the admin endpoint showing the clients
+// tracked by the rate limiters, the top consumers of the last hour and
+// the requests rejected since start
+func (s *Server) handleRateLimitStats(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	top := defaultRateLimitTop
+	if param := r.URL.Query().Get("top"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 0 || parsed > maxRateLimitTop {
+			s.writeErrorResponse(w, r, "Invalid top", http.StatusBadRequest, fmt.Sprintf("top must be between 0 and %d", maxRateLimitTop))
+			return
+		}
+		top = parsed
+	}
+
+	s.writeJSONResponse(w, RateLimitStatsResponse{
+		Limiters:  s.rateLimitStats(top),
+		Generated: time.Now(),
+	}, http.StatusOK)
+}
+
+// handleMetrics serves the rate limiter state, the outbound HTTP client
+// counters, the redemption funnel, the email enumeration guard and the
+// recovered panics in the Prometheus text format. Individual clients are
+// not exported, only the busiest one, so the number of series stays fixed.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	limiters := s.rateLimitStats(-1)
+	names := make([]string, 0, len(limiters))
+	for name := range limiters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetric(w, "cocktailbot_ratelimit_tracked_clients", "gauge", "Clients tracked by the rate limiter", "limiter", names, func(name string) []sample {
+		return []sample{{value: float64(limiters[name].TrackedClients)}}
+	})
+	writeMetric(w, "cocktailbot_ratelimit_limit", "gauge", "Requests allowed per client in the window", "limiter", names, func(name string) []sample {
+		stats := limiters[name]
+		return []sample{
+			{window: "minute", value: float64(stats.RequestsPerMinute)},
+			{window: "hour", value: float64(stats.RequestsPerHour)},
+		}
+	})
+	writeMetric(w, "cocktailbot_ratelimit_busiest_client_requests", "gauge", "Requests of the busiest client in the window", "limiter", names, func(name string) []sample {
+		var minute, hour int
+		for _, consumer := range limiters[name].TopConsumers {
+			minute = max(minute, consumer.LastMinute)
+			hour = max(hour, consumer.LastHour)
+		}
+		return []sample{
+			{window: "minute", value: float64(minute)},
+			{window: "hour", value: float64(hour)},
+		}
+	})
+	writeMetric(w, "cocktailbot_ratelimit_allowed_total", "counter", "Requests allowed since start", "limiter", names, func(name string) []sample {
+		return []sample{{value: float64(limiters[name].Allowed)}}
+	})
+	writeMetric(w, "cocktailbot_ratelimit_rejected_total", "counter", "Requests rejected since start by the limit of the window", "limiter", names, func(name string) []sample {
+		stats := limiters[name]
+		return []sample{
+			{window: "minute", value: float64(stats.RejectedMinute)},
+			{window: "hour", value: float64(stats.RejectedHour)},
+		}
+	})
+
+	// Calls of the clients of the API and external services in this process
+	clients := httpclient.Snapshot()
+	clientNames := make([]string, 0, len(clients))
+	for name := range clients {
+		clientNames = append(clientNames, name)
+	}
+	sort.Strings(clientNames)
+	writeMetric(w, "cocktailbot_http_client_requests_total", "counter", "Outbound calls made since start, whatever the number of attempts", "client", clientNames, func(name string) []sample {
+		return []sample{{value: float64(clients[name].Requests)}}
+	})
+	writeMetric(w, "cocktailbot_http_client_retries_total", "counter", "Outbound attempts after a failed one since start", "client", clientNames, func(name string) []sample {
+		return []sample{{value: float64(clients[name].Retries)}}
+	})
+	writeMetric(w, "cocktailbot_http_client_failures_total", "counter", "Outbound calls that failed on their last attempt since start", "client", clientNames, func(name string) []sample {
+		return []sample{{value: float64(clients[name].Failures)}}
+	})
+
+	// Guests reaching each stage of the funnel, from checking an email to redeeming
+	funnel := s.service.FunnelStats().Total
+	writeMetric(w, "cocktailbot_funnel_total", "counter", "Guests reaching the funnel stage since start", "stage", analytics.Stages, func(stage string) []sample {
+		return []sample{{value: float64(funnel.Stage(stage))}}
+	})
+
+	// Sources checking many emails that are not on the guest list
+	enumeration := s.service.EnumerationStats()
+	fmt.Fprintf(w, "# HELP cocktailbot_enumeration_alarms_total Sources that checked too many unknown emails since start\n# TYPE cocktailbot_enumeration_alarms_total counter\ncocktailbot_enumeration_alarms_total %d\n", enumeration.Alarms)
+	writeMetric(w, "cocktailbot_enumeration_sources", "gauge", "Sources with recent unknown emails, and those refused after an alarm", "state", []string{"tracked", "blocked"}, func(state string) []sample {
+		if state == "blocked" {
+			return []sample{{value: float64(enumeration.Blocked)}}
+		}
+		return []sample{{value: float64(enumeration.Tracked)}}
+	})
+
+	// Panics recovered by the API and the chat bots
+	crashes := crashreport.Counts()
+	sources := []string{crashreport.SourceAPI}
+	for source := range crashes {
+		if source != crashreport.SourceAPI {
+			sources = append(sources, source)
+		}
+	}
+	sort.Strings(sources)
+	writeMetric(w, "cocktailbot_panics_total", "counter", "Panics recovered since start", "source", sources, func(source string) []sample {
+		return []sample{{value: float64(crashes[source])}}
+	})
+}
+
+// sample is one value of a metric for a limiter or client, optionally for a window
+type sample struct {
+	window string
+	value  float64
+}
+
+// writeMetric writes a metric with its help and type lines and the
+// samples of every limiter or client, named by the label
+func writeMetric(w io.Writer, name, kind, help, label string, keys []string, samples func(key string) []sample) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	for _, key := range keys {
+		for _, s := range samples(key) {
+			labels := fmt.Sprintf("%s=%q", label, key)
+			if s.window != "" {
+				labels += fmt.Sprintf(",window=%q", s.window)
+			}
+			fmt.Fprintf(w, "%s{%s} %s\n", name, labels, strconv.FormatFloat(s.value, 'f', -1, 64))
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/crashreport"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+)
+
+// middleware wraps an http.Handler with additional behaviour
+type middleware func(http.Handler) http.Handler
+
+// chain wraps a handler with middlewares. The first middleware listed
+// is the first to see a request.
+func chain(h http.Handler, middlewares ...middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// contextKey is the type of values stored in the request context by middlewares
+type contextKey string
+
+// tokenContextKey holds the authenticated API token
+const tokenContextKey contextKey = "token"
+
+// adminPathPrefix marks endpoints that require an admin token
+const adminPathPrefix = "/api/v1/admin/"
+
+// webhookPathPrefix marks endpoints called by third parties, which are
+// authenticated by a signature instead of an API token
+const webhookPathPrefix = "/api/v1/webhooks/"
+
+// ticketPathPrefix marks ticket downloads, which are authenticated by a
+// signed link instead of an API token
+const ticketPathPrefix = "/api/v1/tickets/"
+
+// tokenFromContext returns the API token authenticated for the request
+func tokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(tokenContextKey).(string)
+	return token
+}
+
+// tokenActor identifies the token of a request in audit logs and records
+func tokenActor(ctx context.Context) string {
+	return "token:" + TokenFingerprint(tokenFromContext(ctx))
+}
+
+// bearerToken extracts the token from the Authorization header
+func bearerToken(r *http.Request) string {
+	apiKey := r.Header.Get("Authorization")
+	if len(apiKey) > 7 && strings.HasPrefix(strings.ToLower(apiKey), "bearer ") {
+		apiKey = apiKey[7:] // Remove 'Bearer ' prefix
+	}
+	return apiKey
+}
+
+// isPublic returns true if the path is a webhook, a ticket download, the
+// counter stream when it is public, or listed in the public endpoints. An
+// entry ending in "*" matches every path starting with it.
+func (s *Server) isPublic(path string) bool {
+	if strings.HasPrefix(path, webhookPathPrefix) || strings.HasPrefix(path, ticketPathPrefix) {
+		return true
+	}
+	if path == counterStreamPath && s.config.API.Counter.Public {
+		return true
+	}
+	for _, endpoint := range s.config.API.PublicEndpoints {
+		if prefix, ok := strings.CutSuffix(endpoint, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// requestIDHeader carries the ID correlating the log lines of a request
+const requestIDHeader = "X-Request-ID"
+
+// requestIDMiddleware tags every request with an ID, taken from the
+// X-Request-ID header if a proxy set one, and stores a logger adding it
+// to every line in the request context
+func (s *Server) requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = GenerateUniqueID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		log := s.logger.With("request_id", requestID)
+		next.ServeHTTP(w, r.WithContext(logger.NewContext(r.Context(), log)))
+	})
+}
+
+// problemContentType is the media type of problem details, RFC 9457
+const problemContentType = "application/problem+json"
+
+// ProblemResponse represents a problem details response, sent when a
+// handler panics
+type ProblemResponse struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"` // Path of the request
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// recoveryWriter records whether the response was started, so a panic
+// after that is not answered twice
+type recoveryWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *recoveryWriter) WriteHeader(statusCode int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *recoveryWriter) Write(data []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush sends buffered data, for the counter stream
+func (w *recoveryWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.started = true
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController
+func (w *recoveryWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// recoveryMiddleware turns a panic in a handler into a 500 problem details
+// response instead of a dropped connection. The panic is logged with its
+// stack, counted and passed to the crash reporter. A panic after the
+// response started only ends it, as its status was already sent.
+func (s *Server) recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoveryWriter{ResponseWriter: w}
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+			if value == http.ErrAbortHandler {
+				panic(value) // Handlers abort responses on purpose with it
+			}
+
+			requestID := w.Header().Get(requestIDHeader)
+			crash := crashreport.New(crashreport.SourceAPI, value, debug.Stack())
+			crash.Method, crash.Path, crash.RequestID = r.Method, r.URL.Path, requestID
+			crashreport.Record(s.log(r), crash)
+			if rw.started {
+				return
+			}
+
+			w.Header().Set("Content-Type", problemContentType)
+			w.WriteHeader(http.StatusInternalServerError)
+			if err := json.NewEncoder(w).Encode(ProblemResponse{
+				Type:      "about:blank",
+				Title:     http.StatusText(http.StatusInternalServerError),
+				Status:    http.StatusInternalServerError,
+				Detail:    "The request failed unexpectedly, quote the request ID when reporting it",
+				Instance:  r.URL.Path,
+				RequestID: requestID,
+			}); err != nil {
+				s.logger.Error("Error encoding problem response", "error", err)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// validRequestID returns true if a client supplied request ID is safe to log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// log returns the logger of the request, which adds its request ID
+func (s *Server) log(r *http.Request) *logger.Logger {
+	return logger.FromContext(r.Context(), s.logger)
+}
+
+// serviceContext returns the context passed to the service for a request.
+// It carries the request logger but is not canceled when the client goes
+// away, so a redemption in progress is not interrupted.
+func serviceContext(r *http.Request) context.Context {
+	return context.WithoutCancel(r.Context())
+}
+
+// authMiddleware rejects requests without a valid token, and requests to
+// admin endpoints without an admin token. Public endpoints are skipped.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.isPublic(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		apiKey := bearerToken(r)
+		if !s.authProvider.Authenticate(apiKey) {
+			s.writeErrorResponse(w, r, "Unauthorized", http.StatusUnauthorized, "Invalid or missing authentication token")
+			return
+		}
+
+		// Require admin scope
+		if strings.HasPrefix(r.URL.Path, adminPathPrefix) && !s.authProvider.IsAdmin(apiKey) {
+			s.writeErrorResponse(w, r, "Forbidden", http.StatusForbidden, "Admin token required")
+			return
+		}
+
+		// Staff sharing a token name themselves for the audit trail
+		operator, err := s.requestOperator(r)
+		if errors.Is(err, errUnknownOperator) {
+			s.writeErrorResponse(w, r, "Forbidden", http.StatusForbidden, "Unknown operator")
+			return
+		}
+		if err != nil {
+			s.writeErrorResponse(w, r, "Invalid operator", http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), tokenContextKey, apiKey)
+		ctx = audit.NewContext(ctx, "token:"+TokenFingerprint(apiKey))
+		if operator != "" {
+			ctx = audit.WithOperator(ctx, operator)
+			ctx = logger.NewContext(ctx, s.log(r).With("operator", operator))
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// rateLimitMiddleware limits requests per client IP and token, and per token
+// across client IPs. Public endpoints are skipped.
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.isPublic(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		fingerprint := TokenFingerprint(tokenFromContext(r.Context()))
+		clientID := s.clientLimitKey(r, fingerprint)
+		tokenID := "token:" + fingerprint
+
+		// The token budget is only spent by requests within the client IP limit
+		limit := ""
+		var retryAfter time.Duration
+		if !s.limiter.AllowKey(clientID) {
+			limit, retryAfter = "ip", s.limiter.RetryAfterKey(clientID)
+		} else if !s.tokenLimiter.AllowKey(tokenID) {
+			limit, retryAfter = "token", s.tokenLimiter.RetryAfterKey(tokenID)
+		}
+
+		// Add rate limit headers
+		w.Header().Set("X-RateLimit-Limit-Minute", strconv.Itoa(s.config.API.RateLimitPerMin))
+		w.Header().Set("X-RateLimit-Remaining-Minute", strconv.Itoa(s.limiter.RemainingMinuteKey(clientID)))
+		w.Header().Set("X-RateLimit-Token-Limit-Minute", strconv.Itoa(s.config.API.TokenRateLimitPerMin))
+		w.Header().Set("X-RateLimit-Token-Remaining-Minute", strconv.Itoa(s.tokenLimiter.RemainingMinuteKey(tokenID)))
+
+		if limit != "" {
+			s.writeRateLimitResponse(w, r, limit, retryAfter)
+			return
+		}
+
+		// The service limits the same client rather than a numeric ID
+		next.ServeHTTP(w, r.WithContext(ratelimit.NewContext(r.Context(), clientID)))
+	})
+}
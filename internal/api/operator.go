@@ -0,0 +1,47 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// operatorHeader names the staff member using a token shared by several
+// people, such as the token of a bar's POS
+const operatorHeader = "X-Operator"
+
+// maxOperatorLength is the longest operator name accepted
+const maxOperatorLength = 64
+
+// Errors for operators that cannot be recorded
+var (
+	errInvalidOperator = errors.New("operator names must be at most 64 printable characters")
+	errUnknownOperator = errors.New("operator is not on the roster")
+)
+
+// requestOperator returns the operator named in the X-Operator header of a
+// request, "" if none. With a roster in api.operators only listed names
+// are accepted, matched without regard to case and recorded as listed.
+// The header is a label for the audit trail, not an identity: anyone
+// holding the token may send any name on the roster.
+func (s *Server) requestOperator(r *http.Request) (string, error) {
+	operator := strings.TrimSpace(r.Header.Get(operatorHeader))
+	if operator == "" {
+		return "", nil
+	}
+	if len(operator) > maxOperatorLength || strings.IndexFunc(operator, func(c rune) bool { return !unicode.IsPrint(c) }) >= 0 {
+		return "", errInvalidOperator
+	}
+
+	roster := s.config.API.Operators
+	if len(roster) == 0 {
+		return operator, nil
+	}
+	for _, name := range roster {
+		if strings.EqualFold(strings.TrimSpace(name), operator) {
+			return strings.TrimSpace(name), nil
+		}
+	}
+	return "", errUnknownOperator
+}
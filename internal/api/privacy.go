@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// revealEmails returns true if guest emails are shown in full in the
+// response. With privacy mode on, only admin tokens asking for reveal=true
+// see them, and every reveal is logged.
+func (s *Server) revealEmails(r *http.Request) bool {
+	if !s.config.Privacy.MaskEmails {
+		return true
+	}
+	if r.URL.Query().Get("reveal") != "true" {
+		return false
+	}
+	token := tokenFromContext(r.Context())
+	if s.authProvider == nil || !s.authProvider.IsAdmin(token) {
+		return false
+	}
+	s.log(r).Info("Guest emails revealed", "token", TokenFingerprint(token), "path", r.URL.Path)
+	return true
+}
+
+// maskUser returns a copy of the user with the email masked and the last
+// name shortened to its initial
+func maskUser(user *domain.User) *domain.User {
+	if user == nil {
+		return nil
+	}
+	masked := *user
+	masked.Email = utils.MaskEmail(user.Email)
+	masked.LastName = maskName(user.LastName)
+	return &masked
+}
+
+// maskName shortens a name to its initial, such as K.
+func maskName(name string) string {
+	for _, r := range name {
+		return string(r) + "."
+	}
+	return ""
+}
+
+// maskUsers returns copies of the users with their emails masked
+func maskUsers(users []*domain.User) []*domain.User {
+	masked := make([]*domain.User, len(users))
+	for i, user := range users {
+		masked[i] = maskUser(user)
+	}
+	return masked
+}
+
+// maskPurchases returns copies of the purchases with buyer emails masked
+func maskPurchases(purchases []domain.Purchase) []domain.Purchase {
+	masked := make([]domain.Purchase, len(purchases))
+	for i, purchase := range purchases {
+		purchase.Email = utils.MaskEmail(purchase.Email)
+		masked[i] = purchase
+	}
+	return masked
+}
+
+// maskAuditEntries returns copies of the entries with guest emails masked
+func maskAuditEntries(entries []audit.Entry) []audit.Entry {
+	masked := make([]audit.Entry, len(entries))
+	for i, entry := range entries {
+		entry.Email = utils.MaskEmail(entry.Email)
+		entry.User = maskUser(entry.User)
+		masked[i] = entry
+	}
+	return masked
+}
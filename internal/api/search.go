@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// Paging of the guest search endpoint
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchResponse represents the JSON response of a guest search
+type SearchResponse struct {
+	Query     string      `json:"query"`
+	Event     string      `json:"event,omitempty"` // Event the guests belong to
+	Total     int         `json:"total"`           // Matching guests across all pages
+	Offset    int         `json:"offset"`
+	Limit     int         `json:"limit"`
+	Count     int         `json:"count"` // Guests in this response
+	Results   []SearchHit `json:"results"`
+	Generated time.Time   `json:"generated"`
+}
+
+// SearchHit is a guest matching a search
+type SearchHit struct {
+	User   *User    `json:"user"`
+	Score  int      `json:"score"`  // Higher is a better match
+	Fields []string `json:"fields"` // Fields a word matched: email, name, notes or tags
+}
+
+// handleSearchUsers searches the guests of the current event by email,
+// name, notes and tags, best match first
+func (s *Server) handleSearchUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	limit := defaultSearchLimit
+	if param := query.Get("limit"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			s.writeErrorResponse(w, r, "Invalid limit", http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit))
+			return
+		}
+		limit = parsed
+	}
+	offset := 0
+	if param := query.Get("offset"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 0 {
+			s.writeErrorResponse(w, r, "Invalid offset", http.StatusBadRequest, "offset must be 0 or greater")
+			return
+		}
+		offset = parsed
+	}
+
+	result, err := s.service.SearchGuests(serviceContext(r), query.Get("q"), offset, limit)
+	switch {
+	case domain.IsValidationError(err):
+		s.writeErrorResponse(w, r, "Invalid query", http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, domain.ErrDatabaseUnavailable):
+		s.writeUnavailable(w, r)
+		return
+	case err != nil:
+		s.log(r).Error("Error searching guests", "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error searching guests")
+		return
+	}
+
+	reveal := s.revealEmails(r)
+	hits := make([]SearchHit, len(result.Hits))
+	for i, hit := range result.Hits {
+		user := hit.User
+		if !reveal {
+			user = maskUser(user)
+		}
+		hits[i] = SearchHit{User: NewUser(user), Score: hit.Score, Fields: hit.Fields}
+	}
+
+	s.writeJSONResponse(w, SearchResponse{
+		Query:     query.Get("q"),
+		Event:     s.config.Event.Name,
+		Total:     result.Total,
+		Offset:    offset,
+		Limit:     limit,
+		Count:     len(hits),
+		Results:   hits,
+		Generated: time.Now(),
+	}, http.StatusOK)
+}
@@ -2,19 +2,28 @@ package api
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
 	"github.com/ceesaxp/cocktail-bot/internal/config"
 	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/i18n"
+	"github.com/ceesaxp/cocktail-bot/internal/importer"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/period"
+	"github.com/ceesaxp/cocktail-bot/internal/ports"
+	"github.com/ceesaxp/cocktail-bot/internal/proxyproto"
 	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
 	"github.com/ceesaxp/cocktail-bot/internal/utils"
 )
@@ -23,28 +32,30 @@ import (
 type Server struct {
 	config       *config.Config
 	logger       *logger.Logger
-	service      ServiceInterface
+	service      ports.Service
 	httpServer   *http.Server
-	limiter      *ratelimit.Limiter
+	limiter      *ratelimit.Limiter // Per client IP and token
+	tokenLimiter *ratelimit.Limiter // Per token across client IPs
 	authProvider *AuthProvider
+	readiness    *readiness
+	calendar     *period.Calendar // Default report ranges and periods
+	counter      *counterHub      // Redemption count streamed to venue screens
+	translator   *i18n.Translator // Error details in the language of the client
 	running      bool
 }
 
-// ServiceInterface defines the required methods from the service layer
-type ServiceInterface interface {
-	CheckEmailStatus(ctx any, userID int64, email string) (string, *domain.User, error)
-	RedeemCocktail(ctx any, userID int64, email string) (time.Time, error)
-	UpdateUser(ctx any, user *domain.User) error
-	AddUser(ctx any, user *domain.User) error
-	GenerateReport(ctx any, reportType string, fromDate, toDate time.Time) ([]*domain.User, error)
-	Close() error
-}
-
 // EmailRequest represents the JSON payload for email submission
 type EmailRequest struct {
 	Email string `json:"email"`
 }
 
+// RedeemRequest represents the JSON payload for redemptions, naming the
+// guest by email or by voucher code
+type RedeemRequest struct {
+	Email   string `json:"email,omitempty"`
+	Voucher string `json:"voucher,omitempty"`
+}
+
 // EmailResponse represents the JSON response for email submission
 type EmailResponse struct {
 	ID      string `json:"id,omitempty"`
@@ -52,6 +63,36 @@ type EmailResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
+// EmailStatusResponse represents the JSON response for email status lookups
+type EmailStatusResponse struct {
+	Email    string     `json:"email"`
+	Status   string     `json:"status"` // eligible, redeemed, not_found, denied or archived
+	Redeemed *time.Time `json:"redeemed,omitempty"`
+}
+
+// RedeemResponse represents the JSON response for redemptions
+type RedeemResponse struct {
+	Email     string    `json:"email"`
+	Status    string    `json:"status"`
+	Redeemed  time.Time `json:"redeemed"`
+	TicketURL string    `json:"ticket_url,omitempty"` // Signed link to a printable ticket
+}
+
+// BatchRedeemRequest represents the JSON payload for redemptions collected offline
+type BatchRedeemRequest struct {
+	Redemptions []domain.OfflineRedemption `json:"redemptions"`
+}
+
+// BatchRedeemResponse represents the JSON response for offline redemptions
+type BatchRedeemResponse struct {
+	Total     int                              `json:"total"`
+	Redeemed  int                              `json:"redeemed"`
+	Duplicate int                              `json:"duplicate"` // Entries applied by an earlier batch
+	Conflict  int                              `json:"conflict"`  // Emails redeemed earlier at another time
+	Failed    int                              `json:"failed"`
+	Results   []domain.OfflineRedemptionResult `json:"results"`
+}
+
 // BulkUploadRequest represents the JSON payload for bulk email upload
 type BulkUploadRequest struct {
 	Emails []string `json:"emails,omitempty"`
@@ -71,20 +112,95 @@ type ErrorResponse struct {
 	Error   string `json:"error"`
 	Code    int    `json:"code"`
 	Details string `json:"details,omitempty"`
+	Limit   string `json:"limit,omitempty"` // Rate limit that was exceeded: ip or token
 }
 
 // ReportResponse represents the JSON response for report requests
 type ReportResponse struct {
-	Type      string         `json:"type"`
-	From      string         `json:"from"`
-	To        string         `json:"to"`
-	Count     int            `json:"count"`
-	Users     []*domain.User `json:"users,omitempty"`
-	Generated time.Time      `json:"generated"`
+	Type      string            `json:"type"`
+	Archived  bool              `json:"archived"` // Whether the report covers an archived event
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Total     int               `json:"total"`            // Matching users across all pages
+	Offset    int               `json:"offset,omitempty"` // Users skipped before this page
+	Limit     int               `json:"limit,omitempty"`  // Page size, 0 if all users are returned
+	Count     int               `json:"count"`            // Users in this response
+	Users     []*User           `json:"users,omitempty"`
+	Compare   *ReportComparison `json:"compare,omitempty"` // Total of the compared range, when requested
+	Generated time.Time         `json:"generated"`
+}
+
+// ReportComparison is the total of a report over the range it is compared with
+type ReportComparison struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Total  int    `json:"total"`
+	Change int    `json:"change"` // Total of the report minus this total
+}
+
+// UserStateResponse represents the JSON response for user lookups
+type UserStateResponse struct {
+	AsOf   *time.Time `json:"as_of,omitempty"`
+	Source string     `json:"source"` // current, audit or derived
+	User   *User      `json:"user"`
+}
+
+// ChangesResponse represents the JSON response for the changes report
+type ChangesResponse struct {
+	Since     string    `json:"since"`
+	Cursor    string    `json:"cursor"` // Pass as since to fetch the next changes
+	Count     int       `json:"count"`
+	Users     []*User   `json:"users,omitempty"`
+	Generated time.Time `json:"generated"`
+}
+
+// RateLimitResetRequest represents the JSON payload for resetting rate limits
+type RateLimitResetRequest struct {
+	UserID   int64  `json:"user_id,omitempty"`   // Telegram user ID (bot limiter)
+	ClientIP string `json:"client_ip,omitempty"` // API client IP (API limiter)
+	Token    string `json:"token,omitempty"`     // API token fingerprint (API token limiter)
+}
+
+// RateLimitResetResponse represents the JSON response for rate limit resets
+type RateLimitResetResponse struct {
+	Status   string `json:"status"`
+	UserID   int64  `json:"user_id,omitempty"`
+	ClientIP string `json:"client_ip,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+// DatabaseStatusResponse represents the JSON response for the database diagnostics endpoint
+type DatabaseStatusResponse struct {
+	Status  string            `json:"status"`
+	Error   string            `json:"error,omitempty"`
+	Stats   *domain.RepoStats `json:"stats,omitempty"`
+	Checked time.Time         `json:"checked"`
+}
+
+// EventStatusResponse represents the JSON response for the event status endpoint
+type EventStatusResponse struct {
+	Event    string                `json:"event"`
+	Archived *domain.EventArchive  `json:"archived,omitempty"` // Set once the current event is archived
+	Archives []domain.EventArchive `json:"archives"`
+}
+
+// AuditResponse represents the JSON response for the audit log endpoint
+type AuditResponse struct {
+	Total     int          `json:"total"` // Matching entries across all pages
+	Offset    int          `json:"offset"`
+	Limit     int          `json:"limit"`
+	Count     int          `json:"count"`
+	Entries   []AuditEntry `json:"entries"`
+	Generated time.Time    `json:"generated"`
+}
+
+// ArchiveEventRequest represents the JSON payload for archiving the event
+type ArchiveEventRequest struct {
+	Export bool `json:"export"` // Write a final report bundle
 }
 
 // New creates a new API server
-func New(cfg *config.Config, svc ServiceInterface, log *logger.Logger) (*Server, error) {
+func New(cfg *config.Config, svc ports.Service, log *logger.Logger) (*Server, error) {
 	// Load authentication tokens if configured in tokens file
 	if err := cfg.LoadAuthTokens(); err != nil {
 		log.Warn("Error loading auth tokens from file", "error", err)
@@ -96,12 +212,31 @@ func New(cfg *config.Config, svc ServiceInterface, log *logger.Logger) (*Server,
 	} else {
 		log.Info("API tokens configured", "count", len(cfg.API.AuthTokens))
 	}
+	if err := CheckTokens(cfg.API.AuthTokens, cfg.API.AdminTokens); err != nil {
+		log.Warn("Ignoring API token", "error", err)
+	}
+
+	// Forwarding headers name the client only behind trusted proxies
+	if err := SetTrustedProxies(cfg.API.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+	}
+
+	// Report dates are in the timezone of the event
+	calendar, err := period.New(cfg.Reports, cfg.Event.StartDate)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create a dedicated rate limiter for API requests
-	limiter := ratelimit.New(cfg.API.RateLimitPerMin, cfg.API.RateLimitPerHour)
+	// Error details are translated for clients asking for a language
+	translator := i18n.NewWithConfig(cfg)
+	i18n.LoadDefaultTranslations(translator)
+
+	// Create dedicated rate limiters for API requests
+	limiter := ratelimit.NewWithCleanup(cfg.API.RateLimitPerMin, cfg.API.RateLimitPerHour, cfg.RateLimiting.CleanupInterval.Duration())
+	tokenLimiter := ratelimit.NewWithCleanup(cfg.API.TokenRateLimitPerMin, cfg.API.TokenRateLimitPerHour, cfg.RateLimiting.CleanupInterval.Duration())
 
 	// Create auth provider with tokens from config
-	authProvider := NewAuthProvider(cfg.API.AuthTokens)
+	authProvider := NewAuthProviderWithAdmins(cfg.API.AuthTokens, cfg.API.AdminTokens)
 
 	mux := http.NewServeMux()
 
@@ -114,20 +249,73 @@ func New(cfg *config.Config, svc ServiceInterface, log *logger.Logger) (*Server,
 		logger:       log,
 		service:      svc,
 		limiter:      limiter,
+		tokenLimiter: tokenLimiter,
 		authProvider: authProvider,
+		readiness:    newReadiness(),
+		calendar:     calendar,
+		translator:   translator,
 		httpServer: &http.Server{
-			Addr:    bindAddr,
-			Handler: mux,
+			Addr:        bindAddr,
+			ConnContext: proxyproto.ConnContext,
 		},
 	}
 
+	// Counter streams end when the server shuts down, which would wait
+	// for them otherwise
+	server.counter = newCounterHub(server)
+	server.httpServer.RegisterOnShutdown(server.counter.close)
+
+	// Authentication and rate limiting apply to every endpoint not listed as public
+	server.httpServer.Handler = chain(mux, server.requestIDMiddleware, server.recoveryMiddleware, server.authMiddleware, server.rateLimitMiddleware)
+
 	// Register routes
+	mux.HandleFunc("/api/v1/auth/introspect", server.handleIntrospect)
 	mux.HandleFunc("/api/v1/email", server.handleEmail)
 	mux.HandleFunc("/api/v1/email/bulk", server.handleBulkUpload)
+	mux.HandleFunc("/api/v1/email/status", server.handleEmailStatus)
+	mux.HandleFunc("/api/v1/email/redeem", server.handleRedeem)
+	mux.HandleFunc("/api/v1/redeem/batch", server.handleRedeemBatch)
+	mux.HandleFunc("/api/v1/import", server.handleImport)
+	mux.HandleFunc("/api/v1/import/csv", server.handleImportCSV)
+	mux.HandleFunc("/api/v1/jobs", server.handleJobs)
+	mux.HandleFunc(jobsPathPrefix, server.handleJob)
+	mux.HandleFunc("/api/v1/users/search", server.handleSearchUsers)
+	mux.HandleFunc(usersPathPrefix, server.handleUserState)
 	mux.HandleFunc("/api/v1/report/redeemed", server.handleReportRedeemed)
 	mux.HandleFunc("/api/v1/report/added", server.handleReportAdded)
 	mux.HandleFunc("/api/v1/report/all", server.handleReportAll)
+	mux.HandleFunc("/api/v1/report/consented", server.handleReportConsented)
+	mux.HandleFunc("/api/v1/report/purchases", server.handleReportPurchases)
+	mux.HandleFunc("/api/v1/report/changes", server.handleReportChanges)
+	mux.HandleFunc("/api/v1/report/bars", server.handleReportBars)
+	mux.HandleFunc("/api/v1/report/dataset", server.handleReportDataset)
+	mux.HandleFunc("/api/v1/webhooks/stripe", server.handleStripeWebhook)
+	mux.HandleFunc(ticketPathPrefix, server.handleTicket)
+	mux.HandleFunc("/api/v1/stats/engagement", server.handleEngagementStats)
+	mux.HandleFunc("/api/v1/stats/funnel", server.handleFunnelStats)
+	mux.HandleFunc("/api/v1/stats/latency", server.handleRedemptionLatency)
+	mux.HandleFunc(counterStreamPath, server.handleCounterStream)
+	mux.HandleFunc("/api/v1/admin/ratelimit", server.handleRateLimitStats)
+	mux.HandleFunc("/api/v1/admin/ratelimit/reset", server.handleRateLimitReset)
+	mux.HandleFunc("/api/v1/admin/db", server.handleDatabaseStatus)
+	mux.HandleFunc("/api/v1/admin/status", server.handleRuntimeStatus)
+	mux.HandleFunc("/api/v1/admin/event", server.handleEventStatus)
+	mux.HandleFunc("/api/v1/admin/event/archive", server.handleArchiveEvent)
+	mux.HandleFunc("/api/v1/admin/audit", server.handleAuditLog)
+	mux.HandleFunc("/api/v1/admin/users/merge", server.handleMergeUsers)
+	mux.HandleFunc(migrationsPathPrefix, server.handleMigration)
+	mux.HandleFunc(featuresPathPrefix, server.handleFeatureToggle)
+	mux.HandleFunc("/api/v1/features", server.handleFeatures)
 	mux.HandleFunc("/api/health", server.handleHealth)
+	mux.HandleFunc("/healthz", server.handleLiveness)
+	mux.HandleFunc("/readyz", server.handleReadiness)
+	mux.HandleFunc("/metrics", server.handleMetrics)
+
+	// The repository was connected and migrated when the service was
+	// created, it only has to stay reachable
+	server.AddReadinessCheck("repository", func(ctx context.Context) error {
+		return svc.DatabaseHealth(ctx)
+	})
 
 	return server, nil
 }
@@ -147,9 +335,20 @@ func (s *Server) Start() error {
 	s.running = true
 	s.logger.Info("Starting API server", "port", s.config.API.Port)
 
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		s.running = false
+		return fmt.Errorf("failed to listen on %s: %w", s.httpServer.Addr, err)
+	}
+	// Behind HAProxy and similar balancers, clients are read from the PROXY protocol
+	if s.config.API.ProxyProtocol {
+		listener = proxyproto.NewListener(listener)
+		s.logger.Info("API server expects the PROXY protocol")
+	}
+
 	// Start server in a separate goroutine
 	go func() {
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
 			s.logger.Error("API server error", "error", err)
 		}
 	}()
@@ -165,7 +364,7 @@ func (s *Server) Stop() error {
 
 	s.logger.Info("Stopping API server")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeouts.Shutdown.Duration())
 	defer cancel()
 
 	if err := s.httpServer.Shutdown(ctx); err != nil {
@@ -175,6 +374,7 @@ func (s *Server) Stop() error {
 	s.running = false
 	// Close rate limiter resources
 	s.limiter.Close()
+	s.tokenLimiter.Close()
 
 	return nil
 }
@@ -183,269 +383,1444 @@ func (s *Server) Stop() error {
 func (s *Server) handleEmail(w http.ResponseWriter, r *http.Request) {
 	// Only allow POST method
 	if r.Method != http.MethodPost {
-		s.writeErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is allowed")
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is allowed")
 		return
 	}
 
 	// Validate Content-Type
 	contentType := r.Header.Get("Content-Type")
 	if !strings.HasPrefix(contentType, "application/json") {
-		s.writeErrorResponse(w, "Invalid Content-Type", http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		s.writeErrorResponse(w, r, "Invalid Content-Type", http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+
+	// Decode request
+	var req EmailRequest
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	// Validate email
+	if !utils.IsValidEmail(req.Email) {
+		s.writeErrorResponse(w, r, "Invalid email", http.StatusBadRequest, "The provided email address is not valid")
+		return
+	}
+
+	// Normalize email
+	email := utils.NormalizeEmail(req.Email)
+
+	// Check if email already exists
+	ctx := serviceContext(r)
+	clientID := HashCode(ClientIP(r))
+	status, user, err := s.service.CheckEmailStatus(ctx, clientID, email)
+	if err != nil {
+		s.log(r).Error("Error checking email status", "email", email, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error processing request")
+		return
+	}
+
+	// Handle based on status
+	switch status {
+	case "eligible", "redeemed":
+		// Email already exists, return conflict status
+		response := EmailResponse{
+			Status:  "exists",
+			Message: "Email already exists in database",
+		}
+		if user != nil {
+			response.ID = user.ID
+		}
+		s.writeJSONResponse(w, response, http.StatusConflict)
+		return
+
+	case "rate_limited":
+		s.writeServiceRateLimited(w, r)
+		return
+
+	case "unavailable":
+		s.writeUnavailable(w, r)
+		return
+
+	case "denied":
+		s.writeErrorResponse(w, r, "Forbidden", http.StatusForbidden, "Email address is not allowed")
+		return
+
+	case "archived":
+		s.writeErrorResponse(w, r, "Conflict", http.StatusConflict, "Event is archived")
+		return
+
+	case "not_found":
+		// Continue with adding the email
+		break
+	}
+
+	// Generate a new user with a unique ID
+	newUser := &domain.User{
+		ID:        GenerateUniqueID(),
+		Email:     email,
+		DateAdded: time.Now(),
+		Redeemed:  nil,
+		CreatedBy: tokenActor(r.Context()),
+	}
+
+	// Store in database using service's AddUser method for new users
+	if err := s.service.AddUser(ctx, newUser); err != nil {
+		if errors.Is(err, domain.ErrEventArchived) {
+			s.writeErrorResponse(w, r, "Conflict", http.StatusConflict, "Event is archived")
+			return
+		}
+		if domain.IsDuplicateUser(err) {
+			// A concurrent request added the email after the status check
+			s.writeJSONResponse(w, EmailResponse{
+				ID:      s.existingUserID(ctx, clientID, email, err),
+				Status:  "exists",
+				Message: "Email already exists in database",
+			}, http.StatusConflict)
+			return
+		}
+		s.log(r).Error("Error adding email to database", "email", email, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error storing email")
+		return
+	}
+
+	// Log successful addition
+	s.log(r).Info("Email added via API", "email", email, "id", newUser.ID)
+
+	// Return success
+	response := EmailResponse{
+		ID:      newUser.ID,
+		Status:  "created",
+		Message: "Email added successfully",
+	}
+	s.writeJSONResponse(w, response, http.StatusCreated)
+}
+
+// existingUserID returns the ID of the user that won a race to add an
+// email, read from the duplicate error or looked up if the error lacks it
+func (s *Server) existingUserID(ctx context.Context, clientID int64, email string, err error) string {
+	var dup *domain.DuplicateUserError
+	if errors.As(err, &dup) && dup.ID != "" {
+		return dup.ID
+	}
+	if _, user, err := s.service.CheckEmailStatus(ctx, clientID, email); err == nil && user != nil {
+		return user.ID
+	}
+	return ""
+}
+
+// handleEmailStatus reports whether an email can redeem a cocktail
+func (s *Server) handleEmailStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	email, ok := s.guestEmail(w, r, r.URL.Query().Get("email"), r.URL.Query().Get("voucher"))
+	if !ok {
+		return
+	}
+
+	status, user, err := s.service.CheckEmailStatus(serviceContext(r), HashCode(ClientIP(r)), email)
+	if err != nil {
+		s.log(r).Error("Error checking email status", "email", email, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error processing request")
+		return
+	}
+
+	switch status {
+	case "rate_limited":
+		s.writeServiceRateLimited(w, r)
+		return
+	case "unavailable":
+		s.writeUnavailable(w, r)
+		return
+	}
+
+	response := EmailStatusResponse{Email: email, Status: status}
+	if status == "redeemed" && user != nil {
+		response.Redeemed = user.Redeemed
+	}
+	s.writeJSONResponse(w, response, http.StatusOK)
+}
+
+// guestEmail returns the normalized email a guest lookup is about, read
+// from the voucher code if one is given. Typed emails are refused when
+// vouchers are required. It writes the error response and returns false
+// if the request names no valid guest.
+func (s *Server) guestEmail(w http.ResponseWriter, r *http.Request, email, voucher string) (string, bool) {
+	if voucher != "" {
+		email, err := s.service.VoucherEmail(voucher)
+		if errors.Is(err, domain.ErrVouchersDisabled) {
+			s.writeErrorResponse(w, r, "Invalid voucher", http.StatusBadRequest, "Voucher codes are not enabled")
+			return "", false
+		}
+		if err != nil {
+			s.writeErrorResponse(w, r, "Invalid voucher", http.StatusBadRequest, "The provided voucher code is not valid")
+			return "", false
+		}
+		return email, true
+	}
+
+	if !s.service.FreeFormEmailAllowed() {
+		s.writeErrorResponse(w, r, "Forbidden", http.StatusForbidden, "Email lookups are disabled, use a voucher code")
+		return "", false
+	}
+	if !utils.IsValidEmail(email) {
+		s.writeErrorResponse(w, r, "Invalid email", http.StatusBadRequest, "The provided email address is not valid")
+		return "", false
+	}
+	return utils.NormalizeEmail(email), true
+}
+
+// handleRedeem redeems the cocktail of an eligible email
+func (s *Server) handleRedeem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	var req RedeemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	email, ok := s.guestEmail(w, r, req.Email, req.Voucher)
+	if !ok {
+		return
+	}
+
+	ctx := serviceContext(r)
+	clientID := HashCode(ClientIP(r))
+
+	// The service returns the earlier date for redeemed emails, so check first
+	status, _, err := s.service.CheckEmailStatus(ctx, clientID, email)
+	if err != nil {
+		s.log(r).Error("Error checking email status", "email", email, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error processing request")
+		return
+	}
+	switch status {
+	case "rate_limited":
+		s.writeServiceRateLimited(w, r)
+		return
+	case "unavailable":
+		s.writeUnavailable(w, r)
+		return
+	case "not_found":
+		s.writeErrorResponse(w, r, "Not Found", http.StatusNotFound, "Email is not in the database")
+		return
+	case "denied":
+		s.writeErrorResponse(w, r, "Forbidden", http.StatusForbidden, "Email address is not allowed")
+		return
+	case "redeemed":
+		s.writeErrorResponse(w, r, "Conflict", http.StatusConflict, "Cocktail already redeemed")
+		return
+	case "archived":
+		s.writeErrorResponse(w, r, "Conflict", http.StatusConflict, "Event is archived")
+		return
+	}
+
+	redeemed, err := s.service.RedeemCocktail(ctx, clientID, email)
+	switch {
+	case err == nil && redeemed.IsZero():
+		s.writeServiceRateLimited(w, r)
+		return
+	case errors.Is(err, domain.ErrDatabaseUnavailable):
+		s.writeUnavailable(w, r)
+		return
+	case errors.Is(err, domain.ErrEmailNotVerified):
+		s.writeErrorResponse(w, r, "Forbidden", http.StatusForbidden, "Email address is not verified")
+		return
+	case errors.Is(err, domain.ErrAlreadyRedeemed):
+		s.writeErrorResponse(w, r, "Conflict", http.StatusConflict, "Cocktail already redeemed")
+		return
+	case errors.Is(err, domain.ErrEventArchived):
+		s.writeErrorResponse(w, r, "Conflict", http.StatusConflict, "Event is archived")
+		return
+	case err != nil:
+		s.log(r).Error("Error redeeming cocktail", "email", email, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error redeeming cocktail")
+		return
+	}
+
+	s.log(r).Info("Cocktail redeemed via API", "email", email, "actor", "token:"+TokenFingerprint(tokenFromContext(r.Context())), "remote", ClientIP(r))
+	s.writeJSONResponse(w, RedeemResponse{
+		Email:     email,
+		Status:    "redeemed",
+		Redeemed:  redeemed,
+		TicketURL: s.service.TicketURL(email, redeemed),
+	}, http.StatusOK)
+}
+
+// maxBatchRedemptions is the number of offline redemptions accepted per request
+const maxBatchRedemptions = 1000
+
+// handleRedeemBatch applies redemptions collected offline, reporting the
+// outcome of each entry
+func (s *Server) handleRedeemBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	var req BatchRedeemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if len(req.Redemptions) == 0 {
+		s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "No redemptions found in payload")
+		return
+	}
+	if len(req.Redemptions) > maxBatchRedemptions {
+		s.writeErrorResponse(w, r, "Request too large", http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Maximum %d redemptions allowed per request", maxBatchRedemptions))
+		return
+	}
+
+	results := s.service.ApplyOfflineRedemptions(serviceContext(r), req.Redemptions)
+	response := BatchRedeemResponse{Total: len(results), Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case "redeemed":
+			response.Redeemed++
+		case "duplicate":
+			response.Duplicate++
+		case "conflict":
+			response.Conflict++
+		default:
+			response.Failed++
+		}
+	}
+
+	s.log(r).Info("Offline redemptions applied via API", "total", response.Total, "redeemed", response.Redeemed,
+		"conflict", response.Conflict, "failed", response.Failed, "actor", "token:"+TokenFingerprint(tokenFromContext(r.Context())))
+	s.writeJSONResponse(w, response, http.StatusOK)
+}
+
+// usersPathPrefix is followed by the ID or email of a guest
+const usersPathPrefix = "/api/v1/users/"
+
+// handleUserState returns the record of a guest, as it is now or as it was
+// at the time given by as_of. PATCH changes the record, see handleUserPatch.
+func (s *Server) handleUserState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPatch {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET and PATCH methods are allowed")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, usersPathPrefix)
+	if id == "" || strings.Contains(id, "/") {
+		s.writeErrorResponse(w, r, "Not Found", http.StatusNotFound, "Use /api/v1/users/{id}")
+		return
+	}
+	if r.Method == http.MethodPatch {
+		s.handleUserPatch(w, r, id)
+		return
+	}
+
+	var response UserStateResponse
+	var asOf time.Time
+	if param := r.URL.Query().Get("as_of"); param != "" {
+		var err error
+		asOf, err = time.Parse(time.RFC3339Nano, param)
+		if err != nil {
+			s.writeErrorResponse(w, r, "Invalid date format", http.StatusBadRequest, "as_of must be an RFC 3339 timestamp")
+			return
+		}
+		response.AsOf = &asOf
+	}
+
+	user, source, err := s.service.UserAsOf(serviceContext(r), id, asOf)
+	switch {
+	case errors.Is(err, domain.ErrUserNotFound):
+		s.writeErrorResponse(w, r, "Not Found", http.StatusNotFound, "No such guest at that time")
+		return
+	case errors.Is(err, domain.ErrDatabaseUnavailable):
+		s.writeUnavailable(w, r)
+		return
+	case err != nil:
+		s.log(r).Error("Error looking up user state", "id", id, "as_of", asOf, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error looking up guest")
+		return
+	}
+
+	if !s.revealEmails(r) {
+		user = maskUser(user)
+	}
+	response.Source, response.User = source, NewUser(user)
+	s.writeJSONResponse(w, response, http.StatusOK)
+}
+
+// handleUserPatch applies a partial update to a guest, such as a corrected
+// email, notes, tags or an undone redemption. Only admin tokens may change
+// guests. Unknown fields are rejected, so a misspelled field is not taken
+// for an empty patch.
+func (s *Server) handleUserPatch(w http.ResponseWriter, r *http.Request, id string) {
+	if s.authProvider == nil || !s.authProvider.IsAdmin(tokenFromContext(r.Context())) {
+		s.writeErrorResponse(w, r, "Forbidden", http.StatusForbidden, "Admin token required")
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		s.writeErrorResponse(w, r, "Invalid Content-Type", http.StatusUnsupportedMediaType, "Content-Type must be application/json")
 		return
 	}
 
-	// Authenticate request
-	apiKey := r.Header.Get("Authorization")
-	if len(apiKey) > 7 && strings.HasPrefix(strings.ToLower(apiKey), "bearer ") {
-		apiKey = apiKey[7:] // Remove 'Bearer ' prefix
+	var req UserPatchRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "Invalid JSON payload: "+err.Error())
+		return
 	}
 
-	if !s.authProvider.Authenticate(apiKey) {
-		s.writeErrorResponse(w, "Unauthorized", http.StatusUnauthorized, "Invalid or missing authentication token")
+	user, err := s.service.PatchUser(serviceContext(r), id, req.Domain())
+	switch {
+	case domain.IsValidationError(err):
+		s.writeErrorResponse(w, r, "Invalid field", http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, domain.ErrUserNotFound):
+		s.writeErrorResponse(w, r, "Not Found", http.StatusNotFound, "No such guest")
+		return
+	case domain.IsDuplicateUser(err):
+		s.writeErrorResponse(w, r, "Conflict", http.StatusConflict, "Another guest has this email")
+		return
+	case errors.Is(err, domain.ErrEventArchived):
+		s.writeErrorResponse(w, r, "Conflict", http.StatusConflict, "Event is archived")
+		return
+	case errors.Is(err, domain.ErrEmailDenied):
+		s.writeErrorResponse(w, r, "Forbidden", http.StatusForbidden, "Email address is not allowed")
+		return
+	case errors.Is(err, domain.ErrEmailChangeUnsupported):
+		s.writeErrorResponse(w, r, "Not Implemented", http.StatusNotImplemented, err.Error())
+		return
+	case errors.Is(err, domain.ErrDatabaseUnavailable):
+		s.writeUnavailable(w, r)
+		return
+	case err != nil:
+		s.log(r).Error("Error updating user", "id", id, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error updating guest")
 		return
 	}
 
-	// Apply rate limiting
-	clientIP := getClientIP(r)
-	clientID := int64(HashCode(clientIP)) // Convert IP to a numeric ID for rate limiter
+	if !s.revealEmails(r) {
+		user = maskUser(user)
+	}
+	s.writeJSONResponse(w, NewUser(user), http.StatusOK)
+}
 
-	if !s.limiter.Allow(clientID) {
-		s.writeErrorResponse(w, "Too Many Requests", http.StatusTooManyRequests, "Rate limit exceeded")
+// handleMergeUsers handles the admin endpoint merging the duplicate record
+// of a guest into the record that is kept
+func (s *Server) handleMergeUsers(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != http.MethodPost {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		s.writeErrorResponse(w, r, "Invalid Content-Type", http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+
+	var req MergeUsersRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "Invalid JSON payload: "+err.Error())
+		return
+	}
+	if req.Keep == "" || req.Duplicate == "" {
+		s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "keep and duplicate are required")
+		return
+	}
+
+	keep, duplicate, err := s.service.MergeUsers(serviceContext(r), req.Keep, req.Duplicate, req.Reason)
+	switch {
+	case domain.IsValidationError(err):
+		s.writeErrorResponse(w, r, "Invalid field", http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, domain.ErrUserNotFound):
+		s.writeErrorResponse(w, r, "Not Found", http.StatusNotFound, "No such guest")
+		return
+	case errors.Is(err, domain.ErrEventArchived):
+		s.writeErrorResponse(w, r, "Conflict", http.StatusConflict, "Event is archived")
+		return
+	case errors.Is(err, domain.ErrDatabaseUnavailable):
+		s.writeUnavailable(w, r)
+		return
+	case err != nil:
+		s.log(r).Error("Error merging users", "keep", req.Keep, "duplicate", req.Duplicate, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error merging guests")
+		return
+	}
+
+	if !s.revealEmails(r) {
+		keep, duplicate = maskUser(keep), maskUser(duplicate)
+	}
+	s.writeJSONResponse(w, MergeUsersResponse{User: NewUser(keep), Duplicate: NewUser(duplicate)}, http.StatusOK)
+}
+
+// handleReportRedeemed handles the redeemed report endpoint
+func (s *Server) handleReportRedeemed(w http.ResponseWriter, r *http.Request) {
+	s.handleReport(w, r, "redeemed")
+}
+
+// handleReportAdded handles the added report endpoint
+func (s *Server) handleReportAdded(w http.ResponseWriter, r *http.Request) {
+	s.handleReport(w, r, "added")
+}
+
+// handleReportAll handles the all report endpoint
+func (s *Server) handleReportAll(w http.ResponseWriter, r *http.Request) {
+	s.handleReport(w, r, "all")
+}
+
+// handleReportConsented handles the marketing consent report endpoint
+func (s *Server) handleReportConsented(w http.ResponseWriter, r *http.Request) {
+	s.handleReport(w, r, "consented")
+}
+
+// handleReport is a generic handler for all report types
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request, reportType string) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	// Parse date range parameters
+	fromDate, toDate, err := s.parseDateParams(r)
+	if err != nil {
+		s.writeErrorResponse(w, r, "Invalid date format", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter, err := reportFilter(r)
+	if err != nil {
+		s.writeErrorResponse(w, r, "Invalid filter", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Set content type
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json" // Default format is JSON
+	}
+
+	// Reports cover the active event unless archived=true asks for an archived one
+	archived := r.URL.Query().Get("archived") == "true"
+	ctx := serviceContext(r)
+
+	compareFrom, compareTo, compare, err := s.parseCompareParams(r, fromDate, toDate)
+	if err != nil {
+		s.writeErrorResponse(w, r, "Invalid comparison", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// count_only=true returns the total without users, counted by the
+	// database where it can
+	if r.URL.Query().Get("count_only") == "true" {
+		total := 0
+		if archived == (s.service.EventArchived() != nil) {
+			total, err = s.service.CountReport(ctx, reportType, fromDate, toDate, filter)
+			if err != nil {
+				s.log(r).Error("Error counting report", "type", reportType, "error", err)
+				s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error generating report")
+				return
+			}
+		}
+		response := ReportResponse{
+			Type:      reportType,
+			Archived:  archived,
+			From:      fromDate.Format(time.RFC3339),
+			To:        toDate.Format(time.RFC3339),
+			Total:     total,
+			Generated: time.Now(),
+		}
+		if compare {
+			if response.Compare, err = s.compareReport(r, reportType, compareFrom, compareTo, filter, archived, total); err != nil {
+				s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error generating report")
+				return
+			}
+		}
+		s.writeJSONResponse(w, response, http.StatusOK)
+		return
+	}
+
+	// Generate report
+	var users []*domain.User
+	if archived == (s.service.EventArchived() != nil) {
+		users, err = s.service.GenerateReport(ctx, reportType, fromDate, toDate, filter)
+		if err != nil {
+			s.log(r).Error("Error generating report", "type", reportType, "error", err)
+			s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error generating report")
+			return
+		}
+	}
+
+	if !s.revealEmails(r) {
+		users = maskUsers(users)
+	}
+
+	// Format-specific response
+	if format == "csv" {
+		s.writeCSVReport(w, users, reportType)
+	} else {
+		// JSON reports are paged when a limit is given, CSV exports never are
+		offset, limit, err := reportPage(r)
+		if err != nil {
+			s.writeErrorResponse(w, r, "Invalid paging", http.StatusBadRequest, err.Error())
+			return
+		}
+		total := len(users)
+		users = users[min(offset, total):]
+		if limit > 0 && len(users) > limit {
+			users = users[:limit]
+		}
+
+		// Prepare JSON response
+		response := ReportResponse{
+			Type:      reportType,
+			Archived:  archived,
+			From:      fromDate.Format(time.RFC3339),
+			To:        toDate.Format(time.RFC3339),
+			Total:     total,
+			Offset:    offset,
+			Limit:     limit,
+			Count:     len(users),
+			Users:     NewUsers(users),
+			Generated: time.Now(),
+		}
+		if compare {
+			if response.Compare, err = s.compareReport(r, reportType, compareFrom, compareTo, filter, archived, total); err != nil {
+				s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error generating report")
+				return
+			}
+		}
+		s.writeJSONResponse(w, response, http.StatusOK)
+	}
+}
+
+// compareReport counts a report over the range it is compared with
+func (s *Server) compareReport(r *http.Request, reportType string, from, to time.Time, filter domain.ReportFilter, archived bool, total int) (*ReportComparison, error) {
+	previous := 0
+	if archived == (s.service.EventArchived() != nil) {
+		var err error
+		previous, err = s.service.CountReport(serviceContext(r), reportType, from, to, filter)
+		if err != nil {
+			s.log(r).Error("Error counting compared report", "type", reportType, "error", err)
+			return nil, err
+		}
+	}
+	return &ReportComparison{
+		From:   from.Format(time.RFC3339),
+		To:     to.Format(time.RFC3339),
+		Total:  previous,
+		Change: total - previous,
+	}, nil
+}
+
+// Paging of the report endpoints. Reports are not paged unless a limit is given.
+const maxReportLimit = 1000
+
+// reportPage reads the optional offset and limit of a report
+func reportPage(r *http.Request) (offset, limit int, err error) {
+	query := r.URL.Query()
+	if param := query.Get("limit"); param != "" {
+		limit, err = strconv.Atoi(param)
+		if err != nil || limit < 1 || limit > maxReportLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxReportLimit)
+		}
+	}
+	if param := query.Get("offset"); param != "" {
+		offset, err = strconv.Atoi(param)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be 0 or greater")
+		}
+	}
+	return offset, limit, nil
+}
+
+// reportFilter reads the optional filters that narrow a report to a subset of guests
+func reportFilter(r *http.Request) (domain.ReportFilter, error) {
+	return domain.NormalizeReportFilter(domain.ReportFilter{
+		Domain: r.URL.Query().Get("domain"),
+		Source: r.URL.Query().Get("source"),
+		Bar:    r.URL.Query().Get("bar"),
+
+		IncludeTest: r.URL.Query().Get("include_test") == "true",
+	})
+}
+
+// BarReportResponse represents the response of the per-bar report
+type BarReportResponse struct {
+	From      string                  `json:"from"`
+	To        string                  `json:"to"`
+	Count     int                     `json:"count"` // Redeemed guests across all bars
+	Bars      []domain.BarRedemptions `json:"bars"`
+	Generated time.Time               `json:"generated"`
+}
+
+// handleReportBars breaks down the redeemed guests of a report per bar
+func (s *Server) handleReportBars(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	fromDate, toDate, err := s.parseDateParams(r)
+	if err != nil {
+		s.writeErrorResponse(w, r, "Invalid date format", http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, err := reportFilter(r)
+	if err != nil {
+		s.writeErrorResponse(w, r, "Invalid filter", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bars, err := s.service.RedemptionsByBar(serviceContext(r), fromDate, toDate, filter)
+	if err != nil {
+		s.log(r).Error("Error generating bar report", "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error generating report")
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"bars-report-%s.csv\"",
+			time.Now().Format("2006-01-02")))
+
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"Bar", "Redeemed"})
+		for _, bar := range bars {
+			writer.Write([]string{bar.Bar, strconv.Itoa(bar.Redeemed)})
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			s.log(r).Error("Error writing CSV report", "error", err)
+		}
+		return
+	}
+
+	count := 0
+	for _, bar := range bars {
+		count += bar.Redeemed
+	}
+	s.writeJSONResponse(w, BarReportResponse{
+		From:      fromDate.Format(time.RFC3339),
+		To:        toDate.Format(time.RFC3339),
+		Count:     count,
+		Bars:      bars,
+		Generated: time.Now(),
+	}, http.StatusOK)
+}
+
+// handleReportChanges returns the guests added, redeemed or otherwise
+// modified after the since parameter, so exports can be synced incrementally
+func (s *Server) handleReportChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	// Without since, all guests are returned to seed the first export
+	since := time.Unix(0, 0)
+	if param := r.URL.Query().Get("since"); param != "" {
+		parsed, err := parseSince(param)
+		if err != nil {
+			s.writeErrorResponse(w, r, "Invalid since parameter", http.StatusBadRequest, err.Error())
+			return
+		}
+		since = parsed
+	}
+
+	// since is exclusive, so passing back the cursor never repeats a change
+	users, err := s.service.GenerateReport(serviceContext(r), string(domain.ReportTypeChanged), since.Add(time.Nanosecond), time.Now(), domain.ReportFilter{})
+	if err != nil {
+		s.log(r).Error("Error generating report", "type", domain.ReportTypeChanged, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error generating report")
+		return
+	}
+
+	cursor := since
+	for _, user := range users {
+		if user.UpdatedAt.After(cursor) {
+			cursor = user.UpdatedAt
+		}
+	}
+	w.Header().Set("X-Changes-Cursor", formatCursor(cursor))
+	if !s.revealEmails(r) {
+		users = maskUsers(users)
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		s.writeCSVReport(w, users, string(domain.ReportTypeChanged))
+		return
+	}
+	s.writeJSONResponse(w, ChangesResponse{
+		Since:     since.UTC().Format(time.RFC3339Nano),
+		Cursor:    formatCursor(cursor),
+		Count:     len(users),
+		Users:     NewUsers(users),
+		Generated: time.Now(),
+	}, http.StatusOK)
+}
+
+// formatCursor encodes a change time as an opaque cursor
+func formatCursor(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// parseSince accepts a cursor returned by the changes report or an RFC 3339 timestamp
+func parseSince(param string) (time.Time, error) {
+	if nanos, err := strconv.ParseInt(param, 10, 64); err == nil {
+		return time.Unix(0, nanos), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, param)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("since must be a cursor or an RFC 3339 timestamp")
+	}
+	return t, nil
+}
+
+// writeCSVReport writes the report as a CSV file
+func (s *Server) writeCSVReport(w http.ResponseWriter, users []*domain.User, reportType string) {
+	// Set headers for CSV download
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-report-%s.csv\"",
+		reportType, time.Now().Format("2006-01-02")))
+
+	// Bar and guest names are free text, so cells are quoted as needed
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"ID", "Email", "DateAdded", "Redeemed", "MarketingConsent", "UpdatedAt", "CreatedBy", "Bar", "FirstName", "LastName"})
+	for _, user := range users {
+		redeemedStr := ""
+		if user.Redeemed != nil {
+			redeemedStr = user.Redeemed.Format(time.RFC3339)
+		}
+
+		consentStr := ""
+		if user.MarketingConsent != nil {
+			consentStr = user.MarketingConsent.Format(time.RFC3339)
+		}
+
+		writer.Write([]string{
+			user.ID,
+			user.Email,
+			user.DateAdded.Format(time.RFC3339),
+			redeemedStr,
+			consentStr,
+			user.UpdatedAt.Format(time.RFC3339Nano),
+			user.CreatedBy,
+			user.RedeemedAt,
+			user.FirstName,
+			user.LastName,
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		s.logger.Error("Error writing CSV report", "error", err)
+	}
+}
+
+// PurchaseReportResponse represents the response of the purchases report
+type PurchaseReportResponse struct {
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Count     int               `json:"count"`
+	Paid      int               `json:"paid"`
+	Purchases []domain.Purchase `json:"purchases"`
+	Generated time.Time         `json:"generated"`
+}
+
+// handleReportPurchases handles the drink purchases report endpoint
+func (s *Server) handleReportPurchases(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	fromDate, toDate, err := s.parseDateParams(r)
+	if err != nil {
+		s.writeErrorResponse(w, r, "Invalid date format", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	purchases, err := s.service.PurchaseReport(serviceContext(r), fromDate, toDate)
+	if errors.Is(err, domain.ErrPaymentsDisabled) {
+		s.writeErrorResponse(w, r, "Not found", http.StatusNotFound, "Payments are not enabled")
+		return
+	}
+	if err != nil {
+		s.log(r).Error("Error generating purchase report", "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error generating report")
+		return
+	}
+	if !s.revealEmails(r) {
+		purchases = maskPurchases(purchases)
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"purchases-report-%s.csv\"",
+			time.Now().Format("2006-01-02")))
+
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"SessionID", "Email", "UserID", "Status", "Amount", "Currency", "CreatedAt", "PaidAt"})
+		for _, purchase := range purchases {
+			paidAt := ""
+			if purchase.PaidAt != nil {
+				paidAt = purchase.PaidAt.Format(time.RFC3339)
+			}
+			writer.Write([]string{
+				purchase.SessionID,
+				purchase.Email,
+				strconv.FormatInt(purchase.UserID, 10),
+				purchase.Status,
+				strconv.FormatInt(purchase.Amount, 10),
+				purchase.Currency,
+				purchase.CreatedAt.Format(time.RFC3339),
+				paidAt,
+			})
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			s.log(r).Error("Error writing CSV report", "error", err)
+		}
+		return
+	}
+
+	response := PurchaseReportResponse{
+		From:      fromDate.Format(time.RFC3339),
+		To:        toDate.Format(time.RFC3339),
+		Count:     len(purchases),
+		Purchases: purchases,
+		Generated: time.Now(),
+	}
+	for _, purchase := range purchases {
+		if purchase.PaidAt != nil {
+			response.Paid++
+		}
+	}
+	s.writeJSONResponse(w, response, http.StatusOK)
+}
+
+// handleStripeWebhook handles payment confirmations from Stripe. It is
+// authenticated by the webhook signature instead of an API token.
+func (s *Server) handleStripeWebhook(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != http.MethodPost {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "Could not read payload")
+		return
+	}
+
+	err = s.service.HandlePaymentWebhook(payload, r.Header.Get("Stripe-Signature"))
+	switch {
+	case err == nil:
+		s.writeJSONResponse(w, map[string]bool{"received": true}, http.StatusOK)
+	case errors.Is(err, domain.ErrInvalidSignature):
+		s.writeErrorResponse(w, r, "Unauthorized", http.StatusUnauthorized, "Invalid signature")
+	case errors.Is(err, domain.ErrPaymentsDisabled):
+		s.writeErrorResponse(w, r, "Not found", http.StatusNotFound, "Payments are not enabled")
+	case errors.Is(err, domain.ErrPurchaseNotFound):
+		s.writeErrorResponse(w, r, "Not found", http.StatusNotFound, "Unknown checkout session")
+	default:
+		s.log(r).Error("Error handling payment webhook", "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error processing webhook")
+	}
+}
+
+// handleTicket serves a printable redemption ticket. It is authenticated
+// by the signature of the link instead of an API token.
+func (s *Server) handleTicket(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, ticketPathPrefix)
+	id, ok := strings.CutSuffix(name, ".pdf")
+	if !ok {
+		s.writeErrorResponse(w, r, "Not found", http.StatusNotFound, "Ticket not found")
+		return
+	}
+
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil {
+		s.writeErrorResponse(w, r, "Forbidden", http.StatusForbidden, "Invalid ticket link")
+		return
+	}
+
+	data, err := s.service.TicketPDF(id, expires, r.URL.Query().Get("sig"))
+	switch {
+	case err == nil:
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=ticket-%s.pdf", id))
+		if _, err := w.Write(data); err != nil {
+			s.log(r).Error("Error writing ticket", "error", err)
+		}
+	case errors.Is(err, domain.ErrInvalidSignature):
+		s.writeErrorResponse(w, r, "Forbidden", http.StatusForbidden, "Invalid ticket link")
+	case errors.Is(err, domain.ErrLinkExpired):
+		s.writeErrorResponse(w, r, "Gone", http.StatusGone, "Ticket link has expired")
+	case errors.Is(err, domain.ErrTicketNotFound):
+		s.writeErrorResponse(w, r, "Not found", http.StatusNotFound, "Ticket not found")
+	default:
+		s.log(r).Error("Error reading ticket", "id", id, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error reading ticket")
+	}
+}
+
+// parseDateParams parses the from and to query parameters, or the period
+// they are a shortcut for. Without either the configured default applies.
+func (s *Server) parseDateParams(r *http.Request) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+	fromParam, toParam := query.Get("from"), query.Get("to")
+
+	if name := query.Get("period"); name != "" {
+		if fromParam != "" || toParam != "" {
+			return time.Time{}, time.Time{}, fmt.Errorf("use either 'period' or 'from' and 'to'")
+		}
+		return s.calendar.Range(name, time.Now())
+	}
+
+	fromDate, toDate := s.calendar.Default(time.Now())
+
+	// Parse 'from' parameter if provided
+	if fromParam != "" {
+		parsedFrom, err := s.calendar.ParseDate(fromParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid 'from' date format. Use YYYY-MM-DD")
+		}
+		fromDate = parsedFrom
+	}
+
+	// Parse 'to' parameter if provided
+	if toParam != "" {
+		parsedTo, err := s.calendar.ParseDate(toParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid 'to' date format. Use YYYY-MM-DD")
+		}
+		// Set time to end of day for inclusive range
+		toDate = parsedTo.AddDate(0, 0, 1).Add(-time.Second)
+	}
+
+	// Validate date range
+	if fromDate.After(toDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf("'from' date cannot be after 'to' date")
+	}
+
+	return fromDate, toDate, nil
+}
+
+// parseCompareParams reads the range a report is compared with:
+// compare=previous for the range before the report, or compare_from and
+// compare_to dates. ok is false when no comparison is requested.
+func (s *Server) parseCompareParams(r *http.Request, fromDate, toDate time.Time) (from, to time.Time, ok bool, err error) {
+	query := r.URL.Query()
+	fromParam, toParam := query.Get("compare_from"), query.Get("compare_to")
+
+	switch query.Get("compare") {
+	case "":
+		if fromParam == "" && toParam == "" {
+			return time.Time{}, time.Time{}, false, nil
+		}
+	case "previous":
+		if fromParam != "" || toParam != "" {
+			return time.Time{}, time.Time{}, false, fmt.Errorf("use either 'compare' or 'compare_from' and 'compare_to'")
+		}
+		// Named periods compare with the same stretch of the period before
+		if name := query.Get("period"); name != "" {
+			from, to, err = s.calendar.Previous(name, time.Now())
+		} else {
+			from, to, err = period.Before(fromDate, toDate)
+		}
+		return from, to, err == nil, err
+	default:
+		return time.Time{}, time.Time{}, false, fmt.Errorf("'compare' must be 'previous'")
+	}
+
+	if fromParam == "" || toParam == "" {
+		return time.Time{}, time.Time{}, false, fmt.Errorf("'compare_from' and 'compare_to' are both required")
+	}
+	from, err = s.calendar.ParseDate(fromParam)
+	if err != nil {
+		return time.Time{}, time.Time{}, false, fmt.Errorf("invalid 'compare_from' date format. Use YYYY-MM-DD")
+	}
+	to, err = s.calendar.ParseDate(toParam)
+	if err != nil {
+		return time.Time{}, time.Time{}, false, fmt.Errorf("invalid 'compare_to' date format. Use YYYY-MM-DD")
+	}
+	to = to.AddDate(0, 0, 1).Add(-time.Second)
+	if from.After(to) {
+		return time.Time{}, time.Time{}, false, fmt.Errorf("'compare_from' date cannot be after 'compare_to' date")
+	}
+	return from, to, true, nil
+}
+
+// handleEngagementStats handles the bot engagement statistics endpoint
+func (s *Server) handleEngagementStats(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	s.writeJSONResponse(w, s.service.EngagementStats(), http.StatusOK)
+}
+
+// handleFunnelStats serves the redemption funnel per day since startup:
+// checks, eligible checks, redeem buttons shown and redemptions
+func (s *Server) handleFunnelStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	s.writeJSONResponse(w, s.service.FunnelStats(), http.StatusOK)
+}
+
+// LatencyResponse represents the redemption latency of a report
+type LatencyResponse struct {
+	domain.RedemptionLatency
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Generated time.Time `json:"generated"`
+}
+
+// handleRedemptionLatency serves the distribution of the time guests of a
+// report took from being added to redeeming
+func (s *Server) handleRedemptionLatency(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	fromDate, toDate, err := s.parseDateParams(r)
+	if err != nil {
+		s.writeErrorResponse(w, r, "Invalid date format", http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, err := reportFilter(r)
+	if err != nil {
+		s.writeErrorResponse(w, r, "Invalid filter", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	latency, err := s.service.RedemptionLatency(serviceContext(r), fromDate, toDate, filter)
+	if err != nil {
+		s.log(r).Error("Error computing redemption latency", "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error generating report")
+		return
+	}
+
+	s.writeJSONResponse(w, LatencyResponse{
+		RedemptionLatency: latency,
+		From:              fromDate.Format(time.RFC3339),
+		To:                toDate.Format(time.RFC3339),
+		Generated:         time.Now(),
+	}, http.StatusOK)
+}
 
-		// Add rate limit headers
-		w.Header().Set("X-RateLimit-Limit-Minute", strconv.Itoa(s.config.API.RateLimitPerMin))
-		w.Header().Set("X-RateLimit-Remaining-Minute", strconv.Itoa(s.limiter.RemainingMinute(clientID)))
+// handleRateLimitReset handles the admin endpoint for resetting rate limits
+func (s *Server) handleRateLimitReset(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != http.MethodPost {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is allowed")
 		return
 	}
 
 	// Decode request
-	var req EmailRequest
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&req); err != nil {
-		s.writeErrorResponse(w, "Invalid request", http.StatusBadRequest, "Invalid JSON payload")
+	var req RateLimitResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
 
-	// Validate email
-	if !utils.IsValidEmail(req.Email) {
-		s.writeErrorResponse(w, "Invalid email", http.StatusBadRequest, "The provided email address is not valid")
+	if req.UserID == 0 && req.ClientIP == "" && req.Token == "" {
+		s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "One of user_id, client_ip or token is required")
 		return
 	}
 
-	// Normalize email
-	email := utils.NormalizeEmail(req.Email)
+	actor := tokenActor(r.Context())
 
-	// Check if email already exists
-	ctx := context.Background()
-	status, user, err := s.service.CheckEmailStatus(ctx, clientID, email)
-	if err != nil {
-		s.logger.Error("Error checking email status", "email", email, "error", err)
-		s.writeErrorResponse(w, "Internal server error", http.StatusInternalServerError, "Error processing request")
-		return
+	// Reset the bot limiter for a Telegram user
+	if req.UserID != 0 {
+		s.service.ResetRateLimit(req.UserID)
+		s.log(r).Info("Audit: rate limit reset", "actor", actor, "target_user_id", req.UserID, "remote", ClientIP(r))
 	}
 
-	// Handle based on status
-	switch status {
-	case "eligible", "redeemed":
-		// Email already exists, return conflict status
-		response := EmailResponse{
-			Status:  "exists",
-			Message: "Email already exists in database",
+	// Reset the API limiter for a client IP, under every token it may have used
+	if req.ClientIP != "" {
+		target := req.ClientIP
+		if ip := normalizeIP(target); ip != "" {
+			target = ClientPrefix(ip, s.config.API.IPv6Prefix)
 		}
-		if user != nil {
-			response.ID = user.ID
+		for _, fingerprint := range s.authProvider.Fingerprints() {
+			s.limiter.ResetKey(clientKey(fingerprint, target))
 		}
-		s.writeJSONResponse(w, response, http.StatusConflict)
-		return
+		s.log(r).Info("Audit: rate limit reset", "actor", actor, "target_client_ip", req.ClientIP, "remote", ClientIP(r))
+	}
 
-	case "rate_limited":
-		s.writeErrorResponse(w, "Too Many Requests", http.StatusTooManyRequests, "Rate limit exceeded")
-		return
+	// Reset the API token limiter for a token
+	if req.Token != "" {
+		s.tokenLimiter.ResetKey("token:" + req.Token)
+		s.log(r).Info("Audit: rate limit reset", "actor", actor, "target_token", req.Token, "remote", ClientIP(r))
+	}
 
-	case "unavailable":
-		s.writeErrorResponse(w, "Service Unavailable", http.StatusServiceUnavailable, "Database is temporarily unavailable")
-		return
+	s.writeJSONResponse(w, RateLimitResetResponse{
+		Status:   "reset",
+		UserID:   req.UserID,
+		ClientIP: req.ClientIP,
+		Token:    req.Token,
+	}, http.StatusOK)
+}
 
-	case "not_found":
-		// Continue with adding the email
-		break
+// handleDatabaseStatus handles the admin endpoint for repository diagnostics
+func (s *Server) handleDatabaseStatus(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
 	}
 
-	// Generate a new user with a unique ID
-	newUser := &domain.User{
-		ID:        GenerateUniqueID(),
-		Email:     email,
-		DateAdded: time.Now(),
-		Redeemed:  nil,
+	ctx := r.Context()
+	resp := DatabaseStatusResponse{
+		Status:  "ok",
+		Checked: time.Now(),
 	}
 
-	// Store in database using service's AddUser method for new users
-	if err := s.service.AddUser(ctx, newUser); err != nil {
-		s.logger.Error("Error adding email to database", "email", email, "error", err)
-		s.writeErrorResponse(w, "Internal server error", http.StatusInternalServerError, "Error storing email")
+	// Check connectivity first, stats are only collected from a healthy repository
+	if err := s.service.DatabaseHealth(ctx); err != nil {
+		s.log(r).Error("Database health check failed", "error", err)
+		resp.Status = "unavailable"
+		resp.Error = err.Error()
+		s.writeJSONResponse(w, resp, http.StatusServiceUnavailable)
 		return
 	}
 
-	// Log successful addition
-	s.logger.Info("Email added via API", "email", email, "id", newUser.ID)
-
-	// Return success
-	response := EmailResponse{
-		ID:      newUser.ID,
-		Status:  "created",
-		Message: "Email added successfully",
+	stats, err := s.service.DatabaseStats(ctx)
+	if err != nil {
+		resp.Status = "degraded"
+		resp.Error = err.Error()
+		s.writeJSONResponse(w, resp, http.StatusOK)
+		return
 	}
-	s.writeJSONResponse(w, response, http.StatusCreated)
-}
+	resp.Stats = &stats
 
-// handleReportRedeemed handles the redeemed report endpoint
-func (s *Server) handleReportRedeemed(w http.ResponseWriter, r *http.Request) {
-	s.handleReport(w, r, "redeemed")
+	s.writeJSONResponse(w, resp, http.StatusOK)
 }
 
-// handleReportAdded handles the added report endpoint
-func (s *Server) handleReportAdded(w http.ResponseWriter, r *http.Request) {
-	s.handleReport(w, r, "added")
-}
+// handleRuntimeStatus handles the admin endpoint summarizing what the bot
+// did since it started, as logged at shutdown
+func (s *Server) handleRuntimeStatus(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
 
-// handleReportAll handles the all report endpoint
-func (s *Server) handleReportAll(w http.ResponseWriter, r *http.Request) {
-	s.handleReport(w, r, "all")
+	s.writeJSONResponse(w, s.service.Status(), http.StatusOK)
 }
 
-// handleReport is a generic handler for all report types
-func (s *Server) handleReport(w http.ResponseWriter, r *http.Request, reportType string) {
+// handleEventStatus handles the admin endpoint showing whether the event is archived
+func (s *Server) handleEventStatus(w http.ResponseWriter, r *http.Request) {
 	// Only allow GET method
 	if r.Method != http.MethodGet {
-		s.writeErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
 		return
 	}
 
-	// Authenticate request
-	apiKey := r.Header.Get("Authorization")
-	if len(apiKey) > 7 && strings.HasPrefix(strings.ToLower(apiKey), "bearer ") {
-		apiKey = apiKey[7:] // Remove 'Bearer ' prefix
-	}
+	s.writeJSONResponse(w, EventStatusResponse{
+		Event:    s.config.Event.Name,
+		Archived: s.service.EventArchived(),
+		Archives: s.service.EventArchives(),
+	}, http.StatusOK)
+}
 
-	if !s.authProvider.Authenticate(apiKey) {
-		s.writeErrorResponse(w, "Unauthorized", http.StatusUnauthorized, "Invalid or missing authentication token")
+// handleArchiveEvent handles the admin endpoint archiving the event
+func (s *Server) handleArchiveEvent(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != http.MethodPost {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is allowed")
 		return
 	}
 
-	// Apply rate limiting
-	clientIP := getClientIP(r)
-	clientID := int64(HashCode(clientIP))
+	var req ArchiveEventRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "Invalid JSON payload")
+			return
+		}
+	}
 
-	if !s.limiter.Allow(clientID) {
-		s.writeErrorResponse(w, "Too Many Requests", http.StatusTooManyRequests, "Rate limit exceeded")
-		return
+	actor := tokenActor(r.Context())
+	archive, err := s.service.ArchiveEvent(serviceContext(r), actor, req.Export)
+	switch {
+	case err == nil:
+		s.writeJSONResponse(w, archive, http.StatusOK)
+	case errors.Is(err, domain.ErrEventArchived):
+		s.writeErrorResponse(w, r, "Conflict", http.StatusConflict, "Event is already archived")
+	default:
+		s.log(r).Error("Error archiving event", "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error archiving event")
 	}
+}
 
-	// Parse date range parameters
-	fromDate, toDate, err := parseDateParams(r)
-	if err != nil {
-		s.writeErrorResponse(w, "Invalid date format", http.StatusBadRequest, err.Error())
+// Paging of the audit log endpoint
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// handleAuditLog handles the admin endpoint browsing the audit log. CSV
+// exports contain every matching entry, JSON responses are paged.
+func (s *Server) handleAuditLog(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only GET method is allowed")
 		return
 	}
 
-	// Set content type
-	format := r.URL.Query().Get("format")
-	if format == "" {
-		format = "json" // Default format is JSON
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Actor:    query.Get("actor"),
+		Operator: query.Get("operator"),
+		Action:   query.Get("action"),
+		Email:    query.Get("email"),
 	}
 
-	// Generate report
-	ctx := context.Background()
-	users, err := s.service.GenerateReport(ctx, reportType, fromDate, toDate)
-	if err != nil {
-		s.logger.Error("Error generating report", "type", reportType, "error", err)
-		s.writeErrorResponse(w, "Internal server error", http.StatusInternalServerError, "Error generating report")
-		return
+	// Dates are optional, without them the whole log is searched
+	if from := query.Get("from"); from != "" {
+		parsed, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			s.writeErrorResponse(w, r, "Invalid date format", http.StatusBadRequest, "invalid 'from' date format. Use YYYY-MM-DD")
+			return
+		}
+		filter.From = parsed
 	}
-
-	// Format-specific response
-	if format == "csv" {
-		s.writeCSVReport(w, users, reportType)
-	} else {
-		// Prepare JSON response
-		response := ReportResponse{
-			Type:      reportType,
-			From:      fromDate.Format(time.RFC3339),
-			To:        toDate.Format(time.RFC3339),
-			Count:     len(users),
-			Users:     users,
-			Generated: time.Now(),
+	if to := query.Get("to"); to != "" {
+		parsed, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			s.writeErrorResponse(w, r, "Invalid date format", http.StatusBadRequest, "invalid 'to' date format. Use YYYY-MM-DD")
+			return
 		}
-		s.writeJSONResponse(w, response, http.StatusOK)
+		filter.To = parsed.Add(24*time.Hour - time.Nanosecond)
 	}
-}
-
-// writeCSVReport writes the report as a CSV file
-func (s *Server) writeCSVReport(w http.ResponseWriter, users []*domain.User, reportType string) {
-	// Set headers for CSV download
-	w.Header().Set("Content-Type", "text/csv")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-report-%s.csv\"",
-		reportType, time.Now().Format("2006-01-02")))
 
-	// Write CSV header
-	if _, err := w.Write([]byte("ID,Email,DateAdded,Redeemed\n")); err != nil {
-		s.logger.Error("Error writing CSV header", "error", err)
-		return
+	// Journals cover the whole log, so a reader can check no entry is missing
+	if query.Get("format") == "journal" {
+		if filter != (audit.Filter{}) {
+			s.writeErrorResponse(w, r, "Invalid parameters", http.StatusBadRequest, "Journal exports cover the whole audit log and cannot be filtered")
+			return
+		}
+		if s.config.Event.JournalKey == "" {
+			s.writeErrorResponse(w, r, "Not found", http.StatusNotFound, "Journal exports are not enabled")
+			return
+		}
 	}
 
-	// Write each row
-	for _, user := range users {
-		redeemedStr := ""
-		if user.Redeemed != nil {
-			redeemedStr = user.Redeemed.Format(time.RFC3339)
+	csvExport := query.Get("format") == "csv"
+	journalExport := query.Get("format") == "journal"
+	if !csvExport && !journalExport {
+		filter.Limit = defaultAuditLimit
+		if param := query.Get("limit"); param != "" {
+			limit, err := strconv.Atoi(param)
+			if err != nil || limit < 1 || limit > maxAuditLimit {
+				s.writeErrorResponse(w, r, "Invalid limit", http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxAuditLimit))
+				return
+			}
+			filter.Limit = limit
+		}
+		if param := query.Get("offset"); param != "" {
+			offset, err := strconv.Atoi(param)
+			if err != nil || offset < 0 {
+				s.writeErrorResponse(w, r, "Invalid offset", http.StatusBadRequest, "offset must be 0 or greater")
+				return
+			}
+			filter.Offset = offset
 		}
+	}
 
-		row := fmt.Sprintf("%s,%s,%s,%s\n",
-			user.ID,
-			user.Email,
-			user.DateAdded.Format(time.RFC3339),
-			redeemedStr)
+	entries, total, err := s.service.AuditLog(serviceContext(r), filter)
+	if err != nil {
+		s.log(r).Error("Error reading audit log", "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error reading audit log")
+		return
+	}
+	if !s.revealEmails(r) {
+		entries = maskAuditEntries(entries)
+	}
 
-		if _, err := w.Write([]byte(row)); err != nil {
-			s.logger.Error("Error writing CSV row", "error", err)
-			return
-		}
+	if csvExport {
+		s.writeCSVAudit(w, entries)
+		return
+	}
+	if journalExport {
+		s.writeJournalAudit(w, r, entries)
+		return
 	}
+	s.writeJSONResponse(w, AuditResponse{
+		Total:     total,
+		Offset:    filter.Offset,
+		Limit:     filter.Limit,
+		Count:     len(entries),
+		Entries:   NewAuditEntries(entries),
+		Generated: time.Now(),
+	}, http.StatusOK)
 }
 
-// parseDateParams parses the from and to query parameters
-func parseDateParams(r *http.Request) (time.Time, time.Time, error) {
-	// Default dates (last 7 days)
-	fromDate := time.Now().AddDate(0, 0, -7)
-	toDate := time.Now()
+// writeCSVAudit writes audit log entries as a CSV file
+func (s *Server) writeCSVAudit(w http.ResponseWriter, entries []audit.Entry) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"audit-%s.csv\"", time.Now().Format("2006-01-02")))
 
-	// Parse 'from' parameter if provided
-	fromParam := r.URL.Query().Get("from")
-	if fromParam != "" {
-		parsedFrom, err := time.Parse("2006-01-02", fromParam)
-		if err != nil {
-			return time.Time{}, time.Time{}, fmt.Errorf("invalid 'from' date format. Use YYYY-MM-DD")
-		}
-		fromDate = parsedFrom
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Time", "Actor", "Action", "Email", "Details", "Operator"})
+	for _, entry := range entries {
+		writer.Write([]string{entry.Time.Format(time.RFC3339), entry.Actor, entry.Action, entry.Email, entry.Details, entry.Operator})
 	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		s.logger.Error("Error writing CSV audit log", "error", err)
+	}
+}
 
-	// Parse 'to' parameter if provided
-	toParam := r.URL.Query().Get("to")
-	if toParam != "" {
-		parsedTo, err := time.Parse("2006-01-02", toParam)
-		if err != nil {
-			return time.Time{}, time.Time{}, fmt.Errorf("invalid 'to' date format. Use YYYY-MM-DD")
-		}
-		// Set time to end of day for inclusive range
-		toDate = parsedTo.Add(24*time.Hour - time.Second)
+// writeJournalAudit writes audit log entries as a hash-chained journal,
+// oldest first. The record count and head hash are sent as headers so they
+// can be published alongside the file.
+func (s *Server) writeJournalAudit(w http.ResponseWriter, r *http.Request, entries []audit.Entry) {
+	oldestFirst := make([]audit.Entry, len(entries))
+	for i, entry := range entries {
+		oldestFirst[len(entries)-1-i] = entry
 	}
 
-	// Validate date range
-	if fromDate.After(toDate) {
-		return time.Time{}, time.Time{}, fmt.Errorf("'from' date cannot be after 'to' date")
+	var journal bytes.Buffer
+	summary, err := audit.WriteJournal(&journal, oldestFirst, []byte(s.config.Event.JournalKey))
+	if err != nil {
+		s.log(r).Error("Error writing audit journal", "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error reading audit log")
+		return
 	}
 
-	return fromDate, toDate, nil
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"audit-%s.jsonl\"", time.Now().Format("2006-01-02")))
+	w.Header().Set("X-Journal-Records", strconv.Itoa(summary.Records))
+	w.Header().Set("X-Journal-Head", summary.Head)
+	if _, err := w.Write(journal.Bytes()); err != nil {
+		s.log(r).Error("Error writing audit journal", "error", err)
+	}
 }
 
 // handleHealth handles the health check endpoint
@@ -456,7 +1831,7 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		"status":  "ok",
 		"version": "1.0.0",
 	}); err != nil {
-		s.logger.Error("Error encoding health check response", "error", err)
+		s.log(r).Error("Error encoding health check response", "error", err)
 	}
 }
 
@@ -470,8 +1845,9 @@ func (s *Server) writeJSONResponse(w http.ResponseWriter, data any, statusCode i
 }
 
 // writeErrorResponse writes a JSON error response with the given status code
-func (s *Server) writeErrorResponse(w http.ResponseWriter, message string, statusCode int, details string) {
+func (s *Server) writeErrorResponse(w http.ResponseWriter, r *http.Request, message string, statusCode int, details string) {
 	w.Header().Set("Content-Type", "application/json")
+	details = s.localizeDetails(w, r, details)
 	w.WriteHeader(statusCode)
 	if err := json.NewEncoder(w).Encode(ErrorResponse{
 		Error:   message,
@@ -482,34 +1858,178 @@ func (s *Server) writeErrorResponse(w http.ResponseWriter, message string, statu
 	}
 }
 
+// writeRateLimitResponse writes a 429 response naming the exceeded limit
+// and when the client may retry
+func (s *Server) writeRateLimitResponse(w http.ResponseWriter, r *http.Request, limit string, retryAfter time.Duration) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-RateLimit-Scope", limit)
+	setRetryAfter(w, retryAfter)
+	details := s.localizeDetails(w, r, "Rate limit exceeded")
+	w.WriteHeader(http.StatusTooManyRequests)
+	if err := json.NewEncoder(w).Encode(ErrorResponse{
+		Error:   "Too Many Requests",
+		Code:    http.StatusTooManyRequests,
+		Details: details,
+		Limit:   limit,
+	}); err != nil {
+		s.logger.Error("Error encoding JSON error response", "error", err)
+	}
+}
+
+// writeServiceRateLimited writes a 429 response for a request rejected by
+// the rate limit of the service
+func (s *Server) writeServiceRateLimited(w http.ResponseWriter, r *http.Request) {
+	setRetryAfter(w, s.service.RetryAfter(serviceContext(r), HashCode(ClientIP(r))))
+	s.writeErrorResponse(w, r, "Too Many Requests", http.StatusTooManyRequests, "Rate limit exceeded")
+}
+
+// writeUnavailable writes a 503 response for a request that failed because
+// the database is unavailable
+func (s *Server) writeUnavailable(w http.ResponseWriter, r *http.Request) {
+	setRetryAfter(w, s.service.UnavailableRetryAfter())
+	s.writeErrorResponse(w, r, "Service Unavailable", http.StatusServiceUnavailable, "Database is temporarily unavailable")
+}
+
+// setRetryAfter sets the Retry-After header to a wait in whole seconds,
+// rounded up. Unknown waits of 0 are left out.
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+	}
+}
+
 // handleBulkUpload handles the bulk email upload endpoint
 func (s *Server) handleBulkUpload(w http.ResponseWriter, r *http.Request) {
 	// Only allow POST method
 	if r.Method != http.MethodPost {
-		s.writeErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is allowed")
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	emails, ok := s.parseBulkPayload(w, r)
+	if !ok {
+		return
+	}
+
+	// Enforce maximum number of emails per request
+	maxEmails := 1000 // Arbitrary limit to prevent abuse
+	if len(emails) > maxEmails {
+		s.writeErrorResponse(w, r, "Request too large", http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Maximum %d emails allowed per request", maxEmails))
+		return
+	}
+
+	// Process emails in bulk
+	ctx := serviceContext(r)
+	response := processBulkEmails(ctx, s, HashCode(ClientIP(r)), emails)
+
+	// Return success
+	s.writeJSONResponse(w, response, http.StatusOK)
+}
+
+// handleImport queues a bulk upload for the import workers and returns the
+// job tracking it, for uploads too large to add within one request
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+	if !s.service.ImportQueueEnabled() {
+		s.writeErrorResponse(w, r, "Not Found", http.StatusNotFound, "Queued imports are not enabled, use /api/v1/email/bulk")
+		return
+	}
+
+	emails, ok := s.parseBulkPayload(w, r)
+	if !ok {
+		return
+	}
+	if maxEmails := s.service.MaxImportEmails(); maxEmails > 0 && len(emails) > maxEmails {
+		s.writeErrorResponse(w, r, "Request too large", http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Maximum %d emails allowed per import", maxEmails))
 		return
 	}
 
-	// Authenticate request
-	apiKey := r.Header.Get("Authorization")
-	if len(apiKey) > 7 && strings.HasPrefix(strings.ToLower(apiKey), "bearer ") {
-		apiKey = apiKey[7:] // Remove 'Bearer ' prefix
+	job, err := s.service.EnqueueImport(serviceContext(r), tokenActor(r.Context()), emails)
+	if err != nil {
+		s.log(r).Error("Error queueing import", "job", job.ID, "emails", len(emails), "error", err)
+		s.writeErrorResponse(w, r, "Service Unavailable", http.StatusServiceUnavailable, "Error queueing import")
+		return
 	}
 
-	if !s.authProvider.Authenticate(apiKey) {
-		s.writeErrorResponse(w, "Unauthorized", http.StatusUnauthorized, "Invalid or missing authentication token")
+	w.Header().Set("Location", jobsPathPrefix+job.ID)
+	s.writeJSONResponse(w, job, http.StatusAccepted)
+}
+
+// maxImportBytes is the size of the largest CSV file imported at once
+const maxImportBytes = 10 << 20
+
+// handleImportCSV imports a CSV file in the background, with the columns
+// of the email, name and tags given in the query, and returns the job
+// tracking it
+func (s *Server) handleImportCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeErrorResponse(w, r, "Method not allowed", http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+	contentType := r.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "text/csv") && !strings.HasPrefix(contentType, "application/csv") {
+		s.writeErrorResponse(w, r, "Invalid Content-Type", http.StatusUnsupportedMediaType, "Content-Type must be text/csv")
 		return
 	}
 
-	// Apply rate limiting
-	clientIP := getClientIP(r)
-	clientID := int64(HashCode(clientIP))
+	query := r.URL.Query()
+	opts := importer.Options{
+		Header:   query.Get("header") != "false",
+		Dedupe:   importer.DedupeMemory,
+		Existing: query.Get("existing"),
+	}
+	for _, param := range []struct {
+		name   string
+		column *int
+	}{{"email_column", &opts.Column}, {"name_column", &opts.NameColumn}, {"tags_column", &opts.TagsColumn}} {
+		if value := query.Get(param.name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				s.writeErrorResponse(w, r, "Invalid parameter", http.StatusBadRequest, param.name+" must be a column number")
+				return
+			}
+			*param.column = n
+		}
+	}
 
-	if !s.limiter.Allow(clientID) {
-		s.writeErrorResponse(w, "Too Many Requests", http.StatusTooManyRequests, "Rate limit exceeded")
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		s.writeErrorResponse(w, r, "Request too large", http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Maximum %d MB allowed per import", maxImportBytes>>20))
+		return
+	}
+	if len(data) == 0 {
+		s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "The file is empty")
 		return
 	}
 
+	name := query.Get("name")
+	if name == "" {
+		name = "upload.csv"
+	}
+	job, err := s.service.StartImport(serviceContext(r), tokenActor(r.Context()), name, data, opts)
+	switch {
+	case err == nil:
+		w.Header().Set("Location", jobsPathPrefix+job.ID)
+		s.writeJSONResponse(w, job, http.StatusAccepted)
+	case domain.IsValidationError(err):
+		s.writeErrorResponse(w, r, "Invalid parameter", http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrEventArchived):
+		s.writeErrorResponse(w, r, "Conflict", http.StatusConflict, "Event is archived")
+	default:
+		s.log(r).Error("Error starting import", "file", name, "error", err)
+		s.writeErrorResponse(w, r, "Internal server error", http.StatusInternalServerError, "Error starting import")
+	}
+}
+
+// parseBulkPayload reads the emails of an upload in any of the accepted
+// content types, writing the error response when there are none
+func (s *Server) parseBulkPayload(w http.ResponseWriter, r *http.Request) ([]string, bool) {
 	// Check Content-Type and parse accordingly
 	contentType := r.Header.Get("Content-Type")
 
@@ -526,35 +2046,22 @@ func (s *Server) handleBulkUpload(w http.ResponseWriter, r *http.Request) {
 		// Parse multipart form file upload
 		emails, err = parseMultipartFormUpload(r)
 	} else {
-		s.writeErrorResponse(w, "Invalid Content-Type", http.StatusUnsupportedMediaType,
+		s.writeErrorResponse(w, r, "Invalid Content-Type", http.StatusUnsupportedMediaType,
 			"Content-Type must be application/json, text/csv, or multipart/form-data")
-		return
+		return nil, false
 	}
 
 	if err != nil {
-		s.writeErrorResponse(w, "Invalid request", http.StatusBadRequest, err.Error())
-		return
+		s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, err.Error())
+		return nil, false
 	}
 
 	if len(emails) == 0 {
-		s.writeErrorResponse(w, "Invalid request", http.StatusBadRequest, "No valid emails found in payload")
-		return
+		s.writeErrorResponse(w, r, "Invalid request", http.StatusBadRequest, "No valid emails found in payload")
+		return nil, false
 	}
 
-	// Enforce maximum number of emails per request
-	maxEmails := 1000 // Arbitrary limit to prevent abuse
-	if len(emails) > maxEmails {
-		s.writeErrorResponse(w, "Request too large", http.StatusRequestEntityTooLarge,
-			fmt.Sprintf("Maximum %d emails allowed per request", maxEmails))
-		return
-	}
-
-	// Process emails in bulk
-	ctx := context.Background()
-	response := processBulkEmails(ctx, s, clientID, emails)
-
-	// Return success
-	s.writeJSONResponse(w, response, http.StatusOK)
+	return emails, true
 }
 
 // processBulkEmails processes multiple emails and returns stats
@@ -591,7 +2098,7 @@ func processBulkEmails(ctx context.Context, s *Server, clientID int64, emails []
 			response.Duplicate++
 			continue
 
-		case "rate_limited", "unavailable":
+		case "rate_limited", "unavailable", "denied", "archived":
 			response.Failed++
 			response.Failures = append(response.Failures, fmt.Sprintf("%s: %s", email, status))
 			continue
@@ -606,13 +2113,18 @@ func processBulkEmails(ctx context.Context, s *Server, clientID int64, emails []
 			Email:     email,
 			DateAdded: time.Now(),
 			Redeemed:  nil,
+			CreatedBy: tokenActor(ctx),
 		}
 
 		// Store in database
 		if err := s.service.AddUser(ctx, newUser); err != nil {
+			if domain.IsDuplicateUser(err) {
+				response.Duplicate++
+				continue
+			}
 			response.Failed++
 			response.Failures = append(response.Failures, fmt.Sprintf("%s: storage error", email))
-			s.logger.Error("Error adding email to database", "email", email, "error", err)
+			logger.FromContext(ctx, s.logger).Error("Error adding email to database", "email", email, "error", err)
 			continue
 		}
 
@@ -620,7 +2132,7 @@ func processBulkEmails(ctx context.Context, s *Server, clientID int64, emails []
 		response.Success++
 
 		// Log successful addition
-		s.logger.Info("Email added via bulk API", "email", email, "id", newUser.ID)
+		logger.FromContext(ctx, s.logger).Info("Email added via bulk API", "email", email, "id", newUser.ID)
 	}
 
 	return response
@@ -739,32 +2251,9 @@ func parseMultipartFormUpload(r *http.Request) ([]string, error) {
 	return emails, nil
 }
 
-// getClientIP extracts the client IP address from the request
-func getClientIP(r *http.Request) string {
-	// Check for X-Forwarded-For header
-	if xForwardedFor := r.Header.Get("X-Forwarded-For"); xForwardedFor != "" {
-		// X-Forwarded-For can contain multiple IPs, the first one is the original client
-		ips := strings.Split(xForwardedFor, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
-	}
-
-	// Check for X-Real-IP header
-	if xRealIP := r.Header.Get("X-Real-IP"); xRealIP != "" {
-		return xRealIP
-	}
-
-	// Extract IP from RemoteAddr
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr // Return as is if we can't split
-	}
-	return ip
-}
-
 // HashCode converts a string to a 64-bit integer hash
-// This is a simple implementation and is not cryptographically secure
+// This is a simple implementation and is not cryptographically secure.
+// Different strings can share a hash, so rate limits use string keys instead.
 func HashCode(s string) int64 {
 	var h int64 = 0
 	for i := 0; i < len(s); i++ {
@@ -773,6 +2262,12 @@ func HashCode(s string) int64 {
 	return h
 }
 
+// clientKey returns the rate limiting key of a client IP using a token, so
+// that terminals sharing an address but not a token are counted separately
+func clientKey(fingerprint, clientIP string) string {
+	return "client:" + fingerprint + "@" + clientIP
+}
+
 // GenerateUniqueID generates a unique ID for a new user
 func GenerateUniqueID() string {
 	now := time.Now()
@@ -1,22 +1,43 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/ceesaxp/cocktail-bot/internal/analytics"
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
 	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/crashreport"
 	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/featureflags"
+	"github.com/ceesaxp/cocktail-bot/internal/importer"
+	"github.com/ceesaxp/cocktail-bot/internal/jobs"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/period"
+	"github.com/ceesaxp/cocktail-bot/internal/ports"
+	"github.com/ceesaxp/cocktail-bot/internal/proxyproto"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
 )
 
-// mockService implements ServiceInterface for testing
+// mockService implements ports.Service for testing. Methods the API
+// does not use are left to the embedded interface and panic if called.
 type mockService struct {
+	ports.Service
+
 	findEmailStatus      string
 	findEmailUser        *domain.User
 	findEmailError       error
@@ -27,59 +48,386 @@ type mockService struct {
 	addUserError         error
 	addUserCalled        bool
 	addUserPayload       *domain.User
+	addUserOperator      string // Operator named by the context of AddUser
 	generateReportUsers  []*domain.User
 	generateReportError  error
 	generateReportCalled bool
+	countReportCalled    bool
+	countReportRanges    [][2]time.Time // Ranges counted, in order
+	countReportTotals    []int          // Totals returned by the counts in order, the number of report users if missing
 	generateReportType   string
 	generateReportFrom   time.Time
 	generateReportTo     time.Time
+	generateReportFilter domain.ReportFilter
+	barRedemptions       []domain.BarRedemptions
+	latency              domain.RedemptionLatency
+	resetUserID          int64
+	dbHealthError        error
+	webhookPayload       []byte
+	webhookSignature     string
+	purchases            []domain.Purchase
+	archive              *domain.EventArchive
+	auditEntries         []audit.Entry
+	auditFilter          audit.Filter
+	voucherOnly          bool
+	vouchers             map[string]string // Emails of valid voucher codes, nil when vouchers are disabled
+	offlineStatus        map[string]string // Outcome of offline redemptions by email, redeemed if missing
+	userAsOf             time.Time
+	importQueue          bool                // Imports can be queued
+	jobs                 map[string]jobs.Job // Jobs by ID
+	userPatch            domain.UserPatch    // Last patch passed to PatchUser
+	importOptions        importer.Options    // Options of the last CSV import
+	features             *featureflags.Set   // Flags listed and toggled, every flag on if nil
 }
 
-func (s *mockService) CheckEmailStatus(ctx any, userID int64, email string) (string, *domain.User, error) {
+func (s *mockService) CheckEmailStatus(ctx context.Context, userID int64, email string) (string, *domain.User, error) {
 	return s.findEmailStatus, s.findEmailUser, s.findEmailError
 }
 
-func (s *mockService) RedeemCocktail(ctx any, userID int64, email string) (time.Time, error) {
+func (s *mockService) RedeemCocktail(ctx context.Context, userID int64, email string) (time.Time, error) {
 	if s.redeemError != nil {
 		return time.Time{}, s.redeemError
 	}
 	return time.Now(), nil
 }
 
-func (s *mockService) UpdateUser(ctx any, user *domain.User) error {
+func (s *mockService) UpdateUser(ctx context.Context, user *domain.User) error {
 	s.updateUserCalled = true
 	s.updateUserPayload = user
 	return s.updateUserError
 }
 
-func (s *mockService) AddUser(ctx any, user *domain.User) error {
+func (s *mockService) AddUser(ctx context.Context, user *domain.User) error {
 	s.addUserCalled = true
 	s.addUserPayload = user
+	s.addUserOperator = audit.OperatorFromContext(ctx)
 	return s.addUserError
 }
 
-func (s *mockService) GenerateReport(ctx any, reportType string, fromDate, toDate time.Time) ([]*domain.User, error) {
+func (s *mockService) GenerateReport(ctx context.Context, reportType string, fromDate, toDate time.Time, filter domain.ReportFilter) ([]*domain.User, error) {
 	s.generateReportCalled = true
 	s.generateReportType = reportType
 	s.generateReportFrom = fromDate
 	s.generateReportTo = toDate
+	s.generateReportFilter = filter
 	return s.generateReportUsers, s.generateReportError
 }
 
+func (s *mockService) CountReport(ctx context.Context, reportType string, fromDate, toDate time.Time, filter domain.ReportFilter) (int, error) {
+	s.countReportCalled = true
+	s.generateReportType = reportType
+	s.generateReportFilter = filter
+	s.countReportRanges = append(s.countReportRanges, [2]time.Time{fromDate, toDate})
+	if n := len(s.countReportRanges); n <= len(s.countReportTotals) {
+		return s.countReportTotals[n-1], s.generateReportError
+	}
+	return len(s.generateReportUsers), s.generateReportError
+}
+
+func (s *mockService) RedemptionsByBar(ctx context.Context, fromDate, toDate time.Time, filter domain.ReportFilter) ([]domain.BarRedemptions, error) {
+	s.generateReportFilter = filter
+	return s.barRedemptions, s.generateReportError
+}
+
+func (s *mockService) SearchGuests(ctx context.Context, query string, offset, limit int) (domain.SearchResult, error) {
+	terms, err := domain.SearchTerms(query)
+	if err != nil {
+		return domain.SearchResult{}, err
+	}
+	return domain.RankSearch(s.generateReportUsers, domain.SearchParams{Terms: terms, Offset: offset, Limit: limit}), s.generateReportError
+}
+
+func (s *mockService) RedemptionLatency(ctx context.Context, fromDate, toDate time.Time, filter domain.ReportFilter) (domain.RedemptionLatency, error) {
+	s.generateReportFilter = filter
+	return s.latency, s.generateReportError
+}
+
+func (s *mockService) ResetRateLimit(userID int64) {
+	s.resetUserID = userID
+}
+
+func (s *mockService) RetryAfter(ctx context.Context, userID int64) time.Duration {
+	return time.Minute
+}
+
+func (s *mockService) UnavailableRetryAfter() time.Duration {
+	return 0
+}
+
+func (s *mockService) FunnelStats() analytics.Funnel {
+	day := analytics.FunnelDay{Checks: 10, Eligible: 6, ButtonsShown: 5, Redeemed: 4, Unclaimed: 2}
+	funnel := analytics.Funnel{Days: []analytics.FunnelDay{day}, Total: day}
+	funnel.Days[0].Date = "2025-06-14"
+	return funnel
+}
+
+func (s *mockService) EnumerationStats() domain.EnumerationStats {
+	return domain.EnumerationStats{Alarms: 2, Tracked: 3, Blocked: 1}
+}
+
+func (s *mockService) EngagementStats() analytics.Engagement {
+	return analytics.Engagement{
+		Languages:      map[string]int{"en": 3, "de": 1},
+		Checks:         4,
+		Redemptions:    2,
+		ConversionRate: 0.5,
+	}
+}
+
+func (s *mockService) Status() domain.RuntimeStatus {
+	return domain.RuntimeStatus{
+		Checks:             4,
+		Redemptions:        2,
+		PendingRedemptions: 1,
+		LastErrors:         []domain.LoggedError{{Message: "Error updating user"}},
+	}
+}
+
+func (s *mockService) DatabaseHealth(ctx context.Context) error {
+	return s.dbHealthError
+}
+
+func (s *mockService) DatabaseStats(ctx context.Context) (domain.RepoStats, error) {
+	return domain.RepoStats{Backend: "mock", Users: 10, Redeemed: 4}, nil
+}
+
+func (s *mockService) HandlePaymentWebhook(payload []byte, signature string) error {
+	if signature != "valid" {
+		return domain.ErrInvalidSignature
+	}
+	s.webhookPayload = payload
+	s.webhookSignature = signature
+	return nil
+}
+
+func (s *mockService) PurchaseReport(ctx context.Context, fromDate, toDate time.Time) ([]domain.Purchase, error) {
+	if s.purchases == nil {
+		return nil, domain.ErrPaymentsDisabled
+	}
+	return s.purchases, nil
+}
+
+func (s *mockService) TicketURL(email string, redeemed time.Time) string {
+	return ""
+}
+
+func (s *mockService) TicketPDF(id string, expires int64, signature string) ([]byte, error) {
+	switch {
+	case signature != "valid":
+		return nil, domain.ErrInvalidSignature
+	case expires < time.Now().Unix():
+		return nil, domain.ErrLinkExpired
+	case id != "0123456789abcdef":
+		return nil, domain.ErrTicketNotFound
+	}
+	return []byte("%PDF-1.4"), nil
+}
+
+func (s *mockService) EventArchived() *domain.EventArchive {
+	return s.archive
+}
+
+func (s *mockService) EventArchives() []domain.EventArchive {
+	if s.archive == nil {
+		return nil
+	}
+	return []domain.EventArchive{*s.archive}
+}
+
+func (s *mockService) ArchiveEvent(ctx context.Context, actor string, export bool) (domain.EventArchive, error) {
+	if s.archive != nil {
+		return domain.EventArchive{}, domain.ErrEventArchived
+	}
+	s.archive = &domain.EventArchive{Event: "Test Event", ArchivedAt: time.Now(), Actor: actor}
+	if export {
+		s.archive.Bundle = "/tmp/archives/test-event"
+	}
+	return *s.archive, nil
+}
+
+func (s *mockService) AuditLog(ctx context.Context, filter audit.Filter) ([]audit.Entry, int, error) {
+	s.auditFilter = filter
+	return s.auditEntries, len(s.auditEntries), nil
+}
+
+func (s *mockService) FreeFormEmailAllowed() bool {
+	return !s.voucherOnly
+}
+
+func (s *mockService) VoucherEmail(code string) (string, error) {
+	if s.vouchers == nil {
+		return "", domain.ErrVouchersDisabled
+	}
+	email, ok := s.vouchers[code]
+	if !ok {
+		return "", domain.ErrInvalidVoucher
+	}
+	return email, nil
+}
+
+func (s *mockService) ApplyOfflineRedemptions(ctx context.Context, entries []domain.OfflineRedemption) []domain.OfflineRedemptionResult {
+	var results []domain.OfflineRedemptionResult
+	for _, entry := range entries {
+		status, ok := s.offlineStatus[entry.Email]
+		if !ok {
+			status = "redeemed"
+		}
+		results = append(results, domain.OfflineRedemptionResult{Email: entry.Email, Status: status})
+	}
+	return results
+}
+
+func (s *mockService) UserAsOf(ctx context.Context, id string, asOf time.Time) (*domain.User, string, error) {
+	s.userAsOf = asOf
+	if id != "7" {
+		return nil, "", domain.ErrUserNotFound
+	}
+	if asOf.IsZero() {
+		return &domain.User{ID: "7", Email: "guest@example.com"}, "current", nil
+	}
+	return &domain.User{ID: "7", Email: "guest@example.com"}, "audit", nil
+}
+
+func (s *mockService) PatchUser(ctx context.Context, id string, patch domain.UserPatch) (*domain.User, error) {
+	s.userPatch = patch
+	patch, err := domain.NormalizeUserPatch(patch)
+	if err != nil {
+		return nil, err
+	}
+	if id != "7" {
+		return nil, domain.ErrUserNotFound
+	}
+	user := &domain.User{ID: "7", Email: "guest@example.com"}
+	if patch.Email != nil {
+		if *patch.Email == "taken@example.com" {
+			return nil, domain.NewDuplicateUserError(*patch.Email, "8")
+		}
+		user.Email = *patch.Email
+	}
+	if patch.Tags != nil {
+		user.Tags = *patch.Tags
+	}
+	return user, nil
+}
+
+func (s *mockService) MergeUsers(ctx context.Context, keepID, duplicateID, reason string) (*domain.User, *domain.User, error) {
+	if keepID != "7" || duplicateID != "8" {
+		return nil, nil, domain.ErrUserNotFound
+	}
+	keep := &domain.User{ID: "7", Email: "guest@example.com", Tags: []string{"vip"}}
+	duplicate := &domain.User{ID: "8", Email: "gest@example.com", Tags: []string{domain.MergedTag}, Notes: "Merged into guest@example.com (#7)"}
+	return keep, duplicate, nil
+}
+
+func (s *mockService) ImportQueueEnabled() bool {
+	return s.importQueue
+}
+
+func (s *mockService) MaxImportEmails() int {
+	return 3
+}
+
+func (s *mockService) addJob(job jobs.Job) jobs.Job {
+	if s.jobs == nil {
+		s.jobs = make(map[string]jobs.Job)
+	}
+	job.ID = fmt.Sprintf("job%d", len(s.jobs)+1)
+	s.jobs[job.ID] = job
+	return job
+}
+
+func (s *mockService) EnqueueImport(ctx context.Context, actor string, emails []string) (jobs.Job, error) {
+	if !s.importQueue {
+		return jobs.Job{}, domain.ErrImportQueueDisabled
+	}
+	return s.addJob(jobs.Job{Type: jobs.TypeImport, Status: jobs.StatusQueued, Actor: actor, Total: len(emails)}), nil
+}
+
+func (s *mockService) StartImport(ctx context.Context, actor, name string, data []byte, opts importer.Options) (jobs.Job, error) {
+	if err := opts.Validate(); err != nil {
+		return jobs.Job{}, domain.NewValidationError("mapping", err.Error())
+	}
+	s.importOptions = opts
+	return s.addJob(jobs.Job{Type: jobs.TypeImport, Name: name, Status: jobs.StatusRunning, Actor: actor, Total: strings.Count(string(data), "\n")}), nil
+}
+
+func (s *mockService) Job(id string) (jobs.Job, error) {
+	job, ok := s.jobs[id]
+	if !ok {
+		return jobs.Job{}, jobs.ErrNotFound
+	}
+	return job, nil
+}
+
+func (s *mockService) Jobs() []jobs.Job {
+	var list []jobs.Job
+	for i := len(s.jobs); i > 0; i-- {
+		list = append(list, s.jobs[fmt.Sprintf("job%d", i)])
+	}
+	return list
+}
+
+func (s *mockService) CancelJob(ctx context.Context, actor, id string) (jobs.Job, error) {
+	job, err := s.Job(id)
+	if err != nil {
+		return job, err
+	}
+	if !job.Active() {
+		return job, jobs.ErrFinished
+	}
+	job.Status = jobs.StatusCanceled
+	s.jobs[id] = job
+	return job, nil
+}
+
+func (s *mockService) StartMigration(ctx context.Context, actor, name string) (jobs.Job, error) {
+	if name != "normalize-emails" {
+		return jobs.Job{}, domain.ErrMigrationUnknown
+	}
+	return s.addJob(jobs.Job{Type: jobs.TypeMigration, Name: name, Status: jobs.StatusRunning, Actor: actor}), nil
+}
+
+func (s *mockService) Features() []featureflags.Flag {
+	return s.features.List()
+}
+
+func (s *mockService) ToggleFeature(ctx context.Context, name featureflags.Name, enabled *bool, actor string) (featureflags.Flag, error) {
+	if enabled == nil {
+		return s.features.Reset(name)
+	}
+	return s.features.Toggle(name, *enabled)
+}
+
+func (s *mockService) RateLimitStats(top int) ratelimit.Stats {
+	return ratelimit.Stats{
+		RequestsPerMinute: 10,
+		RequestsPerHour:   100,
+		TrackedClients:    1,
+		Allowed:           12,
+		RejectedMinute:    3,
+		TopConsumers:      []ratelimit.Consumer{{Key: "42", LastMinute: 2, LastHour: 9, Rejected: 3}},
+	}
+}
+
 func (s *mockService) Close() error {
 	return nil
 }
 
 // createTestServer creates a server for testing
-func createTestServer(t *testing.T, svc ServiceInterface) (*Server, *httptest.Server) {
+func createTestServer(t *testing.T, svc ports.Service) (*Server, *httptest.Server) {
 	// Create test configuration
 	cfg := &config.Config{
 		API: config.APIConfig{
 			Enabled:          true,
 			Port:             8080,
 			AuthTokens:       []string{"test_token"},
+			AdminTokens:      []string{"admin_token"},
 			RateLimitPerMin:  60,
 			RateLimitPerHour: 600,
+			PublicEndpoints:  []string{"/api/health"},
+
+			TokenRateLimitPerMin:  240,
+			TokenRateLimitPerHour: 2400,
 		},
 	}
 
@@ -92,15 +440,8 @@ func createTestServer(t *testing.T, svc ServiceInterface) (*Server, *httptest.Se
 		t.Fatalf("Failed to create server: %v", err)
 	}
 
-	// Create test HTTP server
-	mux := http.NewServeMux()
-	mux.HandleFunc("/api/v1/email", server.handleEmail)
-	mux.HandleFunc("/api/v1/report/redeemed", server.handleReportRedeemed)
-	mux.HandleFunc("/api/v1/report/added", server.handleReportAdded)
-	mux.HandleFunc("/api/v1/report/all", server.handleReportAll)
-	mux.HandleFunc("/api/health", server.handleHealth)
-
-	ts := httptest.NewServer(mux)
+	// Serve the full handler chain so middlewares are exercised
+	ts := httptest.NewServer(server.httpServer.Handler)
 	return server, ts
 }
 
@@ -178,6 +519,37 @@ func TestEmailEndpoint_InvalidAuth(t *testing.T) {
 	}
 }
 
+func TestHashedTokens(t *testing.T) {
+	a := NewAuthProviderWithAdmins([]string{HashToken("door_token"), "plain_token", "sha256:beef"}, []string{HashToken("admin_token")})
+
+	if !a.Authenticate("door_token") || !a.Authenticate("plain_token") || !a.Authenticate("admin_token") {
+		t.Error("Expected hashed and plain tokens to be accepted")
+	}
+	if a.Authenticate(HashToken("door_token")) || a.Authenticate("sha256:beef") || a.Authenticate("") {
+		t.Error("Expected hashes and invalid entries not to work as tokens")
+	}
+	if !a.IsAdmin("admin_token") || a.IsAdmin("door_token") {
+		t.Error("Expected only the hashed admin token to be admin")
+	}
+	if err := CheckTokens([]string{"plain_token", "sha256:beef"}); err == nil {
+		t.Error("Expected an invalid hash to be reported")
+	}
+
+	// Fingerprints are the same whether the token is configured hashed or not
+	if EntryFingerprint(HashToken("door_token")) != TokenFingerprint("door_token") {
+		t.Error("Expected the fingerprint of the hash to match the token's")
+	}
+	fingerprints := a.Fingerprints()
+	if len(fingerprints) != 3 || !slices.Contains(fingerprints, TokenFingerprint("door_token")) {
+		t.Errorf("Unexpected fingerprints: %v", fingerprints)
+	}
+
+	a.RemoveToken("door_token")
+	if a.Authenticate("door_token") {
+		t.Error("Expected a removed token to be refused")
+	}
+}
+
 func TestEmailEndpoint_InvalidEmail(t *testing.T) {
 	svc := &mockService{}
 	_, ts := createTestServer(t, svc)
@@ -310,6 +682,93 @@ func TestEmailEndpoint_ValidNew(t *testing.T) {
 	if svc.addUserPayload != nil && svc.addUserPayload.Email != "new@example.com" {
 		t.Errorf("Expected email to be normalized to 'new@example.com', got %s", svc.addUserPayload.Email)
 	}
+
+	// The token that added the email is recorded
+	if svc.addUserPayload != nil && svc.addUserPayload.CreatedBy != "token:"+TokenFingerprint("test_token") {
+		t.Errorf("Expected the creating token to be recorded, got %q", svc.addUserPayload.CreatedBy)
+	}
+}
+
+func TestOperatorHeader(t *testing.T) {
+	svc := &mockService{findEmailStatus: "not_found"}
+	server, ts := createTestServer(t, svc)
+	defer ts.Close()
+	server.config.API.Operators = []string{"Anna", "bar-2"}
+
+	add := func(operator string) int {
+		t.Helper()
+		req, _ := http.NewRequest("POST", ts.URL+"/api/v1/email", strings.NewReader(`{"email":"new@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer test_token")
+		if operator != "" {
+			req.Header.Set("X-Operator", operator)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Names on the roster are recorded as listed
+	if status := add(" anna "); status != http.StatusCreated || svc.addUserOperator != "Anna" {
+		t.Errorf("Expected the operator Anna to be recorded, got %d with %q", status, svc.addUserOperator)
+	}
+
+	// The header is optional
+	if status := add(""); status != http.StatusCreated || svc.addUserOperator != "" {
+		t.Errorf("Expected no operator without the header, got %d with %q", status, svc.addUserOperator)
+	}
+
+	svc.addUserCalled = false
+	if status := add("Mallory"); status != http.StatusForbidden || svc.addUserCalled {
+		t.Errorf("Expected an operator missing from the roster to be refused, got %d", status)
+	}
+	if status := add(strings.Repeat("x", 65)); status != http.StatusBadRequest {
+		t.Errorf("Expected a name that is too long to be refused, got %d", status)
+	}
+
+	// Without a roster any name is recorded
+	server.config.API.Operators = nil
+	if status := add("Mallory"); status != http.StatusCreated || svc.addUserOperator != "Mallory" {
+		t.Errorf("Expected any operator without a roster, got %d with %q", status, svc.addUserOperator)
+	}
+}
+
+func TestEmailEndpoint_ConcurrentDuplicate(t *testing.T) {
+	// The status check found nothing, but a concurrent request added the
+	// email before this one
+	svc := &mockService{
+		findEmailStatus: "not_found",
+		addUserError:    domain.NewDuplicateUserError("race@example.com", "winner_id"),
+	}
+
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	jsonData, _ := json.Marshal(map[string]string{"email": "race@example.com"})
+	req, _ := http.NewRequest("POST", ts.URL+"/api/v1/email", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer test_token")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", resp.StatusCode)
+	}
+
+	var emailResp EmailResponse
+	if err := json.NewDecoder(resp.Body).Decode(&emailResp); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	if emailResp.Status != "exists" || emailResp.ID != "winner_id" {
+		t.Errorf("Expected the ID of the winning request, got %+v", emailResp)
+	}
 }
 
 func TestReportEndpoints_Unauthorized(t *testing.T) {
@@ -321,6 +780,7 @@ func TestReportEndpoints_Unauthorized(t *testing.T) {
 		"/api/v1/report/redeemed",
 		"/api/v1/report/added",
 		"/api/v1/report/all",
+		"/api/v1/report/consented",
 	}
 
 	for _, endpoint := range endpoints {
@@ -379,6 +839,7 @@ func TestReportEndpoint_Success(t *testing.T) {
 		{"/api/v1/report/redeemed", "redeemed"},
 		{"/api/v1/report/added", "added"},
 		{"/api/v1/report/all", "all"},
+		{"/api/v1/report/consented", "consented"},
 	}
 
 	for _, endpoint := range endpoints {
@@ -479,95 +940,508 @@ func TestReportEndpoint_DateParams(t *testing.T) {
 	}
 }
 
-func TestReportEndpoint_CSVFormat(t *testing.T) {
-	// Create test users
-	now := time.Now()
-	testUsers := []*domain.User{
-		{
-			ID:        "1",
-			Email:     "user1@example.com",
-			DateAdded: now.AddDate(0, 0, -1),
-			Redeemed:  nil,
-		},
-		{
-			ID:        "2",
-			Email:     "user2@example.com",
-			DateAdded: now.AddDate(0, 0, -2),
-			Redeemed:  &now,
-		},
+func TestReportEndpoint_Paging(t *testing.T) {
+	var users []*domain.User
+	for i := 0; i < 5; i++ {
+		users = append(users, &domain.User{ID: strconv.Itoa(i), Email: fmt.Sprintf("user%d@example.com", i), DateAdded: time.Now()})
+	}
+	svc := &mockService{generateReportUsers: users}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	get := func(query string) (*http.Response, ReportResponse) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/all?"+query, nil)
+		req.Header.Set("Authorization", "Bearer test_token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		defer resp.Body.Close()
+		var report ReportResponse
+		json.NewDecoder(resp.Body).Decode(&report)
+		return resp, report
 	}
 
-	svc := &mockService{
-		generateReportUsers: testUsers,
+	// Without a limit the whole report is returned
+	if _, report := get(""); report.Total != 5 || report.Count != 5 {
+		t.Errorf("Expected all users, got total %d, count %d", report.Total, report.Count)
+	}
+	if _, report := get("offset=4&limit=2"); report.Total != 5 || report.Count != 1 || report.Users[0].ID != "4" {
+		t.Errorf("Expected the last page, got %+v", report)
+	}
+	if _, report := get("offset=9&limit=2"); report.Total != 5 || report.Count != 0 {
+		t.Errorf("Expected an empty page past the end, got %+v", report)
+	}
+	for _, query := range []string{"limit=0", "limit=1001", "offset=-1", "limit=x"} {
+		if resp, _ := get(query); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, resp.StatusCode)
+		}
 	}
+}
 
+func TestReportEndpoint_CountOnly(t *testing.T) {
+	users := []*domain.User{{ID: "1", Email: "a@example.com"}, {ID: "2", Email: "b@example.com"}}
+	svc := &mockService{generateReportUsers: users}
 	_, ts := createTestServer(t, svc)
 	defer ts.Close()
 
-	// Test CSV format
-	req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/all?format=csv", nil)
+	req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/redeemed?count_only=true&domain=example.com", nil)
 	req.Header.Set("Authorization", "Bearer test_token")
-	
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		t.Fatalf("Error making request: %v", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	var report ReportResponse
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
 	}
-
-	// Check content type
-	contentType := resp.Header.Get("Content-Type")
-	if contentType != "text/csv" {
-		t.Errorf("Expected Content-Type 'text/csv', got '%s'", contentType)
+	if resp.StatusCode != http.StatusOK || report.Total != 2 || report.Count != 0 || len(report.Users) != 0 {
+		t.Errorf("Expected only the total, got %d %+v", resp.StatusCode, report)
 	}
-
-	// Check for content disposition header
-	contentDisposition := resp.Header.Get("Content-Disposition")
-	if !strings.Contains(contentDisposition, "attachment") || !strings.Contains(contentDisposition, "filename=") {
-		t.Errorf("Expected Content-Disposition header with attachment and filename, got '%s'", contentDisposition)
+	if !svc.countReportCalled || svc.generateReportCalled {
+		t.Error("Expected the report to be counted without loading users")
 	}
+	if svc.generateReportType != "redeemed" || svc.generateReportFilter.Domain != "example.com" {
+		t.Errorf("Expected the type and filter to be passed on, got %s %+v", svc.generateReportType, svc.generateReportFilter)
+	}
+}
 
-	// Read the CSV data
-	body, err := io.ReadAll(resp.Body)
+func TestReportEndpoint_Compare(t *testing.T) {
+	svc := &mockService{countReportTotals: []int{12, 8}}
+	server, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	calendar, err := period.New(config.ReportsConfig{Timezone: "Europe/Berlin"}, "")
 	if err != nil {
-		t.Fatalf("Error reading response body: %v", err)
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	server.calendar = calendar
+
+	get := func(query string) (int, ReportResponse) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/redeemed"+query, nil)
+		req.Header.Set("Authorization", "Bearer test_token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		defer resp.Body.Close()
+		var report ReportResponse
+		json.NewDecoder(resp.Body).Decode(&report)
+		return resp.StatusCode, report
 	}
 
-	// Check CSV content
-	csvContent := string(body)
-	if !strings.Contains(csvContent, "ID,Email,DateAdded,Redeemed") {
-		t.Error("CSV header not found in response")
+	// A week of dates is compared with the week before
+	status, report := get("?from=2025-06-09&to=2025-06-15&count_only=true&compare=previous")
+	if status != http.StatusOK || report.Compare == nil {
+		t.Fatalf("Expected a comparison, got %d %+v", status, report)
+	}
+	if report.Total != 12 || report.Compare.Total != 8 || report.Compare.Change != 4 {
+		t.Errorf("Expected 12 against 8, got %+v", report.Compare)
+	}
+	berlin := calendar.Location()
+	previous := svc.countReportRanges[1]
+	if !previous[0].Equal(time.Date(2025, 6, 2, 0, 0, 0, 0, berlin)) || !previous[1].Equal(time.Date(2025, 6, 8, 23, 59, 59, 0, berlin)) {
+		t.Errorf("Expected the week before to be counted, got %v", previous)
 	}
 
-	for _, user := range testUsers {
-		if !strings.Contains(csvContent, user.ID) || !strings.Contains(csvContent, user.Email) {
-			t.Errorf("User data not found in CSV: %s, %s", user.ID, user.Email)
+	// Explicit ranges are whole days, and full reports are compared too
+	svc.countReportRanges, svc.countReportTotals = nil, nil
+	status, report = get("?from=2025-06-09&to=2025-06-15&compare_from=2025-05-01&compare_to=2025-05-01")
+	if status != http.StatusOK || report.Compare == nil || len(svc.countReportRanges) != 1 {
+		t.Fatalf("Expected the compared range to be counted, got %d %+v", status, report)
+	}
+	if want := time.Date(2025, 5, 1, 23, 59, 59, 0, berlin); !svc.countReportRanges[0][1].Equal(want) {
+		t.Errorf("Expected the comparison to end at %v, got %v", want, svc.countReportRanges[0][1])
+	}
+
+	for _, query := range []string{"?compare=last_year", "?compare=previous&compare_from=2025-05-01", "?compare_from=2025-05-01"} {
+		if status, _ := get(query); status != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, status)
 		}
 	}
 }
 
-func TestReportEndpoint_InvalidDate(t *testing.T) {
-	svc := &mockService{}
-	_, ts := createTestServer(t, svc)
+func TestReportEndpoint_Period(t *testing.T) {
+	svc := &mockService{generateReportUsers: []*domain.User{}}
+	server, ts := createTestServer(t, svc)
 	defer ts.Close()
 
-	// Test with invalid date format
-	req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/all?from=invalid-date", nil)
-	req.Header.Set("Authorization", "Bearer test_token")
-	
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	calendar, err := period.New(config.ReportsConfig{Timezone: "Europe/Berlin"}, "2025-06-01")
 	if err != nil {
-		t.Fatalf("Error making request: %v", err)
+		t.Fatalf("Failed to create calendar: %v", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusBadRequest {
-		t.Errorf("Expected status 400, got %d", resp.StatusCode)
+	server.calendar = calendar
+
+	get := func(query string) int {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/all"+query, nil)
+		req.Header.Set("Authorization", "Bearer test_token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := get("?period=event"); status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	if want := time.Date(2025, 5, 31, 22, 0, 0, 0, time.UTC); !svc.generateReportFrom.Equal(want) {
+		t.Errorf("Expected the event period to start at %v, got %v", want, svc.generateReportFrom)
+	}
+
+	// Explicit dates are days in the configured timezone
+	if status := get("?from=2025-06-10&to=2025-06-10"); status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	if want := time.Date(2025, 6, 10, 21, 59, 59, 0, time.UTC); !svc.generateReportTo.Equal(want) {
+		t.Errorf("Expected the range to end at %v, got %v", want, svc.generateReportTo)
+	}
+
+	for _, query := range []string{"?period=fortnight", "?period=week&from=2025-06-01"} {
+		if status := get(query); status != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, status)
+		}
+	}
+}
+
+func TestReportEndpoint_Filters(t *testing.T) {
+	svc := &mockService{
+		generateReportUsers: []*domain.User{},
+	}
+
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	client := &http.Client{}
+	get := func(query string) int {
+		req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/redeemed?"+query, nil)
+		req.Header.Set("Authorization", "Bearer test_token")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := get("domain=@Example.COM&source=token"); status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	want := domain.ReportFilter{Domain: "example.com", Source: "token"}
+	if svc.generateReportFilter != want {
+		t.Errorf("Expected filter %+v, got %+v", want, svc.generateReportFilter)
+	}
+
+	// Wildcards could widen the match of SQL repositories
+	svc.generateReportCalled = false
+	if status := get("domain=%25.com"); status != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid domain, got %d", status)
+	}
+	if svc.generateReportCalled {
+		t.Error("Expected no report for an invalid domain")
+	}
+}
+
+func TestReportBarsEndpoint(t *testing.T) {
+	svc := &mockService{
+		barRedemptions: []domain.BarRedemptions{{Bar: "Main Bar", Redeemed: 3}, {Bar: "Rooftop", Redeemed: 1}},
+	}
+
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/bars?domain=example.com", nil)
+	req.Header.Set("Authorization", "Bearer test_token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var report BarReportResponse
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	if report.Count != 4 || len(report.Bars) != 2 || report.Bars[0].Bar != "Main Bar" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if svc.generateReportFilter.Domain != "example.com" {
+		t.Errorf("Expected the domain filter passed on, got %+v", svc.generateReportFilter)
+	}
+}
+
+func TestRedemptionLatencyEndpoint(t *testing.T) {
+	svc := &mockService{
+		latency: domain.RedemptionLatency{
+			Event:      "Launch",
+			Count:      3,
+			P50Seconds: 7200,
+			Buckets:    []domain.LatencyBucket{{Label: "<1h", UpToSeconds: 3600, Count: 1}, {Label: "1-6h", UpToSeconds: 21600, Count: 2}},
+		},
+	}
+
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/api/v1/stats/latency?bar=Rooftop", nil)
+	req.Header.Set("Authorization", "Bearer test_token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var latency LatencyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&latency); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	if latency.Count != 3 || latency.P50Seconds != 7200 || len(latency.Buckets) != 2 || latency.From == "" {
+		t.Errorf("Unexpected latency: %+v", latency)
+	}
+	if svc.generateReportFilter.Bar != "Rooftop" {
+		t.Errorf("Expected the bar filter passed on, got %+v", svc.generateReportFilter)
+	}
+}
+
+func TestIntrospectEndpoint(t *testing.T) {
+	server, ts := createTestServer(t, &mockService{})
+	defer ts.Close()
+	server.config.Event.Name = "Launch"
+
+	tests := []struct {
+		name        string
+		caller      string // Token the request is made with
+		method      string
+		body        string
+		wantStatus  int
+		wantValid   bool
+		wantScopes  []string
+		wantSubject string // Token whose fingerprint is returned
+		wantOp      string
+	}{
+		{"own token", "test_token", "GET", "", http.StatusOK, true, []string{ScopeAPI}, "test_token", "alice"},
+		{"own admin token", "admin_token", "GET", "", http.StatusOK, true, []string{ScopeAPI, ScopeAdmin}, "admin_token", "alice"},
+		{"invalid caller", "bad_token", "GET", "", http.StatusUnauthorized, false, nil, "", ""},
+		{"missing caller", "", "POST", `{"token":"test_token"}`, http.StatusUnauthorized, false, nil, "", ""},
+		{"invalid caller with subject", "bad_token", "POST", `{"token":"test_token"}`, http.StatusUnauthorized, false, nil, "", ""},
+		{"valid subject", "admin_token", "POST", `{"token":"test_token"}`, http.StatusOK, true, []string{ScopeAPI}, "test_token", ""},
+		{"own token as subject", "test_token", "POST", `{"token":"test_token"}`, http.StatusOK, true, []string{ScopeAPI}, "test_token", "alice"},
+		{"invalid subject", "admin_token", "POST", `{"token":"expired_token"}`, http.StatusOK, false, []string{}, "", ""},
+		{"other token without admin", "test_token", "POST", `{"token":"admin_token"}`, http.StatusForbidden, false, nil, "", ""},
+		{"invalid token without admin", "test_token", "POST", `{"token":"expired_token"}`, http.StatusForbidden, false, nil, "", ""},
+		{"missing subject", "test_token", "POST", `{}`, http.StatusBadRequest, false, nil, "", ""},
+		{"wrong method", "test_token", "DELETE", "", http.StatusMethodNotAllowed, false, nil, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, ts.URL+"/api/v1/auth/introspect", strings.NewReader(tt.body))
+			if tt.caller != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.caller)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Operator", "alice")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Error making request: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+
+			var info IntrospectResponse
+			if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+				t.Fatalf("Error decoding response: %v", err)
+			}
+			if info.Valid != tt.wantValid || info.Active != tt.wantValid || !reflect.DeepEqual(info.Scopes, tt.wantScopes) || info.Expires != nil {
+				t.Errorf("Unexpected introspection: %+v", info)
+			}
+			wantFingerprint := ""
+			if tt.wantSubject != "" {
+				wantFingerprint = TokenFingerprint(tt.wantSubject)
+			}
+			if info.Fingerprint != wantFingerprint || info.Operator != tt.wantOp {
+				t.Errorf("Expected fingerprint %q and operator %q, got %+v", wantFingerprint, tt.wantOp, info)
+			}
+			wantEvents := []string{}
+			if tt.wantValid {
+				wantEvents = []string{"Launch"}
+			}
+			if !reflect.DeepEqual(info.Events, wantEvents) {
+				t.Errorf("Expected events %v, got %v", wantEvents, info.Events)
+			}
+		})
+	}
+}
+
+func TestReportDatasetEndpoint(t *testing.T) {
+	added := time.Date(2025, 6, 14, 18, 42, 0, 0, time.UTC)
+	svc := &mockService{
+		generateReportUsers: []*domain.User{{ID: "1", Email: "guest@example.com", DateAdded: added, CreatedBy: "token:3f2a9c1b"}},
+	}
+	server, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	get := func(query, token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/dataset"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	// Without a salt there is no dataset
+	resp := get("", "test_token")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 without a salt, got %d", resp.StatusCode)
+	}
+
+	server.config.Reports.Dataset.Salt = "pepper"
+	resp = get("?domain=example.com", "test_token")
+	var dataset DatasetResponse
+	if err := json.NewDecoder(resp.Body).Decode(&dataset); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || dataset.Mode != "hashed" || dataset.Count != 1 {
+		t.Fatalf("Unexpected response %d %+v", resp.StatusCode, dataset)
+	}
+	record := dataset.Records[0]
+	if strings.Contains(record.Guest, "@") || record.Source != "token" || record.Added.Minute() != 0 {
+		t.Errorf("Expected an anonymized record, got %+v", record)
+	}
+	if svc.generateReportFilter.Domain != "example.com" {
+		t.Errorf("Expected the domain filter passed on, got %+v", svc.generateReportFilter)
+	}
+
+	// Reversible datasets need an admin token
+	resp = get("?mode=reversible", "test_token")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a reversible dataset without admin token, got %d", resp.StatusCode)
+	}
+	resp = get("?mode=reversible&format=csv", "admin_token")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "r1.") || strings.Contains(string(body), "guest@") {
+		t.Errorf("Unexpected reversible dataset %d:\n%s", resp.StatusCode, body)
+	}
+
+	resp = get("?mode=plain", "admin_token")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown mode, got %d", resp.StatusCode)
+	}
+}
+
+func TestReportEndpoint_CSVFormat(t *testing.T) {
+	// Create test users
+	now := time.Now()
+	testUsers := []*domain.User{
+		{
+			ID:        "1",
+			Email:     "user1@example.com",
+			DateAdded: now.AddDate(0, 0, -1),
+			Redeemed:  nil,
+		},
+		{
+			ID:        "2",
+			Email:     "user2@example.com",
+			DateAdded: now.AddDate(0, 0, -2),
+			Redeemed:  &now,
+		},
+	}
+
+	svc := &mockService{
+		generateReportUsers: testUsers,
+	}
+
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	// Test CSV format
+	req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/all?format=csv", nil)
+	req.Header.Set("Authorization", "Bearer test_token")
+	
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	// Check content type
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "text/csv" {
+		t.Errorf("Expected Content-Type 'text/csv', got '%s'", contentType)
+	}
+
+	// Check for content disposition header
+	contentDisposition := resp.Header.Get("Content-Disposition")
+	if !strings.Contains(contentDisposition, "attachment") || !strings.Contains(contentDisposition, "filename=") {
+		t.Errorf("Expected Content-Disposition header with attachment and filename, got '%s'", contentDisposition)
+	}
+
+	// Read the CSV data
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Error reading response body: %v", err)
+	}
+
+	// Check CSV content
+	csvContent := string(body)
+	if !strings.Contains(csvContent, "ID,Email,DateAdded,Redeemed") {
+		t.Error("CSV header not found in response")
+	}
+
+	for _, user := range testUsers {
+		if !strings.Contains(csvContent, user.ID) || !strings.Contains(csvContent, user.Email) {
+			t.Errorf("User data not found in CSV: %s, %s", user.ID, user.Email)
+		}
+	}
+}
+
+func TestReportEndpoint_InvalidDate(t *testing.T) {
+	svc := &mockService{}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	// Test with invalid date format
+	req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/all?from=invalid-date", nil)
+	req.Header.Set("Authorization", "Bearer test_token")
+	
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", resp.StatusCode)
 	}
 
 	// Check error response
@@ -581,37 +1455,1726 @@ func TestReportEndpoint_InvalidDate(t *testing.T) {
 	}
 }
 
-func TestServer_Start_Stop(t *testing.T) {
-	// Mock service
-	svc := &mockService{}
-
-	// Create test configuration
-	cfg := &config.Config{
-		API: config.APIConfig{
-			Enabled: true,
-			Port:    8099, // Use a different port to avoid conflicts
+func TestReportChanges(t *testing.T) {
+	since := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	latest := since.Add(90 * time.Second)
+	svc := &mockService{
+		generateReportUsers: []*domain.User{
+			{ID: "1", Email: "user1@example.com", DateAdded: since, UpdatedAt: since.Add(time.Second)},
+			{ID: "2", Email: "user2@example.com", DateAdded: since, UpdatedAt: latest},
 		},
 	}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
 
-	// Create logger
-	log := logger.New("debug")
+	get := func(query string) *http.Response {
+		req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/changes"+query, nil)
+		req.Header.Set("Authorization", "Bearer test_token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
 
-	// Create server
-	server, err := New(cfg, svc, log)
-	if err != nil {
-		t.Fatalf("Failed to create server: %v", err)
+	resp := get("?since=" + since.Format(time.RFC3339))
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var changes ChangesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&changes); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	if svc.generateReportType != "changed" {
+		t.Errorf("Expected report type changed, got %s", svc.generateReportType)
+	}
+	if !svc.generateReportFrom.Equal(since.Add(time.Nanosecond)) {
+		t.Errorf("Expected changes strictly after %v, got from %v", since, svc.generateReportFrom)
+	}
+	if changes.Count != 2 || changes.Cursor != strconv.FormatInt(latest.UnixNano(), 10) {
+		t.Errorf("Unexpected changes response: %+v", changes)
 	}
 
-	// Start server
-	if err := server.Start(); err != nil {
-		t.Fatalf("Failed to start server: %v", err)
+	// The cursor is accepted as since
+	resp = get("?since=" + changes.Cursor)
+	resp.Body.Close()
+	if !svc.generateReportFrom.Equal(latest.Add(time.Nanosecond)) {
+		t.Errorf("Expected changes after the cursor, got from %v", svc.generateReportFrom)
 	}
 
-	// Give server a moment to start
-	time.Sleep(100 * time.Millisecond)
+	resp = get("?since=yesterday")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid since, got %d", resp.StatusCode)
+	}
+}
 
-	// Stop server
-	if err := server.Stop(); err != nil {
-		t.Fatalf("Failed to stop server: %v", err)
+func TestRateLimitReset(t *testing.T) {
+	svc := &mockService{}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	tests := []struct {
+		name           string
+		token          string
+		payload        string
+		expectedStatus int
+		expectedUserID int64
+	}{
+		{"Missing auth", "", `{"user_id": 42}`, http.StatusUnauthorized, 0},
+		{"Non-admin token", "test_token", `{"user_id": 42}`, http.StatusForbidden, 0},
+		{"Empty payload", "admin_token", `{}`, http.StatusBadRequest, 0},
+		{"Reset user", "admin_token", `{"user_id": 42}`, http.StatusOK, 42},
+		{"Reset client IP", "admin_token", `{"client_ip": "10.0.0.1"}`, http.StatusOK, 0},
+		{"Reset token", "admin_token", `{"token": "3f2a9c1b"}`, http.StatusOK, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc.resetUserID = 0
+
+			req, _ := http.NewRequest("POST", ts.URL+"/api/v1/admin/ratelimit/reset", strings.NewReader(tt.payload))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Error making request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+
+			if svc.resetUserID != tt.expectedUserID {
+				t.Errorf("Expected reset for user %d, got %d", tt.expectedUserID, svc.resetUserID)
+			}
+		})
+	}
+}
+
+func TestRateLimitDimensions(t *testing.T) {
+	cfg := &config.Config{
+		API: config.APIConfig{
+			AuthTokens:       []string{"terminal_1", "terminal_2"},
+			AdminTokens:      []string{"admin_token"},
+			RateLimitPerMin:  2,
+			RateLimitPerHour: 100,
+			TrustedProxies:   []string{"127.0.0.1"},
+
+			TokenRateLimitPerMin:  3,
+			TokenRateLimitPerHour: 100,
+		},
+	}
+	server, err := New(cfg, &mockService{findEmailStatus: "eligible"}, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	ts := httptest.NewServer(server.httpServer.Handler)
+	defer ts.Close()
+
+	get := func(token, ip string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+"/api/v1/email/status?email=guest@example.com", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Forwarded-For", ip)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var body ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		if resp.StatusCode == http.StatusTooManyRequests && resp.Header.Get("X-RateLimit-Scope") != body.Limit {
+			t.Errorf("Scope header %q does not match body %q", resp.Header.Get("X-RateLimit-Scope"), body.Limit)
+		}
+		if retry, _ := strconv.Atoi(resp.Header.Get("Retry-After")); resp.StatusCode == http.StatusTooManyRequests && (retry < 1 || retry > 60) {
+			t.Errorf("Expected a Retry-After within a minute, got %q", resp.Header.Get("Retry-After"))
+		}
+		return resp.StatusCode, body.Limit
+	}
+
+	// Terminals behind one address have their own client limit
+	steps := []struct {
+		token, ip string
+		status    int
+		limit     string
+	}{
+		{"terminal_1", "203.0.113.7", http.StatusOK, ""},
+		{"terminal_1", "203.0.113.7", http.StatusOK, ""},
+		{"terminal_1", "203.0.113.7", http.StatusTooManyRequests, "ip"},
+		{"terminal_2", "203.0.113.7", http.StatusOK, ""},
+		{"terminal_2", "203.0.113.7", http.StatusOK, ""},
+		// A token is also limited across addresses
+		{"terminal_1", "198.51.100.1", http.StatusOK, ""},
+		{"terminal_1", "198.51.100.2", http.StatusTooManyRequests, "token"},
+	}
+	for i, step := range steps {
+		status, limit := get(step.token, step.ip)
+		if status != step.status || limit != step.limit {
+			t.Errorf("Step %d: expected %d %q, got %d %q", i, step.status, step.limit, status, limit)
+		}
+	}
+
+	// Resetting the address lifts the client limit of every token
+	req, _ := http.NewRequest("POST", ts.URL+"/api/v1/admin/ratelimit/reset", strings.NewReader(`{"client_ip": "203.0.113.7"}`))
+	req.Header.Set("Authorization", "Bearer admin_token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	resp.Body.Close()
+	if status, _ := get("terminal_2", "203.0.113.7"); status != http.StatusOK {
+		t.Errorf("Expected the reset to lift the limit, got %d", status)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	if err := SetTrustedProxies([]string{"10.0.0.0/8", "2001:db8:ffff::1"}); err != nil {
+		t.Fatalf("Failed to set trusted proxies: %v", err)
+	}
+	defer SetTrustedProxies(nil)
+
+	tests := []struct {
+		forwarded, realIP, remote string
+		want                      string
+	}{
+		{"", "", "203.0.113.7:51234", "203.0.113.7"},
+		{"", "", "[2001:DB8:0:0::7]:51234", "2001:db8::7"},
+		{"", "", "[fe80::1%eth0]:51234", "fe80::1"},
+		{"::ffff:203.0.113.7", "", "10.0.0.1:443", "203.0.113.7"},
+		{"[2001:db8::7]:443, 10.0.0.2", "", "10.0.0.1:443", "2001:db8::7"},
+		{"203.0.113.7:51234", "", "10.0.0.1:443", "203.0.113.7"},
+		{"203.0.113.7", "", "[2001:db8:ffff::1]:443", "203.0.113.7"},
+		{"", "203.0.113.7", "10.0.0.1:443", "203.0.113.7"},
+		{"unknown", "", "10.0.0.1:443", "10.0.0.1"},
+		{"10.0.0.3, 10.0.0.2", "", "10.0.0.1:443", "10.0.0.3"},
+		{"203.0.113.7, unknown", "198.51.100.1", "10.0.0.1:443", "198.51.100.1"},
+
+		// Entries left of the first untrusted address came from the client
+		{"198.51.100.1, 203.0.113.7", "", "10.0.0.1:443", "203.0.113.7"},
+		{"198.51.100.1, 203.0.113.7, 10.0.0.2", "", "10.0.0.1:443", "203.0.113.7"},
+		{"10.0.0.9, 203.0.113.7", "", "10.0.0.1:443", "203.0.113.7"},
+
+		// Peers outside the trusted networks cannot claim another address
+		{"198.51.100.1", "", "203.0.113.7:51234", "203.0.113.7"},
+		{"", "198.51.100.1", "203.0.113.7:51234", "203.0.113.7"},
+		{"10.0.0.1", "", "[2001:db8:ffff::2]:443", "2001:db8:ffff::2"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tt.remote
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if tt.realIP != "" {
+			req.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if got := ClientIP(req); got != tt.want {
+			t.Errorf("%q/%q from %s: expected %s, got %s", tt.forwarded, tt.realIP, tt.remote, tt.want, got)
+		}
+	}
+
+	// Addresses from the PROXY protocol are final, even from a trusted network
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, ClientIP(r))
+	}))
+	ts.Listener = proxyproto.NewListener(ts.Listener)
+	ts.Config.ConnContext = proxyproto.ConnContext
+	ts.Start()
+	defer ts.Close()
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "PROXY TCP4 10.0.0.7 10.0.0.1 51234 443\r\nGET / HTTP/1.1\r\nHost: bar\r\nX-Forwarded-For: 198.51.100.1\r\nConnection: close\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "10.0.0.7" {
+		t.Errorf("Expected the PROXY protocol address, got %s", body)
+	}
+
+	if err := SetTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("Expected an invalid network to be refused")
+	}
+
+	// IPv6 clients count per network when a prefix is set
+	if got := ClientPrefix("2001:db8:1:2:aaaa::1", 64); got != "2001:db8:1:2::/64" {
+		t.Errorf("Expected the /64 network, got %s", got)
+	}
+	if got := ClientPrefix("203.0.113.7", 64); got != "203.0.113.7" {
+		t.Errorf("Expected IPv4 addresses to count alone, got %s", got)
+	}
+	if got := ClientPrefix("2001:db8::1", 0); got != "2001:db8::1" {
+		t.Errorf("Expected full addresses without a prefix, got %s", got)
+	}
+}
+
+func TestErrorDetailsLanguage(t *testing.T) {
+	cfg := &config.Config{
+		API:      config.APIConfig{AuthTokens: []string{"test_token"}, RateLimitPerMin: 60, RateLimitPerHour: 600, TokenRateLimitPerMin: 60, TokenRateLimitPerHour: 600},
+		Language: config.LanguageConfig{DefaultLanguage: "de", Enabled: []string{"en", "de"}},
+	}
+	server, err := New(cfg, &mockService{}, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	ts := httptest.NewServer(server.httpServer.Handler)
+	defer ts.Close()
+
+	get := func(query, accept string) (string, string) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+"/api/v1/email/status"+query, nil)
+		if accept != "" {
+			req.Header.Set("Accept-Language", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		defer resp.Body.Close()
+		var body ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body.Details, resp.Header.Get("Content-Language")
+	}
+
+	tests := []struct {
+		query, accept string
+		details, lang string
+	}{
+		{"", "fr-FR, de-CH;q=0.8, en;q=0.5", "Ungültiges oder fehlendes Authentifizierungstoken", "de"},
+		{"?lang=en", "de", "Invalid or missing authentication token", "en"},
+		// Languages that are not enabled fall back to English, not the default language
+		{"?lang=fr", "", "Invalid or missing authentication token", ""},
+		{"", "", "Invalid or missing authentication token", ""},
+	}
+	for _, tt := range tests {
+		details, lang := get(tt.query, tt.accept)
+		if details != tt.details || lang != tt.lang {
+			t.Errorf("%q %q: expected %q in %q, got %q in %q", tt.query, tt.accept, tt.details, tt.lang, details, lang)
+		}
+	}
+}
+
+func TestRateLimitMiddleware_IPv6Prefix(t *testing.T) {
+	cfg := &config.Config{
+		API: config.APIConfig{
+			AuthTokens:       []string{"terminal_1"},
+			RateLimitPerMin:  1,
+			RateLimitPerHour: 100,
+			IPv6Prefix:       64,
+			TrustedProxies:   []string{"127.0.0.0/8"},
+
+			TokenRateLimitPerMin:  100,
+			TokenRateLimitPerHour: 100,
+		},
+	}
+	server, err := New(cfg, &mockService{findEmailStatus: "eligible"}, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	ts := httptest.NewServer(server.httpServer.Handler)
+	defer ts.Close()
+
+	get := func(ip string) int {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+"/api/v1/email/status?email=guest@example.com", nil)
+		req.Header.Set("Authorization", "Bearer terminal_1")
+		req.Header.Set("X-Forwarded-For", ip)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Addresses of one /64 share a limit, other networks do not
+	if status := get("2001:db8:1:2::1"); status != http.StatusOK {
+		t.Fatalf("Expected the first request to pass, got %d", status)
+	}
+	if status := get("2001:db8:1:2::ffff"); status != http.StatusTooManyRequests {
+		t.Errorf("Expected the same network to be limited, got %d", status)
+	}
+	if status := get("2001:db8:1:3::1"); status != http.StatusOK {
+		t.Errorf("Expected another network to pass, got %d", status)
+	}
+}
+
+func TestServer_Start_Stop(t *testing.T) {
+	// Mock service
+	svc := &mockService{}
+
+	// Create test configuration
+	cfg := &config.Config{
+		API: config.APIConfig{
+			Enabled: true,
+			Port:    8099, // Use a different port to avoid conflicts
+		},
+	}
+
+	// Create logger
+	log := logger.New("debug")
+
+	// Create server
+	server, err := New(cfg, svc, log)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	// Start server
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+
+	// Give server a moment to start
+	time.Sleep(100 * time.Millisecond)
+
+	// Stop server
+	if err := server.Stop(); err != nil {
+		t.Fatalf("Failed to stop server: %v", err)
+	}
+}
+
+func TestEngagementStats(t *testing.T) {
+	svc := &mockService{}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	client := &http.Client{}
+
+	// Unauthenticated requests are rejected
+	req, _ := http.NewRequest("GET", ts.URL+"/api/v1/stats/engagement", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", resp.StatusCode)
+	}
+
+	// Authenticated requests get the statistics
+	req, _ = http.NewRequest("GET", ts.URL+"/api/v1/stats/engagement", nil)
+	req.Header.Set("Authorization", "Bearer test_token")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var stats analytics.Engagement
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+
+	if stats.Languages["en"] != 3 || stats.Checks != 4 || stats.ConversionRate != 0.5 {
+		t.Errorf("Unexpected engagement stats: %+v", stats)
+	}
+
+	// The funnel is served per day
+	req, _ = http.NewRequest("GET", ts.URL+"/api/v1/stats/funnel", nil)
+	req.Header.Set("Authorization", "Bearer test_token")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+	var funnel analytics.Funnel
+	if err := json.NewDecoder(resp.Body).Decode(&funnel); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	if len(funnel.Days) != 1 || funnel.Days[0].Date != "2025-06-14" || funnel.Total.Unclaimed != 2 {
+		t.Errorf("Unexpected funnel: %+v", funnel)
+	}
+}
+
+func TestDatabaseStatus(t *testing.T) {
+	svc := &mockService{}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	get := func(token string) *http.Response {
+		req, _ := http.NewRequest("GET", ts.URL+"/api/v1/admin/db", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	// Only admin tokens may read database diagnostics
+	resp := get("test_token")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", resp.StatusCode)
+	}
+
+	resp = get("admin_token")
+	var status DatabaseStatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || status.Status != "ok" {
+		t.Fatalf("Expected healthy status, got %d %+v", resp.StatusCode, status)
+	}
+	if status.Stats == nil || status.Stats.Backend != "mock" || status.Stats.Users != 10 {
+		t.Errorf("Unexpected stats: %+v", status.Stats)
+	}
+
+	// An unreachable database is reported as unavailable
+	svc.dbHealthError = domain.ErrDatabaseUnavailable
+	resp = get("admin_token")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", resp.StatusCode)
+	}
+}
+
+func TestRuntimeStatus(t *testing.T) {
+	svc := &mockService{}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	get := func(token string) *http.Response {
+		req, _ := http.NewRequest("GET", ts.URL+"/api/v1/admin/status", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	resp := get("test_token")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", resp.StatusCode)
+	}
+
+	resp = get("admin_token")
+	defer resp.Body.Close()
+	var status domain.RuntimeStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || status.PendingRedemptions != 1 || len(status.LastErrors) != 1 {
+		t.Errorf("Unexpected status %d %+v", resp.StatusCode, status)
+	}
+}
+
+func TestPublicEndpoints(t *testing.T) {
+	tests := []struct {
+		name           string
+		public         []string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{"Health is public by default", []string{"/api/health"}, "/api/health", "", http.StatusOK},
+		{"Health requires auth when not listed", nil, "/api/health", "", http.StatusUnauthorized},
+		{"Health with token when not listed", nil, "/api/health", "test_token", http.StatusOK},
+		{"Wildcard matches prefix", []string{"/api/v1/stats/*"}, "/api/v1/stats/engagement", "", http.StatusOK},
+		{"Other endpoints still require auth", []string{"/api/v1/stats/*"}, "/api/v1/report/all", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				API: config.APIConfig{
+					AuthTokens:       []string{"test_token"},
+					RateLimitPerMin:  60,
+					RateLimitPerHour: 600,
+					PublicEndpoints:  tt.public,
+				},
+			}
+			server, err := New(cfg, &mockService{}, logger.New("error"))
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+			ts := httptest.NewServer(server.httpServer.Handler)
+			defer ts.Close()
+
+			req, _ := http.NewRequest("GET", ts.URL+tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Error making request: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestPayments(t *testing.T) {
+	paidAt := time.Now()
+	svc := &mockService{purchases: []domain.Purchase{
+		{SessionID: "cs_1", Email: "guest@example.com", Status: "paid", Amount: 800, Currency: "eur", CreatedAt: paidAt, PaidAt: &paidAt},
+		{SessionID: "cs_2", Email: "other@example.com", Status: "pending", CreatedAt: paidAt},
+	}}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	// Webhooks need no token but a valid signature
+	for signature, expected := range map[string]int{"valid": http.StatusOK, "forged": http.StatusUnauthorized} {
+		req, _ := http.NewRequest("POST", ts.URL+"/api/v1/webhooks/stripe", strings.NewReader(`{"type": "checkout.session.completed"}`))
+		req.Header.Set("Stripe-Signature", signature)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Errorf("Signature %q: expected status %d, got %d", signature, expected, resp.StatusCode)
+		}
+	}
+	if string(svc.webhookPayload) != `{"type": "checkout.session.completed"}` {
+		t.Errorf("Webhook payload not passed on: %q", svc.webhookPayload)
+	}
+
+	// The purchases report counts paid purchases
+	req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/purchases", nil)
+	req.Header.Set("Authorization", "Bearer test_token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var report PurchaseReportResponse
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	if report.Count != 2 || report.Paid != 1 || report.Purchases[0].Amount != 800 {
+		t.Errorf("Unexpected purchases report: %+v", report)
+	}
+}
+
+func TestEmailStatusAndRedeem(t *testing.T) {
+	svc := &mockService{findEmailStatus: "eligible"}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	do := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test_token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	resp := do("GET", "/api/v1/email/status?email=Guest@Example.com", "")
+	var status EmailStatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || status.Status != "eligible" || status.Email != "guest@example.com" {
+		t.Errorf("Unexpected status response %d: %+v", resp.StatusCode, status)
+	}
+
+	resp = do("POST", "/api/v1/email/redeem", `{"email": "guest@example.com"}`)
+	var redeemed RedeemResponse
+	if err := json.NewDecoder(resp.Body).Decode(&redeemed); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || redeemed.Status != "redeemed" || redeemed.Redeemed.IsZero() {
+		t.Errorf("Unexpected redeem response %d: %+v", resp.StatusCode, redeemed)
+	}
+
+	// Redeemed and unknown emails cannot be redeemed
+	for status, expected := range map[string]int{"redeemed": http.StatusConflict, "not_found": http.StatusNotFound} {
+		svc.findEmailStatus = status
+		resp = do("POST", "/api/v1/email/redeem", `{"email": "guest@example.com"}`)
+		resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Errorf("Status %s: expected %d, got %d", status, expected, resp.StatusCode)
+		}
+	}
+}
+
+func TestRedeemBatch(t *testing.T) {
+	svc := &mockService{offlineStatus: map[string]string{
+		"early@example.com":   "conflict",
+		"again@example.com":   "duplicate",
+		"missing@example.com": "not_found",
+	}}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	post := func(body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("POST", ts.URL+"/api/v1/redeem/batch", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test_token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	resp := post(`{"redemptions": [
+		{"email": "guest@example.com", "redeemed_at": "2025-06-01T20:15:00Z", "operator": "anna"},
+		{"email": "early@example.com", "redeemed_at": "2025-06-01T20:16:00Z"},
+		{"email": "again@example.com", "redeemed_at": "2025-06-01T20:17:00Z"},
+		{"email": "missing@example.com", "redeemed_at": "2025-06-01T20:18:00Z"}]}`)
+	var response BatchRedeemResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || response.Total != 4 || response.Redeemed != 1 ||
+		response.Conflict != 1 || response.Duplicate != 1 || response.Failed != 1 || len(response.Results) != 4 {
+		t.Errorf("Unexpected batch response %d: %+v", resp.StatusCode, response)
+	}
+
+	resp = post(`{"redemptions": []}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected an empty batch to be rejected, got %d", resp.StatusCode)
+	}
+}
+
+func TestUserState(t *testing.T) {
+	svc := &mockService{}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	get := func(path string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer test_token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	resp := get("/api/v1/users/7?as_of=2025-06-01T22:03:00%2B02:00")
+	var state UserStateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || state.Source != "audit" || state.User == nil || state.AsOf == nil {
+		t.Errorf("Unexpected response %d: %+v", resp.StatusCode, state)
+	}
+	if !svc.userAsOf.Equal(time.Date(2025, 6, 1, 20, 3, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected as_of passed to the service: %v", svc.userAsOf)
+	}
+
+	for path, expected := range map[string]int{
+		"/api/v1/users/7":                http.StatusOK,
+		"/api/v1/users/8":                http.StatusNotFound,
+		"/api/v1/users/7?as_of=yesterday": http.StatusBadRequest,
+	} {
+		resp := get(path)
+		resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Errorf("%s: expected %d, got %d", path, expected, resp.StatusCode)
+		}
+	}
+}
+
+func TestUserPatch(t *testing.T) {
+	svc := &mockService{}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	patch := func(token, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("PATCH", ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	resp := patch("admin_token", "/api/v1/users/7", `{"email": "fixed@example.com", "tags": ["VIP", "press"], "reason": "typo at the door"}`)
+	var user User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || user.Email != "fixed@example.com" || strings.Join(user.Tags, ",") != "vip,press" {
+		t.Errorf("Unexpected response %d: %+v", resp.StatusCode, user)
+	}
+	if svc.userPatch.Reason != "typo at the door" || svc.userPatch.Notes != nil || svc.userPatch.Unredeem {
+		t.Errorf("Unexpected patch passed to the service: %+v", svc.userPatch)
+	}
+
+	for body, expected := range map[string]int{
+		`{"notes": "Table 4"}`:                    http.StatusOK,
+		`{}`:                                      http.StatusBadRequest,
+		`{"redeemed": null}`:                      http.StatusBadRequest,
+		`{"unredeem": true}`:                      http.StatusBadRequest,
+		`{"tags": ["front row"]}`:                 http.StatusBadRequest,
+		`{"email": "taken@example.com"}`:          http.StatusConflict,
+		`{"unredeem": true, "reason": "mistake"}`: http.StatusOK,
+	} {
+		resp := patch("admin_token", "/api/v1/users/7", body)
+		resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Errorf("%s: expected %d, got %d", body, expected, resp.StatusCode)
+		}
+	}
+
+	// Only admin tokens may change guests
+	resp = patch("admin_token", "/api/v1/users/8", `{"notes": "x"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected an unknown guest to be reported, got %d", resp.StatusCode)
+	}
+	resp = patch("test_token", "/api/v1/users/7", `{"notes": "x"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected a regular token to be refused, got %d", resp.StatusCode)
+	}
+}
+
+func TestMergeUsers(t *testing.T) {
+	svc := &mockService{}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	post := func(token, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("POST", ts.URL+"/api/v1/admin/users/merge", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	resp := post("admin_token", `{"keep": "7", "duplicate": "8", "reason": "typo in the import"}`)
+	var merged MergeUsersResponse
+	if err := json.NewDecoder(resp.Body).Decode(&merged); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || merged.User.ID != "7" || merged.Duplicate.ID != "8" || merged.Duplicate.Tags[0] != domain.MergedTag {
+		t.Errorf("Unexpected response %d: %+v", resp.StatusCode, merged)
+	}
+
+	for body, expected := range map[string]int{
+		`{"keep": "7"}`:                   http.StatusBadRequest,
+		`{"keep": "7", "duplicate": "9"}`: http.StatusNotFound,
+		`{"keep": "7", "duplicate": 8}`:   http.StatusBadRequest,
+		`{"keep": "7", "into": "8"}`:      http.StatusBadRequest,
+	} {
+		resp := post("admin_token", body)
+		resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Errorf("%s: expected %d, got %d", body, expected, resp.StatusCode)
+		}
+	}
+
+	resp = post("test_token", `{"keep": "7", "duplicate": "8"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected a regular token to be refused, got %d", resp.StatusCode)
+	}
+}
+
+func TestImport(t *testing.T) {
+	svc := &mockService{}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	do := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test_token")
+		req.Header.Set("Content-Type", "text/csv")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	resp := do("POST", "/api/v1/import", "a@example.com\n")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 while imports are not queued, got %d", resp.StatusCode)
+	}
+
+	svc.importQueue = true
+	resp = do("POST", "/api/v1/import", "a@example.com\nb@example.com\n")
+	var job jobs.Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || job.Total != 2 || !strings.HasPrefix(job.Actor, "token:") {
+		t.Errorf("Unexpected response %d: %+v", resp.StatusCode, job)
+	}
+	if location := resp.Header.Get("Location"); location != "/api/v1/jobs/"+job.ID {
+		t.Errorf("Unexpected Location %q", location)
+	}
+
+	resp = do("POST", "/api/v1/import", "a@example.com\nb@example.com\nc@example.com\nd@example.com\n")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 above the import limit, got %d", resp.StatusCode)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	svc := &mockService{}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	post := func(query, contentType, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("POST", ts.URL+"/api/v1/import/csv?"+query, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test_token")
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	resp := post("name=guests.csv&email_column=2&name_column=1&tags_column=3&existing=update", "text/csv", "Name,Email,Tags\nAnna,anna@example.com,vip\n")
+	var job jobs.Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || job.Name != "guests.csv" || job.Total != 2 || !strings.HasPrefix(job.Actor, "token:") {
+		t.Errorf("Unexpected response %d: %+v", resp.StatusCode, job)
+	}
+	if opts := svc.importOptions; opts.Column != 2 || opts.NameColumn != 1 || opts.TagsColumn != 3 || !opts.Header || opts.Existing != importer.ExistingUpdate {
+		t.Errorf("Unexpected import options %+v", opts)
+	}
+
+	for _, tc := range []struct {
+		query, contentType, body string
+		expected                 int
+	}{
+		{"email_column=1", "application/json", `{"emails": []}`, http.StatusUnsupportedMediaType},
+		{"email_column=first", "text/csv", "a@example.com\n", http.StatusBadRequest},
+		{"name_column=2", "text/csv", "a@example.com\n", http.StatusBadRequest},
+		{"email_column=1&existing=replace", "text/csv", "a@example.com\n", http.StatusBadRequest},
+		{"email_column=1", "text/csv", "", http.StatusBadRequest},
+	} {
+		resp := post(tc.query, tc.contentType, tc.body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.expected {
+			t.Errorf("%s: expected status %d, got %d", tc.query, tc.expected, resp.StatusCode)
+		}
+	}
+}
+
+func TestJobs(t *testing.T) {
+	svc := &mockService{}
+	svc.addJob(jobs.Job{Type: jobs.TypeImport, Status: jobs.StatusRunning, Actor: "token:" + TokenFingerprint("test_token"),
+		Errors: []string{"guest@example.com: denied", "2 emails not queued: connection refused"}})
+	svc.addJob(jobs.Job{Type: jobs.TypeImport, Status: jobs.StatusRunning, Actor: "token:someone-else"})
+	server, ts := createTestServer(t, svc)
+	defer ts.Close()
+	server.config.Privacy.MaskEmails = true
+
+	do := func(method, path, token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	// Tokens only see the jobs they started, with guest emails masked
+	resp := do("GET", "/api/v1/jobs", "test_token")
+	var list JobsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if list.Count != 1 || list.Jobs[0].ID != "job1" {
+		t.Fatalf("Expected only the own job, got %+v", list)
+	}
+	if errs := list.Jobs[0].Errors; errs[0] != "g***t@example.com: denied" || errs[1] != "2 emails not queued: connection refused" {
+		t.Errorf("Expected the email in the errors masked, got %v", errs)
+	}
+
+	for _, tc := range []struct {
+		method, path, token string
+		expected            int
+	}{
+		{"GET", "/api/v1/jobs/job1", "test_token", http.StatusOK},
+		{"GET", "/api/v1/jobs/job2", "test_token", http.StatusNotFound},
+		{"GET", "/api/v1/jobs/job2", "admin_token", http.StatusOK},
+		{"GET", "/api/v1/jobs/job3", "admin_token", http.StatusNotFound},
+		{"GET", "/api/v1/jobs/job1/cancel", "test_token", http.StatusMethodNotAllowed},
+		{"POST", "/api/v1/jobs/job1/cancel", "test_token", http.StatusOK},
+		{"POST", "/api/v1/jobs/job1/cancel", "test_token", http.StatusConflict},
+		{"POST", "/api/v1/jobs/job2/cancel", "test_token", http.StatusNotFound},
+		{"POST", "/api/v1/admin/migrations/normalize-emails", "test_token", http.StatusForbidden},
+		{"POST", "/api/v1/admin/migrations/drop-everything", "admin_token", http.StatusNotFound},
+	} {
+		resp := do(tc.method, tc.path, tc.token)
+		resp.Body.Close()
+		if resp.StatusCode != tc.expected {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.path, tc.expected, resp.StatusCode)
+		}
+	}
+
+	resp = do("POST", "/api/v1/admin/migrations/normalize-emails", "admin_token")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get("Location") != "/api/v1/jobs/job3" {
+		t.Errorf("Expected the migration to start as job3, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	svc := &mockService{}
+	server, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	do := func(method, path, token, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	// Any token sees the flags
+	resp := do("GET", "/api/v1/features", "test_token", "")
+	var list FeaturesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if len(list.Features) != 3 || !list.Features[0].Enabled || list.RuntimeToggle {
+		t.Errorf("Expected every flag on without runtime toggling, got %+v", list)
+	}
+
+	// Toggling is refused unless configured
+	resp = do("PUT", "/api/v1/admin/features/kiosk", "admin_token", `{"enabled": false}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status %d without runtime toggling, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	svc.features, _ = featureflags.New(nil, true)
+	server.config.Features.RuntimeToggle = true
+	for _, tc := range []struct {
+		method, path, token, body string
+		expected                  int
+	}{
+		{"PUT", "/api/v1/admin/features/kiosk", "test_token", `{"enabled": false}`, http.StatusForbidden},
+		{"PUT", "/api/v1/admin/features/kiosk", "admin_token", `{}`, http.StatusBadRequest},
+		{"PUT", "/api/v1/admin/features/waitlist", "admin_token", `{"enabled": true}`, http.StatusNotFound},
+		{"POST", "/api/v1/admin/features/kiosk", "admin_token", `{"enabled": false}`, http.StatusMethodNotAllowed},
+		{"PUT", "/api/v1/admin/features/kiosk", "admin_token", `{"enabled": false}`, http.StatusOK},
+	} {
+		resp := do(tc.method, tc.path, tc.token, tc.body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.expected {
+			t.Errorf("%s %s with %s: expected status %d, got %d", tc.method, tc.path, tc.token, tc.expected, resp.StatusCode)
+		}
+	}
+	if svc.features.Enabled(featureflags.Kiosk) {
+		t.Error("Expected the kiosk to be turned off")
+	}
+
+	// DELETE returns the flag to its configured state
+	resp = do("DELETE", "/api/v1/admin/features/kiosk", "admin_token", "")
+	var flag featureflags.Flag
+	if err := json.NewDecoder(resp.Body).Decode(&flag); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if !flag.Enabled || flag.Overridden {
+		t.Errorf("Expected the configured state after a reset, got %+v", flag)
+	}
+}
+
+func TestEmailStatusAndRedeem_VoucherOnly(t *testing.T) {
+	svc := &mockService{
+		findEmailStatus: "eligible",
+		voucherOnly:     true,
+		vouchers:        map[string]string{"valid-code": "guest@example.com"},
+	}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	do := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test_token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	tests := []struct {
+		name, method, path, body string
+		want                     int
+	}{
+		{"typed email status", "GET", "/api/v1/email/status?email=guest@example.com", "", http.StatusForbidden},
+		{"typed email redeem", "POST", "/api/v1/email/redeem", `{"email": "guest@example.com"}`, http.StatusForbidden},
+		{"invalid voucher", "GET", "/api/v1/email/status?voucher=forged", "", http.StatusBadRequest},
+		{"voucher status", "GET", "/api/v1/email/status?voucher=valid-code", "", http.StatusOK},
+		{"voucher redeem", "POST", "/api/v1/email/redeem", `{"voucher": "valid-code"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if resp := do(tt.method, tt.path, tt.body); resp.StatusCode != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, resp.StatusCode)
+		}
+	}
+}
+
+func TestArchiveEvent(t *testing.T) {
+	svc := &mockService{
+		findEmailStatus:     "eligible",
+		generateReportUsers: []*domain.User{{ID: "1", Email: "guest@example.com", DateAdded: time.Now()}},
+	}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	do := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		token := "test_token"
+		if strings.HasPrefix(path, "/api/v1/admin/") {
+			token = "admin_token"
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+	reportCount := func(query string) int {
+		t.Helper()
+		resp := do("GET", "/api/v1/report/all"+query, "")
+		defer resp.Body.Close()
+		var report ReportResponse
+		if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+			t.Fatalf("Error decoding report: %v", err)
+		}
+		return report.Count
+	}
+
+	if reportCount("") != 1 || reportCount("?archived=true") != 0 {
+		t.Error("Expected the report of the active event only")
+	}
+
+	resp := do("POST", "/api/v1/admin/event/archive", `{"export": true}`)
+	var archive domain.EventArchive
+	if err := json.NewDecoder(resp.Body).Decode(&archive); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || archive.Bundle == "" || !strings.HasPrefix(archive.Actor, "token:") {
+		t.Errorf("Unexpected archive response %d: %+v", resp.StatusCode, archive)
+	}
+
+	// Archiving twice is refused
+	resp = do("POST", "/api/v1/admin/event/archive", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status 409 when archiving twice, got %d", resp.StatusCode)
+	}
+
+	resp = do("GET", "/api/v1/admin/event", "")
+	var status EventStatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if status.Archived == nil || len(status.Archives) != 1 {
+		t.Errorf("Unexpected event status: %+v", status)
+	}
+
+	if reportCount("") != 0 || reportCount("?archived=true") != 1 {
+		t.Error("Expected the report of the archived event only")
+	}
+
+	// Archived events cannot be redeemed
+	svc.findEmailStatus = "archived"
+	resp = do("POST", "/api/v1/email/redeem", `{"email": "guest@example.com"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status 409 for an archived event, got %d", resp.StatusCode)
+	}
+}
+
+func TestTicketDownload(t *testing.T) {
+	_, ts := createTestServer(t, &mockService{})
+	defer ts.Close()
+
+	// Ticket links need no token but a valid signature
+	future := time.Now().Add(time.Hour).Unix()
+	past := time.Now().Add(-time.Hour).Unix()
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{fmt.Sprintf("/api/v1/tickets/0123456789abcdef.pdf?expires=%d&sig=valid", future), http.StatusOK},
+		{fmt.Sprintf("/api/v1/tickets/0123456789abcdef.pdf?expires=%d&sig=forged", future), http.StatusForbidden},
+		{fmt.Sprintf("/api/v1/tickets/0123456789abcdef.pdf?expires=%d&sig=valid", past), http.StatusGone},
+		{fmt.Sprintf("/api/v1/tickets/fedcba9876543210.pdf?expires=%d&sig=valid", future), http.StatusNotFound},
+		{"/api/v1/tickets/0123456789abcdef.pdf?sig=valid", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		resp, err := http.Get(ts.URL + tt.path)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.expected, resp.StatusCode)
+		}
+		if tt.expected == http.StatusOK && resp.Header.Get("Content-Type") != "application/pdf" {
+			t.Errorf("Expected a PDF, got %s", resp.Header.Get("Content-Type"))
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	_, ts := createTestServer(t, &mockService{})
+	defer ts.Close()
+
+	// IDs set by a proxy are kept, others are replaced
+	for header, keep := range map[string]bool{"": false, "abc-123": true, "bad id!": false} {
+		req, _ := http.NewRequest("GET", ts.URL+"/api/health", nil)
+		if header != "" {
+			req.Header.Set("X-Request-ID", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		resp.Body.Close()
+
+		got := resp.Header.Get("X-Request-ID")
+		if got == "" || (got == header) != keep {
+			t.Errorf("Header %q: unexpected request ID %q", header, got)
+		}
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	server, ts := createTestServer(t, &mockService{})
+	ts.Close()
+
+	handler := chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/started" {
+			w.WriteHeader(http.StatusAccepted)
+		}
+		var guests map[string]int
+		guests["boom"]++ // Writing to a nil map panics
+	}), server.requestIDMiddleware, server.recoveryMiddleware)
+
+	before := crashreport.Counts()[crashreport.SourceAPI]
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/email/status", nil))
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("Expected a 500 problem response, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var problem ProblemResponse
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	if problem.Status != 500 || problem.Instance != "/api/v1/email/status" || problem.RequestID != rec.Header().Get("X-Request-ID") {
+		t.Errorf("Unexpected problem: %+v", problem)
+	}
+
+	// A response already started keeps its status
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/started", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Expected the started response to be kept, got %d", rec.Code)
+	}
+
+	if count := crashreport.Counts()[crashreport.SourceAPI]; count != before+2 {
+		t.Errorf("Expected both panics to be counted, got %d", count-before)
+	}
+}
+
+func TestProbeEndpoints(t *testing.T) {
+	svc := &mockService{}
+	server, ts := createTestServer(t, svc)
+	defer ts.Close()
+	server.config.API.PublicEndpoints = []string{"/healthz", "/readyz"}
+
+	probe := func(path string) (int, ReadinessResponse) {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		defer resp.Body.Close()
+		var body ReadinessResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	// Not ready while a startup step is pending, but alive
+	server.SetReady("messenger", false)
+	if code, body := probe("/readyz"); code != http.StatusServiceUnavailable || body.Checks["messenger"] != "pending" {
+		t.Errorf("Expected 503 with messenger pending, got %d %+v", code, body)
+	}
+	if code, _ := probe("/healthz"); code != http.StatusOK {
+		t.Errorf("Expected liveness 200, got %d", code)
+	}
+
+	server.SetReady("messenger", true)
+	if code, body := probe("/readyz"); code != http.StatusOK || body.Status != "ready" || body.Checks["repository"] != "ok" {
+		t.Errorf("Expected 200 ready, got %d %+v", code, body)
+	}
+
+	// An unreachable repository fails readiness only, without exposing the error
+	svc.dbHealthError = fmt.Errorf("connection refused")
+	code, body := probe("/readyz")
+	if code != http.StatusServiceUnavailable || body.Checks["repository"] != "unavailable" {
+		t.Errorf("Expected 503 with repository unavailable, got %d %+v", code, body)
+	}
+	if code, _ := probe("/healthz"); code != http.StatusOK {
+		t.Errorf("Expected liveness 200 with the repository down, got %d", code)
+	}
+}
+
+func TestAuditLogEndpoint(t *testing.T) {
+	redeemed := time.Date(2025, 6, 1, 21, 30, 0, 0, time.UTC)
+	svc := &mockService{auditEntries: []audit.Entry{
+		{Time: redeemed, Actor: "telegram:42", Action: audit.ActionRedeem, Email: "guest@example.com"},
+	}}
+	server, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	get := func(token, query string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+"/api/v1/admin/audit"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	// The audit log requires an admin token
+	resp := get("test_token", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403 without an admin token, got %d", resp.StatusCode)
+	}
+
+	resp = get("admin_token", "?action=redeem&email=guest&from=2025-06-01&to=2025-06-01&limit=10&offset=20")
+	var auditResp AuditResponse
+	if err := json.NewDecoder(resp.Body).Decode(&auditResp); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || auditResp.Count != 1 || auditResp.Entries[0].Actor != "telegram:42" {
+		t.Errorf("Unexpected audit response %d: %+v", resp.StatusCode, auditResp)
+	}
+	filter := svc.auditFilter
+	if filter.Action != "redeem" || filter.Email != "guest" || filter.Limit != 10 || filter.Offset != 20 {
+		t.Errorf("Unexpected filter: %+v", filter)
+	}
+	if !filter.To.After(redeemed) || filter.From.After(redeemed) {
+		t.Errorf("Expected the date range to cover the whole day, got %v to %v", filter.From, filter.To)
+	}
+
+	resp = get("admin_token", "?limit=5000")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a limit that is too large, got %d", resp.StatusCode)
+	}
+
+	// CSV exports are not paged
+	resp = get("admin_token", "?format=csv")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") || svc.auditFilter.Limit != 0 {
+		t.Errorf("Expected an unpaged CSV export, got %s with %+v", resp.Header.Get("Content-Type"), svc.auditFilter)
+	}
+	if !strings.Contains(string(body), "2025-06-01T21:30:00Z,telegram:42,redeem,guest@example.com,") {
+		t.Errorf("Unexpected CSV export: %s", body)
+	}
+
+	// Journal exports need a key and cover the whole log
+	resp = get("admin_token", "?format=journal")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 without a journal key, got %d", resp.StatusCode)
+	}
+	server.config.Event.JournalKey = "sponsor-secret"
+	resp = get("admin_token", "?format=journal&action=redeem")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a filtered journal, got %d", resp.StatusCode)
+	}
+	resp = get("admin_token", "?format=journal")
+	summary, err := audit.VerifyJournal(resp.Body, []byte("sponsor-secret"))
+	resp.Body.Close()
+	if err != nil || summary.Records != 1 {
+		t.Fatalf("Expected a journal of 1 record, got %+v: %v", summary, err)
+	}
+	if resp.Header.Get("X-Journal-Head") != summary.Head {
+		t.Errorf("Expected the head hash %s in the headers, got %s", summary.Head, resp.Header.Get("X-Journal-Head"))
+	}
+}
+
+func TestPrivacyMasksEmails(t *testing.T) {
+	svc := &mockService{generateReportUsers: []*domain.User{{ID: "1", Email: "john@example.com", FirstName: "John", LastName: "Smith"}}}
+	server, ts := createTestServer(t, svc)
+	defer ts.Close()
+	server.config.Privacy.MaskEmails = true
+
+	report := func(token, query string) (string, string) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/all"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		defer resp.Body.Close()
+		var reportResp ReportResponse
+		if err := json.NewDecoder(resp.Body).Decode(&reportResp); err != nil {
+			t.Fatalf("Error decoding response: %v", err)
+		}
+		if len(reportResp.Users) != 1 {
+			t.Fatalf("Expected 1 user, got %d", len(reportResp.Users))
+		}
+		return reportResp.Users[0].Email, reportResp.Users[0].LastName
+	}
+
+	if email, lastName := report("test_token", ""); email != "j***n@example.com" || lastName != "S." {
+		t.Errorf("Expected a masked email and last name, got %s, %s", email, lastName)
+	}
+	// Only admin tokens may reveal emails
+	if email, _ := report("test_token", "?reveal=true"); email != "j***n@example.com" {
+		t.Errorf("Expected a masked email for a regular token, got %s", email)
+	}
+	if email, lastName := report("admin_token", "?reveal=true"); email != "john@example.com" || lastName != "Smith" {
+		t.Errorf("Expected the full email and last name for an admin token, got %s, %s", email, lastName)
+	}
+	// The service's users are not modified
+	if svc.generateReportUsers[0].Email != "john@example.com" {
+		t.Errorf("Expected the stored user to keep its email, got %s", svc.generateReportUsers[0].Email)
+	}
+}
+
+func TestPrivacyMasksPurchaseEmails(t *testing.T) {
+	svc := &mockService{purchases: []domain.Purchase{
+		{SessionID: "cs_1", Email: "john@example.com", Status: "paid", Amount: 800, Currency: "eur", CreatedAt: time.Now()},
+	}}
+	server, ts := createTestServer(t, svc)
+	defer ts.Close()
+	server.config.Privacy.MaskEmails = true
+
+	get := func(token, query string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+"/api/v1/report/purchases"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+	report := func(token, query string) string {
+		t.Helper()
+		resp := get(token, query)
+		defer resp.Body.Close()
+		var reportResp PurchaseReportResponse
+		if err := json.NewDecoder(resp.Body).Decode(&reportResp); err != nil {
+			t.Fatalf("Error decoding response: %v", err)
+		}
+		if len(reportResp.Purchases) != 1 {
+			t.Fatalf("Expected 1 purchase, got %d", len(reportResp.Purchases))
+		}
+		return reportResp.Purchases[0].Email
+	}
+	csvReport := func(token, query string) string {
+		t.Helper()
+		resp := get(token, query)
+		defer resp.Body.Close()
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("Error reading CSV: %v", err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("Expected a header and 1 purchase, got %d records", len(records))
+		}
+		return records[1][1]
+	}
+
+	if email := report("test_token", ""); email != "j***n@example.com" {
+		t.Errorf("Expected a masked email, got %s", email)
+	}
+	if email := csvReport("test_token", "?format=csv"); email != "j***n@example.com" {
+		t.Errorf("Expected a masked email in the CSV, got %s", email)
+	}
+	// Only admin tokens may reveal emails
+	if email := csvReport("test_token", "?format=csv&reveal=true"); email != "j***n@example.com" {
+		t.Errorf("Expected a masked email for a regular token, got %s", email)
+	}
+	if email := report("admin_token", "?reveal=true"); email != "john@example.com" {
+		t.Errorf("Expected the full email for an admin token, got %s", email)
+	}
+	if email := csvReport("admin_token", "?format=csv&reveal=true"); email != "john@example.com" {
+		t.Errorf("Expected the full email in the CSV for an admin token, got %s", email)
+	}
+	// The service's purchases are not modified
+	if svc.purchases[0].Email != "john@example.com" {
+		t.Errorf("Expected the stored purchase to keep its email, got %s", svc.purchases[0].Email)
+	}
+}
+
+func TestRateLimitStats(t *testing.T) {
+	svc := &mockService{}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	get := func(path, token string) *http.Response {
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	resp := get("/api/v1/admin/ratelimit", "test_token")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", resp.StatusCode)
+	}
+
+	resp = get("/api/v1/admin/ratelimit?top=101", "admin_token")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a top over the maximum, got %d", resp.StatusCode)
+	}
+
+	resp = get("/api/v1/admin/ratelimit?top=5", "admin_token")
+	var stats RateLimitStatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || stats.Limiters["service"].RejectedMinute != 3 {
+		t.Fatalf("Unexpected response %d %+v", resp.StatusCode, stats)
+	}
+	// The admin requests above passed authentication and were counted
+	if client := stats.Limiters["api_client"]; client.TrackedClients != 1 || client.Allowed != 2 || len(client.TopConsumers) != 1 {
+		t.Errorf("Unexpected API client limiter: %+v", client)
+	}
+
+	resp = get("/metrics", "test_token")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, line := range []string{
+		"# TYPE cocktailbot_ratelimit_rejected_total counter",
+		`cocktailbot_ratelimit_rejected_total{limiter="service",window="minute"} 3`,
+		`cocktailbot_ratelimit_busiest_client_requests{limiter="service",window="hour"} 9`,
+		`cocktailbot_ratelimit_tracked_clients{limiter="api_token"} 2`,
+		"# TYPE cocktailbot_http_client_retries_total counter",
+		`cocktailbot_funnel_total{stage="button_shown"} 5`,
+		`cocktailbot_funnel_total{stage="redeemed"} 4`,
+		`cocktailbot_enumeration_alarms_total 2`,
+		`cocktailbot_enumeration_sources{state="blocked"} 1`,
+		"# TYPE cocktailbot_panics_total counter",
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("Expected %q in metrics:\n%s", line, body)
+		}
+	}
+}
+
+func TestUserJSON(t *testing.T) {
+	added := time.Date(2025, 6, 1, 20, 0, 0, 0, time.UTC)
+	redeemed := added.Add(time.Hour)
+	user := &domain.User{ID: "7", Email: "guest@example.com", DateAdded: added, Redeemed: &redeemed, UpdatedAt: redeemed, RedeemedAt: "Rooftop"}
+
+	data, err := json.Marshal(NewUser(user))
+	if err != nil {
+		t.Fatalf("Error encoding user: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Error decoding user: %v", err)
+	}
+	for _, name := range []string{"id", "email", "date_added", "redeemed", "updated_at", "bar"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("Expected field %s in %s", name, data)
+		}
+	}
+	for _, name := range []string{"marketing_consent", "created_by", "Email"} {
+		if _, ok := fields[name]; ok {
+			t.Errorf("Expected no field %s in %s", name, data)
+		}
+	}
+
+	var decoded User
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Error decoding user: %v", err)
+	}
+	if back := decoded.Domain(); !reflect.DeepEqual(back, user) {
+		t.Errorf("Expected %+v after a round trip, got %+v", user, back)
+	}
+}
+
+func TestCounterStream(t *testing.T) {
+	svc := &mockService{
+		generateReportUsers: []*domain.User{{Email: "a@example.com"}, {Email: "b@example.com"}, {Email: "c@example.com"}},
+	}
+	server, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + counterStreamPath)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token while the counter is not public, got %d", resp.StatusCode)
+	}
+
+	server.config.API.Counter.Public = true
+	resp, err = http.Get(ts.URL + counterStreamPath)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var event CounterEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				t.Fatalf("Error decoding event: %v", err)
+			}
+			break
+		}
+	}
+	if event.Count != 3 || event.Period != period.Today {
+		t.Errorf("Expected 3 cocktails served today, got %+v", event)
+	}
+	if strings.Contains(fmt.Sprint(event), "@") {
+		t.Errorf("Expected no guest data in the stream, got %+v", event)
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	svc := &mockService{generateReportUsers: []*domain.User{
+		{ID: "1", Email: "anna@example.com", FirstName: "Anna", LastName: "Smith", Notes: "Asked for the vegan menu"},
+		{ID: "2", Email: "bob@example.com", FirstName: "Bob", Tags: []string{"vip"}},
+		{ID: "3", Email: "vip_desk@example.com", Notes: "Front desk"},
+		{ID: "4", Email: "carla@example.com", Notes: "Plus one of a VIP"},
+	}}
+	_, ts := createTestServer(t, svc)
+	defer ts.Close()
+
+	get := func(path string) *http.Response {
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer test_token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	for _, path := range []string{"/api/v1/users/search?q=a", "/api/v1/users/search?q=vip&limit=101", "/api/v1/users/search?q=vip&offset=-1"} {
+		resp := get(path)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", path, resp.StatusCode)
+		}
+	}
+
+	// A tag ranks above an email containing the word, notes come last
+	resp := get("/api/v1/users/search?q=VIP")
+	var result SearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || result.Total != 3 || result.Count != 3 || result.Limit != defaultSearchLimit {
+		t.Fatalf("Unexpected response %d %+v", resp.StatusCode, result)
+	}
+	var ids []string
+	for _, hit := range result.Results {
+		ids = append(ids, hit.User.ID)
+	}
+	if strings.Join(ids, ",") != "2,3,4" {
+		t.Errorf("Expected guests 2,3,4 best first, got %v", ids)
+	}
+	if fields := result.Results[2].Fields; len(fields) != 1 || fields[0] != "notes" {
+		t.Errorf("Expected the notes to match, got %v", fields)
+	}
+
+	// Every word must match, and pages continue from the offset
+	resp = get("/api/v1/users/search?q=anna+vegan")
+	json.NewDecoder(resp.Body).Decode(&result)
+	resp.Body.Close()
+	if result.Total != 1 || result.Results[0].User.ID != "1" {
+		t.Errorf("Expected only Anna to match both words, got %+v", result)
+	}
+	resp = get("/api/v1/users/search?q=example.com&limit=3&offset=3")
+	json.NewDecoder(resp.Body).Decode(&result)
+	resp.Body.Close()
+	if result.Total != 4 || result.Count != 1 || result.Offset != 3 {
+		t.Errorf("Expected the last of 4 matches, got %+v", result)
 	}
 }
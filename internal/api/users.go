@@ -0,0 +1,143 @@
+package api
+
+import (
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// User is a guest as the API shows it. It is kept apart from domain.User
+// so fields added to the domain do not change responses until they are
+// added here. Names are snake_case. Fields every guest has are always
+// present, timestamps and text that may be unset are omitted until set.
+type User struct {
+	ID               string     `json:"id"`
+	Email            string     `json:"email"`
+	DateAdded        time.Time  `json:"date_added"`
+	Redeemed         *time.Time `json:"redeemed,omitempty"`
+	MarketingConsent *time.Time `json:"marketing_consent,omitempty"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	CreatedBy        string     `json:"created_by,omitempty"`
+	Bar              string     `json:"bar,omitempty"` // Bar that served the drink
+	Notes            string     `json:"notes,omitempty"`
+	Tags             []string   `json:"tags,omitempty"`
+	FirstName        string     `json:"first_name,omitempty"`
+	LastName         string     `json:"last_name,omitempty"` // Only the initial in privacy mode unless revealed
+}
+
+// NewUser converts a guest to its API form, or returns nil for nil
+func NewUser(user *domain.User) *User {
+	if user == nil {
+		return nil
+	}
+	return &User{
+		ID:               user.ID,
+		Email:            user.Email,
+		DateAdded:        user.DateAdded,
+		Redeemed:         user.Redeemed,
+		MarketingConsent: user.MarketingConsent,
+		UpdatedAt:        user.UpdatedAt,
+		CreatedBy:        user.CreatedBy,
+		Bar:              user.RedeemedAt,
+		Notes:            user.Notes,
+		Tags:             user.Tags,
+		FirstName:        user.FirstName,
+		LastName:         user.LastName,
+	}
+}
+
+// NewUsers converts guests to their API form
+func NewUsers(users []*domain.User) []*User {
+	converted := make([]*User, len(users))
+	for i, user := range users {
+		converted[i] = NewUser(user)
+	}
+	return converted
+}
+
+// Domain converts the guest back to the domain form
+func (u *User) Domain() *domain.User {
+	if u == nil {
+		return nil
+	}
+	return &domain.User{
+		ID:               u.ID,
+		Email:            u.Email,
+		DateAdded:        u.DateAdded,
+		Redeemed:         u.Redeemed,
+		MarketingConsent: u.MarketingConsent,
+		UpdatedAt:        u.UpdatedAt,
+		CreatedBy:        u.CreatedBy,
+		RedeemedAt:       u.Bar,
+		Notes:            u.Notes,
+		Tags:             u.Tags,
+		FirstName:        u.FirstName,
+		LastName:         u.LastName,
+	}
+}
+
+// IsRedeemed returns true if the guest has already redeemed their cocktail
+func (u *User) IsRedeemed() bool {
+	return u.Redeemed != nil
+}
+
+// UserPatchRequest is the JSON payload of a partial update of a guest.
+// Fields left out are not changed.
+type UserPatchRequest struct {
+	Email     *string   `json:"email,omitempty"`      // Corrected email
+	Notes     *string   `json:"notes,omitempty"`      // Empty to clear the notes
+	Tags      *[]string `json:"tags,omitempty"`       // Replaces all tags, empty to clear them
+	FirstName *string   `json:"first_name,omitempty"` // Empty to clear the first name
+	LastName  *string   `json:"last_name,omitempty"`  // Empty to clear the last name
+	Unredeem  bool      `json:"unredeem,omitempty"`   // Lets the guest redeem again, needs a reason
+	Reason    string    `json:"reason,omitempty"`     // Recorded in the audit log
+}
+
+// Domain converts the request to the domain form
+func (p UserPatchRequest) Domain() domain.UserPatch {
+	return domain.UserPatch{Email: p.Email, Notes: p.Notes, Tags: p.Tags, FirstName: p.FirstName, LastName: p.LastName, Unredeem: p.Unredeem, Reason: p.Reason}
+}
+
+// MergeUsersRequest is the JSON payload for merging the duplicate record of
+// a guest into the record that is kept
+type MergeUsersRequest struct {
+	Keep      string `json:"keep"`             // ID or email of the record that is kept
+	Duplicate string `json:"duplicate"`        // ID or email of the record merged into it
+	Reason    string `json:"reason,omitempty"` // Recorded in the audit log
+}
+
+// MergeUsersResponse holds both records after a merge
+type MergeUsersResponse struct {
+	User      *User `json:"user"`      // The kept record with the data of both
+	Duplicate *User `json:"duplicate"` // The duplicate, tagged merged
+}
+
+// AuditEntry is an audit log entry as the API shows it, with the guest
+// record in its API form
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor"`
+	Action   string    `json:"action"`
+	Email    string    `json:"email,omitempty"`
+	Details  string    `json:"details,omitempty"`
+	Operator string    `json:"operator,omitempty"`
+	User     *User     `json:"user,omitempty"`
+}
+
+// NewAuditEntries converts audit log entries to their API form
+func NewAuditEntries(entries []audit.Entry) []AuditEntry {
+	converted := make([]AuditEntry, len(entries))
+	for i, entry := range entries {
+		converted[i] = AuditEntry{
+			Time:     entry.Time,
+			Actor:    entry.Actor,
+			Action:   entry.Action,
+			Email:    entry.Email,
+			Details:  entry.Details,
+			Operator: entry.Operator,
+			User:     NewUser(entry.User),
+		}
+	}
+	return converted
+}
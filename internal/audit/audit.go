@@ -0,0 +1,238 @@
+// Package audit keeps a trail of who changed guest records and when. Entries
+// are appended to a JSON lines file, one object per line, and read back
+// with filters for the admin API and WebUI.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionRedeem             = "redeem"
+	ActionAddUser            = "add_user"
+	ActionUpdateUser         = "update_user"
+	ActionConsent            = "consent"
+	ActionArchiveEvent       = "archive_event"
+	ActionRejectRegistration = "reject_registration"
+	ActionChangeEmail        = "change_email"
+	ActionUnredeem           = "unredeem"
+	ActionMergeUser          = "merge_user"
+	ActionToggleFeature      = "toggle_feature"
+)
+
+// Entry is one recorded action
+type Entry struct {
+	Time     time.Time    `json:"time"`
+	Actor    string       `json:"actor"` // Who acted, such as token:<fingerprint> or telegram:<user ID>
+	Action   string       `json:"action"`
+	Email    string       `json:"email,omitempty"` // Guest the action concerns
+	Details  string       `json:"details,omitempty"`
+	Operator string       `json:"operator,omitempty"` // Staff member who acted with a shared token, empty if not named
+	User     *domain.User `json:"user,omitempty"`     // Guest record after the action, used to look up past states
+}
+
+// Filter selects entries. Empty fields match every entry. Actor, operator
+// and email match case-insensitive substrings, action must match exactly.
+type Filter struct {
+	Actor    string
+	Operator string
+	Action   string
+	Email    string
+	From     time.Time // Inclusive, zero for no lower bound
+	To       time.Time // Inclusive, zero for no upper bound
+	Offset   int       // Matching entries to skip, newest first
+	Limit    int       // Maximum entries returned, 0 for all
+}
+
+// matches returns true if the entry passes the filter
+func (f Filter) matches(e Entry) bool {
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	if f.Actor != "" && !strings.Contains(strings.ToLower(e.Actor), strings.ToLower(f.Actor)) {
+		return false
+	}
+	if f.Operator != "" && !strings.Contains(strings.ToLower(e.Operator), strings.ToLower(f.Operator)) {
+		return false
+	}
+	if f.Email != "" && !strings.Contains(strings.ToLower(e.Email), strings.ToLower(f.Email)) {
+		return false
+	}
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && e.Time.After(f.To) {
+		return false
+	}
+	return true
+}
+
+// Log appends entries to a file. Without a file, entries are kept in
+// memory until the process exits.
+type Log struct {
+	path    string
+	mu      sync.Mutex
+	entries []Entry // Only used without a file
+}
+
+// Open returns the audit log kept in the file at path, which is created on
+// the first write. An empty path keeps entries in memory.
+func Open(path string) (*Log, error) {
+	if path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+		}
+	}
+	return &Log{path: path}, nil
+}
+
+// Record appends an entry, setting its time if unset
+func (l *Log) Record(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.path == "" {
+		l.entries = append(l.entries, entry)
+		return nil
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	// A single write keeps lines whole when several processes append
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// Query returns the entries matching the filter, newest first, and the
+// number of matching entries before Offset and Limit were applied
+func (l *Log) Query(filter Filter) ([]Entry, int, error) {
+	entries, err := l.read()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var matched []Entry
+	for i := len(entries) - 1; i >= 0; i-- {
+		if filter.matches(entries[i]) {
+			matched = append(matched, entries[i])
+		}
+	}
+
+	total := len(matched)
+	if filter.Offset > 0 {
+		if filter.Offset >= len(matched) {
+			return []Entry{}, total, nil
+		}
+		matched = matched[filter.Offset:]
+	}
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	if matched == nil {
+		matched = []Entry{}
+	}
+	return matched, total, nil
+}
+
+// read returns all entries, oldest first. Lines that cannot be parsed,
+// such as one cut short by a crash, are skipped.
+func (l *Log) read() ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.path == "" {
+		return append([]Entry(nil), l.entries...), nil
+	}
+
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// contextKey is the type of keys stored in contexts by this package
+type contextKey struct{}
+
+// operatorKey is the context key of the operator
+type operatorKey struct{}
+
+// NewContext returns a context carrying the actor making the request
+func NewContext(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, contextKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by the context, or fallback
+// if there is none. The context may be nil or of any type, since services
+// accept contexts as any.
+func ActorFromContext(ctx any, fallback string) string {
+	c, ok := ctx.(context.Context)
+	if !ok {
+		return fallback
+	}
+	if actor, ok := c.Value(contextKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return fallback
+}
+
+// WithOperator returns a context carrying the staff member acting with a
+// token shared by several people, such as a bar's POS
+func WithOperator(ctx context.Context, operator string) context.Context {
+	return context.WithValue(ctx, operatorKey{}, operator)
+}
+
+// OperatorFromContext returns the operator carried by the context, "" if
+// there is none. Like ActorFromContext it accepts contexts as any.
+func OperatorFromContext(ctx any) string {
+	c, ok := ctx.(context.Context)
+	if !ok {
+		return ""
+	}
+	operator, _ := c.Value(operatorKey{}).(string)
+	return operator
+}
@@ -0,0 +1,79 @@
+package audit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+)
+
+func TestLog_QueryFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	log, err := audit.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+
+	start := time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)
+	entries := []audit.Entry{
+		{Time: start, Actor: "token:abc", Action: audit.ActionAddUser, Email: "guest@example.com"},
+		{Time: start.Add(time.Hour), Actor: "telegram:42", Action: audit.ActionRedeem, Email: "guest@example.com"},
+		{Time: start.Add(2 * time.Hour), Actor: "token:abc", Action: audit.ActionRedeem, Email: "other@example.com"},
+	}
+	for _, entry := range entries {
+		if err := log.Record(entry); err != nil {
+			t.Fatalf("Failed to record entry: %v", err)
+		}
+	}
+
+	// A line cut short by a crash is skipped
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	file.WriteString(`{"time":"2025-06-01T`)
+	file.Close()
+
+	tests := []struct {
+		name   string
+		filter audit.Filter
+		want   []string // Actors of the expected entries, newest first
+		total  int
+	}{
+		{"all", audit.Filter{}, []string{"token:abc", "telegram:42", "token:abc"}, 3},
+		{"who redeemed a guest", audit.Filter{Action: audit.ActionRedeem, Email: "GUEST@"}, []string{"telegram:42"}, 1},
+		{"actor", audit.Filter{Actor: "token"}, []string{"token:abc", "token:abc"}, 2},
+		{"date range", audit.Filter{From: start.Add(30 * time.Minute), To: start.Add(90 * time.Minute)}, []string{"telegram:42"}, 1},
+		{"page", audit.Filter{Offset: 1, Limit: 1}, []string{"telegram:42"}, 3},
+		{"past the end", audit.Filter{Offset: 5}, nil, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := log.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if total != tt.total {
+				t.Errorf("Expected %d matching entries, got %d", tt.total, total)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d entries, got %+v", len(tt.want), got)
+			}
+			for i, entry := range got {
+				if entry.Actor != tt.want[i] {
+					t.Errorf("Entry %d: expected actor %s, got %s", i, tt.want[i], entry.Actor)
+				}
+			}
+		})
+	}
+}
+
+func TestActorFromContext(t *testing.T) {
+	ctx := audit.NewContext(context.Background(), "token:abc")
+	if actor := audit.ActorFromContext(ctx, "fallback"); actor != "token:abc" {
+		t.Errorf("Expected the actor of the context, got %s", actor)
+	}
+	if actor := audit.ActorFromContext(nil, "fallback"); actor != "fallback" {
+		t.Errorf("Expected the fallback without a context, got %s", actor)
+	}
+}
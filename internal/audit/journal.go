@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// GenesisHash is the previous hash of the first record of a journal
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// JournalRecord is one line of a journal export. Each record carries the
+// hash of the one before it, so removing, reordering or editing a line
+// breaks the chain, and an HMAC of its own hash, so the chain cannot be
+// rebuilt without the key.
+type JournalRecord struct {
+	Seq   int             `json:"seq"`   // Position in the journal, starting at 1
+	Prev  string          `json:"prev"`  // Hash of the previous record, GenesisHash for the first
+	Entry json.RawMessage `json:"entry"` // The audit log entry, exactly as hashed
+	Hash  string          `json:"hash"`  // SHA-256 of seq, prev and entry
+	HMAC  string          `json:"hmac"`  // HMAC-SHA256 of hash with the journal key
+}
+
+// JournalSummary describes a written or verified journal. Publishing the
+// head hash lets readers notice records cut from the end later on.
+type JournalSummary struct {
+	Records int    `json:"records"`
+	Head    string `json:"head"` // Hash of the last record, GenesisHash for an empty journal
+}
+
+// JournalError reports the first line of a journal that fails verification
+type JournalError struct {
+	Line   int
+	Reason string
+}
+
+func (e *JournalError) Error() string {
+	return fmt.Sprintf("journal line %d: %s", e.Line, e.Reason)
+}
+
+// ErrNoJournalKey is returned when a journal is written or verified without a key
+var ErrNoJournalKey = errors.New("journal key is not set")
+
+// JournalWriter writes entries as a hash-chained journal, one JSON record
+// per line
+type JournalWriter struct {
+	w       io.Writer
+	key     []byte
+	summary JournalSummary
+}
+
+// NewJournalWriter returns a writer signing records with key
+func NewJournalWriter(w io.Writer, key []byte) (*JournalWriter, error) {
+	if len(key) == 0 {
+		return nil, ErrNoJournalKey
+	}
+	return &JournalWriter{w: w, key: key, summary: JournalSummary{Head: GenesisHash}}, nil
+}
+
+// Write appends an entry to the journal
+func (j *JournalWriter) Write(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	record := JournalRecord{Seq: j.summary.Records + 1, Prev: j.summary.Head, Entry: data}
+	record.Hash = journalHash(record.Seq, record.Prev, record.Entry)
+	record.HMAC = journalHMAC(j.key, record.Hash)
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := j.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	j.summary.Records = record.Seq
+	j.summary.Head = record.Hash
+	return nil
+}
+
+// Summary returns the number of records written and the head hash
+func (j *JournalWriter) Summary() JournalSummary {
+	return j.summary
+}
+
+// WriteJournal writes entries, oldest first, as a journal signed with key
+func WriteJournal(w io.Writer, entries []Entry, key []byte) (JournalSummary, error) {
+	journal, err := NewJournalWriter(w, key)
+	if err != nil {
+		return JournalSummary{}, err
+	}
+	for _, entry := range entries {
+		if err := journal.Write(entry); err != nil {
+			return journal.Summary(), err
+		}
+	}
+	return journal.Summary(), nil
+}
+
+// ExportJournal writes every entry of the log, oldest first, as a journal
+// signed with key. The same log always gives the same records, so a later
+// export starts with the records of an earlier one.
+func (l *Log) ExportJournal(w io.Writer, key []byte) (JournalSummary, error) {
+	entries, err := l.read()
+	if err != nil {
+		return JournalSummary{}, err
+	}
+	return WriteJournal(w, entries, key)
+}
+
+// VerifyJournal checks every record of a journal: its sequence number, the
+// link to the previous record, its hash and its HMAC with key. The first
+// broken record is returned as a *JournalError.
+func VerifyJournal(r io.Reader, key []byte) (JournalSummary, error) {
+	if len(key) == 0 {
+		return JournalSummary{}, ErrNoJournalKey
+	}
+
+	summary := JournalSummary{Head: GenesisHash}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var record JournalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return summary, &JournalError{Line: line, Reason: "not a journal record"}
+		}
+		switch {
+		case record.Seq != summary.Records+1:
+			return summary, &JournalError{Line: line, Reason: fmt.Sprintf("expected record %d, got %d", summary.Records+1, record.Seq)}
+		case record.Prev != summary.Head:
+			return summary, &JournalError{Line: line, Reason: "previous hash does not match, a record was removed or reordered"}
+		case record.Hash != journalHash(record.Seq, record.Prev, record.Entry):
+			return summary, &JournalError{Line: line, Reason: "hash does not match the entry, it was changed"}
+		case !hmac.Equal([]byte(record.HMAC), []byte(journalHMAC(key, record.Hash))):
+			return summary, &JournalError{Line: line, Reason: "HMAC does not match, the record was not signed with this key"}
+		}
+		summary.Records = record.Seq
+		summary.Head = record.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return summary, fmt.Errorf("failed to read journal: %w", err)
+	}
+	return summary, nil
+}
+
+// journalHash returns the hash of a record
+func journalHash(seq int, prev string, entry []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(strconv.Itoa(seq) + "|" + prev + "|"))
+	hash.Write(entry)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// journalHMAC returns the signature of a record hash
+func journalHMAC(key []byte, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("journal|" + hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
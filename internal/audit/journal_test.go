@@ -0,0 +1,77 @@
+package audit_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+)
+
+func TestJournal_Verify(t *testing.T) {
+	log, _ := audit.Open("")
+	start := time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)
+	for i, action := range []string{audit.ActionAddUser, audit.ActionRedeem, audit.ActionUnredeem} {
+		log.Record(audit.Entry{Time: start.Add(time.Duration(i) * time.Hour), Actor: "token:abc", Action: action, Email: "guest@example.com"})
+	}
+
+	key := []byte("sponsor-secret")
+	var journal bytes.Buffer
+	written, err := log.ExportJournal(&journal, key)
+	if err != nil {
+		t.Fatalf("Failed to export journal: %v", err)
+	}
+	if written.Records != 3 || written.Head == audit.GenesisHash {
+		t.Fatalf("Expected 3 records and a head hash, got %+v", written)
+	}
+
+	verified, err := audit.VerifyJournal(bytes.NewReader(journal.Bytes()), key)
+	if err != nil {
+		t.Fatalf("Expected the journal to verify, got %v", err)
+	}
+	if verified != written {
+		t.Errorf("Expected %+v, got %+v", written, verified)
+	}
+
+	lines := strings.SplitAfter(journal.String(), "\n")
+	tests := []struct {
+		name    string
+		journal string
+		key     string
+		line    int
+	}{
+		{"edited entry", strings.Replace(journal.String(), `"action":"redeem"`, `"action":"consent"`, 1), "sponsor-secret", 2},
+		{"removed record", lines[0] + lines[2], "sponsor-secret", 2},
+		{"reordered records", lines[1] + lines[0] + lines[2], "sponsor-secret", 1},
+		{"other key", journal.String(), "guessed", 1},
+		{"garbage", lines[0] + "not json\n", "sponsor-secret", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := audit.VerifyJournal(strings.NewReader(tt.journal), []byte(tt.key))
+			var journalErr *audit.JournalError
+			if !errors.As(err, &journalErr) {
+				t.Fatalf("Expected a journal error, got %v", err)
+			}
+			if journalErr.Line != tt.line {
+				t.Errorf("Expected line %d to fail, got %v", tt.line, err)
+			}
+		})
+	}
+
+	// A later export starts with the records of an earlier one
+	log.Record(audit.Entry{Time: start.Add(4 * time.Hour), Actor: "telegram:42", Action: audit.ActionRedeem, Email: "guest@example.com"})
+	var later bytes.Buffer
+	if _, err := log.ExportJournal(&later, key); err != nil {
+		t.Fatalf("Failed to export journal: %v", err)
+	}
+	if !strings.HasPrefix(later.String(), journal.String()) {
+		t.Error("Expected the later export to extend the earlier one")
+	}
+
+	if _, err := audit.WriteJournal(&later, nil, nil); !errors.Is(err, audit.ErrNoJournalKey) {
+		t.Errorf("Expected a missing key to fail, got %v", err)
+	}
+}
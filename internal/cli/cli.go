@@ -0,0 +1,203 @@
+// Package cli provides what the bot and admin binaries share on top of
+// cobra: the persistent --config and --output flags, JSON or table output,
+// consistent exit codes and the completion command.
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes returned by Execute
+const (
+	ExitOK          = 0
+	ExitError       = 1 // The command failed
+	ExitUsage       = 2 // Unknown command, invalid flag or argument
+	ExitUnavailable = 3 // The database or another dependency cannot be reached
+	ExitConfig      = 4 // The configuration cannot be loaded or is invalid
+)
+
+// Output formats selected with --output
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+// Context carries the persistent flags and output streams to a command
+type Context struct {
+	Config string    // Path to the configuration file
+	Output string    // OutputTable or OutputJSON
+	Stdin  io.Reader // Answers to confirmation prompts
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// exitError is an error with the exit code it should end the process with
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// Exit wraps an error so that Execute returns the given exit code
+func Exit(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// Usagef returns an error for invalid arguments, exiting with ExitUsage
+func Usagef(format string, args ...any) error {
+	return Exit(ExitUsage, fmt.Errorf(format, args...))
+}
+
+// Table is the tabular form of a command result
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Render writes a result as indented JSON with --output json, or as the
+// table built by table otherwise
+func (c *Context) Render(v any, table func() Table) error {
+	if c.Output == OutputJSON {
+		enc := json.NewEncoder(c.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	t := table()
+	w := tabwriter.NewWriter(c.Stdout, 0, 4, 2, ' ', 0)
+	if len(t.Header) > 0 {
+		fmt.Fprintln(w, strings.Join(t.Header, "\t"))
+	}
+	for _, row := range t.Rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// Printf writes a progress message to stderr, keeping stdout for results
+func (c *Context) Printf(format string, args ...any) {
+	fmt.Fprintf(c.Stderr, format, args...)
+}
+
+// Confirm asks a yes/no question on stderr and returns true only if the
+// answer read from stdin starts with y
+func (c *Context) Confirm(format string, args ...any) bool {
+	fmt.Fprintf(c.Stderr, format+" [y/N] ", args...)
+	answer, _ := bufio.NewReader(c.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return strings.HasPrefix(answer, "y")
+}
+
+// NewRoot returns the root command of a binary, with the persistent
+// --config and --output flags
+func NewRoot(use, short string) *cobra.Command {
+	root := &cobra.Command{
+		Use:           use,
+		Short:         short,
+		Args:          cobra.ArbitraryArgs, // Unknown commands are reported with ExitUsage
+		SilenceErrors: true,                // Execute reports errors with their exit code
+		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			output, _ := cmd.Flags().GetString("output")
+			if output != OutputTable && output != OutputJSON {
+				return Usagef("invalid output format %q, use %s or %s", output, OutputTable, OutputJSON)
+			}
+			return nil
+		},
+	}
+	root.PersistentFlags().String("config", "config.yaml", "path to configuration file")
+	root.PersistentFlags().String("output", OutputTable, "output format: table or json")
+	root.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return Exit(ExitUsage, err)
+	})
+	return root
+}
+
+// Run adapts a function taking the Context of the command to cobra's RunE
+func Run(fn func(ctx *Context, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		config, _ := cmd.Flags().GetString("config")
+		output, _ := cmd.Flags().GetString("output")
+		return fn(&Context{
+			Config: config,
+			Output: output,
+			Stdin:  cmd.InOrStdin(),
+			Stdout: cmd.OutOrStdout(),
+			Stderr: cmd.ErrOrStderr(),
+		}, args)
+	}
+}
+
+// Execute runs the command named by args, such as os.Args[1:], and returns
+// the exit code
+func Execute(root *cobra.Command, args []string, stdout, stderr io.Writer) int {
+	root.SetArgs(args)
+	root.SetOut(stdout)
+	root.SetErr(stderr)
+	root.InitDefaultCompletionCmd(args...)
+	requireCommand(root)
+
+	cmd, err := root.ExecuteC()
+	if err == nil {
+		return ExitOK
+	}
+	if cmd == nil {
+		cmd = root
+	}
+
+	fmt.Fprintf(stderr, "Error: %v\n", err)
+	var exit *exitError
+	if errors.As(err, &exit) {
+		if exit.code == ExitUsage {
+			fmt.Fprintf(stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
+		}
+		return exit.code
+	}
+	return ExitError
+}
+
+// requireCommand makes the commands that only group subcommands fail with
+// a usage error when run without one, or with one they don't have
+func requireCommand(cmd *cobra.Command) {
+	for _, sub := range cmd.Commands() {
+		requireCommand(sub)
+	}
+	if !cmd.HasSubCommands() || cmd.Runnable() {
+		return
+	}
+	cmd.Args = cobra.ArbitraryArgs
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if len(args) > 0 {
+			return Usagef("unknown command %q for %s", args[0], cmd.CommandPath())
+		}
+		return Usagef("missing command for %s", cmd.CommandPath())
+	}
+}
+
+// VersionCommand returns a command that prints the version of the binary
+func VersionCommand(version string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the version",
+		RunE: Run(func(ctx *Context, args []string) error {
+			info := map[string]string{"version": version, "go": runtime.Version()}
+			return ctx.Render(info, func() Table {
+				return Table{Rows: [][]string{{"Version:", version}, {"Go:", runtime.Version()}}}
+			})
+		}),
+	}
+}
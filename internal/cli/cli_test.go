@@ -0,0 +1,137 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ceesaxp/cocktail-bot/internal/cli"
+)
+
+// testTree returns a small command tree recording the arguments and flags
+// the leaf command ran with
+func testTree(got *[]string, limit *int, runErr error) *cobra.Command {
+	find := &cobra.Command{
+		Use: "find <email>",
+		RunE: cli.Run(func(ctx *cli.Context, args []string) error {
+			*got = append([]string{ctx.Config, ctx.Output}, args...)
+			if runErr != nil {
+				return runErr
+			}
+			return ctx.Render(map[string]int{"limit": *limit}, func() cli.Table {
+				return cli.Table{Header: []string{"EMAIL", "LIMIT"}, Rows: [][]string{{args[0], "n"}}}
+			})
+		}),
+	}
+	find.Flags().IntVar(limit, "limit", 10, "maximum results")
+
+	users := &cobra.Command{Use: "users"}
+	users.AddCommand(find)
+	root := cli.NewRoot("tool", "")
+	root.AddCommand(users)
+	return root
+}
+
+func TestExecute(t *testing.T) {
+	var got []string
+	var limit int
+	var stdout, stderr bytes.Buffer
+
+	// Persistent flags are accepted at any level, and leaf flags after arguments
+	code := cli.Execute(testTree(&got, &limit, nil),
+		[]string{"--config", "test.yaml", "users", "find", "a@example.com", "--limit", "3", "--output", "json"}, &stdout, &stderr)
+	if code != cli.ExitOK {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if strings.Join(got, " ") != "test.yaml json a@example.com" || limit != 3 {
+		t.Errorf("Unexpected arguments %v, limit %d", got, limit)
+	}
+	var result map[string]int
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil || result["limit"] != 3 {
+		t.Errorf("Expected JSON output, got %q: %v", stdout.String(), err)
+	}
+
+	// Table output is the default
+	stdout.Reset()
+	cli.Execute(testTree(&got, &limit, nil), []string{"users", "find", "--", "-odd@example.com"}, &stdout, &stderr)
+	if got[2] != "-odd@example.com" || !strings.HasPrefix(stdout.String(), "EMAIL") {
+		t.Errorf("Expected a table for %v, got %q", got, stdout.String())
+	}
+}
+
+func TestExecute_ExitCodes(t *testing.T) {
+	var got []string
+	var limit int
+	tests := []struct {
+		name   string
+		args   []string
+		runErr error
+		want   int
+	}{
+		{"help", []string{"users", "--help"}, nil, cli.ExitOK},
+		{"missing command", []string{"users"}, nil, cli.ExitUsage},
+		{"unknown command", []string{"groups"}, nil, cli.ExitUsage},
+		{"unknown subcommand", []string{"users", "list"}, nil, cli.ExitUsage},
+		{"unknown flag", []string{"users", "find", "--verbose"}, nil, cli.ExitUsage},
+		{"invalid output", []string{"--output", "xml", "users", "find", "a"}, nil, cli.ExitUsage},
+		{"failure", []string{"users", "find", "a"}, errors.New("boom"), cli.ExitError},
+		{"unavailable", []string{"users", "find", "a"}, cli.Exit(cli.ExitUnavailable, errors.New("down")), cli.ExitUnavailable},
+		{"config", []string{"users", "find", "a"}, cli.Exit(cli.ExitConfig, errors.New("bad config")), cli.ExitConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := cli.Execute(testTree(&got, &limit, tt.runErr), tt.args, &stdout, &stderr); code != tt.want {
+				t.Errorf("Expected exit code %d, got %d: %s", tt.want, code, stderr.String())
+			}
+		})
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	var got []string
+	var limit int
+	for shell, want := range map[string]string{
+		"bash":       "__start_tool",
+		"zsh":        "#compdef tool",
+		"fish":       "complete -c tool",
+		"powershell": "Register-ArgumentCompleter",
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := cli.Execute(testTree(&got, &limit, nil), []string{"completion", shell}, &stdout, &stderr); code != cli.ExitOK {
+			t.Fatalf("Expected exit code 0 for %s, got %d: %s", shell, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("Expected %s completion to contain %q, got:\n%s", shell, want, stdout.String())
+		}
+	}
+
+	// The scripts ask the binary to complete the command line
+	var stdout, stderr bytes.Buffer
+	cli.Execute(testTree(&got, &limit, nil), []string{"__complete", "users", ""}, &stdout, &stderr)
+	if !strings.HasPrefix(stdout.String(), "find") {
+		t.Errorf("Expected find to complete users, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := cli.Execute(testTree(&got, &limit, nil), []string{"completion", "tcsh"}, &stdout, &stderr); code != cli.ExitUsage {
+		t.Errorf("Expected a usage error for an unsupported shell, got %d", code)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	for answer, want := range map[string]bool{"y\n": true, "Yes\n": true, "n\n": false, "\n": false, "": false} {
+		var stderr bytes.Buffer
+		c := &cli.Context{Stdin: strings.NewReader(answer), Stderr: &stderr}
+		if got := c.Confirm("Apply %d fixes?", 2); got != want {
+			t.Errorf("Answer %q: expected %v, got %v", answer, want, got)
+		}
+		if stderr.String() != "Apply 2 fixes? [y/N] " {
+			t.Errorf("Unexpected prompt %q", stderr.String())
+		}
+	}
+}
@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -91,11 +93,17 @@ rate_limiting:
 	os.Setenv("COCKTAILBOT_TELEGRAM_TOKEN", "env-token")
 	os.Setenv("COCKTAILBOT_DATABASE_TYPE", "sqlite")
 	os.Setenv("COCKTAILBOT_RATE_LIMITING_REQUESTS_PER_MINUTE", "20")
+	os.Setenv("COCKTAILBOT_FEATURES_FLAGS", "kiosk=false, Vouchers=true")
+	os.Setenv("COCKTAILBOT_DATABASE_NEGATIVE_CACHE_BACKENDS", "googlesheet=2m, SQLite=0, csv=soon")
+	os.Setenv("COCKTAILBOT_API_TRUSTED_PROXIES", "10.0.0.0/8, 2001:db8::1")
 	defer func() {
 		os.Unsetenv("COCKTAILBOT_LOG_LEVEL")
 		os.Unsetenv("COCKTAILBOT_TELEGRAM_TOKEN")
 		os.Unsetenv("COCKTAILBOT_DATABASE_TYPE")
 		os.Unsetenv("COCKTAILBOT_RATE_LIMITING_REQUESTS_PER_MINUTE")
+		os.Unsetenv("COCKTAILBOT_FEATURES_FLAGS")
+		os.Unsetenv("COCKTAILBOT_DATABASE_NEGATIVE_CACHE_BACKENDS")
+		os.Unsetenv("COCKTAILBOT_API_TRUSTED_PROXIES")
 	}()
 
 	// Test loading with environment variables
@@ -121,12 +129,71 @@ rate_limiting:
 		t.Errorf("Expected RateLimiting.RequestsPerMinute to be 20, got %d", cfg.RateLimiting.RequestsPerMinute)
 	}
 
+	if kiosk, ok := cfg.Features.Flags["kiosk"]; !ok || kiosk || !cfg.Features.Flags["vouchers"] {
+		t.Errorf("Expected kiosk off and vouchers on, got %v", cfg.Features.Flags)
+	}
+	if backends := cfg.Database.NegativeCache.Backends; len(backends) != 2 || backends["googlesheet"] != Duration(2*time.Minute) || backends["sqlite"] != 0 {
+		t.Errorf("Expected negative cache TTLs of valid durations by backend, got %v", backends)
+	}
+	if proxies := cfg.API.TrustedProxies; len(proxies) != 2 || proxies[1] != "2001:db8::1" {
+		t.Errorf("Expected two trusted proxies, got %v", proxies)
+	}
+
 	// This should still be the default value from the file
 	if cfg.Telegram.User != "default-user" {
 		t.Errorf("Expected Telegram.User to be 'default-user', got '%s'", cfg.Telegram.User)
 	}
 }
 
+func TestValidate(t *testing.T) {
+	cfg := New()
+	cfg.API.TrustedProxies = []string{"10.0.0.0/8", "2001:db8::1"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid trusted proxies, got %v", err)
+	}
+	cfg.API.TrustedProxies = []string{"10.0.0.0/33"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected an invalid trusted proxy to be refused")
+	}
+
+	cfg = New()
+	cfg.Channel = "whatsapp"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected the WhatsApp channel without app secret to be refused")
+	}
+	cfg.WhatsApp.AppSecret = "app-secret"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the WhatsApp channel with app secret to be valid, got %v", err)
+	}
+}
+
+func TestLoadMessages(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+messages:
+  en:
+    eligible: "From the file"
+    welcome: "Welcome from the file"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	t.Setenv("COCKTAILBOT_MESSAGES_EN_ELIGIBLE", "From the environment")
+	t.Setenv("COCKTAILBOT_MESSAGES_DE_ALREADY_REDEEMED", "Schon eingelöst")
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	want := MessagesConfig{
+		"en": {"eligible": "From the environment", "welcome": "Welcome from the file"},
+		"de": {"already_redeemed": "Schon eingelöst"},
+	}
+	if !reflect.DeepEqual(cfg.Messages, want) {
+		t.Errorf("Expected messages %v, got %v", want, cfg.Messages)
+	}
+}
+
 func TestGetConfigPath(t *testing.T) {
 	// Test with provided path
 	providedPath := "provided/path/config.yaml"
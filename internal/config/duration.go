@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time span written with its unit, such as "5s" or "2m".
+// Numbers without a unit are rejected, so a timeout cannot be mistaken
+// for milliseconds or hours.
+type Duration time.Duration
+
+// ParseDuration reads a duration with its unit. Negative durations are
+// rejected.
+func ParseDuration(value string) (Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, use a unit such as 5s or 2m", value)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid duration %q, it cannot be negative", value)
+	}
+	return Duration(d), nil
+}
+
+// UnmarshalYAML parses the duration and reports the line of invalid values
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var value string
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	parsed, err := ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*d = parsed
+	return nil
+}
+
+// MarshalYAML writes the duration with its unit
+func (d Duration) MarshalYAML() (any, error) {
+	return d.String(), nil
+}
+
+// Duration returns the duration as a time.Duration
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// String formats the duration, such as "1m30s"
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDurationConfig(t *testing.T) {
+	load := func(content string) (*Config, error) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		return Load(path)
+	}
+
+	cfg, err := load(`
+database:
+  timeout: 2s
+  cache_ttl: 2m
+timeouts:
+  http_client: 1m30s
+`)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Database.Timeout.Duration() != 2*time.Second || cfg.Database.CacheTTL.Duration() != 2*time.Minute {
+		t.Errorf("Expected 2s and 2m, got %s and %s", cfg.Database.Timeout, cfg.Database.CacheTTL)
+	}
+	if cfg.Timeouts.HTTPClient.Duration() != 90*time.Second {
+		t.Errorf("Expected 1m30s, got %s", cfg.Timeouts.HTTPClient)
+	}
+	// Durations that are not set keep their defaults
+	if cfg.Database.BulkTimeout.Duration() != time.Minute || cfg.RateLimiting.CleanupInterval.Duration() != 10*time.Minute {
+		t.Errorf("Expected default durations, got %s and %s", cfg.Database.BulkTimeout, cfg.RateLimiting.CleanupInterval)
+	}
+
+	// Numbers without a unit and negative durations are rejected with their line
+	for _, value := range []string{"5", "-5s", "soon"} {
+		_, err := load("timeouts:\n  shutdown: " + value + "\n")
+		if err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("%s: expected an error on line 2, got %v", value, err)
+		}
+	}
+
+	t.Setenv("COCKTAILBOT_TIMEOUTS_SHUTDOWN", "20s")
+	cfg, err = Load("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Timeouts.Shutdown.Duration() != 20*time.Second {
+		t.Errorf("Expected the environment to set 20s, got %s", cfg.Timeouts.Shutdown)
+	}
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"embed"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sources holds the files declaring the configuration, whose field
+// comments document the settings
+//
+//go:embed config.go webui_config.go
+var sources embed.FS
+
+// Setting describes one configuration setting
+type Setting struct {
+	Path    string `json:"path"`              // YAML path, such as database.write_behind.max_batch
+	Type    string `json:"type"`              // string, int, float, bool, duration, list or map
+	Default any    `json:"default,omitempty"` // Value without a configuration file
+	Env     string `json:"env,omitempty"`     // Environment variable overriding the setting
+	Doc     string `json:"doc,omitempty"`     // Comment of the field
+}
+
+// docs maps "Type.Field" to the comment of a struct field, and "Type" to
+// the comment of a struct type
+var docs = sync.OnceValue(func() map[string]string {
+	result := make(map[string]string)
+	fset := token.NewFileSet()
+	for _, name := range []string{"config.go", "webui_config.go"} {
+		src, err := sources.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if text := commentText(gen.Doc); text != "" {
+					result[typeSpec.Name.Name] = text
+				}
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				for _, field := range structType.Fields.List {
+					text := commentText(field.Comment)
+					if text == "" {
+						text = commentText(field.Doc)
+					}
+					for _, fieldName := range field.Names {
+						if text != "" {
+							result[typeSpec.Name.Name+"."+fieldName.Name] = text
+						}
+					}
+				}
+			}
+		}
+	}
+	return result
+})
+
+// commentText returns a comment as one line of text
+func commentText(group *ast.CommentGroup) string {
+	if group == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(group.Text()), " ")
+}
+
+// Settings lists every setting with its default, environment variable
+// and documentation, in the order of the configuration file
+func Settings() []Setting {
+	var settings []Setting
+	walkSettings(reflect.ValueOf(*New()), "", func(s Setting, _ reflect.Value) {
+		settings = append(settings, s)
+	}, nil)
+	return settings
+}
+
+// walkSettings calls leaf for every setting below v and section for every
+// nested struct, parents first
+func walkSettings(v reflect.Value, prefix string, leaf func(Setting, reflect.Value), section func(path, doc string)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if !field.IsExported() || key == "" || key == "-" {
+			continue
+		}
+		path := prefix + key
+		doc := docs()[t.Name()+"."+field.Name]
+		value := v.Field(i)
+
+		if field.Type.Kind() == reflect.Struct {
+			if doc == "" {
+				doc = docs()[field.Type.Name()]
+			}
+			if section != nil {
+				section(path, doc)
+			}
+			walkSettings(value, path+".", leaf, section)
+			continue
+		}
+
+		setting := Setting{Path: path, Type: settingType(field.Type), Default: value.Interface(), Doc: doc}
+		if env := field.Tag.Get("env"); env != "" {
+			setting.Env = envPrefix + env
+		}
+		if d, ok := setting.Default.(Duration); ok {
+			setting.Default = d.String()
+		}
+		leaf(setting, value)
+	}
+}
+
+// settingType names the type of a setting as it is written in YAML
+func settingType(t reflect.Type) string {
+	if t == reflect.TypeOf(Duration(0)) {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int64:
+		return "int"
+	case reflect.Float64:
+		return "float"
+	case reflect.Slice:
+		return "list"
+	case reflect.Map:
+		return "map"
+	default:
+		return "string"
+	}
+}
+
+// WriteExample writes a configuration file with every setting at its
+// default, documented by the comments of the configuration structs and
+// the environment variables overriding the settings
+func WriteExample(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	sections := map[string]*yaml.Node{"": root}
+	parent := func(path string) *yaml.Node {
+		i := strings.LastIndex(path, ".")
+		if i < 0 {
+			return root
+		}
+		return sections[path[:i]]
+	}
+	keyNode := func(path, doc string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: path[strings.LastIndex(path, ".")+1:], HeadComment: wrapComment(doc)}
+	}
+
+	var err error
+	section := func(path, doc string) {
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		sections[path] = node
+		p := parent(path)
+		p.Content = append(p.Content, keyNode(path, doc), node)
+	}
+	leaf := func(s Setting, value reflect.Value) {
+		node := &yaml.Node{}
+		if encodeErr := node.Encode(value.Interface()); encodeErr != nil && err == nil {
+			err = encodeErr
+		}
+		key := keyNode(s.Path, s.Doc)
+		// Comments of block lists go after the key, above the items
+		if node.Kind == yaml.ScalarNode || len(node.Content) == 0 {
+			node.LineComment = s.Env
+		} else {
+			key.LineComment = s.Env
+		}
+		p := parent(s.Path)
+		p.Content = append(p.Content, key, node)
+	}
+	walkSettings(reflect.ValueOf(*New()), "", leaf, section)
+	if err != nil {
+		return err
+	}
+
+	doc := &yaml.Node{
+		Kind:        yaml.DocumentNode,
+		HeadComment: wrapComment("Cocktail Bot configuration with every setting at its default, generated by cocktail-admin config schema. Environment variables override the settings they follow."),
+		Content:     []*yaml.Node{root},
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// wrapComment breaks a comment into lines of about 76 characters
+func wrapComment(text string) string {
+	var b strings.Builder
+	width := 0
+	for _, word := range strings.Fields(text) {
+		switch {
+		case width == 0:
+		case width+1+len(word) > 76:
+			b.WriteByte('\n')
+			width = 0
+		default:
+			b.WriteByte(' ')
+			width++
+		}
+		b.WriteString(word)
+		width += len(word)
+	}
+	return b.String()
+}
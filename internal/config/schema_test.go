@@ -0,0 +1,102 @@
+package config
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSettings(t *testing.T) {
+	settings := make(map[string]Setting)
+	for _, s := range Settings() {
+		settings[s.Path] = s
+	}
+
+	s, ok := settings["database.write_behind.max_batch"]
+	if !ok || s.Type != "int" || s.Default != 100 || s.Env != "COCKTAILBOT_DATABASE_WRITE_BEHIND_MAX_BATCH" || s.Doc != "Users written per batch at most" {
+		t.Errorf("Unexpected setting %+v", s)
+	}
+	if s := settings["database.timeout"]; s.Type != "duration" || s.Default != "5s" {
+		t.Errorf("Expected durations with their unit, got %+v", s)
+	}
+	if s := settings["webui.kiosk.pin"]; s.Env != "COCKTAILBOT_WEBUI_KIOSK_PIN" || s.Doc == "" {
+		t.Errorf("Expected the WebUI settings to be documented, got %+v", s)
+	}
+}
+
+// TestSettings_EnvInSync checks that the env tags name exactly the
+// variables read by loadFromEnvironment
+func TestSettings_EnvInSync(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "config.go", nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse config.go: %v", err)
+	}
+	read := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if fn, ok := n.(*ast.FuncDecl); ok {
+			return fn.Name.Name == "loadFromEnvironment"
+		}
+		if expr, ok := n.(*ast.BinaryExpr); ok {
+			if ident, ok := expr.X.(*ast.Ident); ok && ident.Name == "envPrefix" {
+				if lit, ok := expr.Y.(*ast.BasicLit); ok {
+					name, _ := strconv.Unquote(lit.Value)
+					read[envPrefix+name] = true
+				}
+			}
+		}
+		return true
+	})
+
+	tagged := make(map[string]bool)
+	for _, s := range Settings() {
+		if s.Env == "" {
+			continue
+		}
+		// Patterns such as MESSAGES_<LANG>_<KEY> are read by their prefix
+		name, _, _ := strings.Cut(s.Env, "<")
+		tagged[name] = true
+		if !read[name] {
+			t.Errorf("%s is tagged on %s but not read", s.Env, s.Path)
+		}
+	}
+	for name := range read {
+		if !tagged[name] {
+			t.Errorf("%s is read but no setting is tagged with it", name)
+		}
+	}
+}
+
+func TestWriteExample(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteExample(&buf); err != nil {
+		t.Fatalf("Failed to write example: %v", err)
+	}
+	example := buf.String()
+	for _, want := range []string{
+		"log_level: info # COCKTAILBOT_LOG_LEVEL",
+		"    # Users written per batch at most\n    max_batch: 100 # COCKTAILBOT_DATABASE_WRITE_BEHIND_MAX_BATCH",
+		"  enabled: # COCKTAILBOT_LANGUAGE_ENABLED\n    - en",
+	} {
+		if !strings.Contains(example, want) {
+			t.Errorf("Expected the example to contain %q", want)
+		}
+	}
+
+	// Every key of the example is a setting, and it loads as the defaults
+	var cfg Config
+	decoder := yaml.NewDecoder(&buf)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		t.Fatalf("Failed to load the example: %v", err)
+	}
+	defaults := New()
+	if cfg.Database.WriteBehind != defaults.Database.WriteBehind || cfg.WebUI.StatusPage != defaults.WebUI.StatusPage || len(cfg.API.PublicEndpoints) != 3 {
+		t.Errorf("Expected the example to hold the defaults, got %+v", cfg)
+	}
+}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 // WebUIConfig contains configuration for the web UI
 type WebUIConfig struct {
 	// Enable or disable the web UI
@@ -11,24 +13,132 @@ type WebUIConfig struct {
 	// Port to listen on
 	Port int `yaml:"port" env:"WEBUI_PORT"`
 
+	// Connections come from a balancer such as HAProxy sending the PROXY protocol
+	ProxyProtocol bool `yaml:"proxy_protocol" env:"WEBUI_PROXY_PROTOCOL"`
+
 	// Session secret for cookies (optional, auto-generated if not provided)
 	SessionSecret string `yaml:"session_secret" env:"WEBUI_SESSION_SECRET"`
 
+	// Sessions end after this long without requests, and this long after login
+	SessionIdleTimeout Duration `yaml:"session_idle_timeout" env:"WEBUI_SESSION_IDLE_TIMEOUT"`
+	SessionMaxAge      Duration `yaml:"session_max_age" env:"WEBUI_SESSION_MAX_AGE"`
+
+	// Send the session cookie over plain HTTP too, for local development
+	// without TLS. Browsers otherwise only return it over HTTPS.
+	InsecureCookies bool `yaml:"insecure_cookies" env:"WEBUI_INSECURE_COOKIES"`
+
+	// Token the WebUI calls the API with for the dashboard and kiosk. Needed
+	// when API tokens are configured hashed, defaults to the first plain one.
+	APIToken string `yaml:"api_token" env:"WEBUI_API_TOKEN"`
+
+	// Admin token for admin-only API calls, such as the database status
+	AdminToken string `yaml:"admin_token" env:"WEBUI_ADMIN_TOKEN"`
+
 	// Template directory path (optional for embedded templates)
 	TemplateDir string `yaml:"template_dir" env:"WEBUI_TEMPLATE_DIR"`
 
 	// Static files directory path (optional for embedded static files)
 	StaticDir string `yaml:"static_dir" env:"WEBUI_STATIC_DIR"`
+
+	// Range of the user lists without dates, a report period such as "365d" or "event"
+	DefaultPeriod string `yaml:"default_period" env:"WEBUI_DEFAULT_PERIOD"`
+
+	// Public self-service page for guests at the bar
+	Kiosk KioskConfig `yaml:"kiosk"`
+
+	// Logo and colors telling the dashboards of co-hosted events apart
+	Branding BrandingConfig `yaml:"branding"`
+
+	// Public page with live counts of the event, for a screen at the venue
+	StatusPage StatusPageConfig `yaml:"status_page"`
+}
+
+// BrandingConfig contains the look of the web UI for an event. Pages are
+// titled after event.name.
+type BrandingConfig struct {
+	// Logo shown in the navigation bar, a URL or a local image file
+	Logo string `yaml:"logo" env:"WEBUI_BRANDING_LOGO"`
+
+	// Icon shown in browser tabs, a URL or a local image file
+	Favicon string `yaml:"favicon" env:"WEBUI_BRANDING_FAVICON"`
+
+	// CSS colors, e.g. "#7b2cbf": buttons and card headers, and the navigation bar
+	AccentColor string `yaml:"accent_color" env:"WEBUI_BRANDING_ACCENT_COLOR"`
+	NavbarColor string `yaml:"navbar_color" env:"WEBUI_BRANDING_NAVBAR_COLOR"`
+}
+
+// KioskConfig contains configuration for the kiosk page, where guests check
+// their email on a tablet and staff confirm the redemption with a PIN
+type KioskConfig struct {
+	// Serve the kiosk page at /kiosk without login
+	Enabled bool `yaml:"enabled" env:"WEBUI_KIOSK_ENABLED"`
+
+	// PIN staff enter to confirm a redemption (required)
+	PIN string `yaml:"pin" env:"WEBUI_KIOSK_PIN"`
+
+	// Requests allowed per client IP, kept low as the page is public
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	RequestsPerHour   int `yaml:"requests_per_hour"`
+
+	// Cloudflare Turnstile keys, empty secret disables the CAPTCHA
+	CaptchaSiteKey string `yaml:"captcha_site_key"`
+	CaptchaSecret  string `yaml:"captcha_secret" env:"WEBUI_KIOSK_CAPTCHA_SECRET"`
+
+	// Endpoint checking CAPTCHA answers
+	CaptchaVerifyURL string `yaml:"captcha_verify_url"`
+}
+
+// StatusPageConfig contains configuration for the public status page at
+// /public/{event-slug}, which shows how many cocktails were served and no
+// guest data
+type StatusPageConfig struct {
+	// Serve the status page without login
+	Enabled bool `yaml:"enabled" env:"WEBUI_STATUS_PAGE_ENABLED"`
+
+	// Signs the link, so only those given it can open the page. Empty
+	// serves the page to anyone who knows the event slug.
+	Secret string `yaml:"secret" env:"WEBUI_STATUS_PAGE_SECRET"`
+
+	// How long counts are reused, and cached by browsers and proxies
+	CacheTTL Duration `yaml:"cache_ttl"`
+
+	// Requests allowed per client IP
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	RequestsPerHour   int `yaml:"requests_per_hour"`
 }
 
 // DefaultWebUIConfig returns the default WebUI configuration
 func DefaultWebUIConfig() WebUIConfig {
 	return WebUIConfig{
-		Enabled:       false,
-		Host:          "0.0.0.0",
-		Port:          8081,
-		SessionSecret: "",
-		TemplateDir:   "", // Empty means use embedded templates
-		StaticDir:     "", // Empty means use embedded static files
+		Enabled:            false,
+		Host:               "0.0.0.0",
+		Port:               8081,
+		SessionSecret:      "",
+		SessionIdleTimeout: Duration(2 * time.Hour),
+		SessionMaxAge:      Duration(24 * time.Hour),
+		TemplateDir:        "", // Empty means use embedded templates
+		StaticDir:          "", // Empty means use embedded static files
+		Kiosk:              DefaultKioskConfig(),
+		StatusPage:         DefaultStatusPageConfig(),
+	}
+}
+
+// DefaultKioskConfig returns the default kiosk configuration
+func DefaultKioskConfig() KioskConfig {
+	return KioskConfig{
+		Enabled:           false,
+		RequestsPerMinute: 5,
+		RequestsPerHour:   30,
+		CaptchaVerifyURL:  "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+	}
+}
+
+// DefaultStatusPageConfig returns the default status page configuration
+func DefaultStatusPageConfig() StatusPageConfig {
+	return StatusPageConfig{
+		Enabled:           false,
+		CacheTTL:          Duration(30 * time.Second),
+		RequestsPerMinute: 10,
+		RequestsPerHour:   300,
 	}
 }
@@ -0,0 +1,95 @@
+// Package crashreport handles panics recovered by the API and the chat
+// bots: it logs them with their stack, counts them for the metrics and
+// passes them to an optional reporter such as Sentry.
+package crashreport
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// reportTimeout bounds how long a reporter may take to send a crash
+const reportTimeout = 10 * time.Second
+
+// Sources of crashes
+const (
+	SourceAPI      = "api"
+	SourceTelegram = "telegram"
+)
+
+// Crash is a recovered panic
+type Crash struct {
+	Source    string // Where it was recovered, such as api or telegram
+	Message   string // The panic value
+	Stack     []byte
+	Time      time.Time
+	Method    string // Of the API request, empty otherwise
+	Path      string
+	RequestID string
+}
+
+// Reporter sends crashes to an error tracker
+type Reporter interface {
+	Report(ctx context.Context, crash Crash) error
+}
+
+var (
+	// reporter receives every crash, nil if none is set
+	reporter atomic.Pointer[Reporter]
+
+	// counts holds the crashes of every source
+	counts sync.Map
+)
+
+// SetReporter sets the reporter receiving every crash of the process, or
+// removes it if r is nil
+func SetReporter(r Reporter) {
+	if r == nil {
+		reporter.Store(nil)
+		return
+	}
+	reporter.Store(&r)
+}
+
+// New returns the crash of a panic value, with the stack of the recovering
+// goroutine
+func New(source string, value any, stack []byte) Crash {
+	return Crash{Source: source, Message: fmt.Sprint(value), Stack: stack, Time: time.Now()}
+}
+
+// Record logs a crash with its stack, counts it and passes it to the
+// reporter in the background, so the recovering code is not held up by
+// the error tracker
+func Record(log *logger.Logger, crash Crash) {
+	log.Error("Recovered from panic", "source", crash.Source, "panic", crash.Message, "stack", string(crash.Stack))
+
+	count, _ := counts.LoadOrStore(crash.Source, new(atomic.Int64))
+	count.(*atomic.Int64).Add(1)
+
+	r := reporter.Load()
+	if r == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+		defer cancel()
+		if err := (*r).Report(ctx, crash); err != nil {
+			log.Error("Failed to report panic", "source", crash.Source, "error", err)
+		}
+	}()
+}
+
+// Counts returns the crashes recovered since start by source
+func Counts() map[string]int64 {
+	result := make(map[string]int64)
+	counts.Range(func(key, value any) bool {
+		result[key.(string)] = value.(*atomic.Int64).Load()
+		return true
+	})
+	return result
+}
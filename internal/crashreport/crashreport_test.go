@@ -0,0 +1,59 @@
+package crashreport_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/crashreport"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+func TestSentry(t *testing.T) {
+	events := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/42/store/" {
+			t.Errorf("Expected the store endpoint of the project, got %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("X-Sentry-Auth"); !strings.Contains(auth, "sentry_key=public") {
+			t.Errorf("Expected the key in the auth header, got %q", auth)
+		}
+		var event map[string]any
+		json.NewDecoder(r.Body).Decode(&event)
+		events <- event
+	}))
+	defer server.Close()
+
+	if _, err := crashreport.NewSentry("https://o0.ingest.sentry.io/42", "", nil); err == nil {
+		t.Error("Expected a DSN without a key to be refused")
+	}
+	sentry, err := crashreport.NewSentry(strings.Replace(server.URL, "://", "://public@", 1)+"/42", "staging", server.Client())
+	if err != nil {
+		t.Fatalf("Failed to create reporter: %v", err)
+	}
+
+	crashreport.SetReporter(sentry)
+	defer crashreport.SetReporter(nil)
+	crash := crashreport.New(crashreport.SourceAPI, "nil map", []byte("goroutine 1 [running]:"))
+	crash.Method, crash.Path, crash.RequestID = "GET", "/api/v1/email/status", "abc"
+	before := crashreport.Counts()[crashreport.SourceAPI]
+	crashreport.Record(logger.New("error"), crash)
+
+	if count := crashreport.Counts()[crashreport.SourceAPI]; count != before+1 {
+		t.Errorf("Expected the crash to be counted, got %d", count)
+	}
+	select {
+	case event := <-events:
+		if event["message"] != "panic: nil map" || event["environment"] != "staging" || event["level"] != "fatal" {
+			t.Errorf("Unexpected event: %v", event)
+		}
+		if tags, _ := event["tags"].(map[string]any); tags["request_id"] != "abc" {
+			t.Errorf("Expected the request ID as a tag, got %v", event["tags"])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the crash to be reported")
+	}
+}
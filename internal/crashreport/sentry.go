@@ -0,0 +1,130 @@
+package crashreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Sentry reports crashes to Sentry through its store endpoint, which needs
+// no SDK
+type Sentry struct {
+	endpoint    string
+	auth        string // X-Sentry-Auth header
+	environment string
+	client      *http.Client
+}
+
+// NewSentry creates a reporter for a Sentry DSN, such as
+// https://<key>@o0.ingest.sentry.io/<project>. Crashes are tagged with the
+// environment if it is not empty.
+func NewSentry(dsn, environment string, client *http.Client) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	key := u.User.Username()
+	path := strings.TrimSuffix(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	prefix, project := path[:max(slash, 0)], path[slash+1:]
+	if u.Scheme == "" || u.Host == "" || key == "" || project == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: expected https://<key>@<host>/<project>")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: reportTimeout}
+	}
+	return &Sentry{
+		endpoint:    fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project),
+		auth:        "Sentry sentry_version=7, sentry_client=cocktail-bot/1.0, sentry_key=" + key,
+		environment: environment,
+		client:      client,
+	}, nil
+}
+
+// sentryEvent is the part of a Sentry event sent for a crash
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Logger      string            `json:"logger"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Message     string            `json:"message"`
+	Exception   sentryExceptions  `json:"exception"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Request     *sentryRequest    `json:"request,omitempty"`
+	Extra       map[string]string `json:"extra,omitempty"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sentryRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// Report sends a crash as a fatal event. The stack is sent as text, since
+// Sentry cannot map Go stacks without the SDK.
+func (s *Sentry) Report(ctx context.Context, crash Crash) error {
+	id := make([]byte, 16)
+	rand.Read(id)
+	hostname, _ := os.Hostname()
+	event := sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   crash.Time.UTC().Format(time.RFC3339),
+		Platform:    "go",
+		Level:       "fatal",
+		Logger:      crash.Source,
+		ServerName:  hostname,
+		Environment: s.environment,
+		Message:     "panic: " + crash.Message,
+		Exception:   sentryExceptions{Values: []sentryException{{Type: "panic", Value: crash.Message}}},
+		Tags:        map[string]string{"source": crash.Source},
+		Extra:       map[string]string{"stack": string(crash.Stack)},
+	}
+	if crash.RequestID != "" {
+		event.Tags["request_id"] = crash.RequestID
+	}
+	if crash.Path != "" {
+		event.Request = &sentryRequest{Method: crash.Method, URL: crash.Path}
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create Sentry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send to Sentry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("sentry returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
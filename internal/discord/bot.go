@@ -0,0 +1,436 @@
+// Package discord serves the /cocktail slash command on Discord. Discord
+// posts interactions to an HTTP endpoint, so no gateway connection is needed.
+package discord
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/httpclient"
+	"github.com/ceesaxp/cocktail-bot/internal/i18n"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ports"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// maxInteractionBody limits the size of interaction payloads
+const maxInteractionBody = 1 << 20
+
+// Interaction types
+const (
+	interactionPing      = 1
+	interactionCommand   = 2
+	interactionComponent = 3
+)
+
+// Interaction response types
+const (
+	responsePong    = 1
+	responseMessage = 4
+	responseUpdate  = 7
+)
+
+// flagEphemeral makes a reply visible only to the user who invoked the command
+const flagEphemeral = 64
+
+// Message component types and button styles
+const (
+	componentActionRow = 1
+	componentButton    = 2
+	styleSecondary     = 2
+	styleSuccess       = 3
+)
+
+// ServiceInterface defines the methods the Discord bot uses from the service
+type ServiceInterface interface {
+	ports.Guests
+}
+
+// CommandRegistrar publishes the slash command to Discord
+type CommandRegistrar interface {
+	RegisterCommands(ctx context.Context, guildID string) error
+}
+
+// Bot answers /cocktail slash commands
+type Bot struct {
+	registrar  CommandRegistrar
+	service    ServiceInterface
+	config     *config.Config
+	logger     *logger.Logger
+	translator *i18n.Translator
+	publicKey  ed25519.PublicKey
+	server     *http.Server
+
+	mu         sync.Mutex
+	emailCache map[int64]string // Map of Discord user ID -> email awaiting redemption
+}
+
+// New creates a Discord bot checking interactions with the hex encoded public key
+func New(registrar CommandRegistrar, svc ServiceInterface, cfg *config.Config, logger *logger.Logger) (*Bot, error) {
+	publicKey, err := hex.DecodeString(cfg.Discord.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, errors.New("discord public key must be a hex encoded Ed25519 key")
+	}
+
+	translator := i18n.NewWithConfig(cfg)
+	i18n.LoadDefaultTranslations(translator)
+
+	return &Bot{
+		registrar:  registrar,
+		service:    svc,
+		config:     cfg,
+		logger:     logger,
+		translator: translator,
+		publicKey:  ed25519.PublicKey(publicKey),
+		emailCache: make(map[int64]string),
+	}, nil
+}
+
+// NewFromConfig creates a Discord bot using the application settings
+func NewFromConfig(cfg *config.Config, svc *service.Service, logger *logger.Logger) (*Bot, error) {
+	client, err := NewClient(cfg.Discord.BaseURL, cfg.Discord.BotToken, cfg.Discord.ApplicationID, httpclient.New(httpclient.FromConfig(cfg, "discord")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Discord client: %w", err)
+	}
+
+	return New(client, svc, cfg, logger)
+}
+
+// Start registers the slash command and starts the interactions endpoint
+func (b *Bot) Start() error {
+	if b.server != nil {
+		return fmt.Errorf("bot is already running")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := b.registrar.RegisterCommands(ctx, b.config.Discord.GuildID); err != nil {
+		b.logger.Error("Failed to register Discord commands", "error", err)
+	}
+
+	addr := fmt.Sprintf("%s:%d", b.config.Discord.Host, b.config.Discord.Port)
+	b.server = &http.Server{
+		Addr:         addr,
+		Handler:      b,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	go func() {
+		if err := b.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			b.logger.Error("Discord interactions server error", "error", err)
+		}
+	}()
+
+	b.logger.Info("Discord bot started", "addr", addr, "application_id", b.config.Discord.ApplicationID)
+	return nil
+}
+
+// Stop stops the interactions endpoint and waits for running handlers
+func (b *Bot) Stop() {
+	if b.server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.Timeouts.Shutdown.Duration())
+	defer cancel()
+	if err := b.server.Shutdown(ctx); err != nil {
+		b.logger.Error("Error stopping Discord interactions server", "error", err)
+	}
+	b.server = nil
+
+	b.logger.Info("Bot stopped")
+}
+
+// interaction is the part of a Discord interaction the bot reads
+type interaction struct {
+	Type   int    `json:"type"`
+	Locale string `json:"locale"`
+	Data   struct {
+		Name     string              `json:"name"`
+		Options  []interactionOption `json:"options"`
+		CustomID string              `json:"custom_id"`
+	} `json:"data"`
+	Member *struct {
+		User  discordUser `json:"user"`
+		Roles []string    `json:"roles"`
+	} `json:"member"` // Set in servers
+	User *discordUser `json:"user"` // Set in direct messages
+}
+
+// interactionOption is an option the user filled in
+type interactionOption struct {
+	Name    string              `json:"name"`
+	Value   any                 `json:"value"`
+	Options []interactionOption `json:"options"`
+}
+
+// discordUser is a Discord account
+type discordUser struct {
+	ID string `json:"id"`
+}
+
+// response answers an interaction
+type response struct {
+	Type int           `json:"type"`
+	Data *responseData `json:"data,omitempty"`
+}
+
+// responseData is the message sent or updated by a response
+type responseData struct {
+	Content    string      `json:"content"`
+	Flags      int         `json:"flags,omitempty"`
+	Components []component `json:"components"`
+}
+
+// component is an action row or a button
+type component struct {
+	Type       int         `json:"type"`
+	Style      int         `json:"style,omitempty"`
+	Label      string      `json:"label,omitempty"`
+	CustomID   string      `json:"custom_id,omitempty"`
+	Components []component `json:"components,omitempty"`
+}
+
+// userID returns the ID of the user who sent the interaction
+func (i *interaction) userID() (int64, error) {
+	id := ""
+	if i.Member != nil {
+		id = i.Member.User.ID
+	} else if i.User != nil {
+		id = i.User.ID
+	}
+	return strconv.ParseInt(id, 10, 64)
+}
+
+// roles returns the server roles of the user who sent the interaction
+func (i *interaction) roles() []string {
+	if i.Member == nil {
+		return nil
+	}
+	return i.Member.Roles
+}
+
+// findOption finds an option by name, searching subcommands
+func findOption(options []interactionOption, name string) (interactionOption, bool) {
+	for _, option := range options {
+		if option.Name == name {
+			return option, true
+		}
+		if found, ok := findOption(option.Options, name); ok {
+			return found, true
+		}
+	}
+	return interactionOption{}, false
+}
+
+// ServeHTTP checks the signature and answers an interaction
+func (b *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxInteractionBody))
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	// Discord requires rejecting interactions with an invalid signature
+	if !b.validSignature(body, r.Header.Get("X-Signature-Ed25519"), r.Header.Get("X-Signature-Timestamp")) {
+		http.Error(w, "Invalid request signature", http.StatusUnauthorized)
+		return
+	}
+
+	var in interaction
+	if err := json.Unmarshal(body, &in); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b.handleInteraction(r.Context(), &in))
+}
+
+// validSignature checks the Ed25519 signature of timestamp and payload
+func (b *Bot) validSignature(payload []byte, signature, timestamp string) bool {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(b.publicKey, append([]byte(timestamp), payload...), sig)
+}
+
+// handleInteraction returns the response to an interaction
+func (b *Bot) handleInteraction(ctx context.Context, in *interaction) response {
+	if in.Type == interactionPing {
+		return response{Type: responsePong}
+	}
+
+	lang := b.translator.DetectLanguage(in.Locale)
+	userID, err := in.userID()
+	if err != nil {
+		b.logger.Warn("Ignoring Discord interaction without user", "type", in.Type)
+		return b.reply(lang, "error_occurred")
+	}
+	if b.service.IsUserBlocked(userID) {
+		b.logger.Debug("Refusing blocked user", "user_id", userID)
+		return b.reply(lang, "user_blocked")
+	}
+
+	switch in.Type {
+	case interactionCommand:
+		b.service.TrackInteraction(lang, in.Data.Name)
+		option, ok := findOption(in.Data.Options, "email")
+		email, _ := option.Value.(string)
+		if !ok || !utils.IsValidEmail(email) {
+			return b.reply(lang, "invalid_email")
+		}
+		return b.checkEmail(ctx, lang, userID, utils.NormalizeEmail(email))
+	case interactionComponent:
+		b.service.TrackInteraction(lang, "")
+		return b.handleButton(ctx, lang, userID, in.roles(), in.Data.CustomID)
+	default:
+		return b.reply(lang, "error_occurred")
+	}
+}
+
+// checkEmail looks up the email and replies with its status
+func (b *Bot) checkEmail(ctx context.Context, lang string, userID int64, email string) response {
+	status, user, err := b.service.CheckEmailStatus(ctx, userID, email)
+	if err != nil {
+		b.logger.Error("Error checking email status", "email", email, "error", err)
+		return b.reply(lang, "error_occurred")
+	}
+
+	switch status {
+	case "rate_limited":
+		return b.retryReply(lang, "rate_limited", b.service.RetryAfter(ctx, userID))
+	case "not_found":
+		return b.reply(lang, "email_not_found")
+	case "unavailable":
+		return b.retryReply(lang, "system_unavailable", b.service.UnavailableRetryAfter())
+	case "denied":
+		return b.reply(lang, "email_denied")
+	case "archived":
+		return b.reply(lang, "event_archived")
+	case "redeemed":
+		return b.reply(lang, "already_redeemed", "date", user.Redeemed.Format("January 2, 2006"))
+	case "eligible":
+		b.mu.Lock()
+		b.emailCache[userID] = email
+		b.mu.Unlock()
+		b.service.TrackRedeemButton()
+		return b.eligibleReply(lang)
+	default:
+		return b.reply(lang, "error_occurred")
+	}
+}
+
+// handleButton handles the redeem and skip buttons. Only members holding
+// one of the redeem roles can confirm a redemption.
+func (b *Bot) handleButton(ctx context.Context, lang string, userID int64, roles []string, id string) response {
+	if id == "redeem" && !b.canRedeem(roles) {
+		b.logger.Warn("User without redeem role attempted redemption", "user_id", userID)
+		return b.reply(lang, "redeem_not_allowed")
+	}
+
+	b.mu.Lock()
+	email, ok := b.emailCache[userID]
+	delete(b.emailCache, userID)
+	b.mu.Unlock()
+
+	if !ok {
+		return b.update(lang, "email_not_cached")
+	}
+
+	switch id {
+	case "redeem":
+		redemptionTime, err := b.service.RedeemCocktail(ctx, userID, email)
+		if err != nil {
+			if err == domain.ErrDatabaseUnavailable {
+				resp := b.update(lang, "system_unavailable")
+				resp.Data.Content = b.translator.TRetry(lang, "system_unavailable", b.service.UnavailableRetryAfter())
+				return resp
+			} else if err == domain.ErrEmailNotVerified {
+				return b.update(lang, "verification_required")
+			} else if err == domain.ErrEmailDenied {
+				return b.update(lang, "email_denied")
+			} else if err == domain.ErrEventArchived {
+				return b.update(lang, "event_archived")
+			}
+			b.logger.Error("Error redeeming cocktail", "email", email, "error", err)
+			return b.update(lang, "error_occurred")
+		}
+		return b.update(lang, "redemption_success", "date", redemptionTime.Format("January 2, 2006"))
+	case "skip":
+		return b.update(lang, "skip_redemption")
+	default:
+		return b.update(lang, "error_occurred")
+	}
+}
+
+// canRedeem checks if the roles include a redeem role
+func (b *Bot) canRedeem(roles []string) bool {
+	if len(b.config.Discord.RedeemRoles) == 0 {
+		return true
+	}
+	for _, role := range roles {
+		if slices.Contains(b.config.Discord.RedeemRoles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// reply creates an ephemeral message
+func (b *Bot) reply(lang, key string, args ...string) response {
+	return response{Type: responseMessage, Data: &responseData{
+		Content:    b.translator.T(lang, key, args...),
+		Flags:      flagEphemeral,
+		Components: []component{},
+	}}
+}
+
+// retryReply creates an ephemeral message asking to try again later,
+// naming the wait if it is known
+func (b *Bot) retryReply(lang, key string, wait time.Duration) response {
+	resp := b.reply(lang, key)
+	resp.Data.Content = b.translator.TRetry(lang, key, wait)
+	return resp
+}
+
+// update replaces the message holding the pressed button, removing the buttons
+func (b *Bot) update(lang, key string, args ...string) response {
+	return response{Type: responseUpdate, Data: &responseData{
+		Content:    b.translator.T(lang, key, args...),
+		Components: []component{},
+	}}
+}
+
+// eligibleReply creates an ephemeral message with redemption buttons
+func (b *Bot) eligibleReply(lang string) response {
+	resp := b.reply(lang, "eligible")
+	resp.Data.Components = []component{{
+		Type: componentActionRow,
+		Components: []component{
+			{Type: componentButton, Style: styleSuccess, Label: b.translator.T(lang, "button_redeem"), CustomID: "redeem"},
+			{Type: componentButton, Style: styleSecondary, Label: b.translator.T(lang, "button_skip"), CustomID: "skip"},
+		},
+	}}
+	return resp
+}
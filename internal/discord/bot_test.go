@@ -0,0 +1,207 @@
+package discord
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// mockService answers with a fixed status and records redemptions
+type mockService struct {
+	status   string
+	redeemed []string
+}
+
+func (s *mockService) CheckEmailStatus(ctx context.Context, userID int64, email string) (string, *domain.User, error) {
+	return s.status, &domain.User{Email: email}, nil
+}
+
+func (s *mockService) RedeemCocktail(ctx context.Context, userID int64, email string) (time.Time, error) {
+	s.redeemed = append(s.redeemed, email)
+	return time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), nil
+}
+
+func (s *mockService) TrackInteraction(lang, command string) {}
+func (s *mockService) TrackRedeemButton()                    {}
+
+func (s *mockService) IsUserBlocked(userID int64) bool { return false }
+
+func (s *mockService) RetryAfter(ctx context.Context, userID int64) time.Duration { return time.Minute }
+
+func (s *mockService) UnavailableRetryAfter() time.Duration { return 0 }
+
+// noopRegistrar does not register commands
+type noopRegistrar struct{}
+
+func (noopRegistrar) RegisterCommands(ctx context.Context, guildID string) error { return nil }
+
+// testBot is a bot with the key signing its interactions
+type testBot struct {
+	*Bot
+	key ed25519.PrivateKey
+}
+
+func newTestBot(t *testing.T, svc *mockService, redeemRoles ...string) *testBot {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	cfg := config.New()
+	cfg.Discord.PublicKey = hex.EncodeToString(public)
+	cfg.Discord.RedeemRoles = redeemRoles
+
+	bot, err := New(noopRegistrar{}, svc, cfg, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create bot: %v", err)
+	}
+	return &testBot{Bot: bot, key: private}
+}
+
+// post sends a signed interaction and decodes the response
+func (b *testBot) post(t *testing.T, in map[string]any) response {
+	t.Helper()
+
+	body, _ := json.Marshal(in)
+	timestamp := "1700000000"
+	signature := ed25519.Sign(b.key, append([]byte(timestamp), body...))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+	req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(signature))
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+// checkCommand is a /cocktail check interaction from a server member
+func checkCommand(email string, roles ...string) map[string]any {
+	return map[string]any{
+		"type":   interactionCommand,
+		"locale": "en-US",
+		"data": map[string]any{
+			"name": "cocktail",
+			"options": []any{map[string]any{
+				"name": "check", "type": optionSubcommand,
+				"options": []any{map[string]any{"name": "email", "type": optionString, "value": email}},
+			}},
+		},
+		"member": map[string]any{"user": map[string]any{"id": "80351110224678912"}, "roles": roles},
+	}
+}
+
+// button is a button press from a server member
+func button(id string, roles ...string) map[string]any {
+	return map[string]any{
+		"type":   interactionComponent,
+		"data":   map[string]any{"custom_id": id},
+		"member": map[string]any{"user": map[string]any{"id": "80351110224678912"}, "roles": roles},
+	}
+}
+
+func TestPingAndSignature(t *testing.T) {
+	bot := newTestBot(t, &mockService{})
+
+	if resp := bot.post(t, map[string]any{"type": interactionPing}); resp.Type != responsePong {
+		t.Errorf("Expected pong, got %+v", resp)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"type":1}`))
+	req.Header.Set("X-Signature-Ed25519", strings.Repeat("00", ed25519.SignatureSize))
+	req.Header.Set("X-Signature-Timestamp", "1700000000")
+	rec := httptest.NewRecorder()
+	bot.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for invalid signature, got %d", rec.Code)
+	}
+}
+
+func TestCheckAndRedeem(t *testing.T) {
+	svc := &mockService{status: "eligible"}
+	bot := newTestBot(t, svc, "bartender")
+
+	// Eligible emails get an ephemeral reply with redemption buttons
+	resp := bot.post(t, checkCommand("Guest@Example.com"))
+	if resp.Type != responseMessage || resp.Data.Flags != flagEphemeral {
+		t.Fatalf("Expected ephemeral message, got %+v", resp)
+	}
+	if len(resp.Data.Components) != 1 || resp.Data.Components[0].Components[0].CustomID != "redeem" {
+		t.Fatalf("Expected redeem button, got %+v", resp.Data.Components)
+	}
+
+	// Members without a redeem role cannot confirm
+	resp = bot.post(t, button("redeem", "guest"))
+	if len(svc.redeemed) != 0 || !strings.Contains(resp.Data.Content, "bar staff") {
+		t.Fatalf("Expected redemption refused, got %q (redeemed %v)", resp.Data.Content, svc.redeemed)
+	}
+
+	// Bar staff can, and the buttons are removed
+	resp = bot.post(t, button("redeem", "guest", "bartender"))
+	if len(svc.redeemed) != 1 || svc.redeemed[0] != "guest@example.com" {
+		t.Fatalf("Expected guest@example.com redeemed, got %v", svc.redeemed)
+	}
+	if resp.Type != responseUpdate || len(resp.Data.Components) != 0 || !strings.Contains(resp.Data.Content, "June 1, 2025") {
+		t.Errorf("Expected message updated with confirmation, got %+v", resp)
+	}
+}
+
+func TestInvalidEmail(t *testing.T) {
+	bot := newTestBot(t, &mockService{status: "eligible"})
+
+	resp := bot.post(t, checkCommand("not-an-email"))
+	if resp.Type != responseMessage || len(resp.Data.Components) != 0 {
+		t.Errorf("Expected plain reply for invalid email, got %+v", resp)
+	}
+}
+
+func TestRegisterCommands(t *testing.T) {
+	var path string
+	var commands []applicationCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.Method + " " + r.URL.Path
+		if r.Header.Get("Authorization") != "Bot token" {
+			t.Errorf("Unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &commands)
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "token", "app", server.Client())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := client.RegisterCommands(context.Background(), "guild"); err != nil {
+		t.Fatalf("Failed to register commands: %v", err)
+	}
+
+	if path != "PUT /applications/app/guilds/guild/commands" {
+		t.Errorf("Unexpected request %s", path)
+	}
+	if len(commands) != 1 || commands[0].Name != "cocktail" || commands[0].Options[0].Name != "check" {
+		t.Errorf("Unexpected commands %+v", commands)
+	}
+}
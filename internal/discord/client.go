@@ -0,0 +1,118 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ceesaxp/cocktail-bot/internal/httpclient"
+)
+
+// Application command option types
+const (
+	optionSubcommand = 1
+	optionString     = 3
+)
+
+// commandOption describes an option of a slash command
+type commandOption struct {
+	Type        int             `json:"type"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Required    bool            `json:"required,omitempty"`
+	Options     []commandOption `json:"options,omitempty"`
+}
+
+// applicationCommand describes a slash command
+type applicationCommand struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []commandOption `json:"options,omitempty"`
+}
+
+// cocktailCommand is the /cocktail check <email> slash command
+var cocktailCommand = applicationCommand{
+	Name:        "cocktail",
+	Description: "Free cocktail redemption",
+	Options: []commandOption{{
+		Type:        optionSubcommand,
+		Name:        "check",
+		Description: "Check whether an email can redeem a cocktail",
+		Options: []commandOption{{
+			Type:        optionString,
+			Name:        "email",
+			Description: "Email address of the guest",
+			Required:    true,
+		}},
+	}},
+}
+
+// Client calls the Discord REST API
+type Client struct {
+	baseURL       string
+	botToken      string
+	applicationID string
+	httpClient    *http.Client
+}
+
+// NewClient creates a client for the application using its bot token
+func NewClient(baseURL, botToken, applicationID string, httpClient *http.Client) (*Client, error) {
+	if botToken == "" {
+		return nil, errors.New("discord bot token cannot be empty")
+	}
+	if applicationID == "" {
+		return nil, errors.New("discord application ID cannot be empty")
+	}
+	if baseURL == "" {
+		baseURL = "https://discord.com/api/v10"
+	}
+	if httpClient == nil {
+		httpClient = httpclient.New(httpclient.Options{Name: "discord"})
+	}
+
+	return &Client{
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		botToken:      botToken,
+		applicationID: applicationID,
+		httpClient:    httpClient,
+	}, nil
+}
+
+// RegisterCommands replaces the application's slash commands, in one server
+// if guildID is set or globally otherwise. Server commands are available
+// immediately, global ones can take up to an hour to appear.
+func (c *Client) RegisterCommands(ctx context.Context, guildID string) error {
+	path := "/applications/" + c.applicationID + "/commands"
+	if guildID != "" {
+		path = "/applications/" + c.applicationID + "/guilds/" + guildID + "/commands"
+	}
+
+	body, err := json.Marshal([]applicationCommand{cocktailCommand})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+c.botToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("discord request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("discord returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
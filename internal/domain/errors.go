@@ -27,8 +27,102 @@ var (
 
 	// ErrInternalServer indicates a generic internal server error
 	ErrInternalServer = errors.New("internal server error")
+
+	// ErrEmailNotVerified indicates the user has not proved ownership of the email
+	ErrEmailNotVerified = errors.New("email ownership not verified")
+
+	// ErrNoVerificationPending indicates there is no verification code to check
+	ErrNoVerificationPending = errors.New("no verification pending")
+
+	// ErrInvalidVerificationCode indicates the entered verification code is wrong
+	ErrInvalidVerificationCode = errors.New("invalid verification code")
+
+	// ErrVerificationExpired indicates the verification code is no longer valid
+	ErrVerificationExpired = errors.New("verification code expired")
+
+	// ErrTooManyAttempts indicates too many wrong verification codes were entered
+	ErrTooManyAttempts = errors.New("too many verification attempts")
+
+	// ErrFailedRedemptionNotFound indicates there is no failed redemption with the given ID
+	ErrFailedRedemptionNotFound = errors.New("failed redemption not found")
+
+	// ErrEmailDenied indicates the email is on the deny list
+	ErrEmailDenied = errors.New("email address is not allowed")
+
+	// ErrPaymentsDisabled indicates that drink purchases are not configured
+	ErrPaymentsDisabled = errors.New("payments are not enabled")
+
+	// ErrInvalidSignature indicates a webhook whose signature could not be verified
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+
+	// ErrPurchaseNotFound indicates a payment for a checkout session the bot did not create
+	ErrPurchaseNotFound = errors.New("purchase not found")
+
+	// ErrTicketNotFound indicates that no redemption ticket exists for the ID
+	ErrTicketNotFound = errors.New("ticket not found")
+
+	// ErrLinkExpired indicates a signed download link past its expiry time
+	ErrLinkExpired = errors.New("link has expired")
+
+	// ErrEventArchived indicates a change to the records of an archived event
+	ErrEventArchived = errors.New("event is archived")
+
+	// ErrUserExists indicates that a user with the email is already stored
+	ErrUserExists = errors.New("user already exists")
+
+	// ErrUnknownBar indicates a redemption at a bar that is not configured
+	ErrUnknownBar = errors.New("unknown bar")
+
+	// ErrInvalidVoucher indicates a voucher code that is malformed or not signed with our key
+	ErrInvalidVoucher = errors.New("invalid voucher code")
+
+	// ErrVouchersDisabled indicates that no voucher signing key is configured
+	ErrVouchersDisabled = errors.New("voucher codes are not enabled")
+
+	// ErrRegistrationDisabled indicates that guests cannot ask for access
+	ErrRegistrationDisabled = errors.New("self-registration is not enabled")
+
+	// ErrRegistrationNotFound indicates there is no access request with the given ID
+	ErrRegistrationNotFound = errors.New("registration not found")
+
+	// ErrRegistrationDecided indicates an access request that was already approved or rejected
+	ErrRegistrationDecided = errors.New("registration already decided")
+
+	// ErrImportQueueDisabled indicates that imports cannot be queued
+	ErrImportQueueDisabled = errors.New("import queue is not enabled")
+
+	// ErrMigrationUnknown indicates a migration name that does not exist
+	ErrMigrationUnknown = errors.New("unknown migration")
+
+	// ErrMigrationUnsupported indicates a migration the database cannot run
+	ErrMigrationUnsupported = errors.New("migration not supported")
+
+	// ErrEmailChangeUnsupported indicates a database that cannot correct stored emails
+	ErrEmailChangeUnsupported = errors.New("changing emails is not supported by the database")
 )
 
+// DuplicateUserError is returned when adding a user whose email is already
+// stored, including by a concurrent insert that won the race
+type DuplicateUserError struct {
+	Email string // Normalized email of the user
+	ID    string // ID of the stored user, empty if it could not be read
+}
+
+// Error implements the error interface
+func (e *DuplicateUserError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrUserExists.Error(), e.Email)
+}
+
+// Unwrap makes errors.Is match ErrUserExists
+func (e *DuplicateUserError) Unwrap() error {
+	return ErrUserExists
+}
+
+// NewDuplicateUserError creates a new duplicate user error
+func NewDuplicateUserError(email, id string) *DuplicateUserError {
+	return &DuplicateUserError{Email: email, ID: id}
+}
+
 // DatabaseError provides additional context for database related errors
 type DatabaseError struct {
 	OrigErr  error  // Original error from database driver
@@ -114,6 +208,11 @@ func IsDatabaseError(err error) bool {
 	return errors.As(err, &dbErr)
 }
 
+// IsDuplicateUser returns true if the error indicates the user is already stored
+func IsDuplicateUser(err error) bool {
+	return errors.Is(err, ErrUserExists)
+}
+
 // IsAlreadyRedeemed returns true if the error indicates already redeemed status
 func IsAlreadyRedeemed(err error) bool {
 	return errors.Is(err, ErrAlreadyRedeemed)
@@ -2,14 +2,37 @@ package domain
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
 type User struct {
-	ID         string
-	Email      string
-	DateAdded  time.Time
-	Redeemed   *time.Time
+	ID               string
+	Email            string
+	DateAdded        time.Time
+	Redeemed         *time.Time
+	MarketingConsent *time.Time // When the user opted in to marketing, nil if not
+	UpdatedAt        time.Time  // Last change to the record, set by the service on every write
+	CreatedBy        string     // Who added the record, such as token:<fingerprint> or rsvp_import
+	RedeemedAt       string     // Bar that served the drink, empty if the event has a single bar
+	Notes            string     // Free text kept by staff, such as why a record was corrected
+	Tags             []string   // Labels set by staff, such as vip or press
+	FirstName        string     // Given name, used to greet the guest, empty if unknown
+	LastName         string     // Family name, empty if unknown
+}
+
+// FullName returns the first and last name separated by a space, empty if
+// neither is known
+func (u *User) FullName() string {
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
+// SplitName splits a full name into the first name and the rest, which is
+// taken for the last name. Sources holding one name column, such as a CSV
+// import, are read with it.
+func SplitName(name string) (first, last string) {
+	first, last, _ = strings.Cut(strings.Join(strings.Fields(name), " "), " ")
+	return first, last
 }
 
 // IsRedeemed returns true if the user has already redeemed their cocktail
@@ -17,12 +40,47 @@ func (u *User) IsRedeemed() bool {
 	return u.Redeemed != nil
 }
 
+// MergedTag marks a record that was merged into another guest. Records
+// cannot be deleted from every backend, so a merged duplicate is kept with
+// this tag and no longer counts as a guest.
+const MergedTag = "merged"
+
+// IsMerged returns true if the record was merged into another guest
+func (u *User) IsMerged() bool {
+	for _, tag := range u.Tags {
+		if tag == MergedTag {
+			return true
+		}
+	}
+	return false
+}
+
 // Redeem marks the user as having redeemed their cocktail with the current time
 func (u *User) Redeem() {
 	now := time.Now()
 	u.Redeemed = &now
 }
 
+// HasMarketingConsent returns true if the user opted in to marketing messages
+func (u *User) HasMarketingConsent() bool {
+	return u.MarketingConsent != nil
+}
+
+// LastChange returns the latest of the record's timestamps. It stands in
+// for UpdatedAt on records written before UpdatedAt was maintained.
+func (u *User) LastChange() time.Time {
+	latest := u.DateAdded
+	for _, t := range []*time.Time{u.Redeemed, u.MarketingConsent} {
+		if t != nil && t.After(latest) {
+			latest = *t
+		}
+	}
+	if u.UpdatedAt.After(latest) {
+		latest = u.UpdatedAt
+	}
+	return latest
+}
+
 // ReportType defines the type of report to generate
 type ReportType string
 
@@ -33,6 +91,11 @@ const (
 	ReportTypeAdded ReportType = "added"
 	// ReportTypeAll represents a report of all users
 	ReportTypeAll ReportType = "all"
+	// ReportTypeConsented represents a report of users who opted in to marketing
+	ReportTypeConsented ReportType = "consented"
+	// ReportTypeChanged represents a report of users added or changed within
+	// the date range, selected by UpdatedAt instead of DateAdded
+	ReportTypeChanged ReportType = "changed"
 )
 
 // ValidateReportType checks if the provided string is a valid report type
@@ -44,16 +107,96 @@ func ValidateReportType(reportType string) (ReportType, error) {
 		return ReportTypeAdded, nil
 	case string(ReportTypeAll):
 		return ReportTypeAll, nil
+	case string(ReportTypeConsented):
+		return ReportTypeConsented, nil
+	case string(ReportTypeChanged):
+		return ReportTypeChanged, nil
 	default:
 		return "", fmt.Errorf("invalid report type: %s", reportType)
 	}
 }
 
+// BarRedemptions counts the drinks served by one bar
+type BarRedemptions struct {
+	Bar      string `json:"bar"` // Empty for redemptions recorded without a bar
+	Redeemed int    `json:"redeemed"`
+}
+
+// RedemptionLatency is the distribution of the time guests took from being
+// added to redeeming, in seconds
+type RedemptionLatency struct {
+	Event       string          `json:"event,omitempty"`
+	Count       int             `json:"count"` // Redeemed guests measured
+	MinSeconds  int64           `json:"min_seconds"`
+	MeanSeconds int64           `json:"mean_seconds"`
+	P50Seconds  int64           `json:"p50_seconds"`
+	P90Seconds  int64           `json:"p90_seconds"`
+	P99Seconds  int64           `json:"p99_seconds"`
+	MaxSeconds  int64           `json:"max_seconds"`
+	Buckets     []LatencyBucket `json:"buckets"`
+}
+
+// LatencyBucket counts the redemptions within a range of latencies
+type LatencyBucket struct {
+	Label       string `json:"label"`                   // Such as "1-6h"
+	UpToSeconds int64  `json:"up_to_seconds,omitempty"` // Exclusive upper bound, 0 for the last bucket
+	Count       int    `json:"count"`
+}
+
+// ReportFilter narrows a report to a subset of guests. Empty fields match
+// every guest.
+type ReportFilter struct {
+	Domain string // Email domain, such as example.com
+	Source string // Who added the guest: a CreatedBy value, or its kind before the colon such as token or telegram
+	Bar    string // Bar that served the drink
+
+	IncludeTest bool // Add the sandbox records of test emails, left out by default
+}
+
+// NormalizeReportFilter lowercases the filter values and checks the domain,
+// which repositories match with patterns and may only hold host name characters
+func NormalizeReportFilter(filter ReportFilter) (ReportFilter, error) {
+	filter.Domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(filter.Domain)), "@")
+	filter.Source = strings.TrimSpace(filter.Source)
+	filter.Bar = strings.TrimSpace(filter.Bar)
+	for _, c := range filter.Domain {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '-') {
+			return filter, fmt.Errorf("invalid email domain: %s", filter.Domain)
+		}
+	}
+	return filter, nil
+}
+
+// Matches returns true if the user passes the filter
+func (f ReportFilter) Matches(u *User) bool {
+	if f.Domain != "" && !strings.HasSuffix(strings.ToLower(u.Email), "@"+f.Domain) {
+		return false
+	}
+	if f.Source != "" && u.CreatedBy != f.Source && !strings.HasPrefix(u.CreatedBy, f.Source+":") {
+		return false
+	}
+	if f.Bar != "" && u.RedeemedAt != f.Bar {
+		return false
+	}
+	return true
+}
+
 // ReportParams holds parameters for generating reports
 type ReportParams struct {
-	Type      ReportType
-	From      time.Time
-	To        time.Time
+	Type ReportType
+	From time.Time
+	To   time.Time
+	ReportFilter
+}
+
+// RepoStats describes the contents and state of a repository
+type RepoStats struct {
+	Backend   string            `json:"backend"`
+	Users     int               `json:"users"`
+	Redeemed  int               `json:"redeemed"`
+	Consented int               `json:"consented"`
+	LastWrite *time.Time        `json:"last_write,omitempty"` // Most recent change, nil if the repository is empty
+	Details   map[string]string `json:"details,omitempty"`    // Backend-specific information
 }
 
 // Repository is the interface that all database implementations must satisfy
@@ -62,5 +205,106 @@ type Repository interface {
 	UpdateUser(ctx any, user *User) error
 	AddUser(ctx any, user *User) error
 	GetReport(ctx any, params ReportParams) ([]*User, error)
+	Health(ctx any) error
+	Stats(ctx any) (RepoStats, error)
 	Close() error
 }
+
+// FailedRedemption is a redemption the guest confirmed but that could not
+// be written to the database
+type FailedRedemption struct {
+	ID          string    `json:"id"`
+	Email       string    `json:"email"`
+	UserID      int64     `json:"user_id"` // Telegram user who pressed redeem
+	AttemptedAt time.Time `json:"attempted_at"`
+	Error       string    `json:"error"`
+	Retries     int       `json:"retries"`
+	Bar         string    `json:"bar,omitempty"` // Bar that served the drink
+}
+
+// RegistrationStatus is the state of a guest's request for access
+type RegistrationStatus string
+
+const (
+	// RegistrationPending is a request waiting for an admin
+	RegistrationPending RegistrationStatus = "pending"
+	// RegistrationApproved is a request whose email was added to the guest list
+	RegistrationApproved RegistrationStatus = "approved"
+	// RegistrationRejected is a request an admin turned down
+	RegistrationRejected RegistrationStatus = "rejected"
+)
+
+// Registration is a request for access by a guest whose email is not on
+// the guest list. Admins approve or reject it.
+type Registration struct {
+	ID          string             `json:"id"`
+	Email       string             `json:"email"`
+	UserID      int64              `json:"user_id"`            // Telegram user who asked for access
+	ChatID      int64              `json:"chat_id"`            // Chat the decision is sent to
+	Language    string             `json:"language,omitempty"` // Language of the guest
+	Status      RegistrationStatus `json:"status"`
+	RequestedAt time.Time          `json:"requested_at"`
+	DecidedAt   *time.Time         `json:"decided_at,omitempty"`
+	DecidedBy   string             `json:"decided_by,omitempty"` // Admin who approved or rejected it
+}
+
+// OfflineRedemption is a redemption collected by door staff without access
+// to the bot, applied later with the time it happened
+type OfflineRedemption struct {
+	Email    string    `json:"email"`
+	Redeemed time.Time `json:"redeemed_at"`
+	Operator string    `json:"operator,omitempty"` // Staff member who collected it
+}
+
+// OfflineRedemptionResult is the outcome of applying an offline redemption
+type OfflineRedemptionResult struct {
+	Email    string     `json:"email"`
+	Status   string     `json:"status"`             // redeemed, duplicate, conflict, not_found, denied, invalid, archived, unavailable or error
+	Redeemed *time.Time `json:"redeemed,omitempty"` // Redemption time stored for the email
+	Error    string     `json:"error,omitempty"`
+}
+
+// Purchase is an extra drink bought after the free cocktail
+type Purchase struct {
+	SessionID string     `json:"session_id"` // Checkout session of the payment provider
+	Email     string     `json:"email"`
+	UserID    int64      `json:"user_id"` // Telegram user who started the checkout
+	Status    string     `json:"status"`  // "pending" or "paid"
+	Amount    int64      `json:"amount"`  // In the smallest currency unit, set once paid
+	Currency  string     `json:"currency,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	PaidAt    *time.Time `json:"paid_at,omitempty"`
+}
+
+// EventArchive records that an event was archived. The records of an
+// archived event are frozen and only show up in reports asking for them.
+type EventArchive struct {
+	Event      string    `json:"event"`
+	ArchivedAt time.Time `json:"archived_at"`
+	Actor      string    `json:"actor"`            // Who archived the event
+	Bundle     string    `json:"bundle,omitempty"` // Directory of the final report bundle, if exported
+}
+
+// RuntimeStatus summarizes what the bot did since it started
+type RuntimeStatus struct {
+	Started            time.Time     `json:"started"`
+	UptimeSeconds      int64         `json:"uptime_seconds"`
+	Interactions       int           `json:"interactions"`
+	Checks             int           `json:"checks"`
+	Redemptions        int           `json:"redemptions"`
+	PendingRedemptions int           `json:"pending_redemptions"` // Failed redemptions waiting for a retry
+	LastErrors         []LoggedError `json:"last_errors"`         // Oldest first
+}
+
+// EnumerationStats counts the sources suspected of guessing the guest list
+type EnumerationStats struct {
+	Alarms  int `json:"alarms"`  // Alarms raised since start
+	Tracked int `json:"tracked"` // Sources with recent unknown emails
+	Blocked int `json:"blocked"` // Sources currently refused
+}
+
+// LoggedError is an error message logged by the bot
+type LoggedError struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
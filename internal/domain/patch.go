@@ -0,0 +1,154 @@
+package domain
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the fields staff can set on a guest record
+const (
+	MaxNotesLength  = 1000 // Characters
+	MaxNameLength   = 100  // Characters of the first and of the last name
+	MaxTags         = 20
+	MaxTagLength    = 32 // Characters
+	MaxReasonLength = 200
+)
+
+// UserPatch is a partial update of a guest record. Nil fields are left as
+// they are.
+type UserPatch struct {
+	Email     *string   // Corrected email, the record keeps its ID and history
+	Notes     *string   // Replaces the notes, empty to clear them
+	Tags      *[]string // Replaces all tags, empty to clear them
+	FirstName *string   // Replaces the first name, empty to clear it
+	LastName  *string   // Replaces the last name, empty to clear it
+	Unredeem  bool      // Clears the redemption, so the guest can redeem again
+	Reason    string    // Why the record is changed, required to unredeem
+}
+
+// IsEmpty returns true if the patch changes nothing
+func (p UserPatch) IsEmpty() bool {
+	return p.Email == nil && p.Notes == nil && p.Tags == nil && p.FirstName == nil && p.LastName == nil && !p.Unredeem
+}
+
+// NormalizeUserPatch trims the values of a patch and checks them. Tags are
+// lowercased and deduplicated. The email is only trimmed, its format is
+// checked by the service. Errors are ValidationErrors naming the field.
+func NormalizeUserPatch(patch UserPatch) (UserPatch, error) {
+	if patch.IsEmpty() {
+		return patch, NewValidationError("patch", "no fields to update")
+	}
+
+	var err error
+	patch.Reason = strings.TrimSpace(patch.Reason)
+	if utf8.RuneCountInString(patch.Reason) > MaxReasonLength {
+		return patch, NewValidationError("reason", "must be at most 200 characters")
+	}
+	if patch.Unredeem && patch.Reason == "" {
+		return patch, NewValidationError("reason", "is required to unredeem")
+	}
+
+	if patch.Email != nil {
+		email := strings.TrimSpace(*patch.Email)
+		if email == "" {
+			return patch, NewValidationError("email", "cannot be empty")
+		}
+		patch.Email = &email
+	}
+
+	if patch.Notes != nil {
+		notes := strings.TrimSpace(*patch.Notes)
+		if utf8.RuneCountInString(notes) > MaxNotesLength {
+			return patch, NewValidationError("notes", "must be at most 1000 characters")
+		}
+		patch.Notes = &notes
+	}
+
+	if patch.FirstName != nil {
+		if patch.FirstName, err = normalizeName("first_name", *patch.FirstName); err != nil {
+			return patch, err
+		}
+	}
+	if patch.LastName != nil {
+		if patch.LastName, err = normalizeName("last_name", *patch.LastName); err != nil {
+			return patch, err
+		}
+	}
+
+	if patch.Tags != nil {
+		tags, err := NormalizeTags(*patch.Tags)
+		if err != nil {
+			return patch, err
+		}
+		patch.Tags = &tags
+	}
+	return patch, nil
+}
+
+// normalizeName collapses the spaces of a name and checks its length
+func normalizeName(field, name string) (*string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return nil, NewValidationError(field, "must be at most 100 characters")
+	}
+	return &name, nil
+}
+
+// NormalizeTags trims, lowercases and deduplicates tags and checks them.
+// Errors are ValidationErrors of the tags field.
+func NormalizeTags(values []string) ([]string, error) {
+	tags := make([]string, 0, len(values))
+	seen := make(map[string]bool)
+	for _, tag := range values {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if err := validateTag(tag); err != nil {
+			return nil, err
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > MaxTags {
+		return nil, NewValidationError("tags", "at most 20 tags are allowed")
+	}
+	return tags, nil
+}
+
+// validateTag checks that a lowercased tag is short and made of letters,
+// digits, - and _, so tags can be stored comma-separated
+func validateTag(tag string) error {
+	if tag == MergedTag {
+		return NewValidationError("tags", "is reserved for merged records", tag)
+	}
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return NewValidationError("tags", "must be at most 32 characters each", tag)
+	}
+	for _, c := range tag {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return NewValidationError("tags", "may only contain letters, digits, - and _", tag)
+		}
+	}
+	return nil
+}
+
+// JoinTags formats tags for backends that store them in a single text
+// column
+func JoinTags(tags []string) string {
+	return strings.Join(tags, ",")
+}
+
+// SplitTags parses tags stored with JoinTags
+func SplitTags(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits of a guest search
+const (
+	MinSearchLength = 2   // Characters of the query
+	MaxSearchLength = 100 // Characters of the query
+	MaxSearchTerms  = 5
+)
+
+// Fields a search term can match
+const (
+	SearchFieldEmail = "email"
+	SearchFieldName  = "name"
+	SearchFieldNotes = "notes"
+	SearchFieldTags  = "tags"
+)
+
+// SearchParams selects a page of the guests matching a search
+type SearchParams struct {
+	Terms  []string // Lowercased words that must all match, see SearchTerms
+	Offset int
+	Limit  int // Page size, 0 returns all matches
+}
+
+// SearchHit is a guest matching a search
+type SearchHit struct {
+	User   *User
+	Score  int      // Higher is a better match
+	Fields []string // Fields matched by a term, such as email and notes
+}
+
+// SearchResult is a page of the guests matching a search, best match first
+type SearchResult struct {
+	Hits  []SearchHit
+	Total int // Matches across all pages
+}
+
+// SearchTerms splits a query into lowercased words. Guests match a search
+// when every word is found in their email, name, notes or tags. Queries
+// that are too short or long are reported as ValidationErrors.
+func SearchTerms(query string) ([]string, error) {
+	query = strings.TrimSpace(query)
+	if n := utf8.RuneCountInString(query); n < MinSearchLength || n > MaxSearchLength {
+		return nil, NewValidationError("q", fmt.Sprintf("must be %d to %d characters", MinSearchLength, MaxSearchLength))
+	}
+	var terms []string
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		if !slices.Contains(terms, term) {
+			terms = append(terms, term)
+		}
+	}
+	if len(terms) > MaxSearchTerms {
+		return nil, NewValidationError("q", fmt.Sprintf("may have at most %d words", MaxSearchTerms))
+	}
+	return terms, nil
+}
+
+// MatchUser scores how well a guest matches the terms of a search. Every
+// term adds the weight of the best field it matches: a whole email or tag
+// counts most, then the start of the email, a name or a word of the notes,
+// then text inside them. The score is 0 if a term matches no field.
+func MatchUser(user *User, terms []string) SearchHit {
+	hit := SearchHit{User: user}
+	if len(terms) == 0 {
+		return hit
+	}
+
+	email := strings.ToLower(user.Email)
+	local, _, _ := strings.Cut(email, "@")
+	name := strings.ToLower(user.FullName())
+	notes := strings.ToLower(user.Notes)
+
+	for _, term := range terms {
+		best := 0
+		match := func(field string, weight int) {
+			if weight == 0 {
+				return
+			}
+			best = max(best, weight)
+			if !slices.Contains(hit.Fields, field) {
+				hit.Fields = append(hit.Fields, field)
+			}
+		}
+
+		switch {
+		case email == term:
+			match(SearchFieldEmail, 10)
+		case strings.HasPrefix(local, term):
+			match(SearchFieldEmail, 6)
+		case strings.Contains(email, term):
+			match(SearchFieldEmail, 3)
+		}
+		match(SearchFieldName, wordWeight(name, term, 6, 3))
+		match(SearchFieldNotes, wordWeight(notes, term, 2, 1))
+		for _, tag := range user.Tags {
+			switch {
+			case tag == term:
+				match(SearchFieldTags, 8)
+			case strings.HasPrefix(tag, term):
+				match(SearchFieldTags, 5)
+			}
+		}
+
+		if best == 0 {
+			return SearchHit{User: user}
+		}
+		hit.Score += best
+	}
+	sort.Strings(hit.Fields)
+	return hit
+}
+
+// wordWeight returns prefix if a word of text starts with the term, inside
+// if the text contains it elsewhere, and 0 otherwise
+func wordWeight(text, term string, prefix, inside int) int {
+	index := strings.Index(text, term)
+	if index < 0 {
+		return 0
+	}
+	for index >= 0 {
+		before, _ := utf8.DecodeLastRuneInString(text[:index])
+		if index == 0 || !unicode.IsLetter(before) && !unicode.IsDigit(before) {
+			return prefix
+		}
+		next := strings.Index(text[index+1:], term)
+		if next < 0 {
+			break
+		}
+		index += next + 1
+	}
+	return inside
+}
+
+// RankSearch returns the page of users matching the terms, best match
+// first and guests with the same score by email
+func RankSearch(users []*User, params SearchParams) SearchResult {
+	var hits []SearchHit
+	for _, user := range users {
+		if hit := MatchUser(user, params.Terms); hit.Score > 0 {
+			hits = append(hits, hit)
+		}
+	}
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].User.Email < hits[b].User.Email
+	})
+
+	result := SearchResult{Total: len(hits)}
+	hits = hits[min(params.Offset, len(hits)):]
+	if params.Limit > 0 && len(hits) > params.Limit {
+		hits = hits[:params.Limit]
+	}
+	result.Hits = hits
+	return result
+}
@@ -0,0 +1,88 @@
+// Package events passes what happens to guests between the parts of the
+// bot running in one process, such as a redemption made through the API
+// to the Telegram chats that were offered the same email, or a feature flag
+// toggled through the admin API to the Telegram command menu.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type is the kind of an event
+type Type string
+
+// Event types
+const (
+	Redeemed       Type = "redeemed"        // The cocktail of an email was redeemed
+	FeatureToggled Type = "feature_toggled" // A feature flag was changed at runtime, without an email
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// further events are dropped for it
+const subscriberBuffer = 64
+
+// Event is something that happened to a guest
+type Event struct {
+	Type     Type
+	Email    string    // Normalized email of the guest
+	UserID   int64     // Telegram user or hashed API client that caused it, 0 if none
+	Actor    string    // Who caused it, as recorded in the audit log
+	Operator string    // Staff member who collected an offline redemption or named by an API call, empty if none
+	Bar      string    // Bar that served the drink, empty if none
+	Time     time.Time // When it happened
+}
+
+// Hub passes published events to every subscriber. Publishing never
+// blocks: subscribers that fall behind miss events. It is safe for
+// concurrent use.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+// NewHub creates a hub without subscribers
+func NewHub() *Hub {
+	return &Hub{subs: make(map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving the events published from now on,
+// and a function ending the subscription, which closes the channel
+func (h *Hub) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish passes an event to the subscribers. It returns how many
+// subscribers missed the event because they fell behind.
+func (h *Hub) Publish(event Event) int {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	missed := 0
+	for ch := range h.subs {
+		select {
+		case ch <- event:
+		default:
+			missed++
+		}
+	}
+	return missed
+}
@@ -0,0 +1,45 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/ceesaxp/cocktail-bot/internal/events"
+)
+
+func TestHub(t *testing.T) {
+	hub := events.NewHub()
+
+	// Events published without subscribers are dropped silently
+	if missed := hub.Publish(events.Event{Type: events.Redeemed, Email: "early@example.com"}); missed != 0 {
+		t.Errorf("Expected no subscriber to miss the event, got %d", missed)
+	}
+
+	first, unsubscribe := hub.Subscribe()
+	second, unsubscribeSecond := hub.Subscribe()
+	defer unsubscribeSecond()
+
+	hub.Publish(events.Event{Type: events.Redeemed, Email: "guest@example.com", UserID: 42})
+	for _, ch := range []<-chan events.Event{first, second} {
+		event := <-ch
+		if event.Email != "guest@example.com" || event.UserID != 42 || event.Time.IsZero() {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	}
+
+	// An ended subscription is closed and gets nothing more
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-first; ok {
+		t.Error("Expected the channel to be closed")
+	}
+
+	// A subscriber that does not keep up misses events instead of
+	// blocking the publisher
+	missed := 0
+	for i := 0; i < 100; i++ {
+		missed += hub.Publish(events.Event{Type: events.Redeemed, Email: "busy@example.com"})
+	}
+	if missed == 0 || len(second) == 0 {
+		t.Errorf("Expected a full subscriber to miss events, missed %d with %d queued", missed, len(second))
+	}
+}
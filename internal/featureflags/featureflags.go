@@ -0,0 +1,163 @@
+// Package featureflags turns behaviors that are still being rolled out on
+// and off per deployment, so a release can ship them switched off at some
+// events and on at others. Flags are read from the configuration and may
+// be toggled at runtime through the admin API, until the next restart.
+package featureflags
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Name identifies a feature flag
+type Name string
+
+// Feature flags. Each gates a behavior that is also configured on its own:
+// a flag can switch a configured behavior off, but never enables one that
+// is not configured.
+const (
+	Kiosk            Name = "kiosk"
+	SelfRegistration Name = "self_registration"
+	Vouchers         Name = "vouchers"
+)
+
+// descriptions are the known flags and what they gate
+var descriptions = map[Name]string{
+	Kiosk:            "Kiosk page where guests check their email themselves",
+	SelfRegistration: "Guests whose email is not found ask admins for access",
+	Vouchers:         "Guests identify with voucher codes and signed links",
+}
+
+// Errors returned by the flag set
+var (
+	ErrUnknownFlag    = errors.New("unknown feature flag")
+	ErrToggleDisabled = errors.New("runtime toggling of feature flags is disabled")
+)
+
+// Flag is the state of a feature flag
+type Flag struct {
+	Name        Name   `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	Configured  bool   `json:"configured"` // State read from the configuration
+	Overridden  bool   `json:"overridden"` // Toggled at runtime, so Enabled may differ from Configured
+}
+
+// Known returns whether name is a feature flag
+func Known(name Name) bool {
+	_, ok := descriptions[name]
+	return ok
+}
+
+// Set holds the state of every feature flag. Flags missing from the
+// configuration are on, so deployments keep their behavior until they
+// turn a flag off. A nil Set has every flag on. It is safe for concurrent
+// use.
+type Set struct {
+	toggle     bool // Whether flags may be toggled at runtime
+	configured map[Name]bool
+
+	mu        sync.RWMutex
+	overrides map[Name]bool
+}
+
+// New creates the flag set from the configured flags. Unknown flags are
+// refused, so a typo does not leave a behavior on unnoticed. With toggle
+// set, flags may be changed at runtime.
+func New(flags map[string]bool, toggle bool) (*Set, error) {
+	s := &Set{toggle: toggle, configured: make(map[Name]bool), overrides: make(map[Name]bool)}
+	for name := range descriptions {
+		s.configured[name] = true
+	}
+	for name, enabled := range flags {
+		if !Known(Name(name)) {
+			return nil, fmt.Errorf("%w %q", ErrUnknownFlag, name)
+		}
+		s.configured[Name(name)] = enabled
+	}
+	return s, nil
+}
+
+// Enabled returns whether a flag is on. Unknown flags are off.
+func (s *Set) Enabled(name Name) bool {
+	if s == nil {
+		return Known(name)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if enabled, ok := s.overrides[name]; ok {
+		return enabled
+	}
+	return s.configured[name]
+}
+
+// ToggleAllowed returns whether flags may be changed at runtime
+func (s *Set) ToggleAllowed() bool {
+	return s != nil && s.toggle
+}
+
+// Toggle turns a flag on or off until Reset or a restart
+func (s *Set) Toggle(name Name, enabled bool) (Flag, error) {
+	if !s.ToggleAllowed() {
+		return Flag{}, ErrToggleDisabled
+	}
+	if !Known(name) {
+		return Flag{}, fmt.Errorf("%w %q", ErrUnknownFlag, name)
+	}
+	s.mu.Lock()
+	s.overrides[name] = enabled
+	s.mu.Unlock()
+	return s.Get(name)
+}
+
+// Reset returns a flag to its configured state
+func (s *Set) Reset(name Name) (Flag, error) {
+	if !s.ToggleAllowed() {
+		return Flag{}, ErrToggleDisabled
+	}
+	if !Known(name) {
+		return Flag{}, fmt.Errorf("%w %q", ErrUnknownFlag, name)
+	}
+	s.mu.Lock()
+	delete(s.overrides, name)
+	s.mu.Unlock()
+	return s.Get(name)
+}
+
+// Get returns the state of a flag
+func (s *Set) Get(name Name) (Flag, error) {
+	description, ok := descriptions[name]
+	if !ok {
+		return Flag{}, fmt.Errorf("%w %q", ErrUnknownFlag, name)
+	}
+	flag := Flag{Name: name, Description: description, Enabled: true, Configured: true}
+	if s == nil {
+		return flag, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	flag.Configured = s.configured[name]
+	flag.Enabled = flag.Configured
+	if enabled, ok := s.overrides[name]; ok {
+		flag.Enabled = enabled
+		flag.Overridden = true
+	}
+	return flag, nil
+}
+
+// List returns the state of every flag, by name
+func (s *Set) List() []Flag {
+	names := make([]Name, 0, len(descriptions))
+	for name := range descriptions {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(a, b int) bool { return names[a] < names[b] })
+
+	flags := make([]Flag, len(names))
+	for i, name := range names {
+		flags[i], _ = s.Get(name)
+	}
+	return flags
+}
@@ -0,0 +1,71 @@
+package featureflags_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ceesaxp/cocktail-bot/internal/featureflags"
+)
+
+func TestNew(t *testing.T) {
+	flags, err := featureflags.New(map[string]bool{"kiosk": false}, false)
+	if err != nil {
+		t.Fatalf("Failed to create flags: %v", err)
+	}
+	if flags.Enabled(featureflags.Kiosk) {
+		t.Error("Expected the kiosk to be off as configured")
+	}
+	if !flags.Enabled(featureflags.Vouchers) || !flags.Enabled(featureflags.SelfRegistration) {
+		t.Error("Expected flags missing from the configuration to be on")
+	}
+	if flags.Enabled("waitlist") {
+		t.Error("Expected an unknown flag to be off")
+	}
+
+	if _, err := featureflags.New(map[string]bool{"kisok": true}, false); !errors.Is(err, featureflags.ErrUnknownFlag) {
+		t.Errorf("Expected a misspelled flag to be refused, got %v", err)
+	}
+
+	var unset *featureflags.Set
+	if !unset.Enabled(featureflags.Kiosk) || len(unset.List()) != 3 {
+		t.Error("Expected a nil set to have every flag on")
+	}
+}
+
+func TestToggle(t *testing.T) {
+	fixed, _ := featureflags.New(nil, false)
+	if _, err := fixed.Toggle(featureflags.Kiosk, false); !errors.Is(err, featureflags.ErrToggleDisabled) {
+		t.Errorf("Expected toggling to be refused without runtime toggling, got %v", err)
+	}
+	if !fixed.Enabled(featureflags.Kiosk) {
+		t.Error("Expected a refused toggle to change nothing")
+	}
+
+	flags, _ := featureflags.New(map[string]bool{"vouchers": false}, true)
+	flag, err := flags.Toggle(featureflags.Vouchers, true)
+	if err != nil {
+		t.Fatalf("Failed to toggle: %v", err)
+	}
+	if !flag.Enabled || flag.Configured || !flag.Overridden {
+		t.Errorf("Expected an overridden flag that is on, got %+v", flag)
+	}
+	if !flags.Enabled(featureflags.Vouchers) {
+		t.Error("Expected vouchers to be on after toggling")
+	}
+	if _, err := flags.Toggle("waitlist", true); !errors.Is(err, featureflags.ErrUnknownFlag) {
+		t.Errorf("Expected an unknown flag to be refused, got %v", err)
+	}
+
+	flag, err = flags.Reset(featureflags.Vouchers)
+	if err != nil {
+		t.Fatalf("Failed to reset: %v", err)
+	}
+	if flag.Enabled || flag.Overridden {
+		t.Errorf("Expected the configured state after a reset, got %+v", flag)
+	}
+
+	list := flags.List()
+	if len(list) != 3 || list[0].Name != featureflags.Kiosk || list[2].Name != featureflags.Vouchers {
+		t.Errorf("Expected every flag by name, got %+v", list)
+	}
+}
@@ -0,0 +1,244 @@
+// Package httpclient builds the HTTP clients used to call the API and
+// external services: pooled connections, a deadline per call, retries with
+// jittered backoff for requests that are safe to repeat, and counters
+// exported as metrics.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+)
+
+// Defaults for options left zero
+const (
+	defaultTimeout             = 30 * time.Second
+	defaultBaseBackoff         = 200 * time.Millisecond
+	defaultMaxBackoff          = 5 * time.Second
+	defaultMaxIdleConnsPerHost = 16
+)
+
+// maxDrain is how much of a failed response is read so its connection can be reused
+const maxDrain = 64 << 10
+
+// IdempotencyKeyHeader marks a request as safe to repeat whatever its method
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Options configures a client
+type Options struct {
+	Name                string        // Label of the client's metrics
+	Timeout             time.Duration // Deadline of a call, all attempts included
+	Retries             int           // Attempts after a failed one
+	BaseBackoff         time.Duration // Wait before the first retry, doubled for each further one
+	MaxBackoff          time.Duration // Longest wait between attempts
+	MaxIdleConnsPerHost int           // Connections kept open to each host
+	RetryPost           bool          // Also retry requests that are not idempotent
+}
+
+// FromConfig returns the options set in the http_client section of cfg for
+// the client with the given name
+func FromConfig(cfg *config.Config, name string) Options {
+	return Options{
+		Name:                name,
+		Timeout:             cfg.Timeouts.HTTPClient.Duration(),
+		Retries:             cfg.HTTPClient.Retries,
+		BaseBackoff:         cfg.HTTPClient.BackoffBase.Duration(),
+		MaxBackoff:          cfg.HTTPClient.BackoffMax.Duration(),
+		MaxIdleConnsPerHost: cfg.HTTPClient.MaxIdleConnsPerHost,
+	}
+}
+
+// New returns a client with the given options. Requests are retried on
+// connection errors and on 429, 502, 503 and 504 responses when their
+// method is idempotent, they carry an Idempotency-Key header or RetryPost
+// is set, and their body can be sent again.
+func New(opts Options) *http.Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.Retries < 0 {
+		opts.Retries = 0
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = defaultBaseBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultMaxBackoff
+	}
+	if opts.MaxBackoff < opts.BaseBackoff {
+		opts.MaxBackoff = opts.BaseBackoff
+	}
+	if opts.MaxIdleConnsPerHost <= 0 {
+		opts.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+
+	return &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &transport{
+			base:     base,
+			opts:     opts,
+			counters: countersFor(opts.Name),
+		},
+	}
+}
+
+// transport retries failed attempts of a request
+type transport struct {
+	base     http.RoundTripper
+	opts     Options
+	counters *counters
+}
+
+// RoundTrip sends the request, retrying it while it is allowed to
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.counters.requests.Add(1)
+	retryable := t.canRetry(req)
+
+	attemptReq := req
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(attemptReq)
+		if !retryable || attempt >= t.opts.Retries || !shouldRetry(req, resp, err) {
+			if err != nil || shouldRetry(req, resp, nil) {
+				t.counters.failures.Add(1)
+			}
+			return resp, err
+		}
+
+		wait, ok := t.backoff(attempt, resp)
+		if !ok {
+			t.counters.failures.Add(1)
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrain))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			t.counters.failures.Add(1)
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		attemptReq = req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				t.counters.failures.Add(1)
+				return nil, err
+			}
+			attemptReq.Body = body
+		}
+		t.counters.retries.Add(1)
+	}
+}
+
+// canRetry reports whether the request may be sent more than once
+func (t *transport) canRetry(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return t.opts.RetryPost || req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// backoff returns the wait before the attempt after the given one: the
+// Retry-After of the response if any, otherwise an exponential backoff
+// with jitter. It returns false when the server asks for a longer wait
+// than MaxBackoff.
+func (t *transport) backoff(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return wait, wait <= t.opts.MaxBackoff
+		}
+	}
+
+	wait := t.opts.BaseBackoff << attempt
+	if wait <= 0 || wait > t.opts.MaxBackoff {
+		wait = t.opts.MaxBackoff
+	}
+	// Wait between half and all of it, so clients failing together spread out
+	return wait/2 + rand.N(wait/2+1), true
+}
+
+// retryAfter parses a Retry-After header given in seconds or as a date
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// shouldRetry reports whether an attempt failed in a way another attempt
+// may not: a connection error, or the server being busy or unreachable
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Stats counts the calls made by the clients with one name since start
+type Stats struct {
+	Requests int64 `json:"requests"` // Calls made, whatever the number of attempts
+	Retries  int64 `json:"retries"`  // Attempts after a failed one
+	Failures int64 `json:"failures"` // Calls that failed on their last attempt
+}
+
+// counters holds the stats of the clients with one name
+type counters struct {
+	requests atomic.Int64
+	retries  atomic.Int64
+	failures atomic.Int64
+}
+
+// registry holds the counters of every client name
+var registry sync.Map
+
+// countersFor returns the counters of the clients with the given name
+func countersFor(name string) *counters {
+	c, _ := registry.LoadOrStore(name, &counters{})
+	return c.(*counters)
+}
+
+// Snapshot returns the stats of every client name used since start
+func Snapshot() map[string]Stats {
+	stats := make(map[string]Stats)
+	registry.Range(func(key, value any) bool {
+		c := value.(*counters)
+		stats[key.(string)] = Stats{
+			Requests: c.requests.Load(),
+			Retries:  c.retries.Load(),
+			Failures: c.failures.Load(),
+		}
+		return true
+	})
+	return stats
+}
@@ -0,0 +1,143 @@
+package httpclient_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/httpclient"
+)
+
+// flakyServer fails the first requests with the given status and then
+// echoes the request body
+func flakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestRetries(t *testing.T) {
+	opts := httpclient.Options{Name: "test_retries", Timeout: 5 * time.Second, Retries: 2, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	client := httpclient.New(opts)
+
+	// Idempotent requests are retried until they succeed
+	server, calls := flakyServer(t, 2, http.StatusServiceUnavailable)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Errorf("Expected success on the third attempt, got %d after %d", resp.StatusCode, calls.Load())
+	}
+
+	// POST requests are not, unless they carry an idempotency key
+	server, calls = flakyServer(t, 1, http.StatusBadGateway)
+	resp, err = client.Post(server.URL, "text/plain", strings.NewReader("order"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || calls.Load() != 1 {
+		t.Errorf("Expected one attempt of a POST, got %d after %d", resp.StatusCode, calls.Load())
+	}
+
+	server, calls = flakyServer(t, 1, http.StatusBadGateway)
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("order"))
+	req.Header.Set(httpclient.IdempotencyKeyHeader, "abc")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "order" || calls.Load() != 2 {
+		t.Errorf("Expected the body to be sent again, got %d %q after %d", resp.StatusCode, body, calls.Load())
+	}
+
+	// Client errors are final
+	server, calls = flakyServer(t, 1, http.StatusBadRequest)
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || calls.Load() != 1 {
+		t.Errorf("Expected no retry of a 400, got %d after %d", resp.StatusCode, calls.Load())
+	}
+
+	// The last failure is returned once retries run out
+	server, calls = flakyServer(t, 5, http.StatusTooManyRequests)
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || calls.Load() != 3 {
+		t.Errorf("Expected three attempts, got %d after %d", resp.StatusCode, calls.Load())
+	}
+
+	stats := httpclient.Snapshot()["test_retries"]
+	if stats.Requests != 5 || stats.Retries != 5 || stats.Failures != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// A wait longer than the longest backoff is not honored, the failure is returned
+	client := httpclient.New(httpclient.Options{Name: "test_retry_after", Retries: 3, BaseBackoff: time.Millisecond, MaxBackoff: time.Second})
+	start := time.Now()
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if calls.Load() != 1 || time.Since(start) > 5*time.Second {
+		t.Errorf("Expected no retry, got %d attempts", calls.Load())
+	}
+}
+
+func TestConnectionErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	client := httpclient.New(httpclient.Options{Name: "test_connection", Retries: 1, BaseBackoff: time.Millisecond})
+	if _, err := client.Get(url); err == nil {
+		t.Fatal("Expected an error calling a closed server")
+	}
+	if stats := httpclient.Snapshot()["test_connection"]; stats.Retries != 1 || stats.Failures != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	cfg := config.New()
+	cfg.HTTPClient.Retries = 4
+	opts := httpclient.FromConfig(cfg, "webui_api")
+	if opts.Name != "webui_api" || opts.Retries != 4 || opts.Timeout != cfg.Timeouts.HTTPClient.Duration() || opts.RetryPost {
+		t.Errorf("Unexpected options: %+v", opts)
+	}
+}
@@ -0,0 +1,151 @@
+package i18n
+
+// apiTranslations are the details of API error responses, for clients that
+// ask for a language. The English texts are the ones the API sends anyway.
+var apiTranslations = map[string]map[string]string{
+	"en": {
+		"api_get_only":           "Only GET method is allowed",
+		"api_post_only":          "Only POST method is allowed",
+		"api_invalid_json":       "Invalid JSON payload",
+		"api_json_required":      "Content-Type must be application/json",
+		"api_rate_limited":       "Rate limit exceeded",
+		"api_unauthorized":       "Invalid or missing authentication token",
+		"api_admin_required":     "Admin token required",
+		"api_unavailable":        "Database is temporarily unavailable",
+		"api_processing_error":   "Error processing request",
+		"api_invalid_email":      "The provided email address is not valid",
+		"api_email_denied":       "Email address is not allowed",
+		"api_email_not_found":    "Email is not in the database",
+		"api_email_not_verified": "Email address is not verified",
+		"api_voucher_required":   "Email lookups are disabled, use a voucher code",
+		"api_invalid_voucher":    "The provided voucher code is not valid",
+		"api_already_redeemed":   "Cocktail already redeemed",
+		"api_event_archived":     "Event is archived",
+		"api_no_such_guest":      "No such guest",
+		"api_ticket_not_found":   "Ticket not found",
+		"api_ticket_expired":     "Ticket link has expired",
+		"api_ticket_invalid":     "Invalid ticket link",
+	},
+	"es": {
+		"api_get_only":           "Solo se permite el método GET",
+		"api_post_only":          "Solo se permite el método POST",
+		"api_invalid_json":       "Contenido JSON no válido",
+		"api_json_required":      "Content-Type debe ser application/json",
+		"api_rate_limited":       "Límite de solicitudes superado",
+		"api_unauthorized":       "Token de autenticación no válido o ausente",
+		"api_admin_required":     "Se requiere un token de administrador",
+		"api_unavailable":        "La base de datos no está disponible temporalmente",
+		"api_processing_error":   "Error al procesar la solicitud",
+		"api_invalid_email":      "La dirección de correo indicada no es válida",
+		"api_email_denied":       "La dirección de correo no está permitida",
+		"api_email_not_found":    "El correo no está en la base de datos",
+		"api_email_not_verified": "La dirección de correo no está verificada",
+		"api_voucher_required":   "La búsqueda por correo está desactivada, usa un código de cupón",
+		"api_invalid_voucher":    "El código de cupón indicado no es válido",
+		"api_already_redeemed":   "El cóctel ya fue canjeado",
+		"api_event_archived":     "El evento está archivado",
+		"api_no_such_guest":      "No existe ese invitado",
+		"api_ticket_not_found":   "Entrada no encontrada",
+		"api_ticket_expired":     "El enlace de la entrada ha caducado",
+		"api_ticket_invalid":     "Enlace de entrada no válido",
+	},
+	"fr": {
+		"api_get_only":           "Seule la méthode GET est autorisée",
+		"api_post_only":          "Seule la méthode POST est autorisée",
+		"api_invalid_json":       "Contenu JSON invalide",
+		"api_json_required":      "Content-Type doit être application/json",
+		"api_rate_limited":       "Limite de requêtes dépassée",
+		"api_unauthorized":       "Jeton d'authentification invalide ou manquant",
+		"api_admin_required":     "Jeton administrateur requis",
+		"api_unavailable":        "La base de données est temporairement indisponible",
+		"api_processing_error":   "Erreur lors du traitement de la requête",
+		"api_invalid_email":      "L'adresse e-mail fournie n'est pas valide",
+		"api_email_denied":       "Cette adresse e-mail n'est pas autorisée",
+		"api_email_not_found":    "L'e-mail n'est pas dans la base de données",
+		"api_email_not_verified": "L'adresse e-mail n'est pas vérifiée",
+		"api_voucher_required":   "La recherche par e-mail est désactivée, utilisez un code de bon",
+		"api_invalid_voucher":    "Le code de bon fourni n'est pas valide",
+		"api_already_redeemed":   "Cocktail déjà servi",
+		"api_event_archived":     "L'événement est archivé",
+		"api_no_such_guest":      "Invité introuvable",
+		"api_ticket_not_found":   "Billet introuvable",
+		"api_ticket_expired":     "Le lien du billet a expiré",
+		"api_ticket_invalid":     "Lien de billet invalide",
+	},
+	"de": {
+		"api_get_only":           "Nur die Methode GET ist erlaubt",
+		"api_post_only":          "Nur die Methode POST ist erlaubt",
+		"api_invalid_json":       "Ungültige JSON-Daten",
+		"api_json_required":      "Content-Type muss application/json sein",
+		"api_rate_limited":       "Anfragelimit überschritten",
+		"api_unauthorized":       "Ungültiges oder fehlendes Authentifizierungstoken",
+		"api_admin_required":     "Admin-Token erforderlich",
+		"api_unavailable":        "Die Datenbank ist vorübergehend nicht verfügbar",
+		"api_processing_error":   "Fehler bei der Verarbeitung der Anfrage",
+		"api_invalid_email":      "Die angegebene E-Mail-Adresse ist ungültig",
+		"api_email_denied":       "Diese E-Mail-Adresse ist nicht zugelassen",
+		"api_email_not_found":    "Die E-Mail ist nicht in der Datenbank",
+		"api_email_not_verified": "Die E-Mail-Adresse ist nicht bestätigt",
+		"api_voucher_required":   "Die Suche per E-Mail ist deaktiviert, bitte einen Gutscheincode verwenden",
+		"api_invalid_voucher":    "Der angegebene Gutscheincode ist ungültig",
+		"api_already_redeemed":   "Cocktail bereits eingelöst",
+		"api_event_archived":     "Die Veranstaltung ist archiviert",
+		"api_no_such_guest":      "Gast nicht gefunden",
+		"api_ticket_not_found":   "Ticket nicht gefunden",
+		"api_ticket_expired":     "Der Ticketlink ist abgelaufen",
+		"api_ticket_invalid":     "Ungültiger Ticketlink",
+	},
+	"ru": {
+		"api_get_only":           "Разрешён только метод GET",
+		"api_post_only":          "Разрешён только метод POST",
+		"api_invalid_json":       "Некорректные данные JSON",
+		"api_json_required":      "Content-Type должен быть application/json",
+		"api_rate_limited":       "Превышен лимит запросов",
+		"api_unauthorized":       "Неверный или отсутствующий токен аутентификации",
+		"api_admin_required":     "Требуется токен администратора",
+		"api_unavailable":        "База данных временно недоступна",
+		"api_processing_error":   "Ошибка при обработке запроса",
+		"api_invalid_email":      "Указан некорректный адрес электронной почты",
+		"api_email_denied":       "Этот адрес электронной почты не допускается",
+		"api_email_not_found":    "Адреса нет в базе данных",
+		"api_email_not_verified": "Адрес электронной почты не подтверждён",
+		"api_voucher_required":   "Поиск по адресу отключён, используйте код ваучера",
+		"api_invalid_voucher":    "Указан недействительный код ваучера",
+		"api_already_redeemed":   "Коктейль уже получен",
+		"api_event_archived":     "Мероприятие в архиве",
+		"api_no_such_guest":      "Гость не найден",
+		"api_ticket_not_found":   "Билет не найден",
+		"api_ticket_expired":     "Срок действия ссылки на билет истёк",
+		"api_ticket_invalid":     "Недействительная ссылка на билет",
+	},
+	"sr": {
+		"api_get_only":           "Dozvoljen je samo metod GET",
+		"api_post_only":          "Dozvoljen je samo metod POST",
+		"api_invalid_json":       "Neispravan JSON sadržaj",
+		"api_json_required":      "Content-Type mora biti application/json",
+		"api_rate_limited":       "Prekoračeno ograničenje zahteva",
+		"api_unauthorized":       "Neispravan ili nedostaje token za autentifikaciju",
+		"api_admin_required":     "Potreban je administratorski token",
+		"api_unavailable":        "Baza podataka je privremeno nedostupna",
+		"api_processing_error":   "Greška pri obradi zahteva",
+		"api_invalid_email":      "Navedena email adresa nije ispravna",
+		"api_email_denied":       "Ova email adresa nije dozvoljena",
+		"api_email_not_found":    "Email nije u bazi podataka",
+		"api_email_not_verified": "Email adresa nije potvrđena",
+		"api_voucher_required":   "Pretraga po emailu je isključena, koristite kod vaučera",
+		"api_invalid_voucher":    "Navedeni kod vaučera nije ispravan",
+		"api_already_redeemed":   "Koktel je već iskorišćen",
+		"api_event_archived":     "Događaj je arhiviran",
+		"api_no_such_guest":      "Gost ne postoji",
+		"api_ticket_not_found":   "Karta nije pronađena",
+		"api_ticket_expired":     "Link karte je istekao",
+		"api_ticket_invalid":     "Neispravan link karte",
+	},
+}
+
+// loadAPITranslations loads the details of API error responses
+func loadAPITranslations(translator *Translator) {
+	for lang, messages := range apiTranslations {
+		translator.LoadTranslations(lang, messages)
+	}
+}
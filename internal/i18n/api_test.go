@@ -0,0 +1,17 @@
+package i18n
+
+import "testing"
+
+func TestAPITranslations(t *testing.T) {
+	// Every language translates every API text
+	for lang, messages := range apiTranslations {
+		for key := range apiTranslations["en"] {
+			if messages[key] == "" {
+				t.Errorf("%s: missing API text %s", lang, key)
+			}
+		}
+		if len(messages) != len(apiTranslations["en"]) {
+			t.Errorf("%s: expected %d API texts, got %d", lang, len(apiTranslations["en"]), len(messages))
+		}
+	}
+}
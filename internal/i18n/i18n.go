@@ -16,6 +16,8 @@ type Translator struct {
 	fallback     string                       // fallback language
 	mutex        sync.RWMutex                 // to ensure thread safety
 	config       *config.Config               // application configuration
+	tone         string                       // wording variant looked up first, empty for the standard wording
+	decorators   []Decorator                  // applied to every translated text
 }
 
 // New creates a new Translator with the specified fallback language
@@ -28,11 +30,13 @@ func New(fallbackLang string) *Translator {
 
 // NewWithConfig creates a new Translator using configuration
 func NewWithConfig(cfg *config.Config) *Translator {
-	return &Translator{
+	t := &Translator{
 		translations: make(map[string]map[string]string),
 		fallback:     cfg.GetDefaultLanguage(),
 		config:       cfg,
 	}
+	t.applyPersona(cfg.Persona)
+	return t
 }
 
 // LoadTranslations initializes translations for a language
@@ -63,31 +67,16 @@ func (t *Translator) T(lang string, key string, args ...string) string {
 
 	lang = strings.ToLower(lang)
 	
-	// Check if the language is available
-	translations, langExists := t.translations[lang]
-	if !langExists {
-		// If the language doesn't exist, use the fallback language
-		translations, langExists = t.translations[t.fallback]
-		if !langExists {
-			return key
-		}
+	text, ok := t.lookup(lang, key)
+	if !ok {
+		return key
 	}
-	
-	// Check if the key exists for the language
-	text, keyExists := translations[key]
-	if !keyExists {
-		// Try fallback language
-		if fallbackTranslations, fbExists := t.translations[t.fallback]; fbExists {
-			if fallbackText, fbKeyExists := fallbackTranslations[key]; fbKeyExists {
-				text = fallbackText
-			} else {
-				return key
-			}
-		} else {
-			return key
-		}
-	}
-	
+	return t.render(lang, key, text, args)
+}
+
+// render fills the arguments into the text of a key and applies the
+// decorators. The caller must hold the read lock.
+func (t *Translator) render(lang, key, text string, args []string) string {
 	// Replace arguments in the text
 	for i := 0; i < len(args); i += 2 {
 		if i+1 < len(args) {
@@ -95,10 +84,42 @@ func (t *Translator) T(lang string, key string, args ...string) string {
 			text = strings.ReplaceAll(text, placeholder, args[i+1])
 		}
 	}
-	
+
+	for _, decorate := range t.decorators {
+		text = decorate(lang, key, text)
+	}
 	return text
 }
 
+// lookup finds the text of a key in the language, or else in the fallback
+// language, preferring the wording of the tone in each. Overrides win over
+// loaded translations. The caller must hold the read lock.
+func (t *Translator) lookup(lang, key string) (string, bool) {
+	for _, l := range []string{lang, t.fallback} {
+		translations, exists := t.translations[l]
+		if !exists {
+			continue
+		}
+		if t.tone != "" {
+			if text, ok := override(l, key+"."+t.tone); ok {
+				return text, true
+			}
+		}
+		if text, ok := override(l, key); ok {
+			return text, true
+		}
+		if t.tone != "" {
+			if text, ok := translations[key+"."+t.tone]; ok {
+				return text, true
+			}
+		}
+		if text, ok := translations[key]; ok {
+			return text, true
+		}
+	}
+	return "", false
+}
+
 // GetAvailableLanguages returns a list of available languages
 func (t *Translator) GetAvailableLanguages() []string {
 	t.mutex.RLock()
@@ -135,44 +156,69 @@ func (t *Translator) GetFallbackLanguage() string {
 // DetectLanguage attempts to detect the language from Telegram's From.LanguageCode
 // If it cannot detect or the language is not supported, it returns the default language
 func (t *Translator) DetectLanguage(tgLangCode string) string {
-	if tgLangCode == "" {
-		return t.fallback
-	}
-	
-	langCode := strings.ToLower(tgLangCode)
-	
-	// Handle special cases where Telegram uses different codes
-	// than our translation files might use
-	switch {
-	case strings.HasPrefix(langCode, "en"):
-		langCode = "en"
-	case strings.HasPrefix(langCode, "es"):
-		langCode = "es"
-	case strings.HasPrefix(langCode, "fr"):
-		langCode = "fr"
-	case strings.HasPrefix(langCode, "de"):
-		langCode = "de"
-	case strings.HasPrefix(langCode, "it"):
-		langCode = "it"
-	case strings.HasPrefix(langCode, "ru"):
-		langCode = "ru"
-	case strings.HasPrefix(langCode, "sr"):
-		langCode = "sr"
-	case strings.HasPrefix(langCode, "zh"):
-		langCode = "zh"
+	if lang, ok := t.MatchLanguage(tgLangCode); ok {
+		return lang
 	}
-	
+	return t.fallback
+}
+
+// builtinAliases maps language codes some clients still send to the codes
+// translations are kept under
+var builtinAliases = map[string]string{
+	"iw": "he", // Hebrew before ISO 639 changed its code
+	"in": "id", // Indonesian
+	"ji": "yi", // Yiddish
+	"nb": "no", // Norwegian Bokmål
+	"nn": "no", // Norwegian Nynorsk
+}
+
+// MatchLanguage returns the enabled language with translations that best
+// answers a locale such as pt-BR, sr_Latn or zh-Hans-CN. The whole locale
+// is tried first, then without its last part, down to the language alone.
+// At each step the code itself wins over its alias in language.aliases,
+// so an alias such as uk: ru stops applying once translations for uk are
+// loaded. Built-in aliases of legacy codes are tried last.
+func (t *Translator) MatchLanguage(locale string) (string, bool) {
+	tag := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+
 	t.mutex.RLock()
 	defer t.mutex.RUnlock()
-	
-	// If config is provided, check if language is enabled
-	if t.config != nil && !t.config.IsLanguageEnabled(langCode) {
-		return t.fallback
+
+	for tag != "" {
+		for _, lang := range []string{tag, t.configuredAlias(tag), builtinAliases[tag]} {
+			if lang != "" && t.supports(lang) {
+				return lang, true
+			}
+		}
+		i := strings.LastIndex(tag, "-")
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
 	}
-	
-	if _, exists := t.translations[langCode]; exists {
-		return langCode
+	return "", false
+}
+
+// configuredAlias returns the language a code is mapped to in
+// language.aliases, "" if none. The caller must hold the read lock.
+func (t *Translator) configuredAlias(tag string) string {
+	if t.config == nil {
+		return ""
 	}
-	
-	return t.fallback
-}
\ No newline at end of file
+	for code, lang := range t.config.Language.Aliases {
+		if strings.EqualFold(strings.ReplaceAll(code, "_", "-"), tag) {
+			return strings.ToLower(lang)
+		}
+	}
+	return ""
+}
+
+// supports returns true if a language is enabled and has translations.
+// The caller must hold the read lock.
+func (t *Translator) supports(lang string) bool {
+	if t.config != nil && !t.config.IsLanguageEnabled(lang) {
+		return false
+	}
+	_, exists := t.translations[lang]
+	return exists
+}
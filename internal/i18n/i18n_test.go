@@ -0,0 +1,50 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	cfg := config.New()
+	cfg.Language.Enabled = []string{"en", "ru", "sr", "pt", "pt-br", "zh", "he"}
+	cfg.Language.Aliases = map[string]string{"uk": "ru", "be_BY": "RU"}
+	translator := NewWithConfig(cfg)
+	for _, lang := range cfg.Language.Enabled {
+		translator.LoadTranslations(lang, map[string]string{"greeting": lang})
+	}
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"ru", "ru"},
+		{"pt-BR", "pt-br"},   // Regional translations win
+		{"pt_PT", "pt"},      // Other regions get the language
+		{"sr-Latn", "sr"},    // Scripts too
+		{"zh-Hans-CN", "zh"}, // Down to the language alone
+		{"uk", "ru"},         // Configured alias
+		{"uk-UA", "ru"},      // Alias of the language of a locale
+		{"be-BY", "ru"},      // Aliases of whole locales, in any case
+		{"iw", "he"},         // Built-in alias of a legacy code
+		{"fr-FR", "en"},      // Not enabled
+		{"", "en"},
+	}
+	for _, tt := range tests {
+		if got := translator.DetectLanguage(tt.locale); got != tt.want {
+			t.Errorf("DetectLanguage(%q) = %q, expected %q", tt.locale, got, tt.want)
+		}
+	}
+
+	// Once the language has translations, its alias no longer applies
+	cfg.Language.Enabled = append(cfg.Language.Enabled, "uk")
+	translator.LoadTranslations("uk", map[string]string{"greeting": "uk"})
+	if got := translator.DetectLanguage("uk-UA"); got != "uk" {
+		t.Errorf("Expected uk once it has translations, got %q", got)
+	}
+
+	if _, ok := translator.MatchLanguage("fr"); ok {
+		t.Error("Expected no match for a language that is not enabled")
+	}
+}
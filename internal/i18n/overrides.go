@@ -0,0 +1,78 @@
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// overrides holds texts that take precedence over the translations loaded
+// into every translator, by language and key. They are shared by the
+// translators of all channels so that a reload reaches each of them.
+var overrides = struct {
+	sync.RWMutex
+	messages map[string]map[string]string
+}{}
+
+// SetOverrides replaces the texts that take precedence over loaded
+// translations. Keys may carry a tone suffix, such as "welcome.party".
+func SetOverrides(messages map[string]map[string]string) {
+	normalized := make(map[string]map[string]string, len(messages))
+	for lang, texts := range messages {
+		lang = strings.ToLower(lang)
+		if normalized[lang] == nil {
+			normalized[lang] = make(map[string]string, len(texts))
+		}
+		for key, text := range texts {
+			normalized[lang][key] = text
+		}
+	}
+
+	overrides.Lock()
+	defer overrides.Unlock()
+	overrides.messages = normalized
+}
+
+// override returns the overriding text of a key in a language
+func override(lang, key string) (string, bool) {
+	overrides.RLock()
+	defer overrides.RUnlock()
+	text, ok := overrides.messages[lang][key]
+	return text, ok
+}
+
+// LoadOverrides reads the translations file of the configuration and the
+// messages set in the configuration itself, which win over the file, and
+// makes them the current overrides. It returns the number of texts loaded.
+func LoadOverrides(cfg *config.Config) (int, error) {
+	messages := make(map[string]map[string]string)
+	if path := cfg.Language.TranslationsFile; path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read translations file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &messages); err != nil {
+			return 0, fmt.Errorf("failed to parse translations file: %w", err)
+		}
+	}
+
+	count := 0
+	for lang, texts := range cfg.Messages {
+		if messages[lang] == nil {
+			messages[lang] = make(map[string]string, len(texts))
+		}
+		for key, text := range texts {
+			messages[lang][key] = text
+		}
+	}
+	for _, texts := range messages {
+		count += len(texts)
+	}
+
+	SetOverrides(messages)
+	return count, nil
+}
@@ -0,0 +1,67 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+)
+
+func TestLoadOverrides(t *testing.T) {
+	defer SetOverrides(nil)
+
+	file := filepath.Join(t.TempDir(), "translations.yaml")
+	err := os.WriteFile(file, []byte("en:\n  eligible: \"From the file\"\n  welcome: \"Welcome from the file\"\nes:\n  eligible: \"Del archivo\"\n"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write translations file: %v", err)
+	}
+
+	cfg := config.New()
+	cfg.Persona.Tone = ToneParty
+	cfg.Language.TranslationsFile = file
+	cfg.Messages = config.MessagesConfig{"en": {"eligible": "From the config"}}
+	translator := NewWithConfig(cfg)
+	LoadDefaultTranslations(translator)
+
+	count, err := LoadOverrides(cfg)
+	if err != nil || count != 3 {
+		t.Fatalf("Expected 3 texts loaded, got %d, %v", count, err)
+	}
+
+	// The config wins over the file, which wins over the built-in and tone wording
+	tests := []struct{ lang, key, want string }{
+		{"en", "eligible", "🍹 From the config"},
+		{"en", "welcome", "🎉 Welcome from the file"},
+		{"es", "eligible", "🍹 Del archivo"},
+		{"en", "button_skip", "👋 Later"},
+		{"fr", "button_skip", "👋 Sauter"},
+	}
+	for _, tt := range tests {
+		if got := translator.T(tt.lang, tt.key); got != tt.want {
+			t.Errorf("%s %s: expected %q, got %q", tt.lang, tt.key, tt.want, got)
+		}
+	}
+
+	// A reload replaces the overrides, a broken file keeps them
+	cfg.Messages = nil
+	cfg.Language.TranslationsFile = ""
+	if _, err := LoadOverrides(cfg); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if got := translator.T("en", "eligible"); got != "🍹 You're on the list! A free cocktail is waiting for you." {
+		t.Errorf("Expected the built-in wording after the reload, got %q", got)
+	}
+
+	cfg.Messages = config.MessagesConfig{"en": {"eligible": "Fixed"}}
+	if _, err := LoadOverrides(cfg); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	cfg.Language.TranslationsFile = filepath.Join(t.TempDir(), "missing.yaml")
+	if _, err := LoadOverrides(cfg); err == nil {
+		t.Error("Expected an error for a missing translations file")
+	}
+	if got := translator.T("en", "eligible"); got != "🍹 Fixed" {
+		t.Errorf("Expected the previous overrides to stay, got %q", got)
+	}
+}
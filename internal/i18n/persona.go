@@ -0,0 +1,120 @@
+package i18n
+
+import (
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+)
+
+// Tones supported by the persona configuration
+const (
+	ToneStandard = "standard"
+	ToneFormal   = "formal"
+	ToneParty    = "party"
+)
+
+// Decorator changes a translated text before it is returned by T, such as
+// adding an emoji. It is called with the language and key of the text.
+type Decorator func(lang, key, text string) string
+
+// toneEmoji is the emoji set of each tone, by translation key
+var toneEmoji = map[string]map[string]string{
+	ToneParty: {
+		"welcome":            "🎉",
+		"eligible":           "🍹",
+		"eligible_named":     "🍹",
+		"redemption_success": "🥂",
+		"already_redeemed":   "🙈",
+		"email_not_found":    "🤷",
+		"invalid_email":      "🤔",
+		"skip_redemption":    "👋",
+		"consent_thanks":     "💌",
+		"event_archived":     "🌙",
+		"upgrade_offer":      "🍸",
+		"button_redeem":      "🍹",
+		"button_skip":        "👋",
+		"button_buy":         "🍸",
+		"button_ticket":      "🎟",
+		"button_consent_yes": "💌",
+	},
+}
+
+// toneTranslations are the English texts of each tone that differ from the
+// standard wording. Languages without them use their standard wording.
+var toneTranslations = map[string]map[string]string{
+	ToneFormal: {
+		"welcome":            "Welcome. Please send the email address you registered with to check whether a complimentary cocktail is reserved for you.",
+		"eligible":           "Your email address has been found. A complimentary cocktail is reserved for you.",
+		"eligible_named":     "Welcome, {name}. Your email address has been found and a complimentary cocktail is reserved for you.",
+		"redemption_success": "Your complimentary cocktail was redeemed on {date}. We hope you enjoy it.",
+		"already_redeemed":   "Your email address has been found, but the complimentary cocktail was already redeemed on {date}.",
+		"email_not_found":    "We could not find this email address in our guest list.",
+		"skip_redemption":    "You have chosen not to redeem your cocktail now. You may check again later.",
+		"button_redeem":      "Redeem cocktail",
+		"button_skip":        "Not now",
+	},
+	ToneParty: {
+		"welcome":            "Hey, welcome to the party! Drop your email and let's see if a free cocktail has your name on it.",
+		"eligible":           "You're on the list! A free cocktail is waiting for you.",
+		"eligible_named":     "You're on the list, {name}! A free cocktail is waiting for you.",
+		"redemption_success": "Cheers! Your free cocktail was poured on {date}.",
+		"already_redeemed":   "You're on the list, but your free cocktail was already enjoyed on {date}.",
+		"email_not_found":    "Hmm, that email isn't on the list.",
+		"skip_redemption":    "No rush! Come back whenever you're thirsty.",
+		"button_redeem":      "Pour me one",
+		"button_skip":        "Later",
+	},
+}
+
+// loadToneTranslations loads the tone wording as "key.tone" entries
+func loadToneTranslations(translator *Translator) {
+	for tone, messages := range toneTranslations {
+		toned := make(map[string]string, len(messages))
+		for key, text := range messages {
+			toned[key+"."+tone] = text
+		}
+		translator.LoadTranslations("en", toned)
+	}
+}
+
+// SetTone selects the wording variant used by T. Texts without a variant
+// for the tone use the standard wording.
+func (t *Translator) SetTone(tone string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if tone == ToneStandard {
+		tone = ""
+	}
+	t.tone = tone
+}
+
+// AddDecorator adds a decorator applied to every text returned by T, in the
+// order decorators were added
+func (t *Translator) AddDecorator(decorator Decorator) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.decorators = append(t.decorators, decorator)
+}
+
+// PersonaDecorator returns a decorator putting the emoji of the configured
+// tone, with the configured overrides, before messages and button labels
+func PersonaDecorator(cfg config.PersonaConfig) Decorator {
+	emoji := make(map[string]string)
+	for key, value := range toneEmoji[cfg.Tone] {
+		emoji[key] = value
+	}
+	for key, value := range cfg.Emoji {
+		emoji[key] = value
+	}
+
+	return func(lang, key, text string) string {
+		if prefix := emoji[key]; prefix != "" {
+			return prefix + " " + text
+		}
+		return text
+	}
+}
+
+// applyPersona sets the tone and emoji of the configured persona
+func (t *Translator) applyPersona(cfg config.PersonaConfig) {
+	t.SetTone(cfg.Tone)
+	t.AddDecorator(PersonaDecorator(cfg))
+}
@@ -0,0 +1,58 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+)
+
+func TestPersona(t *testing.T) {
+	newTranslator := func(persona config.PersonaConfig) *Translator {
+		cfg := config.New()
+		cfg.Persona = persona
+		translator := NewWithConfig(cfg)
+		LoadDefaultTranslations(translator)
+		return translator
+	}
+
+	// The standard tone keeps the plain wording
+	standard := newTranslator(config.PersonaConfig{Tone: ToneStandard})
+	if got := standard.T("en", "button_redeem"); got != "Get Cocktail" {
+		t.Errorf("Expected the standard label, got %q", got)
+	}
+
+	// The party tone changes wording and adds emoji, also to buttons
+	party := newTranslator(config.PersonaConfig{Tone: ToneParty})
+	if got := party.T("en", "button_redeem"); got != "🍹 Pour me one" {
+		t.Errorf("Expected the party label, got %q", got)
+	}
+	if got := party.T("en", "redemption_success", "date", "today"); got != "🥂 Cheers! Your free cocktail was poured on today." {
+		t.Errorf("Expected the party message with its date, got %q", got)
+	}
+
+	// Languages without tone wording keep their own text, with the emoji
+	if got := party.T("es", "button_skip"); got != "👋 Saltar" {
+		t.Errorf("Expected the Spanish label with emoji, got %q", got)
+	}
+
+	// Configured emoji override and remove those of the tone
+	custom := newTranslator(config.PersonaConfig{
+		Tone:  ToneParty,
+		Emoji: map[string]string{"button_redeem": "🍸", "button_skip": "", "eligible": "✨"},
+	})
+	if got := custom.T("en", "button_redeem"); got != "🍸 Pour me one" {
+		t.Errorf("Expected the configured emoji, got %q", got)
+	}
+	if got := custom.T("en", "button_skip"); got != "Later" {
+		t.Errorf("Expected the emoji to be removed, got %q", got)
+	}
+
+	// Formal wording without emoji, and unknown keys are left alone
+	formal := newTranslator(config.PersonaConfig{Tone: ToneFormal})
+	if got := formal.T("en", "button_skip"); got != "Not now" {
+		t.Errorf("Expected the formal label, got %q", got)
+	}
+	if got := formal.T("en", "no_such_key"); got != "no_such_key" {
+		t.Errorf("Expected the key for a missing text, got %q", got)
+	}
+}
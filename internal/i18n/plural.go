@@ -0,0 +1,102 @@
+package i18n
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Plural categories of a count, as defined by the Unicode CLDR plural
+// rules. Plural texts are loaded under their key with the category as a
+// suffix, such as "minutes.one" and "minutes.other".
+const (
+	PluralOne   = "one"
+	PluralFew   = "few"
+	PluralMany  = "many"
+	PluralOther = "other"
+)
+
+// PluralCategory returns the plural category of the count n in the
+// language. Languages without rules use the English ones.
+func PluralCategory(lang string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+	mod10, mod100 := n%10, n%100
+
+	switch lang {
+	case "zh":
+		return PluralOther
+	case "fr":
+		if n == 0 || n == 1 {
+			return PluralOne
+		}
+		return PluralOther
+	case "ru":
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return PluralOne
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return PluralFew
+		default:
+			return PluralMany
+		}
+	case "sr":
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return PluralOne
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return PluralFew
+		default:
+			return PluralOther
+		}
+	default:
+		if n == 1 {
+			return PluralOne
+		}
+		return PluralOther
+	}
+}
+
+// TN returns the text of key for the count n, in the plural form of the
+// language. It looks up the key with the plural category as a suffix,
+// then with the "other" suffix, then the key itself. The {count}
+// placeholder is replaced with n.
+func (t *Translator) TN(lang string, key string, n int, args ...string) string {
+	lang = strings.ToLower(lang)
+	args = append([]string{"count", strconv.Itoa(n)}, args...)
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	for _, candidate := range []string{key + "." + PluralCategory(lang, n), key + "." + PluralOther, key} {
+		if text, ok := t.lookup(lang, candidate); ok {
+			return t.render(lang, key, text, args)
+		}
+	}
+	return key
+}
+
+// RetryIn returns a wait as a phrase for "try again in ...", such as
+// "2 minutes". Waits under a minute are given in seconds, longer ones are
+// rounded up to whole minutes.
+func (t *Translator) RetryIn(lang string, wait time.Duration) string {
+	if wait < time.Minute {
+		seconds := int((wait + time.Second - 1) / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		return t.TN(lang, "duration_seconds", seconds)
+	}
+	return t.TN(lang, "duration_minutes", int((wait+time.Minute-1)/time.Minute))
+}
+
+// TRetry returns the text of a key asking to try again later, such as
+// rate_limited. If the wait is known, the variant of the key with the
+// "_retry" suffix is used, naming the wait in its {retry} placeholder.
+func (t *Translator) TRetry(lang, key string, wait time.Duration) string {
+	if wait <= 0 {
+		return t.T(lang, key)
+	}
+	return t.T(lang, key+"_retry", "retry", t.RetryIn(lang, wait))
+}
@@ -0,0 +1,70 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPluralCategory(t *testing.T) {
+	tests := []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{"en", 1, PluralOne},
+		{"en", 0, PluralOther},
+		{"en", 2, PluralOther},
+		{"fr", 0, PluralOne},
+		{"fr", 2, PluralOther},
+		{"ru", 1, PluralOne},
+		{"ru", 21, PluralOne},
+		{"ru", 11, PluralMany},
+		{"ru", 3, PluralFew},
+		{"ru", 13, PluralMany},
+		{"ru", 24, PluralFew},
+		{"ru", 5, PluralMany},
+		{"sr", 22, PluralFew},
+		{"sr", 5, PluralOther},
+		{"zh", 1, PluralOther},
+	}
+	for _, tt := range tests {
+		if got := PluralCategory(tt.lang, tt.n); got != tt.want {
+			t.Errorf("PluralCategory(%q, %d) = %q, want %q", tt.lang, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestRetryMessages(t *testing.T) {
+	translator := New("en")
+	LoadDefaultTranslations(translator)
+
+	tests := []struct {
+		lang string
+		wait time.Duration
+		want string
+	}{
+		{"en", time.Minute, "1 minute"},
+		{"en", 61 * time.Second, "2 minutes"},
+		{"en", 500 * time.Millisecond, "1 second"},
+		{"en", 30 * time.Second, "30 seconds"},
+		{"ru", 21 * time.Minute, "21 минуту"},
+		{"ru", 3 * time.Minute, "3 минуты"},
+		{"ru", 12 * time.Minute, "12 минут"},
+		{"sr", 2 * time.Minute, "2 minuta"},
+		{"de", 5 * time.Second, "5 Sekunden"},
+		// Languages without translations use the fallback
+		{"it", 2 * time.Minute, "2 minutes"},
+	}
+	for _, tt := range tests {
+		if got := translator.RetryIn(tt.lang, tt.wait); got != tt.want {
+			t.Errorf("RetryIn(%q, %v) = %q, want %q", tt.lang, tt.wait, got, tt.want)
+		}
+	}
+
+	if got := translator.TRetry("en", "rate_limited", 2*time.Minute); got != "You've made too many requests. Please try again in 2 minutes." {
+		t.Errorf("Unexpected rate limit text: %q", got)
+	}
+	if got := translator.TRetry("en", "system_unavailable", 0); got != translator.T("en", "system_unavailable") {
+		t.Errorf("Expected the generic text without a wait, got %q", got)
+	}
+}
@@ -9,19 +9,107 @@ func LoadDefaultTranslations(translator *Translator) {
 		"unknown_command":        "Unknown command. Please send your email to check eligibility or use /help for more information.",
 		"rate_limited":           "You've made too many requests. Please try again in a few minutes.",
 		"email_not_found":        "Email is not in database.",
+		"email_suggestions":      "Email is not in database. Did you mean one of these?",
+		"suggestion_confirm":     "Check {email} instead?",
+		"button_suggestion_yes":  "Yes, that's mine",
+		"button_suggestion_no":   "No",
 		"system_unavailable":     "Sorry, our system is temporarily unavailable. Please try again later.",
 		"already_redeemed":       "Email found, but free cocktail already consumed on {date}.",
+		"redeemed_elsewhere":     "Someone else just redeemed the free cocktail for this email on {date}. These buttons no longer work.",
 		"eligible":               "Email found! You're eligible for a free cocktail.",
+		"eligible_named":         "Email found, {name}! You're eligible for a free cocktail.",
 		"error_occurred":         "Sorry, an error occurred. Please try again later.",
 		"email_not_cached":       "Sorry, I can't find your email. Please try again.",
 		"redemption_success":     "Enjoy your free cocktail! Redeemed on {date}.",
+		"ticket_ready":           "Need a paper record? Download your printable ticket: {url}",
+		"button_ticket":          "Print ticket",
 		"skip_redemption":        "You've chosen to skip the cocktail redemption. You can check again later.",
+		"redeem_not_allowed":     "Only bar staff can confirm redemptions.",
+		"kiosk_prompt":           "Enter the email address you registered with.",
+		"kiosk_check":            "Check",
+		"status_today":           "Cocktails served today",
+		"status_event":           "Cocktails served since the event started",
+		"status_updated":         "Updated at {time}",
+		"status_unavailable":     "The numbers are not available right now. Please check back soon.",
+		"consent_question":       "Would you like to hear about our future events?",
+		"button_consent_yes":     "Yes, keep me posted",
+		"button_consent_no":      "No, thanks",
+		"bar_question":           "Which bar is serving the drink?",
+		"voucher_required":       "Please use the personal link or voucher code you received for this event. Typed email addresses are not accepted.",
+		"voucher_invalid":        "That voucher code is not valid. Please check the link or code you received.",
+		"consent_thanks":         "Great! We'll let you know about upcoming events.",
+		"consent_declined":       "No problem, we won't send you marketing messages.",
+		"verification_sent":      "We've sent a 6-digit code to {email}. Please enter it here to confirm the email is yours.",
+		"verification_invalid":   "That code is not correct. Please try again.",
+		"verification_expired":   "That code has expired. Please send your email again to get a new code.",
+		"verification_too_many":  "Too many wrong codes. Please send your email again to get a new code.",
+		"verification_required":  "Please verify your email first. Send your email again to get a code.",
 		"button_redeem":          "Get Cocktail",
 		"button_skip":            "Skip",
-		"help_message":           "Here's how to use the Cocktail Bot:\n\n• Send your email address to check if you're eligible for a free cocktail\n• If eligible, you'll receive options to redeem or skip\n• Choose \"Get Cocktail\" to redeem your free drink\n• Each email can only be redeemed once\n\nCommands:\n/start - Start the bot\n/help - Show this help message\n/language - Change language\n\nSend an email address to begin!",
+		"help_message":           "Here's how to use the Cocktail Bot:\n\n• Send your email address to check if you're eligible for a free cocktail\n• If eligible, you'll receive options to redeem or skip\n• Choose \"Get Cocktail\" to redeem your free drink\n• Each email can only be redeemed once\n\nCommands:\n/start - Start the bot\n/help - Show this help message\n/language - Change language\n/mystatus - Show the status of your email\n\nSend an email address to begin!",
 		"language_command":       "Please select your preferred language:",
 		"language_set":           "Language set to English.",
 		"language_not_supported": "Sorry, this language is not supported yet.",
+		"cmd_start":              "Start the bot",
+		"cmd_help":               "Show help",
+		"cmd_language":           "Change language",
+		"cmd_mystatus":           "Show the status of your email",
+		"mystatus_none":          "You haven't checked an email yet. Send your email address to see your status.",
+		"still_checking":         "Still checking, thanks for your patience…",
+		"user_blocked":           "Sorry, this bot is not available to you. Please ask a staff member for help.",
+		"email_denied":           "Sorry, this email address cannot be used for a free cocktail. Please ask a staff member for help.",
+		"event_archived":         "This event has ended and its guest list is closed. Thank you for joining us!",
+		"upgrade_offer":          "Enjoyed it? You can buy another drink right here.",
+		"button_buy":             "Buy another drink",
+		"checkout_ready":         "Tap below to pay. Show the confirmation at the bar to get your drink.",
+		"button_pay":             "Pay now",
+		"checkout_failed":        "Sorry, the payment could not be started. Please try again or ask at the bar.",
+		"registration_offer":     "Email is not in database. You can ask the organizers to add it.",
+		"button_register":        "Request access",
+		"registration_requested": "Thanks! Your request for {email} was sent to the organizers. We'll let you know once they answer.",
+		"registration_pending":   "Your request for {email} is still waiting for the organizers.",
+		"registration_approved":  "Good news! {email} was added to the guest list.",
+		"registration_rejected":  "Sorry, the organizers did not approve {email} for this event.",
+		// Admin-only messages (English only, other languages fall back)
+		"admin_only":                  "This command is only available to administrators.",
+		"resetlimit_usage":            "Usage: /resetlimit <telegram_user_id>",
+		"resetlimit_done":             "Rate limit reset for user {user_id}.",
+		"cmd_stats":                   "Show engagement statistics",
+		"cmd_resetlimit":              "Reset a user's rate limit",
+		"cmd_failed":                  "List, retry or resolve failed redemptions",
+		"failed_none":                 "There are no failed redemptions.",
+		"failed_entry":                "#{id} {email} at {time} (retries: {retries}): {error}",
+		"failed_usage":                "Usage: /failed, /failed retry <id> or /failed resolve <id>",
+		"failed_retried":              "Redemption #{id} saved with time {time}.",
+		"failed_resolved":             "Redemption #{id} marked as resolved.",
+		"failed_not_found":            "There is no failed redemption #{id}.",
+		"failed_error":                "Could not update redemption #{id}: {error}",
+		"cmd_block":                   "Block a Telegram user or deny an email",
+		"cmd_unblock":                 "Unblock a Telegram user or allow an email",
+		"block_usage":                 "Usage: /block <telegram_user_id|email|@domain> or /unblock <telegram_user_id|email|@domain>",
+		"block_done":                  "User {user_id} blocked.",
+		"unblock_done":                "User {user_id} unblocked.",
+		"deny_done":                   "{email} added to the deny list.",
+		"allow_done":                  "{email} removed from the deny list.",
+		"stats_summary":               "Since {since}\nInteractions: {interactions}\nEmail checks: {checks}\nRedemptions: {redemptions}\nConversion: {conversion}\nBusiest hour: {hour}:00",
+		"registration_request":        "Access request #{id}: {email} (Telegram user {user_id}) is not on the guest list.",
+		"button_approve":              "Approve",
+		"button_reject":               "Reject",
+		"registration_approved_admin": "Request #{id} approved, {email} added to the guest list.",
+		"registration_rejected_admin": "Request #{id} for {email} rejected.",
+		"registration_decided":        "Request #{id} was already {status}.",
+		"registration_not_found":      "There is no access request #{id}.",
+		"registration_error":          "Could not update access request #{id}: {error}",
+		"receipt":                     "Redeemed: {email}\nTime: {time}\nBy: {source}",
+		"receipt_bar":                 "Redeemed: {email}\nTime: {time}\nBy: {source}\nBar: {bar}",
+
+		// Texts naming the wait before trying again, in plural forms
+		"rate_limited_retry":       "You've made too many requests. Please try again in {retry}.",
+		"system_unavailable_retry": "Sorry, our system is temporarily unavailable. Please try again in {retry}.",
+		"duration_seconds.one":     "{count} second",
+		"duration_seconds.other":   "{count} seconds",
+		"duration_minutes.one":     "{count} minute",
+		"duration_minutes.other":   "{count} minutes",
 	})
 
 	// Spanish translations
@@ -31,19 +119,75 @@ func LoadDefaultTranslations(translator *Translator) {
 		"unknown_command":        "Comando desconocido. Por favor, envía tu correo electrónico para verificar elegibilidad o usa /help para más información.",
 		"rate_limited":           "Has hecho demasiadas solicitudes. Por favor, inténtalo de nuevo en unos minutos.",
 		"email_not_found":        "El correo no está en la base de datos.",
+		"email_suggestions":      "El correo no está en la base de datos. ¿Quisiste decir alguno de estos?",
+		"suggestion_confirm":     "¿Comprobar {email} en su lugar?",
+		"button_suggestion_yes":  "Sí, es el mío",
+		"button_suggestion_no":   "No",
 		"system_unavailable":     "Lo sentimos, nuestro sistema está temporalmente no disponible. Por favor, inténtalo más tarde.",
 		"already_redeemed":       "Correo encontrado, pero el cóctel gratis ya fue consumido el {date}.",
+		"redeemed_elsewhere":     "Otra persona acaba de canjear el cóctel gratis de este correo el {date}. Estos botones ya no funcionan.",
 		"eligible":               "¡Correo encontrado! Eres elegible para un cóctel gratis.",
+		"eligible_named":         "¡Correo encontrado, {name}! Eres elegible para un cóctel gratis.",
 		"error_occurred":         "Lo sentimos, ocurrió un error. Por favor, inténtalo de nuevo más tarde.",
 		"email_not_cached":       "Lo siento, no puedo encontrar tu correo. Por favor, inténtalo de nuevo.",
 		"redemption_success":     "¡Disfruta tu cóctel gratis! Canjeado el {date}.",
+		"ticket_ready":           "¿Necesitas un comprobante en papel? Descarga tu ticket imprimible: {url}",
+		"button_ticket":          "Imprimir ticket",
 		"skip_redemption":        "Has elegido saltar el canje del cóctel. Puedes verificar nuevamente más tarde.",
+		"redeem_not_allowed":     "Solo el personal del bar puede confirmar los canjes.",
+		"kiosk_prompt":           "Introduce el correo electrónico con el que te registraste.",
+		"kiosk_check":            "Verificar",
+		"status_today":           "Cócteles servidos hoy",
+		"status_event":           "Cócteles servidos desde el inicio del evento",
+		"status_updated":         "Actualizado a las {time}",
+		"status_unavailable":     "Las cifras no están disponibles en este momento. Vuelve a consultar pronto.",
+		"consent_question":       "¿Te gustaría recibir noticias sobre nuestros próximos eventos?",
+		"button_consent_yes":     "Sí, mantenme informado",
+		"button_consent_no":      "No, gracias",
+		"bar_question":           "¿Qué barra sirve la bebida?",
+		"voucher_required":       "Por favor, usa el enlace personal o el código de cupón que recibiste para este evento. No se aceptan direcciones de correo escritas.",
+		"voucher_invalid":        "Ese código de cupón no es válido. Por favor, revisa el enlace o el código que recibiste.",
+		"consent_thanks":         "¡Genial! Te avisaremos de los próximos eventos.",
+		"consent_declined":       "Sin problema, no te enviaremos mensajes promocionales.",
+		"verification_sent":      "Hemos enviado un código de 6 dígitos a {email}. Introdúcelo aquí para confirmar que el correo es tuyo.",
+		"verification_invalid":   "Ese código no es correcto. Inténtalo de nuevo.",
+		"verification_expired":   "Ese código ha caducado. Envía tu correo de nuevo para recibir un código nuevo.",
+		"verification_too_many":  "Demasiados códigos incorrectos. Envía tu correo de nuevo para recibir un código nuevo.",
+		"verification_required":  "Primero verifica tu correo. Envíalo de nuevo para recibir un código.",
 		"button_redeem":          "Obtener Cóctel",
 		"button_skip":            "Saltar",
-		"help_message":           "Aquí tienes cómo usar el Bot de Cócteles:\n\n• Envía tu dirección de correo para verificar si eres elegible para un cóctel gratis\n• Si eres elegible, recibirás opciones para canjear o saltar\n• Elige \"Obtener Cóctel\" para canjear tu bebida gratis\n• Cada correo solo puede ser canjeado una vez\n\nComandos:\n/start - Iniciar el bot\n/help - Mostrar este mensaje de ayuda\n/language - Cambiar idioma\n\n¡Envía una dirección de correo para comenzar!",
+		"help_message":           "Aquí tienes cómo usar el Bot de Cócteles:\n\n• Envía tu dirección de correo para verificar si eres elegible para un cóctel gratis\n• Si eres elegible, recibirás opciones para canjear o saltar\n• Elige \"Obtener Cóctel\" para canjear tu bebida gratis\n• Cada correo solo puede ser canjeado una vez\n\nComandos:\n/start - Iniciar el bot\n/help - Mostrar este mensaje de ayuda\n/language - Cambiar idioma\n/mystatus - Ver el estado de tu correo\n\n¡Envía una dirección de correo para comenzar!",
 		"language_command":       "Por favor, selecciona tu idioma preferido:",
 		"language_set":           "Idioma establecido a Español.",
 		"language_not_supported": "Lo sentimos, este idioma aún no está soportado.",
+		"cmd_start":              "Iniciar el bot",
+		"cmd_help":               "Mostrar ayuda",
+		"cmd_language":           "Cambiar idioma",
+		"cmd_mystatus":           "Ver el estado de tu correo",
+		"mystatus_none":          "Aún no has consultado ningún correo. Envía tu dirección de correo para ver tu estado.",
+		"still_checking":         "Seguimos comprobando, gracias por tu paciencia…",
+		"user_blocked":           "Lo sentimos, este bot no está disponible para ti. Pide ayuda al personal.",
+		"email_denied":           "Lo sentimos, este correo no se puede usar para un cóctel gratis. Pide ayuda al personal.",
+		"event_archived":         "Este evento ha terminado y su lista de invitados está cerrada. ¡Gracias por acompañarnos!",
+		"upgrade_offer":          "¿Te gustó? Puedes comprar otra bebida aquí mismo.",
+		"button_buy":             "Comprar otra bebida",
+		"checkout_ready":         "Toca abajo para pagar. Muestra la confirmación en la barra para recibir tu bebida.",
+		"button_pay":             "Pagar ahora",
+		"checkout_failed":        "Lo sentimos, no se pudo iniciar el pago. Inténtalo de nuevo o pregunta en la barra.",
+		"registration_offer":     "El correo no está en la base de datos. Puedes pedir a los organizadores que lo añadan.",
+		"button_register":        "Solicitar acceso",
+		"registration_requested": "¡Gracias! Tu solicitud para {email} se envió a los organizadores. Te avisaremos cuando respondan.",
+		"registration_pending":   "Tu solicitud para {email} sigue esperando a los organizadores.",
+		"registration_approved":  "¡Buenas noticias! {email} se añadió a la lista de invitados.",
+		"registration_rejected":  "Lo sentimos, los organizadores no aprobaron {email} para este evento.",
+
+		// Texts naming the wait before trying again, in plural forms
+		"rate_limited_retry":       "Has hecho demasiadas solicitudes. Por favor, inténtalo de nuevo en {retry}.",
+		"system_unavailable_retry": "Lo sentimos, nuestro sistema está temporalmente no disponible. Por favor, inténtalo de nuevo en {retry}.",
+		"duration_seconds.one":     "{count} segundo",
+		"duration_seconds.other":   "{count} segundos",
+		"duration_minutes.one":     "{count} minuto",
+		"duration_minutes.other":   "{count} minutos",
 	})
 
 	// French translations
@@ -53,19 +197,75 @@ func LoadDefaultTranslations(translator *Translator) {
 		"unknown_command":        "Commande inconnue. Veuillez envoyer votre email pour vérifier l'éligibilité ou utiliser /help pour plus d'informations.",
 		"rate_limited":           "Vous avez fait trop de demandes. Veuillez réessayer dans quelques minutes.",
 		"email_not_found":        "Email non trouvé dans la base de données.",
+		"email_suggestions":      "Email non trouvé dans la base de données. Vouliez-vous dire l'une de ces adresses ?",
+		"suggestion_confirm":     "Vérifier {email} à la place ?",
+		"button_suggestion_yes":  "Oui, c'est la mienne",
+		"button_suggestion_no":   "Non",
 		"system_unavailable":     "Désolé, notre système est temporairement indisponible. Veuillez réessayer plus tard.",
 		"already_redeemed":       "Email trouvé, mais le cocktail gratuit a déjà été consommé le {date}.",
+		"redeemed_elsewhere":     "Quelqu'un d'autre vient d'utiliser le cocktail gratuit de cet email le {date}. Ces boutons ne fonctionnent plus.",
 		"eligible":               "Email trouvé ! Vous êtes éligible pour un cocktail gratuit.",
+		"eligible_named":         "Email trouvé, {name} ! Vous êtes éligible pour un cocktail gratuit.",
 		"error_occurred":         "Désolé, une erreur s'est produite. Veuillez réessayer plus tard.",
 		"email_not_cached":       "Désolé, je ne trouve pas votre email. Veuillez réessayer.",
 		"redemption_success":     "Profitez de votre cocktail gratuit ! Échangé le {date}.",
+		"ticket_ready":           "Besoin d'un justificatif papier ? Téléchargez votre ticket imprimable : {url}",
+		"button_ticket":          "Imprimer le ticket",
 		"skip_redemption":        "Vous avez choisi de sauter l'échange de cocktail. Vous pouvez vérifier à nouveau plus tard.",
+		"redeem_not_allowed":     "Seul le personnel du bar peut confirmer les échanges.",
+		"kiosk_prompt":           "Saisissez l'adresse email avec laquelle vous vous êtes inscrit.",
+		"kiosk_check":            "Vérifier",
+		"status_today":           "Cocktails servis aujourd'hui",
+		"status_event":           "Cocktails servis depuis le début de l'événement",
+		"status_updated":         "Mis à jour à {time}",
+		"status_unavailable":     "Les chiffres ne sont pas disponibles pour le moment. Revenez bientôt.",
+		"consent_question":       "Souhaitez-vous être informé de nos prochains événements ?",
+		"button_consent_yes":     "Oui, tenez-moi informé",
+		"button_consent_no":      "Non, merci",
+		"bar_question":           "Quel bar sert la boisson ?",
+		"voucher_required":       "Veuillez utiliser le lien personnel ou le code de bon reçu pour cet événement. Les adresses email saisies ne sont pas acceptées.",
+		"voucher_invalid":        "Ce code de bon n'est pas valide. Veuillez vérifier le lien ou le code reçu.",
+		"consent_thanks":         "Super ! Nous vous tiendrons au courant des prochains événements.",
+		"consent_declined":       "Pas de problème, nous ne vous enverrons pas de messages promotionnels.",
+		"verification_sent":      "Nous avons envoyé un code à 6 chiffres à {email}. Saisissez-le ici pour confirmer que cette adresse vous appartient.",
+		"verification_invalid":   "Ce code est incorrect. Veuillez réessayer.",
+		"verification_expired":   "Ce code a expiré. Renvoyez votre e-mail pour recevoir un nouveau code.",
+		"verification_too_many":  "Trop de codes incorrects. Renvoyez votre e-mail pour recevoir un nouveau code.",
+		"verification_required":  "Veuillez d'abord vérifier votre e-mail. Renvoyez-le pour recevoir un code.",
 		"button_redeem":          "Obtenir Cocktail",
 		"button_skip":            "Sauter",
-		"help_message":           "Voici comment utiliser le Bot Cocktail :\n\n• Envoyez votre adresse email pour vérifier si vous êtes éligible pour un cocktail gratuit\n• Si éligible, vous recevrez des options pour échanger ou sauter\n• Choisissez \"Obtenir Cocktail\" pour échanger votre boisson gratuite\n• Chaque email ne peut être échangé qu'une seule fois\n\nCommandes :\n/start - Démarrer le bot\n/help - Afficher ce message d'aide\n/language - Changer de langue\n\nEnvoyez une adresse email pour commencer !",
+		"help_message":           "Voici comment utiliser le Bot Cocktail :\n\n• Envoyez votre adresse email pour vérifier si vous êtes éligible pour un cocktail gratuit\n• Si éligible, vous recevrez des options pour échanger ou sauter\n• Choisissez \"Obtenir Cocktail\" pour échanger votre boisson gratuite\n• Chaque email ne peut être échangé qu'une seule fois\n\nCommandes :\n/start - Démarrer le bot\n/help - Afficher ce message d'aide\n/language - Changer de langue\n/mystatus - Voir le statut de votre email\n\nEnvoyez une adresse email pour commencer !",
 		"language_command":       "Veuillez sélectionner votre langue préférée :",
 		"language_set":           "Langue définie sur Français.",
 		"language_not_supported": "Désolé, cette langue n'est pas encore prise en charge.",
+		"cmd_start":              "Démarrer le bot",
+		"cmd_help":               "Afficher l'aide",
+		"cmd_language":           "Changer de langue",
+		"cmd_mystatus":           "Voir le statut de votre email",
+		"mystatus_none":          "Vous n'avez pas encore vérifié d'email. Envoyez votre adresse email pour voir votre statut.",
+		"still_checking":         "Vérification en cours, merci de votre patience…",
+		"user_blocked":           "Désolé, ce bot n'est pas disponible pour vous. Veuillez demander de l'aide au personnel.",
+		"email_denied":           "Désolé, cette adresse e-mail ne peut pas être utilisée pour un cocktail gratuit. Veuillez demander de l'aide au personnel.",
+		"event_archived":         "Cet événement est terminé et sa liste d'invités est close. Merci d'être venu !",
+		"upgrade_offer":          "Ça vous a plu ? Vous pouvez acheter une autre boisson ici.",
+		"button_buy":             "Acheter une autre boisson",
+		"checkout_ready":         "Appuyez ci-dessous pour payer. Montrez la confirmation au bar pour recevoir votre boisson.",
+		"button_pay":             "Payer maintenant",
+		"checkout_failed":        "Désolé, le paiement n'a pas pu être lancé. Réessayez ou adressez-vous au bar.",
+		"registration_offer":     "Email non trouvé dans la base de données. Vous pouvez demander aux organisateurs de l'ajouter.",
+		"button_register":        "Demander l'accès",
+		"registration_requested": "Merci ! Votre demande pour {email} a été envoyée aux organisateurs. Nous vous préviendrons dès leur réponse.",
+		"registration_pending":   "Votre demande pour {email} attend toujours les organisateurs.",
+		"registration_approved":  "Bonne nouvelle ! {email} a été ajouté à la liste des invités.",
+		"registration_rejected":  "Désolé, les organisateurs n'ont pas accepté {email} pour cet événement.",
+
+		// Texts naming the wait before trying again, in plural forms
+		"rate_limited_retry":       "Vous avez fait trop de demandes. Veuillez réessayer dans {retry}.",
+		"system_unavailable_retry": "Désolé, notre système est temporairement indisponible. Veuillez réessayer dans {retry}.",
+		"duration_seconds.one":     "{count} seconde",
+		"duration_seconds.other":   "{count} secondes",
+		"duration_minutes.one":     "{count} minute",
+		"duration_minutes.other":   "{count} minutes",
 	})
 
 	// German translations
@@ -75,19 +275,75 @@ func LoadDefaultTranslations(translator *Translator) {
 		"unknown_command":        "Unbekannter Befehl. Bitte senden Sie Ihre E-Mail, um die Berechtigung zu prüfen, oder verwenden Sie /help für weitere Informationen.",
 		"rate_limited":           "Sie haben zu viele Anfragen gestellt. Bitte versuchen Sie es in einigen Minuten erneut.",
 		"email_not_found":        "E-Mail nicht in der Datenbank gefunden.",
+		"email_suggestions":      "E-Mail nicht in der Datenbank gefunden. Meinten Sie eine dieser Adressen?",
+		"suggestion_confirm":     "Stattdessen {email} prüfen?",
+		"button_suggestion_yes":  "Ja, das ist meine",
+		"button_suggestion_no":   "Nein",
 		"system_unavailable":     "Entschuldigung, unser System ist vorübergehend nicht verfügbar. Bitte versuchen Sie es später erneut.",
 		"already_redeemed":       "E-Mail gefunden, aber der kostenlose Cocktail wurde bereits am {date} konsumiert.",
+		"redeemed_elsewhere":     "Jemand anderes hat den kostenlosen Cocktail für diese E-Mail gerade am {date} eingelöst. Diese Schaltflächen funktionieren nicht mehr.",
 		"eligible":               "E-Mail gefunden! Sie haben Anspruch auf einen kostenlosen Cocktail.",
+		"eligible_named":         "E-Mail gefunden, {name}! Sie haben Anspruch auf einen kostenlosen Cocktail.",
 		"error_occurred":         "Entschuldigung, ein Fehler ist aufgetreten. Bitte versuchen Sie es später erneut.",
 		"email_not_cached":       "Entschuldigung, ich kann Ihre E-Mail nicht finden. Bitte versuchen Sie es erneut.",
 		"redemption_success":     "Genießen Sie Ihren kostenlosen Cocktail! Eingelöst am {date}.",
+		"ticket_ready":           "Brauchen Sie einen Beleg? Laden Sie Ihr druckbares Ticket herunter: {url}",
+		"button_ticket":          "Ticket drucken",
 		"skip_redemption":        "Sie haben sich entschieden, die Cocktail-Einlösung zu überspringen. Sie können später erneut prüfen.",
+		"redeem_not_allowed":     "Nur das Barpersonal kann Einlösungen bestätigen.",
+		"kiosk_prompt":           "Geben Sie die E-Mail-Adresse ein, mit der Sie sich registriert haben.",
+		"kiosk_check":            "Prüfen",
+		"status_today":           "Heute servierte Cocktails",
+		"status_event":           "Seit Beginn der Veranstaltung servierte Cocktails",
+		"status_updated":         "Aktualisiert um {time}",
+		"status_unavailable":     "Die Zahlen sind gerade nicht verfügbar. Bitte schauen Sie bald wieder vorbei.",
+		"consent_question":       "Möchten Sie über unsere zukünftigen Veranstaltungen informiert werden?",
+		"button_consent_yes":     "Ja, gerne",
+		"button_consent_no":      "Nein, danke",
+		"bar_question":           "Welche Bar serviert das Getränk?",
+		"voucher_required":       "Bitte verwenden Sie den persönlichen Link oder Gutscheincode, den Sie für diese Veranstaltung erhalten haben. Eingetippte E-Mail-Adressen werden nicht akzeptiert.",
+		"voucher_invalid":        "Dieser Gutscheincode ist ungültig. Bitte prüfen Sie den erhaltenen Link oder Code.",
+		"consent_thanks":         "Super! Wir informieren Sie über kommende Veranstaltungen.",
+		"consent_declined":       "Kein Problem, wir senden Ihnen keine Werbenachrichten.",
+		"verification_sent":      "Wir haben einen 6-stelligen Code an {email} gesendet. Bitte geben Sie ihn hier ein, um zu bestätigen, dass die E-Mail Ihnen gehört.",
+		"verification_invalid":   "Dieser Code ist nicht korrekt. Bitte versuchen Sie es erneut.",
+		"verification_expired":   "Dieser Code ist abgelaufen. Senden Sie Ihre E-Mail erneut, um einen neuen Code zu erhalten.",
+		"verification_too_many":  "Zu viele falsche Codes. Senden Sie Ihre E-Mail erneut, um einen neuen Code zu erhalten.",
+		"verification_required":  "Bitte bestätigen Sie zuerst Ihre E-Mail. Senden Sie sie erneut, um einen Code zu erhalten.",
 		"button_redeem":          "Cocktail erhalten",
 		"button_skip":            "Überspringen",
-		"help_message":           "Hier ist, wie Sie den Cocktail-Bot verwenden können:\n\n• Senden Sie Ihre E-Mail-Adresse, um zu prüfen, ob Sie für einen kostenlosen Cocktail berechtigt sind\n• Wenn berechtigt, erhalten Sie Optionen zum Einlösen oder Überspringen\n• Wählen Sie \"Cocktail erhalten\", um Ihr kostenloses Getränk einzulösen\n• Jede E-Mail kann nur einmal eingelöst werden\n\nBefehle:\n/start - Bot starten\n/help - Diese Hilfemeldung anzeigen\n/language - Sprache ändern\n\nSenden Sie eine E-Mail-Adresse, um zu beginnen!",
+		"help_message":           "Hier ist, wie Sie den Cocktail-Bot verwenden können:\n\n• Senden Sie Ihre E-Mail-Adresse, um zu prüfen, ob Sie für einen kostenlosen Cocktail berechtigt sind\n• Wenn berechtigt, erhalten Sie Optionen zum Einlösen oder Überspringen\n• Wählen Sie \"Cocktail erhalten\", um Ihr kostenloses Getränk einzulösen\n• Jede E-Mail kann nur einmal eingelöst werden\n\nBefehle:\n/start - Bot starten\n/help - Diese Hilfemeldung anzeigen\n/language - Sprache ändern\n/mystatus - Status Ihrer E-Mail anzeigen\n\nSenden Sie eine E-Mail-Adresse, um zu beginnen!",
 		"language_command":       "Bitte wählen Sie Ihre bevorzugte Sprache:",
 		"language_set":           "Sprache auf Deutsch eingestellt.",
 		"language_not_supported": "Entschuldigung, diese Sprache wird noch nicht unterstützt.",
+		"cmd_start":              "Bot starten",
+		"cmd_help":               "Hilfe anzeigen",
+		"cmd_language":           "Sprache ändern",
+		"cmd_mystatus":           "Status Ihrer E-Mail anzeigen",
+		"mystatus_none":          "Sie haben noch keine E-Mail geprüft. Senden Sie Ihre E-Mail-Adresse, um Ihren Status zu sehen.",
+		"still_checking":         "Wird noch geprüft, danke für Ihre Geduld…",
+		"user_blocked":           "Leider steht Ihnen dieser Bot nicht zur Verfügung. Bitte wenden Sie sich an das Personal.",
+		"email_denied":           "Leider kann diese E-Mail-Adresse nicht für einen Gratis-Cocktail verwendet werden. Bitte wenden Sie sich an das Personal.",
+		"event_archived":         "Diese Veranstaltung ist beendet und die Gästeliste geschlossen. Danke für Ihren Besuch!",
+		"upgrade_offer":          "Hat es geschmeckt? Hier können Sie ein weiteres Getränk kaufen.",
+		"button_buy":             "Weiteres Getränk kaufen",
+		"checkout_ready":         "Tippen Sie unten, um zu bezahlen. Zeigen Sie die Bestätigung an der Bar, um Ihr Getränk zu erhalten.",
+		"button_pay":             "Jetzt bezahlen",
+		"checkout_failed":        "Leider konnte die Zahlung nicht gestartet werden. Bitte versuchen Sie es erneut oder fragen Sie an der Bar.",
+		"registration_offer":     "E-Mail nicht in der Datenbank gefunden. Sie können die Veranstalter bitten, sie hinzuzufügen.",
+		"button_register":        "Zugang anfragen",
+		"registration_requested": "Danke! Ihre Anfrage für {email} wurde an die Veranstalter gesendet. Wir melden uns, sobald sie antworten.",
+		"registration_pending":   "Ihre Anfrage für {email} wartet noch auf die Veranstalter.",
+		"registration_approved":  "Gute Nachricht! {email} wurde in die Gästeliste aufgenommen.",
+		"registration_rejected":  "Leider haben die Veranstalter {email} für diese Veranstaltung nicht freigegeben.",
+
+		// Texts naming the wait before trying again, in plural forms
+		"rate_limited_retry":       "Sie haben zu viele Anfragen gestellt. Bitte versuchen Sie es in {retry} erneut.",
+		"system_unavailable_retry": "Entschuldigung, unser System ist vorübergehend nicht verfügbar. Bitte versuchen Sie es in {retry} erneut.",
+		"duration_seconds.one":     "{count} Sekunde",
+		"duration_seconds.other":   "{count} Sekunden",
+		"duration_minutes.one":     "{count} Minute",
+		"duration_minutes.other":   "{count} Minuten",
 	})
 
 	// Russian translations
@@ -97,19 +353,79 @@ func LoadDefaultTranslations(translator *Translator) {
 		"unknown_command":        "Неизвестная команда. Пожалуйста, отправьте свой email для проверки права или используйте /help для получения дополнительной информации.",
 		"rate_limited":           "Вы сделали слишком много запросов. Пожалуйста, повторите попытку через несколько минут.",
 		"email_not_found":        "Email не найден в базе данных.",
+		"email_suggestions":      "Email не найден в базе данных. Возможно, вы имели в виду один из этих адресов?",
+		"suggestion_confirm":     "Проверить {email} вместо этого?",
+		"button_suggestion_yes":  "Да, это мой",
+		"button_suggestion_no":   "Нет",
 		"system_unavailable":     "Извините, наша система временно недоступна. Пожалуйста, повторите попытку позже.",
 		"already_redeemed":       "Email найден, но бесплатный коктейль уже был использован {date}.",
+		"redeemed_elsewhere":     "Кто-то другой только что получил бесплатный коктейль по этому email {date}. Эти кнопки больше не работают.",
 		"eligible":               "Email найден! Вы имеете право на бесплатный коктейль.",
+		"eligible_named":         "Email найден, {name}! Вы имеете право на бесплатный коктейль.",
 		"error_occurred":         "Извините, произошла ошибка. Пожалуйста, повторите попытку позже.",
 		"email_not_cached":       "Извините, я не могу найти ваш email. Пожалуйста, повторите попытку.",
 		"redemption_success":     "Наслаждайтесь вашим бесплатным коктейлем! Получено {date}.",
+		"ticket_ready":           "Нужен бумажный чек? Скачайте билет для печати: {url}",
+		"button_ticket":          "Распечатать билет",
 		"skip_redemption":        "Вы решили пропустить получение коктейля. Вы можете проверить снова позже.",
+		"redeem_not_allowed":     "Подтверждать получение коктейля может только персонал бара.",
+		"kiosk_prompt":           "Введите email, указанный при регистрации.",
+		"kiosk_check":            "Проверить",
+		"status_today":           "Коктейлей подано сегодня",
+		"status_event":           "Коктейлей подано с начала мероприятия",
+		"status_updated":         "Обновлено в {time}",
+		"status_unavailable":     "Данные сейчас недоступны. Загляните немного позже.",
+		"consent_question":       "Хотите получать новости о наших будущих мероприятиях?",
+		"button_consent_yes":     "Да, держите меня в курсе",
+		"button_consent_no":      "Нет, спасибо",
+		"bar_question":           "Какой бар подаёт напиток?",
+		"voucher_required":       "Пожалуйста, используйте персональную ссылку или код ваучера, полученные для этого мероприятия. Введённые адреса электронной почты не принимаются.",
+		"voucher_invalid":        "Этот код ваучера недействителен. Пожалуйста, проверьте полученную ссылку или код.",
+		"consent_thanks":         "Отлично! Мы сообщим вам о предстоящих мероприятиях.",
+		"consent_declined":       "Хорошо, мы не будем отправлять вам рекламные сообщения.",
+		"verification_sent":      "Мы отправили 6-значный код на {email}. Введите его здесь, чтобы подтвердить, что это ваш адрес.",
+		"verification_invalid":   "Неверный код. Попробуйте ещё раз.",
+		"verification_expired":   "Срок действия кода истёк. Отправьте email ещё раз, чтобы получить новый код.",
+		"verification_too_many":  "Слишком много неверных кодов. Отправьте email ещё раз, чтобы получить новый код.",
+		"verification_required":  "Сначала подтвердите email. Отправьте его ещё раз, чтобы получить код.",
 		"button_redeem":          "Получить коктейль",
 		"button_skip":            "Пропустить",
-		"help_message":           "Вот как использовать Cocktail Bot:\n\n• Отправьте свой адрес электронной почты, чтобы проверить, имеете ли вы право на бесплатный коктейль\n• Если вы имеете право, вы получите варианты использования или пропуска\n• Выберите \"Получить коктейль\", чтобы получить бесплатный напиток\n• Каждый email может быть использован только один раз\n\nКоманды:\n/start - Запустить бота\n/help - Показать это сообщение справки\n/language - Изменить язык\n\nОтправьте адрес электронной почты, чтобы начать!",
+		"help_message":           "Вот как использовать Cocktail Bot:\n\n• Отправьте свой адрес электронной почты, чтобы проверить, имеете ли вы право на бесплатный коктейль\n• Если вы имеете право, вы получите варианты использования или пропуска\n• Выберите \"Получить коктейль\", чтобы получить бесплатный напиток\n• Каждый email может быть использован только один раз\n\nКоманды:\n/start - Запустить бота\n/help - Показать это сообщение справки\n/language - Изменить язык\n/mystatus - Показать статус вашего email\n\nОтправьте адрес электронной почты, чтобы начать!",
 		"language_command":       "Пожалуйста, выберите предпочитаемый язык:",
 		"language_set":           "Язык установлен на Русский.",
 		"language_not_supported": "Извините, этот язык еще не поддерживается.",
+		"cmd_start":              "Запустить бота",
+		"cmd_help":               "Показать справку",
+		"cmd_language":           "Изменить язык",
+		"cmd_mystatus":           "Показать статус вашего email",
+		"mystatus_none":          "Вы ещё не проверяли email. Отправьте свой адрес электронной почты, чтобы узнать статус.",
+		"still_checking":         "Всё ещё проверяем, спасибо за терпение…",
+		"user_blocked":           "Извините, этот бот вам недоступен. Обратитесь, пожалуйста, к персоналу.",
+		"email_denied":           "Извините, этот email нельзя использовать для бесплатного коктейля. Обратитесь, пожалуйста, к персоналу.",
+		"event_archived":         "Это мероприятие завершено, и список гостей закрыт. Спасибо, что были с нами!",
+		"upgrade_offer":          "Понравилось? Здесь можно купить ещё один напиток.",
+		"button_buy":             "Купить ещё напиток",
+		"checkout_ready":         "Нажмите ниже, чтобы оплатить. Покажите подтверждение в баре, чтобы получить напиток.",
+		"button_pay":             "Оплатить",
+		"checkout_failed":        "Извините, не удалось начать оплату. Попробуйте ещё раз или обратитесь в бар.",
+		"registration_offer":     "Email не найден в базе данных. Вы можете попросить организаторов добавить его.",
+		"button_register":        "Запросить доступ",
+		"registration_requested": "Спасибо! Ваш запрос для {email} отправлен организаторам. Мы сообщим, когда они ответят.",
+		"registration_pending":   "Ваш запрос для {email} ещё ждёт ответа организаторов.",
+		"registration_approved":  "Хорошие новости! {email} добавлен в список гостей.",
+		"registration_rejected":  "Извините, организаторы не одобрили {email} для этого мероприятия.",
+
+		// Texts naming the wait before trying again, in plural forms
+		"rate_limited_retry":       "Вы сделали слишком много запросов. Пожалуйста, повторите попытку через {retry}.",
+		"system_unavailable_retry": "Извините, наша система временно недоступна. Пожалуйста, повторите попытку через {retry}.",
+		"duration_seconds.one":     "{count} секунду",
+		"duration_seconds.few":     "{count} секунды",
+		"duration_seconds.many":    "{count} секунд",
+		"duration_seconds.other":   "{count} секунды",
+		"duration_minutes.one":     "{count} минуту",
+		"duration_minutes.few":     "{count} минуты",
+		"duration_minutes.many":    "{count} минут",
+		"duration_minutes.other":   "{count} минуты",
 	})
 
 	// Serbian translations
@@ -119,18 +435,85 @@ func LoadDefaultTranslations(translator *Translator) {
 		"unknown_command":        "Nepoznata komanda. Molimo vas pošaljite svoju e-mail adresu da proverite podobnost ili koristite /help za više informacija.",
 		"rate_limited":           "Napravili ste previše zahteva. Molimo vas pokušajte ponovo za nekoliko minuta.",
 		"email_not_found":        "E-mail nije pronađen u bazi podataka.",
+		"email_suggestions":      "E-mail nije pronađen u bazi podataka. Da li ste mislili na neku od ovih adresa?",
+		"suggestion_confirm":     "Proveriti {email} umesto toga?",
+		"button_suggestion_yes":  "Da, to je moja",
+		"button_suggestion_no":   "Ne",
 		"system_unavailable":     "Žao nam je, naš sistem je trenutno nedostupan. Molimo vas pokušajte ponovo kasnije.",
 		"already_redeemed":       "E-mail pronađen, ali besplatni koktel je već iskorišćen {date}.",
+		"redeemed_elsewhere":     "Neko drugi je upravo iskoristio besplatni koktel za ovaj e-mail {date}. Ova dugmad više ne rade.",
 		"eligible":               "E-mail pronađen! Imate pravo na besplatni koktel.",
+		"eligible_named":         "E-mail pronađen, {name}! Imate pravo na besplatni koktel.",
 		"error_occurred":         "Žao nam je, došlo je do greške. Molimo vas pokušajte ponovo kasnije.",
 		"email_not_cached":       "Žao mi je, ne mogu da pronađem vašu e-mail adresu. Molimo vas pokušajte ponovo.",
 		"redemption_success":     "Uživajte u vašem besplatnom koktelu! Iskorišćeno {date}.",
+		"ticket_ready":           "Treba vam papirna potvrda? Preuzmite tiket za štampu: {url}",
+		"button_ticket":          "Odštampaj tiket",
 		"skip_redemption":        "Izabrali ste da preskočite iskorišćavanje koktela. Možete proveriti ponovo kasnije.",
+		"redeem_not_allowed":     "Samo osoblje bara može da potvrdi preuzimanje.",
+		"kiosk_prompt":           "Unesite email adresu kojom ste se registrovali.",
+		"kiosk_check":            "Proveri",
+		"status_today":           "Koktela posluženo danas",
+		"status_event":           "Koktela posluženo od početka događaja",
+		"status_updated":         "Ažurirano u {time}",
+		"status_unavailable":     "Brojevi trenutno nisu dostupni. Proverite ponovo uskoro.",
+		"consent_question":       "Da li želite da dobijate obaveštenja o našim budućim događajima?",
+		"button_consent_yes":     "Da, obaveštavajte me",
+		"button_consent_no":      "Ne, hvala",
+		"bar_question":           "Koji bar služi piće?",
+		"voucher_required":       "Molimo vas koristite ličnu vezu ili kod vaučera koji ste dobili za ovaj događaj. Ukucane e-mail adrese se ne prihvataju.",
+		"voucher_invalid":        "Taj kod vaučera nije validan. Molimo vas proverite vezu ili kod koji ste dobili.",
+		"consent_thanks":         "Odlično! Obavestićemo vas o predstojećim događajima.",
+		"consent_declined":       "Nema problema, nećemo vam slati promotivne poruke.",
+		"verification_sent":      "Poslali smo šestocifreni kod na {email}. Unesite ga ovde da potvrdite da je email vaš.",
+		"verification_invalid":   "Kod nije ispravan. Pokušajte ponovo.",
+		"verification_expired":   "Kod je istekao. Pošaljite email ponovo da dobijete novi kod.",
+		"verification_too_many":  "Previše pogrešnih kodova. Pošaljite email ponovo da dobijete novi kod.",
+		"verification_required":  "Prvo potvrdite email. Pošaljite ga ponovo da dobijete kod.",
 		"button_redeem":          "Uzmi Koktel",
 		"button_skip":            "Preskoči",
-		"help_message":           "Evo kako koristiti Cocktail Bot:\n\n• Pošaljite svoju e-mail adresu da proverite da li imate pravo na besplatni koktel\n• Ako imate pravo, dobićete opcije za iskorišćavanje ili preskakanje\n• Izaberite \"Uzmi Koktel\" da iskoristite svoje besplatno piće\n• Svaka e-mail adresa može biti iskorišćena samo jednom\n\nKomande:\n/start - Pokrenite bota\n/help - Prikažite ovu poruku za pomoć\n/language - Promenite jezik\n\nPošaljite e-mail adresu da počnete!",
+		"help_message":           "Evo kako koristiti Cocktail Bot:\n\n• Pošaljite svoju e-mail adresu da proverite da li imate pravo na besplatni koktel\n• Ako imate pravo, dobićete opcije za iskorišćavanje ili preskakanje\n• Izaberite \"Uzmi Koktel\" da iskoristite svoje besplatno piće\n• Svaka e-mail adresa može biti iskorišćena samo jednom\n\nKomande:\n/start - Pokrenite bota\n/help - Prikažite ovu poruku za pomoć\n/language - Promenite jezik\n/mystatus - Prikažite status vaše e-mail adrese\n\nPošaljite e-mail adresu da počnete!",
 		"language_command":       "Molimo izaberite vaš željeni jezik:",
 		"language_set":           "Jezik podešen na Srpski.",
 		"language_not_supported": "Žao nam je, ovaj jezik još uvek nije podržan.",
+		"cmd_start":              "Pokrenite bota",
+		"cmd_help":               "Prikažite pomoć",
+		"cmd_language":           "Promenite jezik",
+		"cmd_mystatus":           "Prikažite status vaše e-mail adrese",
+		"mystatus_none":          "Još niste proverili e-mail adresu. Pošaljite svoju e-mail adresu da vidite status.",
+		"still_checking":         "Još proveravamo, hvala na strpljenju…",
+		"user_blocked":           "Izvinite, ovaj bot vam nije dostupan. Obratite se osoblju za pomoć.",
+		"email_denied":           "Izvinite, ova email adresa ne može da se koristi za besplatan koktel. Obratite se osoblju za pomoć.",
+		"event_archived":         "Ovaj događaj je završen i lista gostiju je zatvorena. Hvala što ste bili sa nama!",
+		"upgrade_offer":          "Svidelo vam se? Ovde možete kupiti još jedno piće.",
+		"button_buy":             "Kupi još jedno piće",
+		"checkout_ready":         "Dodirnite ispod da platite. Pokažite potvrdu na šanku da dobijete piće.",
+		"button_pay":             "Plati sada",
+		"checkout_failed":        "Izvinite, plaćanje nije moglo da počne. Pokušajte ponovo ili pitajte na šanku.",
+		"registration_offer":     "Email nije u bazi podataka. Možete zamoliti organizatore da ga dodaju.",
+		"button_register":        "Zatraži pristup",
+		"registration_requested": "Hvala! Vaš zahtev za {email} je poslat organizatorima. Javićemo vam kada odgovore.",
+		"registration_pending":   "Vaš zahtev za {email} još čeka organizatore.",
+		"registration_approved":  "Dobre vesti! {email} je dodat na listu gostiju.",
+		"registration_rejected":  "Izvinite, organizatori nisu odobrili {email} za ovaj događaj.",
+
+		// Texts naming the wait before trying again, in plural forms
+		"rate_limited_retry":       "Napravili ste previše zahteva. Molimo vas pokušajte ponovo za {retry}.",
+		"system_unavailable_retry": "Žao nam je, naš sistem je trenutno nedostupan. Molimo vas pokušajte ponovo za {retry}.",
+		"duration_seconds.one":     "{count} sekundu",
+		"duration_seconds.few":     "{count} sekunde",
+		"duration_seconds.other":   "{count} sekundi",
+		"duration_minutes.one":     "{count} minut",
+		"duration_minutes.few":     "{count} minuta",
+		"duration_minutes.other":   "{count} minuta",
 	})
+
+	// Wording of the formal and party tones
+	loadToneTranslations(translator)
+
+	// Texts of the staff WebUI
+	loadWebUITranslations(translator)
+
+	// Details of API error responses
+	loadAPITranslations(translator)
 }
@@ -0,0 +1,762 @@
+package i18n
+
+// webUITranslations are the texts of the WebUI pages used by staff
+var webUITranslations = map[string]map[string]string{
+	"en": {
+		"webui_language_name":       "English",
+		"webui_language":            "Language",
+		"webui_nav_dashboard":       "Dashboard",
+		"webui_nav_users":           "All Users",
+		"webui_nav_redeemed":        "Redeemed Cocktails",
+		"webui_nav_audit":           "Audit Log",
+		"webui_welcome":             "Welcome, {user}",
+		"webui_logout":              "Logout",
+		"webui_footer":              "Cocktail Bot Admin Interface",
+		"webui_database":            "Database",
+		"webui_database_unknown":    "unknown",
+		"webui_database_users":      "{count} users",
+		"webui_database_last_write": "last write {time}",
+		"webui_total_users":         "Total Users",
+		"webui_total_users_text":    "Total registered users",
+		"webui_redeemed_text":       "Users who redeemed their cocktails",
+		"webui_last_month":          "Last Month",
+		"webui_last_month_text":     "New users in the last 30 days",
+		"webui_last_week":           "Last Week",
+		"webui_last_week_text":      "New users in the last 7 days",
+		"webui_tonight":             "Tonight",
+		"webui_tonight_text":        "Cocktails redeemed since midnight",
+		"webui_this_week":           "This Week",
+		"webui_this_week_text":      "Cocktails redeemed since the start of the week",
+		"webui_vs_yesterday":        "{change} vs yesterday by this time ({previous})",
+		"webui_vs_last_week":        "{change} vs last week by this time ({previous})",
+		"webui_vs_before":           "{change} vs the same span before ({previous})",
+		"webui_language_split":      "Language Split",
+		"webui_no_interactions":     "No interactions yet",
+		"webui_busiest_hours":       "Busiest Hours",
+		"webui_busiest_hour":        "Busiest hour: {hour}:00",
+		"webui_chart_messages":      "Messages",
+		"webui_conversion":          "Check → Redeem Conversion",
+		"webui_conversion_text":     "{redemptions} redemptions from {checks} email checks since startup",
+		"webui_latency":             "Redemption Latency",
+		"webui_latency_text":        "Median {p50}, 90% within {p90}, over {count} redemptions",
+		"webui_chart_guests":        "Guests",
+		"webui_quick_actions":       "Quick Actions",
+		"webui_view_users":          "View All Users",
+		"webui_view_redeemed":       "View Redeemed Cocktails",
+		"webui_users_total":         "Total: {count} users",
+		"webui_show_emails":         "Show full emails",
+		"webui_hide_emails":         "Hide full emails",
+		"webui_column_id":           "ID",
+		"webui_column_email":        "Email",
+		"webui_column_name":         "Name",
+		"webui_column_added":        "Date Added",
+		"webui_column_redeemed":     "Redeemed",
+		"webui_column_updated":      "Last Updated",
+		"webui_column_added_by":     "Added By",
+		"webui_column_time":         "Time",
+		"webui_column_actor":        "Actor",
+		"webui_column_action":       "Action",
+		"webui_column_details":      "Details",
+		"webui_not_redeemed":        "No",
+		"webui_login":               "Login",
+		"webui_login_title":         "{name} Login",
+		"webui_login_token":         "Authentication Token",
+		"webui_login_placeholder":   "Enter your API token",
+		"webui_login_help":          "Use the same token as configured for API access",
+		"webui_login_footer":        "Enter your authentication token to access the dashboard",
+		"webui_login_invalid":       "Invalid authentication token",
+		"webui_audit_admin":         "The audit log requires an admin token. Log in with an admin token to browse it.",
+		"webui_audit_error":         "Error loading the audit log. Check the filters and try again.",
+		"webui_audit_all_actions":   "All actions",
+		"webui_audit_from":          "From",
+		"webui_audit_to":            "To",
+		"webui_audit_filter":        "Filter",
+		"webui_audit_entries":       "{count} entries",
+		"webui_audit_export":        "Export CSV",
+		"webui_audit_none":          "No entries found",
+		"webui_audit_newer":         "← Newer",
+		"webui_audit_older":         "Older →",
+		"webui_audit_page":          "Page {page}",
+		"webui_nav_console":         "Redeem Console",
+		"webui_console_email":       "Guest email",
+		"webui_console_check":       "Check",
+		"webui_console_confirm":     "Redeem a cocktail for {email}?",
+		"webui_console_queued":      "No connection. The redemption of {email} is saved and will be sent automatically.",
+		"webui_console_pending":     "Waiting to send: {count}",
+		"webui_console_sent":        "Saved redemption of {email} sent: {message}",
+		"webui_console_offline":     "No connection. Check the network and try again.",
+		"webui_console_login":       "Your session has expired. Log in again to send saved redemptions.",
+		"webui_nav_jobs":            "Jobs",
+		"webui_jobs_none":           "No jobs yet",
+		"webui_jobs_error":          "Error loading jobs. Try again later.",
+		"webui_jobs_cancel":         "Cancel",
+		"webui_jobs_canceled":       "Job {id} canceled",
+		"webui_jobs_not_canceled":   "Job {id} could not be canceled, it may have finished already",
+		"webui_column_type":         "Type",
+		"webui_column_status":       "Status",
+		"webui_column_progress":     "Progress",
+		"webui_column_started":      "Started",
+		"webui_nav_import":          "Import",
+		"webui_import_file":         "CSV file",
+		"webui_import_preview":      "Preview",
+		"webui_import_rows":         "First rows of {file}",
+		"webui_import_email":        "Email column",
+		"webui_import_name":         "Name column",
+		"webui_import_name_hint":    "Full names are split into first and last name",
+		"webui_import_tags":         "Tags column",
+		"webui_import_tags_hint":    "Tags are separated by commas",
+		"webui_import_none":         "None",
+		"webui_import_column":       "Column {n}",
+		"webui_import_header":       "The first row is a header",
+		"webui_import_existing":     "Guests already on the list",
+		"webui_import_skip":         "Leave them as they are",
+		"webui_import_update":       "Add the tags of the file, and the name if they have none",
+		"webui_import_start":        "Start import",
+		"webui_import_bad_file":     "The file could not be read as CSV.",
+		"webui_import_expired":      "The upload has expired. Upload the file again.",
+		"webui_import_failed":       "The import could not be started: {error}",
+		"webui_jobs_started":        "Import {id} started",
+		"webui_search":              "Search guests",
+		"webui_search_placeholder":  "Email, name, notes or tags",
+		"webui_search_results":      "Guests found: {count}",
+		"webui_search_none":         "No guests match every word",
+		"webui_search_invalid":      "Enter 2 to 100 characters and at most 5 words.",
+		"webui_search_error":        "Error searching guests. Try again later.",
+		"webui_search_previous":     "← Previous",
+		"webui_search_next":         "Next →",
+		"webui_column_tags":         "Tags",
+		"webui_column_notes":        "Notes",
+	},
+	"es": {
+		"webui_language_name":       "Español",
+		"webui_language":            "Idioma",
+		"webui_nav_dashboard":       "Panel",
+		"webui_nav_users":           "Todos los usuarios",
+		"webui_nav_redeemed":        "Cócteles canjeados",
+		"webui_nav_audit":           "Registro de auditoría",
+		"webui_welcome":             "Bienvenido, {user}",
+		"webui_logout":              "Cerrar sesión",
+		"webui_footer":              "Panel de administración de Cocktail Bot",
+		"webui_database":            "Base de datos",
+		"webui_database_unknown":    "desconocida",
+		"webui_database_users":      "{count} usuarios",
+		"webui_database_last_write": "última escritura {time}",
+		"webui_total_users":         "Usuarios totales",
+		"webui_total_users_text":    "Usuarios registrados en total",
+		"webui_redeemed_text":       "Usuarios que canjearon su cóctel",
+		"webui_last_month":          "Último mes",
+		"webui_last_month_text":     "Usuarios nuevos en los últimos 30 días",
+		"webui_last_week":           "Última semana",
+		"webui_last_week_text":      "Usuarios nuevos en los últimos 7 días",
+		"webui_tonight":             "Esta noche",
+		"webui_tonight_text":        "Cócteles canjeados desde medianoche",
+		"webui_this_week":           "Esta semana",
+		"webui_this_week_text":      "Cócteles canjeados desde el inicio de la semana",
+		"webui_vs_yesterday":        "{change} frente a ayer a esta hora ({previous})",
+		"webui_vs_last_week":        "{change} frente a la semana pasada a esta hora ({previous})",
+		"webui_vs_before":           "{change} frente al mismo periodo anterior ({previous})",
+		"webui_language_split":      "Reparto por idioma",
+		"webui_no_interactions":     "Aún no hay interacciones",
+		"webui_busiest_hours":       "Horas punta",
+		"webui_busiest_hour":        "Hora con más actividad: {hour}:00",
+		"webui_chart_messages":      "Mensajes",
+		"webui_conversion":          "Conversión consulta → canje",
+		"webui_conversion_text":     "{redemptions} canjes de {checks} consultas de email desde el arranque",
+		"webui_latency":             "Tiempo hasta el canje",
+		"webui_latency_text":        "Mediana {p50}, el 90% en {p90}, sobre {count} canjes",
+		"webui_chart_guests":        "Invitados",
+		"webui_quick_actions":       "Acciones rápidas",
+		"webui_view_users":          "Ver todos los usuarios",
+		"webui_view_redeemed":       "Ver cócteles canjeados",
+		"webui_users_total":         "Total: {count} usuarios",
+		"webui_show_emails":         "Mostrar emails completos",
+		"webui_hide_emails":         "Ocultar emails completos",
+		"webui_column_id":           "ID",
+		"webui_column_email":        "Email",
+		"webui_column_name":         "Nombre",
+		"webui_column_added":        "Fecha de alta",
+		"webui_column_redeemed":     "Canjeado",
+		"webui_column_updated":      "Última actualización",
+		"webui_column_added_by":     "Añadido por",
+		"webui_column_time":         "Hora",
+		"webui_column_actor":        "Autor",
+		"webui_column_action":       "Acción",
+		"webui_column_details":      "Detalles",
+		"webui_not_redeemed":        "No",
+		"webui_login":               "Iniciar sesión",
+		"webui_login_title":         "Acceso a {name}",
+		"webui_login_token":         "Token de autenticación",
+		"webui_login_placeholder":   "Introduce tu token de API",
+		"webui_login_help":          "Usa el mismo token configurado para el acceso a la API",
+		"webui_login_footer":        "Introduce tu token de autenticación para acceder al panel",
+		"webui_login_invalid":       "Token de autenticación no válido",
+		"webui_audit_admin":         "El registro de auditoría requiere un token de administrador. Inicia sesión con un token de administrador para consultarlo.",
+		"webui_audit_error":         "Error al cargar el registro de auditoría. Revisa los filtros e inténtalo de nuevo.",
+		"webui_audit_all_actions":   "Todas las acciones",
+		"webui_audit_from":          "Desde",
+		"webui_audit_to":            "Hasta",
+		"webui_audit_filter":        "Filtrar",
+		"webui_audit_entries":       "{count} entradas",
+		"webui_audit_export":        "Exportar CSV",
+		"webui_audit_none":          "No se encontraron entradas",
+		"webui_audit_newer":         "← Más recientes",
+		"webui_audit_older":         "Más antiguas →",
+		"webui_audit_page":          "Página {page}",
+		"webui_nav_console":         "Consola de canje",
+		"webui_console_email":       "Email del invitado",
+		"webui_console_check":       "Comprobar",
+		"webui_console_confirm":     "¿Canjear un cóctel para {email}?",
+		"webui_console_queued":      "Sin conexión. El canje de {email} está guardado y se enviará automáticamente.",
+		"webui_console_pending":     "Pendientes de enviar: {count}",
+		"webui_console_sent":        "Canje guardado de {email} enviado: {message}",
+		"webui_console_offline":     "Sin conexión. Revisa la red e inténtalo de nuevo.",
+		"webui_console_login":       "Tu sesión ha caducado. Inicia sesión de nuevo para enviar los canjes guardados.",
+		"webui_nav_jobs":            "Tareas",
+		"webui_jobs_none":           "Todavía no hay tareas",
+		"webui_jobs_error":          "Error al cargar las tareas. Inténtalo de nuevo más tarde.",
+		"webui_jobs_cancel":         "Cancelar",
+		"webui_jobs_canceled":       "Tarea {id} cancelada",
+		"webui_jobs_not_canceled":   "No se pudo cancelar la tarea {id}, puede que ya haya terminado",
+		"webui_column_type":         "Tipo",
+		"webui_column_status":       "Estado",
+		"webui_column_progress":     "Progreso",
+		"webui_column_started":      "Inicio",
+		"webui_nav_import":          "Importar",
+		"webui_import_file":         "Archivo CSV",
+		"webui_import_preview":      "Vista previa",
+		"webui_import_rows":         "Primeras filas de {file}",
+		"webui_import_email":        "Columna de email",
+		"webui_import_name":         "Columna de nombre",
+		"webui_import_name_hint":    "Los nombres completos se dividen en nombre y apellido",
+		"webui_import_tags":         "Columna de etiquetas",
+		"webui_import_tags_hint":    "Las etiquetas se separan con comas",
+		"webui_import_none":         "Ninguna",
+		"webui_import_column":       "Columna {n}",
+		"webui_import_header":       "La primera fila es un encabezado",
+		"webui_import_existing":     "Invitados que ya están en la lista",
+		"webui_import_skip":         "Dejarlos como están",
+		"webui_import_update":       "Añadir las etiquetas del archivo, y el nombre si no lo tienen",
+		"webui_import_start":        "Iniciar importación",
+		"webui_import_bad_file":     "No se pudo leer el archivo como CSV.",
+		"webui_import_expired":      "La subida ha caducado. Sube el archivo de nuevo.",
+		"webui_import_failed":       "No se pudo iniciar la importación: {error}",
+		"webui_jobs_started":        "Importación {id} iniciada",
+		"webui_search":              "Buscar invitados",
+		"webui_search_placeholder":  "Correo, nombre, notas o etiquetas",
+		"webui_search_results":      "Invitados encontrados: {count}",
+		"webui_search_none":         "Ningún invitado coincide con todas las palabras",
+		"webui_search_invalid":      "Introduce de 2 a 100 caracteres y como máximo 5 palabras.",
+		"webui_search_error":        "Error al buscar invitados. Inténtalo más tarde.",
+		"webui_search_previous":     "← Anterior",
+		"webui_search_next":         "Siguiente →",
+		"webui_column_tags":         "Etiquetas",
+		"webui_column_notes":        "Notas",
+	},
+	"fr": {
+		"webui_language_name":       "Français",
+		"webui_language":            "Langue",
+		"webui_nav_dashboard":       "Tableau de bord",
+		"webui_nav_users":           "Tous les utilisateurs",
+		"webui_nav_redeemed":        "Cocktails servis",
+		"webui_nav_audit":           "Journal d'audit",
+		"webui_welcome":             "Bienvenue, {user}",
+		"webui_logout":              "Déconnexion",
+		"webui_footer":              "Interface d'administration de Cocktail Bot",
+		"webui_database":            "Base de données",
+		"webui_database_unknown":    "inconnue",
+		"webui_database_users":      "{count} utilisateurs",
+		"webui_database_last_write": "dernière écriture {time}",
+		"webui_total_users":         "Utilisateurs",
+		"webui_total_users_text":    "Utilisateurs inscrits au total",
+		"webui_redeemed_text":       "Utilisateurs ayant obtenu leur cocktail",
+		"webui_last_month":          "Dernier mois",
+		"webui_last_month_text":     "Nouveaux utilisateurs sur les 30 derniers jours",
+		"webui_last_week":           "Dernière semaine",
+		"webui_last_week_text":      "Nouveaux utilisateurs sur les 7 derniers jours",
+		"webui_tonight":             "Ce soir",
+		"webui_tonight_text":        "Cocktails servis depuis minuit",
+		"webui_this_week":           "Cette semaine",
+		"webui_this_week_text":      "Cocktails servis depuis le début de la semaine",
+		"webui_vs_yesterday":        "{change} par rapport à hier à la même heure ({previous})",
+		"webui_vs_last_week":        "{change} par rapport à la semaine dernière à la même heure ({previous})",
+		"webui_vs_before":           "{change} par rapport à la période précédente ({previous})",
+		"webui_language_split":      "Répartition par langue",
+		"webui_no_interactions":     "Aucune interaction pour l'instant",
+		"webui_busiest_hours":       "Heures de pointe",
+		"webui_busiest_hour":        "Heure la plus active : {hour}:00",
+		"webui_chart_messages":      "Messages",
+		"webui_conversion":          "Conversion vérification → cocktail",
+		"webui_conversion_text":     "{redemptions} cocktails pour {checks} vérifications d'email depuis le démarrage",
+		"webui_latency":             "Délai avant le cocktail",
+		"webui_latency_text":        "Médiane {p50}, 90 % en {p90}, sur {count} cocktails",
+		"webui_chart_guests":        "Invités",
+		"webui_quick_actions":       "Actions rapides",
+		"webui_view_users":          "Voir tous les utilisateurs",
+		"webui_view_redeemed":       "Voir les cocktails servis",
+		"webui_users_total":         "Total : {count} utilisateurs",
+		"webui_show_emails":         "Afficher les emails complets",
+		"webui_hide_emails":         "Masquer les emails complets",
+		"webui_column_id":           "ID",
+		"webui_column_email":        "Email",
+		"webui_column_name":         "Nom",
+		"webui_column_added":        "Date d'ajout",
+		"webui_column_redeemed":     "Servi",
+		"webui_column_updated":      "Dernière mise à jour",
+		"webui_column_added_by":     "Ajouté par",
+		"webui_column_time":         "Heure",
+		"webui_column_actor":        "Auteur",
+		"webui_column_action":       "Action",
+		"webui_column_details":      "Détails",
+		"webui_not_redeemed":        "Non",
+		"webui_login":               "Connexion",
+		"webui_login_title":         "Connexion à {name}",
+		"webui_login_token":         "Jeton d'authentification",
+		"webui_login_placeholder":   "Saisissez votre jeton d'API",
+		"webui_login_help":          "Utilisez le même jeton que pour l'accès à l'API",
+		"webui_login_footer":        "Saisissez votre jeton d'authentification pour accéder au tableau de bord",
+		"webui_login_invalid":       "Jeton d'authentification invalide",
+		"webui_audit_admin":         "Le journal d'audit nécessite un jeton administrateur. Connectez-vous avec un jeton administrateur pour le consulter.",
+		"webui_audit_error":         "Erreur lors du chargement du journal d'audit. Vérifiez les filtres et réessayez.",
+		"webui_audit_all_actions":   "Toutes les actions",
+		"webui_audit_from":          "Du",
+		"webui_audit_to":            "Au",
+		"webui_audit_filter":        "Filtrer",
+		"webui_audit_entries":       "{count} entrées",
+		"webui_audit_export":        "Exporter en CSV",
+		"webui_audit_none":          "Aucune entrée trouvée",
+		"webui_audit_newer":         "← Plus récentes",
+		"webui_audit_older":         "Plus anciennes →",
+		"webui_audit_page":          "Page {page}",
+		"webui_nav_console":         "Console de service",
+		"webui_console_email":       "Email de l'invité",
+		"webui_console_check":       "Vérifier",
+		"webui_console_confirm":     "Servir un cocktail à {email} ?",
+		"webui_console_queued":      "Pas de connexion. Le cocktail de {email} est enregistré et sera envoyé automatiquement.",
+		"webui_console_pending":     "En attente d'envoi : {count}",
+		"webui_console_sent":        "Cocktail enregistré de {email} envoyé : {message}",
+		"webui_console_offline":     "Pas de connexion. Vérifiez le réseau et réessayez.",
+		"webui_console_login":       "Votre session a expiré. Reconnectez-vous pour envoyer les cocktails enregistrés.",
+		"webui_nav_jobs":            "Tâches",
+		"webui_jobs_none":           "Aucune tâche pour le moment",
+		"webui_jobs_error":          "Erreur lors du chargement des tâches. Réessayez plus tard.",
+		"webui_jobs_cancel":         "Annuler",
+		"webui_jobs_canceled":       "Tâche {id} annulée",
+		"webui_jobs_not_canceled":   "La tâche {id} n'a pas pu être annulée, elle est peut-être déjà terminée",
+		"webui_column_type":         "Type",
+		"webui_column_status":       "Statut",
+		"webui_column_progress":     "Progression",
+		"webui_column_started":      "Début",
+		"webui_nav_import":          "Importer",
+		"webui_import_file":         "Fichier CSV",
+		"webui_import_preview":      "Aperçu",
+		"webui_import_rows":         "Premières lignes de {file}",
+		"webui_import_email":        "Colonne de l'email",
+		"webui_import_name":         "Colonne du nom",
+		"webui_import_name_hint":    "Les noms complets sont séparés en prénom et nom",
+		"webui_import_tags":         "Colonne des étiquettes",
+		"webui_import_tags_hint":    "Les étiquettes sont séparées par des virgules",
+		"webui_import_none":         "Aucune",
+		"webui_import_column":       "Colonne {n}",
+		"webui_import_header":       "La première ligne est un en-tête",
+		"webui_import_existing":     "Invités déjà sur la liste",
+		"webui_import_skip":         "Les laisser tels quels",
+		"webui_import_update":       "Ajouter les étiquettes du fichier, et le nom s'ils n'en ont pas",
+		"webui_import_start":        "Lancer l'import",
+		"webui_import_bad_file":     "Le fichier n'a pas pu être lu comme CSV.",
+		"webui_import_expired":      "Le fichier envoyé a expiré. Envoyez-le à nouveau.",
+		"webui_import_failed":       "L'import n'a pas pu être lancé : {error}",
+		"webui_jobs_started":        "Import {id} lancé",
+		"webui_search":              "Rechercher des invités",
+		"webui_search_placeholder":  "E-mail, nom, notes ou étiquettes",
+		"webui_search_results":      "Invités trouvés : {count}",
+		"webui_search_none":         "Aucun invité ne correspond à tous les mots",
+		"webui_search_invalid":      "Saisissez de 2 à 100 caractères et 5 mots au plus.",
+		"webui_search_error":        "Erreur lors de la recherche des invités. Réessayez plus tard.",
+		"webui_search_previous":     "← Précédent",
+		"webui_search_next":         "Suivant →",
+		"webui_column_tags":         "Étiquettes",
+		"webui_column_notes":        "Notes",
+	},
+	"de": {
+		"webui_language_name":       "Deutsch",
+		"webui_language":            "Sprache",
+		"webui_nav_dashboard":       "Übersicht",
+		"webui_nav_users":           "Alle Nutzer",
+		"webui_nav_redeemed":        "Eingelöste Cocktails",
+		"webui_nav_audit":           "Audit-Log",
+		"webui_welcome":             "Willkommen, {user}",
+		"webui_logout":              "Abmelden",
+		"webui_footer":              "Cocktail Bot Verwaltung",
+		"webui_database":            "Datenbank",
+		"webui_database_unknown":    "unbekannt",
+		"webui_database_users":      "{count} Nutzer",
+		"webui_database_last_write": "zuletzt geschrieben {time}",
+		"webui_total_users":         "Nutzer gesamt",
+		"webui_total_users_text":    "Registrierte Nutzer insgesamt",
+		"webui_redeemed_text":       "Nutzer, die ihren Cocktail eingelöst haben",
+		"webui_last_month":          "Letzter Monat",
+		"webui_last_month_text":     "Neue Nutzer in den letzten 30 Tagen",
+		"webui_last_week":           "Letzte Woche",
+		"webui_last_week_text":      "Neue Nutzer in den letzten 7 Tagen",
+		"webui_tonight":             "Heute Abend",
+		"webui_tonight_text":        "Seit Mitternacht eingelöste Cocktails",
+		"webui_this_week":           "Diese Woche",
+		"webui_this_week_text":      "Seit Wochenbeginn eingelöste Cocktails",
+		"webui_vs_yesterday":        "{change} gegenüber gestern um diese Zeit ({previous})",
+		"webui_vs_last_week":        "{change} gegenüber letzter Woche um diese Zeit ({previous})",
+		"webui_vs_before":           "{change} gegenüber dem gleichen Zeitraum davor ({previous})",
+		"webui_language_split":      "Verteilung nach Sprache",
+		"webui_no_interactions":     "Noch keine Interaktionen",
+		"webui_busiest_hours":       "Stoßzeiten",
+		"webui_busiest_hour":        "Meiste Aktivität: {hour}:00 Uhr",
+		"webui_chart_messages":      "Nachrichten",
+		"webui_conversion":          "Umwandlung Prüfung → Einlösung",
+		"webui_conversion_text":     "{redemptions} Einlösungen aus {checks} E-Mail-Prüfungen seit dem Start",
+		"webui_latency":             "Zeit bis zur Einlösung",
+		"webui_latency_text":        "Median {p50}, 90 % innerhalb von {p90}, aus {count} Einlösungen",
+		"webui_chart_guests":        "Gäste",
+		"webui_quick_actions":       "Schnellzugriff",
+		"webui_view_users":          "Alle Nutzer anzeigen",
+		"webui_view_redeemed":       "Eingelöste Cocktails anzeigen",
+		"webui_users_total":         "Gesamt: {count} Nutzer",
+		"webui_show_emails":         "Vollständige E-Mails anzeigen",
+		"webui_hide_emails":         "Vollständige E-Mails ausblenden",
+		"webui_column_id":           "ID",
+		"webui_column_email":        "E-Mail",
+		"webui_column_name":         "Name",
+		"webui_column_added":        "Hinzugefügt am",
+		"webui_column_redeemed":     "Eingelöst",
+		"webui_column_updated":      "Zuletzt geändert",
+		"webui_column_added_by":     "Hinzugefügt von",
+		"webui_column_time":         "Zeit",
+		"webui_column_actor":        "Akteur",
+		"webui_column_action":       "Aktion",
+		"webui_column_details":      "Details",
+		"webui_not_redeemed":        "Nein",
+		"webui_login":               "Anmelden",
+		"webui_login_title":         "{name} Anmeldung",
+		"webui_login_token":         "Authentifizierungstoken",
+		"webui_login_placeholder":   "API-Token eingeben",
+		"webui_login_help":          "Verwenden Sie dasselbe Token wie für den API-Zugriff",
+		"webui_login_footer":        "Geben Sie Ihr Authentifizierungstoken ein, um die Übersicht zu öffnen",
+		"webui_login_invalid":       "Ungültiges Authentifizierungstoken",
+		"webui_audit_admin":         "Das Audit-Log erfordert ein Admin-Token. Melden Sie sich mit einem Admin-Token an, um es anzusehen.",
+		"webui_audit_error":         "Fehler beim Laden des Audit-Logs. Prüfen Sie die Filter und versuchen Sie es erneut.",
+		"webui_audit_all_actions":   "Alle Aktionen",
+		"webui_audit_from":          "Von",
+		"webui_audit_to":            "Bis",
+		"webui_audit_filter":        "Filtern",
+		"webui_audit_entries":       "{count} Einträge",
+		"webui_audit_export":        "Als CSV exportieren",
+		"webui_audit_none":          "Keine Einträge gefunden",
+		"webui_audit_newer":         "← Neuere",
+		"webui_audit_older":         "Ältere →",
+		"webui_audit_page":          "Seite {page}",
+		"webui_nav_console":         "Einlöse-Konsole",
+		"webui_console_email":       "E-Mail des Gastes",
+		"webui_console_check":       "Prüfen",
+		"webui_console_confirm":     "Cocktail für {email} einlösen?",
+		"webui_console_queued":      "Keine Verbindung. Die Einlösung für {email} ist gespeichert und wird automatisch gesendet.",
+		"webui_console_pending":     "Warten auf Versand: {count}",
+		"webui_console_sent":        "Gespeicherte Einlösung für {email} gesendet: {message}",
+		"webui_console_offline":     "Keine Verbindung. Prüfen Sie das Netzwerk und versuchen Sie es erneut.",
+		"webui_console_login":       "Ihre Sitzung ist abgelaufen. Melden Sie sich erneut an, um gespeicherte Einlösungen zu senden.",
+		"webui_nav_jobs":            "Aufträge",
+		"webui_jobs_none":           "Noch keine Aufträge",
+		"webui_jobs_error":          "Fehler beim Laden der Aufträge. Bitte später erneut versuchen.",
+		"webui_jobs_cancel":         "Abbrechen",
+		"webui_jobs_canceled":       "Auftrag {id} abgebrochen",
+		"webui_jobs_not_canceled":   "Auftrag {id} konnte nicht abgebrochen werden, er ist eventuell bereits beendet",
+		"webui_column_type":         "Typ",
+		"webui_column_status":       "Status",
+		"webui_column_progress":     "Fortschritt",
+		"webui_column_started":      "Gestartet",
+		"webui_nav_import":          "Import",
+		"webui_import_file":         "CSV-Datei",
+		"webui_import_preview":      "Vorschau",
+		"webui_import_rows":         "Erste Zeilen von {file}",
+		"webui_import_email":        "E-Mail-Spalte",
+		"webui_import_name":         "Namensspalte",
+		"webui_import_name_hint":    "Vollständige Namen werden in Vor- und Nachname geteilt",
+		"webui_import_tags":         "Tag-Spalte",
+		"webui_import_tags_hint":    "Tags werden durch Kommas getrennt",
+		"webui_import_none":         "Keine",
+		"webui_import_column":       "Spalte {n}",
+		"webui_import_header":       "Die erste Zeile ist eine Kopfzeile",
+		"webui_import_existing":     "Gäste, die bereits auf der Liste stehen",
+		"webui_import_skip":         "Unverändert lassen",
+		"webui_import_update":       "Tags der Datei hinzufügen, und den Namen, wenn sie keinen haben",
+		"webui_import_start":        "Import starten",
+		"webui_import_bad_file":     "Die Datei konnte nicht als CSV gelesen werden.",
+		"webui_import_expired":      "Der Upload ist abgelaufen. Bitte laden Sie die Datei erneut hoch.",
+		"webui_import_failed":       "Der Import konnte nicht gestartet werden: {error}",
+		"webui_jobs_started":        "Import {id} gestartet",
+		"webui_search":              "Gäste suchen",
+		"webui_search_placeholder":  "E-Mail, Name, Notizen oder Tags",
+		"webui_search_results":      "Gefundene Gäste: {count}",
+		"webui_search_none":         "Kein Gast passt zu allen Wörtern",
+		"webui_search_invalid":      "Geben Sie 2 bis 100 Zeichen und höchstens 5 Wörter ein.",
+		"webui_search_error":        "Fehler bei der Gästesuche. Versuchen Sie es später erneut.",
+		"webui_search_previous":     "← Zurück",
+		"webui_search_next":         "Weiter →",
+		"webui_column_tags":         "Tags",
+		"webui_column_notes":        "Notizen",
+	},
+	"ru": {
+		"webui_language_name":       "Русский",
+		"webui_language":            "Язык",
+		"webui_nav_dashboard":       "Обзор",
+		"webui_nav_users":           "Все пользователи",
+		"webui_nav_redeemed":        "Выданные коктейли",
+		"webui_nav_audit":           "Журнал аудита",
+		"webui_welcome":             "Добро пожаловать, {user}",
+		"webui_logout":              "Выйти",
+		"webui_footer":              "Панель управления Cocktail Bot",
+		"webui_database":            "База данных",
+		"webui_database_unknown":    "неизвестно",
+		"webui_database_users":      "пользователей: {count}",
+		"webui_database_last_write": "последняя запись {time}",
+		"webui_total_users":         "Всего пользователей",
+		"webui_total_users_text":    "Всего зарегистрировано пользователей",
+		"webui_redeemed_text":       "Пользователи, получившие коктейль",
+		"webui_last_month":          "Последний месяц",
+		"webui_last_month_text":     "Новые пользователи за 30 дней",
+		"webui_last_week":           "Последняя неделя",
+		"webui_last_week_text":      "Новые пользователи за 7 дней",
+		"webui_tonight":             "Сегодня вечером",
+		"webui_tonight_text":        "Коктейли, выданные с полуночи",
+		"webui_this_week":           "Эта неделя",
+		"webui_this_week_text":      "Коктейли, выданные с начала недели",
+		"webui_vs_yesterday":        "{change} к вчерашнему дню на это время ({previous})",
+		"webui_vs_last_week":        "{change} к прошлой неделе на это время ({previous})",
+		"webui_vs_before":           "{change} к такому же периоду ранее ({previous})",
+		"webui_language_split":      "Распределение по языкам",
+		"webui_no_interactions":     "Пока нет взаимодействий",
+		"webui_busiest_hours":       "Часы пик",
+		"webui_busiest_hour":        "Самый активный час: {hour}:00",
+		"webui_chart_messages":      "Сообщения",
+		"webui_conversion":          "Конверсия проверка → получение",
+		"webui_conversion_text":     "Получено коктейлей: {redemptions} из {checks} проверок email с момента запуска",
+		"webui_latency":             "Время до получения",
+		"webui_latency_text":        "Медиана {p50}, 90% за {p90}, всего получено: {count}",
+		"webui_chart_guests":        "Гости",
+		"webui_quick_actions":       "Быстрые действия",
+		"webui_view_users":          "Все пользователи",
+		"webui_view_redeemed":       "Выданные коктейли",
+		"webui_users_total":         "Всего пользователей: {count}",
+		"webui_show_emails":         "Показать email полностью",
+		"webui_hide_emails":         "Скрыть email",
+		"webui_column_id":           "ID",
+		"webui_column_email":        "Email",
+		"webui_column_name":         "Имя",
+		"webui_column_added":        "Дата добавления",
+		"webui_column_redeemed":     "Получен",
+		"webui_column_updated":      "Последнее изменение",
+		"webui_column_added_by":     "Кем добавлен",
+		"webui_column_time":         "Время",
+		"webui_column_actor":        "Кто",
+		"webui_column_action":       "Действие",
+		"webui_column_details":      "Подробности",
+		"webui_not_redeemed":        "Нет",
+		"webui_login":               "Вход",
+		"webui_login_title":         "Вход в {name}",
+		"webui_login_token":         "Токен доступа",
+		"webui_login_placeholder":   "Введите токен API",
+		"webui_login_help":          "Используйте тот же токен, что и для доступа к API",
+		"webui_login_footer":        "Введите токен доступа, чтобы открыть панель",
+		"webui_login_invalid":       "Неверный токен доступа",
+		"webui_audit_admin":         "Для журнала аудита нужен токен администратора. Войдите с токеном администратора, чтобы просмотреть его.",
+		"webui_audit_error":         "Не удалось загрузить журнал аудита. Проверьте фильтры и попробуйте снова.",
+		"webui_audit_all_actions":   "Все действия",
+		"webui_audit_from":          "С",
+		"webui_audit_to":            "По",
+		"webui_audit_filter":        "Фильтр",
+		"webui_audit_entries":       "Записей: {count}",
+		"webui_audit_export":        "Экспорт в CSV",
+		"webui_audit_none":          "Записи не найдены",
+		"webui_audit_newer":         "← Новее",
+		"webui_audit_older":         "Старее →",
+		"webui_audit_page":          "Страница {page}",
+		"webui_nav_console":         "Консоль выдачи",
+		"webui_console_email":       "Email гостя",
+		"webui_console_check":       "Проверить",
+		"webui_console_confirm":     "Выдать коктейль для {email}?",
+		"webui_console_queued":      "Нет соединения. Выдача для {email} сохранена и будет отправлена автоматически.",
+		"webui_console_pending":     "Ожидают отправки: {count}",
+		"webui_console_sent":        "Сохранённая выдача для {email} отправлена: {message}",
+		"webui_console_offline":     "Нет соединения. Проверьте сеть и попробуйте снова.",
+		"webui_console_login":       "Сессия истекла. Войдите снова, чтобы отправить сохранённые выдачи.",
+		"webui_nav_jobs":            "Задачи",
+		"webui_jobs_none":           "Задач пока нет",
+		"webui_jobs_error":          "Ошибка загрузки задач. Попробуйте позже.",
+		"webui_jobs_cancel":         "Отменить",
+		"webui_jobs_canceled":       "Задача {id} отменена",
+		"webui_jobs_not_canceled":   "Не удалось отменить задачу {id}, возможно, она уже завершена",
+		"webui_column_type":         "Тип",
+		"webui_column_status":       "Статус",
+		"webui_column_progress":     "Прогресс",
+		"webui_column_started":      "Начало",
+		"webui_nav_import":          "Импорт",
+		"webui_import_file":         "CSV-файл",
+		"webui_import_preview":      "Предпросмотр",
+		"webui_import_rows":         "Первые строки {file}",
+		"webui_import_email":        "Столбец email",
+		"webui_import_name":         "Столбец имени",
+		"webui_import_name_hint":    "Полные имена делятся на имя и фамилию",
+		"webui_import_tags":         "Столбец меток",
+		"webui_import_tags_hint":    "Метки разделяются запятыми",
+		"webui_import_none":         "Нет",
+		"webui_import_column":       "Столбец {n}",
+		"webui_import_header":       "Первая строка — заголовок",
+		"webui_import_existing":     "Гости, уже внесённые в список",
+		"webui_import_skip":         "Оставить без изменений",
+		"webui_import_update":       "Добавить метки из файла и имя, если у гостя его нет",
+		"webui_import_start":        "Начать импорт",
+		"webui_import_bad_file":     "Не удалось прочитать файл как CSV.",
+		"webui_import_expired":      "Срок загрузки истёк. Загрузите файл снова.",
+		"webui_import_failed":       "Не удалось начать импорт: {error}",
+		"webui_jobs_started":        "Импорт {id} начат",
+		"webui_search":              "Поиск гостей",
+		"webui_search_placeholder":  "Email, имя, заметки или метки",
+		"webui_search_results":      "Найдено гостей: {count}",
+		"webui_search_none":         "Нет гостей, подходящих под все слова",
+		"webui_search_invalid":      "Введите от 2 до 100 символов и не больше 5 слов.",
+		"webui_search_error":        "Не удалось выполнить поиск гостей. Попробуйте позже.",
+		"webui_search_previous":     "← Назад",
+		"webui_search_next":         "Далее →",
+		"webui_column_tags":         "Метки",
+		"webui_column_notes":        "Заметки",
+	},
+	"sr": {
+		"webui_language_name":       "Srpski",
+		"webui_language":            "Jezik",
+		"webui_nav_dashboard":       "Pregled",
+		"webui_nav_users":           "Svi korisnici",
+		"webui_nav_redeemed":        "Preuzeti kokteli",
+		"webui_nav_audit":           "Dnevnik izmena",
+		"webui_welcome":             "Dobro došli, {user}",
+		"webui_logout":              "Odjava",
+		"webui_footer":              "Administracija Cocktail Bot-a",
+		"webui_database":            "Baza podataka",
+		"webui_database_unknown":    "nepoznata",
+		"webui_database_users":      "korisnika: {count}",
+		"webui_database_last_write": "poslednji upis {time}",
+		"webui_total_users":         "Ukupno korisnika",
+		"webui_total_users_text":    "Ukupno registrovanih korisnika",
+		"webui_redeemed_text":       "Korisnici koji su preuzeli koktel",
+		"webui_last_month":          "Poslednji mesec",
+		"webui_last_month_text":     "Novi korisnici u poslednjih 30 dana",
+		"webui_last_week":           "Poslednja nedelja",
+		"webui_last_week_text":      "Novi korisnici u poslednjih 7 dana",
+		"webui_tonight":             "Večeras",
+		"webui_tonight_text":        "Koktela iskorišćeno od ponoći",
+		"webui_this_week":           "Ove nedelje",
+		"webui_this_week_text":      "Koktela iskorišćeno od početka nedelje",
+		"webui_vs_yesterday":        "{change} u odnosu na juče u ovo vreme ({previous})",
+		"webui_vs_last_week":        "{change} u odnosu na prošlu nedelju u ovo vreme ({previous})",
+		"webui_vs_before":           "{change} u odnosu na isti period ranije ({previous})",
+		"webui_language_split":      "Podela po jezicima",
+		"webui_no_interactions":     "Još nema interakcija",
+		"webui_busiest_hours":       "Najprometniji sati",
+		"webui_busiest_hour":        "Najprometniji sat: {hour}:00",
+		"webui_chart_messages":      "Poruke",
+		"webui_conversion":          "Konverzija provera → preuzimanje",
+		"webui_conversion_text":     "{redemptions} preuzimanja od {checks} provera e-mail adresa od pokretanja",
+		"webui_latency":             "Vreme do preuzimanja",
+		"webui_latency_text":        "Medijana {p50}, 90% za {p90}, od {count} preuzimanja",
+		"webui_chart_guests":        "Gosti",
+		"webui_quick_actions":       "Brze radnje",
+		"webui_view_users":          "Prikaži sve korisnike",
+		"webui_view_redeemed":       "Prikaži preuzete koktele",
+		"webui_users_total":         "Ukupno korisnika: {count}",
+		"webui_show_emails":         "Prikaži cele e-mail adrese",
+		"webui_hide_emails":         "Sakrij cele e-mail adrese",
+		"webui_column_id":           "ID",
+		"webui_column_email":        "E-mail",
+		"webui_column_name":         "Ime",
+		"webui_column_added":        "Datum dodavanja",
+		"webui_column_redeemed":     "Preuzeto",
+		"webui_column_updated":      "Poslednja izmena",
+		"webui_column_added_by":     "Dodao",
+		"webui_column_time":         "Vreme",
+		"webui_column_actor":        "Izvršilac",
+		"webui_column_action":       "Radnja",
+		"webui_column_details":      "Detalji",
+		"webui_not_redeemed":        "Ne",
+		"webui_login":               "Prijava",
+		"webui_login_title":         "Prijava na {name}",
+		"webui_login_token":         "Token za pristup",
+		"webui_login_placeholder":   "Unesite API token",
+		"webui_login_help":          "Koristite isti token koji je podešen za pristup API-ju",
+		"webui_login_footer":        "Unesite token za pristup da biste otvorili pregled",
+		"webui_login_invalid":       "Neispravan token za pristup",
+		"webui_audit_admin":         "Za dnevnik izmena potreban je administratorski token. Prijavite se administratorskim tokenom da biste ga pregledali.",
+		"webui_audit_error":         "Greška pri učitavanju dnevnika izmena. Proverite filtere i pokušajte ponovo.",
+		"webui_audit_all_actions":   "Sve radnje",
+		"webui_audit_from":          "Od",
+		"webui_audit_to":            "Do",
+		"webui_audit_filter":        "Filtriraj",
+		"webui_audit_entries":       "Unosa: {count}",
+		"webui_audit_export":        "Izvezi CSV",
+		"webui_audit_none":          "Nema pronađenih unosa",
+		"webui_audit_newer":         "← Noviji",
+		"webui_audit_older":         "Stariji →",
+		"webui_audit_page":          "Strana {page}",
+		"webui_nav_console":         "Konzola za preuzimanje",
+		"webui_console_email":       "E-mail gosta",
+		"webui_console_check":       "Proveri",
+		"webui_console_confirm":     "Preuzeti koktel za {email}?",
+		"webui_console_queued":      "Nema veze. Preuzimanje za {email} je sačuvano i biće poslato automatski.",
+		"webui_console_pending":     "Čeka na slanje: {count}",
+		"webui_console_sent":        "Sačuvano preuzimanje za {email} poslato: {message}",
+		"webui_console_offline":     "Nema veze. Proverite mrežu i pokušajte ponovo.",
+		"webui_console_login":       "Sesija je istekla. Prijavite se ponovo da biste poslali sačuvana preuzimanja.",
+		"webui_nav_jobs":            "Zadaci",
+		"webui_jobs_none":           "Još nema zadataka",
+		"webui_jobs_error":          "Greška pri učitavanju zadataka. Pokušajte ponovo kasnije.",
+		"webui_jobs_cancel":         "Otkaži",
+		"webui_jobs_canceled":       "Zadatak {id} je otkazan",
+		"webui_jobs_not_canceled":   "Zadatak {id} nije moguće otkazati, možda je već završen",
+		"webui_column_type":         "Tip",
+		"webui_column_status":       "Status",
+		"webui_column_progress":     "Napredak",
+		"webui_column_started":      "Početak",
+		"webui_nav_import":          "Uvoz",
+		"webui_import_file":         "CSV datoteka",
+		"webui_import_preview":      "Pregled",
+		"webui_import_rows":         "Prvi redovi datoteke {file}",
+		"webui_import_email":        "Kolona email adrese",
+		"webui_import_name":         "Kolona imena",
+		"webui_import_name_hint":    "Puna imena se dele na ime i prezime",
+		"webui_import_tags":         "Kolona oznaka",
+		"webui_import_tags_hint":    "Oznake se razdvajaju zarezima",
+		"webui_import_none":         "Nijedna",
+		"webui_import_column":       "Kolona {n}",
+		"webui_import_header":       "Prvi red je zaglavlje",
+		"webui_import_existing":     "Gosti koji su već na listi",
+		"webui_import_skip":         "Ostaviti ih kakvi jesu",
+		"webui_import_update":       "Dodati oznake iz datoteke, i ime ako ga nemaju",
+		"webui_import_start":        "Pokreni uvoz",
+		"webui_import_bad_file":     "Datoteku nije moguće pročitati kao CSV.",
+		"webui_import_expired":      "Otpremanje je isteklo. Otpremite datoteku ponovo.",
+		"webui_import_failed":       "Uvoz nije moguće pokrenuti: {error}",
+		"webui_jobs_started":        "Uvoz {id} je pokrenut",
+		"webui_search":              "Pretraga gostiju",
+		"webui_search_placeholder":  "Email, ime, beleške ili oznake",
+		"webui_search_results":      "Pronađeno gostiju: {count}",
+		"webui_search_none":         "Nijedan gost ne odgovara svim rečima",
+		"webui_search_invalid":      "Unesite od 2 do 100 znakova i najviše 5 reči.",
+		"webui_search_error":        "Greška pri pretrazi gostiju. Pokušajte ponovo kasnije.",
+		"webui_search_previous":     "← Prethodna",
+		"webui_search_next":         "Sledeća →",
+		"webui_column_tags":         "Oznake",
+		"webui_column_notes":        "Beleške",
+	},
+}
+
+// loadWebUITranslations loads the texts of the WebUI pages
+func loadWebUITranslations(translator *Translator) {
+	for lang, messages := range webUITranslations {
+		translator.LoadTranslations(lang, messages)
+	}
+}
@@ -0,0 +1,23 @@
+package i18n
+
+import "testing"
+
+func TestWebUITranslations(t *testing.T) {
+	// Every language translates every WebUI text
+	for lang, messages := range webUITranslations {
+		for key := range webUITranslations["en"] {
+			if messages[key] == "" {
+				t.Errorf("%s: missing WebUI text %s", lang, key)
+			}
+		}
+		if len(messages) != len(webUITranslations["en"]) {
+			t.Errorf("%s: expected %d WebUI texts, got %d", lang, len(webUITranslations["en"]), len(messages))
+		}
+	}
+
+	translator := New("en")
+	LoadDefaultTranslations(translator)
+	if got := translator.T("de", "webui_users_total", "count", "3"); got != "Gesamt: 3 Nutzer" {
+		t.Errorf("Expected the German total, got %q", got)
+	}
+}
@@ -0,0 +1,244 @@
+// Package importer adds guests from CSV files to the repository. Rows are
+// streamed one at a time, so only the dedupe index grows with the size of
+// the file, and it can be kept on disk for million-row lists.
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// UserStore is where imported guests are added
+type UserStore interface {
+	FindUser(ctx context.Context, email string) (*domain.User, error)
+	AddUser(ctx context.Context, user *domain.User) error
+}
+
+// UserUpdater is implemented by stores that can update guests already
+// on the list, as ExistingUpdate requires
+type UserUpdater interface {
+	UpdateUser(ctx context.Context, user *domain.User) error
+}
+
+// What happens to emails of the file that are already on the list
+const (
+	ExistingSkip   = "skip"   // Counted as duplicates and left as they are
+	ExistingUpdate = "update" // The tags of the file are added, and the name unless the guest has one
+)
+
+// ValidateExisting checks that a mode for existing guests is known
+func ValidateExisting(mode string) error {
+	switch mode {
+	case ExistingSkip, ExistingUpdate, "":
+		return nil
+	default:
+		return fmt.Errorf("unknown mode for existing guests %q, use %s or %s", mode, ExistingSkip, ExistingUpdate)
+	}
+}
+
+// Options configure an import
+type Options struct {
+	Column        int          // Column number holding emails, 1-based
+	NameColumn    int          // Column number holding full names, split into first and last name, 0 for none
+	TagsColumn    int          // Column number holding tags separated by commas, 0 for none
+	Header        bool         // The first row is a header
+	Dedupe        string       // DedupeMemory, DedupeDisk or DedupeNone
+	Existing      string       // ExistingSkip or ExistingUpdate, ExistingSkip if empty
+	IndexDir      string       // Directory of the disk index, the system temporary directory if empty
+	CreatedBy     string       // Recorded as the creator of imported users
+	ProgressEvery int          // Rows between calls of Progress, 0 disables progress reporting
+	Progress      func(Result) // Receives the counts so far
+	OnInvalid     func(row int, email string)
+}
+
+// Validate checks the columns and modes of the options
+func (o Options) Validate() error {
+	if o.Column < 1 {
+		return errors.New("column must be 1 or greater")
+	}
+	if o.NameColumn < 0 || o.TagsColumn < 0 {
+		return errors.New("name and tags columns must be 1 or greater, or 0 for none")
+	}
+	if err := ValidateDedupe(o.Dedupe); err != nil {
+		return err
+	}
+	return ValidateExisting(o.Existing)
+}
+
+// Result summarizes an import run
+type Result struct {
+	Rows      int `json:"rows"` // Rows read, including the header
+	Imported  int `json:"imported"`
+	Updated   int `json:"updated"`   // Guests already on the list that got the tags or name of their row
+	Duplicate int `json:"duplicate"` // Emails already in the database or seen earlier in the file
+	Invalid   int `json:"invalid"`   // Rows without a valid or allowed email, or with invalid tags
+}
+
+// RowError reports the row an import stopped at
+type RowError struct {
+	Row    int
+	Lookup bool // The email could not be looked up, the store may be unreachable
+	Err    error
+}
+
+func (e *RowError) Error() string {
+	if e.Lookup {
+		return fmt.Sprintf("error looking up row %d: %v", e.Row, e.Err)
+	}
+	return fmt.Sprintf("error at row %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Run imports the emails of a CSV stream into the store. On error the
+// result holds the counts up to the failing row. The import stops once
+// ctx is canceled.
+func Run(ctx context.Context, input io.Reader, store UserStore, opts Options) (Result, error) {
+	var result Result
+	if err := opts.Validate(); err != nil {
+		return result, err
+	}
+	if _, ok := store.(UserUpdater); opts.Existing == ExistingUpdate && !ok {
+		return result, errors.New("the store cannot update existing guests")
+	}
+
+	index, err := NewIndex(opts.Dedupe, opts.IndexDir)
+	if err != nil {
+		return result, err
+	}
+	defer index.Close()
+
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	for row := 1; ; row++ {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, &RowError{Row: row, Err: err}
+		}
+		result.Rows = row
+
+		if row > 1 || !opts.Header {
+			if err := importRow(ctx, store, index, opts, row, record, &result); err != nil {
+				return result, err
+			}
+		}
+
+		if opts.ProgressEvery > 0 && opts.Progress != nil && row%opts.ProgressEvery == 0 {
+			opts.Progress(result)
+		}
+	}
+
+	return result, nil
+}
+
+// importRow adds the email of one row, counting the outcome
+func importRow(ctx context.Context, store UserStore, index Index, opts Options, row int, record []string, result *Result) error {
+	email := utils.NormalizeEmail(cell(record, opts.Column))
+	if email == "" {
+		return nil
+	}
+	tags, err := domain.NormalizeTags(domain.SplitTags(cell(record, opts.TagsColumn)))
+	if !utils.IsValidEmail(email) || err != nil {
+		if opts.OnInvalid != nil {
+			opts.OnInvalid(row, email)
+		}
+		result.Invalid++
+		return nil
+	}
+	first, last := domain.SplitName(cell(record, opts.NameColumn))
+
+	seen, err := index.Add(email)
+	if err != nil {
+		return &RowError{Row: row, Err: err}
+	}
+	if seen {
+		result.Duplicate++
+		return nil
+	}
+
+	if existing, err := store.FindUser(ctx, email); err == nil {
+		if opts.Existing != ExistingUpdate || !updateExisting(existing, first, last, tags) {
+			result.Duplicate++
+			return nil
+		}
+		if err := store.(UserUpdater).UpdateUser(ctx, existing); err != nil {
+			return &RowError{Row: row, Err: err}
+		}
+		result.Updated++
+		return nil
+	} else if !errors.Is(err, domain.ErrUserNotFound) {
+		return &RowError{Row: row, Lookup: true, Err: err}
+	}
+
+	user := &domain.User{
+		ID:        "import_" + strconv.FormatInt(time.Now().UnixNano(), 10),
+		Email:     email,
+		DateAdded: time.Now(),
+		CreatedBy: opts.CreatedBy,
+		Tags:      tags,
+		FirstName: first,
+		LastName:  last,
+	}
+	if err := store.AddUser(ctx, user); err != nil {
+		if errors.Is(err, domain.ErrEmailDenied) {
+			result.Invalid++
+			return nil
+		}
+		if domain.IsDuplicateUser(err) {
+			result.Duplicate++
+			return nil
+		}
+		return &RowError{Row: row, Err: err}
+	}
+	result.Imported++
+	return nil
+}
+
+// cell returns the value of a 1-based column, empty if the row is shorter
+// or the column is 0
+func cell(record []string, column int) string {
+	if column < 1 || column > len(record) {
+		return ""
+	}
+	return record[column-1]
+}
+
+// updateExisting adds the tags of a row to a guest already on the list, and
+// the name if the guest has none. It reports whether anything changed.
+// Merged records are left alone.
+func updateExisting(user *domain.User, first, last string, tags []string) bool {
+	if user.IsMerged() {
+		return false
+	}
+	changed := false
+	for _, tag := range tags {
+		if !slices.Contains(user.Tags, tag) && len(user.Tags) < domain.MaxTags {
+			user.Tags = append(user.Tags, tag)
+			changed = true
+		}
+	}
+	if first != "" && user.FullName() == "" {
+		user.FirstName, user.LastName = first, last
+		changed = true
+	}
+	return changed
+}
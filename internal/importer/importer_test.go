@@ -0,0 +1,186 @@
+package importer_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/importer"
+)
+
+// memoryStore keeps users in a map and can simulate an outage
+type memoryStore struct {
+	users   map[string]*domain.User
+	lookups int
+	down    bool
+}
+
+func (s *memoryStore) FindUser(ctx context.Context, email string) (*domain.User, error) {
+	s.lookups++
+	if s.down {
+		return nil, domain.ErrDatabaseUnavailable
+	}
+	user, ok := s.users[email]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *memoryStore) AddUser(ctx context.Context, user *domain.User) error {
+	if s.down {
+		return domain.ErrDatabaseUnavailable
+	}
+	if existing, ok := s.users[user.Email]; ok {
+		return domain.NewDuplicateUserError(user.Email, existing.ID)
+	}
+	s.users[user.Email] = user
+	return nil
+}
+
+func (s *memoryStore) UpdateUser(ctx context.Context, user *domain.User) error {
+	s.users[user.Email] = user
+	return nil
+}
+
+const guestList = `name,email
+One,Guest1@Example.com
+Two,not an email
+Three,existing@example.com
+Four,guest1@example.com
+Five,guest2@example.com
+`
+
+func TestRun(t *testing.T) {
+	for _, mode := range []string{importer.DedupeMemory, importer.DedupeDisk, importer.DedupeNone} {
+		t.Run(mode, func(t *testing.T) {
+			store := &memoryStore{users: map[string]*domain.User{
+				"existing@example.com": {ID: "1", Email: "existing@example.com"},
+			}}
+
+			var invalid []int
+			result, err := importer.Run(context.Background(), strings.NewReader(guestList), store, importer.Options{
+				Column:    2,
+				Header:    true,
+				Dedupe:    mode,
+				IndexDir:  t.TempDir(),
+				CreatedBy: "test",
+				OnInvalid: func(row int, email string) { invalid = append(invalid, row) },
+			})
+			if err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+
+			want := importer.Result{Rows: 6, Imported: 2, Duplicate: 2, Invalid: 1}
+			if result != want {
+				t.Errorf("Expected %+v, got %+v", want, result)
+			}
+			if len(invalid) != 1 || invalid[0] != 3 {
+				t.Errorf("Expected row 3 to be reported invalid, got %v", invalid)
+			}
+			if user := store.users["guest1@example.com"]; user == nil || user.CreatedBy != "test" {
+				t.Errorf("Expected the imported user with its creator, got %+v", user)
+			}
+
+			// Repeats in the file are caught by the index without a lookup
+			wantLookups := 3
+			if mode == importer.DedupeNone {
+				wantLookups = 4
+			}
+			if store.lookups != wantLookups {
+				t.Errorf("Expected %d lookups, got %d", wantLookups, store.lookups)
+			}
+		})
+	}
+}
+
+func TestRun_Progress(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 25; i++ {
+		input.WriteString("guest")
+		input.WriteByte(byte('a' + i))
+		input.WriteString("@example.com\n")
+	}
+
+	store := &memoryStore{users: map[string]*domain.User{}}
+	var reports []importer.Result
+	result, err := importer.Run(context.Background(), strings.NewReader(input.String()), store, importer.Options{
+		Column:        1,
+		ProgressEvery: 10,
+		Progress:      func(r importer.Result) { reports = append(reports, r) },
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Imported != 25 {
+		t.Errorf("Expected 25 imported, got %d", result.Imported)
+	}
+	if len(reports) != 2 || reports[0].Rows != 10 || reports[1].Imported != 20 {
+		t.Errorf("Expected progress after rows 10 and 20, got %+v", reports)
+	}
+}
+
+func TestRun_StoreDown(t *testing.T) {
+	store := &memoryStore{users: map[string]*domain.User{}, down: true}
+	_, err := importer.Run(context.Background(), strings.NewReader("guest@example.com\n"), store, importer.Options{Column: 1})
+
+	var rowErr *importer.RowError
+	if !errors.As(err, &rowErr) || !rowErr.Lookup || rowErr.Row != 1 {
+		t.Fatalf("Expected a lookup error at row 1, got %v", err)
+	}
+	if !errors.Is(err, domain.ErrDatabaseUnavailable) {
+		t.Errorf("Expected the store error to be wrapped, got %v", err)
+	}
+}
+
+func TestRun_Mapping(t *testing.T) {
+	const list = `Tags;E-mail;Name
+"vip, press";anna@example.com;Anna Karenina
+vip;existing@example.com;Existing Guest
+not a tag!;bad@example.com;Bad Tags
+`
+	for _, existing := range []string{importer.ExistingSkip, importer.ExistingUpdate} {
+		t.Run(existing, func(t *testing.T) {
+			store := &memoryStore{users: map[string]*domain.User{
+				"existing@example.com": {ID: "1", Email: "existing@example.com", Tags: []string{"staff"}},
+			}}
+
+			reader := strings.NewReader(strings.ReplaceAll(list, ";", ","))
+			result, err := importer.Run(context.Background(), reader, store, importer.Options{
+				Column:     2,
+				NameColumn: 3,
+				TagsColumn: 1,
+				Header:     true,
+				Existing:   existing,
+			})
+			if err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+			if result.Imported != 1 || result.Invalid != 1 {
+				t.Errorf("Expected 1 imported and 1 invalid, got %+v", result)
+			}
+			if user := store.users["anna@example.com"]; user == nil || user.FirstName != "Anna" || user.LastName != "Karenina" || strings.Join(user.Tags, ",") != "vip,press" {
+				t.Errorf("Expected the name and tags of the row, got %+v", user)
+			}
+
+			user := store.users["existing@example.com"]
+			if existing == importer.ExistingSkip {
+				if result.Duplicate != 1 || len(user.Tags) != 1 || user.FullName() != "" {
+					t.Errorf("Expected the existing guest to be left alone, got %+v, %+v", result, user)
+				}
+				return
+			}
+			if result.Updated != 1 || strings.Join(user.Tags, ",") != "staff,vip" || user.FirstName != "Existing" || user.LastName != "Guest" {
+				t.Errorf("Expected the existing guest to be updated, got %+v, %+v", result, user)
+			}
+		})
+	}
+}
+
+func TestNewIndex_UnknownMode(t *testing.T) {
+	if _, err := importer.NewIndex("btree", ""); err == nil {
+		t.Error("Expected an error for an unknown dedupe mode")
+	}
+}
@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+)
+
+// Dedupe modes selecting how emails seen earlier in a file are remembered
+const (
+	DedupeMemory = "memory" // Exact set in memory, grows with the number of distinct emails
+	DedupeDisk   = "disk"   // Exact set in a temporary SQLite file, for lists that do not fit in memory
+	DedupeNone   = "none"   // No index, repeated emails are caught by the repository lookup
+)
+
+// Index remembers the emails seen earlier in a file
+type Index interface {
+	// Add records an email and reports whether it was seen before
+	Add(email string) (bool, error)
+	Close() error
+}
+
+// ValidateDedupe checks that a dedupe mode is known
+func ValidateDedupe(mode string) error {
+	switch mode {
+	case DedupeMemory, DedupeDisk, DedupeNone, "":
+		return nil
+	default:
+		return fmt.Errorf("unknown dedupe mode %q, use %s, %s or %s", mode, DedupeMemory, DedupeDisk, DedupeNone)
+	}
+}
+
+// NewIndex creates an index of the given mode. The disk index is created
+// in dir, or in the system temporary directory if dir is empty, and removed
+// when the index is closed.
+func NewIndex(mode, dir string) (Index, error) {
+	if err := ValidateDedupe(mode); err != nil {
+		return nil, err
+	}
+	switch mode {
+	case DedupeMemory, "":
+		return &memoryIndex{seen: make(map[string]struct{})}, nil
+	case DedupeDisk:
+		return newDiskIndex(dir)
+	default:
+		return noIndex{}, nil
+	}
+}
+
+// memoryIndex keeps the seen emails in a map
+type memoryIndex struct {
+	seen map[string]struct{}
+}
+
+func (i *memoryIndex) Add(email string) (bool, error) {
+	if _, ok := i.seen[email]; ok {
+		return true, nil
+	}
+	i.seen[email] = struct{}{}
+	return false, nil
+}
+
+func (i *memoryIndex) Close() error {
+	i.seen = nil
+	return nil
+}
+
+// noIndex remembers nothing
+type noIndex struct{}
+
+func (noIndex) Add(email string) (bool, error) { return false, nil }
+func (noIndex) Close() error                   { return nil }
+
+// diskIndex keeps the seen emails in a temporary SQLite file. The file is
+// scratch space, so durability is traded for speed.
+type diskIndex struct {
+	db   *sql.DB
+	path string
+	add  *sql.Stmt
+}
+
+func newDiskIndex(dir string) (*diskIndex, error) {
+	file, err := os.CreateTemp(dir, "cocktail-import-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dedupe index: %w", err)
+	}
+	path := file.Name()
+	file.Close()
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to open dedupe index: %w", err)
+	}
+	// A single connection keeps the pragmas in effect
+	db.SetMaxOpenConns(1)
+
+	index := &diskIndex{db: db, path: path}
+	for _, stmt := range []string{
+		"PRAGMA journal_mode = OFF",
+		"PRAGMA synchronous = OFF",
+		"CREATE TABLE seen (email TEXT PRIMARY KEY) WITHOUT ROWID",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			index.Close()
+			return nil, fmt.Errorf("failed to prepare dedupe index: %w", err)
+		}
+	}
+
+	index.add, err = db.Prepare("INSERT OR IGNORE INTO seen (email) VALUES (?)")
+	if err != nil {
+		index.Close()
+		return nil, fmt.Errorf("failed to prepare dedupe index: %w", err)
+	}
+	return index, nil
+}
+
+func (i *diskIndex) Add(email string) (bool, error) {
+	result, err := i.add.Exec(email)
+	if err != nil {
+		return false, fmt.Errorf("dedupe index: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("dedupe index: %w", err)
+	}
+	return inserted == 0, nil
+}
+
+func (i *diskIndex) Close() error {
+	if i.add != nil {
+		i.add.Close()
+	}
+	err := i.db.Close()
+	if rmErr := os.Remove(i.path); rmErr != nil && err == nil {
+		err = rmErr
+	}
+	return err
+}
@@ -0,0 +1,121 @@
+package eventbrite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/httpclient"
+)
+
+// Attendee is a ticket holder of an Eventbrite event
+type Attendee struct {
+	ID        string `json:"id"`
+	Cancelled bool   `json:"cancelled"`
+	Refunded  bool   `json:"refunded"`
+	Status    string `json:"status"`
+	Profile   struct {
+		Email     string `json:"email"`
+		Name      string `json:"name"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	} `json:"profile"`
+}
+
+// attendeesPage is a single page of the attendee list
+type attendeesPage struct {
+	Pagination struct {
+		HasMoreItems bool   `json:"has_more_items"`
+		Continuation string `json:"continuation"`
+	} `json:"pagination"`
+	Attendees []Attendee `json:"attendees"`
+}
+
+// Client reads attendees from the Eventbrite API
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates an API client using a private OAuth token
+func NewClient(baseURL, token string, httpClient *http.Client) (*Client, error) {
+	if token == "" {
+		return nil, errors.New("eventbrite token cannot be empty")
+	}
+	if baseURL == "" {
+		baseURL = "https://www.eventbriteapi.com/v3"
+	}
+	if httpClient == nil {
+		httpClient = httpclient.New(httpclient.Options{Name: "eventbrite"})
+	}
+
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: httpClient,
+	}, nil
+}
+
+// Attendees returns all attendees of the event changed since the given
+// time, or all attendees if since is zero
+func (c *Client) Attendees(ctx context.Context, eventID string, since time.Time) ([]Attendee, error) {
+	var attendees []Attendee
+	continuation := ""
+
+	for {
+		query := url.Values{}
+		if continuation != "" {
+			query.Set("continuation", continuation)
+		}
+		if !since.IsZero() {
+			query.Set("changed_since", since.UTC().Format("2006-01-02T15:04:05Z"))
+		}
+
+		page, err := c.attendeesPage(ctx, eventID, query)
+		if err != nil {
+			return nil, err
+		}
+		attendees = append(attendees, page.Attendees...)
+
+		if !page.Pagination.HasMoreItems || page.Pagination.Continuation == "" {
+			return attendees, nil
+		}
+		continuation = page.Pagination.Continuation
+	}
+}
+
+// attendeesPage fetches one page of attendees
+func (c *Client) attendeesPage(ctx context.Context, eventID string, query url.Values) (*attendeesPage, error) {
+	endpoint := fmt.Sprintf("%s/events/%s/attendees/", c.baseURL, url.PathEscape(eventID))
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch attendees: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("eventbrite returned status %d", resp.StatusCode)
+	}
+
+	var page attendeesPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to parse attendees: %w", err)
+	}
+	return &page, nil
+}
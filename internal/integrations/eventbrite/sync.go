@@ -0,0 +1,204 @@
+package eventbrite
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// AttendeeSource lists the attendees of an event
+type AttendeeSource interface {
+	Attendees(ctx context.Context, eventID string, since time.Time) ([]Attendee, error)
+}
+
+// UserStore is where attendees are added, and denied once their ticket is
+// cancelled or refunded
+type UserStore interface {
+	FindUser(ctx context.Context, email string) (*domain.User, error)
+	AddUser(ctx context.Context, user *domain.User) error
+	DenyEmail(email string)
+}
+
+// Result summarizes a single sync run
+type Result struct {
+	Imported int // New guests added
+	Existing int // Attendees already in the repository
+	Skipped  int // Cancelled or refunded tickets and attendees without a valid email
+	Denied   int // Guests added by the sync whose ticket was cancelled or refunded since
+}
+
+// Syncer keeps the guest list in sync with the attendees of an Eventbrite event
+type Syncer struct {
+	cfg    config.EventbriteConfig
+	source AttendeeSource
+	store  UserStore
+	logger *logger.Logger
+
+	mu        sync.Mutex // Serializes sync runs
+	lastSync  time.Time  // Start of the last successful run, only changes since are fetched
+	stopCh    chan struct{}
+	waitGroup sync.WaitGroup
+}
+
+// NewSyncer creates a syncer for the configured event
+func NewSyncer(cfg config.EventbriteConfig, source AttendeeSource, store UserStore, logger *logger.Logger) (*Syncer, error) {
+	if cfg.EventID == "" {
+		return nil, errors.New("event ID cannot be empty")
+	}
+	if source == nil || store == nil {
+		return nil, errors.New("attendee source and user store are required")
+	}
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+
+	return &Syncer{
+		cfg:    cfg,
+		source: source,
+		store:  store,
+		logger: logger,
+		stopCh: make(chan struct{}),
+	}, nil
+}
+
+// SyncOnce adds attendees that are not yet in the repository. Cancelled
+// and refunded tickets are skipped, existing guests are left untouched.
+// A guest the sync added from a ticket that was cancelled or refunded
+// since is put on the deny list, so the drink cannot be redeemed. The deny
+// list lasts until restart, when the first run fetches every attendee and
+// denies them again.
+func (s *Syncer) SyncOnce(ctx context.Context) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result Result
+	started := time.Now()
+
+	attendees, err := s.source.Attendees(ctx, s.cfg.EventID, s.lastSync)
+	if err != nil {
+		return result, err
+	}
+
+	for _, attendee := range attendees {
+		email := utils.NormalizeEmail(attendee.Profile.Email)
+		if !utils.IsValidEmail(email) {
+			result.Skipped++
+			continue
+		}
+		if attendee.Cancelled || attendee.Refunded {
+			denied, err := s.revoke(ctx, attendee, email)
+			if err != nil {
+				return result, err
+			}
+			if denied {
+				result.Denied++
+			} else {
+				result.Skipped++
+			}
+			continue
+		}
+
+		_, err := s.store.FindUser(ctx, email)
+		if err == nil {
+			result.Existing++
+			continue
+		}
+		if !errors.Is(err, domain.ErrUserNotFound) {
+			return result, err
+		}
+
+		user := &domain.User{
+			ID:        "eventbrite_" + attendee.ID,
+			Email:     email,
+			FirstName: strings.TrimSpace(attendee.Profile.FirstName),
+			LastName:  strings.TrimSpace(attendee.Profile.LastName),
+			DateAdded: time.Now(),
+			CreatedBy: "eventbrite",
+		}
+		if user.FullName() == "" {
+			user.FirstName, user.LastName = domain.SplitName(attendee.Profile.Name)
+		}
+		if err := s.store.AddUser(ctx, user); err != nil {
+			if errors.Is(err, domain.ErrEmailDenied) {
+				result.Skipped++
+				continue
+			}
+			if domain.IsDuplicateUser(err) {
+				// Added by someone else since the lookup
+				result.Existing++
+				continue
+			}
+			return result, err
+		}
+		result.Imported++
+	}
+
+	s.lastSync = started
+	s.logger.Info("Eventbrite attendees synced", "event_id", s.cfg.EventID,
+		"imported", result.Imported, "existing", result.Existing, "skipped", result.Skipped, "denied", result.Denied)
+	return result, nil
+}
+
+// revoke denies the email of a cancelled or refunded ticket if the sync
+// added the guest from that ticket. Guests added any other way, or from
+// another ticket with the same email, keep their drink.
+func (s *Syncer) revoke(ctx context.Context, attendee Attendee, email string) (bool, error) {
+	user, err := s.store.FindUser(ctx, email)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if user.CreatedBy != "eventbrite" || user.ID != "eventbrite_"+attendee.ID {
+		return false, nil
+	}
+
+	s.store.DenyEmail(email)
+	s.logger.Warn("Eventbrite ticket cancelled or refunded, guest denied", "event_id", s.cfg.EventID,
+		"attendee_id", attendee.ID, "email", email, "redeemed", user.IsRedeemed())
+	return true, nil
+}
+
+// Start runs a sync immediately and then on the configured interval
+func (s *Syncer) Start() {
+	interval := time.Duration(s.cfg.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+
+	s.waitGroup.Add(1)
+	go func() {
+		defer s.waitGroup.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.SyncOnce(context.Background()); err != nil {
+				s.logger.Error("Eventbrite sync failed", "event_id", s.cfg.EventID, "error", err)
+			}
+
+			select {
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	s.logger.Info("Eventbrite sync started", "event_id", s.cfg.EventID, "interval", interval)
+}
+
+// Stop waits for a running sync to finish and stops the schedule
+func (s *Syncer) Stop() {
+	close(s.stopCh)
+	s.waitGroup.Wait()
+}
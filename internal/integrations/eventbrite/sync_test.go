@@ -0,0 +1,146 @@
+package eventbrite_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/integrations/eventbrite"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// memoryStore keeps users in a map
+type memoryStore struct {
+	users  map[string]*domain.User
+	denied []string
+}
+
+func (s *memoryStore) FindUser(ctx context.Context, email string) (*domain.User, error) {
+	user, ok := s.users[email]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *memoryStore) AddUser(ctx context.Context, user *domain.User) error {
+	s.users[user.Email] = user
+	return nil
+}
+
+func (s *memoryStore) DenyEmail(email string) {
+	s.denied = append(s.denied, email)
+}
+
+func TestSyncer(t *testing.T) {
+	var changedSince []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/events/42/attendees/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		changedSince = append(changedSince, r.URL.Query().Get("changed_since"))
+
+		// Two pages of attendees
+		if r.URL.Query().Get("continuation") == "" {
+			fmt.Fprint(w, `{"pagination": {"has_more_items": true, "continuation": "page2"}, "attendees": [
+				{"id": "1", "profile": {"email": "Guest1@Example.com", "name": "Anna Karenina", "first_name": "Anna", "last_name": "Karenina"}},
+				{"id": "2", "cancelled": true, "profile": {"email": "cancelled@example.com"}}
+			]}`)
+			return
+		}
+		fmt.Fprint(w, `{"pagination": {"has_more_items": false}, "attendees": [
+			{"id": "3", "profile": {"email": "existing@example.com"}},
+			{"id": "4", "refunded": true, "profile": {"email": "refunded@example.com"}}
+		]}`)
+	}))
+	defer server.Close()
+
+	client, err := eventbrite.NewClient(server.URL, "secret", server.Client())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	store := &memoryStore{users: map[string]*domain.User{
+		"existing@example.com": {ID: "1", Email: "existing@example.com"},
+	}}
+	syncer, err := eventbrite.NewSyncer(config.EventbriteConfig{EventID: "42"}, client, store, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create syncer: %v", err)
+	}
+
+	result, err := syncer.SyncOnce(context.Background())
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Imported != 1 || result.Existing != 1 || result.Skipped != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if user, ok := store.users["guest1@example.com"]; !ok || user.ID != "eventbrite_1" || user.FullName() != "Anna Karenina" {
+		t.Errorf("Expected attendee to be imported, got %+v", user)
+	}
+
+	// Later runs only ask for changes
+	if _, err := syncer.SyncOnce(context.Background()); err != nil {
+		t.Fatalf("Second sync failed: %v", err)
+	}
+	if changedSince[0] != "" || changedSince[len(changedSince)-1] == "" {
+		t.Errorf("Expected changed_since only on later runs, got %q", changedSince)
+	}
+
+	// API errors are reported
+	badClient, _ := eventbrite.NewClient(server.URL, "wrong", server.Client())
+	syncer, _ = eventbrite.NewSyncer(config.EventbriteConfig{EventID: "42"}, badClient, store, logger.New("error"))
+	if _, err := syncer.SyncOnce(context.Background()); err == nil {
+		t.Error("Expected error for rejected token")
+	}
+}
+
+func TestSyncerRefundedTicket(t *testing.T) {
+	refunded := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"pagination": {"has_more_items": false}, "attendees": [
+			{"id": "5", "refunded": %[1]t, "profile": {"email": "guest5@example.com"}},
+			{"id": "6", "refunded": %[1]t, "profile": {"email": "existing@example.com"}},
+			{"id": "7", "cancelled": %[1]t, "profile": {"email": "unknown@example.com"}}
+		]}`, refunded)
+	}))
+	defer server.Close()
+
+	client, err := eventbrite.NewClient(server.URL, "secret", server.Client())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	store := &memoryStore{users: map[string]*domain.User{
+		"existing@example.com": {ID: "1", Email: "existing@example.com", CreatedBy: "token:abc"},
+	}}
+	syncer, err := eventbrite.NewSyncer(config.EventbriteConfig{EventID: "42"}, client, store, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create syncer: %v", err)
+	}
+
+	if result, err := syncer.SyncOnce(context.Background()); err != nil || result.Imported != 2 || result.Denied != 0 {
+		t.Fatalf("Unexpected first sync: %+v, %v", result, err)
+	}
+	delete(store.users, "unknown@example.com")
+
+	// Only the guest the sync added from the refunded ticket is denied
+	refunded = true
+	result, err := syncer.SyncOnce(context.Background())
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Denied != 1 || result.Skipped != 2 || result.Imported != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if len(store.denied) != 1 || store.denied[0] != "guest5@example.com" {
+		t.Errorf("Expected only guest5@example.com to be denied, got %q", store.denied)
+	}
+}
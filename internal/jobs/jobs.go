@@ -0,0 +1,325 @@
+// Package jobs tracks long-running operations, such as imports and
+// migrations, so their progress can be followed and they can be canceled.
+// Jobs are kept in memory: a restart forgets them.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Status is the state of a job
+type Status string
+
+// Job statuses
+const (
+	StatusQueued   Status = "queued"   // Accepted, nothing processed yet
+	StatusRunning  Status = "running"  // Items are being processed
+	StatusDone     Status = "done"     // Finished, possibly with errors on some items
+	StatusFailed   Status = "failed"   // Stopped by an error
+	StatusCanceled Status = "canceled" // Stopped on request
+)
+
+// Job types
+const (
+	TypeImport    = "import"    // Emails queued through the API, or CSV files uploaded in the WebUI
+	TypeMigration = "migration" // Rewrites of the stored records
+)
+
+// Limits of tracked jobs
+const (
+	DefaultKeep = 100 // Jobs kept when the manager is created with no limit
+	maxErrors   = 50  // Errors listed per job
+)
+
+var (
+	// ErrNotFound indicates there is no job with the given ID
+	ErrNotFound = errors.New("job not found")
+	// ErrFinished indicates the job cannot be canceled as it already finished
+	ErrFinished = errors.New("job already finished")
+)
+
+// Job is the record of a long-running operation
+type Job struct {
+	ID       string         `json:"id"`
+	Type     string         `json:"type"`           // One of the Type constants
+	Name     string         `json:"name,omitempty"` // What the job does, such as normalize-emails or the imported file
+	Status   Status         `json:"status"`
+	Actor    string         `json:"actor,omitempty"`  // Who started the job
+	Total    int            `json:"total"`            // Items to process, 0 while unknown
+	Done     int            `json:"done"`             // Items processed
+	Counts   map[string]int `json:"counts,omitempty"` // Processed items by outcome, such as added or duplicate
+	Errors   []string       `json:"errors"`           // Up to 50 errors, oldest first
+	Created  time.Time      `json:"created"`
+	Updated  time.Time      `json:"updated"`
+	Finished *time.Time     `json:"finished,omitempty"`
+}
+
+// Active returns whether the job is still queued or running
+func (j Job) Active() bool {
+	return j.Status == StatusQueued || j.Status == StatusRunning
+}
+
+// Percent returns the share of items processed, from 0 to 100. Finished
+// jobs are at 100, jobs of unknown size at 0 until they finish.
+func (j Job) Percent() int {
+	switch {
+	case !j.Active():
+		return 100
+	case j.Total <= 0:
+		return 0
+	default:
+		return min(j.Done*100/j.Total, 100)
+	}
+}
+
+// Manager keeps the most recent jobs. It is safe for concurrent use.
+type Manager struct {
+	mu   sync.Mutex
+	keep int
+	jobs map[string]*Handle
+	ids  []string // Oldest first
+}
+
+// NewManager creates a manager keeping the last keep jobs, or DefaultKeep
+// if keep is not positive. Older jobs are forgotten, even if still running.
+func NewManager(keep int) *Manager {
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	return &Manager{keep: keep, jobs: make(map[string]*Handle)}
+}
+
+// Handle updates a job while it runs
+type Handle struct {
+	m      *Manager
+	job    Job // Guarded by m.mu
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Create tracks a new queued job. A random ID is assigned unless the job
+// has one; the handle of a job already tracked under that ID is returned
+// as it is, so jobs whose work arrives in parts can be created by
+// whichever part comes first.
+func (m *Manager) Create(job Job) *Handle {
+	if job.ID == "" {
+		job.ID = newID()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if h, ok := m.jobs[job.ID]; ok {
+		return h
+	}
+
+	now := time.Now()
+	if job.Created.IsZero() {
+		job.Created = now
+	}
+	job.Updated = now
+	job.Status = StatusQueued
+	job.Errors = []string{}
+	job.Counts = nil
+	job.Done = 0
+	job.Finished = nil
+
+	h := &Handle{m: m, job: job}
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+	m.jobs[job.ID] = h
+	m.ids = append(m.ids, job.ID)
+	if len(m.ids) > m.keep {
+		delete(m.jobs, m.ids[0])
+		m.ids = m.ids[1:]
+	}
+	return h
+}
+
+// Run creates a job and runs fn for it in the background. The job is
+// finished with the error fn returns; fn should stop when its context is
+// canceled.
+func (m *Manager) Run(job Job, fn func(ctx context.Context, h *Handle) error) Job {
+	h := m.Create(job)
+	h.Start()
+	go func() {
+		h.Finish(fn(h.ctx, h))
+	}()
+	return h.Job()
+}
+
+// Get returns a copy of a job
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	h, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return h.Job(), nil
+}
+
+// List returns copies of the tracked jobs, newest first
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]Job, 0, len(m.ids))
+	for i := len(m.ids) - 1; i >= 0; i-- {
+		list = append(list, copyJob(m.jobs[m.ids[i]].job))
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Created.After(list[j].Created) })
+	return list
+}
+
+// Cancel stops a queued or running job. The job is marked canceled at
+// once and its context is canceled; items already being processed may
+// still be counted.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	h, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return Job{}, ErrNotFound
+	}
+	if !h.job.Active() {
+		job := copyJob(h.job)
+		m.mu.Unlock()
+		return job, ErrFinished
+	}
+	h.finishLocked(StatusCanceled)
+	job := copyJob(h.job)
+	m.mu.Unlock()
+
+	h.cancel()
+	return job, nil
+}
+
+// ID returns the ID of the job
+func (h *Handle) ID() string {
+	return h.job.ID // Never changes after Create
+}
+
+// Context returns a context canceled when the job is canceled
+func (h *Handle) Context() context.Context {
+	return h.ctx
+}
+
+// Job returns a copy of the job
+func (h *Handle) Job() Job {
+	h.m.mu.Lock()
+	defer h.m.mu.Unlock()
+	return copyJob(h.job)
+}
+
+// Start marks a queued job running
+func (h *Handle) Start() {
+	h.update(func(job *Job) {
+		if job.Status == StatusQueued {
+			job.Status = StatusRunning
+		}
+	})
+}
+
+// SetTotal sets the number of items to process
+func (h *Handle) SetTotal(total int) {
+	h.update(func(job *Job) { job.Total = total })
+}
+
+// Advance counts n processed items with an outcome, such as added or
+// failed. An empty outcome only counts the items as done.
+func (h *Handle) Advance(outcome string, n int) {
+	h.update(func(job *Job) {
+		if job.Status == StatusQueued {
+			job.Status = StatusRunning
+		}
+		job.Done += n
+		if outcome != "" {
+			if job.Counts == nil {
+				job.Counts = make(map[string]int)
+			}
+			job.Counts[outcome] += n
+		}
+	})
+}
+
+// Error lists an error of the job, such as an item that failed. Only the
+// first errors are kept.
+func (h *Handle) Error(msg string) {
+	h.update(func(job *Job) {
+		if len(job.Errors) < maxErrors {
+			job.Errors = append(job.Errors, msg)
+		}
+	})
+}
+
+// Finish marks the job done, or failed with err. Jobs stopped because
+// their context was canceled are marked canceled. Finishing a job that
+// already finished changes nothing.
+func (h *Handle) Finish(err error) {
+	h.m.mu.Lock()
+	defer h.m.mu.Unlock()
+
+	if !h.job.Active() {
+		return
+	}
+	switch {
+	case err == nil:
+		h.finishLocked(StatusDone)
+	case h.ctx.Err() != nil && errors.Is(err, context.Canceled):
+		h.finishLocked(StatusCanceled)
+	default:
+		if len(h.job.Errors) < maxErrors {
+			h.job.Errors = append(h.job.Errors, err.Error())
+		}
+		h.finishLocked(StatusFailed)
+	}
+}
+
+// update changes the job under the manager lock
+func (h *Handle) update(change func(job *Job)) {
+	h.m.mu.Lock()
+	defer h.m.mu.Unlock()
+	change(&h.job)
+	h.job.Updated = time.Now()
+}
+
+// finishLocked sets the final status of the job. The caller must hold m.mu.
+func (h *Handle) finishLocked(status Status) {
+	now := time.Now()
+	h.job.Status = status
+	h.job.Updated = now
+	h.job.Finished = &now
+}
+
+// copyJob copies a job, so callers cannot change the tracked one
+func copyJob(job Job) Job {
+	job.Errors = append([]string{}, job.Errors...)
+	if job.Counts != nil {
+		counts := make(map[string]int, len(job.Counts))
+		for outcome, n := range job.Counts {
+			counts[outcome] = n
+		}
+		job.Counts = counts
+	}
+	if job.Finished != nil {
+		finished := *job.Finished
+		job.Finished = &finished
+	}
+	return job
+}
+
+// newID returns a random job ID, unique across instances
+func newID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,94 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/jobs"
+)
+
+// waitFinished polls a job until it is no longer active
+func waitFinished(t *testing.T, m *jobs.Manager, id string) jobs.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("Failed to get job: %v", err)
+		}
+		if !job.Active() {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for the job")
+	return jobs.Job{}
+}
+
+func TestManagerTracksProgress(t *testing.T) {
+	m := jobs.NewManager(0)
+
+	h := m.Create(jobs.Job{Type: jobs.TypeImport, Actor: "token:abc", Total: 3})
+	if job := h.Job(); job.ID == "" || job.Status != jobs.StatusQueued || job.Percent() != 0 {
+		t.Fatalf("Expected a queued job, got %+v", job)
+	}
+	if again := m.Create(jobs.Job{ID: h.ID(), Type: jobs.TypeImport}); again != h {
+		t.Error("Expected the tracked job for a known ID")
+	}
+
+	h.Advance("added", 2)
+	h.Advance("failed", 1)
+	h.Error("bad@: invalid format")
+	job := h.Job()
+	if job.Status != jobs.StatusRunning || job.Done != 3 || job.Counts["added"] != 2 || job.Percent() != 100 {
+		t.Errorf("Unexpected progress: %+v", job)
+	}
+
+	h.Finish(nil)
+	if job, _ := m.Get(h.ID()); job.Status != jobs.StatusDone || job.Finished == nil || len(job.Errors) != 1 {
+		t.Errorf("Expected a finished job with one error, got %+v", job)
+	}
+	if _, err := m.Cancel(h.ID()); !errors.Is(err, jobs.ErrFinished) {
+		t.Errorf("Expected a finished job not to be canceled, got %v", err)
+	}
+	if _, err := m.Get("missing"); !errors.Is(err, jobs.ErrNotFound) {
+		t.Errorf("Expected an unknown job, got %v", err)
+	}
+}
+
+func TestManagerRun(t *testing.T) {
+	m := jobs.NewManager(2)
+
+	failed := m.Run(jobs.Job{Type: jobs.TypeMigration}, func(ctx context.Context, h *jobs.Handle) error {
+		return errors.New("duplicate emails")
+	})
+	if job := waitFinished(t, m, failed.ID); job.Status != jobs.StatusFailed || job.Errors[0] != "duplicate emails" {
+		t.Errorf("Expected a failed job, got %+v", job)
+	}
+
+	started := make(chan struct{})
+	canceled := m.Run(jobs.Job{Type: jobs.TypeMigration}, func(ctx context.Context, h *jobs.Handle) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+	if job, err := m.Cancel(canceled.ID); err != nil || job.Status != jobs.StatusCanceled {
+		t.Fatalf("Expected the job to be canceled, got %+v, %v", job, err)
+	}
+	if job := waitFinished(t, m, canceled.ID); job.Status != jobs.StatusCanceled || len(job.Errors) != 0 {
+		t.Errorf("Expected the job to stay canceled, got %+v", job)
+	}
+
+	// Only the last two jobs are kept, newest first
+	newest := m.Create(jobs.Job{Type: jobs.TypeImport})
+	list := m.List()
+	if len(list) != 2 || list[0].ID != newest.ID() || list[1].ID != canceled.ID {
+		t.Errorf("Expected the two newest jobs, got %+v", list)
+	}
+	if _, err := m.Get(failed.ID); !errors.Is(err, jobs.ErrNotFound) {
+		t.Errorf("Expected the oldest job to be forgotten, got %v", err)
+	}
+}
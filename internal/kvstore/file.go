@@ -0,0 +1,90 @@
+package kvstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// File keeps values in a JSON file that is rewritten on every change. It
+// suits the little state of a single bot; use SQLite or Redis for more.
+type File struct {
+	path string
+
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+// NewFile opens the store saved at path, creating it on the first change
+func NewFile(path string) (*File, error) {
+	f := &File{path: path, values: make(map[string][]byte)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, &f.values); err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %w", err)
+	}
+	return f, nil
+}
+
+// Get returns the value of a key
+func (f *File) Get(ctx context.Context, key string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+// Set stores the value of a key and saves the file
+func (f *File) Set(ctx context.Context, key string, value []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[key] = append([]byte(nil), value...)
+	return f.save()
+}
+
+// Delete removes a key and saves the file
+func (f *File) Delete(ctx context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.values[key]; !ok {
+		return nil
+	}
+	delete(f.values, key)
+	return f.save()
+}
+
+// Close does nothing, every change is already saved
+func (f *File) Close() error {
+	return nil
+}
+
+// save writes all values to the file. The caller must hold mu.
+func (f *File) save() error {
+	data, err := json.Marshal(f.values)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash cannot leave a truncated file
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
@@ -0,0 +1,108 @@
+// Package kvstore keeps small values by key in memory, a file, SQLite or
+// Redis, so operational state such as the position in the Telegram update
+// stream survives restarts.
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// Default locations of the file and SQLite stores
+const (
+	DefaultFilePath   = "./data/telegram_state.json"
+	DefaultSQLitePath = "./data/telegram_state.db"
+)
+
+// ErrNotFound is returned when getting a key that has no value
+var ErrNotFound = errors.New("key not found")
+
+// Store keeps values by key. Implementations are safe for concurrent use.
+type Store interface {
+	// Get returns the value of a key, or ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores the value of a key, replacing any previous value
+	Set(ctx context.Context, key string, value []byte) error
+	// Delete removes a key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// Close releases the resources of the store
+	Close() error
+}
+
+// New creates a store based on the state configuration
+func New(cfg config.StateConfig, logger *logger.Logger) (Store, error) {
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+
+	storeType := strings.ToLower(cfg.Store)
+	switch storeType {
+	case "", "memory":
+		return NewMemory(), nil
+	case "file":
+		path := cfg.Path
+		if path == "" {
+			path = DefaultFilePath
+		}
+		return NewFile(path)
+	case "sqlite":
+		path := cfg.Path
+		if path == "" {
+			path = DefaultSQLitePath
+		}
+		return NewSQLite(path)
+	case "redis":
+		return NewRedis(cfg.URL, cfg.Prefix, logger)
+	default:
+		return nil, fmt.Errorf("unsupported state store: %s", storeType)
+	}
+}
+
+// Memory keeps values in memory. They are lost on restart.
+type Memory struct {
+	mu     sync.RWMutex
+	values map[string][]byte
+}
+
+// NewMemory creates an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{values: make(map[string][]byte)}
+}
+
+// Get returns the value of a key
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, ok := m.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+// Set stores the value of a key
+func (m *Memory) Set(ctx context.Context, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Delete removes a key
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+// Close does nothing, the values stay readable
+func (m *Memory) Close() error {
+	return nil
+}
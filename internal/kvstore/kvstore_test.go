@@ -0,0 +1,270 @@
+package kvstore_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/kvstore"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// checkStore sets, reads and deletes values of a store
+func checkStore(t *testing.T, store kvstore.Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "telegram:offset"); !errors.Is(err, kvstore.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing key, got %v", err)
+	}
+	if err := store.Set(ctx, "telegram:offset", []byte("42")); err != nil {
+		t.Fatalf("Failed to set: %v", err)
+	}
+	if err := store.Set(ctx, "telegram:offset", []byte("43")); err != nil {
+		t.Fatalf("Failed to replace: %v", err)
+	}
+	if value, err := store.Get(ctx, "telegram:offset"); err != nil || string(value) != "43" {
+		t.Errorf("Expected the replaced value, got %q (%v)", value, err)
+	}
+	binary := []byte("line\r\nbreak\x00")
+	if err := store.Set(ctx, "telegram:lang:1", binary); err != nil {
+		t.Fatalf("Failed to set: %v", err)
+	}
+	if value, err := store.Get(ctx, "telegram:lang:1"); err != nil || string(value) != string(binary) {
+		t.Errorf("Expected the value unchanged, got %q (%v)", value, err)
+	}
+	if err := store.Delete(ctx, "telegram:lang:1"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if err := store.Delete(ctx, "telegram:lang:1"); err != nil {
+		t.Errorf("Expected deleting a missing key to succeed, got %v", err)
+	}
+	if _, err := store.Get(ctx, "telegram:lang:1"); !errors.Is(err, kvstore.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after deleting, got %v", err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	store, err := kvstore.New(config.StateConfig{Store: "memory"}, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	checkStore(t, store)
+
+	if _, err := kvstore.New(config.StateConfig{Store: "etcd"}, logger.New("error")); err == nil {
+		t.Error("Expected an unsupported store to be refused")
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "telegram_state.json")
+	store, err := kvstore.New(config.StateConfig{Store: "file", Path: path}, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	checkStore(t, store)
+	store.Close()
+
+	// Values are read back after a restart
+	reopened, err := kvstore.NewFile(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	if value, err := reopened.Get(context.Background(), "telegram:offset"); err != nil || string(value) != "43" {
+		t.Errorf("Expected the saved value, got %q (%v)", value, err)
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	store, err := kvstore.New(config.StateConfig{Store: "sqlite", Path: path}, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	checkStore(t, store)
+	store.Close()
+
+	reopened, err := kvstore.NewSQLite(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer reopened.Close()
+	if value, err := reopened.Get(context.Background(), "telegram:offset"); err != nil || string(value) != "43" {
+		t.Errorf("Expected the saved value, got %q (%v)", value, err)
+	}
+}
+
+// fakeRedis is a Redis server that keeps values in memory and checks the
+// password
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	conns    []net.Conn
+	commands []string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &fakeRedis{listener: listener, password: password, values: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+// dropConnections closes every open connection, as a server restart would
+func (s *fakeRedis) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authenticated := s.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.commands = append(s.commands, strings.Join(args, " "))
+		var reply string
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "AUTH":
+			if args[len(args)-1] != s.password {
+				reply = "-WRONGPASS invalid password\r\n"
+				break
+			}
+			authenticated = true
+			reply = "+OK\r\n"
+		case !authenticated:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case cmd == "SELECT":
+			reply = "+OK\r\n"
+		case cmd == "GET":
+			if value, ok := s.values[args[1]]; ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			} else {
+				reply = "$-1\r\n"
+			}
+		case cmd == "SET":
+			s.values[args[1]] = args[2]
+			reply = "+OK\r\n"
+		case cmd == "DEL":
+			deleted := 0
+			if _, ok := s.values[args[1]]; ok {
+				delete(s.values, args[1])
+				deleted = 1
+			}
+			reply = fmt.Sprintf(":%d\r\n", deleted)
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		s.mu.Unlock()
+
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func TestRedis(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+	addr := server.listener.Addr().String()
+
+	if _, err := kvstore.NewRedis("redis://:wrong@"+addr, "", logger.New("error")); err == nil {
+		t.Error("Expected a wrong password to be refused")
+	}
+	if _, err := kvstore.NewRedis("http://"+addr, "", logger.New("error")); err == nil {
+		t.Error("Expected an unsupported scheme to be refused")
+	}
+
+	store, err := kvstore.New(config.StateConfig{Store: "redis", URL: "redis://:secret@" + addr + "/2", Prefix: "cocktailbot:"}, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	checkStore(t, store)
+
+	server.mu.Lock()
+	_, prefixed := server.values["cocktailbot:telegram:offset"]
+	selected := strings.Join(server.commands, "\n")
+	server.mu.Unlock()
+	if !prefixed {
+		t.Error("Expected keys to carry the prefix")
+	}
+	if !strings.Contains(selected, "SELECT 2") {
+		t.Errorf("Expected the database to be selected, got commands:\n%s", selected)
+	}
+
+	// A dropped connection is replaced without failing the command
+	server.dropConnections()
+	if value, err := store.Get(context.Background(), "telegram:offset"); err != nil || string(value) != "43" {
+		t.Errorf("Expected the value after reconnecting, got %q (%v)", value, err)
+	}
+
+	store.Close()
+	if _, err := store.Get(context.Background(), "telegram:offset"); err == nil {
+		t.Error("Expected a closed store to fail")
+	}
+}
@@ -0,0 +1,270 @@
+package kvstore
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// Timeouts of the Redis connection
+const (
+	redisDialTimeout = 5 * time.Second
+	redisIOTimeout   = 5 * time.Second
+)
+
+// redisError is an error reply of the Redis server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// Redis keeps values in a Redis server, so state outlives the machine the
+// bot runs on. It speaks the Redis protocol over one connection and
+// connects again when the connection drops.
+type Redis struct {
+	addr   string // host:port of the server
+	tls    bool
+	user   string
+	pass   string
+	db     int
+	prefix string // Prepended to every key
+	logger *logger.Logger
+
+	mu     sync.Mutex // Guards conn and r, and serializes commands
+	conn   net.Conn   // nil until connected again after an error
+	r      *bufio.Reader
+	closed bool
+}
+
+// NewRedis connects to the Redis server at rawURL, such as
+// redis://:password@localhost:6379/0, or rediss:// for TLS
+func NewRedis(rawURL, prefix string, logger *logger.Logger) (*Redis, error) {
+	if rawURL == "" {
+		rawURL = "redis://localhost:6379"
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("unsupported Redis URL scheme: %s", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+
+	s := &Redis{addr: addr, tls: u.Scheme == "rediss", prefix: prefix, logger: logger}
+	if u.User != nil {
+		s.user = u.User.Username()
+		s.pass, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if s.db, err = strconv.Atoi(db); err != nil || s.db < 0 {
+			return nil, fmt.Errorf("invalid Redis database: %q", db)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	logger.Info("Connected to Redis", "server", addr, "db", s.db)
+	return s, nil
+}
+
+// connect dials the server, authenticates and selects the database. The
+// caller must hold mu.
+func (s *Redis) connect() error {
+	var conn net.Conn
+	var err error
+	if s.tls {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: redisDialTimeout}, "tcp", s.addr, nil)
+	} else {
+		conn, err = net.DialTimeout("tcp", s.addr, redisDialTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	s.conn, s.r = conn, bufio.NewReader(conn)
+
+	conn.SetDeadline(time.Now().Add(redisDialTimeout))
+	var setup [][]string
+	switch {
+	case s.pass != "" && s.user != "":
+		setup = append(setup, []string{"AUTH", s.user, s.pass})
+	case s.pass != "":
+		setup = append(setup, []string{"AUTH", s.pass})
+	}
+	if s.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(s.db)})
+	}
+	for _, args := range setup {
+		if _, err := s.roundTrip(args); err != nil {
+			s.disconnect()
+			return fmt.Errorf("failed to set up Redis connection: %w", err)
+		}
+	}
+	return nil
+}
+
+// disconnect closes the connection. The caller must hold mu.
+func (s *Redis) disconnect() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn, s.r = nil, nil
+	}
+}
+
+// do runs a command, connecting first if needed. A connection that broke
+// while idle is replaced and the command sent once more.
+func (s *Redis) do(ctx context.Context, args ...string) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errors.New("redis store is closed")
+	}
+
+	for attempt := 0; ; attempt++ {
+		reused := s.conn != nil
+		if !reused {
+			if err := s.connect(); err != nil {
+				return nil, err
+			}
+		}
+
+		deadline := time.Now().Add(redisIOTimeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		s.conn.SetDeadline(deadline)
+
+		reply, err := s.roundTrip(args)
+		var replyErr redisError
+		if err == nil || errors.As(err, &replyErr) {
+			return reply, err
+		}
+		s.disconnect()
+		if !reused || attempt > 0 {
+			return nil, fmt.Errorf("redis %s failed: %w", args[0], err)
+		}
+		s.logger.Warn("Lost connection to Redis, reconnecting", "error", err)
+	}
+}
+
+// roundTrip writes a command and reads its reply. The caller must hold mu.
+func (s *Redis) roundTrip(args []string) (any, error) {
+	cmd := fmt.Appendf(nil, "*%d\r\n", len(args))
+	for _, arg := range args {
+		cmd = fmt.Appendf(cmd, "$%d\r\n", len(arg))
+		cmd = append(cmd, arg...)
+		cmd = append(cmd, '\r', '\n')
+	}
+	if _, err := s.conn.Write(cmd); err != nil {
+		return nil, err
+	}
+	return readReply(s.r)
+}
+
+// readReply reads one reply: a string, an error, an integer, a bulk string
+// as []byte or nil, or an array of replies
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty Redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid Redis bulk length: %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2) // Followed by CRLF
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid Redis array length: %q", line)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]any, count)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected Redis reply: %q", line)
+	}
+}
+
+// Get returns the value of a key
+func (s *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := s.do(ctx, "GET", s.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+// Set stores the value of a key
+func (s *Redis) Set(ctx context.Context, key string, value []byte) error {
+	_, err := s.do(ctx, "SET", s.prefix+key, string(value))
+	return err
+}
+
+// Delete removes a key
+func (s *Redis) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, "DEL", s.prefix+key)
+	return err
+}
+
+// Close disconnects from the server
+func (s *Redis) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.disconnect()
+	return nil
+}
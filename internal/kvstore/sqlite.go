@@ -0,0 +1,69 @@
+package kvstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+)
+
+// SQLite keeps values in a table of a SQLite database, which may be the
+// guest database itself
+type SQLite struct {
+	db *sql.DB
+}
+
+// NewSQLite opens the database at path and creates the state table
+func NewSQLite(path string) (*SQLite, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database: %w", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS bot_state (
+		key TEXT PRIMARY KEY,
+		value BLOB NOT NULL
+	)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create state table: %w", err)
+	}
+	return &SQLite{db: db}, nil
+}
+
+// Get returns the value of a key
+func (s *SQLite) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM bot_state WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+// Set stores the value of a key
+func (s *SQLite) Set(ctx context.Context, key string, value []byte) error {
+	if value == nil {
+		value = []byte{} // Not NULL
+	}
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO bot_state (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value",
+		key, value)
+	return err
+}
+
+// Delete removes a key
+func (s *SQLite) Delete(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM bot_state WHERE key = ?", key)
+	return err
+}
+
+// Close closes the database
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,25 @@
+package logger
+
+import "context"
+
+// contextKey is the type of keys stored in contexts by this package
+type contextKey struct{}
+
+// NewContext returns a context carrying the logger
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by the context, or fallback if
+// there is none. The context may be nil or of any type, since services
+// accept contexts as any.
+func FromContext(ctx any, fallback *Logger) *Logger {
+	c, ok := ctx.(context.Context)
+	if !ok {
+		return fallback
+	}
+	if l, ok := c.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return fallback
+}
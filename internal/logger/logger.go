@@ -7,7 +7,10 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
 )
 
 // Logger represents a structured logger for the application.
@@ -17,9 +20,24 @@ type Logger struct {
 	mu        sync.Mutex
 	out       io.Writer
 	timestamp bool
+	color     bool          // Colorize levels with ANSI escape codes
+	priority  bool          // Prefix lines with their syslog priority for journald
+	sink      levelWriter   // Receives lines instead of out, e.g. syslog
+	closer    io.Closer     // Log file closed by Close, if any
+	sampler   *sampler      // Shared by all loggers derived from this one
+	sampleKey string        // Set on loggers returned by Sampled
+	recent    *recentErrors // Shared by all loggers derived from this one
+	mask      *atomic.Bool  // Whether email values are masked, shared by all loggers derived from this one
+	fields    []any         // Key-value pairs added to every message
 	logger    *log.Logger
 }
 
+// levelWriter writes log lines along with their level
+type levelWriter interface {
+	WriteLevel(level Level, line string) error
+	Close() error
+}
+
 // Level represents the logging level.
 type Level int
 
@@ -52,10 +70,29 @@ var levelColors = map[Level]string{
 	FatalLevel: "\033[35m", // Magenta
 }
 
+// levelPriorities maps levels to syslog severities
+var levelPriorities = map[Level]int{
+	DebugLevel: 7, // debug
+	InfoLevel:  6, // info
+	WarnLevel:  4, // warning
+	ErrorLevel: 3, // err
+	FatalLevel: 2, // crit
+}
+
 const (
 	colorReset = "\033[0m"
 )
 
+// Log outputs
+const (
+	OutputStdout   = "stdout"   // Standard output, colored when it is a terminal
+	OutputJournald = "journald" // Standard output with priority prefixes read by journald
+	OutputSyslog   = "syslog"   // The local syslog daemon
+)
+
+// syslogTag identifies the application in syslog
+const syslogTag = "cocktail-bot"
+
 // New creates a new logger with the specified logging level.
 func New(level any) *Logger {
 	var logLevel Level
@@ -76,6 +113,10 @@ func New(level any) *Logger {
 		prefix:    "",
 		out:       os.Stdout,
 		timestamp: true,
+		color:     colorEnabled(os.Stdout),
+		sampler:   newSampler(DefaultSampleFirst, DefaultSampleInterval),
+		recent:    newRecentErrors(),
+		mask:      new(atomic.Bool),
 		logger:    log.New(os.Stdout, "", 0),
 	}
 	return l
@@ -106,11 +147,67 @@ func NewWithWriter(level any, writer io.Writer) *Logger {
 		prefix:    "",
 		out:       writer,
 		timestamp: true,
+		color:     colorEnabled(writer),
+		sampler:   newSampler(DefaultSampleFirst, DefaultSampleInterval),
+		recent:    newRecentErrors(),
+		mask:      new(atomic.Bool),
 		logger:    log.New(writer, "", 0),
 	}
 	return l
 }
 
+// NewWithOutput creates a logger writing to the named output. Journald
+// and syslog add their own timestamps, so messages sent there have none.
+func NewWithOutput(level any, output string) (*Logger, error) {
+	switch strings.ToLower(output) {
+	case "", OutputStdout:
+		return New(level), nil
+	case OutputJournald:
+		l := New(level)
+		l.timestamp = false
+		l.color = false
+		l.priority = true
+		return l, nil
+	case OutputSyslog:
+		sink, err := dialSyslog(syslogTag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+		l := New(level)
+		l.timestamp = false
+		l.color = false
+		l.sink = sink
+		return l, nil
+	default:
+		return nil, fmt.Errorf("unknown log output: %s", output)
+	}
+}
+
+// NewWithFile creates a logger writing to a file that rotates itself
+func NewWithFile(level any, path string, rotation RotationConfig) (*Logger, error) {
+	file, err := NewRotatingFile(path, rotation)
+	if err != nil {
+		return nil, err
+	}
+	l := NewWithWriter(level, file)
+	l.closer = file
+	return l, nil
+}
+
+// colorEnabled returns true if the writer is a terminal and colors are not
+// disabled by NO_COLOR (https://no-color.org) or a dumb terminal
+func colorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 // parseLevel converts a string level to a Level.
 func parseLevel(level string) Level {
 	switch strings.ToLower(level) {
@@ -154,11 +251,54 @@ func (l *Logger) WithPrefix(prefix string) *Logger {
 		prefix:    prefix,
 		out:       l.out,
 		timestamp: l.timestamp,
+		color:     l.color,
+		priority:  l.priority,
+		sink:      l.sink,
+		sampler:   l.sampler,
+		sampleKey: l.sampleKey,
+		recent:    l.recent,
+		mask:      l.mask,
+		fields:    l.fields,
 		logger:    log.New(l.out, "", 0),
 	}
 	return newLogger
 }
 
+// With returns a logger adding the key-value pairs to every message, for
+// example the ID of the request being handled.
+func (l *Logger) With(args ...any) *Logger {
+	if len(args)%2 != 0 {
+		args = append(args, "MISSING_VALUE")
+	}
+	newLogger := l.WithPrefix(l.prefix)
+	newLogger.fields = append(l.fields[:len(l.fields):len(l.fields)], args...)
+	return newLogger
+}
+
+// Sampled returns a logger that logs only some of the messages sent with
+// the key: the first few, then one per sampling interval, reporting how many
+// were suppressed. Use it on error paths that repeat during outages.
+func (l *Logger) Sampled(key string) *Logger {
+	newLogger := l.WithPrefix(l.prefix)
+	newLogger.sampleKey = key
+	return newLogger
+}
+
+// SetSampling sets how many messages per key Sampled loggers pass before
+// passing only one per interval. It applies to all loggers sharing the
+// sampler of this logger.
+func (l *Logger) SetSampling(first int, interval time.Duration) {
+	l.sampler.configure(first, interval)
+}
+
+// SetMaskEmails sets whether emails are logged masked: the values of keys
+// naming an email, such as email or target_email, and any email found in
+// the message or in other values, such as errors and slices. It applies to
+// all loggers sharing the setting of this logger.
+func (l *Logger) SetMaskEmails(mask bool) {
+	l.mask.Store(mask)
+}
+
 // Debug logs a debug message with key-value pairs.
 func (l *Logger) Debug(msg string, args ...any) {
 	l.log(DebugLevel, msg, args...)
@@ -191,6 +331,20 @@ func (l *Logger) log(level Level, msg string, args ...any) {
 		return
 	}
 
+	if len(l.fields) > 0 {
+		args = append(l.fields[:len(l.fields):len(l.fields)], args...)
+	}
+
+	if l.sampleKey != "" {
+		allowed, suppressed := l.sampler.allow(l.sampleKey, time.Now())
+		if !allowed {
+			return
+		}
+		if suppressed > 0 {
+			args = append(args[:len(args):len(args)], "suppressed", suppressed)
+		}
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
@@ -204,6 +358,11 @@ func (l *Logger) log(level Level, msg string, args ...any) {
 	levelName := levelNames[level]
 	levelColor := levelColors[level]
 
+	mask := l.mask.Load()
+	if mask {
+		msg = utils.MaskEmails(msg)
+	}
+
 	// Format key-value pairs
 	var kvStr string
 	if len(args) > 0 {
@@ -216,8 +375,13 @@ func (l *Logger) log(level Level, msg string, args ...any) {
 			if !ok {
 				key = fmt.Sprintf("%v", args[i])
 			}
-			value := args[i+1]
-			pairs = append(pairs, fmt.Sprintf("%s=%v", key, value))
+			value := fmt.Sprintf("%v", args[i+1])
+			if email, ok := args[i+1].(string); ok && mask && strings.Contains(strings.ToLower(key), "email") {
+				value = utils.MaskEmail(email)
+			} else if mask {
+				value = utils.MaskEmails(value)
+			}
+			pairs = append(pairs, key+"="+value)
 		}
 		kvStr = " " + strings.Join(pairs, " ")
 	}
@@ -228,6 +392,11 @@ func (l *Logger) log(level Level, msg string, args ...any) {
 		prefixStr = "[" + l.prefix + "] "
 	}
 
+	// Keep errors for status reports
+	if level >= ErrorLevel {
+		l.recent.add(ErrorRecord{Time: time.Now(), Message: prefixStr + msg + kvStr})
+	}
+
 	// Build the final log line
 	var builder strings.Builder
 
@@ -236,8 +405,12 @@ func (l *Logger) log(level Level, msg string, args ...any) {
 		builder.WriteString(fmt.Sprintf("%s ", timeStr))
 	}
 
-	// Add colored level
-	builder.WriteString(fmt.Sprintf("%s%-5s%s ", levelColor, levelName, colorReset))
+	// Add level, colored on terminals
+	if l.color {
+		builder.WriteString(fmt.Sprintf("%s%-5s%s ", levelColor, levelName, colorReset))
+	} else {
+		builder.WriteString(fmt.Sprintf("%-5s ", levelName))
+	}
 
 	// Add prefix if present
 	if prefixStr != "" {
@@ -251,7 +424,27 @@ func (l *Logger) log(level Level, msg string, args ...any) {
 	builder.WriteString(kvStr)
 
 	// Output the log line
-	l.logger.Println(builder.String())
+	switch {
+	case l.sink != nil:
+		if err := l.sink.WriteLevel(level, builder.String()); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write log: %v\n", err)
+		}
+	case l.priority:
+		l.logger.Printf("<%d>%s\n", levelPriorities[level], builder.String())
+	default:
+		l.logger.Println(builder.String())
+	}
+}
+
+// Close releases the connection to syslog or the log file, if any.
+func (l *Logger) Close() error {
+	if l.sink != nil {
+		return l.sink.Close()
+	}
+	if l.closer != nil {
+		return l.closer.Close()
+	}
+	return nil
 }
 
 // SetLevel sets the logging level.
@@ -2,6 +2,9 @@ package logger
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -157,19 +160,19 @@ func TestLoggerWithIntLevel(t *testing.T) {
 	logger.Error("error message") // Should be logged
 
 	output := buf.String()
-	
+
 	if strings.Contains(output, "debug message") {
 		t.Error("Debug message should not appear when level is set to Warn")
 	}
-	
+
 	if strings.Contains(output, "info message") {
 		t.Error("Info message should not appear when level is set to Warn")
 	}
-	
+
 	if !strings.Contains(output, "warn message") {
 		t.Error("Warn message should appear when level is set to Warn")
 	}
-	
+
 	if !strings.Contains(output, "error message") {
 		t.Error("Error message should appear when level is set to Warn")
 	}
@@ -177,27 +180,27 @@ func TestLoggerWithIntLevel(t *testing.T) {
 
 func TestSetAndGetLevel(t *testing.T) {
 	logger := New("info")
-	
+
 	if logger.GetLevel() != InfoLevel {
 		t.Errorf("Expected initial level to be InfoLevel, got %v", logger.GetLevel())
 	}
-	
+
 	logger.SetLevel("debug")
 	if logger.GetLevel() != DebugLevel {
 		t.Errorf("Expected level to be DebugLevel after SetLevel, got %v", logger.GetLevel())
 	}
-	
+
 	logger.SetLevel("error")
 	if logger.GetLevel() != ErrorLevel {
 		t.Errorf("Expected level to be ErrorLevel after SetLevel, got %v", logger.GetLevel())
 	}
-	
+
 	// Test setting level with integer
 	logger.SetLevel(0) // DebugLevel
 	if logger.GetLevel() != DebugLevel {
 		t.Errorf("Expected level to be DebugLevel after SetLevel(0), got %v", logger.GetLevel())
 	}
-	
+
 	// Test setting level with Level type
 	logger.SetLevel(ErrorLevel)
 	if logger.GetLevel() != ErrorLevel {
@@ -208,21 +211,196 @@ func TestSetAndGetLevel(t *testing.T) {
 func TestTimestampControl(t *testing.T) {
 	var buf bytes.Buffer
 	logger := NewWithWriter("info", &buf)
-	
+
 	// Enable timestamp (default)
 	logger.EnableTimestamp()
 	buf.Reset()
 	logger.Info("with timestamp")
 	withTimestamp := buf.String()
-	
+
 	// Disable timestamp
 	logger.DisableTimestamp()
 	buf.Reset()
 	logger.Info("without timestamp")
 	withoutTimestamp := buf.String()
-	
+
 	// First log should be longer due to timestamp
 	if len(withoutTimestamp) >= len(withTimestamp) {
 		t.Error("Expected log with timestamp to be longer than log without timestamp")
 	}
-}
\ No newline at end of file
+}
+
+func TestNoColorWithoutTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithWriter("info", &buf)
+	logger.DisableTimestamp()
+
+	logger.Warn("test message")
+
+	if strings.Contains(buf.String(), "\033[") {
+		t.Errorf("Expected no ANSI escape codes when not writing to a terminal, got %q", buf.String())
+	}
+	if buf.String() != "WARN  test message\n" {
+		t.Errorf("Unexpected output %q", buf.String())
+	}
+}
+
+func TestJournaldPriorities(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithWriter("debug", &buf)
+	logger.DisableTimestamp()
+	logger.priority = true
+
+	logger.Debug("debug message")
+	logger.Info("info message")
+	logger.Warn("warn message")
+	logger.Error("error message")
+
+	expected := []string{"<7>DEBUG debug message", "<6>INFO  info message", "<4>WARN  warn message", "<3>ERROR error message"}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %q", len(expected), buf.String())
+	}
+	for i, line := range lines {
+		if line != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], line)
+		}
+	}
+}
+
+func TestNewWithOutput(t *testing.T) {
+	if _, err := NewWithOutput("info", "carrier-pigeon"); err == nil {
+		t.Error("Expected an error for an unknown output")
+	}
+
+	logger, err := NewWithOutput("info", OutputJournald)
+	if err != nil {
+		t.Fatalf("Failed to create journald logger: %v", err)
+	}
+	if logger.color || logger.timestamp || !logger.priority {
+		t.Errorf("Expected journald logger without colors and timestamps, got %+v", logger)
+	}
+}
+
+func TestLoggerWith(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithWriter("info", &buf)
+	logger.DisableTimestamp()
+
+	child := logger.With("request_id", "abc").With("chat_id", 42)
+	child.Info("test message", "key", "value")
+	logger.Info("parent message")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", buf.String())
+	}
+	if !strings.HasSuffix(lines[0], "test message request_id=abc chat_id=42 key=value") {
+		t.Errorf("Expected persistent key-values before the message key-values, got %q", lines[0])
+	}
+	if strings.Contains(lines[1], "request_id") {
+		t.Errorf("Expected the parent logger not to change, got %q", lines[1])
+	}
+}
+
+func TestLoggerContext(t *testing.T) {
+	fallback := New("info")
+	child := fallback.With("request_id", "abc")
+
+	if got := FromContext(NewContext(context.Background(), child), fallback); got != child {
+		t.Error("Expected the logger stored in the context")
+	}
+	if got := FromContext(context.Background(), fallback); got != fallback {
+		t.Error("Expected the fallback for a context without logger")
+	}
+	if got := FromContext(nil, fallback); got != fallback {
+		t.Error("Expected the fallback for a nil context")
+	}
+}
+
+func TestRecentErrors(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWithWriter("info", &buf)
+	derived := l.WithPrefix("db").With("backend", "csv")
+
+	l.Info("not an error")
+	derived.Error("write failed", "error", "disk full")
+	for i := 0; i < RecentErrorsKept; i++ {
+		l.Error("later failure")
+	}
+
+	records := l.RecentErrors()
+	if len(records) != RecentErrorsKept {
+		t.Fatalf("Expected %d records, got %d", RecentErrorsKept, len(records))
+	}
+	// The oldest error was dropped once the ring was full
+	for _, record := range records {
+		if record.Message != "later failure" {
+			t.Errorf("Unexpected record %q", record.Message)
+		}
+	}
+
+	l = NewWithWriter("info", &buf)
+	l.WithPrefix("db").With("backend", "csv").Error("write failed", "error", "disk full")
+	records = l.RecentErrors()
+	if len(records) != 1 || records[0].Message != "[db] write failed backend=csv error=disk full" {
+		t.Errorf("Expected the error of a derived logger, got %+v", records)
+	}
+}
+
+func TestMaskEmails(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWithWriter("info", &buf)
+	derived := l.With("user_id", 42)
+
+	l.SetMaskEmails(true)
+	derived.Info("Checking email status", "email", "john.smith@gmail.com", "target_email", "ab@example.com", "bar", "Rooftop")
+	got := buf.String()
+	for _, want := range []string{"email=j***h@gmail.com", "target_email=a***@example.com", "bar=Rooftop"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in %q", want, got)
+		}
+	}
+	if strings.Contains(got, "john.smith") {
+		t.Errorf("Expected the email to be masked, got %q", got)
+	}
+
+	buf.Reset()
+	l.SetMaskEmails(false)
+	derived.Info("Checking email status", "email", "john.smith@gmail.com")
+	if !strings.Contains(buf.String(), "email=john.smith@gmail.com") {
+		t.Errorf("Expected the full email without masking, got %q", buf.String())
+	}
+}
+
+func TestMaskEmailsInValues(t *testing.T) {
+	errNotFound := errors.New("user not found")
+	tests := []struct {
+		name string
+		msg  string
+		args []any
+		want string
+	}{
+		{"wrapped error", "Lookup failed", []any{"error", fmt.Errorf("lookup john.smith@gmail.com: %w", errNotFound)},
+			"Lookup failed error=lookup j***h@gmail.com: user not found"},
+		{"slice", "Imported", []any{"added", []string{"ann@example.com", "bob.b@example.co.uk"}},
+			"Imported added=[a***n@example.com b***b@example.co.uk]"},
+		{"message", "Sent receipt to john.smith@gmail.com", nil,
+			"Sent receipt to j***h@gmail.com"},
+		{"other key", "Merged", []any{"keep", "ann@example.com", "count", 2},
+			"Merged keep=a***n@example.com count=2"},
+		{"no email", "Failed", []any{"error", errors.New("host@ unreachable")},
+			"Failed error=host@ unreachable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := NewWithWriter("info", &buf)
+			l.SetMaskEmails(true)
+			l.Info(tt.msg, tt.args...)
+			if got := strings.TrimSpace(buf.String()); !strings.HasSuffix(got, "INFO  "+tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
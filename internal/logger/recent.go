@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// RecentErrorsKept is the number of error messages kept for RecentErrors
+const RecentErrorsKept = 10
+
+// ErrorRecord is an error or fatal message that was logged
+type ErrorRecord struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"` // Prefix, message and key-value pairs as logged
+}
+
+// recentErrors keeps the last error messages in a ring
+type recentErrors struct {
+	mu      sync.Mutex
+	records []ErrorRecord
+	next    int // Slot overwritten by the next record once the ring is full
+}
+
+// newRecentErrors creates an empty ring of error messages
+func newRecentErrors() *recentErrors {
+	return &recentErrors{records: make([]ErrorRecord, 0, RecentErrorsKept)}
+}
+
+// add records a message, dropping the oldest one if the ring is full
+func (r *recentErrors) add(record ErrorRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.records) < cap(r.records) {
+		r.records = append(r.records, record)
+		return
+	}
+	r.records[r.next] = record
+	r.next = (r.next + 1) % len(r.records)
+}
+
+// list returns the recorded messages, oldest first
+func (r *recentErrors) list() []ErrorRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	records := make([]ErrorRecord, 0, len(r.records))
+	records = append(records, r.records[r.next:]...)
+	return append(records, r.records[:r.next]...)
+}
+
+// RecentErrors returns the last error and fatal messages logged by this
+// logger and all loggers derived from it, oldest first.
+func (l *Logger) RecentErrors() []ErrorRecord {
+	return l.recent.list()
+}
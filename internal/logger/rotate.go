@@ -0,0 +1,256 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat names rotated files so that they sort by age
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotationConfig controls when a log file is rotated and how long
+// rotated files are kept. Zero values disable the respective limit.
+type RotationConfig struct {
+	MaxSizeMB  int  // Rotate once the file reaches this size
+	MaxAgeDays int  // Delete rotated files older than this
+	MaxBackups int  // Keep at most this many rotated files
+	Compress   bool // Gzip rotated files
+	Daily      bool // Rotate when the first line of a new day is written
+}
+
+// RotatingFile is a log file that rotates itself, so that logs cannot
+// fill the disk on hosts without logrotate
+type RotatingFile struct {
+	mu     sync.Mutex
+	path   string
+	cfg    RotationConfig
+	file   *os.File
+	size   int64
+	opened time.Time // When the current file was opened, for daily rotation
+	now    func() time.Time
+
+	cleanMu sync.Mutex     // Held while rotated files are compressed and removed
+	cleanup sync.WaitGroup // Cleanups in progress
+}
+
+// NewRotatingFile opens the log file for appending, creating it and its
+// directory if needed
+func NewRotatingFile(path string, cfg RotationConfig) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, cfg: cfg, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the log file and records its size
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.opened = f.now()
+	return nil
+}
+
+// Write appends to the log file, rotating it first if the write would
+// exceed the size limit or a new day started
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// shouldRotate returns true if the current file must be rotated before
+// writing the given number of bytes
+func (f *RotatingFile) shouldRotate(n int64) bool {
+	if f.size == 0 {
+		return false // Never rotate an empty file
+	}
+	if f.cfg.MaxSizeMB > 0 && f.size+n > int64(f.cfg.MaxSizeMB)*1024*1024 {
+		return true
+	}
+	if f.cfg.Daily {
+		y1, m1, d1 := f.opened.Date()
+		y2, m2, d2 := f.now().Date()
+		return y1 != y2 || m1 != m2 || d1 != d2
+	}
+	return false
+}
+
+// rotate renames the current file to a timestamped backup and opens a new
+// one. Old backups are compressed and removed in the background.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	f.file = nil
+
+	ext := filepath.Ext(f.path)
+	backup := strings.TrimSuffix(f.path, ext) + "-" + f.now().Format(backupTimeFormat) + ext
+	if err := os.Rename(f.path, backup); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	now := f.now()
+	f.cleanup.Add(1)
+	go func() {
+		defer f.cleanup.Done()
+		if err := f.cleanBackups(now); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to clean up rotated logs: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// backups returns the rotated files of the log, oldest first
+func (f *RotatingFile) backups() ([]string, error) {
+	ext := filepath.Ext(f.path)
+	prefix := filepath.Base(strings.TrimSuffix(f.path, ext)) + "-"
+
+	entries, err := os.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		// Skip other files sharing the prefix, such as bot-errors.log next to bot.log
+		path := filepath.Join(filepath.Dir(f.path), name)
+		if !backupTime(f.path, path).IsZero() {
+			backups = append(backups, path)
+		}
+	}
+	sort.Strings(backups) // Timestamps in the names sort by age
+	return backups, nil
+}
+
+// cleanBackups removes backups beyond the count and age limits and
+// compresses the remaining ones
+func (f *RotatingFile) cleanBackups(now time.Time) error {
+	// Only one cleanup runs at a time, so files are not compressed twice
+	f.cleanMu.Lock()
+	defer f.cleanMu.Unlock()
+
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+
+	keep := backups
+	if f.cfg.MaxBackups > 0 && len(keep) > f.cfg.MaxBackups {
+		keep = keep[len(keep)-f.cfg.MaxBackups:]
+	}
+
+	cutoff := now.AddDate(0, 0, -f.cfg.MaxAgeDays)
+	for _, backup := range backups {
+		expired := f.cfg.MaxAgeDays > 0 && backupTime(f.path, backup).Before(cutoff)
+		if !slices.Contains(keep, backup) || expired {
+			if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if f.cfg.Compress && !strings.HasSuffix(backup, ".gz") {
+			if err := compressFile(backup); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// backupTime returns when a backup was rotated, read from its name
+func backupTime(path, backup string) time.Time {
+	ext := filepath.Ext(path)
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(backup), ".gz"), ext)
+	stamp := strings.TrimPrefix(name, filepath.Base(strings.TrimSuffix(path, ext))+"-")
+	t, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// compressFile gzips a file and removes the original
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz.tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".gz.tmp", path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Close closes the log file and waits for background cleanup to finish
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.cleanup.Wait()
+	return err
+}
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bot.log")
+	if err := os.WriteFile(filepath.Join(dir, "bot-errors.log"), []byte("unrelated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewRotatingFile(path, RotationConfig{MaxSizeMB: 1, MaxBackups: 2, Compress: true})
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+
+	// Each write of half a megabyte after the first fills a file
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+	f.now = func() time.Time { return now }
+	line := []byte(strings.Repeat("x", 512*1024-1) + "\n")
+	for range 8 {
+		now = now.Add(time.Second)
+		if _, err := f.Write(line); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	backups, err := filepath.Glob(filepath.Join(dir, "bot-2025-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got %v", backups)
+	}
+	for _, backup := range backups {
+		if !strings.HasSuffix(backup, ".log.gz") {
+			t.Errorf("Expected compressed backup, got %s", backup)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bot-errors.log")); err != nil {
+		t.Errorf("Expected unrelated file to be kept: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Size() != 2*int64(len(line)) {
+		t.Errorf("Expected the current file to hold the last two writes, got %v", info)
+	}
+}
+
+func TestRotatingFileDailyAndAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bot.log")
+
+	f, err := NewRotatingFile(path, RotationConfig{MaxAgeDays: 2, Daily: true})
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+	f.now = func() time.Time { return now }
+	f.opened = now
+
+	// One rotation per day; backups rotated more than two days before the
+	// last rotation are removed
+	for range 5 {
+		if _, err := f.Write([]byte("line\n")); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		now = now.AddDate(0, 0, 1)
+	}
+	f.Close()
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "bot-*.log"))
+	if len(backups) != 3 || !strings.Contains(backups[0], "2025-06-03") {
+		t.Errorf("Expected backups rotated from June 3 on, got %v", backups)
+	}
+}
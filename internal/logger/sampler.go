@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// Default sampling of loggers returned by Sampled
+const (
+	DefaultSampleFirst    = 5
+	DefaultSampleInterval = time.Minute
+)
+
+// sampler decides which messages of sampled loggers are logged
+type sampler struct {
+	mu       sync.Mutex
+	first    int
+	interval time.Duration
+	keys     map[string]*sampleState
+}
+
+// sampleState tracks the messages logged with one key
+type sampleState struct {
+	count      int       // Messages seen since the key was last quiet
+	lastSeen   time.Time // Last message, logged or not
+	lastLogged time.Time
+	suppressed int // Messages dropped since the last one logged
+}
+
+// newSampler creates a sampler passing the first messages of each key,
+// then one per interval
+func newSampler(first int, interval time.Duration) *sampler {
+	return &sampler{
+		first:    first,
+		interval: interval,
+		keys:     make(map[string]*sampleState),
+	}
+}
+
+// configure changes the sampling of all keys
+func (s *sampler) configure(first int, interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.first = first
+	s.interval = interval
+}
+
+// allow returns true if a message with the key should be logged, along
+// with the number of messages suppressed since the last one logged
+func (s *sampler) allow(key string, now time.Time) (bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Sampling is disabled without an interval
+	if s.interval <= 0 {
+		return true, 0
+	}
+
+	state, ok := s.keys[key]
+	if !ok {
+		state = &sampleState{}
+		s.keys[key] = state
+	}
+	if now.Sub(state.lastSeen) >= s.interval {
+		// The key was quiet for an interval, so start over
+		state.count = 0
+	}
+	state.count++
+	state.lastSeen = now
+
+	if state.count <= s.first || now.Sub(state.lastLogged) >= s.interval {
+		suppressed := state.suppressed
+		state.suppressed = 0
+		state.lastLogged = now
+		return true, suppressed
+	}
+
+	state.suppressed++
+	return false, 0
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSamplerAllow(t *testing.T) {
+	s := newSampler(2, time.Minute)
+	start := time.Now()
+
+	// The first messages pass, then one per interval
+	steps := []struct {
+		offset     time.Duration
+		allowed    bool
+		suppressed int
+	}{
+		{0, true, 0},
+		{time.Second, true, 0},
+		{2 * time.Second, false, 0},
+		{3 * time.Second, false, 0},
+		{61 * time.Second, true, 2},
+		{62 * time.Second, false, 0},
+		// After a quiet interval the first messages pass again
+		{200 * time.Second, true, 1},
+		{201 * time.Second, true, 0},
+		{202 * time.Second, false, 0},
+	}
+	for i, step := range steps {
+		allowed, suppressed := s.allow("db", start.Add(step.offset))
+		if allowed != step.allowed || suppressed != step.suppressed {
+			t.Errorf("Step %d: expected (%v, %d), got (%v, %d)", i, step.allowed, step.suppressed, allowed, suppressed)
+		}
+	}
+
+	// Keys are sampled independently
+	if allowed, _ := s.allow("other", start.Add(3*time.Second)); !allowed {
+		t.Error("Expected a new key to be allowed")
+	}
+}
+
+func TestSampledLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithWriter("info", &buf)
+	logger.DisableTimestamp()
+	logger.SetSampling(3, time.Hour)
+
+	sampled := logger.Sampled("backend")
+	for range 10 {
+		sampled.Error("backend down")
+	}
+	logger.Error("not sampled")
+
+	if count := strings.Count(buf.String(), "backend down"); count != 3 {
+		t.Errorf("Expected 3 sampled messages, got %d", count)
+	}
+	if !strings.Contains(buf.String(), "not sampled") {
+		t.Error("Expected messages of the parent logger not to be sampled")
+	}
+
+	// Sampling is disabled without an interval
+	buf.Reset()
+	logger.SetSampling(3, 0)
+	for range 5 {
+		sampled.Error("backend down")
+	}
+	if count := strings.Count(buf.String(), "backend down"); count != 5 {
+		t.Errorf("Expected all messages without sampling, got %d", count)
+	}
+}
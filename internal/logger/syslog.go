@@ -0,0 +1,40 @@
+//go:build !windows && !plan9
+
+package logger
+
+import "log/syslog"
+
+// syslogWriter sends log lines to the local syslog daemon
+type syslogWriter struct {
+	w *syslog.Writer
+}
+
+// dialSyslog connects to the local syslog daemon
+func dialSyslog(tag string) (levelWriter, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogWriter{w: w}, nil
+}
+
+// WriteLevel writes a line with the syslog severity of its level
+func (s *syslogWriter) WriteLevel(level Level, line string) error {
+	switch level {
+	case DebugLevel:
+		return s.w.Debug(line)
+	case InfoLevel:
+		return s.w.Info(line)
+	case WarnLevel:
+		return s.w.Warning(line)
+	case ErrorLevel:
+		return s.w.Err(line)
+	default:
+		return s.w.Crit(line)
+	}
+}
+
+// Close closes the connection to syslog
+func (s *syslogWriter) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,10 @@
+//go:build windows || plan9
+
+package logger
+
+import "errors"
+
+// dialSyslog fails because syslog is not available on this platform
+func dialSyslog(tag string) (levelWriter, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,68 @@
+// Package messenger abstracts the chat channel guests use to check and
+// redeem their email, so a deployment can pick Telegram, WhatsApp or Discord.
+package messenger
+
+import (
+	"fmt"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/discord"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+	"github.com/ceesaxp/cocktail-bot/internal/telegram"
+	"github.com/ceesaxp/cocktail-bot/internal/whatsapp"
+)
+
+// Supported channels
+const (
+	ChannelTelegram = "telegram"
+	ChannelWhatsApp = "whatsapp"
+	ChannelDiscord  = "discord"
+)
+
+// Messenger is a chat channel serving the guest flows
+type Messenger interface {
+	// Start begins receiving messages in the background
+	Start() error
+	// Stop stops receiving messages and waits for handlers to finish
+	Stop()
+}
+
+// Reloader is a messenger that applies a reloaded configuration while
+// running, such as its command menu
+type Reloader interface {
+	Reload(cfg *config.Config) error
+}
+
+var (
+	_ Reloader  = (*telegram.Bot)(nil)
+	_ Messenger = (*telegram.Bot)(nil)
+	_ Messenger = (*whatsapp.Bot)(nil)
+	_ Messenger = (*discord.Bot)(nil)
+)
+
+// New creates the messenger for the channel selected in the configuration
+func New(cfg *config.Config, svc *service.Service, logger *logger.Logger) (Messenger, error) {
+	switch cfg.Channel {
+	case ChannelTelegram, "":
+		bot, err := telegram.NewFromToken(cfg.Telegram.Token, svc, logger, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return bot, nil
+	case ChannelWhatsApp:
+		bot, err := whatsapp.NewFromConfig(cfg, svc, logger)
+		if err != nil {
+			return nil, err
+		}
+		return bot, nil
+	case ChannelDiscord:
+		bot, err := discord.NewFromConfig(cfg, svc, logger)
+		if err != nil {
+			return nil, err
+		}
+		return bot, nil
+	default:
+		return nil, fmt.Errorf("unsupported messaging channel: %s", cfg.Channel)
+	}
+}
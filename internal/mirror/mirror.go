@@ -0,0 +1,129 @@
+// Package mirror copies the guest list to a Google Sheet for reporting
+package mirror
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+)
+
+// UserSource provides the guest list
+type UserSource interface {
+	GenerateReport(ctx context.Context, reportType string, fromDate, toDate time.Time, filter domain.ReportFilter) ([]*domain.User, error)
+}
+
+// Target is the sheet the guest list is written to
+type Target interface {
+	Mirror(ctx any, users []*domain.User) (repository.MirrorResult, error)
+}
+
+// Syncer periodically writes the guest list to the target
+type Syncer struct {
+	cfg    config.SheetsMirrorConfig
+	source UserSource
+	target Target
+	logger *logger.Logger
+
+	mu          sync.Mutex // Serializes sync runs
+	fingerprint [sha256.Size]byte
+	synced      bool // Whether fingerprint holds the list of a successful run
+	stopCh      chan struct{}
+	waitGroup   sync.WaitGroup
+}
+
+// NewSyncer creates a syncer writing to the configured sheet
+func NewSyncer(cfg config.SheetsMirrorConfig, source UserSource, target Target, logger *logger.Logger) (*Syncer, error) {
+	if source == nil || target == nil {
+		return nil, errors.New("user source and target are required")
+	}
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+
+	return &Syncer{
+		cfg:    cfg,
+		source: source,
+		target: target,
+		logger: logger,
+		stopCh: make(chan struct{}),
+	}, nil
+}
+
+// SyncOnce writes the guest list to the target. The write is skipped if the
+// list has not changed since the last successful run.
+func (s *Syncer) SyncOnce(ctx context.Context) (repository.MirrorResult, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The epoch rather than a zero time, which reports treat as the last week
+	users, err := s.source.GenerateReport(ctx, string(domain.ReportTypeAll), time.Unix(0, 0), time.Now(), domain.ReportFilter{})
+	if err != nil {
+		return repository.MirrorResult{}, false, fmt.Errorf("failed to read guest list: %w", err)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+
+	data, err := json.Marshal(users)
+	if err != nil {
+		return repository.MirrorResult{}, false, err
+	}
+	fingerprint := sha256.Sum256(data)
+	if s.synced && fingerprint == s.fingerprint {
+		s.logger.Debug("Guest list unchanged, skipping sheet mirror", "users", len(users))
+		return repository.MirrorResult{}, false, nil
+	}
+
+	result, err := s.target.Mirror(ctx, users)
+	if err != nil {
+		return result, true, fmt.Errorf("failed to write sheet: %w", err)
+	}
+	s.fingerprint, s.synced = fingerprint, true
+
+	s.logger.Info("Sheet mirror updated", "added", result.Added, "updated", result.Updated, "unchanged", result.Unchanged)
+	return result, true, nil
+}
+
+// Start runs a sync immediately and then on the configured interval
+func (s *Syncer) Start() {
+	interval := time.Duration(s.cfg.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+
+	s.waitGroup.Add(1)
+	go func() {
+		defer s.waitGroup.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, _, err := s.SyncOnce(context.Background()); err != nil {
+				s.logger.Error("Sheet mirror failed", "error", err)
+			}
+
+			select {
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	s.logger.Info("Sheet mirror started", "interval", interval)
+}
+
+// Stop waits for a running sync to finish and stops the schedule
+func (s *Syncer) Stop() {
+	close(s.stopCh)
+	s.waitGroup.Wait()
+}
@@ -0,0 +1,78 @@
+package mirror_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/mirror"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+)
+
+// listSource returns a fixed guest list
+type listSource struct {
+	users []*domain.User
+}
+
+func (s *listSource) GenerateReport(ctx context.Context, reportType string, fromDate, toDate time.Time, filter domain.ReportFilter) ([]*domain.User, error) {
+	return s.users, nil
+}
+
+// recordingTarget counts writes and can simulate an outage
+type recordingTarget struct {
+	writes int
+	down   bool
+}
+
+func (t *recordingTarget) Mirror(ctx any, users []*domain.User) (repository.MirrorResult, error) {
+	if t.down {
+		return repository.MirrorResult{}, errors.New("sheet unavailable")
+	}
+	t.writes++
+	return repository.MirrorResult{Added: len(users)}, nil
+}
+
+func TestSyncer(t *testing.T) {
+	source := &listSource{users: []*domain.User{
+		{ID: "1", Email: "guest1@example.com", DateAdded: time.Now()},
+	}}
+	target := &recordingTarget{}
+
+	syncer, err := mirror.NewSyncer(config.SheetsMirrorConfig{}, source, target, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create syncer: %v", err)
+	}
+	ctx := context.Background()
+
+	result, written, err := syncer.SyncOnce(ctx)
+	if err != nil || !written || result.Added != 1 {
+		t.Fatalf("Expected the list to be written, got %+v, %v, %v", result, written, err)
+	}
+
+	// An unchanged list is not written again
+	if _, written, err := syncer.SyncOnce(ctx); err != nil || written || target.writes != 1 {
+		t.Errorf("Expected the write to be skipped, got %v, %v, %d writes", written, err, target.writes)
+	}
+
+	// A failed write is retried on the next run
+	now := time.Now()
+	source.users[0].Redeemed = &now
+	target.down = true
+	if _, _, err := syncer.SyncOnce(ctx); err == nil {
+		t.Fatal("Expected the write to fail")
+	}
+	target.down = false
+	if _, written, err := syncer.SyncOnce(ctx); err != nil || !written || target.writes != 2 {
+		t.Errorf("Expected the change to be written, got %v, %v, %d writes", written, err, target.writes)
+	}
+}
+
+func TestNewSyncer_RequiresSourceAndTarget(t *testing.T) {
+	if _, err := mirror.NewSyncer(config.SheetsMirrorConfig{}, nil, &recordingTarget{}, logger.New("error")); err == nil {
+		t.Error("Expected an error without a source")
+	}
+}
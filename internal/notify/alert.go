@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/httpclient"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// Alerter notifies staff about problems that need attention
+type Alerter interface {
+	Alert(ctx context.Context, text string) error
+}
+
+// SlackAlerter posts alerts to a Slack incoming webhook
+type SlackAlerter struct {
+	webhookURL string
+	client     *http.Client
+	logger     *logger.Logger
+}
+
+// NewSlackAlerter creates an alerter for the given webhook URL, posting
+// with httpClient. Alerts are better sent twice than lost, so the client
+// may retry them.
+func NewSlackAlerter(webhookURL string, httpClient *http.Client, logger *logger.Logger) *SlackAlerter {
+	if httpClient == nil {
+		httpClient = httpclient.New(httpclient.Options{Name: "slack", Timeout: 10 * time.Second, RetryPost: true})
+	}
+	return &SlackAlerter{
+		webhookURL: webhookURL,
+		client:     httpClient,
+		logger:     logger,
+	}
+}
+
+// Alert posts the text to the webhook
+func (a *SlackAlerter) Alert(ctx context.Context, text string) error {
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create Slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		a.logger.Error("Failed to send Slack alert", "error", err)
+		return fmt.Errorf("failed to send Slack alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		a.logger.Error("Slack rejected alert", "status", resp.StatusCode)
+		return fmt.Errorf("slack returned status %d", resp.StatusCode)
+	}
+
+	a.logger.Debug("Slack alert sent")
+	return nil
+}
@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// Notifier sends messages to guests outside of Telegram
+type Notifier interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// New creates a notifier based on the notification configuration
+func New(cfg config.NotifyConfig, logger *logger.Logger) (Notifier, error) {
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+
+	notifyType := strings.ToLower(cfg.Type)
+	switch notifyType {
+	case "", "log":
+		return NewLogNotifier(logger), nil
+	case "smtp":
+		return NewSMTPNotifier(cfg, logger)
+	default:
+		return nil, fmt.Errorf("unsupported notification type: %s", notifyType)
+	}
+}
+
+// LogNotifier writes messages to the log instead of delivering them.
+// It is meant for development and testing.
+type LogNotifier struct {
+	logger *logger.Logger
+}
+
+// NewLogNotifier creates a notifier that only logs messages
+func NewLogNotifier(logger *logger.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+// Send logs the message
+func (n *LogNotifier) Send(ctx context.Context, to, subject, body string) error {
+	n.logger.Info("Notification", "to", to, "subject", subject, "body", body)
+	return nil
+}
+
+// SMTPNotifier delivers messages by email
+type SMTPNotifier struct {
+	addr   string
+	auth   smtp.Auth
+	from   string
+	logger *logger.Logger
+}
+
+// NewSMTPNotifier creates a notifier that sends email through an SMTP server
+func NewSMTPNotifier(cfg config.NotifyConfig, logger *logger.Logger) (*SMTPNotifier, error) {
+	if cfg.SMTPHost == "" {
+		return nil, errors.New("SMTP host cannot be empty")
+	}
+	if cfg.From == "" {
+		return nil, errors.New("sender address cannot be empty")
+	}
+
+	var auth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+
+	logger.Info("SMTP notifier initialized", "host", cfg.SMTPHost, "port", cfg.SMTPPort)
+	return &SMTPNotifier{
+		addr:   fmt.Sprintf("%s:%d", cfg.SMTPHost, cfg.SMTPPort),
+		auth:   auth,
+		from:   cfg.From,
+		logger: logger,
+	}, nil
+}
+
+// Send delivers a plain text email
+func (n *SMTPNotifier) Send(ctx context.Context, to, subject, body string) error {
+	msg := "From: " + n.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body + "\r\n"
+
+	if err := smtp.SendMail(n.addr, n.auth, n.from, []string{to}, []byte(msg)); err != nil {
+		n.logger.Error("Failed to send email", "to", to, "error", err)
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	n.logger.Debug("Email sent", "to", to, "subject", subject)
+	return nil
+}
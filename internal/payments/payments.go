@@ -0,0 +1,228 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// Purchase states
+const (
+	StatusPending = "pending"
+	StatusPaid    = "paid"
+)
+
+// CheckoutCreator creates hosted checkout pages
+type CheckoutCreator interface {
+	CreateCheckoutSession(ctx context.Context, req CheckoutRequest) (*CheckoutSession, error)
+}
+
+// Manager sells extra drinks and keeps track of the purchases
+type Manager struct {
+	cfg      config.PaymentsConfig
+	checkout CheckoutCreator
+	logger   *logger.Logger
+
+	mu        sync.Mutex
+	purchases map[string]*domain.Purchase // Map of checkout session ID -> purchase
+}
+
+// New creates a manager that sells through Stripe Checkout, calling Stripe
+// with httpClient
+func New(cfg config.PaymentsConfig, httpClient *http.Client, logger *logger.Logger) (*Manager, error) {
+	client, err := NewStripeClient("", cfg.StripeSecretKey, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return NewManager(cfg, client, logger)
+}
+
+// NewManager creates a manager with the given checkout provider
+func NewManager(cfg config.PaymentsConfig, checkout CheckoutCreator, logger *logger.Logger) (*Manager, error) {
+	if checkout == nil {
+		return nil, errors.New("checkout provider cannot be nil")
+	}
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+	if cfg.PriceID == "" {
+		return nil, errors.New("price ID cannot be empty")
+	}
+	if cfg.WebhookSecret == "" {
+		return nil, errors.New("webhook secret cannot be empty, payments could not be confirmed")
+	}
+
+	m := &Manager{
+		cfg:       cfg,
+		checkout:  checkout,
+		logger:    logger,
+		purchases: make(map[string]*domain.Purchase),
+	}
+	if cfg.PurchasesFile == "" {
+		logger.Warn("No purchases file configured, payments for checkouts started before a restart will be rejected")
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// load reads purchases saved by a previous run
+func (m *Manager) load() error {
+	if m.cfg.PurchasesFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(m.cfg.PurchasesFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read purchases file: %w", err)
+	}
+
+	var purchases []*domain.Purchase
+	if err := json.Unmarshal(data, &purchases); err != nil {
+		return fmt.Errorf("failed to parse purchases file: %w", err)
+	}
+	for _, purchase := range purchases {
+		m.purchases[purchase.SessionID] = purchase
+	}
+	return nil
+}
+
+// save writes all purchases to the file. The caller must hold mu.
+func (m *Manager) save() error {
+	if m.cfg.PurchasesFile == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(m.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.cfg.PurchasesFile), 0755); err != nil {
+		return err
+	}
+
+	tmp := m.cfg.PurchasesFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.cfg.PurchasesFile)
+}
+
+// sorted returns copies of all purchases, oldest first. The caller must hold mu.
+func (m *Manager) sorted() []domain.Purchase {
+	purchases := make([]domain.Purchase, 0, len(m.purchases))
+	for _, purchase := range m.purchases {
+		purchases = append(purchases, *purchase)
+	}
+	sort.Slice(purchases, func(i, j int) bool {
+		return purchases[i].CreatedAt.Before(purchases[j].CreatedAt)
+	})
+	return purchases
+}
+
+// Checkout creates a payment page for an extra drink and returns its URL
+func (m *Manager) Checkout(ctx context.Context, userID int64, email string) (string, error) {
+	session, err := m.checkout.CreateCheckoutSession(ctx, CheckoutRequest{
+		PriceID:    m.cfg.PriceID,
+		Email:      email,
+		UserID:     userID,
+		SuccessURL: m.cfg.SuccessURL,
+		CancelURL:  m.cfg.CancelURL,
+	})
+	if err != nil {
+		m.logger.Error("Failed to create checkout session", "email", email, "error", err)
+		return "", err
+	}
+
+	m.mu.Lock()
+	m.purchases[session.ID] = &domain.Purchase{
+		SessionID: session.ID,
+		Email:     email,
+		UserID:    userID,
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+	}
+	err = m.save()
+	m.mu.Unlock()
+	if err != nil {
+		m.logger.Error("Failed to save purchases", "error", err)
+	}
+
+	m.logger.Info("Checkout session created", "session_id", session.ID, "email", email, "user_id", userID)
+	return session.URL, nil
+}
+
+// HandleWebhook verifies a Stripe webhook and records completed payments
+func (m *Manager) HandleWebhook(payload []byte, signature string) error {
+	event, err := VerifyWebhook(payload, signature, m.cfg.WebhookSecret, time.Now())
+	if err != nil {
+		m.logger.Warn("Rejected payment webhook", "error", err)
+		return err
+	}
+
+	if event.Type != "checkout.session.completed" {
+		m.logger.Debug("Ignoring payment webhook", "type", event.Type)
+		return nil
+	}
+
+	var session CheckoutSession
+	if err := json.Unmarshal(event.Data.Object, &session); err != nil {
+		return fmt.Errorf("failed to parse checkout session: %w", err)
+	}
+	if session.PaymentStatus != "paid" {
+		m.logger.Info("Checkout completed without payment", "session_id", session.ID, "status", session.PaymentStatus)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Payments are only recorded for sessions the bot created, so a signed
+	// event of another integration on the same Stripe account cannot add
+	// purchases
+	purchase, ok := m.purchases[session.ID]
+	if !ok {
+		m.logger.Warn("Rejected payment for an unknown checkout session", "session_id", session.ID)
+		return fmt.Errorf("%w: %s", domain.ErrPurchaseNotFound, session.ID)
+	}
+
+	now := time.Now()
+	purchase.Status = StatusPaid
+	purchase.Amount = session.AmountTotal
+	purchase.Currency = session.Currency
+	purchase.PaidAt = &now
+
+	m.logger.Info("Drink purchase paid", "session_id", session.ID, "email", purchase.Email,
+		"amount", session.AmountTotal, "currency", session.Currency)
+	return m.save()
+}
+
+// Purchases returns the purchases started in the given period, oldest first
+func (m *Manager) Purchases(from, to time.Time) []domain.Purchase {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purchases []domain.Purchase
+	for _, purchase := range m.sorted() {
+		if purchase.CreatedAt.Before(from) || purchase.CreatedAt.After(to) {
+			continue
+		}
+		purchases = append(purchases, purchase)
+	}
+	return purchases
+}
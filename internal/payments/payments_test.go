@@ -0,0 +1,115 @@
+package payments_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/payments"
+)
+
+// fakeCheckout returns numbered sessions
+type fakeCheckout struct {
+	requests []payments.CheckoutRequest
+}
+
+func (f *fakeCheckout) CreateCheckoutSession(ctx context.Context, req payments.CheckoutRequest) (*payments.CheckoutSession, error) {
+	f.requests = append(f.requests, req)
+	id := fmt.Sprintf("cs_%d", len(f.requests))
+	return &payments.CheckoutSession{ID: id, URL: "https://checkout.example.com/" + id}, nil
+}
+
+// sign creates a Stripe-Signature header for the payload
+func sign(payload, secret string, at time.Time) string {
+	timestamp := fmt.Sprint(at.Unix())
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	payload := `{"id": "evt_1", "type": "checkout.session.completed"}`
+	now := time.Now()
+
+	event, err := payments.VerifyWebhook([]byte(payload), sign(payload, "whsec", now), "whsec", now)
+	if err != nil || event.ID != "evt_1" {
+		t.Fatalf("Expected valid event, got %+v (%v)", event, err)
+	}
+
+	if _, err := payments.VerifyWebhook([]byte(payload), sign(payload, "", now), "", now); !errors.Is(err, domain.ErrInvalidSignature) {
+		t.Errorf("Expected an empty secret to verify nothing, got %v", err)
+	}
+
+	tests := map[string]string{
+		"wrong secret": sign(payload, "other", now),
+		"too old":      sign(payload, "whsec", now.Add(-time.Hour)),
+		"missing":      "",
+	}
+	for name, header := range tests {
+		if _, err := payments.VerifyWebhook([]byte(payload), header, "whsec", now); !errors.Is(err, domain.ErrInvalidSignature) {
+			t.Errorf("%s: expected invalid signature, got %v", name, err)
+		}
+	}
+}
+
+func TestManager(t *testing.T) {
+	cfg := config.PaymentsConfig{
+		WebhookSecret: "whsec",
+		PriceID:       "price_1",
+		PurchasesFile: filepath.Join(t.TempDir(), "purchases.json"),
+	}
+	checkout := &fakeCheckout{}
+	manager, err := payments.NewManager(cfg, checkout, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	paymentURL, err := manager.Checkout(context.Background(), 42, "guest@example.com")
+	if err != nil || paymentURL != "https://checkout.example.com/cs_1" {
+		t.Fatalf("Unexpected checkout: %q (%v)", paymentURL, err)
+	}
+	if checkout.requests[0].PriceID != "price_1" || checkout.requests[0].UserID != 42 {
+		t.Errorf("Unexpected checkout request: %+v", checkout.requests[0])
+	}
+
+	// The webhook marks the purchase as paid
+	payload := `{"type": "checkout.session.completed", "data": {"object": {"id": "cs_1", "payment_status": "paid", "amount_total": 800, "currency": "eur"}}}`
+	if err := manager.HandleWebhook([]byte(payload), sign(payload, "whsec", time.Now())); err != nil {
+		t.Fatalf("Webhook failed: %v", err)
+	}
+
+	// Signed payments for sessions the bot did not create are rejected
+	payload = `{"type": "checkout.session.completed", "data": {"object": {"id": "cs_other", "payment_status": "paid", "amount_total": 800, "currency": "eur", "metadata": {"telegram_user_id": "7"}}}}`
+	if err := manager.HandleWebhook([]byte(payload), sign(payload, "whsec", time.Now())); !errors.Is(err, domain.ErrPurchaseNotFound) {
+		t.Errorf("Expected an unknown session to be rejected, got %v", err)
+	}
+
+	// Purchases survive a restart
+	manager, err = payments.NewManager(cfg, checkout, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to reload manager: %v", err)
+	}
+	purchases := manager.Purchases(time.Now().Add(-time.Hour), time.Now())
+	if len(purchases) != 1 {
+		t.Fatalf("Expected 1 purchase, got %d", len(purchases))
+	}
+	if p := purchases[0]; p.Status != payments.StatusPaid || p.Amount != 800 || p.Email != "guest@example.com" || p.PaidAt == nil {
+		t.Errorf("Unexpected purchase: %+v", p)
+	}
+}
+
+func TestNewManagerRequiresWebhookSecret(t *testing.T) {
+	cfg := config.PaymentsConfig{PriceID: "price_1"}
+	if _, err := payments.NewManager(cfg, &fakeCheckout{}, logger.New("error")); err == nil {
+		t.Error("Expected a manager without webhook secret to be refused")
+	}
+}
@@ -0,0 +1,176 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/httpclient"
+)
+
+// signatureTolerance is how old a webhook may be before it is rejected as a replay
+const signatureTolerance = 5 * time.Minute
+
+// CheckoutRequest describes the checkout session to create
+type CheckoutRequest struct {
+	PriceID    string
+	Email      string
+	UserID     int64
+	SuccessURL string
+	CancelURL  string
+}
+
+// CheckoutSession is a Stripe Checkout session
+type CheckoutSession struct {
+	ID                string            `json:"id"`
+	URL               string            `json:"url"`
+	PaymentStatus     string            `json:"payment_status"`
+	AmountTotal       int64             `json:"amount_total"`
+	Currency          string            `json:"currency"`
+	ClientReferenceID string            `json:"client_reference_id"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+// Event is a Stripe webhook event
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// StripeClient creates checkout sessions through the Stripe API
+type StripeClient struct {
+	baseURL    string
+	secretKey  string
+	httpClient *http.Client
+}
+
+// NewStripeClient creates a client using the secret API key
+func NewStripeClient(baseURL, secretKey string, httpClient *http.Client) (*StripeClient, error) {
+	if secretKey == "" {
+		return nil, errors.New("stripe secret key cannot be empty")
+	}
+	if baseURL == "" {
+		baseURL = "https://api.stripe.com"
+	}
+	if httpClient == nil {
+		httpClient = httpclient.New(httpclient.Options{Name: "stripe"})
+	}
+
+	return &StripeClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		secretKey:  secretKey,
+		httpClient: httpClient,
+	}, nil
+}
+
+// newIdempotencyKey returns a random key identifying one checkout call
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// CreateCheckoutSession creates a one-off payment for a single item
+func (c *StripeClient) CreateCheckoutSession(ctx context.Context, req CheckoutRequest) (*CheckoutSession, error) {
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("line_items[0][price]", req.PriceID)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("success_url", req.SuccessURL)
+	form.Set("cancel_url", req.CancelURL)
+	form.Set("customer_email", req.Email)
+	form.Set("client_reference_id", req.Email)
+	form.Set("metadata[telegram_user_id]", strconv.FormatInt(req.UserID, 10))
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/checkout/sessions", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.SetBasicAuth(c.secretKey, "")
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// Stripe creates one session for all attempts with the same key, so the
+	// client may retry the call
+	httpReq.Header.Set(httpclient.IdempotencyKeyHeader, newIdempotencyKey())
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkout session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("stripe returned status %d", resp.StatusCode)
+	}
+
+	var session CheckoutSession
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return nil, fmt.Errorf("failed to parse checkout session: %w", err)
+	}
+	return &session, nil
+}
+
+// VerifyWebhook checks the Stripe-Signature header of a webhook and returns the event
+func VerifyWebhook(payload []byte, header, secret string, now time.Time) (*Event, error) {
+	if secret == "" {
+		return nil, domain.ErrInvalidSignature // Anyone could sign with an empty secret
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return nil, domain.ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return nil, domain.ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	valid := false
+	for _, signature := range signatures {
+		if decoded, err := hex.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, domain.ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook event: %w", err)
+	}
+	return &event, nil
+}
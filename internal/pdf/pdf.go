@@ -0,0 +1,109 @@
+// Package pdf writes small single-page PDF documents such as redemption
+// tickets. It supports text in the standard Helvetica fonts, filled
+// rectangles and QR codes, which is all a ticket needs.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Points per millimeter
+const mm = 72 / 25.4
+
+// Document is a single-page PDF being built
+type Document struct {
+	width, height float64 // Page size in points
+	content       bytes.Buffer
+}
+
+// New creates a document with a page of the given size in points
+func New(width, height float64) *Document {
+	return &Document{width: width, height: height}
+}
+
+// Text draws text with its baseline starting at x, y, measured from the
+// bottom left corner. Characters outside Latin-1 are replaced by "?".
+func (d *Document) Text(x, y, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&d.content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, escapeText(text))
+}
+
+// Rect draws a filled black rectangle
+func (d *Document) Rect(x, y, width, height float64) {
+	fmt.Fprintf(&d.content, "%.2f %.2f %.2f %.2f re f\n", x, y, width, height)
+}
+
+// Line draws a thin horizontal line
+func (d *Document) Line(x, y, width float64) {
+	fmt.Fprintf(&d.content, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x, y, x+width, y)
+}
+
+// QR draws a QR code with its bottom left corner at x, y. The size
+// includes the four module quiet zone required around the symbol.
+func (d *Document) QR(x, y, size float64, code *QR) {
+	modules := code.Size() + 8
+	module := size / float64(modules)
+	for row := range code.Size() {
+		for col := range code.Size() {
+			if code.Dark(col, row) {
+				d.Rect(x+float64(col+4)*module, y+size-float64(row+5)*module, module, module)
+			}
+		}
+	}
+}
+
+// Bytes returns the encoded document
+func (d *Document) Bytes() []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R >>", d.width, d.height),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", d.content.Len(), d.content.String()),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+// escapeText encodes text as the body of a PDF string in WinAnsi encoding
+func escapeText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20:
+			b.WriteByte(' ')
+		case r < 0x80:
+			b.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
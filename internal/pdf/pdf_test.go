@@ -0,0 +1,153 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// "HELLO WORLD" at version 1-M from the QR code specification walkthrough
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	expected := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	if ec := rsRemainder(data, rsDivisor(10)); !bytes.Equal(ec, expected) {
+		t.Errorf("Expected error correction %v, got %v", expected, ec)
+	}
+}
+
+func TestQRFormatAndVersion(t *testing.T) {
+	q := newQR(7)
+	q.drawFunctionPatterns(7, qrVersions[6].alignment)
+	q.drawFormatBits(0)
+
+	// Level M with mask 0 is 101010000010010, read along row 8 from the left
+	var format strings.Builder
+	for x := 0; x <= 8; x++ {
+		if x == 6 {
+			continue // Timing pattern
+		}
+		format.WriteString(map[bool]string{true: "1", false: "0"}[q.Dark(x, 8)])
+	}
+	if got := format.String(); got != "10101000" {
+		t.Errorf("Expected format bits 10101000 in row 8, got %s", got)
+	}
+
+	// Version 7 information is 000111110010010100, least significant bit first
+	bits := 0
+	for i := range 18 {
+		if q.Dark(i/3, q.size-11+i%3) {
+			bits |= 1 << i
+		}
+	}
+	if bits != 0x07C94 {
+		t.Errorf("Expected version bits %018b, got %018b", 0x07C94, bits)
+	}
+}
+
+func TestQRRoundTrip(t *testing.T) {
+	for _, length := range []int{5, 60, 150, 200} {
+		data := []byte(strings.Repeat("cocktail-", length/9+1)[:length])
+		q, err := NewQR(data)
+		if err != nil {
+			t.Fatalf("Failed to encode %d bytes: %v", length, err)
+		}
+		version := (q.Size() - 17) / 4
+
+		// Read the mask from the format bits
+		mask := -1
+		for m := range 8 {
+			check := newQR(version)
+			check.drawFormatBits(m)
+			if check.Dark(8, 0) == q.Dark(8, 0) && check.Dark(8, 1) == q.Dark(8, 1) && check.Dark(8, 2) == q.Dark(8, 2) &&
+				check.Dark(8, 3) == q.Dark(8, 3) && check.Dark(8, 4) == q.Dark(8, 4) && check.Dark(8, 5) == q.Dark(8, 5) &&
+				check.Dark(8, 7) == q.Dark(8, 7) && check.Dark(8, 8) == q.Dark(8, 8) {
+				mask = m
+			}
+		}
+		if mask < 0 {
+			t.Fatalf("Version %d: no mask matches the format bits", version)
+		}
+
+		// Unmask and read back the codewords in placement order
+		q.applyMask(mask)
+		var codewords []byte
+		var current byte
+		n := 0
+		for right := q.size - 1; right >= 1; right -= 2 {
+			if right == 6 {
+				right = 5
+			}
+			upward := (right+1)&2 == 0
+			for vert := range q.size {
+				for j := range 2 {
+					x, y := right-j, vert
+					if upward {
+						y = q.size - 1 - vert
+					}
+					if q.function[y][x] {
+						continue
+					}
+					current = current<<1 | map[bool]byte{true: 1}[q.modules[y][x]]
+					if n++; n%8 == 0 {
+						codewords = append(codewords, current)
+					}
+				}
+			}
+		}
+
+		v := qrVersions[version-1]
+		capacity := 0
+		for _, blockLen := range v.blocks {
+			capacity += blockLen
+		}
+		countBits := 8
+		if version >= 10 {
+			countBits = 16
+		}
+		expected := interleave(encodeData(data, countBits, capacity), v)
+		if !bytes.Equal(codewords[:len(expected)], expected) {
+			t.Errorf("Version %d: codewords do not round trip", version)
+		}
+	}
+
+	if _, err := NewQR(make([]byte, 300)); err != ErrQRTooLong {
+		t.Errorf("Expected ErrQRTooLong, got %v", err)
+	}
+}
+
+func TestRenderTicket(t *testing.T) {
+	data, err := RenderTicket(Ticket{
+		ID:         "a1b2c3d4",
+		EventName:  "Launch (Party) Zürich",
+		EmailHash:  "3f2a9c",
+		RedeemedAt: time.Date(2025, 6, 1, 21, 15, 0, 0, time.UTC),
+		Record:     "redemption a1b2c3d4",
+	})
+	if err != nil {
+		t.Fatalf("Failed to render ticket: %v", err)
+	}
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("Not a PDF document")
+	}
+	if !bytes.Contains(data, []byte(`(Launch \(Party\) Z\374rich)`)) {
+		t.Errorf("Event name not escaped")
+	}
+
+	// Every cross reference entry points at its object
+	xref := regexp.MustCompile(`(?m)^(\d{10}) 00000 n $`).FindAllSubmatch(data, -1)
+	if len(xref) != 6 {
+		t.Fatalf("Expected 6 objects in the cross reference table, got %d", len(xref))
+	}
+	for i, entry := range xref {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if !bytes.HasPrefix(data[offset:], []byte(fmt.Sprintf("%d 0 obj", i+1))) {
+			t.Errorf("Cross reference of object %d points to the wrong offset", i+1)
+		}
+	}
+}
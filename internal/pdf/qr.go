@@ -0,0 +1,451 @@
+package pdf
+
+import (
+	"errors"
+)
+
+// ErrQRTooLong is returned when data does not fit in the largest supported QR version
+var ErrQRTooLong = errors.New("data too long for QR code")
+
+// qrVersion describes the error correction blocks of a QR version at level M
+type qrVersion struct {
+	ecPerBlock int   // Error correction codewords per block
+	blocks     []int // Data codewords of each block
+	alignment  []int // Alignment pattern centers
+	remainder  int   // Remainder bits after the last codeword
+}
+
+// qrVersions holds versions 1 to 10 at error correction level M
+var qrVersions = []qrVersion{
+	{10, []int{16}, nil, 0},
+	{16, []int{28}, []int{6, 18}, 7},
+	{26, []int{44}, []int{6, 22}, 7},
+	{18, []int{32, 32}, []int{6, 26}, 7},
+	{24, []int{43, 43}, []int{6, 30}, 7},
+	{16, []int{27, 27, 27, 27}, []int{6, 34}, 7},
+	{18, []int{31, 31, 31, 31}, []int{6, 22, 38}, 0},
+	{22, []int{38, 38, 39, 39}, []int{6, 24, 42}, 0},
+	{22, []int{36, 36, 36, 37, 37}, []int{6, 26, 46}, 0},
+	{26, []int{43, 43, 43, 43, 44}, []int{6, 28, 50}, 0},
+}
+
+// QR is a QR code symbol at error correction level M
+type QR struct {
+	size     int
+	modules  [][]bool // Dark modules, indexed [y][x]
+	function [][]bool // Modules belonging to function patterns
+}
+
+// NewQR encodes data in byte mode using the smallest version that fits
+func NewQR(data []byte) (*QR, error) {
+	for i, v := range qrVersions {
+		version := i + 1
+		capacity := 0
+		for _, n := range v.blocks {
+			capacity += n
+		}
+
+		countBits := 8
+		if version >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) > capacity*8 {
+			continue
+		}
+
+		codewords := encodeData(data, countBits, capacity)
+		q := newQR(version)
+		q.drawFunctionPatterns(version, v.alignment)
+		q.drawCodewords(interleave(codewords, v))
+		q.applyBestMask()
+		return q, nil
+	}
+	return nil, ErrQRTooLong
+}
+
+// Size returns the number of modules per side
+func (q *QR) Size() int {
+	return q.size
+}
+
+// Dark reports whether the module at column x and row y is dark
+func (q *QR) Dark(x, y int) bool {
+	return q.modules[y][x]
+}
+
+// newQR creates an empty symbol of the version
+func newQR(version int) *QR {
+	size := version*4 + 17
+	q := &QR{size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for y := range size {
+		q.modules[y] = make([]bool, size)
+		q.function[y] = make([]bool, size)
+	}
+	return q
+}
+
+// encodeData builds the data codewords: mode, length, data, terminator and padding
+func encodeData(data []byte, countBits, capacity int) []byte {
+	var bits bitBuffer
+	bits.append(0x4, 4) // Byte mode
+	bits.append(len(data), countBits)
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	capacityBits := capacity * 8
+	bits.append(0, min(4, capacityBits-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacityBits; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	codewords := make([]byte, capacity)
+	for i, bit := range bits {
+		if bit {
+			codewords[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return codewords
+}
+
+// bitBuffer is a sequence of bits
+type bitBuffer []bool
+
+// append adds the n low bits of value, most significant first
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 == 1)
+	}
+}
+
+// interleave splits the data into blocks, adds error correction and
+// interleaves the blocks as they are placed in the symbol
+func interleave(data []byte, v qrVersion) []byte {
+	divisor := rsDivisor(v.ecPerBlock)
+
+	var dataBlocks, ecBlocks [][]byte
+	offset := 0
+	for _, n := range v.blocks {
+		block := data[offset : offset+n]
+		offset += n
+		dataBlocks = append(dataBlocks, block)
+		ecBlocks = append(ecBlocks, rsRemainder(block, divisor))
+	}
+
+	var result []byte
+	for i := 0; i < v.blocks[len(v.blocks)-1]; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < v.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// gfMul multiplies in GF(2^8) with the QR code polynomial
+func gfMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of the degree
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder computes the error correction codewords of the data
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMul(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+// setFunction sets a function pattern module
+func (q *QR) setFunction(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+// drawFunctionPatterns draws finders, timing and alignment patterns and
+// reserves the format and version areas
+func (q *QR) drawFunctionPatterns(version int, alignment []int) {
+	for i := range q.size {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+
+	q.drawFinder(3, 3)
+	q.drawFinder(q.size-4, 3)
+	q.drawFinder(3, q.size-4)
+
+	last := len(alignment) - 1
+	for i, cy := range alignment {
+		for j, cx := range alignment {
+			// Skip the corners taken by finder patterns
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	q.drawFormatBits(0)
+	q.drawVersion(version)
+}
+
+// drawFinder draws a finder pattern and its separator around the center
+func (q *QR) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= q.size || y < 0 || y >= q.size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			q.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawFormatBits draws both copies of the error correction level and mask
+func (q *QR) drawFormatBits(mask int) {
+	data := mask // Level M is 00
+	rem := data
+	for range 10 {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(i))
+	}
+	q.setFunction(8, 7, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		q.setFunction(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, q.size-15+i, bit(i))
+	}
+	q.setFunction(8, q.size-8, true) // Always dark
+}
+
+// drawVersion draws both copies of the version information, used from version 7
+func (q *QR) drawVersion(version int) {
+	if version < 7 {
+		return
+	}
+
+	rem := version
+	for range 12 {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := version<<12 | rem
+
+	for i := range 18 {
+		dark := (bits>>i)&1 == 1
+		a, b := q.size-11+i%3, i/3
+		q.setFunction(a, b, dark)
+		q.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the zigzag order, skipping function patterns
+func (q *QR) drawCodewords(codewords []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range q.size {
+			for j := range 2 {
+				x := right - j
+				y := vert
+				if upward {
+					y = q.size - 1 - vert
+				}
+				if q.function[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				q.modules[y][x] = (codewords[i/8]>>(7-i%8))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// masked reports whether the mask pattern inverts the module
+func masked(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// applyMask inverts the data modules selected by the mask
+func (q *QR) applyMask(mask int) {
+	for y := range q.size {
+		for x := range q.size {
+			if !q.function[y][x] && masked(mask, x, y) {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// applyBestMask applies the mask with the lowest penalty score
+func (q *QR) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		q.applyMask(mask)
+		q.drawFormatBits(mask)
+		if penalty := q.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		q.applyMask(mask) // Masks are their own inverse
+	}
+	q.applyMask(best)
+	q.drawFormatBits(best)
+}
+
+// penalty scores patterns that make a symbol hard to read
+func (q *QR) penalty() int {
+	penalty := 0
+	finderLike := []bool{true, false, true, true, true, false, true}
+
+	line := make([]bool, q.size)
+	for _, vertical := range []bool{false, true} {
+		for i := range q.size {
+			for j := range q.size {
+				if vertical {
+					line[j] = q.modules[j][i]
+				} else {
+					line[j] = q.modules[i][j]
+				}
+			}
+
+			// Runs of five or more modules of the same color
+			run := 1
+			for j := 1; j <= q.size; j++ {
+				if j < q.size && line[j] == line[j-1] {
+					run++
+					continue
+				}
+				if run >= 5 {
+					penalty += run - 2
+				}
+				run = 1
+			}
+
+			// Patterns resembling a finder, with four light modules on one side
+			for j := 0; j+7 <= q.size; j++ {
+				match := true
+				for k, dark := range finderLike {
+					if line[j+k] != dark {
+						match = false
+						break
+					}
+				}
+				if match && (lightRun(line, j-4, j) || lightRun(line, j+7, j+11)) {
+					penalty += 40
+				}
+			}
+		}
+	}
+
+	// 2x2 blocks of the same color
+	dark := 0
+	for y := range q.size {
+		for x := range q.size {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < q.size && y+1 < q.size {
+				c := q.modules[y][x]
+				if q.modules[y][x+1] == c && q.modules[y+1][x] == c && q.modules[y+1][x+1] == c {
+					penalty += 3
+				}
+			}
+		}
+	}
+
+	// Imbalance of dark and light modules
+	total := q.size * q.size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	penalty += k * 10
+
+	return penalty
+}
+
+// lightRun reports whether the modules from start to end are light,
+// counting modules outside the symbol as light
+func lightRun(line []bool, start, end int) bool {
+	for i := start; i < end; i++ {
+		if i >= 0 && i < len(line) && line[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,51 @@
+package pdf
+
+import (
+	"fmt"
+	"time"
+)
+
+// Ticket is the content of a printable redemption ticket
+type Ticket struct {
+	ID         string
+	EventName  string
+	EmailHash  string // Hash identifying the guest without showing the email
+	RedeemedAt time.Time
+	Record     string // Audit record encoded in the QR code
+}
+
+// RenderTicket draws a ticket sized for 80 mm receipt printers
+func RenderTicket(t Ticket) ([]byte, error) {
+	code, err := NewQR([]byte(t.Record))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ticket QR code: %w", err)
+	}
+
+	width, height := 80*mm, 120*mm
+	margin := 6 * mm
+	doc := New(width, height)
+
+	title := t.EventName
+	if title == "" {
+		title = "Free Cocktail"
+	}
+
+	y := height - margin - 12
+	doc.Text(margin, y, 14, true, title)
+	y -= 16
+	doc.Text(margin, y, 10, false, "Redemption ticket")
+	y -= 8
+	doc.Line(margin, y, width-2*margin)
+
+	y -= 16
+	doc.Text(margin, y, 9, false, "Guest: "+t.EmailHash)
+	y -= 13
+	doc.Text(margin, y, 9, false, "Redeemed: "+t.RedeemedAt.Format("2006-01-02 15:04:05 MST"))
+	y -= 13
+	doc.Text(margin, y, 9, false, "Ticket: "+t.ID)
+
+	qrSize := width - 2*margin
+	doc.QR(margin, margin, qrSize, code)
+
+	return doc.Bytes(), nil
+}
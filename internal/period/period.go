@@ -0,0 +1,166 @@
+// Package period turns report periods such as "week" or "7d" into date
+// ranges in the timezone of the event.
+package period
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+)
+
+// Named periods. Each runs from its start until now.
+const (
+	Today = "today" // Since midnight
+	Week  = "week"  // Since the start of the week
+	Month = "month" // Since the first of the month
+	Event = "event" // Since the first day of the event
+)
+
+// dateLayout is the format of report dates
+const dateLayout = "2006-01-02"
+
+// ErrNoEventStart is returned for the event period when no start date is configured
+var ErrNoEventStart = errors.New("event.start_date is not configured")
+
+// Calendar resolves periods and dates in a timezone
+type Calendar struct {
+	loc           *time.Location
+	weekStart     time.Weekday
+	eventStart    time.Time // Zero when no start date is configured
+	defaultPeriod string
+}
+
+// New creates a calendar from the report settings and the event start date
+func New(cfg config.ReportsConfig, eventStart string) (*Calendar, error) {
+	c := &Calendar{loc: time.UTC, weekStart: time.Monday}
+
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reports timezone %q: %w", cfg.Timezone, err)
+		}
+		c.loc = loc
+	}
+
+	if cfg.WeekStart != "" {
+		weekday, ok := parseWeekday(cfg.WeekStart)
+		if !ok {
+			return nil, fmt.Errorf("invalid reports week start %q", cfg.WeekStart)
+		}
+		c.weekStart = weekday
+	}
+
+	if eventStart != "" {
+		start, err := c.ParseDate(eventStart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event start date %q, use YYYY-MM-DD", eventStart)
+		}
+		c.eventStart = start
+	}
+
+	c.defaultPeriod = cfg.DefaultPeriod
+	if c.defaultPeriod != "" {
+		if _, _, err := c.Range(c.defaultPeriod, time.Now()); err != nil {
+			return nil, fmt.Errorf("invalid reports default period: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// parseWeekday reads an English day name such as "monday" or "Sun"
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) < 3 {
+		return 0, false
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.HasPrefix(strings.ToLower(day.String()), name) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// Location returns the timezone of the calendar
+func (c *Calendar) Location() *time.Location {
+	return c.loc
+}
+
+// ParseDate reads a YYYY-MM-DD date as midnight in the calendar's timezone
+func (c *Calendar) ParseDate(value string) (time.Time, error) {
+	return time.ParseInLocation(dateLayout, value, c.loc)
+}
+
+// Default returns the range of reports requested without dates. Without a
+// default period it is the last 7 days.
+func (c *Calendar) Default(now time.Time) (time.Time, time.Time) {
+	if c.defaultPeriod != "" {
+		if from, to, err := c.Range(c.defaultPeriod, now); err == nil {
+			return from, to
+		}
+	}
+	return now.AddDate(0, 0, -7), now
+}
+
+// Range returns the start of a period and now. period is a named period
+// or a number of days, such as "30d", counted back from now.
+func (c *Calendar) Range(period string, now time.Time) (time.Time, time.Time, error) {
+	now = now.In(c.loc)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, c.loc)
+
+	switch strings.ToLower(period) {
+	case Today:
+		return midnight, now, nil
+	case Week:
+		days := (int(now.Weekday()) - int(c.weekStart) + 7) % 7
+		return midnight.AddDate(0, 0, -days), now, nil
+	case Month:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, c.loc), now, nil
+	case Event:
+		if c.eventStart.IsZero() {
+			return time.Time{}, time.Time{}, ErrNoEventStart
+		}
+		return c.eventStart, now, nil
+	}
+
+	if days, ok := strings.CutSuffix(strings.ToLower(period), "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, -n), now, nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unknown period %q, use today, week, month, event or a number of days such as 7d", period)
+}
+
+// Previous returns the range a period is compared with: the same stretch
+// of the period before, such as yesterday until this time for today or
+// last week until this weekday and time for week. The period before the
+// event is as long as the event so far.
+func (c *Calendar) Previous(period string, now time.Time) (time.Time, time.Time, error) {
+	from, to, err := c.Range(period, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	switch strings.ToLower(period) {
+	case Today:
+		return from.AddDate(0, 0, -1), to.AddDate(0, 0, -1), nil
+	case Week:
+		return from.AddDate(0, 0, -7), to.AddDate(0, 0, -7), nil
+	case Month:
+		return from.AddDate(0, -1, 0), to.AddDate(0, -1, 0), nil
+	}
+	return Before(from, to)
+}
+
+// Before returns the range just before from and to that is as long as it
+func Before(from, to time.Time) (time.Time, time.Time, error) {
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("range starts after it ends")
+	}
+	end := from.Add(-time.Second)
+	return end.Add(-to.Sub(from)), end, nil
+}
@@ -0,0 +1,115 @@
+package period_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/period"
+)
+
+func TestRange(t *testing.T) {
+	cal, err := period.New(config.ReportsConfig{Timezone: "Europe/Berlin", WeekStart: "sunday"}, "2025-06-01")
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	berlin := cal.Location()
+
+	// Wednesday 01:30 in Berlin is still Tuesday in UTC
+	now := time.Date(2025, 6, 17, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		period string
+		from   time.Time
+	}{
+		{"today", time.Date(2025, 6, 18, 0, 0, 0, 0, berlin)},
+		{"week", time.Date(2025, 6, 15, 0, 0, 0, 0, berlin)},
+		{"month", time.Date(2025, 6, 1, 0, 0, 0, 0, berlin)},
+		{"event", time.Date(2025, 6, 1, 0, 0, 0, 0, berlin)},
+		{"7d", now.AddDate(0, 0, -7)},
+	}
+	for _, tt := range tests {
+		from, to, err := cal.Range(tt.period, now)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.period, err)
+			continue
+		}
+		if !from.Equal(tt.from) || !to.Equal(now) {
+			t.Errorf("%s: expected %v to %v, got %v to %v", tt.period, tt.from, now, from, to)
+		}
+	}
+
+	if _, _, err := cal.Range("fortnight", now); err == nil {
+		t.Error("Expected an error for an unknown period")
+	}
+
+	// Explicit dates are midnight in the configured timezone
+	date, err := cal.ParseDate("2025-06-18")
+	if err != nil || !date.Equal(time.Date(2025, 6, 17, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Berlin midnight, got %v, %v", date, err)
+	}
+}
+
+func TestPrevious(t *testing.T) {
+	cal, err := period.New(config.ReportsConfig{Timezone: "Europe/Berlin"}, "2025-06-16")
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	berlin := cal.Location()
+
+	// Wednesday 21:00 in Berlin
+	now := time.Date(2025, 6, 18, 21, 0, 0, 0, berlin)
+	tests := []struct {
+		period   string
+		from, to time.Time
+	}{
+		{"today", time.Date(2025, 6, 17, 0, 0, 0, 0, berlin), time.Date(2025, 6, 17, 21, 0, 0, 0, berlin)},
+		{"week", time.Date(2025, 6, 9, 0, 0, 0, 0, berlin), time.Date(2025, 6, 11, 21, 0, 0, 0, berlin)},
+		{"month", time.Date(2025, 5, 1, 0, 0, 0, 0, berlin), time.Date(2025, 5, 18, 21, 0, 0, 0, berlin)},
+		{"event", time.Date(2025, 6, 13, 2, 59, 59, 0, berlin), time.Date(2025, 6, 15, 23, 59, 59, 0, berlin)},
+	}
+	for _, tt := range tests {
+		from, to, err := cal.Previous(tt.period, now)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.period, err)
+			continue
+		}
+		if !from.Equal(tt.from) || !to.Equal(tt.to) {
+			t.Errorf("%s: expected %v to %v, got %v to %v", tt.period, tt.from, tt.to, from, to)
+		}
+	}
+
+	// Whole days are compared with as many days before them
+	from, _ := cal.ParseDate("2025-06-09")
+	to := time.Date(2025, 6, 15, 23, 59, 59, 0, berlin)
+	prevFrom, prevTo, err := period.Before(from, to)
+	if err != nil || !prevFrom.Equal(time.Date(2025, 6, 2, 0, 0, 0, 0, berlin)) || !prevTo.Equal(time.Date(2025, 6, 8, 23, 59, 59, 0, berlin)) {
+		t.Errorf("Expected the week before, got %v to %v (%v)", prevFrom, prevTo, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	// Monday is the default start of the week
+	cal, err := period.New(config.ReportsConfig{DefaultPeriod: "week"}, "")
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	sunday := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	if from, _ := cal.Default(sunday); !from.Equal(time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the week to start on Monday, got %v", from)
+	}
+	if _, _, err := cal.Range("event", sunday); !errors.Is(err, period.ErrNoEventStart) {
+		t.Errorf("Expected ErrNoEventStart, got %v", err)
+	}
+
+	for _, cfg := range []config.ReportsConfig{
+		{Timezone: "Mars/Olympus"},
+		{WeekStart: "someday"},
+		{DefaultPeriod: "event"}, // No event start date
+		{DefaultPeriod: "0d"},
+	} {
+		if _, err := period.New(cfg, ""); err == nil {
+			t.Errorf("Expected an error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Package ports declares what the channels guests and staff reach the bot
+// through, such as the API and the chat bots, use from the service. The
+// service implements every interface here, so methods are added in one
+// place instead of one interface per channel.
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/analytics"
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/events"
+	"github.com/ceesaxp/cocktail-bot/internal/featureflags"
+	"github.com/ceesaxp/cocktail-bot/internal/importer"
+	"github.com/ceesaxp/cocktail-bot/internal/jobs"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+)
+
+// Guests are the email checks and redemptions every chat channel offers
+type Guests interface {
+	CheckEmailStatus(ctx context.Context, userID int64, email string) (string, *domain.User, error)
+	RedeemCocktail(ctx context.Context, userID int64, email string) (time.Time, error)
+	TrackInteraction(lang, command string)
+	TrackRedeemButton()
+	IsUserBlocked(userID int64) bool
+	RetryAfter(ctx context.Context, userID int64) time.Duration
+	UnavailableRetryAfter() time.Duration
+}
+
+// Verification proves that guests own the email they check, for channels
+// that can send them a code
+type Verification interface {
+	VerificationRequired() bool
+	SendVerificationCode(ctx context.Context, userID int64, email string) error
+	VerifyEmailCode(ctx context.Context, userID int64, code string) (string, error)
+}
+
+// Service is what the API and the Telegram bot use from the service
+type Service interface {
+	Guests
+	Verification
+
+	// Guests and redemptions
+	RedeemCocktailAt(ctx context.Context, userID int64, email, bar string) (time.Time, error)
+	SetMarketingConsent(ctx context.Context, userID int64, email string, consent bool) error
+	UpdateUser(ctx context.Context, user *domain.User) error
+	AddUser(ctx context.Context, user *domain.User) error
+	UserAsOf(ctx context.Context, id string, asOf time.Time) (*domain.User, string, error)
+	PatchUser(ctx context.Context, id string, patch domain.UserPatch) (*domain.User, error)
+	MergeUsers(ctx context.Context, keepID, duplicateID, reason string) (*domain.User, *domain.User, error)
+	ApplyOfflineRedemptions(ctx context.Context, entries []domain.OfflineRedemption) []domain.OfflineRedemptionResult
+	FreeFormEmailAllowed() bool
+	VoucherEmail(code string) (string, error)
+	SuggestEmails(ctx context.Context, email string) []string
+	Events() *events.Hub
+
+	// Failed redemptions
+	FailedRedemptions() []domain.FailedRedemption
+	RetryFailedRedemption(ctx context.Context, id string) (time.Time, error)
+	ResolveFailedRedemption(id string) error
+
+	// Blocking
+	BlockUser(userID int64)
+	UnblockUser(userID int64)
+	DenyEmail(email string)
+	AllowEmail(email string)
+	ResetRateLimit(userID int64)
+
+	// Self-registration
+	SelfRegistrationEnabled() bool
+	RequestRegistration(ctx context.Context, userID, chatID int64, email, lang string) (domain.Registration, bool, error)
+	ApproveRegistration(ctx context.Context, id, actor string) (domain.Registration, error)
+	RejectRegistration(ctx context.Context, id, actor string) (domain.Registration, error)
+
+	// Payments and tickets
+	PaymentsEnabled() bool
+	CreateCheckout(ctx context.Context, userID int64, email string) (string, error)
+	HandlePaymentWebhook(payload []byte, signature string) error
+	PurchaseReport(ctx context.Context, fromDate, toDate time.Time) ([]domain.Purchase, error)
+	TicketURL(email string, redeemed time.Time) string
+	TicketPDF(id string, expires int64, signature string) ([]byte, error)
+
+	// Reports and status
+	GenerateReport(ctx context.Context, reportType string, fromDate, toDate time.Time, filter domain.ReportFilter) ([]*domain.User, error)
+	CountReport(ctx context.Context, reportType string, fromDate, toDate time.Time, filter domain.ReportFilter) (int, error)
+	RedemptionsByBar(ctx context.Context, fromDate, toDate time.Time, filter domain.ReportFilter) ([]domain.BarRedemptions, error)
+	RedemptionLatency(ctx context.Context, fromDate, toDate time.Time, filter domain.ReportFilter) (domain.RedemptionLatency, error)
+	SearchGuests(ctx context.Context, query string, offset, limit int) (domain.SearchResult, error)
+	RateLimitStats(top int) ratelimit.Stats
+	EnumerationStats() domain.EnumerationStats
+	EngagementStats() analytics.Engagement
+	FunnelStats() analytics.Funnel
+	DatabaseHealth(ctx context.Context) error
+	DatabaseStats(ctx context.Context) (domain.RepoStats, error)
+	Status() domain.RuntimeStatus
+	AuditLog(ctx context.Context, filter audit.Filter) ([]audit.Entry, int, error)
+
+	// Archives
+	EventArchived() *domain.EventArchive
+	EventArchives() []domain.EventArchive
+	ArchiveEvent(ctx context.Context, actor string, export bool) (domain.EventArchive, error)
+
+	// Jobs
+	ImportQueueEnabled() bool
+	MaxImportEmails() int
+	EnqueueImport(ctx context.Context, actor string, emails []string) (jobs.Job, error)
+	StartImport(ctx context.Context, actor, name string, data []byte, opts importer.Options) (jobs.Job, error)
+	Job(id string) (jobs.Job, error)
+	Jobs() []jobs.Job
+	CancelJob(ctx context.Context, actor, id string) (jobs.Job, error)
+	StartMigration(ctx context.Context, actor, name string) (jobs.Job, error)
+
+	// Feature flags
+	Features() []featureflags.Flag
+	ToggleFeature(ctx context.Context, name featureflags.Name, enabled *bool, actor string) (featureflags.Flag, error)
+
+	Close() error
+}
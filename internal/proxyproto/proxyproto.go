@@ -0,0 +1,230 @@
+// Package proxyproto reads the PROXY protocol header load balancers such as
+// HAProxy send ahead of each connection, so servers behind them see the
+// address of the client instead of the balancer. Versions 1 and 2 are read.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is how long a connection may take to send its header
+const DefaultTimeout = 10 * time.Second
+
+// Signatures that start the header of each version
+var (
+	v1Signature = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+// maxV1Header is the longest version 1 header, including CRLF
+const maxV1Header = 107
+
+// ErrNoHeader is returned for connections that do not start with a header.
+// Such connections are closed, since any client could otherwise reach the
+// server directly and claim to be someone else.
+var ErrNoHeader = errors.New("connection did not send a PROXY protocol header")
+
+// Listener accepts connections that start with a PROXY protocol header
+type Listener struct {
+	net.Listener
+	Timeout time.Duration // How long a connection may take to send its header, 0 for DefaultTimeout
+}
+
+// NewListener wraps a listener whose connections come from a load balancer
+// sending the PROXY protocol
+func NewListener(inner net.Listener) *Listener {
+	return &Listener{Listener: inner, Timeout: DefaultTimeout}
+}
+
+// Accept returns the next connection. Its header is read on the first Read
+// or RemoteAddr, in the goroutine serving it, so a slow client does not hold
+// up other connections.
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	timeout := l.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Conn{Conn: conn, reader: bufio.NewReader(conn), timeout: timeout}, nil
+}
+
+// Conn is a connection whose remote address is the one in its header
+type Conn struct {
+	net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+
+	once   sync.Once
+	remote net.Addr // Client address of the header, nil to keep the connection's own
+	err    error
+}
+
+// Read reads past the header
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the client address of the header. Connections the
+// balancer opened itself, such as health checks, keep their own address.
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// contextKey marks the contexts of connections accepted by a Listener
+type contextKey struct{}
+
+// ConnContext marks the context of connections accepted by a Listener, for
+// use as http.Server.ConnContext. Other connections are left unmarked.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	if _, ok := c.(*Conn); ok {
+		return context.WithValue(ctx, contextKey{}, true)
+	}
+	return ctx
+}
+
+// FromContext reports whether a request came over a connection whose
+// client address was read from the PROXY protocol
+func FromContext(ctx context.Context) bool {
+	proxied, _ := ctx.Value(contextKey{}).(bool)
+	return proxied
+}
+
+// readHeader reads the header, and closes the connection if it is missing
+// or invalid
+func (c *Conn) readHeader() {
+	c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	defer c.Conn.SetReadDeadline(time.Time{})
+
+	c.remote, c.err = ReadHeader(c.reader)
+	if c.err != nil {
+		c.Conn.Close()
+	}
+}
+
+// ReadHeader reads a version 1 or 2 header and returns the client address
+// it carries, nil for connections without one, such as health checks
+func ReadHeader(r *bufio.Reader) (net.Addr, error) {
+	start, err := r.Peek(len(v1Signature))
+	if err != nil {
+		return nil, ErrNoHeader
+	}
+	if bytes.Equal(start, v1Signature) {
+		return readV1(r)
+	}
+	if start, err = r.Peek(len(v2Signature)); err == nil && bytes.Equal(start, v2Signature) {
+		return readV2(r)
+	}
+	return nil, ErrNoHeader
+}
+
+// readV1 reads a text header such as "PROXY TCP4 203.0.113.7 10.0.0.1 51234 443"
+func readV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < maxV1Header {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("reading PROXY header: %w", err)
+		}
+		line = append(line, b)
+		if bytes.HasSuffix(line, []byte("\r\n")) {
+			return parseV1(string(line[:len(line)-2]))
+		}
+	}
+	return nil, errors.New("PROXY header too long")
+}
+
+// parseV1 returns the source address of a version 1 header line
+func parseV1(line string) (net.Addr, error) {
+	fields := strings.Fields(line)
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("invalid PROXY header %q", line)
+	}
+	addr, err := netip.ParseAddr(fields[2])
+	if err != nil || addr.Is4() != (fields[1] == "TCP4") {
+		return nil, fmt.Errorf("invalid PROXY source address %q", fields[2])
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PROXY source port %q", fields[4])
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, uint16(port))), nil
+}
+
+// Version 2 commands and address families
+const (
+	v2Local = 0x0
+	v2Proxy = 0x1
+
+	v2Unspec = 0x0
+	v2INET   = 0x1
+	v2INET6  = 0x2
+)
+
+// readV2 reads a binary header
+func readV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("reading PROXY header: %w", err)
+	}
+	if header[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported PROXY version %d", header[12]>>4)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("reading PROXY header: %w", err)
+	}
+
+	command, family := header[12]&0x0f, header[13]>>4
+	switch {
+	case command == v2Local:
+		return nil, nil
+	case command != v2Proxy:
+		return nil, fmt.Errorf("unsupported PROXY command %d", command)
+	}
+
+	// Source address, destination address, source port, destination port
+	switch family {
+	case v2INET:
+		if len(body) < 12 {
+			return nil, errors.New("PROXY header too short for IPv4 addresses")
+		}
+		addr := netip.AddrFrom4([4]byte(body[0:4]))
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, binary.BigEndian.Uint16(body[8:10]))), nil
+	case v2INET6:
+		if len(body) < 36 {
+			return nil, errors.New("PROXY header too short for IPv6 addresses")
+		}
+		addr := netip.AddrFrom16([16]byte(body[0:16]))
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, binary.BigEndian.Uint16(body[32:34]))), nil
+	case v2Unspec:
+		return nil, nil
+	}
+	// Other families, such as Unix sockets, carry no client IP
+	return nil, nil
+}
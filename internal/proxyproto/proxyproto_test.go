@@ -0,0 +1,107 @@
+package proxyproto_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/proxyproto"
+)
+
+func TestReadHeader(t *testing.T) {
+	v2 := func(command, family byte, body []byte) string {
+		header := []byte("\r\n\r\n\x00\r\nQUIT\n")
+		header = append(header, 0x20|command, family<<4|0x1, 0, 0)
+		binary.BigEndian.PutUint16(header[14:], uint16(len(body)))
+		return string(append(header, body...))
+	}
+	ipv6 := make([]byte, 36)
+	copy(ipv6, net.ParseIP("2001:db8::7"))
+	binary.BigEndian.PutUint16(ipv6[32:], 51234)
+
+	tests := []struct {
+		name   string
+		header string
+		remote string // Empty for connections without a client address
+		err    bool
+	}{
+		{"v1 IPv4", "PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\n", "203.0.113.7:51234", false},
+		{"v1 IPv6", "PROXY TCP6 2001:db8::7 2001:db8::1 51234 443\r\n", "[2001:db8::7]:51234", false},
+		{"v1 unknown", "PROXY UNKNOWN\r\n", "", false},
+		{"v1 family mismatch", "PROXY TCP4 2001:db8::7 10.0.0.1 51234 443\r\n", "", true},
+		{"v1 without CRLF", "PROXY TCP4 203.0.113.7 10.0.0.1 51234 443" + strings.Repeat(" ", 80), "", true},
+		{"v2 IPv4", v2(0x1, 0x1, []byte{203, 0, 113, 7, 10, 0, 0, 1, 0xc8, 0x22, 0x01, 0xbb}), "203.0.113.7:51234", false},
+		{"v2 IPv6", v2(0x1, 0x2, ipv6), "[2001:db8::7]:51234", false},
+		{"v2 local", v2(0x0, 0x0, nil), "", false},
+		{"no header", "GET / HTTP/1.1\r\n", "", true},
+	}
+	for _, tt := range tests {
+		reader := bufio.NewReader(strings.NewReader(tt.header + "GET / HTTP/1.1\r\n"))
+		addr, err := proxyproto.ReadHeader(reader)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.err, err)
+			continue
+		}
+		if tt.err {
+			continue
+		}
+		if got := ""; addr != nil {
+			got = addr.String()
+			if got != tt.remote {
+				t.Errorf("%s: expected %q, got %q", tt.name, tt.remote, got)
+			}
+		} else if tt.remote != "" {
+			t.Errorf("%s: expected %q, got no address", tt.name, tt.remote)
+		}
+
+		// The request follows the header
+		if rest, _ := reader.ReadString('\n'); rest != "GET / HTTP/1.1\r\n" {
+			t.Errorf("%s: expected the request after the header, got %q", tt.name, rest)
+		}
+	}
+}
+
+func TestListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	listener := proxyproto.NewListener(inner)
+	listener.Timeout = time.Second
+	defer listener.Close()
+
+	dial := func(data string) net.Conn {
+		t.Helper()
+		client, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			t.Fatalf("Failed to dial: %v", err)
+		}
+		client.Write([]byte(data))
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatalf("Failed to accept: %v", err)
+		}
+		client.Close()
+		return conn
+	}
+
+	conn := dial("PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\nhello")
+	if addr := conn.RemoteAddr().String(); addr != "203.0.113.7:51234" {
+		t.Errorf("Expected the client address of the header, got %s", addr)
+	}
+	if data, _ := io.ReadAll(conn); string(data) != "hello" {
+		t.Errorf("Expected the data after the header, got %q", data)
+	}
+	conn.Close()
+
+	// Connections without a header are refused
+	conn = dial("hello")
+	if _, err := conn.Read(make([]byte, 5)); !errors.Is(err, proxyproto.ErrNoHeader) {
+		t.Errorf("Expected a missing header to fail, got %v", err)
+	}
+}
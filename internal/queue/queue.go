@@ -0,0 +1,142 @@
+// Package queue moves messages through a message broker, so work can be
+// accepted right away and done later by a pool of consumers.
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// bufferSize is the number of received messages held for the consumers
+const bufferSize = 256
+
+// ErrClosed is returned when publishing to a closed broker
+var ErrClosed = errors.New("queue is closed")
+
+// Broker publishes messages to a queue and delivers them to consumers.
+// Consumers acknowledge each message once it is processed, or reject it.
+// The memory broker delivers messages at most once, so a message whose
+// processing fails is not delivered again. NATS JetStream and RabbitMQ hold
+// a message until it is acknowledged and deliver it again if it is requeued
+// or the consumer disconnects first.
+type Broker interface {
+	// Publish sends a message to the queue
+	Publish(ctx context.Context, data []byte) error
+	// Messages returns the messages received from the queue. The channel
+	// is closed when the broker is closed.
+	Messages() <-chan Message
+	// Close stops receiving messages and disconnects from the broker
+	Close() error
+}
+
+// Message is a message received from a queue
+type Message struct {
+	Data []byte
+
+	ack  func() error             // nil for brokers without acknowledgements
+	nack func(requeue bool) error // nil for brokers without acknowledgements
+}
+
+// Ack tells the broker the message was processed, so it is not delivered
+// again
+func (m Message) Ack() error {
+	if m.ack == nil {
+		return nil
+	}
+	return m.ack()
+}
+
+// Nack tells the broker the message was not processed. With requeue it is
+// delivered again, if the broker can; without it, it is dropped.
+func (m Message) Nack(requeue bool) error {
+	if m.nack == nil {
+		return nil
+	}
+	return m.nack(requeue)
+}
+
+// CanRequeue reports whether a message rejected with requeue is delivered
+// again, so its consumer may leave it for later
+func (m Message) CanRequeue() bool {
+	return m.nack != nil
+}
+
+// New creates a broker based on the import queue configuration
+func New(cfg config.ImportQueueConfig, logger *logger.Logger) (Broker, error) {
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+
+	brokerType := strings.ToLower(cfg.Broker)
+	switch brokerType {
+	case "", "memory":
+		return NewMemoryBroker(), nil
+	case "nats":
+		return NewNATSBroker(cfg.URL, cfg.Queue, logger)
+	case "rabbitmq":
+		return NewRabbitMQBroker(cfg.URL, cfg.VHost, cfg.Queue, logger)
+	default:
+		return nil, fmt.Errorf("unsupported queue broker: %s", brokerType)
+	}
+}
+
+// MemoryBroker keeps messages in memory. It does not survive a restart and
+// is not shared between instances, so it only smooths spikes within one
+// process. It is meant for single instances and testing.
+type MemoryBroker struct {
+	messages chan Message
+	done     chan struct{} // Closed first, so waiting publishers give up
+	once     sync.Once
+
+	mu     sync.RWMutex // Held by publishers, so messages is closed after the last send
+	closed bool
+}
+
+// NewMemoryBroker creates an in-memory broker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		messages: make(chan Message, bufferSize),
+		done:     make(chan struct{}),
+	}
+}
+
+// Publish queues a message, waiting while the queue is full
+func (b *MemoryBroker) Publish(ctx context.Context, data []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrClosed
+	}
+
+	select {
+	case b.messages <- Message{Data: data}:
+		return nil
+	case <-b.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Messages returns the queued messages
+func (b *MemoryBroker) Messages() <-chan Message {
+	return b.messages
+}
+
+// Close stops accepting messages. Messages already queued are still delivered.
+func (b *MemoryBroker) Close() error {
+	b.once.Do(func() {
+		close(b.done)
+		b.mu.Lock()
+		b.closed = true
+		close(b.messages)
+		b.mu.Unlock()
+	})
+	return nil
+}
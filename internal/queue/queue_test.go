@@ -0,0 +1,207 @@
+package queue_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/queue"
+)
+
+// receive waits for the next message of a broker
+func receive(t *testing.T, b queue.Broker) string {
+	t.Helper()
+	select {
+	case msg, ok := <-b.Messages():
+		if !ok {
+			t.Fatal("Messages closed early")
+		}
+		return string(msg.Data)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a message")
+		return ""
+	}
+}
+
+func TestMemoryBroker(t *testing.T) {
+	b, err := queue.New(config.ImportQueueConfig{Broker: "memory"}, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create broker: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, msg := range []string{"one", "two"} {
+		if err := b.Publish(ctx, []byte(msg)); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+	}
+	b.Close()
+
+	// Queued messages are delivered after closing, new ones are refused
+	if got := receive(t, b) + receive(t, b); got != "onetwo" {
+		t.Errorf("Expected both messages in order, got %q", got)
+	}
+	if _, ok := <-b.Messages(); ok {
+		t.Error("Expected messages to be closed")
+	}
+	if err := b.Publish(ctx, []byte("three")); err != queue.ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+
+	if _, err := queue.New(config.ImportQueueConfig{Broker: "kafka"}, logger.New("error")); err == nil {
+		t.Error("Expected an unsupported broker to fail")
+	}
+}
+
+// startNATS runs a NATS server with JetStream storing to dir, on a random
+// port if port is -1
+func startNATS(t *testing.T, dir string, port int) *natsserver.Server {
+	t.Helper()
+	srv, err := natsserver.NewServer(&natsserver.Options{
+		Host:      "127.0.0.1",
+		Port:      port,
+		JetStream: true,
+		StoreDir:  dir,
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create NATS server: %v", err)
+	}
+	srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+func TestNATSBroker(t *testing.T) {
+	url := startNATS(t, t.TempDir(), -1).ClientURL()
+	ctx := context.Background()
+	b, err := queue.NewNATSBroker(url, "cocktailbot.import", logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	for _, msg := range []string{"one", "two", "three"} {
+		if err := b.Publish(ctx, []byte(msg)); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+	}
+
+	// A requeued message is delivered again, a dropped one is not
+	var delivered []string
+	requeued := false
+	for range 4 {
+		msg := next(t, b)
+		delivered = append(delivered, string(msg.Data))
+		switch {
+		case string(msg.Data) == "one" && !requeued:
+			requeued = true
+			msg.Nack(true)
+		case string(msg.Data) == "three":
+			msg.Nack(false)
+		default:
+			msg.Ack()
+		}
+	}
+	if got := strings.Join(delivered, " "); got != "one two three one" {
+		t.Errorf("Expected the requeued message again, got %q", got)
+	}
+
+	// A message not received before closing stays in the stream, and one
+	// published while no instance consumes waits for the next
+	if err := b.Publish(ctx, []byte("four")); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond) // Let the client fetch it
+	b.Close()
+	if _, ok := <-b.Messages(); ok {
+		t.Error("Expected messages to be closed")
+	}
+	if err := b.Publish(ctx, []byte("five")); err != queue.ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+
+	restarted, err := queue.NewNATSBroker(url, "cocktailbot.import", logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to connect again: %v", err)
+	}
+	defer restarted.Close()
+	if err := restarted.Publish(ctx, []byte("five")); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	delivered = nil
+	for range 2 {
+		msg := next(t, restarted)
+		delivered = append(delivered, string(msg.Data))
+		msg.Ack()
+	}
+	if got := strings.Join(delivered, " "); got != "four five" {
+		t.Errorf("Expected the messages left in the stream, got %q", got)
+	}
+	select {
+	case msg := <-restarted.Messages():
+		t.Errorf("Expected acknowledged and dropped messages to be gone, got %q", msg.Data)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if _, err := queue.NewNATSBroker(url, "bad subject", logger.New("error")); err == nil {
+		t.Error("Expected a subject with spaces to fail")
+	}
+}
+
+// next waits for the next message of a broker
+func next(t *testing.T, b queue.Broker) queue.Message {
+	t.Helper()
+	select {
+	case msg, ok := <-b.Messages():
+		if !ok {
+			t.Fatal("Messages closed early")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a message")
+		return queue.Message{}
+	}
+}
+
+func TestNATSBrokerReconnect(t *testing.T) {
+	dir := t.TempDir()
+	srv := startNATS(t, dir, -1)
+	port := srv.Addr().(*net.TCPAddr).Port
+	b, err := queue.NewNATSBroker(srv.ClientURL(), "cocktailbot.import", logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer b.Close()
+
+	// A message published while the server is down is sent once it is back,
+	// and the stream kept on disk is consumed again
+	srv.Shutdown()
+	published := make(chan error, 1)
+	go func() {
+		published <- b.Publish(context.Background(), []byte("during outage"))
+	}()
+	time.Sleep(200 * time.Millisecond)
+	startNATS(t, dir, port)
+
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Fatalf("Failed to publish during the outage: %v", err)
+		}
+	case <-time.After(20 * time.Second):
+		t.Fatal("Timed out publishing during the outage")
+	}
+	if got := receive(t, b); got != "during outage" {
+		t.Errorf("Unexpected message: %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+package ratelimit
+
+import "context"
+
+// contextKey is the type of keys stored in contexts by this package
+type contextKey struct{}
+
+// NewContext returns a context carrying the rate limiting key of the
+// client making the request
+func NewContext(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// KeyFromContext returns the rate limiting key carried by the context, or
+// fallback if there is none. The context may be nil or of any type, since
+// services accept contexts as any.
+func KeyFromContext(ctx any, fallback string) string {
+	c, ok := ctx.(context.Context)
+	if !ok {
+		return fallback
+	}
+	if key, ok := c.Value(contextKey{}).(string); ok && key != "" {
+		return key
+	}
+	return fallback
+}
@@ -1,20 +1,34 @@
 package ratelimit
 
 import (
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 )
 
+// DefaultCleanupInterval is how often expired entries are cleaned up by default
+const DefaultCleanupInterval = 10 * time.Minute
+
 // Limiter provides rate limiting functionality to prevent API abuse.
 // It implements a sliding window algorithm for tracking requests
 // with configurable limits at minute and hour levels.
+//
+// Clients are identified by string keys, which callers build from the
+// parts that identify a client (such as "client:" + token + "@" + IP)
+// instead of hashing them, so unrelated clients never share a limit.
+// The methods taking an int64 user ID are adapters for numeric IDs such
+// as Telegram user IDs.
 type Limiter struct {
 	requestsPerMinute int
 	requestsPerHour   int
-	userRequests      map[int64]*userRequestData
+	userRequests      map[string]*userRequestData
 	mu                sync.RWMutex
 	cleanupInterval   time.Duration
 	stopCleanup       chan struct{}
+	allowed           int64 // Requests allowed since start
+	rejectedMinute    int64 // Requests rejected by the per-minute limit since start
+	rejectedHour      int64 // Requests rejected by the per-hour limit since start
 }
 
 // userRequestData tracks request timing for a specific user.
@@ -24,6 +38,7 @@ type userRequestData struct {
 	minuteRequests []time.Time
 	hourRequests   []time.Time
 	lastCleanup    time.Time
+	rejected       int64 // Requests of the client rejected since it was first tracked
 }
 
 // New creates a new rate limiter with the specified limits.
@@ -35,6 +50,16 @@ type userRequestData struct {
 // If any limit is <= 0, sensible defaults (10 req/min, 100 req/hour) will be used.
 // The limiter starts a background goroutine to clean up expired entries.
 func New(requestsPerMinute, requestsPerHour int) *Limiter {
+	return NewWithCleanup(requestsPerMinute, requestsPerHour, DefaultCleanupInterval)
+}
+
+// NewWithCleanup creates a rate limiter that cleans up expired entries at
+// the given interval. An interval <= 0 uses DefaultCleanupInterval.
+func NewWithCleanup(requestsPerMinute, requestsPerHour int, cleanupInterval time.Duration) *Limiter {
+	if cleanupInterval <= 0 {
+		cleanupInterval = DefaultCleanupInterval
+	}
+
 	// Ensure sensible defaults if invalid values are provided
 	if requestsPerMinute <= 0 {
 		requestsPerMinute = 10
@@ -46,8 +71,8 @@ func New(requestsPerMinute, requestsPerHour int) *Limiter {
 	limiter := &Limiter{
 		requestsPerMinute: requestsPerMinute,
 		requestsPerHour:   requestsPerHour,
-		userRequests:      make(map[int64]*userRequestData),
-		cleanupInterval:   10 * time.Minute,
+		userRequests:      make(map[string]*userRequestData),
+		cleanupInterval:   cleanupInterval,
 		stopCleanup:       make(chan struct{}),
 	}
 
@@ -57,48 +82,65 @@ func New(requestsPerMinute, requestsPerHour int) *Limiter {
 	return limiter
 }
 
-// Allow checks if a user is allowed to make a request based on their usage history.
+// IDKey returns the key of a numeric user ID. Keys built by callers start
+// with a prefix such as "ip:", so they never collide with numeric IDs.
+func IDKey(userID int64) string {
+	return strconv.FormatInt(userID, 10)
+}
+
+// Allow checks if a user is allowed to make a request. It is an adapter
+// for AllowKey with a numeric user ID such as a Telegram user ID.
+func (l *Limiter) Allow(userID int64) bool {
+	return l.AllowKey(IDKey(userID))
+}
+
+// AllowKey checks if a client is allowed to make a request based on its usage history.
 // It returns true if the request is allowed, or false if either the per-minute
 // or per-hour limit has been exceeded.
 //
 // This method is thread-safe and can be called concurrently from multiple goroutines.
 //
 // If allowed, the request is recorded and counts against future rate limits.
-// The userID parameter should be a unique identifier for the user or client
-// (e.g., Telegram user ID, IP address hash, etc.)
-func (l *Limiter) Allow(userID int64) bool {
+// The key parameter should uniquely identify the client
+// (e.g., "ip:203.0.113.7" or "client:<token fingerprint>@<IP>")
+func (l *Limiter) AllowKey(key string) bool {
 	now := time.Now()
 	
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	// Get or create user data
-	data, exists := l.userRequests[userID]
+	data, exists := l.userRequests[key]
 	if !exists {
 		data = &userRequestData{
 			minuteRequests: make([]time.Time, 0, l.requestsPerMinute),
 			hourRequests:   make([]time.Time, 0, l.requestsPerHour),
 			lastCleanup:    now,
 		}
-		l.userRequests[userID] = data
+		l.userRequests[key] = data
 	}
 
 	// Clean up old requests for this user
-	l.cleanupUserData(userID, now)
+	l.cleanupUserData(key, now)
 
 	// Check minute limit
 	if len(data.minuteRequests) >= l.requestsPerMinute {
+		data.rejected++
+		l.rejectedMinute++
 		return false
 	}
 
 	// Check hour limit
 	if len(data.hourRequests) >= l.requestsPerHour {
+		data.rejected++
+		l.rejectedHour++
 		return false
 	}
 
 	// Record the request
 	data.minuteRequests = append(data.minuteRequests, now)
 	data.hourRequests = append(data.hourRequests, now)
+	l.allowed++
 	
 	return true
 }
@@ -109,8 +151,8 @@ func (l *Limiter) Allow(userID int64) bool {
 // It filters out request timestamps older than one minute from minuteRequests
 // and older than one hour from hourRequests. This implements the sliding window
 // approach for rate limiting.
-func (l *Limiter) cleanupUserData(userID int64, now time.Time) {
-	data := l.userRequests[userID]
+func (l *Limiter) cleanupUserData(key string, now time.Time) {
+	data := l.userRequests[key]
 	
 	// Remove requests older than one minute
 	minuteAgo := now.Add(-time.Minute)
@@ -137,62 +179,114 @@ func (l *Limiter) cleanupUserData(userID int64, now time.Time) {
 	data.lastCleanup = now
 }
 
-// RemainingMinute returns the number of requests remaining in the current minute
-// for the specified user. If the user hasn't made any requests yet, it returns
+// RemainingMinute returns the number of requests remaining in the current
+// minute for a numeric user ID
+func (l *Limiter) RemainingMinute(userID int64) int {
+	return l.RemainingMinuteKey(IDKey(userID))
+}
+
+// RemainingMinuteKey returns the number of requests remaining in the current minute
+// for the specified client. If the client hasn't made any requests yet, it returns
 // the full per-minute limit.
 //
 // This method is thread-safe and can be used to display rate limit information
 // to users or for making decisions about when to retry requests.
-func (l *Limiter) RemainingMinute(userID int64) int {
+func (l *Limiter) RemainingMinuteKey(key string) int {
 	now := time.Now()
 	
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	
-	data, exists := l.userRequests[userID]
+	data, exists := l.userRequests[key]
 	if !exists {
 		return l.requestsPerMinute
 	}
 	
 	// Clean up first to get accurate count
-	l.cleanupUserData(userID, now)
+	l.cleanupUserData(key, now)
 	
 	return max(0, l.requestsPerMinute - len(data.minuteRequests))
 }
 
-// RemainingHour returns the number of requests remaining in the current hour
-// for the specified user. If the user hasn't made any requests yet, it returns
+// RemainingHour returns the number of requests remaining in the current
+// hour for a numeric user ID
+func (l *Limiter) RemainingHour(userID int64) int {
+	return l.RemainingHourKey(IDKey(userID))
+}
+
+// RemainingHourKey returns the number of requests remaining in the current hour
+// for the specified client. If the client hasn't made any requests yet, it returns
 // the full per-hour limit.
 //
 // This method is thread-safe and useful for displaying hourly rate limit information
 // to users or for making decisions about retry strategies.
-func (l *Limiter) RemainingHour(userID int64) int {
+func (l *Limiter) RemainingHourKey(key string) int {
 	now := time.Now()
 	
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	
-	data, exists := l.userRequests[userID]
+	data, exists := l.userRequests[key]
 	if !exists {
 		return l.requestsPerHour
 	}
 	
 	// Clean up first to get accurate count
-	l.cleanupUserData(userID, now)
+	l.cleanupUserData(key, now)
 	
 	return max(0, l.requestsPerHour - len(data.hourRequests))
 }
 
-// ResetFor resets all rate limits for a specific user, effectively clearing
-// their request history. This can be useful for administrative purposes,
+// RetryAfter returns how long a numeric user ID has to wait until its
+// next request is allowed
+func (l *Limiter) RetryAfter(userID int64) time.Duration {
+	return l.RetryAfterKey(IDKey(userID))
+}
+
+// RetryAfterKey returns how long the specified client has to wait until its
+// next request is allowed, or 0 if it is not limited. Both windows slide,
+// so the wait ends when the oldest request of a full window expires.
+func (l *Limiter) RetryAfterKey(key string) time.Duration {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, exists := l.userRequests[key]
+	if !exists {
+		return 0
+	}
+
+	// Clean up first to get accurate windows
+	l.cleanupUserData(key, now)
+
+	var wait time.Duration
+	if n := len(data.minuteRequests); n > 0 && n >= l.requestsPerMinute {
+		wait = data.minuteRequests[n-l.requestsPerMinute].Add(time.Minute).Sub(now)
+	}
+	if n := len(data.hourRequests); n > 0 && n >= l.requestsPerHour {
+		if hourWait := data.hourRequests[n-l.requestsPerHour].Add(time.Hour).Sub(now); hourWait > wait {
+			wait = hourWait
+		}
+	}
+	return wait
+}
+
+// ResetFor resets all rate limits for a numeric user ID
+func (l *Limiter) ResetFor(userID int64) {
+	l.ResetKey(IDKey(userID))
+}
+
+// ResetKey resets all rate limits for a specific client, effectively clearing
+// its request history. This can be useful for administrative purposes,
 // testing, or when a user's circumstances change (e.g., upgrading to a premium tier).
 //
 // This method is thread-safe.
-func (l *Limiter) ResetFor(userID int64) {
+func (l *Limiter) ResetKey(key string) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	
-	delete(l.userRequests, userID)
+	delete(l.userRequests, key)
 }
 
 // GetLimits returns the configured rate limits (requests per minute and per hour).
@@ -203,6 +297,83 @@ func (l *Limiter) GetLimits() (requestsPerMinute, requestsPerHour int) {
 	return l.requestsPerMinute, l.requestsPerHour
 }
 
+// Consumer is the recent usage of one client
+type Consumer struct {
+	Key        string `json:"key"`
+	LastMinute int    `json:"last_minute"` // Requests allowed in the last minute
+	LastHour   int    `json:"last_hour"`   // Requests allowed in the last hour
+	Rejected   int64  `json:"rejected"`    // Requests rejected since the client was first tracked
+}
+
+// Stats describes the state of a limiter, to help tune its limits
+type Stats struct {
+	RequestsPerMinute int        `json:"requests_per_minute"`
+	RequestsPerHour   int        `json:"requests_per_hour"`
+	TrackedClients    int        `json:"tracked_clients"`
+	Allowed           int64      `json:"allowed"`         // Since start
+	RejectedMinute    int64      `json:"rejected_minute"` // Rejected by the per-minute limit since start
+	RejectedHour      int64      `json:"rejected_hour"`   // Rejected by the per-hour limit since start
+	TopConsumers      []Consumer `json:"top_consumers"`   // Most requests in the last hour first
+}
+
+// Stats returns the number of tracked clients, the requests allowed and
+// rejected since start and the top clients by requests in the last hour.
+//
+// This method is thread-safe.
+func (l *Limiter) Stats(top int) Stats {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := Stats{
+		RequestsPerMinute: l.requestsPerMinute,
+		RequestsPerHour:   l.requestsPerHour,
+		TrackedClients:    len(l.userRequests),
+		Allowed:           l.allowed,
+		RejectedMinute:    l.rejectedMinute,
+		RejectedHour:      l.rejectedHour,
+		TopConsumers:      []Consumer{},
+	}
+
+	// Requests are counted without cleaning up, which would mark idle
+	// clients as active
+	consumers := make([]Consumer, 0, len(l.userRequests))
+	for key, data := range l.userRequests {
+		consumers = append(consumers, Consumer{
+			Key:        key,
+			LastMinute: countSince(data.minuteRequests, now.Add(-time.Minute)),
+			LastHour:   countSince(data.hourRequests, now.Add(-time.Hour)),
+			Rejected:   data.rejected,
+		})
+	}
+	sort.Slice(consumers, func(i, j int) bool {
+		if consumers[i].LastHour != consumers[j].LastHour {
+			return consumers[i].LastHour > consumers[j].LastHour
+		}
+		if consumers[i].Rejected != consumers[j].Rejected {
+			return consumers[i].Rejected > consumers[j].Rejected
+		}
+		return consumers[i].Key < consumers[j].Key
+	})
+	if top >= 0 && len(consumers) > top {
+		consumers = consumers[:top]
+	}
+	stats.TopConsumers = append(stats.TopConsumers, consumers...)
+	return stats
+}
+
+// countSince returns the number of request times after since
+func countSince(times []time.Time, since time.Time) int {
+	count := 0
+	for _, t := range times {
+		if t.After(since) {
+			count++
+		}
+	}
+	return count
+}
+
 // startCleanupRoutine periodically cleans up the rate limiter data.
 // This runs as a background goroutine to prevent the map from growing
 // unbounded as users come and go. It cleans up expired requests and
@@ -232,13 +403,13 @@ func (l *Limiter) cleanup() {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	
-	for userID := range l.userRequests {
+	for key := range l.userRequests {
 		// Clean up user data
-		l.cleanupUserData(userID, now)
+		l.cleanupUserData(key, now)
 		
 		// Remove users that haven't made requests in over 24 hours
-		if now.Sub(l.userRequests[userID].lastCleanup) > 24*time.Hour {
-			delete(l.userRequests, userID)
+		if now.Sub(l.userRequests[key].lastCleanup) > 24*time.Hour {
+			delete(l.userRequests, key)
 		}
 	}
 }
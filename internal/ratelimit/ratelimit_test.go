@@ -1,13 +1,14 @@
 package ratelimit
 
 import (
+	"context"
 	"testing"
 	"time"
 )
 
 func TestRateLimiterInitialization(t *testing.T) {
 	limiter := New(10, 100)
-	
+
 	// Test default values
 	rpm, rph := limiter.GetLimits()
 	if rpm != 10 {
@@ -16,7 +17,7 @@ func TestRateLimiterInitialization(t *testing.T) {
 	if rph != 100 {
 		t.Errorf("Expected requests per hour to be 100, got %d", rph)
 	}
-	
+
 	// Test negative values are corrected to defaults
 	limiter = New(-5, -20)
 	rpm, rph = limiter.GetLimits()
@@ -31,33 +32,33 @@ func TestRateLimiterInitialization(t *testing.T) {
 func TestRateLimiterAllow(t *testing.T) {
 	// Create a limiter with a small limit for testing
 	limiter := New(3, 5)
-	
+
 	// Test user IDs
 	user1 := int64(1001)
 	user2 := int64(1002)
-	
+
 	// First 3 requests for user1 should be allowed
 	for i := 0; i < 3; i++ {
 		if !limiter.Allow(user1) {
 			t.Errorf("Expected request %d to be allowed for user1", i+1)
 		}
 	}
-	
+
 	// 4th request for user1 should be denied (minute limit)
 	if limiter.Allow(user1) {
 		t.Errorf("Expected 4th request to be denied for user1")
 	}
-	
+
 	// But user2 should still be allowed
 	for i := 0; i < 3; i++ {
 		if !limiter.Allow(user2) {
 			t.Errorf("Expected request %d to be allowed for user2", i+1)
 		}
 	}
-	
+
 	// Reset for user1
 	limiter.ResetFor(user1)
-	
+
 	// Now user1 should be allowed again
 	if !limiter.Allow(user1) {
 		t.Errorf("Expected request to be allowed for user1 after reset")
@@ -67,16 +68,16 @@ func TestRateLimiterAllow(t *testing.T) {
 func TestRateLimiterHourlyLimit(t *testing.T) {
 	// Create a limiter with high minute limit but low hour limit
 	limiter := New(100, 3)
-	
+
 	user := int64(2001)
-	
+
 	// First 3 requests should be allowed
 	for i := 0; i < 3; i++ {
 		if !limiter.Allow(user) {
 			t.Errorf("Expected request %d to be allowed", i+1)
 		}
 	}
-	
+
 	// 4th request should be denied (hourly limit)
 	if limiter.Allow(user) {
 		t.Errorf("Expected 4th request to be denied due to hourly limit")
@@ -85,60 +86,89 @@ func TestRateLimiterHourlyLimit(t *testing.T) {
 
 func TestRateLimiterRemaining(t *testing.T) {
 	limiter := New(5, 50)
-	
+
 	user := int64(3001)
-	
+
 	// Check initial remaining counts
 	if remaining := limiter.RemainingMinute(user); remaining != 5 {
 		t.Errorf("Expected 5 requests remaining for new user minute, got %d", remaining)
 	}
-	
+
 	if remaining := limiter.RemainingHour(user); remaining != 50 {
 		t.Errorf("Expected 50 requests remaining for new user hour, got %d", remaining)
 	}
-	
+
 	// Make some requests
 	for i := 0; i < 3; i++ {
 		limiter.Allow(user)
 	}
-	
+
 	// Check updated remaining counts
 	if remaining := limiter.RemainingMinute(user); remaining != 2 {
 		t.Errorf("Expected 2 requests remaining after 3 requests, got %d", remaining)
 	}
-	
+
 	if remaining := limiter.RemainingHour(user); remaining != 47 {
 		t.Errorf("Expected 47 requests remaining for hour, got %d", remaining)
 	}
 }
 
+func TestRateLimiterRetryAfter(t *testing.T) {
+	limiter := New(2, 3)
+	user := int64(3002)
+
+	if wait := limiter.RetryAfter(user); wait != 0 {
+		t.Errorf("Expected no wait for a new user, got %v", wait)
+	}
+
+	limiter.Allow(user)
+	if wait := limiter.RetryAfter(user); wait != 0 {
+		t.Errorf("Expected no wait below the limit, got %v", wait)
+	}
+
+	// The minute window is full until the first request expires
+	limiter.Allow(user)
+	if wait := limiter.RetryAfter(user); wait <= 55*time.Second || wait > time.Minute {
+		t.Errorf("Expected a wait of about a minute, got %v", wait)
+	}
+
+	// A full hour window outlasts the minute window
+	limiter.ResetFor(user)
+	limiter.mu.Lock()
+	limiter.userRequests[IDKey(user)] = &userRequestData{hourRequests: []time.Time{time.Now().Add(-30 * time.Minute), time.Now(), time.Now()}}
+	limiter.mu.Unlock()
+	if wait := limiter.RetryAfter(user); wait <= 29*time.Minute || wait > 30*time.Minute {
+		t.Errorf("Expected a wait of about 30 minutes, got %v", wait)
+	}
+}
+
 func TestRateLimiterCleanup(t *testing.T) {
 	limiter := New(3, 10)
 	user := int64(4001)
-	
+
 	// Make 3 requests (hitting the minute limit)
 	for i := 0; i < 3; i++ {
 		limiter.Allow(user)
 	}
-	
+
 	// Additional request should be denied
 	if limiter.Allow(user) {
 		t.Errorf("Expected request to be denied after limit reached")
 	}
-	
+
 	// Manually force cleanup with time in the future
 	// This is a direct test of the cleaning logic
 	limiter.mu.Lock()
-	data := limiter.userRequests[user]
-	
+	data := limiter.userRequests[IDKey(user)]
+
 	// Create minute timestamps older than one minute
 	now := time.Now()
 	oldTime := now.Add(-2 * time.Minute)
 	data.minuteRequests = []time.Time{oldTime, oldTime, oldTime}
-	
-	limiter.cleanupUserData(user, now)
+
+	limiter.cleanupUserData(IDKey(user), now)
 	limiter.mu.Unlock()
-	
+
 	// Now should be allowed again because old requests were cleaned
 	if !limiter.Allow(user) {
 		t.Errorf("Expected request to be allowed after cleanup")
@@ -148,7 +178,7 @@ func TestRateLimiterCleanup(t *testing.T) {
 func TestRateLimiterConcurrentUsers(t *testing.T) {
 	// Test with lots of different users to ensure map works correctly
 	limiter := New(5, 20)
-	
+
 	// Test with 100 different users
 	for i := int64(1); i <= 100; i++ {
 		// Each user should be able to make 5 requests
@@ -157,13 +187,13 @@ func TestRateLimiterConcurrentUsers(t *testing.T) {
 				t.Errorf("Expected request %d to be allowed for user %d", j+1, i)
 			}
 		}
-		
+
 		// 6th request should be denied
 		if limiter.Allow(i) {
 			t.Errorf("Expected 6th request to be denied for user %d", i)
 		}
 	}
-	
+
 	// Check we have entries for all users
 	limiter.mu.Lock()
 	if len(limiter.userRequests) != 100 {
@@ -177,7 +207,7 @@ func TestRateLimiterTimeWindow(t *testing.T) {
 	// Best practice would be to inject a clock, but keeping it simple for this example
 	limiter := New(5, 10)
 	user := int64(5001)
-	
+
 	// Create a sequence of requests in the past that should have expired
 	limiter.mu.Lock()
 	data := &userRequestData{
@@ -185,22 +215,22 @@ func TestRateLimiterTimeWindow(t *testing.T) {
 		hourRequests:   make([]time.Time, 0),
 		lastCleanup:    time.Now().Add(-30 * time.Minute),
 	}
-	
+
 	// Add one recent request that shouldn't expire
 	now := time.Now()
 	data.minuteRequests = append(data.minuteRequests, now.Add(-30*time.Second))
 	data.hourRequests = append(data.hourRequests, now.Add(-30*time.Second))
-	
+
 	// Add some expired requests (older than 1 minute for minute window)
 	for i := 0; i < 10; i++ {
 		oldTime := now.Add(-time.Duration(90+i) * time.Second)
 		data.minuteRequests = append(data.minuteRequests, oldTime)
 		data.hourRequests = append(data.hourRequests, oldTime)
 	}
-	
-	limiter.userRequests[user] = data
+
+	limiter.userRequests[IDKey(user)] = data
 	limiter.mu.Unlock()
-	
+
 	// Now only the recent request should count, and we should have 4 remaining
 	if remaining := limiter.RemainingMinute(user); remaining != 4 {
 		t.Errorf("Expected 4 requests remaining after cleanup, got %d", remaining)
@@ -211,4 +241,66 @@ func TestRateLimiterClose(t *testing.T) {
 	// Test that Close doesn't panic
 	limiter := New(10, 100)
 	limiter.Close()
-}
\ No newline at end of file
+}
+
+func TestRateLimiterKeys(t *testing.T) {
+	limiter := New(1, 10)
+	defer limiter.Close()
+
+	// "ip:Aa" and "ip:BB" share a 31-based string hash but not a key
+	if !limiter.AllowKey("ip:Aa") || !limiter.AllowKey("ip:BB") {
+		t.Error("Expected clients with different keys to have separate limits")
+	}
+	if limiter.AllowKey("ip:Aa") {
+		t.Error("Expected the second request of a client to be denied")
+	}
+
+	// Numeric IDs are keys too
+	if !limiter.Allow(42) || limiter.AllowKey(IDKey(42)) {
+		t.Error("Expected a numeric ID and its key to share a limit")
+	}
+	limiter.ResetKey("ip:Aa")
+	if limiter.RemainingMinuteKey("ip:Aa") != 1 || limiter.RemainingHourKey("ip:BB") != 9 {
+		t.Error("Unexpected remaining requests after reset")
+	}
+}
+
+func TestKeyFromContext(t *testing.T) {
+	ctx := NewContext(context.Background(), "client:abc@10.0.0.1")
+	if key := KeyFromContext(ctx, "42"); key != "client:abc@10.0.0.1" {
+		t.Errorf("Expected the key of the context, got %q", key)
+	}
+	if key := KeyFromContext(context.Background(), "42"); key != "42" {
+		t.Errorf("Expected the fallback without a key, got %q", key)
+	}
+	if key := KeyFromContext(nil, "42"); key != "42" {
+		t.Errorf("Expected the fallback without a context, got %q", key)
+	}
+}
+
+func TestRateLimiterStats(t *testing.T) {
+	limiter := New(2, 100)
+	defer limiter.Close()
+
+	for i := 0; i < 4; i++ {
+		limiter.AllowKey("ip:203.0.113.7")
+	}
+	limiter.AllowKey("ip:198.51.100.1")
+
+	stats := limiter.Stats(1)
+	if stats.TrackedClients != 2 || stats.Allowed != 3 || stats.RejectedMinute != 2 || stats.RejectedHour != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if len(stats.TopConsumers) != 1 {
+		t.Fatalf("Expected the top consumer only, got %+v", stats.TopConsumers)
+	}
+	top := stats.TopConsumers[0]
+	if top.Key != "ip:203.0.113.7" || top.LastHour != 2 || top.LastMinute != 2 || top.Rejected != 2 {
+		t.Errorf("Unexpected top consumer: %+v", top)
+	}
+
+	// A negative count returns every client
+	if all := limiter.Stats(-1); len(all.TopConsumers) != 2 {
+		t.Errorf("Expected all clients, got %+v", all.TopConsumers)
+	}
+}
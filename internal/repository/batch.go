@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// ErrBatchUnsupported is returned by AddUsers of a wrapper whose underlying
+// repository cannot add users in batches
+var ErrBatchUnsupported = errors.New("repository does not support batch adds")
+
+// insertBatchRows is the number of users per INSERT statement. With twelve
+// columns it stays below the parameter limit of older SQLite versions.
+const insertBatchRows = 80
+
+// BatchAdder is implemented by repositories that can add many users in a
+// single write, such as a multi-row INSERT
+type BatchAdder interface {
+	// AddUsers adds all users or none of them. If any email is already
+	// stored, nothing is written.
+	AddUsers(ctx any, users []*domain.User) error
+}
+
+// insertUsers adds users with multi-row INSERTs in a single transaction
+func insertUsers(ctx context.Context, db *sql.DB, users []*domain.User, ph placeholder) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(users); start += insertBatchRows {
+		chunk := users[start:min(start+insertBatchRows, len(users))]
+
+		var rows []string
+		var args []any
+		for _, user := range chunk {
+			stampUser(user)
+			values := userArgs(user)
+			params := make([]string, len(values))
+			for i := range values {
+				params[i] = ph(len(args) + i + 1)
+			}
+			rows = append(rows, "("+strings.Join(params, ", ")+")")
+			args = append(args, values...)
+		}
+
+		query := "INSERT INTO users (" + userColumns + ") VALUES " + strings.Join(rows, ", ")
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// minBloomCapacity is the number of emails a filter is sized for at least,
+// so that an empty guest list can grow without rebuilding at once
+const minBloomCapacity = 1024
+
+// bloomFilter is a set of emails that can answer that an email was
+// certainly not added, or that it may have been
+type bloomFilter struct {
+	bits   []uint64
+	size   uint64 // Number of bits
+	hashes uint64 // Number of bits set per email
+}
+
+// newBloomFilter sizes a filter for capacity emails at the given false positive rate
+func newBloomFilter(capacity int, rate float64) *bloomFilter {
+	n := float64(capacity)
+	size := uint64(math.Ceil(-n * math.Log(rate) / (math.Ln2 * math.Ln2)))
+	size = max(size, 64)
+	hashes := uint64(math.Round(float64(size) / n * math.Ln2))
+	hashes = max(hashes, 1)
+
+	return &bloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// positions returns the two hashes the bits of the key are derived from
+func positions(key string) (uint64, uint64) {
+	h1 := fnv.New64a()
+	h1.Write([]byte(key))
+	h2 := fnv.New64()
+	h2.Write([]byte(key))
+	// An odd step visits different bits for every hash
+	return h1.Sum64(), h2.Sum64() | 1
+}
+
+// add puts a normalized email in the filter
+func (f *bloomFilter) add(key string) {
+	h1, h2 := positions(key)
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.size
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// mayContain returns false if the normalized email was certainly not added
+func (f *bloomFilter) mayContain(key string) bool {
+	h1, h2 := positions(key)
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.size
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// BloomRepository answers lookups of emails that are certainly not on the
+// guest list without asking the repository. Most emails checked at the
+// door are not on the list, and a bloom filter built from the repository
+// tells them apart in memory. Emails the filter may contain, including a
+// share of unknown ones set by the false positive rate, are looked up in
+// the repository as before.
+type BloomRepository struct {
+	domain.Repository
+	rate    float64       // False positive rate the filter is sized for
+	rebuild time.Duration // How often the filter is built again, 0 never rebuilds it
+	logger  *logger.Logger
+
+	mu       sync.RWMutex
+	filter   *bloomFilter // nil until the first build succeeds, when every lookup goes to the repository
+	capacity int          // Emails the filter is sized for
+	count    int          // Emails added to the filter
+	added    []string     // Emails added while a build runs, nil if none runs
+	building atomic.Bool
+
+	skipped atomic.Int64 // Lookups answered by the filter
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewBloomRepository builds a bloom filter of the emails in the repository.
+// If the build fails, lookups go to the repository until a rebuild succeeds.
+func NewBloomRepository(ctx any, repo domain.Repository, rate float64, rebuild time.Duration, logger *logger.Logger) (*BloomRepository, error) {
+	if repo == nil {
+		return nil, errors.New("repository is required")
+	}
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+	if rate <= 0 || rate >= 1 {
+		return nil, fmt.Errorf("false positive rate must be between 0 and 1, got %g", rate)
+	}
+
+	r := &BloomRepository{
+		Repository: repo,
+		rate:       rate,
+		rebuild:    rebuild,
+		logger:     logger,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if err := r.Rebuild(ctx); err != nil {
+		logger.Warn("Bloom filter not built, looking up every email in the database", "error", err)
+	}
+
+	go r.rebuildLoop()
+	return r, nil
+}
+
+// Rebuild replaces the filter with one built from all emails in the repository
+func (r *BloomRepository) Rebuild(ctx any) error {
+	if !r.building.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer r.building.Store(false)
+
+	// Emails added during the build may be missing from the report, so
+	// they are collected and added to the new filter as well
+	r.mu.Lock()
+	r.added = []string{}
+	r.mu.Unlock()
+
+	start := time.Now()
+	users, err := r.Repository.GetReport(ctx, domain.ReportParams{
+		Type: domain.ReportTypeAll,
+		From: time.Unix(0, 0),
+		To:   time.Now().AddDate(100, 0, 0),
+	})
+	if err != nil {
+		r.mu.Lock()
+		r.added = nil
+		r.mu.Unlock()
+		return fmt.Errorf("failed to load guest list: %w", err)
+	}
+
+	// Leave room to grow, so guests added during the event keep the
+	// false positive rate near the configured one
+	capacity := max(2*len(users), minBloomCapacity)
+	filter := newBloomFilter(capacity, r.rate)
+	for _, user := range users {
+		filter.add(utils.NormalizeEmail(user.Email))
+	}
+
+	r.mu.Lock()
+	for _, key := range r.added {
+		filter.add(key)
+	}
+	r.filter = filter
+	r.capacity = capacity
+	r.count = len(users) + len(r.added)
+	r.added = nil
+	r.mu.Unlock()
+
+	r.logger.Info("Bloom filter built", "users", len(users), "bytes", len(filter.bits)*8, "hashes", filter.hashes,
+		"false_positive_rate", r.rate, "duration", time.Since(start))
+	return nil
+}
+
+// rebuildLoop builds the filter again at the rebuild interval until Close
+func (r *BloomRepository) rebuildLoop() {
+	defer close(r.done)
+	if r.rebuild <= 0 {
+		<-r.stop
+		return
+	}
+
+	ticker := time.NewTicker(r.rebuild)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Rebuild(nil); err != nil {
+				r.logger.Warn("Keeping the current bloom filter", "error", err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// remember adds emails to the filter before they are written, so a lookup
+// right after the write cannot miss them. A failed write only leaves a
+// false positive behind.
+func (r *BloomRepository) remember(emails ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, email := range emails {
+		key := utils.NormalizeEmail(email)
+		if r.added != nil {
+			r.added = append(r.added, key)
+		}
+		if r.filter != nil {
+			r.filter.add(key)
+			r.count++
+		}
+	}
+
+	// A full filter answers "may contain" more often, so it is built
+	// again with more room
+	if r.filter != nil && r.count > r.capacity && !r.building.Load() {
+		go func() {
+			if err := r.Rebuild(nil); err != nil {
+				r.logger.Warn("Failed to grow the bloom filter", "error", err)
+			}
+		}()
+	}
+}
+
+// FindByEmail returns ErrUserNotFound for emails certainly not on the
+// guest list, and looks up all others in the repository
+func (r *BloomRepository) FindByEmail(ctx any, email string) (*domain.User, error) {
+	r.mu.RLock()
+	known := r.filter == nil || r.filter.mayContain(utils.NormalizeEmail(email))
+	r.mu.RUnlock()
+
+	if !known {
+		r.skipped.Add(1)
+		return nil, domain.ErrUserNotFound
+	}
+	return r.Repository.FindByEmail(ctx, email)
+}
+
+// UpdateUser adds the email to the filter and updates the guest in the repository
+func (r *BloomRepository) UpdateUser(ctx any, user *domain.User) error {
+	r.remember(user.Email)
+	return r.Repository.UpdateUser(ctx, user)
+}
+
+// AddUser adds the email to the filter and the guest to the repository
+func (r *BloomRepository) AddUser(ctx any, user *domain.User) error {
+	r.remember(user.Email)
+	return r.Repository.AddUser(ctx, user)
+}
+
+// AddUsers adds guests in a batch if the repository supports batches
+func (r *BloomRepository) AddUsers(ctx any, users []*domain.User) error {
+	adder, ok := r.Repository.(BatchAdder)
+	if !ok {
+		return ErrBatchUnsupported
+	}
+	emails := make([]string, len(users))
+	for i, user := range users {
+		emails[i] = user.Email
+	}
+	r.remember(emails...)
+	return adder.AddUsers(ctx, users)
+}
+
+// NormalizeEmails normalizes stored emails in the repository and builds
+// the filter again
+func (r *BloomRepository) NormalizeEmails(ctx any) (int, error) {
+	normalizer, ok := r.Repository.(EmailNormalizer)
+	if !ok {
+		return 0, errors.New("repository does not support email normalization")
+	}
+	changed, err := normalizer.NormalizeEmails(ctx)
+	if err != nil {
+		return changed, err
+	}
+	return changed, r.Rebuild(ctx)
+}
+
+// Stats returns statistics of the repository with the lookups answered by the filter
+func (r *BloomRepository) Stats(ctx any) (domain.RepoStats, error) {
+	stats, err := r.Repository.Stats(ctx)
+	if err != nil {
+		return stats, err
+	}
+	if stats.Details == nil {
+		stats.Details = make(map[string]string)
+	}
+
+	stats.Details["bloom_skipped_lookups"] = fmt.Sprint(r.skipped.Load())
+	r.mu.RLock()
+	if r.filter != nil {
+		stats.Details["bloom_bytes"] = fmt.Sprint(len(r.filter.bits) * 8)
+	}
+	r.mu.RUnlock()
+	return stats, nil
+}
+
+// Close stops rebuilding the filter and closes the repository
+func (r *BloomRepository) Close() error {
+	close(r.stop)
+	<-r.done
+	return r.Repository.Close()
+}
@@ -0,0 +1,71 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+)
+
+func TestBloomRepository(t *testing.T) {
+	now := time.Now().Format(time.RFC3339)
+	var rows strings.Builder
+	for i := 1; i <= 200; i++ {
+		fmt.Fprintf(&rows, "%d,user%d@example.com,%s,,\n", i, i, now)
+	}
+	backend := &flakyRepository{Repository: newCSVForTest(t, rows.String())}
+
+	repo, err := repository.NewBloomRepository(context.Background(), backend, 0.01, 0, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create bloom repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	if _, err := repo.FindByEmail(ctx, "User7@Example.com"); err != nil {
+		t.Fatalf("Failed to find user: %v", err)
+	}
+
+	// With the backend down, emails answered by the filter still come back
+	// as not found, while known emails reach the backend
+	backend.down = true
+	if _, err := repo.FindByEmail(ctx, "user7@example.com"); !errors.Is(err, errConnectionRefused) {
+		t.Errorf("Expected known email to be looked up, got %v", err)
+	}
+	answered := 0
+	for i := 0; i < 1000; i++ {
+		if _, err := repo.FindByEmail(ctx, fmt.Sprintf("stranger%d@example.com", i)); domain.IsNotFound(err) {
+			answered++
+		}
+	}
+	if answered < 950 {
+		t.Errorf("Expected most unknown emails to be answered by the filter, got %d of 1000", answered)
+	}
+
+	// Added guests are known to the filter right away
+	backend.down = false
+	if err := repo.AddUser(ctx, &domain.User{ID: "201", Email: "late@example.com", DateAdded: time.Now()}); err != nil {
+		t.Fatalf("Failed to add user: %v", err)
+	}
+	if _, err := repo.FindByEmail(ctx, "late@example.com"); err != nil {
+		t.Errorf("Expected added user to be found, got %v", err)
+	}
+
+	stats, err := repo.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.Details["bloom_skipped_lookups"] != fmt.Sprint(answered) {
+		t.Errorf("Expected %d skipped lookups, got %s", answered, stats.Details["bloom_skipped_lookups"])
+	}
+
+	if _, err := repository.NewBloomRepository(ctx, backend, 1.5, 0, logger.New("error")); err == nil {
+		t.Error("Expected an invalid false positive rate to be rejected")
+	}
+}
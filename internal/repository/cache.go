@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// CachedRepository keeps every guest of a repository in memory, loaded
+// at startup and at a refresh interval, and answers lookups of known
+// guests from there. Writes go to the repository first and update the
+// cache once they succeed. Lookups of emails not in the cache go to the
+// repository, so guests added by other processes are still found.
+type CachedRepository struct {
+	domain.Repository
+	refresh time.Duration // How often the guest list is loaded again, 0 never reloads it
+	logger  *logger.Logger
+
+	mu     sync.RWMutex
+	users  map[string]*domain.User // Map of normalized email -> guest
+	loaded time.Time               // When the guest list was last loaded, zero until the first load succeeds
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewCachedRepository loads all guests of the repository into memory. If
+// the first load fails, lookups go to the repository until a refresh
+// succeeds.
+func NewCachedRepository(ctx any, repo domain.Repository, refresh time.Duration, logger *logger.Logger) (*CachedRepository, error) {
+	if repo == nil {
+		return nil, errors.New("repository is required")
+	}
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+
+	r := &CachedRepository{
+		Repository: repo,
+		refresh:    refresh,
+		logger:     logger,
+		users:      make(map[string]*domain.User),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if err := r.Reload(ctx); err != nil {
+		logger.Warn("Guest list not preloaded, looking up guests in the database", "error", err)
+	}
+
+	go r.refreshLoop()
+	return r, nil
+}
+
+// Reload replaces the cache with all guests of the repository
+func (r *CachedRepository) Reload(ctx any) error {
+	start := time.Now()
+	users, err := r.Repository.GetReport(ctx, domain.ReportParams{
+		Type: domain.ReportTypeAll,
+		From: time.Unix(0, 0),
+		To:   time.Now().AddDate(100, 0, 0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load guest list: %w", err)
+	}
+
+	cached := make(map[string]*domain.User, len(users))
+	var size uintptr
+	for _, user := range users {
+		key := utils.NormalizeEmail(user.Email)
+		cached[key] = copyUser(user)
+		size += cachedSize(key, user)
+	}
+
+	r.mu.Lock()
+	r.users = cached
+	r.loaded = time.Now()
+	r.mu.Unlock()
+
+	r.logger.Info("Guest list preloaded", "users", len(cached), "approx_bytes", size, "duration", time.Since(start))
+	return nil
+}
+
+// refreshLoop reloads the guest list at the refresh interval until Close
+func (r *CachedRepository) refreshLoop() {
+	defer close(r.done)
+	if r.refresh <= 0 {
+		<-r.stop
+		return
+	}
+
+	ticker := time.NewTicker(r.refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Reload(nil); err != nil {
+				r.logger.Warn("Keeping the cached guest list", "error", err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// cachedSize estimates the memory a cached guest takes, including its key
+func cachedSize(key string, user *domain.User) uintptr {
+	size := unsafe.Sizeof(*user) + uintptr(len(key)+len(user.ID)+len(user.Email)+len(user.CreatedBy)+len(user.RedeemedAt)+len(user.Notes)+len(user.FirstName)+len(user.LastName))
+	if user.Redeemed != nil {
+		size += unsafe.Sizeof(*user.Redeemed)
+	}
+	if user.MarketingConsent != nil {
+		size += unsafe.Sizeof(*user.MarketingConsent)
+	}
+	for _, tag := range user.Tags {
+		size += unsafe.Sizeof(tag) + uintptr(len(tag))
+	}
+	return size
+}
+
+// copyUser returns a copy of a guest that shares no pointers with it
+func copyUser(user *domain.User) *domain.User {
+	userCopy := *user
+	if user.Redeemed != nil {
+		redeemed := *user.Redeemed
+		userCopy.Redeemed = &redeemed
+	}
+	if user.MarketingConsent != nil {
+		consent := *user.MarketingConsent
+		userCopy.MarketingConsent = &consent
+	}
+	userCopy.Tags = append([]string(nil), user.Tags...)
+	return &userCopy
+}
+
+// cached returns a copy of the cached guest with the email, if any
+func (r *CachedRepository) cached(email string) (*domain.User, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[utils.NormalizeEmail(email)]
+	if !ok {
+		return nil, false
+	}
+	return copyUser(user), true
+}
+
+// store puts a copy of the guest in the cache
+func (r *CachedRepository) store(user *domain.User) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[utils.NormalizeEmail(user.Email)] = copyUser(user)
+}
+
+// FindByEmail returns a cached guest, or looks up and caches one that is not cached
+func (r *CachedRepository) FindByEmail(ctx any, email string) (*domain.User, error) {
+	if user, ok := r.cached(email); ok {
+		return user, nil
+	}
+
+	user, err := r.Repository.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	r.store(user)
+	return user, nil
+}
+
+// UpdateUser writes the guest to the repository and then to the cache
+func (r *CachedRepository) UpdateUser(ctx any, user *domain.User) error {
+	if err := r.Repository.UpdateUser(ctx, user); err != nil {
+		if errors.Is(err, domain.ErrAlreadyRedeemed) {
+			// Another process changed the guest since it was cached
+			r.forget(user.Email)
+		}
+		return err
+	}
+	r.store(user)
+	return nil
+}
+
+// AddUser adds the guest to the repository and then to the cache
+func (r *CachedRepository) AddUser(ctx any, user *domain.User) error {
+	if err := r.Repository.AddUser(ctx, user); err != nil {
+		return err
+	}
+	r.store(user)
+	return nil
+}
+
+// AddUsers adds guests in a batch if the repository supports batches, and
+// caches them once they are written
+func (r *CachedRepository) AddUsers(ctx any, users []*domain.User) error {
+	adder, ok := r.Repository.(BatchAdder)
+	if !ok {
+		return ErrBatchUnsupported
+	}
+	if err := adder.AddUsers(ctx, users); err != nil {
+		return err
+	}
+	for _, user := range users {
+		r.store(user)
+	}
+	return nil
+}
+
+// NormalizeEmails normalizes stored emails in the repository and reloads
+// the cache, whose keys may have changed
+func (r *CachedRepository) NormalizeEmails(ctx any) (int, error) {
+	normalizer, ok := r.Repository.(EmailNormalizer)
+	if !ok {
+		return 0, errors.New("repository does not support email normalization")
+	}
+	changed, err := normalizer.NormalizeEmails(ctx)
+	if err != nil {
+		return changed, err
+	}
+	return changed, r.Reload(ctx)
+}
+
+// forget drops a guest from the cache, so the next lookup reads it again
+func (r *CachedRepository) forget(email string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.users, utils.NormalizeEmail(email))
+}
+
+// Stats returns statistics of the repository with the size and age of the cache
+func (r *CachedRepository) Stats(ctx any) (domain.RepoStats, error) {
+	stats, err := r.Repository.Stats(ctx)
+	if err != nil {
+		return stats, err
+	}
+	if stats.Details == nil {
+		stats.Details = make(map[string]string)
+	}
+
+	r.mu.RLock()
+	stats.Details["cached_users"] = fmt.Sprint(len(r.users))
+	if !r.loaded.IsZero() {
+		stats.Details["cache_loaded"] = r.loaded.UTC().Format(time.RFC3339)
+	}
+	r.mu.RUnlock()
+	return stats, nil
+}
+
+// Close stops refreshing the cache and closes the repository
+func (r *CachedRepository) Close() error {
+	close(r.stop)
+	<-r.done
+	return r.Repository.Close()
+}
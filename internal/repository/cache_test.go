@@ -0,0 +1,90 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+)
+
+func TestCachedRepository(t *testing.T) {
+	now := time.Now().Format(time.RFC3339)
+	backend := &flakyRepository{Repository: newCSVForTest(t, "1,user1@example.com,"+now+",,\n")}
+
+	repo, err := repository.NewCachedRepository(context.Background(), backend, 0, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create cached repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	// Preloaded guests are found while the backend is slow or down
+	backend.down = true
+	user, err := repo.FindByEmail(ctx, "User1@Example.com")
+	if err != nil {
+		t.Fatalf("Failed to find preloaded user: %v", err)
+	}
+
+	// Writes go to the backend first and reach the cache once they succeed
+	user.Redeem()
+	if err := repo.UpdateUser(ctx, user); err == nil {
+		t.Fatalf("Expected update to fail while the backend is down")
+	}
+	if cached, _ := repo.FindByEmail(ctx, "user1@example.com"); cached.IsRedeemed() {
+		t.Fatalf("Failed update should not change the cache")
+	}
+
+	backend.down = false
+	if err := repo.UpdateUser(ctx, user); err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+	if err := repo.AddUser(ctx, &domain.User{ID: "2", Email: "user2@example.com", DateAdded: time.Now()}); err != nil {
+		t.Fatalf("Failed to add user: %v", err)
+	}
+
+	backend.down = true
+	if cached, err := repo.FindByEmail(ctx, "user1@example.com"); err != nil || !cached.IsRedeemed() {
+		t.Errorf("Expected cached redemption, got %+v (%v)", cached, err)
+	}
+	if _, err := repo.FindByEmail(ctx, "user2@example.com"); err != nil {
+		t.Errorf("Expected added user to be cached, got %v", err)
+	}
+
+	// Guests missing from the cache are looked up in the backend
+	backend.down = false
+	if _, err := repo.FindByEmail(ctx, "nobody@example.com"); !domain.IsNotFound(err) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+
+	stats, err := repo.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.Details["cached_users"] != "2" {
+		t.Errorf("Expected 2 cached users, got %s", stats.Details["cached_users"])
+	}
+}
+
+func TestCachedRepositoryPreloadFailure(t *testing.T) {
+	now := time.Now().Format(time.RFC3339)
+	backend := &flakyRepository{Repository: newCSVForTest(t, "1,user1@example.com,"+now+",,\n"), down: true}
+
+	repo, err := repository.NewCachedRepository(context.Background(), backend, 0, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Expected a failed preload to fall back to direct queries, got %v", err)
+	}
+	defer repo.Close()
+
+	// Lookups go to the backend until a refresh succeeds
+	backend.down = false
+	if _, err := repo.FindByEmail(context.Background(), "user1@example.com"); err != nil {
+		t.Fatalf("Failed to find user in backend: %v", err)
+	}
+	if err := repo.Reload(context.Background()); err != nil {
+		t.Fatalf("Failed to reload guest list: %v", err)
+	}
+}
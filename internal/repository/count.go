@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// ReportCounter is implemented by repositories that can count the users of
+// a report without loading them, such as with a COUNT query
+type ReportCounter interface {
+	CountReport(ctx any, params domain.ReportParams) (int, error)
+}
+
+// CountReport counts the users of a report, without loading them if the
+// repository can count, and from the full report otherwise
+func CountReport(ctx any, repo domain.Repository, params domain.ReportParams) (int, error) {
+	if counter, ok := repo.(ReportCounter); ok {
+		return counter.CountReport(ctx, params)
+	}
+	users, err := repo.GetReport(ctx, params)
+	return len(users), err
+}
+
+// CountReport counts the users of a report with a COUNT query
+func (r *SQLiteRepository) CountReport(ctx any, params domain.ReportParams) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count, err := countReportSQL(context.Background(), r.db, params, questionPlaceholder)
+	if err != nil {
+		r.logger.Error("Error counting report", "type", params.Type, "error", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	return count, nil
+}
+
+// CountReport counts the users of a report with a COUNT query
+func (r *PostgresRepository) CountReport(ctx any, params domain.ReportParams) (int, error) {
+	ctxWithTimeout, cancel := r.bulkContext()
+	defer cancel()
+
+	count, err := countReportSQL(ctxWithTimeout, r.db, params, dollarPlaceholder)
+	if err != nil {
+		r.logger.Error("Failed to count report", "type", params.Type, "error", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	return count, nil
+}
+
+// CountReport counts the users of a report with a COUNT query
+func (r *MySQLRepository) CountReport(ctx any, params domain.ReportParams) (int, error) {
+	ctxWithTimeout, cancel := r.bulkContext()
+	defer cancel()
+
+	count, err := countReportSQL(ctxWithTimeout, r.db, params, questionPlaceholder)
+	if err != nil {
+		r.logger.Error("Failed to count report", "type", params.Type, "error", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	return count, nil
+}
+
+// CountReport counts the users of a report in the repository
+func (r *CachedRepository) CountReport(ctx any, params domain.ReportParams) (int, error) {
+	return CountReport(ctx, r.Repository, params)
+}
+
+// CountReport counts the users of a report in the repository
+func (r *BloomRepository) CountReport(ctx any, params domain.ReportParams) (int, error) {
+	return CountReport(ctx, r.Repository, params)
+}
+
+// CountReport counts the users of a report in the repository
+func (r *NegativeCacheRepository) CountReport(ctx any, params domain.ReportParams) (int, error) {
+	return CountReport(ctx, r.Repository, params)
+}
+
+// CountReport counts the users of a report in the primary, or in the
+// fallback if the primary is down
+func (r *FailoverRepository) CountReport(ctx any, params domain.ReportParams) (int, error) {
+	if r.usePrimary() {
+		count, err := CountReport(ctx, r.primary, params)
+		if !primaryFailed(err) {
+			r.markRecovered()
+			return count, err
+		}
+		r.markFailed("report", err)
+	}
+	return CountReport(ctx, r.fallback, params)
+}
+
+// Ensure the database repositories count with queries, and the wrappers
+// pass counts through
+var (
+	_ ReportCounter = (*SQLiteRepository)(nil)
+	_ ReportCounter = (*PostgresRepository)(nil)
+	_ ReportCounter = (*MySQLRepository)(nil)
+	_ ReportCounter = (*MongoDBRepository)(nil)
+	_ ReportCounter = (*CachedRepository)(nil)
+	_ ReportCounter = (*BloomRepository)(nil)
+	_ ReportCounter = (*FailoverRepository)(nil)
+	_ ReportCounter = (*NegativeCacheRepository)(nil)
+)
@@ -3,18 +3,25 @@ package repository
 import (
 	"encoding/csv"
 	"errors"
+	"fmt"
 	"io"
 	"os"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/ceesaxp/cocktail-bot/internal/domain"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
 )
 
+// csvHeader lists the columns of the CSV file. Files created by older
+// versions lack the trailing columns and are upgraded on the next write.
+var csvHeader = []string{"ID", "Email", "DateAdded", "Redeemed", "MarketingConsent", "UpdatedAt", "CreatedBy", "Bar", "Notes", "Tags", "FirstName", "LastName"}
+
 type CSVRepository struct {
 	filePath string
 	logger   *logger.Logger
+	mu       sync.Mutex // Serializes read-modify-write cycles of the file
 }
 
 func NewCSVRepository(filePath string, logger *logger.Logger) (*CSVRepository, error) {
@@ -39,7 +46,7 @@ func NewCSVRepository(filePath string, logger *logger.Logger) (*CSVRepository, e
 		defer writer.Flush()
 		
 		// Write header
-		err = writer.Write([]string{"ID", "Email", "DateAdded", "Redeemed"})
+		err = writer.Write(csvHeader)
 		if err != nil {
 			return nil, err
 		}
@@ -70,6 +77,7 @@ func (r *CSVRepository) FindByEmail(ctx any, email string) (*domain.User, error)
 	defer file.Close()
 
 	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
 
 	// Read header
 	_, err = reader.Read()
@@ -86,10 +94,10 @@ func (r *CSVRepository) FindByEmail(ctx any, email string) (*domain.User, error)
 		}
 
 		// Check if this is the email we're looking for (case-insensitive)
-			if len(record) >= 2 && strings.EqualFold(record[1], email) {
+			if len(record) >= 2 && sameEmail(record[1], email) {
 			user := &domain.User{
 				ID:    record[0],
-				Email: record[1],
+				Email: utils.NormalizeEmail(record[1]),
 			}
 
 			// Parse DateAdded
@@ -108,6 +116,29 @@ func (r *CSVRepository) FindByEmail(ctx any, email string) (*domain.User, error)
 				}
 			}
 
+			// Parse MarketingConsent
+			if len(record) >= 5 && record[4] != "" {
+				consent, err := time.Parse(time.RFC3339, record[4])
+				if err == nil {
+					user.MarketingConsent = &consent
+				}
+			}
+			user.UpdatedAt = parseCSVUpdatedAt(record, user)
+			if len(record) >= 7 {
+				user.CreatedBy = record[6]
+			}
+			if len(record) >= 8 {
+				user.RedeemedAt = record[7]
+			}
+			if len(record) >= 10 {
+				user.Notes = record[8]
+				user.Tags = domain.SplitTags(record[9])
+			}
+			if len(record) >= 12 {
+				user.FirstName = record[10]
+				user.LastName = record[11]
+			}
+
 			r.logger.Debug("Found user in CSV", "email", email, "redeemed", user.IsRedeemed())
 			return user, nil
 		}
@@ -124,6 +155,9 @@ func (r *CSVRepository) UpdateUser(ctx any, user *domain.User) error {
 
 	r.logger.Debug("Updating user in CSV", "email", user.Email)
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	// Read all records
 	file, err := os.Open(r.filePath)
 	if err != nil {
@@ -132,6 +166,7 @@ func (r *CSVRepository) UpdateUser(ctx any, user *domain.User) error {
 	}
 
 	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
 	records, err := reader.ReadAll()
 	if err != nil {
 		file.Close()
@@ -147,16 +182,21 @@ func (r *CSVRepository) UpdateUser(ctx any, user *domain.User) error {
 			continue
 		}
 
-		if len(record) >= 2 && strings.EqualFold(record[1], user.Email) {
+		if len(record) >= 2 && sameEmail(record[1], user.Email) {
 			// Update record
+			record = padCSVRecord(record)
 			record[0] = user.ID
+			record[1] = utils.NormalizeEmail(user.Email)
 			record[2] = user.DateAdded.Format(time.RFC3339)
-
-			if user.Redeemed != nil {
-				record[3] = user.Redeemed.Format(time.RFC3339)
-			} else {
-				record[3] = ""
-			}
+			record[3] = formatCSVTime(user.Redeemed)
+			record[4] = formatCSVTime(user.MarketingConsent)
+			stampUser(user)
+			record[5] = user.UpdatedAt.Format(time.RFC3339Nano)
+			record[7] = user.RedeemedAt
+			record[8] = user.Notes
+			record[9] = domain.JoinTags(user.Tags)
+			record[10] = user.FirstName
+			record[11] = user.LastName
 
 			records[i] = record
 			found = true
@@ -170,6 +210,11 @@ func (r *CSVRepository) UpdateUser(ctx any, user *domain.User) error {
 		return domain.ErrUserNotFound
 	}
 
+	// Upgrade header written by older versions
+	if len(records) > 0 {
+		records[0] = csvHeader
+	}
+
 	// Write all records back
 	outFile, err := os.Create(r.filePath)
 	if err != nil {
@@ -198,6 +243,11 @@ func (r *CSVRepository) AddUser(ctx any, user *domain.User) error {
 
 	r.logger.Debug("Adding user to CSV", "email", user.Email)
 
+	// The check and the append happen under one lock, so concurrent adds
+	// of the same email cannot both succeed
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	// Read all records
 	file, err := os.Open(r.filePath)
 	if err != nil {
@@ -206,6 +256,7 @@ func (r *CSVRepository) AddUser(ctx any, user *domain.User) error {
 	}
 
 	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
 	records, err := reader.ReadAll()
 	if err != nil {
 		file.Close()
@@ -220,27 +271,22 @@ func (r *CSVRepository) AddUser(ctx any, user *domain.User) error {
 			continue
 		}
 
-		if len(record) >= 2 && strings.EqualFold(record[1], user.Email) {
+		if len(record) >= 2 && sameEmail(record[1], user.Email) {
 			// User already exists, should use UpdateUser instead
 			r.logger.Debug("User already exists", "email", user.Email)
-			return errors.New("user already exists")
+			return domain.NewDuplicateUserError(utils.NormalizeEmail(user.Email), record[0])
 		}
 	}
 
 	// Add new record
-	newRecord := []string{
-		user.ID,
-		user.Email,
-		user.DateAdded.Format(time.RFC3339),
-		"",
-	}
+	stampUser(user)
+	records = append(records, csvRecord(user))
 
-	if user.Redeemed != nil {
-		newRecord[3] = user.Redeemed.Format(time.RFC3339)
+	// Upgrade header written by older versions
+	if len(records) > 0 {
+		records[0] = csvHeader
 	}
 
-	records = append(records, newRecord)
-
 	// Write all records back
 	outFile, err := os.Create(r.filePath)
 	if err != nil {
@@ -275,6 +321,7 @@ func (r *CSVRepository) GetReport(ctx any, params domain.ReportParams) ([]*domai
 	defer file.Close()
 
 	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
 
 	// Read header
 	_, err = reader.Read()
@@ -323,6 +370,49 @@ func (r *CSVRepository) GetReport(ctx any, params domain.ReportParams) ([]*domai
 			}
 		}
 
+		// Parse MarketingConsent
+		var consent *time.Time
+		if len(record) >= 5 && record[4] != "" {
+			parsedConsent, err := time.Parse(time.RFC3339, record[4])
+			if err == nil {
+				consent = &parsedConsent
+			}
+		}
+
+		user := &domain.User{
+			ID:               record[0],
+			Email:            record[1],
+			DateAdded:        dateAdded,
+			Redeemed:         redeemed,
+			MarketingConsent: consent,
+		}
+		user.UpdatedAt = parseCSVUpdatedAt(record, user)
+		if len(record) >= 7 {
+			user.CreatedBy = record[6]
+		}
+		if len(record) >= 8 {
+			user.RedeemedAt = record[7]
+		}
+		if len(record) >= 10 {
+			user.Notes = record[8]
+			user.Tags = domain.SplitTags(record[9])
+		}
+		if len(record) >= 12 {
+			user.FirstName = record[10]
+			user.LastName = record[11]
+		}
+		if !params.Matches(user) {
+			continue
+		}
+
+		// Changes are selected by the time of the last write
+		if params.Type == domain.ReportTypeChanged {
+			if !user.UpdatedAt.Before(params.From) && !user.UpdatedAt.After(params.To) {
+				users = append(users, user)
+			}
+			continue
+		}
+
 		// Apply date filters
 		if !dateAdded.Before(params.From) && !dateAdded.After(params.To) {
 			// Apply report type filter
@@ -330,37 +420,177 @@ func (r *CSVRepository) GetReport(ctx any, params domain.ReportParams) ([]*domai
 			case domain.ReportTypeRedeemed:
 				// Include only redeemed records
 				if redeemed != nil {
-					users = append(users, &domain.User{
-						ID:        record[0],
-						Email:     record[1],
-						DateAdded: dateAdded,
-						Redeemed:  redeemed,
-					})
+					users = append(users, user)
+				}
+			case domain.ReportTypeConsented:
+				// Include only records with marketing consent
+				if consent != nil {
+					users = append(users, user)
 				}
 			case domain.ReportTypeAdded:
 				// Include all records within the date range
-				users = append(users, &domain.User{
-					ID:        record[0],
-					Email:     record[1],
-					DateAdded: dateAdded,
-					Redeemed:  redeemed,
-				})
+				users = append(users, user)
 			case domain.ReportTypeAll:
 				// Include all records
-				users = append(users, &domain.User{
-					ID:        record[0],
-					Email:     record[1],
-					DateAdded: dateAdded,
-					Redeemed:  redeemed,
-				})
+				users = append(users, user)
 			}
 		}
 	}
 
+	if params.Type == domain.ReportTypeChanged {
+		sortByUpdatedAt(users)
+	}
+
 	r.logger.Info("Report generated from CSV", "type", params.Type, "count", len(users))
 	return users, nil
 }
 
+// NormalizeEmails rewrites stored emails to lowercase without surrounding spaces
+func (r *CSVRepository) NormalizeEmails(ctx any) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Read all records
+	file, err := os.Open(r.filePath)
+	if err != nil {
+		r.logger.Error("Failed to open CSV file for normalization", "error", err)
+		return 0, domain.ErrDatabaseUnavailable
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	file.Close()
+	if err != nil {
+		r.logger.Error("Failed to read CSV records", "error", err)
+		return 0, err
+	}
+	if len(records) <= 1 {
+		return 0, nil
+	}
+
+	// Refuse to run if two rows would end up with the same email
+	var emails []string
+	for _, record := range records[1:] {
+		if len(record) >= 2 {
+			emails = append(emails, record[1])
+		}
+	}
+	if dups := duplicateEmails(emails); len(dups) > 0 {
+		return 0, duplicateEmailsError(dups)
+	}
+
+	changed := 0
+	for _, record := range records[1:] {
+		if len(record) >= 2 {
+			if normalized := utils.NormalizeEmail(record[1]); normalized != record[1] {
+				record[1] = normalized
+				changed++
+			}
+		}
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+
+	// Write all records back
+	outFile, err := os.Create(r.filePath)
+	if err != nil {
+		r.logger.Error("Failed to open CSV file for writing", "error", err)
+		return 0, err
+	}
+	defer outFile.Close()
+
+	writer := csv.NewWriter(outFile)
+	if err := writer.WriteAll(records); err != nil {
+		r.logger.Error("Failed to write CSV records", "error", err)
+		return 0, err
+	}
+
+	r.logger.Info("Emails normalized in CSV", "changed", changed)
+	return changed, nil
+}
+
+// csvRecord returns the row of a user, with the columns of csvHeader
+func csvRecord(user *domain.User) []string {
+	return []string{
+		user.ID,
+		utils.NormalizeEmail(user.Email),
+		user.DateAdded.Format(time.RFC3339),
+		formatCSVTime(user.Redeemed),
+		formatCSVTime(user.MarketingConsent),
+		user.UpdatedAt.Format(time.RFC3339Nano),
+		user.CreatedBy,
+		user.RedeemedAt,
+		user.Notes,
+		domain.JoinTags(user.Tags),
+		user.FirstName,
+		user.LastName,
+	}
+}
+
+// padCSVRecord extends rows written by older versions to the current column count
+func padCSVRecord(record []string) []string {
+	for len(record) < len(csvHeader) {
+		record = append(record, "")
+	}
+	return record
+}
+
+// parseCSVUpdatedAt reads the UpdatedAt column, which keeps fractional
+// seconds so that changes within one second stay apart. Rows written by
+// older versions fall back to the latest timestamp of the user.
+func parseCSVUpdatedAt(record []string, user *domain.User) time.Time {
+	if len(record) >= 6 && record[5] != "" {
+		if updatedAt, err := time.Parse(time.RFC3339Nano, record[5]); err == nil {
+			return updatedAt
+		}
+	}
+	return user.LastChange()
+}
+
+// formatCSVTime formats an optional time, leaving the cell empty when unset
+func formatCSVTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// Health checks that the CSV file can be read
+func (r *CSVRepository) Health(ctx any) error {
+	file, err := os.Open(r.filePath)
+	if err != nil {
+		r.logger.Sampled("health_check").Error("CSV health check failed", "error", err)
+		return domain.ErrDatabaseUnavailable
+	}
+	return file.Close()
+}
+
+// Stats returns record counts and file information
+func (r *CSVRepository) Stats(ctx any) (domain.RepoStats, error) {
+	info, err := os.Stat(r.filePath)
+	if err != nil {
+		r.logger.Error("Failed to stat CSV file", "error", err)
+		return domain.RepoStats{Backend: "csv"}, domain.ErrDatabaseUnavailable
+	}
+
+	users, err := r.GetReport(ctx, allUsersReport)
+	if err != nil {
+		return domain.RepoStats{Backend: "csv"}, err
+	}
+
+	stats := statsFromUsers("csv", users)
+
+	// The file modification time also covers edits made outside the bot
+	modTime := info.ModTime()
+	stats.LastWrite = &modTime
+	stats.Details["path"] = r.filePath
+	stats.Details["file_size_bytes"] = fmt.Sprint(info.Size())
+
+	return stats, nil
+}
+
 func (r *CSVRepository) Close() error {
 	r.logger.Debug("Closing CSV repository")
 	// No resources to close for CSV
@@ -2,7 +2,11 @@ package repository_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -157,3 +161,400 @@ func TestCSVRepository(t *testing.T) {
 		t.Errorf("Expected error when adding duplicate user")
 	}
 }
+
+func TestCSVRepository_MarketingConsent(t *testing.T) {
+	// Create a temporary CSV file in the pre-consent four column layout
+	tmpfile, err := os.CreateTemp("", "users*.csv")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	now := time.Now().Truncate(time.Second)
+	initialData := "ID,Email,DateAdded,Redeemed\n" +
+		"1,user1@example.com," + now.Format(time.RFC3339) + ",\n" +
+		"2,user2@example.com," + now.Format(time.RFC3339) + ",\n"
+
+	if _, err := tmpfile.Write([]byte(initialData)); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	if err := tmpfile.Close(); err != nil {
+		t.Fatalf("Failed to close temp file: %v", err)
+	}
+
+	repo, err := repository.NewCSVRepository(tmpfile.Name(), logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	// Record consent for one user
+	user1, err := repo.FindByEmail(ctx, "user1@example.com")
+	if err != nil {
+		t.Fatalf("Failed to find user1: %v", err)
+	}
+	user1.MarketingConsent = &now
+	if err := repo.UpdateUser(ctx, user1); err != nil {
+		t.Fatalf("Failed to update user1: %v", err)
+	}
+
+	// Rows in the old layout are still readable after the upgrade
+	user2, err := repo.FindByEmail(ctx, "user2@example.com")
+	if err != nil {
+		t.Fatalf("Failed to find user2 after upgrade: %v", err)
+	}
+	if user2.HasMarketingConsent() {
+		t.Errorf("User2 should not have marketing consent")
+	}
+
+	// Only the consenting user is in the consent report
+	users, err := repo.GetReport(ctx, domain.ReportParams{
+		Type: domain.ReportTypeConsented,
+		From: now.Add(-time.Hour),
+		To:   now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate consent report: %v", err)
+	}
+	if len(users) != 1 || users[0].Email != "user1@example.com" {
+		t.Fatalf("Expected only user1 in consent report, got %+v", users)
+	}
+	if users[0].MarketingConsent == nil || !users[0].MarketingConsent.Equal(now) {
+		t.Errorf("Expected consent time %v, got %v", now, users[0].MarketingConsent)
+	}
+}
+
+func TestCSVRepository_NormalizeEmails(t *testing.T) {
+	// Create a temporary CSV file with mixed case emails
+	tmpfile, err := os.CreateTemp("", "users*.csv")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	now := time.Now().Truncate(time.Second)
+	initialData := "ID,Email,DateAdded,Redeemed\n" +
+		"1, User1@Example.COM ," + now.Format(time.RFC3339) + ",\n" +
+		"2,user2@example.com," + now.Format(time.RFC3339) + ",\n"
+
+	if _, err := tmpfile.Write([]byte(initialData)); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	if err := tmpfile.Close(); err != nil {
+		t.Fatalf("Failed to close temp file: %v", err)
+	}
+
+	repo, err := repository.NewCSVRepository(tmpfile.Name(), logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	// Lookups ignore case and surrounding spaces even before normalization
+	user1, err := repo.FindByEmail(ctx, "USER1@example.com")
+	if err != nil {
+		t.Fatalf("Failed to find user1 with different case: %v", err)
+	}
+	if user1.ID != "1" {
+		t.Errorf("Expected user1, got: %+v", user1)
+	}
+
+	changed, err := repo.NormalizeEmails(ctx)
+	if err != nil {
+		t.Fatalf("Failed to normalize emails: %v", err)
+	}
+	if changed != 1 {
+		t.Errorf("Expected 1 changed email, got %d", changed)
+	}
+
+	user1, err = repo.FindByEmail(ctx, "user1@example.com")
+	if err != nil {
+		t.Fatalf("Failed to find user1 after normalization: %v", err)
+	}
+	if user1.Email != "user1@example.com" {
+		t.Errorf("Expected normalized email, got %q", user1.Email)
+	}
+
+	// Running again changes nothing
+	changed, err = repo.NormalizeEmails(ctx)
+	if err != nil || changed != 0 {
+		t.Errorf("Expected no changes on second run, got %d (%v)", changed, err)
+	}
+
+	// Rows that would collide are reported instead of merged
+	if err := os.WriteFile(tmpfile.Name(), []byte(initialData+"3,USER2@example.com,"+now.Format(time.RFC3339)+",\n"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite temp file: %v", err)
+	}
+	if _, err := repo.NormalizeEmails(ctx); !errors.Is(err, repository.ErrDuplicateEmails) {
+		t.Errorf("Expected ErrDuplicateEmails, got: %v", err)
+	}
+}
+
+func TestCSVRepository_Stats(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "users*.csv")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	now := time.Now().Truncate(time.Second)
+	initialData := "ID,Email,DateAdded,Redeemed,MarketingConsent\n" +
+		"1,user1@example.com," + now.Format(time.RFC3339) + ",,\n" +
+		"2,user2@example.com," + now.Format(time.RFC3339) + "," + now.Format(time.RFC3339) + "," + now.Format(time.RFC3339) + "\n"
+
+	if _, err := tmpfile.Write([]byte(initialData)); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	if err := tmpfile.Close(); err != nil {
+		t.Fatalf("Failed to close temp file: %v", err)
+	}
+
+	repo, err := repository.NewCSVRepository(tmpfile.Name(), logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	if err := repo.Health(ctx); err != nil {
+		t.Errorf("Expected healthy repository, got: %v", err)
+	}
+
+	stats, err := repo.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.Backend != "csv" || stats.Users != 2 || stats.Redeemed != 1 || stats.Consented != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.LastWrite == nil || stats.Details["file_size_bytes"] == "" {
+		t.Errorf("Expected file information in stats: %+v", stats)
+	}
+
+	// A missing file makes the repository unhealthy
+	os.Remove(tmpfile.Name())
+	if err := repo.Health(ctx); err != domain.ErrDatabaseUnavailable {
+		t.Errorf("Expected ErrDatabaseUnavailable, got: %v", err)
+	}
+}
+
+func TestCSVRepository_ChangedReport(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "users*.csv")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	// A file written by an older version has no UpdatedAt column
+	old := time.Now().AddDate(0, 0, -30).Truncate(time.Second)
+	recent := time.Now().Add(-time.Hour).Truncate(time.Second)
+	initialData := "ID,Email,DateAdded,Redeemed,MarketingConsent\n" +
+		"1,user1@example.com," + old.Format(time.RFC3339) + ",,\n" +
+		"2,user2@example.com," + old.Format(time.RFC3339) + "," + recent.Format(time.RFC3339) + ",\n" +
+		"3,user3@example.com," + old.Format(time.RFC3339) + ",,\n"
+	if err := os.WriteFile(tmpfile.Name(), []byte(initialData), 0644); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+
+	repo, err := repository.NewCSVRepository(tmpfile.Name(), logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	user, err := repo.FindByEmail(ctx, "user3@example.com")
+	if err != nil {
+		t.Fatalf("Failed to find user: %v", err)
+	}
+	// The service records the time of each change
+	user.Redeem()
+	user.UpdatedAt = time.Now()
+	if err := repo.UpdateUser(ctx, user); err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+	if err := repo.AddUser(ctx, &domain.User{ID: "4", Email: "user4@example.com", DateAdded: time.Now(), CreatedBy: "token:abcd"}); err != nil {
+		t.Fatalf("Failed to add user: %v", err)
+	}
+
+	// Legacy rows fall back to their latest timestamp
+	users, err := repo.GetReport(ctx, domain.ReportParams{
+		Type: domain.ReportTypeChanged,
+		From: time.Now().AddDate(0, 0, -1),
+		To:   time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to get changes: %v", err)
+	}
+	if len(users) != 3 || users[0].Email != "user2@example.com" || users[1].Email != "user3@example.com" {
+		t.Fatalf("Expected user2, user3 and user4, got %+v", users)
+	}
+	if !users[0].UpdatedAt.Equal(recent) {
+		t.Errorf("Expected user2 changed at %v, got %v", recent, users[0].UpdatedAt)
+	}
+
+	// The creator is kept, and rows written by older versions have none
+	if users[2].CreatedBy != "token:abcd" || users[0].CreatedBy != "" {
+		t.Errorf("Unexpected creators %q and %q", users[2].CreatedBy, users[0].CreatedBy)
+	}
+	if found, err := repo.FindByEmail(ctx, "user4@example.com"); err != nil || found.CreatedBy != "token:abcd" {
+		t.Errorf("Expected the creator on lookup, got %+v: %v", found, err)
+	}
+}
+
+func TestCSVRepository_ReportFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	repo, err := repository.NewCSVRepository(path, logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	for _, user := range []*domain.User{
+		{ID: "1", Email: "guest@example.com", DateAdded: time.Now(), CreatedBy: "rsvp_import"},
+		{ID: "2", Email: "guest@Other.org", DateAdded: time.Now(), CreatedBy: "token:abcd"},
+		{ID: "3", Email: "staff@other.org", DateAdded: time.Now()},
+	} {
+		if err := repo.AddUser(ctx, user); err != nil {
+			t.Fatalf("Failed to add user: %v", err)
+		}
+	}
+
+	report := func(filter domain.ReportFilter) []*domain.User {
+		users, err := repo.GetReport(ctx, domain.ReportParams{
+			Type:         domain.ReportTypeAll,
+			From:         time.Now().Add(-time.Hour),
+			To:           time.Now(),
+			ReportFilter: filter,
+		})
+		if err != nil {
+			t.Fatalf("Failed to get report: %v", err)
+		}
+		return users
+	}
+
+	if users := report(domain.ReportFilter{Domain: "other.org"}); len(users) != 2 {
+		t.Errorf("Expected 2 guests at other.org, got %+v", users)
+	}
+	if users := report(domain.ReportFilter{Source: "token"}); len(users) != 1 || users[0].ID != "2" {
+		t.Errorf("Expected the guest added with a token, got %+v", users)
+	}
+	if users := report(domain.ReportFilter{Domain: "other.org", Source: "rsvp_import"}); len(users) != 0 {
+		t.Errorf("Expected no guests, got %+v", users)
+	}
+}
+
+func TestCSVRepository_ConcurrentAdd(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "users*.csv")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpfile.Close()
+	os.Remove(tmpfile.Name())
+	defer os.Remove(tmpfile.Name())
+
+	repo, err := repository.NewCSVRepository(tmpfile.Name(), logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	// Concurrent adds of the same email: exactly one wins, the others
+	// learn the ID of the winner
+	const adders = 16
+	errs := make([]error, adders)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < adders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = repo.AddUser(nil, &domain.User{
+				ID:        fmt.Sprintf("user_%d", i),
+				Email:     "Race@Example.com",
+				DateAdded: time.Now(),
+			})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	winner := ""
+	for i, err := range errs {
+		if err == nil {
+			if winner != "" {
+				t.Fatalf("Expected a single successful add, got %s and user_%d", winner, i)
+			}
+			winner = fmt.Sprintf("user_%d", i)
+		}
+	}
+	if winner == "" {
+		t.Fatal("Expected one add to succeed")
+	}
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		var dup *domain.DuplicateUserError
+		if !errors.As(err, &dup) || !errors.Is(err, domain.ErrUserExists) {
+			t.Fatalf("Expected a duplicate user error, got: %v", err)
+		}
+		if dup.ID != winner {
+			t.Errorf("Expected the ID of the winner %s, got %s", winner, dup.ID)
+		}
+	}
+
+	users, err := repo.GetReport(nil, domain.ReportParams{Type: domain.ReportTypeAll, From: time.Time{}, To: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Failed to get report: %v", err)
+	}
+	if len(users) != 1 {
+		t.Errorf("Expected one stored user, got %d", len(users))
+	}
+}
+
+func TestCSVRepository_ChangeEmail(t *testing.T) {
+	repo, err := repository.NewCSVRepository(filepath.Join(t.TempDir(), "users.csv"), logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	for _, user := range []*domain.User{
+		{ID: "1", Email: "gest@example.com", DateAdded: time.Now(), Tags: []string{"vip"}},
+		{ID: "2", Email: "other@example.com", DateAdded: time.Now()},
+	} {
+		if err := repo.AddUser(nil, user); err != nil {
+			t.Fatalf("Failed to add user: %v", err)
+		}
+	}
+
+	if err := repo.ChangeEmail(nil, "gest@example.com", "Guest@Example.com"); err != nil {
+		t.Fatalf("Failed to change email: %v", err)
+	}
+	user, err := repo.FindByEmail(nil, "guest@example.com")
+	if err != nil || user.ID != "1" || len(user.Tags) != 1 || user.Tags[0] != "vip" {
+		t.Errorf("Expected the user under the new email, got %+v, %v", user, err)
+	}
+
+	// Notes and names written by UpdateUser are read back
+	user.Notes = "Table 4, near the bar"
+	user.FirstName, user.LastName = "Anna", "Karenina"
+	if err := repo.UpdateUser(nil, user); err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+	if user, err := repo.FindByEmail(nil, "guest@example.com"); err != nil || user.Notes != "Table 4, near the bar" || user.FullName() != "Anna Karenina" {
+		t.Errorf("Expected the notes and names to be stored, got %+v, %v", user, err)
+	}
+
+	if err := repo.ChangeEmail(nil, "guest@example.com", "other@example.com"); !domain.IsDuplicateUser(err) {
+		t.Errorf("Expected a duplicate user error, got %v", err)
+	}
+	if err := repo.ChangeEmail(nil, "gest@example.com", "new@example.com"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("Expected the old email to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,398 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// SchemaIssue is a difference between a stored database and the layout the
+// current version expects
+type SchemaIssue struct {
+	Backend string `json:"backend"`       // Database type, prefixed with "fallback " for the fallback
+	Problem string `json:"problem"`       // What is wrong
+	Fix     string `json:"fix,omitempty"` // What Repair does about it, empty if it must be fixed by hand
+}
+
+// sqlIndexes lists the indexes each SQL dialect creates on the users table
+var sqlIndexes = map[string][]string{
+	dialectSQLite:   {"idx_users_email", "idx_users_email_lower"},
+	dialectPostgres: {"idx_users_email", "idx_users_email_lower"},
+	dialectMySQL:    {"idx_users_email"},
+}
+
+// Diagnose inspects the configured database, or the SQLite database a CSV
+// file was promoted to, and its fallback if any, without changing anything
+// and returns the issues found
+func Diagnose(ctx any, cfg config.DatabaseConfig) ([]SchemaIssue, error) {
+	dbType, connectionString := servedDatabase(cfg)
+	issues, err := diagnose(ctx, dbType, connectionString)
+	if err != nil || cfg.Fallback.Type == "" {
+		return issues, err
+	}
+
+	fallback, err := diagnose(ctx, cfg.Fallback.Type, cfg.Fallback.ConnectionString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect fallback database: %w", err)
+	}
+	for _, issue := range fallback {
+		issue.Backend = "fallback " + issue.Backend
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+// Repair applies the fixes of the issues Diagnose reports for the
+// configured database and its fallback
+func Repair(ctx any, cfg config.DatabaseConfig, logger *logger.Logger) error {
+	if logger == nil {
+		return errors.New("logger cannot be nil")
+	}
+	dbType, connectionString := servedDatabase(cfg)
+	if err := repair(ctx, dbType, connectionString, logger); err != nil {
+		return err
+	}
+	if cfg.Fallback.Type == "" {
+		return nil
+	}
+	if err := repair(ctx, cfg.Fallback.Type, cfg.Fallback.ConnectionString, logger); err != nil {
+		return fmt.Errorf("failed to repair fallback database: %w", err)
+	}
+	return nil
+}
+
+// diagnose inspects a single database of the given type
+func diagnose(ctx any, dbType, connectionString string) ([]SchemaIssue, error) {
+	dbType = strings.ToLower(dbType)
+	var (
+		issues []SchemaIssue
+		err    error
+	)
+
+	switch dbType {
+	case "csv":
+		issues, err = diagnoseCSV(connectionString)
+	case "sqlite":
+		issues, err = diagnoseSQLite(connectionString)
+	case "postgresql":
+		issues, err = diagnoseSQL("postgres", dialectPostgres, connectionString)
+	case "mysql":
+		issues, err = diagnoseSQL("mysql", dialectMySQL, connectionString)
+	case "googlesheet":
+		issues, err = diagnoseSheet(connectionString)
+	case "mongodb":
+		issues, err = diagnoseMongo(connectionString)
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", dbType)
+	}
+
+	for i := range issues {
+		issues[i].Backend = dbType
+	}
+	return issues, err
+}
+
+// repair fixes a single database of the given type. SQL and MongoDB
+// databases are migrated by their constructors, so opening them is enough.
+func repair(ctx any, dbType, connectionString string, logger *logger.Logger) error {
+	switch strings.ToLower(dbType) {
+	case "csv":
+		return repairCSV(connectionString, logger)
+	case "googlesheet":
+		return repairSheet(ctx, connectionString, logger)
+	default:
+		repo, err := open(ctx, dbType, connectionString, logger)
+		if err != nil {
+			return err
+		}
+		return repo.Close()
+	}
+}
+
+// diagnoseCSV compares the header of a CSV file with csvHeader and looks
+// for rows with missing fields
+func diagnoseCSV(path string) ([]SchemaIssue, error) {
+	records, err := readCSVFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []SchemaIssue{{Problem: "file " + path + " does not exist", Fix: "create it with the current header"}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return []SchemaIssue{{Problem: "file has no header", Fix: "write the current header"}}, nil
+	}
+
+	var issues []SchemaIssue
+	if header := records[0]; strings.Join(header, ",") != strings.Join(csvHeader, ",") {
+		issues = append(issues, SchemaIssue{
+			Problem: fmt.Sprintf("header is %q, expected %q", strings.Join(header, ","), strings.Join(csvHeader, ",")),
+			Fix:     "rewrite the header",
+		})
+	}
+
+	short := 0
+	for _, record := range records[1:] {
+		if len(record) < len(csvHeader) {
+			short++
+		}
+	}
+	if short > 0 {
+		issues = append(issues, SchemaIssue{
+			Problem: fmt.Sprintf("%d rows have fewer than %d fields", short, len(csvHeader)),
+			Fix:     "pad them with empty fields",
+		})
+	}
+	return issues, nil
+}
+
+// repairCSV rewrites a CSV file with the current header and every row
+// padded to its length. The file is replaced atomically.
+func repairCSV(path string, logger *logger.Logger) error {
+	records, err := readCSVFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		_, err = NewCSVRepository(path, logger)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		records = [][]string{csvHeader}
+	}
+	records[0] = csvHeader
+	for i := range records[1:] {
+		records[i+1] = padCSVRecord(records[i+1])
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := csv.NewWriter(tmp)
+	if err := writer.WriteAll(records); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	logger.Info("CSV file repaired", "path", path, "rows", len(records)-1)
+	return nil
+}
+
+// readCSVFile reads all records of a CSV file, allowing rows of any length
+func readCSVFile(path string) ([][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	return reader.ReadAll()
+}
+
+// diagnoseSQLite inspects a SQLite database without creating the file
+// if it does not exist
+func diagnoseSQLite(path string) ([]SchemaIssue, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return []SchemaIssue{{Problem: "database file " + path + " does not exist", Fix: "create it with the users table"}}, nil
+	}
+	return diagnoseSQL("sqlite3", dialectSQLite, path)
+}
+
+// diagnoseSQL compares the columns and indexes of a SQL users table with
+// those the repository creates
+func diagnoseSQL(driver, dialect, connectionString string) ([]SchemaIssue, error) {
+	db, err := sql.Open(driver, connectionString)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if err := db.PingContext(context.Background()); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseUnavailable, err)
+	}
+
+	var columnsQuery, indexesQuery string
+	switch dialect {
+	case dialectSQLite:
+		columnsQuery = `SELECT name FROM pragma_table_info('users')`
+		indexesQuery = `SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'users'`
+	case dialectPostgres:
+		columnsQuery = `SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'users'`
+		indexesQuery = `SELECT indexname FROM pg_indexes WHERE schemaname = current_schema() AND tablename = 'users'`
+	case dialectMySQL:
+		columnsQuery = `SELECT column_name FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'users'`
+		indexesQuery = `SELECT DISTINCT index_name FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'users'`
+	}
+
+	columns, err := queryNames(db, columnsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns: %w", err)
+	}
+	if len(columns) == 0 {
+		return []SchemaIssue{{Problem: "table users does not exist", Fix: "create it"}}, nil
+	}
+
+	var issues []SchemaIssue
+	for _, column := range strings.Split(userColumns, ", ") {
+		if !columns[column] {
+			issues = append(issues, SchemaIssue{Problem: "column users." + column + " is missing", Fix: "add the column"})
+		}
+	}
+
+	indexes, err := queryNames(db, indexesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	for _, index := range sqlIndexes[dialect] {
+		if !indexes[index] {
+			issues = append(issues, SchemaIssue{Problem: "index " + index + " is missing", Fix: "create the index"})
+		}
+	}
+	return issues, nil
+}
+
+// queryNames returns the set of names selected by a single-column query,
+// lowercased since MySQL may report them in upper case
+func queryNames(db *sql.DB, query string) (map[string]bool, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names[strings.ToLower(name)] = true
+	}
+	return names, rows.Err()
+}
+
+// openSheet connects to the sheet named by a Google Sheets connection string
+func openSheet(connectionString string) (*GoogleSheetRepository, error) {
+	parts := parseConnectionString(connectionString)
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("invalid connection string format: %s", connectionString)
+	}
+	service, err := sheets.NewService(context.Background(), option.WithCredentialsFile(parts[0]))
+	if err != nil {
+		return nil, err
+	}
+	return &GoogleSheetRepository{service: service, spreadsheetID: parts[1], sheetName: parts[2]}, nil
+}
+
+// diagnoseSheet checks that the header row of a sheet maps every user field
+func diagnoseSheet(connectionString string) ([]SchemaIssue, error) {
+	repo, err := openSheet(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	cols, rows, err := repo.readSheet()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseUnavailable, err)
+	}
+
+	if len(rows) == 0 {
+		return []SchemaIssue{{Problem: "sheet has no header row", Fix: "write the current header"}}, nil
+	}
+	var issues []SchemaIssue
+	namesEmail := false
+	for _, name := range cols.header {
+		namesEmail = namesEmail || sheetHeaderAliases[normalizeHeader(name)] == "Email"
+	}
+	if !namesEmail {
+		issues = append(issues, SchemaIssue{
+			Problem: "header row names no Email column, the default column order is assumed",
+		})
+	}
+
+	known := len(cols.header)
+	if cols.ensure(csvHeader...) {
+		issues = append(issues, SchemaIssue{
+			Problem: "header is missing columns " + strings.Join(cols.header[known:], ", "),
+			Fix:     "append them to the header",
+		})
+	}
+	return issues, nil
+}
+
+// repairSheet adds the columns missing from the header of a sheet
+func repairSheet(ctx any, connectionString string, logger *logger.Logger) error {
+	repo, err := NewGoogleSheetRepository(ctx, connectionString, logger)
+	if err != nil {
+		return err
+	}
+	cols, rows, err := repo.readSheet()
+	if err != nil {
+		return err
+	}
+	return repo.prepareColumns(cols, rows)
+}
+
+// diagnoseMongo checks the indexes of the users collection
+func diagnoseMongo(connectionString string) ([]SchemaIssue, error) {
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	if err != nil {
+		return nil, err
+	}
+	defer client.Disconnect(ctx)
+
+	if err := client.Ping(ctx, nil); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseUnavailable, err)
+	}
+
+	cursor, err := client.Database("cocktailbot").Collection("users").Indexes().List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	var specs []bson.M
+	if err := cursor.All(ctx, &specs); err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+
+	indexes := make(map[string]bool)
+	for _, spec := range specs {
+		if name, ok := spec["name"].(string); ok {
+			indexes[name] = true
+		}
+	}
+
+	var issues []SchemaIssue
+	for _, index := range []string{"email_1", "email_ci"} {
+		if !indexes[index] {
+			issues = append(issues, SchemaIssue{Problem: "index " + index + " is missing", Fix: "create the index"})
+		}
+	}
+	return issues, nil
+}
@@ -0,0 +1,103 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+)
+
+func TestDoctor_CSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	// Header and row written by a version without UpdatedAt, CreatedBy and Bar
+	data := "ID,Email,DateAdded,Redeemed,MarketingConsent\n1,guest@example.com,2025-06-01T18:00:00Z,,\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write CSV file: %v", err)
+	}
+	cfg := config.DatabaseConfig{Type: "csv", ConnectionString: path}
+
+	ctx := context.Background()
+	issues, err := repository.Diagnose(ctx, cfg)
+	if err != nil {
+		t.Fatalf("Diagnose failed: %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("Expected an outdated header and a short row, got %+v", issues)
+	}
+	if after, _ := os.ReadFile(path); string(after) != data {
+		t.Error("Diagnose should not change the file")
+	}
+
+	if err := repository.Repair(ctx, cfg, logger.New("error")); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if issues, err := repository.Diagnose(ctx, cfg); err != nil || len(issues) != 0 {
+		t.Fatalf("Expected no issues after repair, got %+v, %v", issues, err)
+	}
+
+	repo, err := repository.NewCSVRepository(path, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to open repaired file: %v", err)
+	}
+	if user, err := repo.FindByEmail(ctx, "guest@example.com"); err != nil || user.ID != "1" {
+		t.Errorf("Expected the guest to survive the repair, got %+v, %v", user, err)
+	}
+}
+
+func TestDoctor_SQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.db")
+	cfg := config.DatabaseConfig{Type: "sqlite", ConnectionString: path}
+	ctx := context.Background()
+
+	// A missing database is reported without being created
+	issues, err := repository.Diagnose(ctx, cfg)
+	if err != nil || len(issues) != 1 {
+		t.Fatalf("Expected a missing database, got %+v, %v", issues, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("Diagnose should not create the database")
+	}
+
+	// Table created by an early version
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = db.Exec(`CREATE TABLE users (id TEXT PRIMARY KEY, email TEXT UNIQUE NOT NULL, date_added TIMESTAMP NOT NULL, redeemed TIMESTAMP);
+		CREATE INDEX idx_users_email ON users(email);`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("Failed to create old table: %v", err)
+	}
+
+	issues, err = repository.Diagnose(ctx, cfg)
+	if err != nil {
+		t.Fatalf("Diagnose failed: %v", err)
+	}
+	var problems []string
+	for _, issue := range issues {
+		problems = append(problems, issue.Problem)
+	}
+	got := strings.Join(problems, "; ")
+	for _, want := range []string{"users.marketing_consent", "users.updated_at", "users.created_by", "users.bar", "users.notes", "users.tags", "users.first_name", "users.last_name", "idx_users_email_lower"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected an issue about %s, got %s", want, got)
+		}
+	}
+	if len(issues) != 9 {
+		t.Errorf("Expected 9 issues, got %d: %s", len(issues), got)
+	}
+
+	if err := repository.Repair(ctx, cfg, logger.New("error")); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if issues, err := repository.Diagnose(ctx, cfg); err != nil || len(issues) != 0 {
+		t.Fatalf("Expected no issues after repair, got %+v, %v", issues, err)
+	}
+}
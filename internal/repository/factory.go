@@ -1,36 +1,200 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/ceesaxp/cocktail-bot/internal/config"
 	"github.com/ceesaxp/cocktail-bot/internal/domain"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
 )
 
-// New creates a new repository instance based on the database configuration
+// New creates a new repository instance based on the database configuration.
+// A CSV file is promoted to SQLite once it outgrows the promotion threshold.
+// If a fallback is configured the repository fails over to it for reads,
+// and Google Sheets fails over to the rows it returned last otherwise.
+// If preloading is enabled the guest list is kept in memory, and if the
+// bloom filter is enabled unknown emails are answered without a lookup.
+// If the negative cache is enabled emails not found are remembered for a
+// while.
 func New(ctx any, cfg config.DatabaseConfig, logger *logger.Logger) (domain.Repository, error) {
 	if logger == nil {
 		return nil, fmt.Errorf("logger cannot be nil")
 	}
 
-	dbType := strings.ToLower(cfg.Type)
-	logger.Info("Initializing repository", "type", dbType, "connection", cfg.ConnectionString)
+	repo, err := openWithFallback(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Preload.Enabled {
+		logger.Info("Guest list preload enabled", "refresh", cfg.Preload.Refresh.Duration())
+		cached, err := NewCachedRepository(ctx, repo, cfg.Preload.Refresh.Duration(), logger)
+		if err != nil {
+			repo.Close()
+			return nil, err
+		}
+		repo = cached
+	}
+
+	if cfg.BloomFilter.Enabled {
+		logger.Info("Bloom filter enabled", "false_positive_rate", cfg.BloomFilter.FalsePositiveRate, "rebuild", cfg.BloomFilter.Rebuild.Duration())
+		filtered, err := NewBloomRepository(ctx, repo, cfg.BloomFilter.FalsePositiveRate, cfg.BloomFilter.Rebuild.Duration(), logger)
+		if err != nil {
+			repo.Close()
+			return nil, err
+		}
+		repo = filtered
+	}
+
+	if ttl := negativeCacheTTL(cfg); cfg.NegativeCache.Enabled && ttl > 0 {
+		logger.Info("Negative cache enabled", "ttl", ttl, "max_entries", cfg.NegativeCache.MaxEntries)
+		negative, err := NewNegativeCacheRepository(repo, ttl, cfg.NegativeCache.MaxEntries, logger)
+		if err != nil {
+			repo.Close()
+			return nil, err
+		}
+		repo = negative
+	}
+	return repo, nil
+}
+
+// negativeCacheTTL returns how long absent emails are remembered for the
+// configured database type
+func negativeCacheTTL(cfg config.DatabaseConfig) time.Duration {
+	if ttl, ok := cfg.NegativeCache.Backends[strings.ToLower(cfg.Type)]; ok {
+		return ttl.Duration()
+	}
+	return cfg.NegativeCache.TTL.Duration()
+}
+
+// openWithFallback opens the primary repository, wrapped for failover if a
+// fallback is configured
+func openWithFallback(ctx any, cfg config.DatabaseConfig, logger *logger.Logger) (domain.Repository, error) {
+	primary, err := openPrimary(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	setTimeouts(primary, cfg)
+	if cfg.Fallback.Type == "" {
+		if sheet, ok := primary.(*GoogleSheetRepository); ok && cfg.SheetsBreaker.Enabled {
+			return newSheetsBreaker(sheet, cfg.SheetsBreaker, cfg.SpoolFile, logger)
+		}
+		return primary, nil
+	}
+
+	fallback, err := openChecked(ctx, cfg.Fallback.Type, cfg.Fallback.ConnectionString, cfg.Timeout.Duration(), logger)
+	if err != nil {
+		primary.Close()
+		return nil, fmt.Errorf("failed to open fallback repository: %w", err)
+	}
+	setTimeouts(fallback, cfg)
+
+	retryInterval := time.Duration(cfg.Fallback.RetrySeconds) * time.Second
+	logger.Info("Failover enabled", "fallback_type", strings.ToLower(cfg.Fallback.Type), "retry_interval", retryInterval)
+	failover, err := NewFailoverRepository(primary, fallback, retryInterval, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := setSpoolFile(failover, cfg.SpoolFile); err != nil {
+		return nil, err
+	}
+	return failover, nil
+}
+
+// setSpoolFile keeps the spool of a failover repository in a journal file
+// if one is configured, closing the repository if it cannot be read
+func setSpoolFile(failover *FailoverRepository, path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := failover.SetSpoolFile(path); err != nil {
+		failover.Close()
+		return err
+	}
+	return nil
+}
+
+// openPrimary opens the primary repository, a CSV file for promotion to
+// SQLite if promotion is enabled
+func openPrimary(ctx any, cfg config.DatabaseConfig, logger *logger.Logger) (domain.Repository, error) {
+	if !strings.EqualFold(cfg.Type, "csv") || !cfg.Promotion.Enabled {
+		return openChecked(ctx, cfg.Type, cfg.ConnectionString, cfg.Timeout.Duration(), logger)
+	}
+	if err := preflight("csv", cfg.ConnectionString); err != nil {
+		return nil, err
+	}
+	return NewPromotingRepository(ctx, cfg.ConnectionString, cfg.Promotion.MaxRows, logger)
+}
+
+// newSheetsBreaker wraps a Google Sheets repository for failover to the rows
+// it returned last, skipping Sheets for the cooldown after repeated errors
+func newSheetsBreaker(sheet *GoogleSheetRepository, cfg config.BreakerConfig, spoolFile string, logger *logger.Logger) (domain.Repository, error) {
+	breaker, err := NewFailoverRepository(sheet, sheet.Snapshot(), cfg.Cooldown.Duration(), logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := setSpoolFile(breaker, spoolFile); err != nil {
+		return nil, err
+	}
+	breaker.SetFailureThreshold(cfg.Failures)
+	logger.Info("Google Sheets breaker enabled", "failures", cfg.Failures, "cooldown", cfg.Cooldown.Duration())
+	return breaker, nil
+}
+
+// setTimeouts applies the configured timeouts to repositories whose
+// operations time out
+func setTimeouts(repo domain.Repository, cfg config.DatabaseConfig) {
+	if setter, ok := repo.(TimeoutSetter); ok {
+		setter.SetTimeouts(cfg.Timeout.Duration(), cfg.BulkTimeout.Duration())
+	}
+}
+
+// openChecked opens a single repository of the given type and probes it,
+// so a missing credentials file, a bad spreadsheet ID or an unreachable
+// host fails startup with a hint instead of failing the first guest
+func openChecked(ctx any, dbType, connectionString string, timeout time.Duration, logger *logger.Logger) (domain.Repository, error) {
+	dbType = strings.ToLower(dbType)
+	if err := preflight(dbType, connectionString); err != nil {
+		return nil, err
+	}
+
+	repo, err := open(ctx, dbType, connectionString, logger)
+	if errors.Is(err, domain.ErrDatabaseUnavailable) {
+		return nil, explain(dbType, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := probe(repo, dbType, timeout); err != nil {
+		repo.Close()
+		return nil, err
+	}
+	logger.Info("Repository probe succeeded", "type", dbType)
+	return repo, nil
+}
+
+// open creates a single repository of the given type
+func open(ctx any, dbType, connectionString string, logger *logger.Logger) (domain.Repository, error) {
+	dbType = strings.ToLower(dbType)
+	logger.Info("Initializing repository", "type", dbType, "connection", connectionString)
 
 	switch dbType {
 	case "csv":
-		return NewCSVRepository(cfg.ConnectionString, logger)
+		return NewCSVRepository(connectionString, logger)
 	case "sqlite":
-		return NewSQLiteRepository(cfg.ConnectionString, logger)
+		return NewSQLiteRepository(connectionString, logger)
 	case "googlesheet":
-		return NewGoogleSheetRepository(ctx, cfg.ConnectionString, logger)
+		return NewGoogleSheetRepository(ctx, connectionString, logger)
 	case "postgresql":
-		return NewPostgresRepository(ctx, cfg.ConnectionString, logger)
+		return NewPostgresRepository(ctx, connectionString, logger)
 	case "mysql":
-		return NewMySQLRepository(ctx, cfg.ConnectionString, logger)
+		return NewMySQLRepository(ctx, connectionString, logger)
 	case "mongodb":
-		return NewMongoDBRepository(ctx, cfg.ConnectionString, logger)
+		return NewMongoDBRepository(ctx, connectionString, logger)
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", dbType)
 	}
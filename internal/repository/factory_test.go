@@ -2,10 +2,21 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"google.golang.org/api/googleapi"
+
 	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
 )
 
@@ -100,4 +111,74 @@ func TestNew(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestNewStartupChecks(t *testing.T) {
+	testLogger := logger.New("error")
+	dir := t.TempDir()
+	notKey := filepath.Join(dir, "notes.json")
+	os.WriteFile(notKey, []byte(`{"hello":"world"}`), 0o600)
+
+	tests := []struct {
+		name          string
+		dbType        string
+		connectionStr string
+		expectedErr   string // Substring of the error, empty for none
+	}{
+		{"SQLite probed", "sqlite", filepath.Join(dir, "users.db"), ""},
+		{"SQLite missing directory", "sqlite", filepath.Join(dir, "missing", "users.db"), "does not exist"},
+		{"CSV missing directory", "csv", filepath.Join(dir, "missing", "users.csv"), "does not exist"},
+		{"Sheets missing credentials", "googlesheet", filepath.Join(dir, "credentials.json") + "|abc|Sheet1", "credentials file"},
+		{"Sheets not a key", "googlesheet", notKey + "|abc|Sheet1", "not a Google credentials file"},
+		{"Sheets missing spreadsheet ID", "googlesheet", notKey + "||Sheet1", "invalid connection string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := New(context.Background(), config.DatabaseConfig{Type: tt.dbType, ConnectionString: tt.connectionStr}, testLogger)
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("Expected no error but got: %v", err)
+				}
+				repo.Close()
+				return
+			}
+
+			var startupErr *StartupError
+			if !errors.As(err, &startupErr) {
+				t.Fatalf("Expected a startup error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.expectedErr) || startupErr.Hint == "" {
+				t.Errorf("Expected %q with a hint, got %q", tt.expectedErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		problem string
+	}{
+		{"Unknown host", &net.DNSError{Name: "db.invalid", IsNotFound: true}, "host db.invalid not found"},
+		{"Refused", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, "connection refused"},
+		{"Wrong password", &pq.Error{Code: "28P01"}, "authentication failed"},
+		{"Missing database", &mysql.MySQLError{Number: 1049}, "database does not exist"},
+		{"Bad spreadsheet ID", &googleapi.Error{Code: 404}, "spreadsheet not found"},
+		{"Not shared", &googleapi.Error{Code: 403}, "access to the spreadsheet denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := explain("postgresql", fmt.Errorf("%w: %w", domain.ErrDatabaseUnavailable, tt.err))
+			var startupErr *StartupError
+			if !errors.As(err, &startupErr) || startupErr.Problem != tt.problem {
+				t.Errorf("Expected problem %q, got %v", tt.problem, err)
+			}
+			if !errors.Is(err, domain.ErrDatabaseUnavailable) {
+				t.Error("Expected the cause to be kept")
+			}
+		})
+	}
+}
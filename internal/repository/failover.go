@@ -0,0 +1,596 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// FailoverRepository reads from a primary repository and falls back to a
+// read-only snapshot when the primary is unavailable. Updates made while the
+// primary is down are spooled, in a journal file if one is set, and replayed
+// once it recovers.
+type FailoverRepository struct {
+	primary       domain.Repository
+	fallback      domain.Repository
+	retryInterval time.Duration // How long the primary is skipped after a failure
+	threshold     int           // Failures in a row before the primary is skipped
+	logger        *logger.Logger
+
+	replayMu sync.Mutex // Serializes replays of the spool
+
+	mu         sync.Mutex
+	spool      map[string]*domain.User // Map of normalized email -> pending update
+	path       string                  // Journal of the spool, empty keeps it in memory only
+	journal    *os.File
+	onConflict ConflictHandler
+	failing    bool      // True while the primary is considered down
+	failures   int       // Primary failures since it last answered
+	retryAt    time.Time // When to try the primary again
+}
+
+// ConflictHandler is told about a spooled update the primary contradicts,
+// such as a guest redeemed at another bar or time while the primary was
+// down. update is the spooled user, current the one in the primary, which
+// is kept.
+type ConflictHandler func(update, current *domain.User)
+
+// ConflictReporter is implemented by repositories that replay spooled
+// updates and report those in conflict instead of overwriting
+type ConflictReporter interface {
+	SetConflictHandler(handler ConflictHandler)
+}
+
+// SetConflictHandler passes handler to the repository if it, or the one it
+// wraps, reports conflicts. It returns false if none does.
+func SetConflictHandler(repo domain.Repository, handler ConflictHandler) bool {
+	reporter, ok := repo.(ConflictReporter)
+	if ok {
+		reporter.SetConflictHandler(handler)
+	}
+	return ok
+}
+
+// SetConflictHandler passes handler to the wrapped repository
+func (r *CachedRepository) SetConflictHandler(handler ConflictHandler) {
+	SetConflictHandler(r.Repository, handler)
+}
+
+// SetConflictHandler passes handler to the wrapped repository
+func (r *BloomRepository) SetConflictHandler(handler ConflictHandler) {
+	SetConflictHandler(r.Repository, handler)
+}
+
+// SetConflictHandler passes handler to the wrapped repository
+func (r *NegativeCacheRepository) SetConflictHandler(handler ConflictHandler) {
+	SetConflictHandler(r.Repository, handler)
+}
+
+// spoolRecord is a spooled update, as kept in the journal
+type spoolRecord struct {
+	User domain.User `json:"user"`
+}
+
+// NewFailoverRepository wraps a primary repository with a read-only fallback
+func NewFailoverRepository(primary, fallback domain.Repository, retryInterval time.Duration, logger *logger.Logger) (*FailoverRepository, error) {
+	if primary == nil || fallback == nil {
+		return nil, errors.New("primary and fallback repositories are required")
+	}
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+
+	return &FailoverRepository{
+		primary:       primary,
+		fallback:      fallback,
+		retryInterval: retryInterval,
+		threshold:     1,
+		logger:        logger,
+		spool:         make(map[string]*domain.User),
+	}, nil
+}
+
+// SetFailureThreshold sets how many failures in a row it takes before the
+// primary is skipped for the retry interval. Reads that fail before that
+// are still answered from the fallback.
+func (r *FailoverRepository) SetFailureThreshold(failures int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.threshold = max(failures, 1)
+}
+
+// SetConflictHandler sets who is told about spooled updates that are
+// dropped because the primary contradicts them. Without one they are only
+// logged.
+func (r *FailoverRepository) SetConflictHandler(handler ConflictHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onConflict = handler
+}
+
+// SetSpoolFile keeps spooled updates in a journal file, so updates accepted
+// while the primary is down survive a restart or a crash. Updates left in
+// the file by a previous run are spooled again, served to lookups and
+// replayed once the primary answers.
+func (r *FailoverRepository) SetSpoolFile(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.loadSpool(path); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	r.path = path
+	if err := r.rewriteSpool(); err != nil {
+		return fmt.Errorf("failed to write spool: %w", err)
+	}
+
+	if len(r.spool) > 0 {
+		r.logger.Warn("Spooled updates of a previous run loaded, replaying them once the primary database answers", "pending", len(r.spool), "spool", path)
+	}
+	return nil
+}
+
+// loadSpool reads the updates spooled by a previous run. Later records of
+// an email replace earlier ones. A record cut short by a crash is skipped.
+// The caller must hold mu.
+func (r *FailoverRepository) loadSpool(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read spool: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record spoolRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			r.logger.Warn("Skipping unreadable spool record", "spool", path, "error", err)
+			continue
+		}
+		user := record.User
+		r.spool[utils.NormalizeEmail(user.Email)] = &user
+	}
+	return scanner.Err()
+}
+
+// appendSpool adds an update to the journal and syncs it to disk. The
+// caller must hold mu.
+func (r *FailoverRepository) appendSpool(user *domain.User) error {
+	if r.journal == nil {
+		return nil
+	}
+	data, err := json.Marshal(spoolRecord{User: *user})
+	if err != nil {
+		return err
+	}
+	if _, err := r.journal.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return r.journal.Sync()
+}
+
+// rewriteSpool replaces the journal with the updates still spooled. The
+// caller must hold mu.
+func (r *FailoverRepository) rewriteSpool() error {
+	if r.path == "" {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, user := range r.spool {
+		data, err := json.Marshal(spoolRecord{User: *user})
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	}
+
+	// Write to a temporary file first so a crash cannot leave a truncated journal
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	if r.journal != nil {
+		r.journal.Close()
+		r.journal = nil
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return err
+	}
+
+	journal, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	r.journal = journal
+	return nil
+}
+
+// spoolUpdate keeps a copy of the update until the primary recovers, in
+// the journal first if there is one. It returns the number of spooled
+// updates.
+func (r *FailoverRepository) spoolUpdate(user *domain.User) (int, error) {
+	userCopy := *user
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.appendSpool(&userCopy); err != nil {
+		return 0, fmt.Errorf("failed to write spool: %w", err)
+	}
+	r.spool[utils.NormalizeEmail(user.Email)] = &userCopy
+	return len(r.spool), nil
+}
+
+// unspool removes the spooled update of an email and returns it, nil if
+// there is none
+func (r *FailoverRepository) unspool(key string) *domain.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.spool[key]
+	if !ok {
+		return nil
+	}
+	delete(r.spool, key)
+	if err := r.rewriteSpool(); err != nil {
+		r.logger.Error("Failed to write spool", "spool", r.path, "error", err)
+	}
+	return user
+}
+
+// primaryFailed returns true if the error means the primary could not
+// answer, as opposed to a regular negative result
+func primaryFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+	return !errors.Is(err, domain.ErrUserNotFound) &&
+		!errors.Is(err, domain.ErrAlreadyRedeemed) &&
+		!domain.IsValidationError(err)
+}
+
+// usePrimary returns false while the primary is failing and not yet due for a retry
+func (r *FailoverRepository) usePrimary() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.failing || !time.Now().Before(r.retryAt)
+}
+
+// markFailed records a primary failure, and skips the primary once it
+// failed threshold times in a row
+func (r *FailoverRepository) markFailed(op string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures++
+	if r.failures < r.threshold {
+		r.logger.Warn("Primary database failed, serving from fallback", "op", op, "failures", r.failures, "error", err)
+		return
+	}
+	if !r.failing {
+		r.logger.Warn("Primary database unavailable, serving from fallback", "op", op, "error", err)
+	}
+	r.failing = true
+	r.retryAt = time.Now().Add(r.retryInterval)
+}
+
+// markRecovered records a successful primary call
+func (r *FailoverRepository) markRecovered() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failing {
+		r.logger.Info("Primary database available again")
+	}
+	r.failing = false
+	r.failures = 0
+}
+
+// flushSpool replays spooled updates to the primary, unless a replay is
+// already running. Lookups go on meanwhile, answered from the spool.
+func (r *FailoverRepository) flushSpool(ctx any) {
+	if !r.replayMu.TryLock() {
+		return
+	}
+	defer r.replayMu.Unlock()
+	r.replay(ctx)
+}
+
+// replay writes a copy of the spooled updates to the primary without
+// holding mu. It stops at the first failure and keeps the remaining
+// updates for the next attempt. Updates spooled again during the replay
+// stay in the spool. The caller must hold replayMu.
+func (r *FailoverRepository) replay(ctx any) {
+	r.mu.Lock()
+	pending := maps.Clone(r.spool)
+	r.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	var done []string
+	for key, user := range pending {
+		if !r.replayUpdate(ctx, *user) {
+			break
+		}
+		done = append(done, key)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range done {
+		if r.spool[key] == pending[key] {
+			delete(r.spool, key)
+		}
+	}
+	if err := r.rewriteSpool(); err != nil {
+		r.logger.Error("Failed to write spool", "spool", r.path, "error", err)
+	}
+}
+
+// replayUpdate writes a spooled update to the primary, after reading the
+// guest again. A redemption the primary already has at another time or bar
+// is reported as a conflict instead, and an update spooled without the
+// redemption the primary has keeps it. It returns false if the primary
+// failed.
+func (r *FailoverRepository) replayUpdate(ctx any, user domain.User) bool {
+	current, err := r.primary.FindByEmail(ctx, user.Email)
+	if primaryFailed(err) {
+		r.skipPrimary()
+		return false
+	}
+	if err == nil && current.IsRedeemed() {
+		if user.IsRedeemed() && !sameRedemption(&user, current) {
+			r.logger.Error("Spooled redemption conflicts with primary, keeping the primary's", "email", user.Email,
+				"spooled", user.Redeemed.Format(time.RFC3339), "spooled_bar", user.RedeemedAt,
+				"stored", current.Redeemed.Format(time.RFC3339), "stored_bar", current.RedeemedAt)
+			r.mu.Lock()
+			handler := r.onConflict
+			r.mu.Unlock()
+			if handler != nil {
+				handler(&user, current)
+			}
+			return true
+		}
+		user.Redeemed, user.RedeemedAt = current.Redeemed, current.RedeemedAt
+	}
+
+	// Stamp the time the update reaches the primary, so that exports
+	// polling for changes during the outage still pick it up
+	user.UpdatedAt = time.Now()
+	err = r.primary.UpdateUser(ctx, &user)
+	if primaryFailed(err) {
+		r.skipPrimary()
+		return false
+	}
+	if err != nil {
+		r.logger.Error("Dropping spooled update rejected by primary", "email", user.Email, "error", err)
+	} else {
+		r.logger.Info("Spooled update written to primary", "email", user.Email)
+	}
+	return true
+}
+
+// sameRedemption returns true if both users were redeemed at the same bar
+// within the same second, as databases storing seconds only keep it
+func sameRedemption(a, b *domain.User) bool {
+	return a.RedeemedAt == b.RedeemedAt && a.Redeemed.Truncate(time.Second).Equal(b.Redeemed.Truncate(time.Second))
+}
+
+// skipPrimary skips the primary for the retry interval after a replay
+// failed
+func (r *FailoverRepository) skipPrimary() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failing = true
+	r.retryAt = time.Now().Add(r.retryInterval)
+}
+
+// spooled returns a copy of the pending update for the email, if any
+func (r *FailoverRepository) spooled(email string) *domain.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.spool[utils.NormalizeEmail(email)]
+	if !ok {
+		return nil
+	}
+	userCopy := *user
+	return &userCopy
+}
+
+// FindByEmail looks up a user in the primary, or in the fallback if the primary is down
+func (r *FailoverRepository) FindByEmail(ctx any, email string) (*domain.User, error) {
+	if r.usePrimary() {
+		r.flushSpool(ctx)
+	}
+
+	// Spooled updates are newer than anything in either repository
+	if user := r.spooled(email); user != nil {
+		return user, nil
+	}
+
+	if r.usePrimary() {
+		user, err := r.primary.FindByEmail(ctx, email)
+		if !primaryFailed(err) {
+			r.markRecovered()
+			return user, err
+		}
+		r.markFailed("find", err)
+	}
+
+	return r.fallback.FindByEmail(ctx, email)
+}
+
+// UpdateUser writes to the primary, or spools the update if the primary is down
+func (r *FailoverRepository) UpdateUser(ctx any, user *domain.User) error {
+	key := utils.NormalizeEmail(user.Email)
+
+	if r.usePrimary() {
+		// Replay older updates first. This update supersedes a spooled
+		// one of the same email, which is taken out of the spool before
+		// another replay could write it after this one.
+		r.replayMu.Lock()
+		r.replay(ctx)
+		superseded := r.unspool(key)
+		r.replayMu.Unlock()
+
+		err := r.primary.UpdateUser(ctx, user)
+		if !primaryFailed(err) {
+			r.markRecovered()
+			if err != nil && superseded != nil {
+				// Rejected, so the older update still has to be written
+				if _, spoolErr := r.spoolUpdate(superseded); spoolErr != nil {
+					r.logger.Error("Failed to spool update again", "email", user.Email, "error", spoolErr)
+				}
+			}
+			return err
+		}
+		r.markFailed("update", err)
+	}
+
+	pending, err := r.spoolUpdate(user)
+	if err != nil {
+		r.logger.Error("Failed to spool update", "email", user.Email, "error", err)
+		return domain.ErrDatabaseUnavailable
+	}
+
+	r.logger.Warn("Update spooled until primary database recovers", "email", user.Email, "pending", pending)
+	return nil
+}
+
+// AddUser writes to the primary. The fallback is read-only, so adding
+// users fails while the primary is down.
+func (r *FailoverRepository) AddUser(ctx any, user *domain.User) error {
+	if !r.usePrimary() {
+		return domain.ErrDatabaseUnavailable
+	}
+
+	return r.primary.AddUser(ctx, user)
+}
+
+// AddUsers adds users to the primary in a batch if it supports batches
+func (r *FailoverRepository) AddUsers(ctx any, users []*domain.User) error {
+	if !r.usePrimary() {
+		return domain.ErrDatabaseUnavailable
+	}
+
+	adder, ok := r.primary.(BatchAdder)
+	if !ok {
+		return ErrBatchUnsupported
+	}
+	return adder.AddUsers(ctx, users)
+}
+
+// GetReport generates a report from the primary, or from the fallback if the primary is down.
+// Spooled updates are applied to fallback results.
+func (r *FailoverRepository) GetReport(ctx any, params domain.ReportParams) ([]*domain.User, error) {
+	if r.usePrimary() {
+		users, err := r.primary.GetReport(ctx, params)
+		if !primaryFailed(err) {
+			r.markRecovered()
+			return users, err
+		}
+		r.markFailed("report", err)
+	}
+	return r.fallbackReport(ctx, params)
+}
+
+// fallbackReport generates a report from the fallback with the spooled
+// updates applied
+func (r *FailoverRepository) fallbackReport(ctx any, params domain.ReportParams) ([]*domain.User, error) {
+	users, err := r.fallback.GetReport(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, user := range users {
+		if pending := r.spooled(user.Email); pending != nil {
+			users[i] = pending
+		}
+	}
+	return users, nil
+}
+
+// NormalizeEmails normalizes stored emails in the primary
+func (r *FailoverRepository) NormalizeEmails(ctx any) (int, error) {
+	normalizer, ok := r.primary.(EmailNormalizer)
+	if !ok {
+		return 0, errors.New("primary repository does not support email normalization")
+	}
+	return normalizer.NormalizeEmails(ctx)
+}
+
+// Health reports the state of the primary database
+func (r *FailoverRepository) Health(ctx any) error {
+	return r.primary.Health(ctx)
+}
+
+// Stats returns statistics of the repository currently serving reads
+func (r *FailoverRepository) Stats(ctx any) (domain.RepoStats, error) {
+	serving := "primary"
+	stats, err := r.primary.Stats(ctx)
+	if err != nil {
+		serving = "fallback"
+		stats, err = r.fallback.Stats(ctx)
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	if stats.Details == nil {
+		stats.Details = make(map[string]string)
+	}
+
+	r.mu.Lock()
+	stats.Details["spooled_updates"] = fmt.Sprint(len(r.spool))
+	r.mu.Unlock()
+	stats.Details["serving"] = serving
+
+	return stats, nil
+}
+
+// Close closes both repositories. Updates still in the spool are replayed
+// on the next start if they are kept in a journal, and lost otherwise.
+func (r *FailoverRepository) Close() error {
+	r.mu.Lock()
+	switch {
+	case len(r.spool) > 0 && r.journal != nil:
+		r.logger.Warn("Closing with spooled updates, they are replayed on the next start", "pending", len(r.spool), "spool", r.path)
+	case len(r.spool) > 0:
+		r.logger.Error("Closing with spooled updates not written to primary", "pending", len(r.spool))
+	}
+	if r.journal != nil {
+		r.journal.Close()
+		r.journal = nil
+	}
+	r.path = ""
+	r.mu.Unlock()
+
+	err := r.primary.Close()
+	if fallbackErr := r.fallback.Close(); err == nil {
+		err = fallbackErr
+	}
+	return err
+}
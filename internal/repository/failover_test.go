@@ -0,0 +1,371 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+)
+
+// flakyRepository wraps a repository and fails every call while down is set
+type flakyRepository struct {
+	domain.Repository
+	down bool
+}
+
+var errConnectionRefused = errors.New("connection refused")
+
+func (r *flakyRepository) FindByEmail(ctx any, email string) (*domain.User, error) {
+	if r.down {
+		return nil, errConnectionRefused
+	}
+	return r.Repository.FindByEmail(ctx, email)
+}
+
+func (r *flakyRepository) UpdateUser(ctx any, user *domain.User) error {
+	if r.down {
+		return errConnectionRefused
+	}
+	return r.Repository.UpdateUser(ctx, user)
+}
+
+func (r *flakyRepository) AddUser(ctx any, user *domain.User) error {
+	if r.down {
+		return errConnectionRefused
+	}
+	return r.Repository.AddUser(ctx, user)
+}
+
+func (r *flakyRepository) GetReport(ctx any, params domain.ReportParams) ([]*domain.User, error) {
+	if r.down {
+		return nil, errConnectionRefused
+	}
+	return r.Repository.GetReport(ctx, params)
+}
+
+// newCSVForTest creates a CSV repository with the given rows
+func newCSVForTest(t *testing.T, rows string) *repository.CSVRepository {
+	t.Helper()
+
+	tmpfile, err := os.CreateTemp("", "users*.csv")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(tmpfile.Name()) })
+
+	if _, err := tmpfile.Write([]byte("ID,Email,DateAdded,Redeemed,MarketingConsent\n" + rows)); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	if err := tmpfile.Close(); err != nil {
+		t.Fatalf("Failed to close temp file: %v", err)
+	}
+
+	repo, err := repository.NewCSVRepository(tmpfile.Name(), logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	return repo
+}
+
+func TestFailoverRepository(t *testing.T) {
+	now := time.Now().Format(time.RFC3339)
+	rows := "1,user1@example.com," + now + ",,\n"
+
+	primary := &flakyRepository{Repository: newCSVForTest(t, rows)}
+	snapshot := newCSVForTest(t, rows)
+
+	repo, err := repository.NewFailoverRepository(primary, snapshot, 0, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create failover repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	// Reads come from the primary while it is up
+	if _, err := repo.FindByEmail(ctx, "user1@example.com"); err != nil {
+		t.Fatalf("Failed to find user in primary: %v", err)
+	}
+
+	// Reads fall back to the snapshot during an outage
+	primary.down = true
+	user, err := repo.FindByEmail(ctx, "user1@example.com")
+	if err != nil {
+		t.Fatalf("Failed to find user in fallback: %v", err)
+	}
+	if user.IsRedeemed() {
+		t.Fatalf("User should not be redeemed yet")
+	}
+
+	// Redemptions are spooled and visible to later reads
+	user.Redeem()
+	if err := repo.UpdateUser(ctx, user); err != nil {
+		t.Fatalf("Expected update to be spooled, got: %v", err)
+	}
+	user, err = repo.FindByEmail(ctx, "user1@example.com")
+	if err != nil || !user.IsRedeemed() {
+		t.Fatalf("Expected spooled redemption to be visible, got %+v (%v)", user, err)
+	}
+
+	stats, err := repo.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.Details["spooled_updates"] != "1" {
+		t.Errorf("Expected 1 spooled update, got %s", stats.Details["spooled_updates"])
+	}
+
+	// New users cannot be added to the read-only snapshot
+	err = repo.AddUser(ctx, &domain.User{ID: "2", Email: "user2@example.com", DateAdded: time.Now()})
+	if err == nil {
+		t.Errorf("Expected error adding user while primary is down")
+	}
+
+	// The spool is written to the primary once it is retried
+	primary.down = false
+	if err := repo.UpdateUser(ctx, &domain.User{ID: "1", Email: "user1@example.com", DateAdded: user.DateAdded, Redeemed: user.Redeemed}); err != nil {
+		t.Fatalf("Failed to update primary after recovery: %v", err)
+	}
+	stored, err := primary.Repository.FindByEmail(ctx, "user1@example.com")
+	if err != nil || !stored.IsRedeemed() {
+		t.Errorf("Expected redemption in primary, got %+v (%v)", stored, err)
+	}
+}
+
+func TestFailoverRepository_FailureThreshold(t *testing.T) {
+	now := time.Now().Format(time.RFC3339)
+	rows := "1,user1@example.com," + now + ",,\n"
+
+	primary := &flakyRepository{Repository: newCSVForTest(t, rows+"2,user2@example.com,"+now+",,\n")}
+	snapshot := newCSVForTest(t, rows)
+
+	repo, err := repository.NewFailoverRepository(primary, snapshot, time.Hour, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create failover repository: %v", err)
+	}
+	defer repo.Close()
+	repo.SetFailureThreshold(2)
+
+	ctx := context.Background()
+
+	// A single failure is answered from the fallback, but the primary is
+	// still asked next time
+	primary.down = true
+	if _, err := repo.FindByEmail(ctx, "user1@example.com"); err != nil {
+		t.Fatalf("Failed to find user in fallback: %v", err)
+	}
+	primary.down = false
+	if _, err := repo.FindByEmail(ctx, "user2@example.com"); err != nil {
+		t.Fatalf("Failed to find user added to primary: %v", err)
+	}
+	user, err := repo.FindByEmail(ctx, "user1@example.com")
+	if err != nil {
+		t.Fatalf("Failed to find user in primary: %v", err)
+	}
+
+	// Failures in a row skip the primary for the retry interval, while
+	// redemptions are spooled
+	primary.down = true
+	for i := 0; i < 2; i++ {
+		if _, err := repo.FindByEmail(ctx, "user1@example.com"); err != nil {
+			t.Fatalf("Failed to find user in fallback: %v", err)
+		}
+	}
+	primary.down = false
+	user.Redeem()
+	if err := repo.UpdateUser(ctx, user); err != nil {
+		t.Fatalf("Expected update to be spooled, got: %v", err)
+	}
+	stored, err := primary.Repository.FindByEmail(ctx, "user1@example.com")
+	if err != nil || stored.IsRedeemed() {
+		t.Errorf("Expected the primary to be skipped, got %+v (%v)", stored, err)
+	}
+}
+
+func TestFailoverRepository_SpoolFile(t *testing.T) {
+	now := time.Now().Format(time.RFC3339)
+	rows := "1,user1@example.com," + now + ",,\n"
+	path := filepath.Join(t.TempDir(), "spool.jsonl")
+
+	primary := &flakyRepository{Repository: newCSVForTest(t, rows), down: true}
+	open := func() *repository.FailoverRepository {
+		t.Helper()
+		repo, err := repository.NewFailoverRepository(primary, newCSVForTest(t, rows), 0, logger.New("error"))
+		if err != nil {
+			t.Fatalf("Failed to create failover repository: %v", err)
+		}
+		if err := repo.SetSpoolFile(path); err != nil {
+			t.Fatalf("Failed to set spool file: %v", err)
+		}
+		return repo
+	}
+	ctx := context.Background()
+
+	// A redemption spooled during the outage survives a restart
+	repo := open()
+	user, err := repo.FindByEmail(ctx, "user1@example.com")
+	if err != nil {
+		t.Fatalf("Failed to find user in fallback: %v", err)
+	}
+	user.Redeem()
+	user.RedeemedAt = "Main Bar"
+	if err := repo.UpdateUser(ctx, user); err != nil {
+		t.Fatalf("Expected update to be spooled, got: %v", err)
+	}
+	repo.Close()
+
+	repo = open()
+	defer repo.Close()
+	spooled, err := repo.FindByEmail(ctx, "user1@example.com")
+	if err != nil || !spooled.IsRedeemed() {
+		t.Fatalf("Expected the spooled redemption after a restart, got %+v (%v)", spooled, err)
+	}
+
+	// It is replayed once the primary answers, and leaves the spool
+	primary.down = false
+	if _, err := repo.FindByEmail(ctx, "user1@example.com"); err != nil {
+		t.Fatalf("Failed to find user in primary: %v", err)
+	}
+	stored, err := primary.Repository.FindByEmail(ctx, "user1@example.com")
+	if err != nil || !stored.IsRedeemed() {
+		t.Errorf("Expected the redemption in the primary, got %+v (%v)", stored, err)
+	}
+	if data, err := os.ReadFile(path); err != nil || len(data) != 0 {
+		t.Errorf("Expected an empty spool file, got %q (%v)", data, err)
+	}
+}
+
+func TestFailoverRepository_SpoolConflict(t *testing.T) {
+	now := time.Now().Format(time.RFC3339)
+	rows := "1,user1@example.com," + now + ",,\n2,user2@example.com," + now + ",,\n"
+
+	primary := &flakyRepository{Repository: newCSVForTest(t, rows)}
+	repo, err := repository.NewFailoverRepository(primary, newCSVForTest(t, rows), 0, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create failover repository: %v", err)
+	}
+	defer repo.Close()
+	var conflicts []*domain.User
+	repository.SetConflictHandler(repo, func(update, current *domain.User) {
+		conflicts = append(conflicts, update)
+	})
+	ctx := context.Background()
+
+	// During the outage user1 is redeemed and user2 opts in to marketing
+	primary.down = true
+	spooledAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	consent := time.Now()
+	if err := repo.UpdateUser(ctx, &domain.User{ID: "1", Email: "user1@example.com", Redeemed: &spooledAt}); err != nil {
+		t.Fatalf("Expected update to be spooled, got: %v", err)
+	}
+	if err := repo.UpdateUser(ctx, &domain.User{ID: "2", Email: "user2@example.com", MarketingConsent: &consent}); err != nil {
+		t.Fatalf("Expected update to be spooled, got: %v", err)
+	}
+
+	// Meanwhile both were redeemed in the primary by another instance
+	storedAt := time.Now().Truncate(time.Second)
+	for _, email := range []string{"user1@example.com", "user2@example.com"} {
+		user, _ := primary.Repository.FindByEmail(ctx, email)
+		user.Redeemed = &storedAt
+		if err := primary.Repository.UpdateUser(ctx, user); err != nil {
+			t.Fatalf("Failed to redeem in primary: %v", err)
+		}
+	}
+
+	primary.down = false
+	if _, err := repo.FindByEmail(ctx, "user1@example.com"); err != nil {
+		t.Fatalf("Failed to find user in primary: %v", err)
+	}
+
+	// The conflicting redemption is reported and the primary's kept
+	if len(conflicts) != 1 || conflicts[0].Email != "user1@example.com" || !conflicts[0].Redeemed.Equal(spooledAt) {
+		t.Fatalf("Expected the redemption of user1 to conflict, got %+v", conflicts)
+	}
+	stored, _ := primary.Repository.FindByEmail(ctx, "user1@example.com")
+	if !stored.Redeemed.Equal(storedAt) {
+		t.Errorf("Expected the primary's redemption to be kept, got %v", stored.Redeemed)
+	}
+
+	// The consent is written without undoing the redemption
+	stored, _ = primary.Repository.FindByEmail(ctx, "user2@example.com")
+	if !stored.HasMarketingConsent() || stored.Redeemed == nil || !stored.Redeemed.Equal(storedAt) {
+		t.Errorf("Expected the consent and the primary's redemption, got %+v", stored)
+	}
+}
+
+// blockingRepository holds updates until release is closed
+type blockingRepository struct {
+	domain.Repository
+	down     atomic.Bool
+	updating chan struct{}
+	release  chan struct{}
+}
+
+func (r *blockingRepository) FindByEmail(ctx any, email string) (*domain.User, error) {
+	if r.down.Load() {
+		return nil, errConnectionRefused
+	}
+	return r.Repository.FindByEmail(ctx, email)
+}
+
+func (r *blockingRepository) UpdateUser(ctx any, user *domain.User) error {
+	if r.down.Load() {
+		return errConnectionRefused
+	}
+	r.updating <- struct{}{}
+	<-r.release
+	return r.Repository.UpdateUser(ctx, user)
+}
+
+func TestFailoverRepository_ReplayDoesNotBlockLookups(t *testing.T) {
+	now := time.Now().Format(time.RFC3339)
+	rows := "1,user1@example.com," + now + ",,\n"
+
+	primary := &blockingRepository{Repository: newCSVForTest(t, rows), updating: make(chan struct{}, 1), release: make(chan struct{})}
+	repo, err := repository.NewFailoverRepository(primary, newCSVForTest(t, rows), 0, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create failover repository: %v", err)
+	}
+	defer repo.Close()
+	ctx := context.Background()
+
+	primary.down.Store(true)
+	redeemed := time.Now()
+	if err := repo.UpdateUser(ctx, &domain.User{ID: "1", Email: "user1@example.com", Redeemed: &redeemed}); err != nil {
+		t.Fatalf("Expected update to be spooled, got: %v", err)
+	}
+	primary.down.Store(false)
+
+	// A lookup starts the replay, which waits for the primary
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		repo.FindByEmail(ctx, "user1@example.com")
+	}()
+	<-primary.updating
+
+	// Other lookups are answered from the spool meanwhile
+	found := make(chan *domain.User, 1)
+	go func() {
+		user, _ := repo.FindByEmail(ctx, "user1@example.com")
+		found <- user
+	}()
+	select {
+	case user := <-found:
+		if user == nil || !user.IsRedeemed() {
+			t.Errorf("Expected the spooled redemption, got %+v", user)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected lookups to go on during the replay")
+	}
+
+	close(primary.release)
+	<-done
+}
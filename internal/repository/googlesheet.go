@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/ceesaxp/cocktail-bot/internal/domain"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
@@ -17,6 +19,11 @@ type GoogleSheetRepository struct {
 	spreadsheetID string
 	sheetName     string
 	logger        *logger.Logger
+	addMu         sync.Mutex // Serializes adds made by this process
+
+	lastMu   sync.RWMutex
+	lastRows [][]interface{} // Rows of the last successful read, served by Snapshot
+	lastRead time.Time       // When the sheet was last read, zero before the first read
 }
 
 func NewGoogleSheetRepository(ctx any, connectionString string, logger *logger.Logger) (*GoogleSheetRepository, error) {
@@ -53,78 +60,92 @@ func NewGoogleSheetRepository(ctx any, connectionString string, logger *logger.L
 	}, nil
 }
 
-func (r *GoogleSheetRepository) FindByEmail(ctx any, email string) (*domain.User, error) {
-	if email == "" {
-		return nil, errors.New("email cannot be empty")
+// readSheet reads all rows of the sheet, the header row first, and maps its columns
+func (r *GoogleSheetRepository) readSheet() (*sheetColumns, [][]interface{}, error) {
+	resp, err := r.service.Spreadsheets.Values.Get(r.spreadsheetID, r.sheetName).Context(context.Background()).Do()
+	if err != nil {
+		return nil, nil, err
 	}
 
-	r.logger.Debug("Looking for email in Google Sheets", "email", email)
+	var header []interface{}
+	if len(resp.Values) > 0 {
+		header = resp.Values[0]
+	}
 
-	// Define the range to read
-	readRange := fmt.Sprintf("%s!A:D", r.sheetName)
+	r.lastMu.Lock()
+	r.lastRows, r.lastRead = resp.Values, time.Now()
+	r.lastMu.Unlock()
+	return parseSheetHeader(header), resp.Values, nil
+}
 
-	// Read data from sheet
-	resp, err := r.service.Spreadsheets.Values.Get(r.spreadsheetID, readRange).Context(context.Background()).Do()
-	if err != nil {
-		r.logger.Error("Failed to read Google Sheet", "error", err)
-		return nil, domain.ErrDatabaseUnavailable
+// prepareColumns adds the columns of all user fields missing from the
+// header, writing the header of an empty sheet or one missing new fields
+func (r *GoogleSheetRepository) prepareColumns(cols *sheetColumns, rows [][]interface{}) error {
+	if !cols.ensure(csvHeader...) && len(rows) > 0 {
+		return nil
 	}
 
-	if len(resp.Values) == 0 {
-		r.logger.Debug("Sheet is empty", "sheet", r.sheetName)
-		return nil, domain.ErrUserNotFound
+	headerRange := fmt.Sprintf("%s!A1:%s1", r.sheetName, cols.lastColumn())
+	_, err := r.service.Spreadsheets.Values.Update(r.spreadsheetID, headerRange,
+		&sheets.ValueRange{Values: [][]interface{}{cols.headerValues()}}).
+		ValueInputOption("RAW").Context(context.Background()).Do()
+	if err != nil {
+		return fmt.Errorf("failed to write sheet header: %w", err)
 	}
+	r.logger.Info("Google Sheet header updated", "sheet", r.sheetName, "columns", len(cols.header))
+	return nil
+}
 
-	// Skip header and look for email
-	for i, row := range resp.Values {
+// findRow returns the index of the row holding the email, or -1
+func findRow(cols *sheetColumns, rows [][]interface{}, email string) int {
+	for i, row := range rows {
 		if i == 0 { // Skip header
 			continue
 		}
+		if rowEmail := cols.cell(row, "Email"); rowEmail != "" && sameEmail(rowEmail, email) {
+			return i
+		}
+	}
+	return -1
+}
 
-		if len(row) >= 2 {
-			// Convert interface{} to string safely
-			rowEmail, ok := row[1].(string)
-			if ok && rowEmail == email {
-				// Found the user
-				user := &domain.User{
-					Email: email,
-				}
+func (r *GoogleSheetRepository) FindByEmail(ctx any, email string) (*domain.User, error) {
+	if email == "" {
+		return nil, errors.New("email cannot be empty")
+	}
 
-				// Get ID if available
-				if len(row) >= 1 {
-					if id, ok := row[0].(string); ok {
-						user.ID = id
-					}
-				}
+	r.logger.Debug("Looking for email in Google Sheets", "email", email)
 
-				// Parse DateAdded
-				if len(row) >= 3 {
-					if dateStr, ok := row[2].(string); ok && dateStr != "" {
-						dateAdded, err := time.Parse(time.RFC3339, dateStr)
-						if err == nil {
-							user.DateAdded = dateAdded
-						}
-					}
-				}
+	cols, rows, err := r.readSheet()
+	if err != nil {
+		r.logger.Error("Failed to read Google Sheet", "error", err)
+		return nil, domain.ErrDatabaseUnavailable
+	}
 
-				// Parse Redeemed
-				if len(row) >= 4 {
-					if redeemedStr, ok := row[3].(string); ok && redeemedStr != "" {
-						redeemed, err := time.Parse(time.RFC3339, redeemedStr)
-						if err == nil {
-							user.Redeemed = &redeemed
-						}
-					}
-				}
+	user, err := r.findInRows(cols, rows, email)
+	if err != nil {
+		return nil, err
+	}
+	r.logger.Debug("Found user in Google Sheets", "email", email, "redeemed", user.IsRedeemed())
+	return user, nil
+}
 
-				r.logger.Debug("Found user in Google Sheets", "email", email, "redeemed", user.IsRedeemed())
-				return user, nil
-			}
-		}
+// findInRows returns the user of the row holding the email
+func (r *GoogleSheetRepository) findInRows(cols *sheetColumns, rows [][]interface{}, email string) (*domain.User, error) {
+	if len(rows) == 0 {
+		r.logger.Debug("Sheet is empty", "sheet", r.sheetName)
+		return nil, domain.ErrUserNotFound
 	}
 
-	r.logger.Debug("User not found in Google Sheets", "email", email)
-	return nil, domain.ErrUserNotFound
+	i := findRow(cols, rows, email)
+	if i < 0 {
+		r.logger.Debug("User not found in Google Sheets", "email", email)
+		return nil, domain.ErrUserNotFound
+	}
+
+	user := cols.user(rows[i])
+	user.Email = utils.NormalizeEmail(user.Email)
+	return user, nil
 }
 
 func (r *GoogleSheetRepository) UpdateUser(ctx any, user *domain.User) error {
@@ -134,64 +155,30 @@ func (r *GoogleSheetRepository) UpdateUser(ctx any, user *domain.User) error {
 
 	r.logger.Debug("Updating user in Google Sheets", "email", user.Email)
 
-	// Define the range to read
-	readRange := fmt.Sprintf("%s!A:D", r.sheetName)
-
-	// Read data from sheet to find the row
-	resp, err := r.service.Spreadsheets.Values.Get(r.spreadsheetID, readRange).Context(context.Background()).Do()
+	// Read the sheet to find the row
+	cols, rows, err := r.readSheet()
 	if err != nil {
 		r.logger.Error("Failed to read Google Sheet for update", "error", err)
 		return domain.ErrDatabaseUnavailable
 	}
-
-	// Find the row with the user's email
-	rowIndex := -1
-	for i, row := range resp.Values {
-		if i == 0 { // Skip header
-			continue
-		}
-
-		if len(row) >= 2 {
-			if rowEmail, ok := row[1].(string); ok && rowEmail == user.Email {
-				rowIndex = i + 1 // 1-based index for API
-				break
-			}
-		}
-	}
-
-	// Prepare the updated/new row data
-	var values []interface{}
-	values = append(values, user.ID)
-	values = append(values, user.Email)
-	values = append(values, user.DateAdded.Format(time.RFC3339))
-
-	if user.Redeemed != nil {
-		values = append(values, user.Redeemed.Format(time.RFC3339))
-	} else {
-		values = append(values, "")
+	if err := r.prepareColumns(cols, rows); err != nil {
+		r.logger.Error("Failed to prepare Google Sheet columns", "error", err)
+		return err
 	}
 
-	var updateRange string
-	var valueRange sheets.ValueRange
-
-	if rowIndex > 0 {
-		// Update existing row
-		updateRange = fmt.Sprintf("%s!A%d:D%d", r.sheetName, rowIndex, rowIndex)
-		valueRange = sheets.ValueRange{
-			Values: [][]interface{}{values},
-		}
+	stampUser(user)
+	i := findRow(cols, rows, user.Email)
 
-		_, err = r.service.Spreadsheets.Values.Update(r.spreadsheetID, updateRange, &valueRange).
+	if i > 0 {
+		// Update the existing row, keeping the cells of other columns
+		rowNumber := i + 1 // 1-based index for API
+		updateRange := fmt.Sprintf("%s!A%d:%s%d", r.sheetName, rowNumber, cols.lastColumn(), rowNumber)
+		values := cols.setUser(append([]interface{}(nil), rows[i]...), user, false)
+		_, err = r.service.Spreadsheets.Values.Update(r.spreadsheetID, updateRange,
+			&sheets.ValueRange{Values: [][]interface{}{values}}).
 			ValueInputOption("RAW").Context(context.Background()).Do()
 	} else {
-		// Append new row
-		updateRange = fmt.Sprintf("%s!A:D", r.sheetName)
-		valueRange = sheets.ValueRange{
-			Values: [][]interface{}{values},
-		}
-
-		_, err = r.service.Spreadsheets.Values.Append(r.spreadsheetID, updateRange, &valueRange).
-			ValueInputOption("RAW").InsertDataOption("INSERT_ROWS").Context(context.Background()).Do()
+		err = r.appendUser(cols, user)
 	}
 
 	if err != nil {
@@ -203,6 +190,16 @@ func (r *GoogleSheetRepository) UpdateUser(ctx any, user *domain.User) error {
 	return nil
 }
 
+// appendUser adds a row for the user after the last row of the sheet
+func (r *GoogleSheetRepository) appendUser(cols *sheetColumns, user *domain.User) error {
+	appendRange := fmt.Sprintf("%s!A:%s", r.sheetName, cols.lastColumn())
+	values := cols.setUser(nil, user, true)
+	_, err := r.service.Spreadsheets.Values.Append(r.spreadsheetID, appendRange,
+		&sheets.ValueRange{Values: [][]interface{}{values}}).
+		ValueInputOption("RAW").InsertDataOption("INSERT_ROWS").Context(context.Background()).Do()
+	return err
+}
+
 // AddUser adds a new user to the Google Sheet
 func (r *GoogleSheetRepository) AddUser(ctx any, user *domain.User) error {
 	if user == nil {
@@ -211,157 +208,260 @@ func (r *GoogleSheetRepository) AddUser(ctx any, user *domain.User) error {
 
 	r.logger.Debug("Adding user to Google Sheets", "email", user.Email)
 
-	// Define the range to read
-	readRange := fmt.Sprintf("%s!A:D", r.sheetName)
+	// Sheets have no unique constraint. Adds of this process are serialized,
+	// those of other processes are detected after appending.
+	r.addMu.Lock()
+	defer r.addMu.Unlock()
 
-	// Read data from sheet to check for duplicates
-	resp, err := r.service.Spreadsheets.Values.Get(r.spreadsheetID, readRange).Context(context.Background()).Do()
+	// Read the sheet to check for duplicates
+	cols, rows, err := r.readSheet()
 	if err != nil {
 		r.logger.Error("Failed to read Google Sheet for add", "error", err)
 		return domain.ErrDatabaseUnavailable
 	}
 
-	// Check if user already exists
-	for i, row := range resp.Values {
-		if i == 0 { // Skip header
-			continue
-		}
+	if i := findRow(cols, rows, user.Email); i > 0 {
+		r.logger.Debug("User already exists in Google Sheets", "email", user.Email)
+		return domain.NewDuplicateUserError(utils.NormalizeEmail(user.Email), cols.cell(rows[i], "ID"))
+	}
 
-		if len(row) >= 2 {
-			if rowEmail, ok := row[1].(string); ok && rowEmail == user.Email {
-				r.logger.Debug("User already exists in Google Sheets", "email", user.Email)
-				return errors.New("user already exists")
-			}
-		}
+	if err := r.prepareColumns(cols, rows); err != nil {
+		r.logger.Error("Failed to prepare Google Sheet columns", "error", err)
+		return err
 	}
 
-	// Prepare the new row data
-	var values []interface{}
-	values = append(values, user.ID)
-	values = append(values, user.Email)
-	values = append(values, user.DateAdded.Format(time.RFC3339))
+	stampUser(user)
+	if err := r.appendUser(cols, user); err != nil {
+		r.logger.Error("Failed to add user to Google Sheet", "error", err)
+		return err
+	}
 
-	if user.Redeemed != nil {
-		values = append(values, user.Redeemed.Format(time.RFC3339))
-	} else {
-		values = append(values, "")
+	if err := r.resolveAddRace(user); err != nil {
+		return err
 	}
 
-	// Append new row
-	updateRange := fmt.Sprintf("%s!A:D", r.sheetName)
-	valueRange := sheets.ValueRange{
-		Values: [][]interface{}{values},
+	r.logger.Debug("User added to Google Sheets", "email", user.Email)
+	return nil
+}
+
+// AddUsers appends rows for all users in a single request. Nothing is
+// written if any of the emails is already in the sheet.
+func (r *GoogleSheetRepository) AddUsers(ctx any, users []*domain.User) error {
+	r.addMu.Lock()
+	defer r.addMu.Unlock()
+
+	cols, rows, err := r.readSheet()
+	if err != nil {
+		r.logger.Error("Failed to read Google Sheet for add", "error", err)
+		return domain.ErrDatabaseUnavailable
 	}
 
-	_, err = r.service.Spreadsheets.Values.Append(r.spreadsheetID, updateRange, &valueRange).
-		ValueInputOption("RAW").InsertDataOption("INSERT_ROWS").Context(context.Background()).Do()
+	for _, user := range users {
+		if i := findRow(cols, rows, user.Email); i > 0 {
+			return domain.NewDuplicateUserError(utils.NormalizeEmail(user.Email), cols.cell(rows[i], "ID"))
+		}
+	}
 
+	if err := r.prepareColumns(cols, rows); err != nil {
+		r.logger.Error("Failed to prepare Google Sheet columns", "error", err)
+		return err
+	}
+
+	values := make([][]interface{}, len(users))
+	for i, user := range users {
+		stampUser(user)
+		values[i] = cols.setUser(nil, user, true)
+	}
+	appendRange := fmt.Sprintf("%s!A:%s", r.sheetName, cols.lastColumn())
+	_, err = r.service.Spreadsheets.Values.Append(r.spreadsheetID, appendRange,
+		&sheets.ValueRange{Values: values}).
+		ValueInputOption("RAW").InsertDataOption("INSERT_ROWS").Context(context.Background()).Do()
 	if err != nil {
-		r.logger.Error("Failed to add user to Google Sheet", "error", err)
+		r.logger.Error("Failed to add users to Google Sheet", "count", len(users), "error", err)
 		return err
 	}
 
-	r.logger.Debug("User added to Google Sheets", "email", user.Email)
+	r.logger.Debug("Users added to Google Sheets", "count", len(users))
 	return nil
 }
 
+// resolveAddRace checks the sheet after appending a user. Another process
+// may have appended the same email at the same time, in which case the
+// first row wins: the later row is cleared and the add reported as a
+// duplicate of the first one.
+func (r *GoogleSheetRepository) resolveAddRace(user *domain.User) error {
+	cols, rows, err := r.readSheet()
+	if err != nil {
+		// The row was appended, only the check failed
+		r.logger.Warn("Failed to re-read Google Sheet after add", "email", user.Email, "error", err)
+		return nil
+	}
+
+	winner := findRow(cols, rows, user.Email)
+	if winner < 0 || cols.cell(rows[winner], "ID") == user.ID {
+		return nil
+	}
+
+	for i, row := range rows {
+		if i == 0 || cols.cell(row, "ID") != user.ID || !sameEmail(cols.cell(row, "Email"), user.Email) {
+			continue
+		}
+		clearRange := fmt.Sprintf("%s!A%d:%s%d", r.sheetName, i+1, cols.lastColumn(), i+1)
+		_, err := r.service.Spreadsheets.Values.Clear(r.spreadsheetID, clearRange, &sheets.ClearValuesRequest{}).
+			Context(context.Background()).Do()
+		if err != nil {
+			r.logger.Error("Failed to clear duplicate Google Sheet row", "row", i+1, "error", err)
+			return err
+		}
+	}
+
+	r.logger.Info("Concurrent add of the same email detected in Google Sheets", "email", user.Email)
+	return domain.NewDuplicateUserError(utils.NormalizeEmail(user.Email), cols.cell(rows[winner], "ID"))
+}
+
 // GetReport retrieves users based on the report parameters
 func (r *GoogleSheetRepository) GetReport(ctx any, params domain.ReportParams) ([]*domain.User, error) {
 	r.logger.Debug("Generating report from Google Sheets", "type", params.Type, "from", params.From, "to", params.To)
 
-	// Define the range to read
-	readRange := fmt.Sprintf("%s!A:D", r.sheetName)
-
-	// Read data from sheet
-	resp, err := r.service.Spreadsheets.Values.Get(r.spreadsheetID, readRange).Context(context.Background()).Do()
+	cols, rows, err := r.readSheet()
 	if err != nil {
 		r.logger.Error("Failed to read Google Sheet for report", "error", err)
 		return nil, domain.ErrDatabaseUnavailable
 	}
 
-	if len(resp.Values) == 0 {
-		r.logger.Debug("Sheet is empty", "sheet", r.sheetName)
-		return []*domain.User{}, nil
-	}
+	users := reportFromRows(cols, rows, params)
+	r.logger.Info("Report generated from Google Sheets", "type", params.Type, "count", len(users))
+	return users, nil
+}
 
-	var users []*domain.User
+// reportFromRows selects the users of the rows matching the report parameters
+func reportFromRows(cols *sheetColumns, rows [][]interface{}, params domain.ReportParams) []*domain.User {
+	users := []*domain.User{}
 
 	// Skip header and process rows
-	for i, row := range resp.Values {
+	for i, row := range rows {
 		if i == 0 { // Skip header
 			continue
 		}
 
-		if len(row) < 2 {
-			continue // Skip invalid rows
-		}
-
-		// Extract user data
-		var user domain.User
-
-		// Get ID
-		if id, ok := row[0].(string); ok {
-			user.ID = id
-		} else {
-			continue // Skip row without ID
-		}
-
-		// Get Email
-		if email, ok := row[1].(string); ok {
-			user.Email = email
-		} else {
-			continue // Skip row without email
+		// Skip rows without an ID, an email or a valid date added
+		user := cols.user(row)
+		if user.ID == "" || user.Email == "" || user.DateAdded.IsZero() {
+			continue
 		}
-
-		// Parse DateAdded
-		var dateAdded time.Time
-		if len(row) >= 3 {
-			if dateStr, ok := row[2].(string); ok && dateStr != "" {
-				parsedDate, err := time.Parse(time.RFC3339, dateStr)
-				if err == nil {
-					dateAdded = parsedDate
-					user.DateAdded = parsedDate
-				} else {
-					continue // Skip row with invalid date
-				}
-			} else {
-				continue // Skip row without date
-			}
-		} else {
-			continue // Skip row without date
+		if !params.Matches(user) {
+			continue
 		}
 
-		// Parse Redeemed
-		if len(row) >= 4 {
-			if redeemedStr, ok := row[3].(string); ok && redeemedStr != "" {
-				redeemed, err := time.Parse(time.RFC3339, redeemedStr)
-				if err == nil {
-					user.Redeemed = &redeemed
-				}
+		// Changes are selected by the time of the last write
+		if params.Type == domain.ReportTypeChanged {
+			if !user.UpdatedAt.Before(params.From) && !user.UpdatedAt.After(params.To) {
+				users = append(users, user)
 			}
+			continue
 		}
 
 		// Apply date range filter
-		if !dateAdded.Before(params.From) && !dateAdded.After(params.To) {
+		if !user.DateAdded.Before(params.From) && !user.DateAdded.After(params.To) {
 			// Apply report type filter
 			switch params.Type {
 			case domain.ReportTypeRedeemed:
 				// Include only redeemed records
 				if user.Redeemed != nil {
-					users = append(users, &user)
+					users = append(users, user)
+				}
+			case domain.ReportTypeConsented:
+				// Include only records with marketing consent
+				if user.MarketingConsent != nil {
+					users = append(users, user)
 				}
 			case domain.ReportTypeAdded:
 				// Include all records within the date range
-				users = append(users, &user)
+				users = append(users, user)
 			case domain.ReportTypeAll:
 				// Include all records
-				users = append(users, &user)
+				users = append(users, user)
 			}
 		}
 	}
 
-	r.logger.Info("Report generated from Google Sheets", "type", params.Type, "count", len(users))
-	return users, nil
+	if params.Type == domain.ReportTypeChanged {
+		sortByUpdatedAt(users)
+	}
+	return users
+}
+
+// NormalizeEmails rewrites stored emails to lowercase without surrounding spaces
+func (r *GoogleSheetRepository) NormalizeEmails(ctx any) (int, error) {
+	cols, rows, err := r.readSheet()
+	if err != nil {
+		r.logger.Error("Failed to read Google Sheet for normalization", "error", err)
+		return 0, domain.ErrDatabaseUnavailable
+	}
+	if len(rows) <= 1 {
+		return 0, nil
+	}
+
+	// Collect emails, skipping the header
+	emails := make([]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		emails = append(emails, cols.cell(row, "Email"))
+	}
+
+	// Refuse to run if two rows would end up with the same email
+	if dups := duplicateEmails(emails); len(dups) > 0 {
+		return 0, duplicateEmailsError(dups)
+	}
+
+	changed := 0
+	values := make([][]interface{}, len(emails))
+	for i, email := range emails {
+		normalized := utils.NormalizeEmail(email)
+		if normalized != email {
+			changed++
+		}
+		values[i] = []interface{}{normalized}
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+
+	// Write the whole column back in one request
+	column := cols.column("Email")
+	updateRange := fmt.Sprintf("%s!%s2:%s%d", r.sheetName, column, column, len(emails)+1)
+	_, err = r.service.Spreadsheets.Values.Update(r.spreadsheetID, updateRange, &sheets.ValueRange{Values: values}).
+		ValueInputOption("RAW").Context(context.Background()).Do()
+	if err != nil {
+		r.logger.Error("Failed to write normalized emails to Google Sheet", "error", err)
+		return 0, err
+	}
+
+	r.logger.Info("Emails normalized in Google Sheets", "changed", changed)
+	return changed, nil
+}
+
+// Health checks that the spreadsheet can be reached
+func (r *GoogleSheetRepository) Health(ctx any) error {
+	_, err := r.service.Spreadsheets.Get(r.spreadsheetID).Fields("spreadsheetId").Context(context.Background()).Do()
+	if err != nil {
+		r.logger.Sampled("health_check").Error("Google Sheets health check failed", "error", err)
+		return domain.ErrDatabaseUnavailable
+	}
+	return nil
+}
+
+// Stats returns record counts and spreadsheet information
+func (r *GoogleSheetRepository) Stats(ctx any) (domain.RepoStats, error) {
+	users, err := r.GetReport(ctx, allUsersReport)
+	if err != nil {
+		return domain.RepoStats{Backend: "googlesheet"}, err
+	}
+
+	stats := statsFromUsers("googlesheet", users)
+	stats.Details["spreadsheet_id"] = r.spreadsheetID
+	stats.Details["sheet"] = r.sheetName
+
+	return stats, nil
 }
 
 func (r *GoogleSheetRepository) Close() error {
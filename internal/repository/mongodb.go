@@ -3,10 +3,13 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/ceesaxp/cocktail-bot/internal/domain"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -14,17 +17,51 @@ import (
 
 // MongoDBRepository implements a MongoDB-backed repository
 type MongoDBRepository struct {
+	timeouts
 	client     *mongo.Client
 	collection *mongo.Collection
 	logger     *logger.Logger
 }
 
+// emailCollation makes email comparisons case-insensitive
+var emailCollation = &options.Collation{Locale: "en", Strength: 2}
+
 // User represents a user document in MongoDB
 type mongoUser struct {
-	ID        string     `bson:"_id"`
-	Email     string     `bson:"email"`
-	DateAdded time.Time  `bson:"date_added"`
-	Redeemed  *time.Time `bson:"redeemed,omitempty"`
+	ID               string     `bson:"_id"`
+	Email            string     `bson:"email"`
+	DateAdded        time.Time  `bson:"date_added"`
+	Redeemed         *time.Time `bson:"redeemed,omitempty"`
+	MarketingConsent *time.Time `bson:"marketing_consent,omitempty"`
+	UpdatedAt        time.Time  `bson:"updated_at,omitempty"`
+	CreatedBy        string     `bson:"created_by,omitempty"`
+	Bar              string     `bson:"bar,omitempty"`
+	Notes            string     `bson:"notes,omitempty"`
+	Tags             []string   `bson:"tags,omitempty"`
+	FirstName        string     `bson:"first_name,omitempty"`
+	LastName         string     `bson:"last_name,omitempty"`
+}
+
+// toUser converts a document to the domain model
+func (m mongoUser) toUser() *domain.User {
+	user := &domain.User{
+		ID:               m.ID,
+		Email:            m.Email,
+		DateAdded:        m.DateAdded,
+		Redeemed:         m.Redeemed,
+		MarketingConsent: m.MarketingConsent,
+		UpdatedAt:        m.UpdatedAt,
+		CreatedBy:        m.CreatedBy,
+		RedeemedAt:       m.Bar,
+		Notes:            m.Notes,
+		Tags:             m.Tags,
+		FirstName:        m.FirstName,
+		LastName:         m.LastName,
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = user.LastChange()
+	}
+	return user
 }
 
 // NewMongoDBRepository creates a new MongoDB repository
@@ -44,14 +81,16 @@ func NewMongoDBRepository(ctx any, connectionString string, logger *logger.Logge
 	}
 
 	// Check connection
-	err = client.Ping(context.Background(), nil)
+	pingCtx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	err = client.Ping(pingCtx, nil)
+	cancel()
 	if err != nil {
 		disconnectErr := client.Disconnect(context.Background())
 		if disconnectErr != nil {
 			logger.Error("Failed to disconnect MongoDB client", "error", disconnectErr)
 		}
 		logger.Error("Failed to ping MongoDB", "error", err)
-		return nil, domain.ErrDatabaseUnavailable
+		return nil, fmt.Errorf("%w: %w", domain.ErrDatabaseUnavailable, err)
 	}
 
 	// Extract database and collection names from connection string
@@ -68,6 +107,13 @@ func NewMongoDBRepository(ctx any, connectionString string, logger *logger.Logge
 		Options: options.Index().SetUnique(true),
 	}
 	_, err = collection.Indexes().CreateOne(context.Background(), indexModel)
+	if err == nil {
+		// Case-insensitive index used by email lookups
+		_, err = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetName("email_ci").SetCollation(emailCollation),
+		})
+	}
 	if err != nil {
 		disconnectErr := client.Disconnect(context.Background())
 		if disconnectErr != nil {
@@ -77,8 +123,21 @@ func NewMongoDBRepository(ctx any, connectionString string, logger *logger.Logge
 		return nil, err
 	}
 
+	// Documents written by older versions get the latest of their timestamps
+	// as updated_at, so that changes reports can select them
+	_, err = collection.UpdateMany(context.Background(),
+		bson.M{"updated_at": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"updated_at": bson.M{"$max": bson.A{"$date_added", "$redeemed", "$marketing_consent"}},
+		}}}},
+	)
+	if err != nil {
+		logger.Warn("Failed to backfill updated_at", "error", err)
+	}
+
 	logger.Info("MongoDB Repository initialized", "database", database, "collection", collectionName)
 	return &MongoDBRepository{
+		timeouts:   defaultTimeouts(),
 		client:     client,
 		collection: collection,
 		logger:     logger,
@@ -94,10 +153,11 @@ func (r *MongoDBRepository) FindByEmail(ctx any, email string) (*domain.User, er
 	r.logger.Debug("Looking for email in MongoDB", "email", email)
 
 	// Query for user
-	filter := bson.M{"email": email}
+	filter := bson.M{"email": utils.NormalizeEmail(email)}
 	var result mongoUser
 
-	err := r.collection.FindOne(context.Background(), filter).Decode(&result)
+	opts := options.FindOne().SetCollation(emailCollation)
+	err := r.collection.FindOne(context.Background(), filter, opts).Decode(&result)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			r.logger.Debug("User not found in MongoDB", "email", email)
@@ -108,12 +168,7 @@ func (r *MongoDBRepository) FindByEmail(ctx any, email string) (*domain.User, er
 	}
 
 	// Convert to domain model
-	user := &domain.User{
-		ID:              result.ID,
-		Email:           result.Email,
-		DateAdded:       result.DateAdded,
-		Redeemed: result.Redeemed,
-	}
+	user := result.toUser()
 
 	r.logger.Debug("Found user in MongoDB", "email", email, "redeemed", user.IsRedeemed())
 	return user, nil
@@ -128,17 +183,45 @@ func (r *MongoDBRepository) UpdateUser(ctx any, user *domain.User) error {
 	r.logger.Debug("Updating user in MongoDB", "email", user.Email)
 
 	// Convert to MongoDB document
+	stampUser(user)
 	doc := mongoUser{
 		ID:              user.ID,
-		Email:           user.Email,
+		Email:           utils.NormalizeEmail(user.Email),
 		DateAdded:       user.DateAdded,
 		Redeemed: user.Redeemed,
+		MarketingConsent: user.MarketingConsent,
+		UpdatedAt:        user.UpdatedAt,
+		CreatedBy:        user.CreatedBy,
+		Bar:              user.RedeemedAt,
+		Notes:            user.Notes,
+		Tags:             user.Tags,
+		FirstName:        user.FirstName,
+		LastName:         user.LastName,
 	}
 
 	// Use upsert to create or update
-	filter := bson.M{"email": user.Email}
+	filter := bson.M{"email": doc.Email}
 	update := bson.M{"$set": doc}
-	opts := options.Update().SetUpsert(true)
+
+	// Fields left out of the document are removed, so that a redemption
+	// can be undone and notes and names cleared
+	unset := bson.M{}
+	if doc.Redeemed == nil {
+		unset["redeemed"] = ""
+	}
+	if doc.MarketingConsent == nil {
+		unset["marketing_consent"] = ""
+	}
+	for field, empty := range map[string]bool{"bar": doc.Bar == "", "notes": doc.Notes == "", "tags": len(doc.Tags) == 0,
+		"first_name": doc.FirstName == "", "last_name": doc.LastName == ""} {
+		if empty {
+			unset[field] = ""
+		}
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	opts := options.Update().SetUpsert(true).SetCollation(emailCollation)
 
 	_, err := r.collection.UpdateOne(context.Background(), filter, update, opts)
 	if err != nil {
@@ -159,29 +242,45 @@ func (r *MongoDBRepository) AddUser(ctx any, user *domain.User) error {
 	r.logger.Debug("Adding user to MongoDB", "email", user.Email)
 
 	// Check if user already exists
-	filter := bson.M{"email": user.Email}
-	count, err := r.collection.CountDocuments(context.Background(), filter)
+	email := utils.NormalizeEmail(user.Email)
+	id, err := r.storedUserID(email)
 	if err != nil {
 		r.logger.Error("Error checking if user exists", "error", err)
 		return err
 	}
-	
-	if count > 0 {
+
+	if id != "" {
 		r.logger.Debug("User already exists in MongoDB", "email", user.Email)
-		return errors.New("user already exists")
+		return domain.NewDuplicateUserError(email, id)
 	}
 
 	// Convert to MongoDB document
+	stampUser(user)
 	doc := mongoUser{
 		ID:        user.ID,
-		Email:     user.Email,
+		Email:     email,
 		DateAdded: user.DateAdded,
 		Redeemed:  user.Redeemed,
+		MarketingConsent: user.MarketingConsent,
+		UpdatedAt:        user.UpdatedAt,
+		CreatedBy:        user.CreatedBy,
+		Bar:              user.RedeemedAt,
+		Notes:            user.Notes,
+		Tags:             user.Tags,
+		FirstName:        user.FirstName,
+		LastName:         user.LastName,
 	}
 
 	// Insert document
 	_, err = r.collection.InsertOne(context.Background(), doc)
 	if err != nil {
+		// A concurrent insert of the same email fails on the unique index
+		if mongo.IsDuplicateKeyError(err) {
+			if id, _ := r.storedUserID(email); id != "" {
+				r.logger.Debug("User already exists in MongoDB", "email", user.Email)
+				return domain.NewDuplicateUserError(email, id)
+			}
+		}
 		r.logger.Error("Error adding user to MongoDB", "error", err)
 		return err
 	}
@@ -190,10 +289,81 @@ func (r *MongoDBRepository) AddUser(ctx any, user *domain.User) error {
 	return nil
 }
 
+// storedUserID returns the ID of the user stored with an email, or an
+// empty ID if there is none
+func (r *MongoDBRepository) storedUserID(email string) (string, error) {
+	var doc mongoUser
+	opts := options.FindOne().SetCollation(emailCollation).SetProjection(bson.M{"_id": 1})
+	err := r.collection.FindOne(context.Background(), bson.M{"email": email}, opts).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	return doc.ID, err
+}
+
 // GetReport retrieves users based on the report parameters
 func (r *MongoDBRepository) GetReport(ctx any, params domain.ReportParams) ([]*domain.User, error) {
 	r.logger.Debug("Generating report from MongoDB", "type", params.Type, "from", params.From, "to", params.To)
 
+	filter, err := mongoReportFilter(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Changes are sorted oldest first, other reports newest first
+	findOptions := options.Find().SetSort(bson.M{"date_added": -1})
+	if params.Type == domain.ReportTypeChanged {
+		findOptions.SetSort(bson.M{"updated_at": 1})
+	}
+
+	// Execute query with timeout
+	ctxWithTimeout, cancel := r.bulkContext()
+	defer cancel()
+	
+	cursor, err := r.collection.Find(ctxWithTimeout, filter, findOptions)
+	if err != nil {
+		r.logger.Error("Failed to execute MongoDB query", "error", err)
+		return nil, err
+	}
+	defer cursor.Close(ctxWithTimeout)
+
+	// Process results
+	var mongoUsers []mongoUser
+	if err = cursor.All(ctxWithTimeout, &mongoUsers); err != nil {
+		r.logger.Error("Failed to decode MongoDB results", "error", err)
+		return nil, err
+	}
+
+	// Convert to domain objects
+	users := make([]*domain.User, len(mongoUsers))
+	for i, mongoUser := range mongoUsers {
+		users[i] = mongoUser.toUser()
+	}
+
+	r.logger.Info("Report generated from MongoDB", "type", params.Type, "count", len(users))
+	return users, nil
+}
+
+// CountReport counts the users of a report without loading them
+func (r *MongoDBRepository) CountReport(ctx any, params domain.ReportParams) (int, error) {
+	filter, err := mongoReportFilter(params)
+	if err != nil {
+		return 0, err
+	}
+
+	ctxWithTimeout, cancel := r.bulkContext()
+	defer cancel()
+
+	count, err := r.collection.CountDocuments(ctxWithTimeout, filter)
+	if err != nil {
+		r.logger.Error("Failed to count MongoDB report", "error", err)
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// mongoReportFilter returns the filter selecting the users of a report
+func mongoReportFilter(params domain.ReportParams) (bson.M, error) {
 	// Create date filter
 	dateFilter := bson.M{
 		"date_added": bson.M{
@@ -205,6 +375,14 @@ func (r *MongoDBRepository) GetReport(ctx any, params domain.ReportParams) ([]*d
 	// Add report type filter
 	var filter bson.M
 	switch params.Type {
+	case domain.ReportTypeChanged:
+		// Changes are selected by the time of the last write
+		filter = bson.M{
+			"updated_at": bson.M{
+				"$gte": params.From,
+				"$lte": params.To,
+			},
+		}
 	case domain.ReportTypeRedeemed:
 		// Only get users who have redeemed within the date range
 		filter = bson.M{
@@ -213,6 +391,14 @@ func (r *MongoDBRepository) GetReport(ctx any, params domain.ReportParams) ([]*d
 				{"redeemed": bson.M{"$ne": nil}},
 			},
 		}
+	case domain.ReportTypeConsented:
+		// Only get users who opted in to marketing
+		filter = bson.M{
+			"$and": []bson.M{
+				dateFilter,
+				{"marketing_consent": bson.M{"$ne": nil}},
+			},
+		}
 	case domain.ReportTypeAdded, domain.ReportTypeAll:
 		// Get all users within the date range
 		filter = dateFilter
@@ -220,40 +406,140 @@ func (r *MongoDBRepository) GetReport(ctx any, params domain.ReportParams) ([]*d
 		return nil, errors.New("invalid report type")
 	}
 
-	// Set up options (sorting by date added, newest first)
-	findOptions := options.Find().SetSort(bson.M{"date_added": -1})
+	// Add the optional filters
+	var conditions []bson.M
+	if params.Domain != "" {
+		conditions = append(conditions, bson.M{"email": bson.M{"$regex": "@" + regexp.QuoteMeta(params.Domain) + "$", "$options": "i"}})
+	}
+	if params.Source != "" {
+		conditions = append(conditions, bson.M{"$or": []bson.M{
+			{"created_by": params.Source},
+			{"created_by": bson.M{"$regex": "^" + regexp.QuoteMeta(params.Source+":")}},
+		}})
+	}
+	if params.Bar != "" {
+		conditions = append(conditions, bson.M{"bar": params.Bar})
+	}
+	if len(conditions) > 0 {
+		filter = bson.M{"$and": append([]bson.M{filter}, conditions...)}
+	}
+	return filter, nil
+}
 
-	// Execute query with timeout
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// NormalizeEmails rewrites stored emails to lowercase without surrounding spaces
+func (r *MongoDBRepository) NormalizeEmails(ctx any) (int, error) {
+	ctxWithTimeout, cancel := r.bulkContext()
 	defer cancel()
-	
-	cursor, err := r.collection.Find(ctxWithTimeout, filter, findOptions)
+
+	// Load all emails
+	cursor, err := r.collection.Find(ctxWithTimeout, bson.M{}, options.Find().SetProjection(bson.M{"email": 1}))
 	if err != nil {
-		r.logger.Error("Failed to execute MongoDB query", "error", err)
-		return nil, err
+		r.logger.Error("Failed to read emails from MongoDB", "error", err)
+		return 0, err
 	}
-	defer cursor.Close(ctxWithTimeout)
-
-	// Process results
-	var mongoUsers []mongoUser
-	if err = cursor.All(ctxWithTimeout, &mongoUsers); err != nil {
+	var docs []mongoUser
+	if err := cursor.All(ctxWithTimeout, &docs); err != nil {
 		r.logger.Error("Failed to decode MongoDB results", "error", err)
-		return nil, err
+		return 0, err
 	}
 
-	// Convert to domain objects
-	users := make([]*domain.User, len(mongoUsers))
-	for i, mongoUser := range mongoUsers {
-		users[i] = &domain.User{
-			ID:        mongoUser.ID,
-			Email:     mongoUser.Email,
-			DateAdded: mongoUser.DateAdded,
-			Redeemed:  mongoUser.Redeemed,
+	// Refuse to run if two documents would end up with the same email
+	emails := make([]string, len(docs))
+	for i, doc := range docs {
+		emails[i] = doc.Email
+	}
+	if dups := duplicateEmails(emails); len(dups) > 0 {
+		return 0, duplicateEmailsError(dups)
+	}
+
+	changed := 0
+	for _, doc := range docs {
+		normalized := utils.NormalizeEmail(doc.Email)
+		if normalized == doc.Email {
+			continue
 		}
+		_, err := r.collection.UpdateOne(ctxWithTimeout, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{"email": normalized}})
+		if err != nil {
+			r.logger.Error("Failed to normalize email", "email", doc.Email, "error", err)
+			return changed, err
+		}
+		changed++
 	}
 
-	r.logger.Info("Report generated from MongoDB", "type", params.Type, "count", len(users))
-	return users, nil
+	r.logger.Info("Emails normalized in MongoDB", "changed", changed)
+	return changed, nil
+}
+
+// Health checks that the MongoDB server can be reached
+func (r *MongoDBRepository) Health(ctx any) error {
+	ctxWithTimeout, cancel := r.queryContext()
+	defer cancel()
+
+	if err := r.client.Ping(ctxWithTimeout, nil); err != nil {
+		r.logger.Sampled("health_check").Error("MongoDB health check failed", "error", err)
+		return domain.ErrDatabaseUnavailable
+	}
+	return nil
+}
+
+// Stats returns document counts and collection information
+func (r *MongoDBRepository) Stats(ctx any) (domain.RepoStats, error) {
+	ctxWithTimeout, cancel := r.bulkContext()
+	defer cancel()
+
+	stats := domain.RepoStats{
+		Backend: "mongodb",
+		Details: map[string]string{
+			"database":   r.collection.Database().Name(),
+			"collection": r.collection.Name(),
+		},
+	}
+
+	counts := []struct {
+		filter bson.M
+		dest   *int
+	}{
+		{bson.M{}, &stats.Users},
+		{bson.M{"redeemed": bson.M{"$ne": nil}}, &stats.Redeemed},
+		{bson.M{"marketing_consent": bson.M{"$ne": nil}}, &stats.Consented},
+	}
+	for _, c := range counts {
+		count, err := r.collection.CountDocuments(ctxWithTimeout, c.filter)
+		if err != nil {
+			r.logger.Error("Error counting MongoDB documents", "error", err)
+			return stats, domain.ErrDatabaseUnavailable
+		}
+		*c.dest = int(count)
+	}
+
+	// The last write is the most recent of any timestamp field
+	for _, field := range []string{"date_added", "redeemed", "marketing_consent", "updated_at"} {
+		var doc mongoUser
+		opts := options.FindOne().SetSort(bson.D{{Key: field, Value: -1}})
+		err := r.collection.FindOne(ctxWithTimeout, bson.M{field: bson.M{"$ne": nil}}, opts).Decode(&doc)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			r.logger.Error("Error reading MongoDB last write", "error", err)
+			return stats, domain.ErrDatabaseUnavailable
+		}
+
+		t := doc.DateAdded
+		switch {
+		case field == "redeemed" && doc.Redeemed != nil:
+			t = *doc.Redeemed
+		case field == "marketing_consent" && doc.MarketingConsent != nil:
+			t = *doc.MarketingConsent
+		case field == "updated_at":
+			t = doc.UpdatedAt
+		}
+		if stats.LastWrite == nil || t.After(*stats.LastWrite) {
+			stats.LastWrite = &t
+		}
+	}
+
+	return stats, nil
 }
 
 func (r *MongoDBRepository) Close() error {
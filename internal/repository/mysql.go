@@ -5,14 +5,15 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/ceesaxp/cocktail-bot/internal/domain"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
 	_ "github.com/go-sql-driver/mysql"
 )
 
 type MySQLRepository struct {
+	timeouts
 	db     *sql.DB
 	logger *logger.Logger
 }
@@ -33,20 +34,30 @@ func NewMySQLRepository(ctx any, connectionString string, logger *logger.Logger)
 	}
 
 	// Check connection
-	err = db.PingContext(context.Background())
+	pingCtx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	err = db.PingContext(pingCtx)
+	cancel()
 	if err != nil {
 		db.Close()
 		logger.Error("Failed to ping MySQL", "error", err)
-		return nil, domain.ErrDatabaseUnavailable
+		return nil, fmt.Errorf("%w: %w", domain.ErrDatabaseUnavailable, err)
 	}
 
 	// Create table if it doesn't exist
 	_, err = db.ExecContext(context.Background(), `
 		CREATE TABLE IF NOT EXISTS users (
 			id VARCHAR(255) PRIMARY KEY,
-			email VARCHAR(255) UNIQUE NOT NULL,
+			email VARCHAR(255) COLLATE utf8mb4_unicode_ci UNIQUE NOT NULL,
 			date_added DATETIME NOT NULL,
-			redeemed DATETIME
+			redeemed DATETIME,
+			marketing_consent DATETIME,
+			updated_at DATETIME(6),
+			created_by VARCHAR(255),
+			bar VARCHAR(255),
+			notes TEXT,
+			tags TEXT,
+			first_name VARCHAR(255),
+			last_name VARCHAR(255)
 		);
 		CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 	`)
@@ -56,10 +67,54 @@ func NewMySQLRepository(ctx any, connectionString string, logger *logger.Logger)
 		return nil, err
 	}
 
+	// Upgrade tables created by older versions
+	if err := addColumnIfMissing(db, dialectMySQL, "marketing_consent", "DATETIME"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+	// Fractional seconds keep changes within one second apart
+	if err := addUpdatedAt(db, dialectMySQL, "DATETIME(6)"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, dialectMySQL, "created_by", "VARCHAR(255)"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, dialectMySQL, "bar", "VARCHAR(255)"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, dialectMySQL, "notes", "TEXT"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, dialectMySQL, "tags", "TEXT"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, dialectMySQL, "first_name", "VARCHAR(255)"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, dialectMySQL, "last_name", "VARCHAR(255)"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+
 	logger.Info("MySQL Repository initialized")
 	return &MySQLRepository{
-		db:     db,
-		logger: logger,
+		timeouts: defaultTimeouts(),
+		db:       db,
+		logger:   logger,
 	}, nil
 }
 
@@ -71,23 +126,16 @@ func (r *MySQLRepository) FindByEmail(ctx any, email string) (*domain.User, erro
 	r.logger.Debug("Looking for email in MySQL", "email", email)
 
 	// Query for user
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctxWithTimeout, cancel := r.queryContext()
 	defer cancel()
 	row := r.db.QueryRowContext(ctxWithTimeout, `
-		SELECT id, email, date_added, redeemed
+		SELECT `+userColumns+`
 		FROM users
 		WHERE email = ?
-	`, email)
+	`, utils.NormalizeEmail(email))
 
 	// Parse result
-	var (
-		id          string
-		userEmail   string
-		dateAdded   time.Time
-		redeemedSQL sql.NullTime
-	)
-
-	err := row.Scan(&id, &userEmail, &dateAdded, &redeemedSQL)
+	user, err := scanUser(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			r.logger.Debug("User not found in MySQL", "email", email)
@@ -97,19 +145,6 @@ func (r *MySQLRepository) FindByEmail(ctx any, email string) (*domain.User, erro
 		return nil, err
 	}
 
-	// Create user
-	user := &domain.User{
-		ID:        id,
-		Email:     userEmail,
-		DateAdded: dateAdded,
-	}
-
-	// Handle redeemed
-	if redeemedSQL.Valid {
-		redeemed := redeemedSQL.Time
-		user.Redeemed = &redeemed
-	}
-
 	r.logger.Debug("Found user in MySQL", "email", email, "redeemed", user.IsRedeemed())
 	return user, nil
 }
@@ -122,7 +157,7 @@ func (r *MySQLRepository) UpdateUser(ctx any, user *domain.User) error {
 	r.logger.Debug("Updating user in MySQL", "email", user.Email)
 
 	// Prepare transaction
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctxWithTimeout, cancel := r.queryContext()
 	defer cancel()
 	tx, err := r.db.BeginTx(ctxWithTimeout, nil)
 	if err != nil {
@@ -135,40 +170,26 @@ func (r *MySQLRepository) UpdateUser(ctx any, user *domain.User) error {
 		}
 	}()
 
-	// Check if user exists
+	// Check if user exists (the email column uses a case-insensitive collation)
+	email := utils.NormalizeEmail(user.Email)
 	var exists bool
-	err = tx.QueryRowContext(ctxWithTimeout, "SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)", user.Email).Scan(&exists)
+	err = tx.QueryRowContext(ctxWithTimeout, "SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)", email).Scan(&exists)
 	if err != nil {
 		r.logger.Error("Error checking if user exists", "error", err)
 		return err
 	}
 
+	stampUser(user)
 	if exists {
-		// Update existing user
-		var query string
-		var args []interface{}
-
-		if user.Redeemed != nil {
-			query = "UPDATE users SET id = ?, date_added = ?, redeemed = ? WHERE email = ?"
-			args = []interface{}{user.ID, user.DateAdded, user.Redeemed, user.Email}
-		} else {
-			query = "UPDATE users SET id = ?, date_added = ?, redeemed = NULL WHERE email = ?"
-			args = []interface{}{user.ID, user.DateAdded, user.Email}
-		}
+		// Update existing user, storing the normalized email
+		query := "UPDATE users SET id = ?, email = ?, date_added = ?, redeemed = ?, marketing_consent = ?, updated_at = ?, created_by = COALESCE(NULLIF(?, ''), created_by), bar = ?, notes = ?, tags = ?, first_name = ?, last_name = ? WHERE email = ?"
+		args := append(userArgs(user), email)
 
 		_, err = tx.ExecContext(ctxWithTimeout, query, args...)
 	} else {
 		// Insert new user
-		var query string
-		var args []interface{}
-
-		if user.Redeemed != nil {
-			query = "INSERT INTO users(id, email, date_added, redeemed) VALUES(?, ?, ?, ?)"
-			args = []interface{}{user.ID, user.Email, user.DateAdded, user.Redeemed}
-		} else {
-			query = "INSERT INTO users(id, email, date_added, redeemed) VALUES(?, ?, ?, NULL)"
-			args = []interface{}{user.ID, user.Email, user.DateAdded}
-		}
+		query := "INSERT INTO users(" + userColumns + ") VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+		args := userArgs(user)
 
 		_, err = tx.ExecContext(ctxWithTimeout, query, args...)
 	}
@@ -197,36 +218,36 @@ func (r *MySQLRepository) AddUser(ctx any, user *domain.User) error {
 	r.logger.Debug("Adding user to MySQL", "email", user.Email)
 
 	// Prepare transaction
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctxWithTimeout, cancel := r.queryContext()
 	defer cancel()
 	
 	// Check if user already exists
-	var exists bool
-	err := r.db.QueryRowContext(ctxWithTimeout, "SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)", user.Email).Scan(&exists)
+	email := utils.NormalizeEmail(user.Email)
+	idQuery := "SELECT id FROM users WHERE email = ? LIMIT 1"
+	id, err := storedUserID(ctxWithTimeout, r.db, idQuery, email)
 	if err != nil {
 		r.logger.Error("Error checking if user exists", "error", err)
 		return err
 	}
-	
-	if exists {
+
+	if id != "" {
 		r.logger.Debug("User already exists in MySQL", "email", user.Email)
-		return errors.New("user already exists")
+		return domain.NewDuplicateUserError(email, id)
 	}
 
 	// Insert new user
-	var query string
-	var args []interface{}
-	
-	if user.Redeemed != nil {
-		query = "INSERT INTO users(id, email, date_added, redeemed) VALUES(?, ?, ?, ?)"
-		args = []interface{}{user.ID, user.Email, user.DateAdded, user.Redeemed}
-	} else {
-		query = "INSERT INTO users(id, email, date_added, redeemed) VALUES(?, ?, ?, NULL)"
-		args = []interface{}{user.ID, user.Email, user.DateAdded}
-	}
+	stampUser(user)
+	query := "INSERT INTO users(" + userColumns + ") VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	args := userArgs(user)
 	
 	_, err = r.db.ExecContext(ctxWithTimeout, query, args...)
 	if err != nil {
+		// A concurrent insert of the same email fails on the unique column
+		err = insertConflict(ctxWithTimeout, r.db, idQuery, email, err)
+		if domain.IsDuplicateUser(err) {
+			r.logger.Debug("User already exists in MySQL", "email", user.Email)
+			return err
+		}
 		r.logger.Error("Error adding user", "error", err)
 		return fmt.Errorf("failed to add user: %w", err)
 	}
@@ -235,49 +256,32 @@ func (r *MySQLRepository) AddUser(ctx any, user *domain.User) error {
 	return nil
 }
 
+// AddUsers adds users in one transaction, or none of them if any fails
+func (r *MySQLRepository) AddUsers(ctx any, users []*domain.User) error {
+	ctxWithTimeout, cancel := r.bulkContext()
+	defer cancel()
+
+	if err := insertUsers(ctxWithTimeout, r.db, users, questionPlaceholder); err != nil {
+		r.logger.Debug("Error adding users in batch", "count", len(users), "error", err)
+		return fmt.Errorf("failed to add users: %w", err)
+	}
+
+	r.logger.Debug("Users added to MySQL", "count", len(users))
+	return nil
+}
+
 // GetReport retrieves users based on the report parameters
 func (r *MySQLRepository) GetReport(ctx any, params domain.ReportParams) ([]*domain.User, error) {
 	r.logger.Debug("Generating report from MySQL", "type", params.Type, "from", params.From, "to", params.To)
 
-	var query string
-	var args []interface{}
-
 	// Build different queries based on report type
-	switch params.Type {
-	case domain.ReportTypeRedeemed:
-		// Only get users who have redeemed within the date range
-		query = `
-			SELECT id, email, date_added, redeemed 
-			FROM users 
-			WHERE date_added >= ? AND date_added <= ? 
-			AND redeemed IS NOT NULL
-			ORDER BY date_added DESC
-		`
-		args = []interface{}{params.From, params.To}
-	case domain.ReportTypeAdded:
-		// Get users added within the date range
-		query = `
-			SELECT id, email, date_added, redeemed 
-			FROM users 
-			WHERE date_added >= ? AND date_added <= ?
-			ORDER BY date_added DESC
-		`
-		args = []interface{}{params.From, params.To}
-	case domain.ReportTypeAll:
-		// Get all users
-		query = `
-			SELECT id, email, date_added, redeemed 
-			FROM users
-			WHERE date_added >= ? AND date_added <= ?
-			ORDER BY date_added DESC
-		`
-		args = []interface{}{params.From, params.To}
-	default:
-		return nil, fmt.Errorf("invalid report type: %s", params.Type)
+	query, args, err := reportQuery(params, questionPlaceholder)
+	if err != nil {
+		return nil, err
 	}
 
 	// Execute query with timeout
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctxWithTimeout, cancel := r.bulkContext()
 	defer cancel()
 
 	rows, err := r.db.QueryContext(ctxWithTimeout, query, args...)
@@ -290,31 +294,11 @@ func (r *MySQLRepository) GetReport(ctx any, params domain.ReportParams) ([]*dom
 	// Process results
 	var users []*domain.User
 	for rows.Next() {
-		var (
-			id            string
-			email         string
-			dateAdded     time.Time
-			redeemedTime  sql.NullTime
-		)
-
-		if err := rows.Scan(&id, &email, &dateAdded, &redeemedTime); err != nil {
+		user, err := scanUser(rows)
+		if err != nil {
 			r.logger.Error("Error scanning row", "error", err)
 			return nil, fmt.Errorf("error scanning row: %w", err)
 		}
-
-		// Create user object
-		user := &domain.User{
-			ID:        id,
-			Email:     email,
-			DateAdded: dateAdded,
-		}
-
-		// Handle redeemed time
-		if redeemedTime.Valid {
-			t := redeemedTime.Time
-			user.Redeemed = &t
-		}
-
 		users = append(users, user)
 	}
 
@@ -327,6 +311,43 @@ func (r *MySQLRepository) GetReport(ctx any, params domain.ReportParams) ([]*dom
 	return users, nil
 }
 
+// NormalizeEmails rewrites stored emails to lowercase without surrounding spaces
+func (r *MySQLRepository) NormalizeEmails(ctx any) (int, error) {
+	changed, err := normalizeEmailsSQL(r.db)
+	if err != nil {
+		r.logger.Error("Error normalizing emails", "error", err)
+		return 0, err
+	}
+
+	r.logger.Info("Emails normalized in MySQL", "changed", changed)
+	return changed, nil
+}
+
+// Health checks that the database server can be reached
+func (r *MySQLRepository) Health(ctx any) error {
+	if err := r.db.Ping(); err != nil {
+		r.logger.Sampled("health_check").Error("MySQL health check failed", "error", err)
+		return domain.ErrDatabaseUnavailable
+	}
+	return nil
+}
+
+// Stats returns record counts and server information
+func (r *MySQLRepository) Stats(ctx any) (domain.RepoStats, error) {
+	stats, err := sqlStats(r.db, dialectMySQL)
+	if err != nil {
+		r.logger.Error("Error collecting MySQL stats", "error", err)
+		return stats, domain.ErrDatabaseUnavailable
+	}
+
+	var version string
+	if err := r.db.QueryRow("SELECT VERSION()").Scan(&version); err == nil {
+		stats.Details["server_version"] = version
+	}
+
+	return stats, nil
+}
+
 func (r *MySQLRepository) Close() error {
 	r.logger.Debug("Closing MySQL repository")
 	if r.db != nil {
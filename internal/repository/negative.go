@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// NegativeCacheRepository remembers emails the repository did not find for
+// a TTL and answers repeated lookups of them as not found without asking
+// the repository. Adding or renaming a guest forgets the email right away,
+// so guests added through the API or an import are found at once. Guests
+// added by other processes are found once the TTL expires.
+type NegativeCacheRepository struct {
+	domain.Repository
+	ttl        time.Duration
+	maxEntries int
+	logger     *logger.Logger
+
+	mu         sync.Mutex
+	absent     map[string]time.Time // Map of normalized email -> when it expires
+	generation uint64               // Counts forgotten emails, so a lookup racing a write is not remembered
+
+	hits atomic.Int64 // Lookups answered from the cache
+}
+
+// NewNegativeCacheRepository remembers absent emails for ttl, at most
+// maxEntries of them
+func NewNegativeCacheRepository(repo domain.Repository, ttl time.Duration, maxEntries int, logger *logger.Logger) (*NegativeCacheRepository, error) {
+	if repo == nil {
+		return nil, errors.New("repository is required")
+	}
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+	if ttl <= 0 || maxEntries <= 0 {
+		return nil, fmt.Errorf("negative cache needs a positive TTL and size, got %s and %d", ttl, maxEntries)
+	}
+
+	return &NegativeCacheRepository{
+		Repository: repo,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		logger:     logger,
+		absent:     make(map[string]time.Time),
+	}, nil
+}
+
+// isAbsent reports whether the email was not found within the TTL
+func (r *NegativeCacheRepository) isAbsent(key string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expires, ok := r.absent[key]
+	if ok && now.After(expires) {
+		delete(r.absent, key)
+		return false
+	}
+	return ok
+}
+
+// remember marks the email as absent, unless an email was forgotten since
+// the lookup started. When full, expired emails are dropped, and the cache
+// is cleared if none has expired.
+func (r *NegativeCacheRepository) remember(key string, generation uint64, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if generation != r.generation {
+		return
+	}
+	if len(r.absent) >= r.maxEntries {
+		for email, expires := range r.absent {
+			if now.After(expires) {
+				delete(r.absent, email)
+			}
+		}
+		if len(r.absent) >= r.maxEntries {
+			r.logger.Debug("Negative cache full, clearing it", "entries", len(r.absent))
+			clear(r.absent)
+		}
+	}
+	r.absent[key] = now.Add(r.ttl)
+}
+
+// forget drops emails from the cache, so their next lookups reach the
+// repository
+func (r *NegativeCacheRepository) forget(emails ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	for _, email := range emails {
+		delete(r.absent, utils.NormalizeEmail(email))
+	}
+}
+
+// FindByEmail answers a recently absent email as not found, and looks up
+// other emails in the repository
+func (r *NegativeCacheRepository) FindByEmail(ctx any, email string) (*domain.User, error) {
+	key := utils.NormalizeEmail(email)
+	if r.isAbsent(key, time.Now()) {
+		r.hits.Add(1)
+		return nil, domain.ErrUserNotFound
+	}
+
+	r.mu.Lock()
+	generation := r.generation
+	r.mu.Unlock()
+
+	user, err := r.Repository.FindByEmail(ctx, email)
+	if domain.IsNotFound(err) {
+		r.remember(key, generation, time.Now())
+	}
+	return user, err
+}
+
+// UpdateUser updates the guest in the repository and forgets the email
+func (r *NegativeCacheRepository) UpdateUser(ctx any, user *domain.User) error {
+	defer r.forget(user.Email)
+	return r.Repository.UpdateUser(ctx, user)
+}
+
+// AddUser adds the guest to the repository and forgets the email. It is
+// forgotten after the write, also if it fails, so a lookup made while the
+// guest was written is not remembered.
+func (r *NegativeCacheRepository) AddUser(ctx any, user *domain.User) error {
+	defer r.forget(user.Email)
+	return r.Repository.AddUser(ctx, user)
+}
+
+// AddUsers adds guests in a batch if the repository supports batches, and
+// forgets their emails
+func (r *NegativeCacheRepository) AddUsers(ctx any, users []*domain.User) error {
+	adder, ok := r.Repository.(BatchAdder)
+	if !ok {
+		return ErrBatchUnsupported
+	}
+	emails := make([]string, len(users))
+	for i, user := range users {
+		emails[i] = user.Email
+	}
+	defer r.forget(emails...)
+	return adder.AddUsers(ctx, users)
+}
+
+// NormalizeEmails normalizes stored emails in the repository and clears
+// the cache, since stored emails may now match absent ones
+func (r *NegativeCacheRepository) NormalizeEmails(ctx any) (int, error) {
+	normalizer, ok := r.Repository.(EmailNormalizer)
+	if !ok {
+		return 0, errors.New("repository does not support email normalization")
+	}
+	changed, err := normalizer.NormalizeEmails(ctx)
+	r.mu.Lock()
+	r.generation++
+	clear(r.absent)
+	r.mu.Unlock()
+	return changed, err
+}
+
+// Stats returns statistics of the repository with the size of the cache
+// and the lookups it answered
+func (r *NegativeCacheRepository) Stats(ctx any) (domain.RepoStats, error) {
+	stats, err := r.Repository.Stats(ctx)
+	if err != nil {
+		return stats, err
+	}
+	if stats.Details == nil {
+		stats.Details = make(map[string]string)
+	}
+
+	r.mu.Lock()
+	stats.Details["negative_cache_entries"] = fmt.Sprint(len(r.absent))
+	r.mu.Unlock()
+	stats.Details["negative_cache_hits"] = fmt.Sprint(r.hits.Load())
+	stats.Details["negative_cache_ttl"] = r.ttl.String()
+	return stats, nil
+}
@@ -0,0 +1,97 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+)
+
+func TestNegativeCacheRepository(t *testing.T) {
+	now := time.Now().Format(time.RFC3339)
+	backend := &flakyRepository{Repository: newCSVForTest(t, "1,guest@example.com,"+now+",,\n")}
+
+	repo, err := repository.NewNegativeCacheRepository(backend, 100*time.Millisecond, 10, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create negative cache: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	if _, err := repo.FindByEmail(ctx, "Stranger@Example.com"); !domain.IsNotFound(err) {
+		t.Fatalf("Expected not found, got %v", err)
+	}
+
+	// With the backend down, the absent email is answered from the cache
+	// while other emails reach the backend
+	backend.down = true
+	if _, err := repo.FindByEmail(ctx, "stranger@example.com"); !domain.IsNotFound(err) {
+		t.Errorf("Expected absent email to be answered from the cache, got %v", err)
+	}
+	if _, err := repo.FindByEmail(ctx, "guest@example.com"); !errors.Is(err, errConnectionRefused) {
+		t.Errorf("Expected known email to be looked up, got %v", err)
+	}
+
+	// Adding the email forgets it at once
+	backend.down = false
+	if err := repo.AddUser(ctx, &domain.User{ID: "2", Email: "stranger@example.com", DateAdded: time.Now()}); err != nil {
+		t.Fatalf("Failed to add user: %v", err)
+	}
+	if _, err := repo.FindByEmail(ctx, "stranger@example.com"); err != nil {
+		t.Errorf("Expected added email to be found, got %v", err)
+	}
+
+	// Absent emails are looked up again once the TTL expires
+	repo.FindByEmail(ctx, "late@example.com")
+	time.Sleep(150 * time.Millisecond)
+	backend.down = true
+	if _, err := repo.FindByEmail(ctx, "late@example.com"); !errors.Is(err, errConnectionRefused) {
+		t.Errorf("Expected expired email to be looked up, got %v", err)
+	}
+
+	backend.down = false
+	stats, err := repo.Stats(ctx)
+	if err != nil || stats.Details["negative_cache_hits"] != "1" {
+		t.Errorf("Expected one cache hit in the stats, got %v, %v", stats.Details, err)
+	}
+}
+
+func TestNegativeCacheBackendTTL(t *testing.T) {
+	cfg := config.DatabaseConfig{
+		Type:             "csv",
+		ConnectionString: filepath.Join(t.TempDir(), "users.csv"),
+		NegativeCache: config.NegativeCacheConfig{
+			Enabled:    true,
+			TTL:        config.Duration(30 * time.Second),
+			Backends:   map[string]config.Duration{"csv": 0},
+			MaxEntries: 10,
+		},
+	}
+
+	ctx := context.Background()
+	repo, err := repository.New(ctx, cfg, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	stats, _ := repo.Stats(ctx)
+	repo.Close()
+	if _, ok := stats.Details["negative_cache_ttl"]; ok {
+		t.Error("Expected a zero backend TTL to disable the cache")
+	}
+
+	cfg.NegativeCache.Backends = map[string]config.Duration{"googlesheet": config.Duration(time.Minute)}
+	repo, err = repository.New(ctx, cfg, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+	if stats, _ := repo.Stats(ctx); stats.Details["negative_cache_ttl"] != "30s" {
+		t.Errorf("Expected the default TTL for CSV, got %v", stats.Details)
+	}
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// ErrDuplicateEmails is returned when normalizing stored emails would
+// merge two different rows into the same address
+var ErrDuplicateEmails = errors.New("emails collide after normalization")
+
+// EmailNormalizer is implemented by repositories that can rewrite stored
+// emails to their normalized (trimmed, lowercase) form
+type EmailNormalizer interface {
+	// NormalizeEmails rewrites stored emails and returns how many were changed
+	NormalizeEmails(ctx any) (int, error)
+}
+
+// sameEmail compares two emails ignoring case and surrounding spaces
+func sameEmail(a, b string) bool {
+	return utils.NormalizeEmail(a) == utils.NormalizeEmail(b)
+}
+
+// duplicateEmails returns the normalized emails that occur more than once
+func duplicateEmails(emails []string) []string {
+	seen := make(map[string]int, len(emails))
+	for _, email := range emails {
+		seen[utils.NormalizeEmail(email)]++
+	}
+
+	var dups []string
+	for email, count := range seen {
+		if count > 1 {
+			dups = append(dups, email)
+		}
+	}
+	sort.Strings(dups)
+	return dups
+}
+
+// duplicateEmailsError wraps ErrDuplicateEmails with the offending addresses
+func duplicateEmailsError(dups []string) error {
+	return fmt.Errorf("%w: %s", ErrDuplicateEmails, strings.Join(dups, ", "))
+}
+
+// normalizeEmailsSQL rewrites stored emails in a SQL users table. It is
+// shared by the SQLite, PostgreSQL and MySQL repositories.
+func normalizeEmailsSQL(db *sql.DB) (int, error) {
+	// Refuse to run if two rows would end up with the same email
+	rows, err := db.Query(`SELECT LOWER(TRIM(email)) FROM users GROUP BY LOWER(TRIM(email)) HAVING COUNT(*) > 1`)
+	if err != nil {
+		return 0, err
+	}
+	var dups []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		dups = append(dups, email)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(dups) > 0 {
+		return 0, duplicateEmailsError(dups)
+	}
+
+	result, err := db.Exec(`UPDATE users SET email = LOWER(TRIM(email)) WHERE email <> LOWER(TRIM(email))`)
+	if err != nil {
+		return 0, err
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(changed), nil
+}
+
+// Ensure all repositories support email normalization
+var (
+	_ EmailNormalizer = (*CSVRepository)(nil)
+	_ EmailNormalizer = (*SQLiteRepository)(nil)
+	_ EmailNormalizer = (*PostgresRepository)(nil)
+	_ EmailNormalizer = (*MySQLRepository)(nil)
+	_ EmailNormalizer = (*MongoDBRepository)(nil)
+	_ EmailNormalizer = (*GoogleSheetRepository)(nil)
+)
@@ -5,14 +5,15 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/ceesaxp/cocktail-bot/internal/domain"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
 	_ "github.com/lib/pq" // PostgreSQL driver
 )
 
 type PostgresRepository struct {
+	timeouts
 	db     *sql.DB
 	logger *logger.Logger
 }
@@ -33,11 +34,13 @@ func NewPostgresRepository(ctx any, connectionString string, logger *logger.Logg
 	}
 
 	// Check connection
-	err = db.PingContext(context.Background())
+	pingCtx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	err = db.PingContext(pingCtx)
+	cancel()
 	if err != nil {
 		db.Close()
 		logger.Error("Failed to ping PostgreSQL", "error", err)
-		return nil, domain.ErrDatabaseUnavailable
+		return nil, fmt.Errorf("%w: %w", domain.ErrDatabaseUnavailable, err)
 	}
 
 	// Create table if it doesn't exist
@@ -46,9 +49,18 @@ func NewPostgresRepository(ctx any, connectionString string, logger *logger.Logg
 			id VARCHAR(255) PRIMARY KEY,
 			email VARCHAR(255) UNIQUE NOT NULL,
 			date_added TIMESTAMP NOT NULL,
-			redeemed TIMESTAMP
+			redeemed TIMESTAMP,
+			marketing_consent TIMESTAMP,
+			updated_at TIMESTAMP,
+			created_by TEXT,
+			bar TEXT,
+			notes TEXT,
+			tags TEXT,
+			first_name TEXT,
+			last_name TEXT
 		);
 		CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+		CREATE INDEX IF NOT EXISTS idx_users_email_lower ON users(LOWER(email));
 	`)
 	if err != nil {
 		db.Close()
@@ -56,10 +68,60 @@ func NewPostgresRepository(ctx any, connectionString string, logger *logger.Logg
 		return nil, err
 	}
 
+	// Upgrade tables created by older versions
+	if err := addColumnIfMissing(db, dialectPostgres, "marketing_consent", "TIMESTAMP"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+	if err := addUpdatedAt(db, dialectPostgres, "TIMESTAMP"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, dialectPostgres, "created_by", "TEXT"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, dialectPostgres, "bar", "TEXT"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, dialectPostgres, "notes", "TEXT"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, dialectPostgres, "tags", "TEXT"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, dialectPostgres, "first_name", "TEXT"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, dialectPostgres, "last_name", "TEXT"); err != nil {
+		db.Close()
+		logger.Error("Failed to migrate table", "error", err)
+		return nil, err
+	}
+
+	// Guest search looks for text inside the columns, which a trigram index
+	// speeds up. Without pg_trgm, or the right to install it, searches scan
+	// the table.
+	if err := createTrigramIndex(db); err != nil {
+		logger.Info("Guest search runs without a trigram index", "error", err)
+	}
+
 	logger.Info("PostgreSQL Repository initialized")
 	return &PostgresRepository{
-		db:     db,
-		logger: logger,
+		timeouts: defaultTimeouts(),
+		db:       db,
+		logger:   logger,
 	}, nil
 }
 
@@ -71,23 +133,16 @@ func (r *PostgresRepository) FindByEmail(ctx any, email string) (*domain.User, e
 	r.logger.Debug("Looking for email in PostgreSQL", "email", email)
 
 	// Query for user
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctxWithTimeout, cancel := r.queryContext()
 	defer cancel()
 	row := r.db.QueryRowContext(ctxWithTimeout, `
-		SELECT id, email, date_added, redeemed
+		SELECT `+userColumns+`
 		FROM users
-		WHERE email = $1
-	`, email)
+		WHERE LOWER(email) = $1
+	`, utils.NormalizeEmail(email))
 
 	// Parse result
-	var (
-		id          string
-		userEmail   string
-		dateAdded   time.Time
-		redeemedSQL sql.NullTime
-	)
-
-	err := row.Scan(&id, &userEmail, &dateAdded, &redeemedSQL)
+	user, err := scanUser(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			r.logger.Debug("User not found in PostgreSQL", "email", email)
@@ -97,19 +152,6 @@ func (r *PostgresRepository) FindByEmail(ctx any, email string) (*domain.User, e
 		return nil, err
 	}
 
-	// Create user
-	user := &domain.User{
-		ID:        id,
-		Email:     userEmail,
-		DateAdded: dateAdded,
-	}
-
-	// Handle redeemed
-	if redeemedSQL.Valid {
-		redeemed := redeemedSQL.Time
-		user.Redeemed = &redeemed
-	}
-
 	r.logger.Debug("Found user in PostgreSQL", "email", email, "redeemed", user.IsRedeemed())
 	return user, nil
 }
@@ -122,7 +164,7 @@ func (r *PostgresRepository) UpdateUser(ctx any, user *domain.User) error {
 	r.logger.Debug("Updating user in PostgreSQL", "email", user.Email)
 
 	// Prepare transaction
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctxWithTimeout, cancel := r.queryContext()
 	defer cancel()
 	tx, err := r.db.BeginTx(ctxWithTimeout, nil)
 	if err != nil {
@@ -135,28 +177,29 @@ func (r *PostgresRepository) UpdateUser(ctx any, user *domain.User) error {
 		}
 	}()
 
-	// Use upsert (INSERT ON CONFLICT UPDATE) for atomic operation
-	query := `
-		INSERT INTO users (id, email, date_added, redeemed)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (email)
-		DO UPDATE SET
-			id = EXCLUDED.id,
-			date_added = EXCLUDED.date_added,
-			redeemed = EXCLUDED.redeemed
-	`
-
-	var args []interface{}
-	if user.Redeemed != nil {
-		args = []interface{}{user.ID, user.Email, user.DateAdded, user.Redeemed}
-	} else {
-		args = []interface{}{user.ID, user.Email, user.DateAdded, nil}
-	}
-
-	_, err = tx.ExecContext(ctxWithTimeout, query, args...)
+	stampUser(user)
+	args := userArgs(user)
+
+	// Update the existing row, matching the email case-insensitively so rows
+	// stored before emails were normalized are still found
+	result, err := tx.ExecContext(ctxWithTimeout, `
+		UPDATE users
+		SET id = $1, email = $2, date_added = $3, redeemed = $4, marketing_consent = $5, updated_at = $6,
+			created_by = COALESCE(NULLIF($7, ''), created_by), bar = $8, notes = $9, tags = $10, first_name = $11, last_name = $12
+		WHERE LOWER(email) = $2
+	`, args...)
 	if err != nil {
-		r.logger.Error("Error upserting user", "error", err)
-		return fmt.Errorf("failed to upsert user: %w", err)
+		r.logger.Error("Error updating user", "error", err)
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	// Insert the user if it did not exist yet
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		query := `INSERT INTO users (` + userColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+		if _, err := tx.ExecContext(ctxWithTimeout, query, args...); err != nil {
+			r.logger.Error("Error inserting user", "error", err)
+			return fmt.Errorf("failed to insert user: %w", err)
+		}
 	}
 
 	// Commit transaction
@@ -178,33 +221,35 @@ func (r *PostgresRepository) AddUser(ctx any, user *domain.User) error {
 	r.logger.Debug("Adding user to PostgreSQL", "email", user.Email)
 
 	// Check if user already exists
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctxWithTimeout, cancel := r.queryContext()
 	defer cancel()
-	
-	var exists bool
-	err := r.db.QueryRowContext(ctxWithTimeout, "SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)", user.Email).Scan(&exists)
+
+	email := utils.NormalizeEmail(user.Email)
+	idQuery := "SELECT id FROM users WHERE LOWER(email) = $1 LIMIT 1"
+	id, err := storedUserID(ctxWithTimeout, r.db, idQuery, email)
 	if err != nil {
 		r.logger.Error("Error checking if user exists", "error", err)
 		return err
 	}
-	
-	if exists {
+
+	if id != "" {
 		r.logger.Debug("User already exists in PostgreSQL", "email", user.Email)
-		return errors.New("user already exists")
+		return domain.NewDuplicateUserError(email, id)
 	}
 
 	// Insert new user
-	query := `INSERT INTO users (id, email, date_added, redeemed) VALUES ($1, $2, $3, $4)`
-	
-	var args []interface{}
-	if user.Redeemed != nil {
-		args = []interface{}{user.ID, user.Email, user.DateAdded, user.Redeemed}
-	} else {
-		args = []interface{}{user.ID, user.Email, user.DateAdded, nil}
-	}
+	stampUser(user)
+	query := `INSERT INTO users (` + userColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	args := userArgs(user)
 	
 	_, err = r.db.ExecContext(ctxWithTimeout, query, args...)
 	if err != nil {
+		// A concurrent insert of the same email fails on the unique column
+		err = insertConflict(ctxWithTimeout, r.db, idQuery, email, err)
+		if domain.IsDuplicateUser(err) {
+			r.logger.Debug("User already exists in PostgreSQL", "email", user.Email)
+			return err
+		}
 		r.logger.Error("Error adding user", "error", err)
 		return fmt.Errorf("failed to add user: %w", err)
 	}
@@ -213,49 +258,32 @@ func (r *PostgresRepository) AddUser(ctx any, user *domain.User) error {
 	return nil
 }
 
+// AddUsers adds users in one transaction, or none of them if any fails
+func (r *PostgresRepository) AddUsers(ctx any, users []*domain.User) error {
+	ctxWithTimeout, cancel := r.bulkContext()
+	defer cancel()
+
+	if err := insertUsers(ctxWithTimeout, r.db, users, dollarPlaceholder); err != nil {
+		r.logger.Debug("Error adding users in batch", "count", len(users), "error", err)
+		return fmt.Errorf("failed to add users: %w", err)
+	}
+
+	r.logger.Debug("Users added to PostgreSQL", "count", len(users))
+	return nil
+}
+
 // GetReport retrieves users based on the report parameters
 func (r *PostgresRepository) GetReport(ctx any, params domain.ReportParams) ([]*domain.User, error) {
 	r.logger.Debug("Generating report from PostgreSQL", "type", params.Type, "from", params.From, "to", params.To)
 
-	var query string
-	var args []interface{}
-
 	// Build different queries based on report type
-	switch params.Type {
-	case domain.ReportTypeRedeemed:
-		// Only get users who have redeemed within the date range
-		query = `
-			SELECT id, email, date_added, redeemed 
-			FROM users 
-			WHERE date_added >= $1 AND date_added <= $2 
-			AND redeemed IS NOT NULL
-			ORDER BY date_added DESC
-		`
-		args = []interface{}{params.From, params.To}
-	case domain.ReportTypeAdded:
-		// Get users added within the date range
-		query = `
-			SELECT id, email, date_added, redeemed 
-			FROM users 
-			WHERE date_added >= $1 AND date_added <= $2
-			ORDER BY date_added DESC
-		`
-		args = []interface{}{params.From, params.To}
-	case domain.ReportTypeAll:
-		// Get all users
-		query = `
-			SELECT id, email, date_added, redeemed 
-			FROM users
-			WHERE date_added >= $1 AND date_added <= $2
-			ORDER BY date_added DESC
-		`
-		args = []interface{}{params.From, params.To}
-	default:
-		return nil, fmt.Errorf("invalid report type: %s", params.Type)
+	query, args, err := reportQuery(params, dollarPlaceholder)
+	if err != nil {
+		return nil, err
 	}
 
 	// Execute query with timeout
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctxWithTimeout, cancel := r.bulkContext()
 	defer cancel()
 
 	rows, err := r.db.QueryContext(ctxWithTimeout, query, args...)
@@ -268,31 +296,11 @@ func (r *PostgresRepository) GetReport(ctx any, params domain.ReportParams) ([]*
 	// Process results
 	var users []*domain.User
 	for rows.Next() {
-		var (
-			id            string
-			email         string
-			dateAdded     time.Time
-			redeemedTime  sql.NullTime
-		)
-
-		if err := rows.Scan(&id, &email, &dateAdded, &redeemedTime); err != nil {
+		user, err := scanUser(rows)
+		if err != nil {
 			r.logger.Error("Error scanning row", "error", err)
 			return nil, fmt.Errorf("error scanning row: %w", err)
 		}
-
-		// Create user object
-		user := &domain.User{
-			ID:        id,
-			Email:     email,
-			DateAdded: dateAdded,
-		}
-
-		// Handle redeemed time
-		if redeemedTime.Valid {
-			t := redeemedTime.Time
-			user.Redeemed = &t
-		}
-
 		users = append(users, user)
 	}
 
@@ -305,6 +313,43 @@ func (r *PostgresRepository) GetReport(ctx any, params domain.ReportParams) ([]*
 	return users, nil
 }
 
+// NormalizeEmails rewrites stored emails to lowercase without surrounding spaces
+func (r *PostgresRepository) NormalizeEmails(ctx any) (int, error) {
+	changed, err := normalizeEmailsSQL(r.db)
+	if err != nil {
+		r.logger.Error("Error normalizing emails", "error", err)
+		return 0, err
+	}
+
+	r.logger.Info("Emails normalized in PostgreSQL", "changed", changed)
+	return changed, nil
+}
+
+// Health checks that the database server can be reached
+func (r *PostgresRepository) Health(ctx any) error {
+	if err := r.db.Ping(); err != nil {
+		r.logger.Sampled("health_check").Error("PostgreSQL health check failed", "error", err)
+		return domain.ErrDatabaseUnavailable
+	}
+	return nil
+}
+
+// Stats returns record counts and server information
+func (r *PostgresRepository) Stats(ctx any) (domain.RepoStats, error) {
+	stats, err := sqlStats(r.db, dialectPostgres)
+	if err != nil {
+		r.logger.Error("Error collecting PostgreSQL stats", "error", err)
+		return stats, domain.ErrDatabaseUnavailable
+	}
+
+	var version string
+	if err := r.db.QueryRow("SHOW server_version").Scan(&version); err == nil {
+		stats.Details["server_version"] = version
+	}
+
+	return stats, nil
+}
+
 func (r *PostgresRepository) Close() error {
 	r.logger.Debug("Closing PostgreSQL repository")
 	if r.db != nil {
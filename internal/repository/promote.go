@@ -0,0 +1,423 @@
+package repository
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// ErrNotPromoted is returned by Demote when the CSV file was not promoted
+var ErrNotPromoted = errors.New("CSV file was not promoted to SQLite")
+
+// everyUserReport lists all guests, with the sandbox records of test
+// emails, so a promotion copies the whole file
+var everyUserReport = domain.ReportParams{
+	Type:         allUsersReport.Type,
+	From:         allUsersReport.From,
+	To:           allUsersReport.To,
+	ReportFilter: domain.ReportFilter{IncludeTest: true},
+}
+
+// PromotedPath returns the SQLite database a CSV file is promoted to, the
+// same path with a .db extension
+func PromotedPath(csvPath string) string {
+	return strings.TrimSuffix(csvPath, filepath.Ext(csvPath)) + ".db"
+}
+
+// BackupPath returns the name a CSV file is kept under once promoted, and
+// a SQLite database once demoted
+func BackupPath(path string) string {
+	return path + ".bak"
+}
+
+// promoted reports whether a CSV file was promoted: it was renamed and
+// the SQLite database next to it exists
+func promoted(csvPath string) bool {
+	_, csvErr := os.Stat(csvPath)
+	_, dbErr := os.Stat(PromotedPath(csvPath))
+	return csvPath != "" && errors.Is(csvErr, os.ErrNotExist) && dbErr == nil
+}
+
+// servedDatabase returns the type and connection string of the primary
+// database the bot serves, the SQLite database if the configured CSV file
+// was promoted
+func servedDatabase(cfg config.DatabaseConfig) (string, string) {
+	if strings.EqualFold(cfg.Type, "csv") && cfg.Promotion.Enabled && promoted(cfg.ConnectionString) {
+		return "sqlite", PromotedPath(cfg.ConnectionString)
+	}
+	return cfg.Type, cfg.ConnectionString
+}
+
+// PromotingRepository serves a CSV file until it holds more than maxRows
+// guests, then copies them into a SQLite database next to it and serves
+// that instead. The CSV file is renamed to its backup path, so the next
+// start opens the SQLite database, and Demote writes the guests back to
+// the CSV file.
+type PromotingRepository struct {
+	csvPath string
+	maxRows int
+	logger  *logger.Logger
+
+	mu       sync.RWMutex // Held for writing while the guests are copied
+	repo     domain.Repository
+	promoted bool
+	failed   bool // A promotion failed, the CSV file is served until restart
+	timeouts timeouts
+
+	rows atomic.Int64 // Guests in the CSV file
+}
+
+// NewPromotingRepository opens a CSV file for promotion, or the SQLite
+// database it was promoted to. A file already past maxRows guests is
+// promoted right away.
+func NewPromotingRepository(ctx any, csvPath string, maxRows int, logger *logger.Logger) (*PromotingRepository, error) {
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+	if maxRows <= 0 {
+		return nil, fmt.Errorf("promotion threshold must be positive, got %d", maxRows)
+	}
+	r := &PromotingRepository{csvPath: csvPath, maxRows: maxRows, logger: logger, timeouts: defaultTimeouts()}
+
+	dbPath := PromotedPath(csvPath)
+	if promoted(csvPath) {
+		sqlite, err := NewSQLiteRepository(dbPath, logger)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("CSV file was promoted to SQLite, serving the SQLite database", "csv", csvPath, "database", dbPath, "backup", BackupPath(csvPath))
+		r.repo, r.promoted = sqlite, true
+		return r, nil
+	}
+	if _, err := os.Stat(dbPath); err == nil {
+		return nil, fmt.Errorf("cannot promote %s: %s already exists, remove it or move the CSV file away and run cocktail-admin db demote", csvPath, dbPath)
+	}
+
+	csvRepo, err := NewCSVRepository(csvPath, logger)
+	if err != nil {
+		return nil, err
+	}
+	users, err := csvRepo.GetReport(ctx, everyUserReport)
+	if err != nil {
+		return nil, err
+	}
+	r.repo = csvRepo
+	r.rows.Store(int64(len(users)))
+	logger.Info("CSV promotion enabled", "rows", len(users), "max_rows", maxRows, "database", dbPath)
+
+	r.maybePromote(ctx)
+	return r, nil
+}
+
+// serve runs fn on the repository being served, holding the read lock so
+// the repository is not swapped while fn runs
+func (r *PromotingRepository) serve(fn func(repo domain.Repository) error) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return fn(r.repo)
+}
+
+// Promoted reports whether the SQLite database is served
+func (r *PromotingRepository) Promoted() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.promoted
+}
+
+// maybePromote promotes the CSV file once it holds more than maxRows
+// guests. A failed promotion is logged and the CSV file is served on.
+func (r *PromotingRepository) maybePromote(ctx any) {
+	if r.rows.Load() <= int64(r.maxRows) {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.promoted || r.failed {
+		return
+	}
+	if err := r.promote(ctx); err != nil {
+		r.failed = true
+		r.logger.Error("Failed to promote CSV file to SQLite, serving the CSV file until restart", "csv", r.csvPath, "error", err)
+	}
+}
+
+// promote copies the guests of the CSV file into a new SQLite database,
+// serves it and renames the CSV file to its backup path. It is called with
+// the write lock held, so no guest is added during the copy.
+func (r *PromotingRepository) promote(ctx any) error {
+	start := time.Now()
+	dbPath := PromotedPath(r.csvPath)
+	if _, err := os.Stat(dbPath); err == nil {
+		return fmt.Errorf("%s already exists", dbPath)
+	}
+
+	users, err := r.repo.GetReport(ctx, everyUserReport)
+	if err != nil {
+		return fmt.Errorf("failed to read CSV file: %w", err)
+	}
+	r.logger.Info("Promoting CSV file to SQLite", "csv", r.csvPath, "rows", len(users), "max_rows", r.maxRows, "database", dbPath)
+
+	sqlite, err := NewSQLiteRepository(dbPath, r.logger)
+	if err != nil {
+		os.Remove(dbPath)
+		return err
+	}
+	abort := func(err error) error {
+		sqlite.Close()
+		os.Remove(dbPath)
+		return err
+	}
+	if err := sqlite.(BatchAdder).AddUsers(ctx, users); err != nil {
+		return abort(fmt.Errorf("failed to copy guests: %w", err))
+	}
+	copied, err := sqlite.GetReport(ctx, everyUserReport)
+	if err != nil {
+		return abort(err)
+	}
+	if len(copied) != len(users) {
+		return abort(fmt.Errorf("copied %d of %d guests", len(copied), len(users)))
+	}
+	if err := os.Rename(r.csvPath, BackupPath(r.csvPath)); err != nil {
+		return abort(fmt.Errorf("failed to back up CSV file: %w", err))
+	}
+
+	if setter, ok := sqlite.(TimeoutSetter); ok {
+		setter.SetTimeouts(r.timeouts.timeout, r.timeouts.bulkTimeout)
+	}
+	r.repo.Close()
+	r.repo, r.promoted = sqlite, true
+	r.logger.Info("CSV file promoted to SQLite", "rows", len(users), "database", dbPath, "backup", BackupPath(r.csvPath), "duration", time.Since(start),
+		"revert", "stop the bot and run cocktail-admin db demote")
+	return nil
+}
+
+// FindByEmail looks up a guest in the repository being served
+func (r *PromotingRepository) FindByEmail(ctx any, email string) (*domain.User, error) {
+	var user *domain.User
+	err := r.serve(func(repo domain.Repository) (err error) {
+		user, err = repo.FindByEmail(ctx, email)
+		return err
+	})
+	return user, err
+}
+
+// UpdateUser updates a guest in the repository being served
+func (r *PromotingRepository) UpdateUser(ctx any, user *domain.User) error {
+	return r.serve(func(repo domain.Repository) error {
+		return repo.UpdateUser(ctx, user)
+	})
+}
+
+// AddUser adds a guest, and promotes the CSV file if it grew past the
+// threshold
+func (r *PromotingRepository) AddUser(ctx any, user *domain.User) error {
+	err := r.serve(func(repo domain.Repository) error {
+		return repo.AddUser(ctx, user)
+	})
+	if err != nil {
+		return err
+	}
+	r.rows.Add(1)
+	r.maybePromote(ctx)
+	return nil
+}
+
+// AddUsers adds guests in a batch if the repository being served supports
+// batches, and promotes the CSV file if it grew past the threshold
+func (r *PromotingRepository) AddUsers(ctx any, users []*domain.User) error {
+	err := r.serve(func(repo domain.Repository) error {
+		adder, ok := repo.(BatchAdder)
+		if !ok {
+			return ErrBatchUnsupported
+		}
+		return adder.AddUsers(ctx, users)
+	})
+	if err != nil {
+		return err
+	}
+	r.rows.Add(int64(len(users)))
+	r.maybePromote(ctx)
+	return nil
+}
+
+// GetReport reports from the repository being served
+func (r *PromotingRepository) GetReport(ctx any, params domain.ReportParams) ([]*domain.User, error) {
+	var users []*domain.User
+	err := r.serve(func(repo domain.Repository) (err error) {
+		users, err = repo.GetReport(ctx, params)
+		return err
+	})
+	return users, err
+}
+
+// CountReport counts the users of a report in the repository being served
+func (r *PromotingRepository) CountReport(ctx any, params domain.ReportParams) (int, error) {
+	var count int
+	err := r.serve(func(repo domain.Repository) (err error) {
+		count, err = CountReport(ctx, repo, params)
+		return err
+	})
+	return count, err
+}
+
+// SearchUsers searches the repository being served
+func (r *PromotingRepository) SearchUsers(ctx any, params domain.SearchParams) (domain.SearchResult, error) {
+	var result domain.SearchResult
+	err := r.serve(func(repo domain.Repository) (err error) {
+		result, err = SearchUsers(ctx, repo, params)
+		return err
+	})
+	return result, err
+}
+
+// NormalizeEmails normalizes stored emails in the repository being served
+func (r *PromotingRepository) NormalizeEmails(ctx any) (int, error) {
+	var changed int
+	err := r.serve(func(repo domain.Repository) (err error) {
+		normalizer, ok := repo.(EmailNormalizer)
+		if !ok {
+			return errors.New("repository does not support email normalization")
+		}
+		changed, err = normalizer.NormalizeEmails(ctx)
+		return err
+	})
+	return changed, err
+}
+
+// ChangeEmail corrects an email in the repository being served
+func (r *PromotingRepository) ChangeEmail(ctx any, oldEmail, newEmail string) error {
+	return r.serve(func(repo domain.Repository) error {
+		changer, ok := repo.(EmailChanger)
+		if !ok {
+			return errors.New("repository does not support changing emails")
+		}
+		return changer.ChangeEmail(ctx, oldEmail, newEmail)
+	})
+}
+
+// SetTimeouts sets the timeouts of the repository being served, and of the
+// SQLite database once promoted
+func (r *PromotingRepository) SetTimeouts(timeout, bulkTimeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timeouts.SetTimeouts(timeout, bulkTimeout)
+	if setter, ok := r.repo.(TimeoutSetter); ok {
+		setter.SetTimeouts(timeout, bulkTimeout)
+	}
+}
+
+// Health checks the repository being served
+func (r *PromotingRepository) Health(ctx any) error {
+	return r.serve(func(repo domain.Repository) error {
+		return repo.Health(ctx)
+	})
+}
+
+// Stats returns statistics of the repository being served, with the
+// promotion state
+func (r *PromotingRepository) Stats(ctx any) (domain.RepoStats, error) {
+	var stats domain.RepoStats
+	err := r.serve(func(repo domain.Repository) (err error) {
+		stats, err = repo.Stats(ctx)
+		return err
+	})
+	if err != nil {
+		return stats, err
+	}
+	if stats.Details == nil {
+		stats.Details = make(map[string]string)
+	}
+
+	stats.Details["promotion_max_rows"] = fmt.Sprint(r.maxRows)
+	if r.Promoted() {
+		stats.Details["promoted_from"] = r.csvPath
+		stats.Details["csv_backup"] = BackupPath(r.csvPath)
+	}
+	return stats, nil
+}
+
+// Close closes the repository being served
+func (r *PromotingRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.repo.Close()
+}
+
+// Demote reverses the promotion of the configured CSV file: it writes the
+// guests of the SQLite database, including those added since the
+// promotion, back to the CSV file and renames the database to its backup
+// path. The bot must be stopped, since it would keep serving the database.
+func Demote(ctx any, cfg config.DatabaseConfig, logger *logger.Logger) (int, error) {
+	if !strings.EqualFold(cfg.Type, "csv") {
+		return 0, fmt.Errorf("only CSV databases are promoted, not %s", cfg.Type)
+	}
+	csvPath := cfg.ConnectionString
+	dbPath := PromotedPath(csvPath)
+	if _, err := os.Stat(dbPath); errors.Is(err, os.ErrNotExist) {
+		return 0, ErrNotPromoted
+	}
+	if _, err := os.Stat(csvPath); err == nil {
+		return 0, fmt.Errorf("%s already exists, move it away to demote %s", csvPath, dbPath)
+	}
+
+	sqlite, err := NewSQLiteRepository(dbPath, logger)
+	if err != nil {
+		return 0, err
+	}
+	users, err := sqlite.GetReport(ctx, everyUserReport)
+	sqlite.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	records := make([][]string, 0, len(users)+1)
+	records = append(records, csvHeader)
+	for _, user := range users {
+		records = append(records, csvRecord(user))
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(csvPath), filepath.Base(csvPath)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := csv.NewWriter(tmp)
+	if err := writer.WriteAll(records); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), csvPath); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(dbPath, BackupPath(dbPath)); err != nil {
+		os.Remove(csvPath)
+		return 0, fmt.Errorf("failed to back up SQLite database: %w", err)
+	}
+
+	logger.Info("SQLite database demoted to CSV", "database", dbPath, "csv", csvPath, "rows", len(users), "backup", BackupPath(dbPath))
+	return len(users), nil
+}
+
+// Ensure the promoting repository passes optional operations through
+var (
+	_ BatchAdder      = (*PromotingRepository)(nil)
+	_ ReportCounter   = (*PromotingRepository)(nil)
+	_ UserSearcher    = (*PromotingRepository)(nil)
+	_ EmailNormalizer = (*PromotingRepository)(nil)
+	_ EmailChanger    = (*PromotingRepository)(nil)
+	_ TimeoutSetter   = (*PromotingRepository)(nil)
+)
@@ -0,0 +1,86 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+)
+
+func TestPromotingRepository(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New("error")
+	path := filepath.Join(t.TempDir(), "users.csv")
+
+	repo, err := repository.NewPromotingRepository(ctx, path, 3, log)
+	if err != nil {
+		t.Fatalf("Failed to create promoting repository: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		user := &domain.User{ID: fmt.Sprint(i), Email: fmt.Sprintf("user%d@example.com", i), DateAdded: time.Now(), Tags: []string{"vip"}}
+		if err := repo.AddUser(ctx, user); err != nil {
+			t.Fatalf("Failed to add user: %v", err)
+		}
+	}
+	if repo.Promoted() {
+		t.Fatal("Expected the CSV file to be served up to the threshold")
+	}
+
+	if err := repo.AddUser(ctx, &domain.User{ID: "4", Email: "test@example.com", DateAdded: time.Now()}); err != nil {
+		t.Fatalf("Failed to add user: %v", err)
+	}
+	if !repo.Promoted() {
+		t.Fatal("Expected the CSV file to be promoted past the threshold")
+	}
+	if _, err := os.Stat(repository.BackupPath(path)); err != nil {
+		t.Errorf("Expected the CSV file to be kept as a backup: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the CSV file to be renamed, got %v", err)
+	}
+	user, err := repo.FindByEmail(ctx, "user2@example.com")
+	if err != nil || len(user.Tags) != 1 {
+		t.Errorf("Expected copied guest with its tags, got %+v, %v", user, err)
+	}
+	if _, err := repo.FindByEmail(ctx, "test@example.com"); err != nil {
+		t.Errorf("Expected test email to be copied: %v", err)
+	}
+	if err := repo.AddUser(ctx, &domain.User{ID: "5", Email: "late@example.com", DateAdded: time.Now()}); err != nil {
+		t.Fatalf("Failed to add user after promotion: %v", err)
+	}
+	repo.Close()
+
+	// The next start serves the SQLite database
+	cfg := config.DatabaseConfig{Type: "csv", ConnectionString: path, Promotion: config.PromotionConfig{Enabled: true, MaxRows: 3}}
+	reopened, err := repository.New(ctx, cfg, log)
+	if err != nil {
+		t.Fatalf("Failed to reopen: %v", err)
+	}
+	if stats, err := reopened.Stats(ctx); err != nil || stats.Backend != "sqlite" || stats.Details["promoted_from"] != path {
+		t.Errorf("Expected the promoted database, got %+v, %v", stats, err)
+	}
+	reopened.Close()
+
+	// Demoting writes every guest back, including those added since
+	rows, err := repository.Demote(ctx, cfg, log)
+	if err != nil || rows != 5 {
+		t.Fatalf("Expected 5 guests demoted, got %d, %v", rows, err)
+	}
+	if _, err := repository.Demote(ctx, cfg, log); err != repository.ErrNotPromoted {
+		t.Errorf("Expected a second demotion to be refused, got %v", err)
+	}
+	csvRepo, err := repository.NewCSVRepository(path, log)
+	if err != nil {
+		t.Fatalf("Failed to open demoted CSV file: %v", err)
+	}
+	if user, err := csvRepo.FindByEmail(ctx, "late@example.com"); err != nil || user.ID != "5" {
+		t.Errorf("Expected guest added after promotion in the CSV file, got %+v, %v", user, err)
+	}
+}
@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/api/sheets/v4"
+)
+
+// EmailChanger is implemented by repositories that can correct the email
+// of a stored user. Users are found by email, so UpdateUser cannot change
+// it.
+type EmailChanger interface {
+	// ChangeEmail moves the user stored with oldEmail to newEmail, keeping
+	// its ID and other fields. It returns a DuplicateUserError if newEmail
+	// is already stored, and ErrUserNotFound if oldEmail is not.
+	ChangeEmail(ctx any, oldEmail, newEmail string) error
+}
+
+// changeEmailSQL renames a user in a SQL users table. It is shared by the
+// SQLite, PostgreSQL and MySQL repositories.
+func changeEmailSQL(ctx context.Context, db *sql.DB, ph placeholder, oldEmail, newEmail string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id string
+	err = tx.QueryRowContext(ctx, "SELECT id FROM users WHERE LOWER(email) = "+ph(1), newEmail).Scan(&id)
+	if err == nil {
+		return domain.NewDuplicateUserError(newEmail, id)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, "UPDATE users SET email = "+ph(1)+" WHERE LOWER(email) = "+ph(2), newEmail, oldEmail)
+	if err != nil {
+		return err
+	}
+	if changed, err := result.RowsAffected(); err == nil && changed == 0 {
+		return domain.ErrUserNotFound
+	}
+	return tx.Commit()
+}
+
+// ChangeEmail corrects the email of a user
+func (r *SQLiteRepository) ChangeEmail(ctx any, oldEmail, newEmail string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := changeEmailSQL(context.Background(), r.db, questionPlaceholder, utils.NormalizeEmail(oldEmail), utils.NormalizeEmail(newEmail))
+	if err != nil && !domain.IsDuplicateUser(err) && !errors.Is(err, domain.ErrUserNotFound) {
+		r.logger.Error("Error changing email", "email", oldEmail, "error", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+	return err
+}
+
+// ChangeEmail corrects the email of a user
+func (r *PostgresRepository) ChangeEmail(ctx any, oldEmail, newEmail string) error {
+	ctxWithTimeout, cancel := r.queryContext()
+	defer cancel()
+
+	err := changeEmailSQL(ctxWithTimeout, r.db, dollarPlaceholder, utils.NormalizeEmail(oldEmail), utils.NormalizeEmail(newEmail))
+	if err != nil && !domain.IsDuplicateUser(err) && !errors.Is(err, domain.ErrUserNotFound) {
+		r.logger.Error("Error changing email", "email", oldEmail, "error", err)
+		return fmt.Errorf("failed to change email: %w", err)
+	}
+	return err
+}
+
+// ChangeEmail corrects the email of a user
+func (r *MySQLRepository) ChangeEmail(ctx any, oldEmail, newEmail string) error {
+	ctxWithTimeout, cancel := r.queryContext()
+	defer cancel()
+
+	err := changeEmailSQL(ctxWithTimeout, r.db, questionPlaceholder, utils.NormalizeEmail(oldEmail), utils.NormalizeEmail(newEmail))
+	if err != nil && !domain.IsDuplicateUser(err) && !errors.Is(err, domain.ErrUserNotFound) {
+		r.logger.Error("Error changing email", "email", oldEmail, "error", err)
+		return fmt.Errorf("failed to change email: %w", err)
+	}
+	return err
+}
+
+// ChangeEmail corrects the email of a user
+func (r *CSVRepository) ChangeEmail(ctx any, oldEmail, newEmail string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, err := os.Open(r.filePath)
+	if err != nil {
+		r.logger.Error("Failed to open CSV file for email change", "error", err)
+		return domain.ErrDatabaseUnavailable
+	}
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	file.Close()
+	if err != nil {
+		r.logger.Error("Failed to read CSV records", "error", err)
+		return err
+	}
+
+	found := -1
+	for i, record := range records {
+		if i == 0 || len(record) < 2 {
+			continue
+		}
+		if sameEmail(record[1], newEmail) {
+			return domain.NewDuplicateUserError(utils.NormalizeEmail(newEmail), record[0])
+		}
+		if found < 0 && sameEmail(record[1], oldEmail) {
+			found = i
+		}
+	}
+	if found < 0 {
+		return domain.ErrUserNotFound
+	}
+	records[found][1] = utils.NormalizeEmail(newEmail)
+
+	outFile, err := os.Create(r.filePath)
+	if err != nil {
+		r.logger.Error("Failed to open CSV file for writing", "error", err)
+		return err
+	}
+	defer outFile.Close()
+
+	writer := csv.NewWriter(outFile)
+	if err := writer.WriteAll(records); err != nil {
+		r.logger.Error("Failed to write CSV records", "error", err)
+		return err
+	}
+	return nil
+}
+
+// ChangeEmail corrects the email of a user
+func (r *MongoDBRepository) ChangeEmail(ctx any, oldEmail, newEmail string) error {
+	newEmail = utils.NormalizeEmail(newEmail)
+	id, err := r.storedUserID(newEmail)
+	if err != nil {
+		r.logger.Error("Error checking if user exists", "error", err)
+		return err
+	}
+	if id != "" {
+		return domain.NewDuplicateUserError(newEmail, id)
+	}
+
+	ctxWithTimeout, cancel := r.queryContext()
+	defer cancel()
+
+	opts := options.Update().SetCollation(emailCollation)
+	result, err := r.collection.UpdateOne(ctxWithTimeout, bson.M{"email": utils.NormalizeEmail(oldEmail)}, bson.M{"$set": bson.M{"email": newEmail}}, opts)
+	if err != nil {
+		r.logger.Error("Error changing email in MongoDB", "error", err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// ChangeEmail corrects the email of a user, writing only the email cell
+func (r *GoogleSheetRepository) ChangeEmail(ctx any, oldEmail, newEmail string) error {
+	r.addMu.Lock()
+	defer r.addMu.Unlock()
+
+	cols, rows, err := r.readSheet()
+	if err != nil {
+		r.logger.Error("Failed to read Google Sheet for email change", "error", err)
+		return domain.ErrDatabaseUnavailable
+	}
+	if i := findRow(cols, rows, newEmail); i > 0 {
+		return domain.NewDuplicateUserError(utils.NormalizeEmail(newEmail), cols.cell(rows[i], "ID"))
+	}
+	i := findRow(cols, rows, oldEmail)
+	if i <= 0 {
+		return domain.ErrUserNotFound
+	}
+
+	cell := fmt.Sprintf("%s!%s%d", r.sheetName, cols.column("Email"), i+1)
+	_, err = r.service.Spreadsheets.Values.Update(r.spreadsheetID, cell,
+		&sheets.ValueRange{Values: [][]interface{}{{utils.NormalizeEmail(newEmail)}}}).
+		ValueInputOption("RAW").Context(context.Background()).Do()
+	if err != nil {
+		r.logger.Error("Failed to update Google Sheet", "error", err)
+		return err
+	}
+	return nil
+}
+
+// ChangeEmail corrects the email in the repository and moves the cached
+// guest to its new key
+func (r *CachedRepository) ChangeEmail(ctx any, oldEmail, newEmail string) error {
+	changer, ok := r.Repository.(EmailChanger)
+	if !ok {
+		return errors.New("repository does not support changing emails")
+	}
+	if err := changer.ChangeEmail(ctx, oldEmail, newEmail); err != nil {
+		return err
+	}
+	r.forget(oldEmail)
+	r.forget(newEmail)
+	return nil
+}
+
+// ChangeEmail adds the new email to the filter and corrects it in the
+// repository. The old email stays in the filter until it is rebuilt.
+func (r *BloomRepository) ChangeEmail(ctx any, oldEmail, newEmail string) error {
+	changer, ok := r.Repository.(EmailChanger)
+	if !ok {
+		return errors.New("repository does not support changing emails")
+	}
+	r.remember(newEmail)
+	return changer.ChangeEmail(ctx, oldEmail, newEmail)
+}
+
+// ChangeEmail corrects the email in the repository and forgets both emails
+func (r *NegativeCacheRepository) ChangeEmail(ctx any, oldEmail, newEmail string) error {
+	changer, ok := r.Repository.(EmailChanger)
+	if !ok {
+		return errors.New("repository does not support changing emails")
+	}
+	defer r.forget(oldEmail, newEmail)
+	return changer.ChangeEmail(ctx, oldEmail, newEmail)
+}
+
+// ChangeEmail corrects the email in the primary. The fallback is
+// read-only, so emails cannot be changed while the primary is down.
+func (r *FailoverRepository) ChangeEmail(ctx any, oldEmail, newEmail string) error {
+	if !r.usePrimary() {
+		return domain.ErrDatabaseUnavailable
+	}
+	changer, ok := r.primary.(EmailChanger)
+	if !ok {
+		return errors.New("primary repository does not support changing emails")
+	}
+	r.flushSpool(ctx)
+	return changer.ChangeEmail(ctx, oldEmail, newEmail)
+}
+
+// Ensure all repositories support email changes
+var (
+	_ EmailChanger = (*CSVRepository)(nil)
+	_ EmailChanger = (*SQLiteRepository)(nil)
+	_ EmailChanger = (*PostgresRepository)(nil)
+	_ EmailChanger = (*MySQLRepository)(nil)
+	_ EmailChanger = (*MongoDBRepository)(nil)
+	_ EmailChanger = (*GoogleSheetRepository)(nil)
+	_ EmailChanger = (*CachedRepository)(nil)
+	_ EmailChanger = (*BloomRepository)(nil)
+	_ EmailChanger = (*FailoverRepository)(nil)
+	_ EmailChanger = (*NegativeCacheRepository)(nil)
+)
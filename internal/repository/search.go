@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// UserSearcher is implemented by repositories that can select the guests
+// matching a search with a query instead of loading every guest
+type UserSearcher interface {
+	SearchUsers(ctx any, params domain.SearchParams) (domain.SearchResult, error)
+}
+
+// SearchUsers returns a page of the guests matching a search, best match
+// first. Repositories that cannot search are searched in memory, from a
+// report of all guests.
+func SearchUsers(ctx any, repo domain.Repository, params domain.SearchParams) (domain.SearchResult, error) {
+	if searcher, ok := repo.(UserSearcher); ok {
+		return searcher.SearchUsers(ctx, params)
+	}
+	users, err := repo.GetReport(ctx, allUsersReport)
+	if err != nil {
+		return domain.SearchResult{}, err
+	}
+	return domain.RankSearch(users, params), nil
+}
+
+// searchDocument returns the expression joining the searched columns of a
+// dialect in lowercase. PostgreSQL indexes it with trigrams, so it must
+// stay the same as in the index.
+func searchDocument(dialect string) string {
+	if dialect == dialectMySQL {
+		return `LOWER(CONCAT_WS(' ', email, first_name, last_name, notes, tags))`
+	}
+	return `LOWER(email || ' ' || COALESCE(first_name, '') || ' ' || COALESCE(last_name, '') || ' ' || COALESCE(notes, '') || ' ' || COALESCE(tags, ''))`
+}
+
+// createTrigramIndex installs pg_trgm and indexes the search document of
+// PostgreSQL with it
+func createTrigramIndex(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm`); err != nil {
+		return err
+	}
+	_, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_users_search ON users USING GIN ((` + searchDocument(dialectPostgres) + `) gin_trgm_ops)`)
+	return err
+}
+
+// escapeLike escapes the LIKE wildcards of a term with !, such as the _
+// found in many emails
+func escapeLike(term string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(term)
+}
+
+// searchSQL selects the guests whose columns contain every term and ranks
+// them. It is shared by the SQLite, PostgreSQL and MySQL repositories.
+func searchSQL(ctx context.Context, db *sql.DB, params domain.SearchParams, dialect string, ph placeholder) (domain.SearchResult, error) {
+	if len(params.Terms) == 0 {
+		return domain.SearchResult{}, nil
+	}
+
+	document := searchDocument(dialect)
+	conditions := make([]string, len(params.Terms))
+	args := make([]any, len(params.Terms))
+	for i, term := range params.Terms {
+		conditions[i] = document + ` LIKE ` + ph(i+1) + ` ESCAPE '!'`
+		args[i] = "%" + escapeLike(term) + "%"
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT `+userColumns+` FROM users WHERE `+strings.Join(conditions, ` AND `), args...)
+	if err != nil {
+		return domain.SearchResult{}, err
+	}
+	defer rows.Close()
+
+	var users []*domain.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return domain.SearchResult{}, fmt.Errorf("error scanning row: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return domain.SearchResult{}, err
+	}
+	return domain.RankSearch(users, params), nil
+}
+
+// SearchUsers selects the guests containing the terms with a query
+func (r *SQLiteRepository) SearchUsers(ctx any, params domain.SearchParams) (domain.SearchResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result, err := searchSQL(context.Background(), r.db, params, dialectSQLite, questionPlaceholder)
+	if err != nil {
+		r.logger.Error("Error searching users", "error", err)
+		return domain.SearchResult{}, fmt.Errorf("database error: %w", err)
+	}
+	return result, nil
+}
+
+// SearchUsers selects the guests containing the terms with a query, which
+// uses the trigram index where pg_trgm is installed
+func (r *PostgresRepository) SearchUsers(ctx any, params domain.SearchParams) (domain.SearchResult, error) {
+	ctxWithTimeout, cancel := r.bulkContext()
+	defer cancel()
+
+	result, err := searchSQL(ctxWithTimeout, r.db, params, dialectPostgres, dollarPlaceholder)
+	if err != nil {
+		r.logger.Error("Failed to search users", "error", err)
+		return domain.SearchResult{}, fmt.Errorf("database error: %w", err)
+	}
+	return result, nil
+}
+
+// SearchUsers selects the guests containing the terms with a query
+func (r *MySQLRepository) SearchUsers(ctx any, params domain.SearchParams) (domain.SearchResult, error) {
+	ctxWithTimeout, cancel := r.bulkContext()
+	defer cancel()
+
+	result, err := searchSQL(ctxWithTimeout, r.db, params, dialectMySQL, questionPlaceholder)
+	if err != nil {
+		r.logger.Error("Failed to search users", "error", err)
+		return domain.SearchResult{}, fmt.Errorf("database error: %w", err)
+	}
+	return result, nil
+}
+
+// SearchUsers searches the repository
+func (r *CachedRepository) SearchUsers(ctx any, params domain.SearchParams) (domain.SearchResult, error) {
+	return SearchUsers(ctx, r.Repository, params)
+}
+
+// SearchUsers searches the repository
+func (r *BloomRepository) SearchUsers(ctx any, params domain.SearchParams) (domain.SearchResult, error) {
+	return SearchUsers(ctx, r.Repository, params)
+}
+
+// SearchUsers searches the repository
+func (r *NegativeCacheRepository) SearchUsers(ctx any, params domain.SearchParams) (domain.SearchResult, error) {
+	return SearchUsers(ctx, r.Repository, params)
+}
+
+// SearchUsers searches the primary, or the fallback if the primary is down.
+// The fallback is searched in memory so spooled updates are found.
+func (r *FailoverRepository) SearchUsers(ctx any, params domain.SearchParams) (domain.SearchResult, error) {
+	if r.usePrimary() {
+		result, err := SearchUsers(ctx, r.primary, params)
+		if !primaryFailed(err) {
+			r.markRecovered()
+			return result, err
+		}
+		r.markFailed("search", err)
+	}
+
+	users, err := r.fallbackReport(ctx, allUsersReport)
+	if err != nil {
+		return domain.SearchResult{}, err
+	}
+	return domain.RankSearch(users, params), nil
+}
+
+// Ensure the SQL repositories search with queries, and the wrappers pass
+// searches through
+var (
+	_ UserSearcher = (*SQLiteRepository)(nil)
+	_ UserSearcher = (*PostgresRepository)(nil)
+	_ UserSearcher = (*MySQLRepository)(nil)
+	_ UserSearcher = (*CachedRepository)(nil)
+	_ UserSearcher = (*BloomRepository)(nil)
+	_ UserSearcher = (*FailoverRepository)(nil)
+	_ UserSearcher = (*NegativeCacheRepository)(nil)
+)
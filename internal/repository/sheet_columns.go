@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// sheetHeaderAliases maps normalized header names to the column names of
+// csvHeader, which sheets share. Headers are compared ignoring case, spaces
+// and punctuation, so "Date Added" and "date_added" both match DateAdded.
+var sheetHeaderAliases = map[string]string{
+	"id":               "ID",
+	"email":            "Email",
+	"emailaddress":     "Email",
+	"dateadded":        "DateAdded",
+	"added":            "DateAdded",
+	"redeemed":         "Redeemed",
+	"marketingconsent": "MarketingConsent",
+	"consent":          "MarketingConsent",
+	"updatedat":        "UpdatedAt",
+	"createdby":        "CreatedBy",
+	"bar":              "Bar",
+	"venue":            "Bar",
+	"redeemedat":       "Bar", // Like User.RedeemedAt, the bar that served the drink
+	"notes":            "Notes",
+	"note":             "Notes",
+	"tags":             "Tags",
+	"firstname":        "FirstName",
+	"givenname":        "FirstName",
+	"lastname":         "LastName",
+	"surname":          "LastName",
+	"familyname":       "LastName",
+}
+
+// sheetColumns maps user fields to the columns of a sheet, read from its
+// header row. Columns may be in any order, and columns the bot does not
+// know are left alone.
+type sheetColumns struct {
+	header []string       // Header row as stored in the sheet
+	index  map[string]int // Column of each known field, 0-based
+}
+
+// parseSheetHeader maps the columns named in a header row. Sheets whose
+// header names no Email column are assumed to use the default layout.
+func parseSheetHeader(row []interface{}) *sheetColumns {
+	cols := &sheetColumns{index: make(map[string]int)}
+	for i, value := range row {
+		name, _ := value.(string)
+		cols.header = append(cols.header, name)
+		if field, ok := sheetHeaderAliases[normalizeHeader(name)]; ok {
+			if _, seen := cols.index[field]; !seen {
+				cols.index[field] = i
+			}
+		}
+	}
+
+	if _, ok := cols.index["Email"]; !ok {
+		cols.index = make(map[string]int)
+		for i, field := range csvHeader {
+			cols.index[field] = i
+			if i >= len(cols.header) {
+				cols.header = append(cols.header, field)
+			}
+		}
+	}
+	return cols
+}
+
+// normalizeHeader lowercases a header name and drops everything but letters and digits
+func normalizeHeader(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// ensure adds columns for fields missing from the header after the last
+// column and reports whether the header changed
+func (c *sheetColumns) ensure(fields ...string) bool {
+	changed := false
+	for _, field := range fields {
+		if _, ok := c.index[field]; !ok {
+			c.index[field] = len(c.header)
+			c.header = append(c.header, field)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// headerValues returns the header row for writing
+func (c *sheetColumns) headerValues() []interface{} {
+	values := make([]interface{}, len(c.header))
+	for i, name := range c.header {
+		values[i] = name
+	}
+	return values
+}
+
+// lastColumn returns the letter of the last column of the header
+func (c *sheetColumns) lastColumn() string {
+	return columnLetter(len(c.header) - 1)
+}
+
+// column returns the letter of the column holding a field
+func (c *sheetColumns) column(field string) string {
+	return columnLetter(c.index[field])
+}
+
+// cell returns the value of a field in a row, empty if the row or the
+// header lacks it
+func (c *sheetColumns) cell(row []interface{}, field string) string {
+	i, ok := c.index[field]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	value, _ := row[i].(string)
+	return value
+}
+
+// set writes the value of a field into a row, extending the row to the
+// width of the header
+func (c *sheetColumns) set(row []interface{}, field, value string) []interface{} {
+	for len(row) < len(c.header) {
+		row = append(row, "")
+	}
+	row[c.index[field]] = value
+	return row
+}
+
+// user reads the user stored in a row
+func (c *sheetColumns) user(row []interface{}) *domain.User {
+	user := &domain.User{
+		ID:         c.cell(row, "ID"),
+		Email:      c.cell(row, "Email"),
+		CreatedBy:  c.cell(row, "CreatedBy"),
+		RedeemedAt: c.cell(row, "Bar"),
+		Notes:      c.cell(row, "Notes"),
+		Tags:       domain.SplitTags(c.cell(row, "Tags")),
+		FirstName:  c.cell(row, "FirstName"),
+		LastName:   c.cell(row, "LastName"),
+	}
+	if dateAdded, err := time.Parse(time.RFC3339, c.cell(row, "DateAdded")); err == nil {
+		user.DateAdded = dateAdded
+	}
+	if redeemed, err := time.Parse(time.RFC3339, c.cell(row, "Redeemed")); err == nil {
+		user.Redeemed = &redeemed
+	}
+	if consent, err := time.Parse(time.RFC3339, c.cell(row, "MarketingConsent")); err == nil {
+		user.MarketingConsent = &consent
+	}
+
+	// Rows written by older versions fall back to the latest timestamp of the user
+	if updatedAt, err := time.Parse(time.RFC3339Nano, c.cell(row, "UpdatedAt")); err == nil {
+		user.UpdatedAt = updatedAt
+	} else {
+		user.UpdatedAt = user.LastChange()
+	}
+	return user
+}
+
+// setUser writes the fields of a user into a row. The creator is only
+// written to new rows, and columns the bot does not know keep their values.
+func (c *sheetColumns) setUser(row []interface{}, user *domain.User, created bool) []interface{} {
+	row = c.set(row, "ID", user.ID)
+	row = c.set(row, "Email", utils.NormalizeEmail(user.Email))
+	row = c.set(row, "DateAdded", user.DateAdded.Format(time.RFC3339))
+	row = c.set(row, "Redeemed", formatCSVTime(user.Redeemed))
+	row = c.set(row, "MarketingConsent", formatCSVTime(user.MarketingConsent))
+	row = c.set(row, "UpdatedAt", user.UpdatedAt.Format(time.RFC3339Nano))
+	row = c.set(row, "Bar", user.RedeemedAt)
+	row = c.set(row, "Notes", user.Notes)
+	row = c.set(row, "Tags", domain.JoinTags(user.Tags))
+	row = c.set(row, "FirstName", user.FirstName)
+	row = c.set(row, "LastName", user.LastName)
+	if created {
+		row = c.set(row, "CreatedBy", user.CreatedBy)
+	}
+	return row
+}
+
+// columnLetter converts a 0-based column index to its A1 notation letters
+func columnLetter(i int) string {
+	letters := ""
+	for i >= 0 {
+		letters = string(rune('A'+i%26)) + letters
+		i = i/26 - 1
+	}
+	return letters
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+func TestSheetColumns(t *testing.T) {
+	// A user-arranged sheet with reordered columns and a column of its own
+	header := []interface{}{"Email Address", "Notes", "Date Added", "id", "Redeemed"}
+	row := []interface{}{"Guest@Example.com", "VIP", "2025-06-01T18:00:00Z", "42", ""}
+
+	cols := parseSheetHeader(header)
+	user := cols.user(row)
+	if user.ID != "42" || user.Email != "Guest@Example.com" || user.Redeemed != nil {
+		t.Fatalf("Unexpected user %+v", user)
+	}
+	if !user.DateAdded.Equal(time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected date added %v", user.DateAdded)
+	}
+
+	// Missing fields are added after the last column
+	if !cols.ensure(csvHeader...) {
+		t.Fatal("Expected missing columns to be added")
+	}
+	if cols.ensure(csvHeader...) {
+		t.Error("Expected no change once all columns exist")
+	}
+	if got := cols.header[len(cols.header)-1]; got != "LastName" || cols.lastColumn() != "L" {
+		t.Errorf("Unexpected header %v", cols.header)
+	}
+
+	// An existing Notes column holds the notes of the guest
+	if user.Notes != "VIP" {
+		t.Errorf("Expected the notes column to be read, got %q", user.Notes)
+	}
+
+	// Writing keeps the cells of unknown columns and the creator of existing rows
+	redeemed := time.Date(2025, 6, 1, 20, 0, 0, 0, time.UTC)
+	user.Redeemed = &redeemed
+	user.CreatedBy = "token:abcd"
+	user.RedeemedAt = "Rooftop"
+	user.Tags = []string{"vip", "press"}
+	user.FirstName, user.LastName = "Anna", "Karenina"
+	updated := cols.setUser(row, user, false)
+	if len(updated) != 12 || updated[1] != "VIP" || updated[0] != "guest@example.com" || updated[7] != "" || updated[8] != "Rooftop" || updated[9] != "vip,press" || updated[10] != "Anna" || updated[11] != "Karenina" {
+		t.Errorf("Unexpected row %v", updated)
+	}
+	if reread := cols.user(updated); reread.Redeemed == nil || !reread.Redeemed.Equal(redeemed) || reread.RedeemedAt != "Rooftop" || len(reread.Tags) != 2 || reread.FullName() != "Anna Karenina" {
+		t.Errorf("Expected the redemption to be written, got %+v", reread)
+	}
+	if created := cols.setUser(nil, user, true); created[7] != "token:abcd" {
+		t.Errorf("Expected the creator on new rows, got %v", created)
+	}
+}
+
+func TestSheetColumns_RedeemedAt(t *testing.T) {
+	// Redeemed At names the bar, as User.RedeemedAt does, not the time
+	cols := parseSheetHeader([]interface{}{"Email", "Redeemed", "Redeemed At"})
+	user := cols.user([]interface{}{"guest@example.com", "2025-06-01T20:00:00Z", "Rooftop"})
+	if user.Redeemed == nil || !user.Redeemed.Equal(time.Date(2025, 6, 1, 20, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the redemption time from Redeemed, got %v", user.Redeemed)
+	}
+	if user.RedeemedAt != "Rooftop" {
+		t.Errorf("Expected the bar from Redeemed At, got %q", user.RedeemedAt)
+	}
+}
+
+func TestSheetColumns_DefaultLayout(t *testing.T) {
+	// Sheets without a recognizable header use the original column order
+	cols := parseSheetHeader([]interface{}{"a", "b", "c", "d"})
+	user := cols.user([]interface{}{"1", "guest@example.com", "2025-06-01T18:00:00Z", "2025-06-01T19:00:00Z"})
+	if user.ID != "1" || user.Email != "guest@example.com" || user.Redeemed == nil {
+		t.Errorf("Unexpected user %+v", user)
+	}
+	if cols.ensure(csvHeader...) {
+		t.Error("Expected the default layout to cover all fields")
+	}
+
+	// Empty sheets get the full header
+	empty := parseSheetHeader(nil)
+	if len(empty.header) != len(csvHeader) || empty.column("Email") != "B" {
+		t.Errorf("Unexpected header %v", empty.header)
+	}
+	if got := empty.user(nil); got.Email != "" || !got.UpdatedAt.Equal((&domain.User{}).LastChange()) {
+		t.Errorf("Expected an empty user, got %+v", got)
+	}
+}
+
+func TestColumnLetter(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 6: "G", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := columnLetter(i); got != want {
+			t.Errorf("columnLetter(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestSameRow(t *testing.T) {
+	// Rows read back from the API lack trailing empty cells
+	if !sameRow([]interface{}{"1", "guest@example.com"}, []interface{}{"1", "guest@example.com", "", ""}) {
+		t.Error("Expected missing trailing cells to count as empty")
+	}
+	if sameRow([]interface{}{"1", "guest@example.com"}, []interface{}{"1", "guest@example.com", "2025-06-01T20:00:00Z"}) {
+		t.Error("Expected a new value to differ")
+	}
+}
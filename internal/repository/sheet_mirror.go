@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+	"google.golang.org/api/sheets/v4"
+)
+
+// MirrorResult summarizes writing a user list to a sheet
+type MirrorResult struct {
+	Added     int // Users without a row
+	Updated   int // Rows that differed from the user
+	Unchanged int // Rows left as they were
+}
+
+// Mirror writes the users to the sheet, one row per user. Rows that already
+// match are skipped, changed rows are written in a single batch and new users
+// appended at once. Rows of users not in the list are left alone.
+func (r *GoogleSheetRepository) Mirror(ctx any, users []*domain.User) (MirrorResult, error) {
+	var result MirrorResult
+
+	r.addMu.Lock()
+	defer r.addMu.Unlock()
+
+	cols, rows, err := r.readSheet()
+	if err != nil {
+		return result, fmt.Errorf("failed to read sheet: %w", err)
+	}
+	if err := r.prepareColumns(cols, rows); err != nil {
+		return result, err
+	}
+
+	existing := make(map[string]int, len(rows))
+	for i, row := range rows {
+		if i == 0 {
+			continue
+		}
+		email := utils.NormalizeEmail(cols.cell(row, "Email"))
+		if _, ok := existing[email]; email != "" && !ok {
+			existing[email] = i
+		}
+	}
+
+	var updates []*sheets.ValueRange
+	var appends [][]interface{}
+	for _, user := range users {
+		i, ok := existing[utils.NormalizeEmail(user.Email)]
+		if !ok {
+			appends = append(appends, cols.setUser(nil, user, true))
+			continue
+		}
+
+		values := cols.setUser(append([]interface{}(nil), rows[i]...), user, true)
+		if sameRow(rows[i], values) {
+			result.Unchanged++
+			continue
+		}
+		rowNumber := i + 1 // 1-based index for API
+		updates = append(updates, &sheets.ValueRange{
+			Range:  fmt.Sprintf("%s!A%d:%s%d", r.sheetName, rowNumber, cols.lastColumn(), rowNumber),
+			Values: [][]interface{}{values},
+		})
+	}
+
+	if len(updates) > 0 {
+		_, err := r.service.Spreadsheets.Values.BatchUpdate(r.spreadsheetID,
+			&sheets.BatchUpdateValuesRequest{ValueInputOption: "RAW", Data: updates}).
+			Context(context.Background()).Do()
+		if err != nil {
+			return result, fmt.Errorf("failed to update sheet rows: %w", err)
+		}
+		result.Updated = len(updates)
+	}
+
+	if len(appends) > 0 {
+		appendRange := fmt.Sprintf("%s!A:%s", r.sheetName, cols.lastColumn())
+		_, err := r.service.Spreadsheets.Values.Append(r.spreadsheetID, appendRange,
+			&sheets.ValueRange{Values: appends}).
+			ValueInputOption("RAW").InsertDataOption("INSERT_ROWS").Context(context.Background()).Do()
+		if err != nil {
+			return result, fmt.Errorf("failed to append sheet rows: %w", err)
+		}
+		result.Added = len(appends)
+	}
+
+	r.logger.Debug("Users mirrored to Google Sheets", "added", result.Added, "updated", result.Updated, "unchanged", result.Unchanged)
+	return result, nil
+}
+
+// sameRow returns true if two rows hold the same values. The API leaves out
+// trailing empty cells, so missing cells count as empty.
+func sameRow(a, b []interface{}) bool {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y interface{} = "", ""
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if fmt.Sprint(x) != fmt.Sprint(y) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// SheetSnapshot answers reads from the rows Google Sheets returned last.
+// It is the fallback of the Sheets breaker, so eligibility checks are still
+// answered while Sheets keeps failing. The rows may be outdated, so every
+// answer is logged with their age. It is read-only.
+type SheetSnapshot struct {
+	sheet *GoogleSheetRepository
+}
+
+// Snapshot returns a read-only view of the rows the sheet returned last
+func (r *GoogleSheetRepository) Snapshot() *SheetSnapshot {
+	return &SheetSnapshot{sheet: r}
+}
+
+// last returns the rows read last and when, or ErrDatabaseUnavailable if
+// the sheet was never read
+func (s *SheetSnapshot) last() (*sheetColumns, [][]interface{}, time.Time, error) {
+	s.sheet.lastMu.RLock()
+	defer s.sheet.lastMu.RUnlock()
+
+	if s.sheet.lastRead.IsZero() {
+		return nil, nil, time.Time{}, domain.ErrDatabaseUnavailable
+	}
+	var header []interface{}
+	if len(s.sheet.lastRows) > 0 {
+		header = s.sheet.lastRows[0]
+	}
+	return parseSheetHeader(header), s.sheet.lastRows, s.sheet.lastRead, nil
+}
+
+// FindByEmail looks up a user in the rows read last
+func (s *SheetSnapshot) FindByEmail(ctx any, email string) (*domain.User, error) {
+	cols, rows, read, err := s.last()
+	if err != nil {
+		return nil, err
+	}
+	s.sheet.logger.Warn("Answering from the last Google Sheet read, possibly stale", "email", email, "age", time.Since(read).Round(time.Second))
+	return s.sheet.findInRows(cols, rows, email)
+}
+
+// UpdateUser fails, the snapshot is read-only
+func (s *SheetSnapshot) UpdateUser(ctx any, user *domain.User) error {
+	return domain.ErrDatabaseUnavailable
+}
+
+// AddUser fails, the snapshot is read-only
+func (s *SheetSnapshot) AddUser(ctx any, user *domain.User) error {
+	return domain.ErrDatabaseUnavailable
+}
+
+// GetReport selects the users of the rows read last
+func (s *SheetSnapshot) GetReport(ctx any, params domain.ReportParams) ([]*domain.User, error) {
+	cols, rows, read, err := s.last()
+	if err != nil {
+		return nil, err
+	}
+	s.sheet.logger.Warn("Report from the last Google Sheet read, possibly stale", "type", params.Type, "age", time.Since(read).Round(time.Second))
+	return reportFromRows(cols, rows, params), nil
+}
+
+// Health fails until the sheet was read once
+func (s *SheetSnapshot) Health(ctx any) error {
+	_, _, _, err := s.last()
+	return err
+}
+
+// Stats returns record counts of the rows read last and when they were read
+func (s *SheetSnapshot) Stats(ctx any) (domain.RepoStats, error) {
+	cols, rows, read, err := s.last()
+	if err != nil {
+		return domain.RepoStats{Backend: "googlesheet"}, err
+	}
+
+	stats := statsFromUsers("googlesheet", reportFromRows(cols, rows, allUsersReport))
+	stats.Details["snapshot_read"] = read.UTC().Format(time.RFC3339)
+	return stats, nil
+}
+
+// Close does nothing, the sheet is closed by its owner
+func (s *SheetSnapshot) Close() error {
+	return nil
+}
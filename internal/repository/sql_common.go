@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// userColumns is the column list selected by all SQL-backed repositories.
+// scanUser expects rows selected in exactly this order.
+const userColumns = "id, email, date_added, redeemed, marketing_consent, updated_at, created_by, bar, notes, tags, first_name, last_name"
+
+// SQL dialects understood by the schema helpers
+const (
+	dialectSQLite   = "sqlite"
+	dialectPostgres = "postgresql"
+	dialectMySQL    = "mysql"
+)
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanUser reads a single user selected with userColumns
+func scanUser(row rowScanner) (*domain.User, error) {
+	var (
+		user             domain.User
+		redeemed         sql.NullTime
+		marketingConsent sql.NullTime
+		updatedAt        sql.NullTime
+		createdBy        sql.NullString
+		bar              sql.NullString
+		notes            sql.NullString
+		tags             sql.NullString
+		firstName        sql.NullString
+		lastName         sql.NullString
+	)
+
+	if err := row.Scan(&user.ID, &user.Email, &user.DateAdded, &redeemed, &marketingConsent, &updatedAt, &createdBy, &bar, &notes, &tags, &firstName, &lastName); err != nil {
+		return nil, err
+	}
+
+	// Convert nullable times to pointers
+	if redeemed.Valid {
+		t := redeemed.Time
+		user.Redeemed = &t
+	}
+	if marketingConsent.Valid {
+		t := marketingConsent.Time
+		user.MarketingConsent = &t
+	}
+	if updatedAt.Valid {
+		user.UpdatedAt = updatedAt.Time
+	} else {
+		user.UpdatedAt = user.LastChange()
+	}
+	user.CreatedBy = createdBy.String
+	user.RedeemedAt = bar.String
+	user.Notes = notes.String
+	user.Tags = domain.SplitTags(tags.String)
+	user.FirstName = firstName.String
+	user.LastName = lastName.String
+
+	return &user, nil
+}
+
+// userArgs returns the values of a user in the order of userColumns
+func userArgs(user *domain.User) []any {
+	return []any{
+		user.ID,
+		utils.NormalizeEmail(user.Email),
+		user.DateAdded,
+		nullTime(user.Redeemed),
+		nullTime(user.MarketingConsent),
+		user.UpdatedAt,
+		user.CreatedBy,
+		user.RedeemedAt,
+		user.Notes,
+		domain.JoinTags(user.Tags),
+		user.FirstName,
+		user.LastName,
+	}
+}
+
+// placeholder returns the n-th query parameter placeholder of a dialect,
+// counting from 1
+type placeholder func(n int) string
+
+// questionPlaceholder is the placeholder of SQLite and MySQL
+func questionPlaceholder(int) string { return "?" }
+
+// dollarPlaceholder is the placeholder of PostgreSQL
+func dollarPlaceholder(n int) string { return "$" + strconv.Itoa(n) }
+
+// reportQuery returns the query selecting the users of a report and its
+// arguments, with parameters written by the placeholder of the dialect
+func reportQuery(params domain.ReportParams, ph placeholder) (string, []any, error) {
+	where, args, err := reportWhere(params, ph)
+	if err != nil {
+		return "", nil, err
+	}
+	query := `SELECT ` + userColumns + ` FROM users WHERE ` + where
+
+	// Changes are selected oldest first, so that callers can continue from
+	// the last one
+	if params.Type == domain.ReportTypeChanged {
+		return query + ` ORDER BY updated_at ASC`, args, nil
+	}
+	return query + ` ORDER BY date_added DESC`, args, nil
+}
+
+// countReportSQL counts the users of a report with a COUNT query. It is
+// shared by the SQLite, PostgreSQL and MySQL repositories.
+func countReportSQL(ctx context.Context, db *sql.DB, params domain.ReportParams, ph placeholder) (int, error) {
+	where, args, err := reportWhere(params, ph)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE `+where, args...).Scan(&count)
+	return count, err
+}
+
+// reportWhere returns the conditions selecting the users of a report and
+// their arguments
+func reportWhere(params domain.ReportParams, ph placeholder) (string, []any, error) {
+	args := []any{params.From, params.To}
+	arg := func(value any) string {
+		args = append(args, value)
+		return ph(len(args))
+	}
+
+	// Changes are selected by the time of the last write
+	column := "date_added"
+	if params.Type == domain.ReportTypeChanged {
+		column = "updated_at"
+	}
+	query := column + ` >= ` + ph(1) + ` AND ` + column + ` <= ` + ph(2)
+
+	switch params.Type {
+	case domain.ReportTypeRedeemed:
+		// Only get users who have redeemed within the date range
+		query += ` AND redeemed IS NOT NULL`
+	case domain.ReportTypeConsented:
+		// Only get users who opted in to marketing
+		query += ` AND marketing_consent IS NOT NULL`
+	case domain.ReportTypeAdded, domain.ReportTypeAll, domain.ReportTypeChanged:
+		// Get all users within the date range
+	default:
+		return "", nil, fmt.Errorf("invalid report type: %s", params.Type)
+	}
+
+	// Emails are stored normalized and the domain may not hold LIKE
+	// wildcards, see domain.NormalizeReportFilter
+	if params.Domain != "" {
+		query += ` AND email LIKE ` + arg("%@"+params.Domain)
+	}
+	// The kind of source is compared with substr, since CreatedBy values
+	// such as rsvp_import contain the LIKE wildcard _
+	if params.Source != "" {
+		query += ` AND (created_by = ` + arg(params.Source) +
+			` OR substr(created_by, 1, ` + strconv.Itoa(len(params.Source)+1) + `) = ` + arg(params.Source+":") + `)`
+	}
+	if params.Bar != "" {
+		query += ` AND bar = ` + arg(params.Bar)
+	}
+	return query, args, nil
+}
+
+// addUpdatedAt adds the updated_at column to tables created by older
+// versions and fills it with the latest timestamp of each row
+func addUpdatedAt(db *sql.DB, dialect, definition string) error {
+	if err := addColumnIfMissing(db, dialect, "updated_at", definition); err != nil {
+		return err
+	}
+	for _, query := range []string{
+		`UPDATE users SET updated_at = date_added WHERE updated_at IS NULL`,
+		`UPDATE users SET updated_at = redeemed WHERE redeemed IS NOT NULL AND redeemed > updated_at`,
+		`UPDATE users SET updated_at = marketing_consent WHERE marketing_consent IS NOT NULL AND marketing_consent > updated_at`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storedUserID returns the ID of the user stored with an email, or an
+// empty ID if there is none. query selects the id column by the email.
+func storedUserID(ctx context.Context, db *sql.DB, query, email string) (string, error) {
+	var id string
+	err := db.QueryRowContext(ctx, query, email).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return id, err
+}
+
+// insertConflict checks whether a failed insert lost the race for an email
+// against a concurrent one. The unique email column rejects the second
+// insert, which is then reported with the ID of the stored user.
+func insertConflict(ctx context.Context, db *sql.DB, query, email string, insertErr error) error {
+	id, err := storedUserID(ctx, db, query, email)
+	if err != nil || id == "" {
+		return insertErr
+	}
+	return domain.NewDuplicateUserError(email, id)
+}
+
+// nullTime converts an optional time into a nullable SQL value
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// addColumnIfMissing adds a column to the users table of an existing
+// database so that deployments created by older versions keep working
+func addColumnIfMissing(db *sql.DB, dialect, column, definition string) error {
+	switch dialect {
+	case dialectPostgres:
+		_, err := db.Exec(fmt.Sprintf("ALTER TABLE users ADD COLUMN IF NOT EXISTS %s %s", column, definition))
+		return err
+	case dialectMySQL:
+		var count int
+		err := db.QueryRow(`
+			SELECT COUNT(*) FROM information_schema.columns
+			WHERE table_schema = DATABASE() AND table_name = 'users' AND column_name = ?
+		`, column).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		_, err = db.Exec(fmt.Sprintf("ALTER TABLE users ADD COLUMN %s %s", column, definition))
+		return err
+	case dialectSQLite:
+		rows, err := db.Query("PRAGMA table_info(users)")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				cid       int
+				name      string
+				colType   string
+				notNull   int
+				dfltValue sql.NullString
+				pk        int
+			)
+			if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+				return err
+			}
+			if name == column {
+				return nil
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		_, err = db.Exec(fmt.Sprintf("ALTER TABLE users ADD COLUMN %s %s", column, definition))
+		return err
+	default:
+		return fmt.Errorf("unsupported SQL dialect: %s", dialect)
+	}
+}
+
+// sqlStats collects the record counts and last write time of a SQL users
+// table. Callers add their backend-specific details.
+func sqlStats(db *sql.DB, backend string) (domain.RepoStats, error) {
+	stats := domain.RepoStats{
+		Backend: backend,
+		Details: make(map[string]string),
+	}
+
+	err := db.QueryRow("SELECT COUNT(*), COUNT(redeemed), COUNT(marketing_consent) FROM users").
+		Scan(&stats.Users, &stats.Redeemed, &stats.Consented)
+	if err != nil {
+		return stats, err
+	}
+
+	// The last write is the most recent of any timestamp column
+	for _, column := range []string{"date_added", "redeemed", "marketing_consent", "updated_at"} {
+		var t sql.NullTime
+		err := db.QueryRow(fmt.Sprintf(
+			"SELECT %s FROM users WHERE %s IS NOT NULL ORDER BY %s DESC LIMIT 1", column, column, column,
+		)).Scan(&t)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return stats, err
+		}
+		if stats.LastWrite == nil || t.Time.After(*stats.LastWrite) {
+			lastWrite := t.Time
+			stats.LastWrite = &lastWrite
+		}
+	}
+
+	dbStats := db.Stats()
+	stats.Details["open_connections"] = fmt.Sprint(dbStats.OpenConnections)
+	stats.Details["in_use_connections"] = fmt.Sprint(dbStats.InUse)
+
+	return stats, nil
+}
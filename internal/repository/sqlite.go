@@ -1,19 +1,22 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"os"
 	"sync"
-	"time"
 
 	"github.com/ceesaxp/cocktail-bot/internal/domain"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
 	_ "github.com/mattn/go-sqlite3" // SQLite driver
 )
 
 // SQLiteRepository implements the domain.Repository interface for SQLite
 type SQLiteRepository struct {
 	db     *sql.DB
+	path   string
 	logger *logger.Logger
 	mu     sync.Mutex // For thread safety
 }
@@ -63,6 +66,7 @@ func NewSQLiteRepository(dbPath string, logger *logger.Logger) (domain.Repositor
 
 	return &SQLiteRepository{
 		db:     db,
+		path:   dbPath,
 		logger: logger,
 	}, nil
 }
@@ -74,12 +78,46 @@ func createTableIfNotExists(db *sql.DB) error {
 		id TEXT PRIMARY KEY,
 		email TEXT UNIQUE NOT NULL,
 		date_added TIMESTAMP NOT NULL,
-		redeemed TIMESTAMP
+		redeemed TIMESTAMP,
+		marketing_consent TIMESTAMP,
+		updated_at TIMESTAMP,
+		created_by TEXT,
+		bar TEXT,
+		notes TEXT,
+		tags TEXT,
+		first_name TEXT,
+		last_name TEXT
 	);
 	CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+	CREATE INDEX IF NOT EXISTS idx_users_email_lower ON users(LOWER(email));
 	`
-	_, err := db.Exec(query)
-	return err
+	if _, err := db.Exec(query); err != nil {
+		return err
+	}
+
+	// Upgrade tables created by older versions
+	if err := addColumnIfMissing(db, dialectSQLite, "marketing_consent", "TIMESTAMP"); err != nil {
+		return err
+	}
+	if err := addUpdatedAt(db, dialectSQLite, "TIMESTAMP"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, dialectSQLite, "created_by", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, dialectSQLite, "bar", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, dialectSQLite, "notes", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, dialectSQLite, "tags", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, dialectSQLite, "first_name", "TEXT"); err != nil {
+		return err
+	}
+	return addColumnIfMissing(db, dialectSQLite, "last_name", "TEXT")
 }
 
 // FindByEmail looks up a user by email
@@ -87,17 +125,10 @@ func (r *SQLiteRepository) FindByEmail(ctx any, email string) (*domain.User, err
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	query := `SELECT id, email, date_added, redeemed FROM users WHERE LOWER(email) = LOWER(?)`
-	row := r.db.QueryRow(query, email)
-
-	var (
-		id              string
-		dbEmail         string
-		dateAdded       time.Time
-		alreadyConsumed sql.NullTime
-	)
+	query := `SELECT ` + userColumns + ` FROM users WHERE LOWER(email) = ?`
+	row := r.db.QueryRow(query, utils.NormalizeEmail(email))
 
-	err := row.Scan(&id, &dbEmail, &dateAdded, &alreadyConsumed)
+	user, err := scanUser(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, domain.ErrUserNotFound
@@ -108,18 +139,7 @@ func (r *SQLiteRepository) FindByEmail(ctx any, email string) (*domain.User, err
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 
-	// Convert nullable time to pointer
-	var consumedTime *time.Time
-	if alreadyConsumed.Valid {
-		consumedTime = &alreadyConsumed.Time
-	}
-
-	return &domain.User{
-		ID:              id,
-		Email:           dbEmail,
-		DateAdded:       dateAdded,
-		Redeemed: consumedTime,
-	}, nil
+	return user, nil
 }
 
 // UpdateUser updates an existing user information (primarily for marking cocktail as redeemed)
@@ -127,16 +147,9 @@ func (r *SQLiteRepository) UpdateUser(ctx any, user *domain.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	var consumedTime sql.NullTime
-	if user.Redeemed != nil {
-		consumedTime = sql.NullTime{
-			Time:  *user.Redeemed,
-			Valid: true,
-		}
-	}
-
-	query := `UPDATE users SET redeemed = ? WHERE id = ?`
-	result, err := r.db.Exec(query, consumedTime, user.ID)
+	stampUser(user)
+	query := `UPDATE users SET redeemed = ?, marketing_consent = ?, updated_at = ?, bar = ?, notes = ?, tags = ?, first_name = ?, last_name = ? WHERE id = ?`
+	result, err := r.db.Exec(query, nullTime(user.Redeemed), nullTime(user.MarketingConsent), user.UpdatedAt, user.RedeemedAt, user.Notes, domain.JoinTags(user.Tags), user.FirstName, user.LastName, user.ID)
 	if err != nil {
 		if r.logger != nil {
 			r.logger.Error("Error updating user", "id", user.ID, "error", err)
@@ -164,19 +177,17 @@ func (r *SQLiteRepository) AddUser(ctx any, user *domain.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Prepare consumed time for SQL
-	var consumedTime sql.NullTime
-	if user.Redeemed != nil {
-		consumedTime = sql.NullTime{
-			Time:  *user.Redeemed,
-			Valid: true,
-		}
-	}
-
 	// Insert new user
-	query := `INSERT INTO users (id, email, date_added, redeemed) VALUES (?, ?, ?, ?)`
-	_, err := r.db.Exec(query, user.ID, user.Email, user.DateAdded, consumedTime)
+	stampUser(user)
+	query := `INSERT INTO users (` + userColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := r.db.Exec(query, userArgs(user)...)
 	if err != nil {
+		// Another process sharing the file may have added the email
+		err = insertConflict(context.Background(), r.db, "SELECT id FROM users WHERE email = ?", utils.NormalizeEmail(user.Email), err)
+		if domain.IsDuplicateUser(err) {
+			r.logger.Debug("User already exists in SQLite", "email", user.Email)
+			return err
+		}
 		r.logger.Error("Error adding user", "email", user.Email, "error", err)
 		return fmt.Errorf("database error: %w", err)
 	}
@@ -185,6 +196,20 @@ func (r *SQLiteRepository) AddUser(ctx any, user *domain.User) error {
 	return nil
 }
 
+// AddUsers adds users in one transaction, or none of them if any fails
+func (r *SQLiteRepository) AddUsers(ctx any, users []*domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := insertUsers(context.Background(), r.db, users, questionPlaceholder); err != nil {
+		r.logger.Debug("Error adding users in batch", "count", len(users), "error", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	r.logger.Debug("Users added to SQLite", "count", len(users))
+	return nil
+}
+
 // GetReport retrieves users based on the report parameters
 func (r *SQLiteRepository) GetReport(ctx any, params domain.ReportParams) ([]*domain.User, error) {
 	r.mu.Lock()
@@ -192,41 +217,10 @@ func (r *SQLiteRepository) GetReport(ctx any, params domain.ReportParams) ([]*do
 
 	r.logger.Debug("Generating report from SQLite", "type", params.Type, "from", params.From, "to", params.To)
 
-	var query string
-	var args []interface{}
-
 	// Build different queries based on report type
-	switch params.Type {
-	case domain.ReportTypeRedeemed:
-		// Only get users who have redeemed within the date range
-		query = `
-			SELECT id, email, date_added, redeemed 
-			FROM users
-			WHERE date_added >= ? AND date_added <= ?
-			AND redeemed IS NOT NULL
-			ORDER BY date_added DESC
-		`
-		args = []interface{}{params.From, params.To}
-	case domain.ReportTypeAdded:
-		// Get users added within the date range
-		query = `
-			SELECT id, email, date_added, redeemed 
-			FROM users
-			WHERE date_added >= ? AND date_added <= ?
-			ORDER BY date_added DESC
-		`
-		args = []interface{}{params.From, params.To}
-	case domain.ReportTypeAll:
-		// Get all users
-		query = `
-			SELECT id, email, date_added, redeemed 
-			FROM users
-			WHERE date_added >= ? AND date_added <= ?
-			ORDER BY date_added DESC
-		`
-		args = []interface{}{params.From, params.To}
-	default:
-		return nil, fmt.Errorf("invalid report type: %s", params.Type)
+	query, args, err := reportQuery(params, questionPlaceholder)
+	if err != nil {
+		return nil, err
 	}
 
 	// Execute query
@@ -240,30 +234,12 @@ func (r *SQLiteRepository) GetReport(ctx any, params domain.ReportParams) ([]*do
 	// Parse results
 	var users []*domain.User
 	for rows.Next() {
-		var (
-			id              string
-			email           string
-			dateAdded       time.Time
-			alreadyConsumed sql.NullTime
-		)
-
-		if err := rows.Scan(&id, &email, &dateAdded, &alreadyConsumed); err != nil {
+		user, err := scanUser(rows)
+		if err != nil {
 			r.logger.Error("Error scanning row", "error", err)
 			return nil, fmt.Errorf("error scanning row: %w", err)
 		}
-
-		// Convert nullable time to pointer
-		var consumedTime *time.Time
-		if alreadyConsumed.Valid {
-			consumedTime = &alreadyConsumed.Time
-		}
-
-		users = append(users, &domain.User{
-			ID:        id,
-			Email:     email,
-			DateAdded: dateAdded,
-			Redeemed:  consumedTime,
-		})
+		users = append(users, user)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -275,6 +251,49 @@ func (r *SQLiteRepository) GetReport(ctx any, params domain.ReportParams) ([]*do
 	return users, nil
 }
 
+// NormalizeEmails rewrites stored emails to lowercase without surrounding spaces
+func (r *SQLiteRepository) NormalizeEmails(ctx any) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed, err := normalizeEmailsSQL(r.db)
+	if err != nil {
+		r.logger.Error("Error normalizing emails", "error", err)
+		return 0, err
+	}
+
+	r.logger.Info("Emails normalized in SQLite", "changed", changed)
+	return changed, nil
+}
+
+// Health checks that the database can be reached
+func (r *SQLiteRepository) Health(ctx any) error {
+	if err := r.db.Ping(); err != nil {
+		r.logger.Sampled("health_check").Error("SQLite health check failed", "error", err)
+		return domain.ErrDatabaseUnavailable
+	}
+	return nil
+}
+
+// Stats returns record counts and database file information
+func (r *SQLiteRepository) Stats(ctx any) (domain.RepoStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats, err := sqlStats(r.db, dialectSQLite)
+	if err != nil {
+		r.logger.Error("Error collecting SQLite stats", "error", err)
+		return stats, domain.ErrDatabaseUnavailable
+	}
+
+	stats.Details["path"] = r.path
+	if info, err := os.Stat(r.path); err == nil {
+		stats.Details["file_size_bytes"] = fmt.Sprint(info.Size())
+	}
+
+	return stats, nil
+}
+
 // Close closes the database connection
 func (r *SQLiteRepository) Close() error {
 	r.mu.Lock()
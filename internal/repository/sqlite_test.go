@@ -1,7 +1,10 @@
 package repository_test
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -122,6 +125,7 @@ func TestSQLiteRepository(t *testing.T) {
 			Email:     "newuser@example.com",
 			DateAdded: time.Now(),
 			Redeemed:  nil,
+			CreatedBy: "rsvp_import",
 		}
 		
 		// Add the user
@@ -136,7 +140,7 @@ func TestSQLiteRepository(t *testing.T) {
 			t.Errorf("Failed to find added user: %v", err)
 		}
 		
-		if addedUser == nil || addedUser.ID != "3" {
+		if addedUser == nil || addedUser.ID != "3" || addedUser.CreatedBy != "rsvp_import" {
 			t.Errorf("Added user data incorrect: %+v", addedUser)
 		}
 	})
@@ -155,6 +159,94 @@ func TestSQLiteRepository(t *testing.T) {
 			t.Errorf("Expected error when adding user with duplicate email")
 		}
 	})
+
+	t.Run("GetReport - Changed", func(t *testing.T) {
+		// test1 was redeemed and newuser added above; test2 was not written since
+		users, err := repo.GetReport(nil, domain.ReportParams{
+			Type: domain.ReportTypeChanged,
+			From: time.Now().Add(-time.Minute),
+			To:   time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("Failed to get changes: %v", err)
+		}
+		if len(users) != 2 || users[0].Email != "test1@example.com" || users[1].Email != "newuser@example.com" {
+			t.Fatalf("Expected test1 then newuser, got %+v", users)
+		}
+		if users[0].UpdatedAt.After(users[1].UpdatedAt) {
+			t.Errorf("Expected changes oldest first")
+		}
+	})
+
+	t.Run("Health and Stats", func(t *testing.T) {
+		if err := repo.Health(nil); err != nil {
+			t.Errorf("Expected healthy repository, got: %v", err)
+		}
+
+		stats, err := repo.Stats(nil)
+		if err != nil {
+			t.Fatalf("Failed to get stats: %v", err)
+		}
+
+		if stats.Backend != "sqlite" || stats.Users != 3 || stats.Redeemed != 2 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+		if stats.LastWrite == nil {
+			t.Errorf("Expected last write time to be set")
+		}
+		if stats.Details["path"] != dbPath {
+			t.Errorf("Expected path %s, got %s", dbPath, stats.Details["path"])
+		}
+	})
+
+	t.Run("GetReport - Filters", func(t *testing.T) {
+		guest := &domain.User{ID: "5", Email: "guest@other.org", DateAdded: time.Now(), CreatedBy: "token:abcd"}
+		if err := repo.AddUser(nil, guest); err != nil {
+			t.Fatalf("Failed to add user: %v", err)
+		}
+		guest.Redeem()
+		guest.RedeemedAt = "Rooftop"
+		if err := repo.UpdateUser(nil, guest); err != nil {
+			t.Fatalf("Failed to redeem: %v", err)
+		}
+		if found, err := repo.FindByEmail(nil, "guest@other.org"); err != nil || found.RedeemedAt != "Rooftop" {
+			t.Errorf("Expected the bar stored with the redemption, got %+v: %v", found, err)
+		}
+
+		tests := []struct {
+			name   string
+			filter domain.ReportFilter
+			want   []string // IDs of the expected users
+		}{
+			{"domain", domain.ReportFilter{Domain: "other.org"}, []string{"5"}},
+			{"source", domain.ReportFilter{Source: "rsvp_import"}, []string{"3"}},
+			{"kind of source", domain.ReportFilter{Source: "token"}, []string{"5"}},
+			{"source prefix without colon", domain.ReportFilter{Source: "tok"}, nil},
+			{"both", domain.ReportFilter{Domain: "example.com", Source: "token"}, nil},
+			{"bar", domain.ReportFilter{Bar: "Rooftop"}, []string{"5"}},
+			{"other bar", domain.ReportFilter{Bar: "Garden"}, nil},
+		}
+		for _, tt := range tests {
+			users, err := repo.GetReport(nil, domain.ReportParams{
+				Type:         domain.ReportTypeAll,
+				From:         time.Now().Add(-time.Hour),
+				To:           time.Now(),
+				ReportFilter: tt.filter,
+			})
+			if err != nil {
+				t.Fatalf("%s: failed to get report: %v", tt.name, err)
+			}
+			if len(users) != len(tt.want) {
+				t.Errorf("%s: expected %v, got %+v", tt.name, tt.want, users)
+				continue
+			}
+			for i, user := range users {
+				if user.ID != tt.want[i] {
+					t.Errorf("%s: expected user %s, got %s", tt.name, tt.want[i], user.ID)
+				}
+			}
+		}
+	})
 }
 
 // initTestData initializes the test database with sample data
@@ -189,4 +281,238 @@ func initTestData(repo domain.Repository, dbPath string) error {
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+func TestSQLiteRepository_DuplicateAdd(t *testing.T) {
+	dbPath := t.TempDir() + "/users.db"
+
+	// Two repositories on one file, like the bot and the admin CLI
+	first, err := repository.NewSQLiteRepository(dbPath, logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite repository: %v", err)
+	}
+	defer first.Close()
+	second, err := repository.NewSQLiteRepository(dbPath, logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite repository: %v", err)
+	}
+	defer second.Close()
+
+	if err := first.AddUser(nil, &domain.User{ID: "winner", Email: "race@example.com", DateAdded: time.Now()}); err != nil {
+		t.Fatalf("Failed to add user: %v", err)
+	}
+
+	// The unique email column rejects the second insert, which reports the stored user
+	err = second.AddUser(nil, &domain.User{ID: "loser", Email: "Race@Example.com", DateAdded: time.Now()})
+	var dup *domain.DuplicateUserError
+	if !errors.As(err, &dup) || !errors.Is(err, domain.ErrUserExists) {
+		t.Fatalf("Expected a duplicate user error, got: %v", err)
+	}
+	if dup.ID != "winner" {
+		t.Errorf("Expected the ID of the stored user, got %q", dup.ID)
+	}
+}
+
+func TestSQLiteRepository_AddUsers(t *testing.T) {
+	repo, err := repository.NewSQLiteRepository(t.TempDir()+"/users.db", logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite repository: %v", err)
+	}
+	defer repo.Close()
+	adder, ok := repo.(repository.BatchAdder)
+	if !ok {
+		t.Fatal("Expected SQLite to support batch adds")
+	}
+
+	// More users than fit in one INSERT
+	users := make([]*domain.User, 250)
+	for i := range users {
+		users[i] = &domain.User{ID: fmt.Sprint(i), Email: fmt.Sprintf("guest%d@example.com", i), DateAdded: time.Now()}
+	}
+	if err := adder.AddUsers(nil, users); err != nil {
+		t.Fatalf("Failed to add users: %v", err)
+	}
+	if user, err := repo.FindByEmail(nil, "guest249@example.com"); err != nil || user.ID != "249" {
+		t.Errorf("Expected the last user to be stored, got %+v, %v", user, err)
+	}
+
+	// A stored email rolls back the whole batch
+	err = adder.AddUsers(nil, []*domain.User{
+		{ID: "new", Email: "new@example.com", DateAdded: time.Now()},
+		{ID: "dup", Email: "guest1@example.com", DateAdded: time.Now()},
+	})
+	if err == nil {
+		t.Fatal("Expected an error for a batch with a stored email")
+	}
+	if _, err := repo.FindByEmail(nil, "new@example.com"); err != domain.ErrUserNotFound {
+		t.Errorf("Expected no user of the failed batch to be stored, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_ChangeEmail(t *testing.T) {
+	repo, err := repository.NewSQLiteRepository(t.TempDir()+"/users.db", logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite repository: %v", err)
+	}
+	defer repo.Close()
+	changer, ok := repo.(repository.EmailChanger)
+	if !ok {
+		t.Fatal("Expected SQLite to support email changes")
+	}
+
+	for _, user := range []*domain.User{
+		{ID: "1", Email: "gest@example.com", DateAdded: time.Now(), Notes: "Table 4", Tags: []string{"vip", "press"}},
+		{ID: "2", Email: "other@example.com", DateAdded: time.Now()},
+	} {
+		if err := repo.AddUser(nil, user); err != nil {
+			t.Fatalf("Failed to add user: %v", err)
+		}
+	}
+
+	if err := changer.ChangeEmail(nil, "Gest@Example.com", "guest@example.com"); err != nil {
+		t.Fatalf("Failed to change email: %v", err)
+	}
+	user, err := repo.FindByEmail(nil, "guest@example.com")
+	if err != nil || user.ID != "1" || user.Notes != "Table 4" || strings.Join(user.Tags, ",") != "vip,press" {
+		t.Errorf("Expected the user with its notes and tags under the new email, got %+v, %v", user, err)
+	}
+	if _, err := repo.FindByEmail(nil, "gest@example.com"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("Expected the old email to be gone, got %v", err)
+	}
+
+	if err := changer.ChangeEmail(nil, "guest@example.com", "other@example.com"); !domain.IsDuplicateUser(err) {
+		t.Errorf("Expected a duplicate user error, got %v", err)
+	}
+	if err := changer.ChangeEmail(nil, "missing@example.com", "new@example.com"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("Expected an unknown email to be reported, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_Names(t *testing.T) {
+	repo, err := repository.NewSQLiteRepository(t.TempDir()+"/users.db", logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite repository: %v", err)
+	}
+	defer repo.Close()
+
+	user := &domain.User{ID: "1", Email: "anna@example.com", DateAdded: time.Now(), FirstName: "Anna", LastName: "Karenina"}
+	if err := repo.AddUser(nil, user); err != nil {
+		t.Fatalf("Failed to add user: %v", err)
+	}
+	found, err := repo.FindByEmail(nil, "anna@example.com")
+	if err != nil || found.FirstName != "Anna" || found.LastName != "Karenina" {
+		t.Fatalf("Expected the names to be stored, got %+v, %v", found, err)
+	}
+
+	found.LastName = ""
+	if err := repo.UpdateUser(nil, found); err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+	users, err := repo.GetReport(nil, domain.ReportParams{Type: domain.ReportTypeAll, From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Hour)})
+	if err != nil || len(users) != 1 || users[0].FullName() != "Anna" {
+		t.Errorf("Expected the cleared last name in the report, got %+v, %v", users, err)
+	}
+}
+
+func TestSQLiteRepository_CountReport(t *testing.T) {
+	repo, err := repository.NewSQLiteRepository(t.TempDir()+"/users.db", logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite repository: %v", err)
+	}
+	defer repo.Close()
+
+	now := time.Now()
+	for i, email := range []string{"anna@example.com", "ben@example.com", "cleo@other.org"} {
+		user := &domain.User{ID: fmt.Sprint(i), Email: email, DateAdded: now.Add(-time.Hour)}
+		if i < 2 {
+			redeemed := now.Add(-time.Minute)
+			user.Redeemed = &redeemed
+		}
+		if err := repo.AddUser(nil, user); err != nil {
+			t.Fatalf("Failed to add user: %v", err)
+		}
+	}
+
+	// The count matches the report, with and without filters
+	for _, params := range []domain.ReportParams{
+		{Type: domain.ReportTypeAll, From: now.Add(-2 * time.Hour), To: now},
+		{Type: domain.ReportTypeRedeemed, From: now.Add(-2 * time.Hour), To: now},
+		{Type: domain.ReportTypeAll, From: now.Add(-2 * time.Hour), To: now, ReportFilter: domain.ReportFilter{Domain: "example.com"}},
+	} {
+		users, err := repo.GetReport(nil, params)
+		if err != nil {
+			t.Fatalf("Failed to get report: %v", err)
+		}
+		count, err := repository.CountReport(nil, repo, params)
+		if err != nil || count != len(users) {
+			t.Errorf("Expected a %s count of %d, got %d (%v)", params.Type, len(users), count, err)
+		}
+	}
+}
+
+func TestSQLiteRepository_Search(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := repository.NewSQLiteRepository(dir+"/users.db", logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite repository: %v", err)
+	}
+	defer repo.Close()
+	csvRepo, err := repository.NewCSVRepository(dir+"/users.csv", logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create CSV repository: %v", err)
+	}
+	defer csvRepo.Close()
+
+	for _, user := range []*domain.User{
+		{ID: "1", Email: "ann_k@example.com", FirstName: "Anna", LastName: "Karenina", Notes: "Seated at table 5"},
+		{ID: "2", Email: "annabel@example.com", Tags: []string{"press"}},
+		{ID: "3", Email: "ben@example.com", Notes: "Friend of Anna, 100% on time"},
+		{ID: "4", Email: "annkex@example.com"},
+	} {
+		user.DateAdded = time.Now()
+		if err := repo.AddUser(nil, user); err != nil {
+			t.Fatalf("Failed to add user: %v", err)
+		}
+		if err := csvRepo.AddUser(nil, user); err != nil {
+			t.Fatalf("Failed to add user: %v", err)
+		}
+	}
+
+	ids := func(result domain.SearchResult) string {
+		var ids []string
+		for _, hit := range result.Hits {
+			ids = append(ids, hit.User.ID)
+		}
+		return strings.Join(ids, ",")
+	}
+	for _, tc := range []struct {
+		terms []string
+		want  string
+	}{
+		{[]string{"anna"}, "1,2,3"},      // Name and email start before notes
+		{[]string{"anna", "press"}, "2"}, // Every term must match
+		{[]string{"n_k"}, "1"},           // _ is not a wildcard
+		{[]string{"100%"}, "3"},          // Nor is %
+		{[]string{"karenina", "table"}, "1"},
+		{[]string{"nobody"}, ""},
+	} {
+		params := domain.SearchParams{Terms: tc.terms}
+		result, err := repository.SearchUsers(nil, repo, params)
+		if err != nil {
+			t.Fatalf("Failed to search: %v", err)
+		}
+		if got := ids(result); got != tc.want || result.Total != len(result.Hits) {
+			t.Errorf("Expected %v to find %q, got %q (total %d)", tc.terms, tc.want, got, result.Total)
+		}
+		// CSV files are searched in memory with the same ranking
+		inMemory, err := repository.SearchUsers(nil, csvRepo, params)
+		if err != nil || ids(inMemory) != tc.want {
+			t.Errorf("Expected the CSV search for %v to find %q, got %q (%v)", tc.terms, tc.want, ids(inMemory), err)
+		}
+	}
+
+	result, err := repository.SearchUsers(nil, repo, domain.SearchParams{Terms: []string{"example"}, Offset: 1, Limit: 2})
+	if err != nil || result.Total != 4 || ids(result) != "2,4" {
+		t.Errorf("Expected the second page of 4 matches, got %q of %d (%v)", ids(result), result.Total, err)
+	}
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"google.golang.org/api/googleapi"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// defaultProbeTimeout bounds the startup probe when no database timeout is
+// configured
+const defaultProbeTimeout = 10 * time.Second
+
+// connectTimeout bounds the first connection made by the SQL and MongoDB
+// constructors, so an unreachable host fails startup instead of hanging it
+const connectTimeout = 15 * time.Second
+
+// StartupError is a database that failed its check at startup, with what
+// to fix
+type StartupError struct {
+	Backend string // Database type
+	Problem string // What failed, such as "connection refused by db:5432"
+	Hint    string // What to check
+	Err     error
+}
+
+// Error returns the problem and the hint, followed by the cause
+func (e *StartupError) Error() string {
+	msg := e.Backend + " database: " + e.Problem
+	if e.Hint != "" {
+		msg += " (" + e.Hint + ")"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the cause
+func (e *StartupError) Unwrap() error {
+	return e.Err
+}
+
+// Prober is implemented by repositories that can check their connection
+// with a lightweight request
+type Prober interface {
+	Probe(ctx context.Context) error
+}
+
+// preflight checks what can be checked in a connection string without
+// connecting, such as the files it names
+func preflight(dbType, connectionString string) error {
+	switch dbType {
+	case "csv", "sqlite":
+		if connectionString == "" {
+			return nil // Refused by the constructor
+		}
+		path, _, _ := strings.Cut(strings.TrimPrefix(connectionString, "file:"), "?")
+		dir := filepath.Dir(path)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return &StartupError{Backend: dbType, Problem: "directory " + dir + " does not exist", Hint: "create it or fix the path in the connection string", Err: err}
+		}
+	case "googlesheet":
+		parts := parseConnectionString(connectionString)
+		if parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return &StartupError{Backend: dbType, Problem: "invalid connection string", Hint: "expected credentials.json|spreadsheet ID|sheet name"}
+		}
+		data, err := os.ReadFile(parts[0])
+		if errors.Is(err, os.ErrNotExist) {
+			return &StartupError{Backend: dbType, Problem: "credentials file " + parts[0] + " does not exist", Hint: "download a service account key from the Google Cloud console and set its path as the first part of the connection string"}
+		}
+		if err != nil {
+			return &StartupError{Backend: dbType, Problem: "credentials file " + parts[0] + " cannot be read", Hint: "check its permissions", Err: err}
+		}
+		var key struct {
+			Type        string `json:"type"`
+			ClientEmail string `json:"client_email"`
+		}
+		if err := json.Unmarshal(data, &key); err != nil || key.Type == "" {
+			return &StartupError{Backend: dbType, Problem: "credentials file " + parts[0] + " is not a Google credentials file", Hint: "download the JSON key of a service account", Err: err}
+		}
+	}
+	return nil
+}
+
+// probe checks the connection of a repository with a lightweight request,
+// bounded by timeout, and explains a failure
+func probe(repo domain.Repository, dbType string, timeout time.Duration) error {
+	prober, ok := repo.(Prober)
+	if !ok {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := prober.Probe(ctx); err != nil {
+		return explain(dbType, err)
+	}
+	return nil
+}
+
+// explain turns a failed connection into a StartupError naming the likely
+// cause. Errors that are already explained are returned as they are.
+func explain(dbType string, err error) error {
+	var startupErr *StartupError
+	if errors.As(err, &startupErr) {
+		return err
+	}
+	e := &StartupError{Backend: dbType, Problem: "cannot connect", Err: err}
+
+	var (
+		dnsErr   *net.DNSError
+		netErr   net.Error
+		pqErr    *pq.Error
+		mysqlErr *mysql.MySQLError
+		apiErr   *googleapi.Error
+	)
+	switch {
+	case errors.As(err, &dnsErr):
+		e.Problem, e.Hint = "host "+dnsErr.Name+" not found", "check the host in the connection string"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		e.Problem, e.Hint = "connection refused", "check that the database is running and listens on the host and port of the connection string"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		e.Problem, e.Hint = "no answer in time", "check the host and port, and that no firewall blocks the bot"
+	case errors.As(err, &pqErr) && (pqErr.Code == "28P01" || pqErr.Code == "28000"),
+		errors.As(err, &mysqlErr) && mysqlErr.Number == 1045:
+		e.Problem, e.Hint = "authentication failed", "check the user and password in the connection string"
+	case errors.As(err, &pqErr) && pqErr.Code == "3D000",
+		errors.As(err, &mysqlErr) && mysqlErr.Number == 1049:
+		e.Problem, e.Hint = "database does not exist", "create it or fix the database name in the connection string"
+	case errors.As(err, &apiErr) && apiErr.Code == 404:
+		e.Problem, e.Hint = "spreadsheet not found", "check the spreadsheet ID, the part of the sheet URL after /d/"
+	case errors.As(err, &apiErr) && apiErr.Code == 403:
+		e.Problem, e.Hint = "access to the spreadsheet denied", "share the spreadsheet with the client_email of the credentials file as an editor, and enable the Google Sheets API"
+	case errors.As(err, &apiErr) && apiErr.Code == 401, strings.Contains(err.Error(), "oauth2"):
+		e.Problem, e.Hint = "credentials rejected", "the service account key may have been deleted, download a new one"
+	case dbType == "mongodb":
+		e.Hint = "check that MongoDB runs at the host of the connection string and that its user and password are right"
+	}
+	return e
+}
+
+// probeSQL runs SELECT 1
+func probeSQL(ctx context.Context, db *sql.DB) error {
+	var one int
+	return db.QueryRowContext(ctx, `SELECT 1`).Scan(&one)
+}
+
+// Probe runs SELECT 1
+func (r *SQLiteRepository) Probe(ctx context.Context) error {
+	return probeSQL(ctx, r.db)
+}
+
+// Probe runs SELECT 1
+func (r *PostgresRepository) Probe(ctx context.Context) error {
+	return probeSQL(ctx, r.db)
+}
+
+// Probe runs SELECT 1
+func (r *MySQLRepository) Probe(ctx context.Context) error {
+	return probeSQL(ctx, r.db)
+}
+
+// Probe pings the server
+func (r *MongoDBRepository) Probe(ctx context.Context) error {
+	return r.client.Ping(ctx, nil)
+}
+
+// Probe reads the titles of the sheets of the spreadsheet, which checks
+// the credentials, the spreadsheet ID and the sheet name
+func (r *GoogleSheetRepository) Probe(ctx context.Context) error {
+	spreadsheet, err := r.service.Spreadsheets.Get(r.spreadsheetID).Fields("sheets.properties.title").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	name, _, _ := strings.Cut(r.sheetName, "!")
+	name = strings.Trim(name, "'")
+	titles := make([]string, len(spreadsheet.Sheets))
+	for i, sheet := range spreadsheet.Sheets {
+		if sheet.Properties.Title == name {
+			return nil
+		}
+		titles[i] = sheet.Properties.Title
+	}
+	return &StartupError{
+		Backend: "googlesheet",
+		Problem: fmt.Sprintf("sheet %q not found in the spreadsheet", name),
+		Hint:    "the spreadsheet has " + strings.Join(titles, ", "),
+	}
+}
+
+// Ensure the repositories that connect to a server or file are probed at
+// startup
+var (
+	_ Prober = (*SQLiteRepository)(nil)
+	_ Prober = (*PostgresRepository)(nil)
+	_ Prober = (*MySQLRepository)(nil)
+	_ Prober = (*MongoDBRepository)(nil)
+	_ Prober = (*GoogleSheetRepository)(nil)
+)
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// allUsersReport selects every user regardless of when they were added
+var allUsersReport = domain.ReportParams{
+	Type: domain.ReportTypeAll,
+	From: time.Time{},
+	To:   time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+}
+
+// stampUser sets UpdatedAt for writes that did not go through the service,
+// which maintains it
+func stampUser(user *domain.User) {
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = time.Now()
+	}
+}
+
+// sortByUpdatedAt orders the users of a changes report, oldest change first
+func sortByUpdatedAt(users []*domain.User) {
+	sort.SliceStable(users, func(i, j int) bool {
+		return users[i].UpdatedAt.Before(users[j].UpdatedAt)
+	})
+}
+
+// statsFromUsers computes repository statistics from a full list of users.
+// It is used by backends that cannot aggregate on the server side.
+func statsFromUsers(backend string, users []*domain.User) domain.RepoStats {
+	stats := domain.RepoStats{
+		Backend: backend,
+		Users:   len(users),
+		Details: make(map[string]string),
+	}
+
+	latest := func(t time.Time) {
+		if stats.LastWrite == nil || t.After(*stats.LastWrite) {
+			stats.LastWrite = &t
+		}
+	}
+
+	for _, user := range users {
+		latest(user.LastChange())
+		if user.Redeemed != nil {
+			stats.Redeemed++
+			latest(*user.Redeemed)
+		}
+		if user.MarketingConsent != nil {
+			stats.Consented++
+			latest(*user.MarketingConsent)
+		}
+	}
+
+	return stats
+}
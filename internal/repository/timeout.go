@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// Default timeouts of database operations
+const (
+	DefaultTimeout     = 5 * time.Second // Lookups, writes and health checks of a single guest
+	DefaultBulkTimeout = time.Minute     // Reports, statistics, batch writes and migrations
+)
+
+// TimeoutSetter is implemented by repositories whose database operations
+// time out
+type TimeoutSetter interface {
+	SetTimeouts(timeout, bulkTimeout time.Duration)
+}
+
+// timeouts holds the timeouts of a repository's database operations
+type timeouts struct {
+	timeout     time.Duration
+	bulkTimeout time.Duration
+}
+
+// defaultTimeouts returns the timeouts used until SetTimeouts is called
+func defaultTimeouts() timeouts {
+	return timeouts{timeout: DefaultTimeout, bulkTimeout: DefaultBulkTimeout}
+}
+
+// SetTimeouts sets the timeouts of single guest and bulk operations. A
+// timeout <= 0 keeps the current one.
+func (t *timeouts) SetTimeouts(timeout, bulkTimeout time.Duration) {
+	if timeout > 0 {
+		t.timeout = timeout
+	}
+	if bulkTimeout > 0 {
+		t.bulkTimeout = bulkTimeout
+	}
+}
+
+// queryContext returns a context for an operation on a single guest
+func (t *timeouts) queryContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), t.timeout)
+}
+
+// bulkContext returns a context for an operation on many guests
+func (t *timeouts) bulkContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), t.bulkTimeout)
+}
@@ -0,0 +1,153 @@
+// Package richtext formats outgoing chat messages for Telegram's HTML and
+// MarkdownV2 parse modes. Text from templates and users, such as emails and
+// dates, is escaped, so it cannot break the markup or inject links. Markup
+// is only added through Fragments.
+package richtext
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mode is a Telegram parse mode
+type Mode string
+
+// Supported modes
+const (
+	Plain      Mode = ""
+	HTML       Mode = "HTML"
+	MarkdownV2 Mode = "MarkdownV2"
+)
+
+// ParseMode validates a configured mode. Names are case-insensitive, and
+// "plain" or an empty string select plain text.
+func ParseMode(name string) (Mode, error) {
+	switch strings.ToLower(name) {
+	case "", "plain", "none":
+		return Plain, nil
+	case "html":
+		return HTML, nil
+	case "markdownv2", "markdown":
+		return MarkdownV2, nil
+	default:
+		return Plain, fmt.Errorf("unsupported parse mode %q, use plain, html or markdownv2", name)
+	}
+}
+
+// Fragment is text already formatted for a mode. It is inserted into
+// messages as is.
+type Fragment string
+
+// markdownSpecial are the characters MarkdownV2 requires to be escaped
+// outside of entities
+const markdownSpecial = "_*[]()~`>#+-=|{}.!\\"
+
+// Escape makes text safe to send in the mode
+func Escape(mode Mode, text string) string {
+	switch mode {
+	case HTML:
+		return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+	case MarkdownV2:
+		var b strings.Builder
+		for _, r := range text {
+			if strings.ContainsRune(markdownSpecial, r) {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	default:
+		return text
+	}
+}
+
+// Bold returns text shown in bold
+func Bold(mode Mode, text string) Fragment {
+	switch mode {
+	case HTML:
+		return Fragment("<b>" + Escape(mode, text) + "</b>")
+	case MarkdownV2:
+		return Fragment("*" + Escape(mode, text) + "*")
+	default:
+		return Fragment(text)
+	}
+}
+
+// Italic returns text shown in italics
+func Italic(mode Mode, text string) Fragment {
+	switch mode {
+	case HTML:
+		return Fragment("<i>" + Escape(mode, text) + "</i>")
+	case MarkdownV2:
+		return Fragment("_" + Escape(mode, text) + "_")
+	default:
+		return Fragment(text)
+	}
+}
+
+// Code returns text shown in a monospace font, such as a code or an email
+func Code(mode Mode, text string) Fragment {
+	switch mode {
+	case HTML:
+		return Fragment("<code>" + Escape(mode, text) + "</code>")
+	case MarkdownV2:
+		// Inside code entities only ` and \ are escaped
+		return Fragment("`" + strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(text) + "`")
+	default:
+		return Fragment(text)
+	}
+}
+
+// Link returns a clickable label. In plain text the URL follows the label.
+func Link(mode Mode, label, url string) Fragment {
+	switch mode {
+	case HTML:
+		quoted := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(url)
+		return Fragment(`<a href="` + quoted + `">` + Escape(mode, label) + "</a>")
+	case MarkdownV2:
+		// Inside the URL part only ) and \ are escaped
+		escaped := strings.NewReplacer("\\", "\\\\", ")", "\\)").Replace(url)
+		return Fragment("[" + Escape(mode, label) + "](" + escaped + ")")
+	default:
+		return Fragment(label + ": " + url)
+	}
+}
+
+// Format fills the {name} placeholders of a template with name/value
+// pairs. The template and string values are escaped, Fragment values are
+// inserted as is. Placeholders without a value are kept, escaped.
+func Format(mode Mode, template string, args ...any) string {
+	values := make(map[string]string, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		name := fmt.Sprint(args[i])
+		switch value := args[i+1].(type) {
+		case Fragment:
+			values[name] = string(value)
+		default:
+			values[name] = Escape(mode, fmt.Sprint(value))
+		}
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		b.WriteString(Escape(mode, template[:start]))
+		if value, ok := values[template[start+1:end]]; ok {
+			b.WriteString(value)
+		} else {
+			b.WriteString(Escape(mode, template[start:end+1]))
+		}
+		template = template[end+1:]
+	}
+	b.WriteString(Escape(mode, template))
+	return b.String()
+}
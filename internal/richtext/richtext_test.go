@@ -0,0 +1,57 @@
+package richtext
+
+import "testing"
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		mode Mode
+		in   string
+		want string
+	}{
+		{HTML, "a<b>&c", "a&lt;b&gt;&amp;c"},
+		{MarkdownV2, "first_name+tag@example.com (VIP)!", "first\\_name\\+tag@example\\.com \\(VIP\\)\\!"},
+		{Plain, "a_b <c>", "a_b <c>"},
+	}
+	for _, tt := range tests {
+		if got := Escape(tt.mode, tt.in); got != tt.want {
+			t.Errorf("Escape(%q, %q) = %q, want %q", tt.mode, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	template := "Redeemed by {email} on {date}. See {help} {missing}"
+	tests := []struct {
+		mode Mode
+		want string
+	}{
+		{HTML, `Redeemed by <code>a&lt;b&gt;@example.com</code> on <b>2025-06-01</b>. See <a href="https://example.com/?a=1&amp;b=2">help</a> {missing}`},
+		{MarkdownV2, "Redeemed by `a<b>@example.com` on *2025\\-06\\-01*\\. See [help](https://example.com/?a=1&b=2) \\{missing\\}"},
+		{Plain, "Redeemed by a<b>@example.com on 2025-06-01. See help: https://example.com/?a=1&b=2 {missing}"},
+	}
+	for _, tt := range tests {
+		got := Format(tt.mode, template,
+			"email", Code(tt.mode, "a<b>@example.com"),
+			"date", Bold(tt.mode, "2025-06-01"),
+			"help", Link(tt.mode, "help", "https://example.com/?a=1&b=2"))
+		if got != tt.want {
+			t.Errorf("Format(%q) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+
+	// String values are escaped like the template
+	if got := Format(HTML, "Hi {name}", "name", "<b>Eve</b>"); got != "Hi &lt;b&gt;Eve&lt;/b&gt;" {
+		t.Errorf("Expected the value to be escaped, got %q", got)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for name, want := range map[string]Mode{"": Plain, "plain": Plain, "HTML": HTML, "markdownv2": MarkdownV2} {
+		if got, err := ParseMode(name); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v", name, got, err)
+		}
+	}
+	if _, err := ParseMode("bbcode"); err == nil {
+		t.Error("Expected an error for an unsupported mode")
+	}
+}
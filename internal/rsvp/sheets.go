@@ -0,0 +1,53 @@
+package rsvp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// SheetSource reads RSVP rows from a Google Sheet, e.g. the responses of a Google Form
+type SheetSource struct {
+	service       *sheets.Service
+	spreadsheetID string
+	sheet         string
+}
+
+// NewSheetSource connects to the configured spreadsheet
+func NewSheetSource(ctx context.Context, cfg config.RSVPImportConfig) (*SheetSource, error) {
+	if cfg.SpreadsheetID == "" {
+		return nil, errors.New("spreadsheet ID cannot be empty")
+	}
+
+	service, err := sheets.NewService(ctx, option.WithCredentialsFile(cfg.CredentialsFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Google Sheets service: %w", err)
+	}
+
+	return &SheetSource{
+		service:       service,
+		spreadsheetID: cfg.SpreadsheetID,
+		sheet:         cfg.Sheet,
+	}, nil
+}
+
+// Rows returns all rows of the sheet as strings
+func (s *SheetSource) Rows(ctx context.Context) ([][]string, error) {
+	resp, err := s.service.Spreadsheets.Values.Get(s.spreadsheetID, s.sheet).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, len(resp.Values))
+	for i, row := range resp.Values {
+		rows[i] = make([]string, len(row))
+		for j, cell := range row {
+			rows[i][j] = fmt.Sprint(cell)
+		}
+	}
+	return rows, nil
+}
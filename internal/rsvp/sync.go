@@ -0,0 +1,264 @@
+package rsvp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// RowSource provides the rows of an RSVP sheet, header row first
+type RowSource interface {
+	Rows(ctx context.Context) ([][]string, error)
+}
+
+// UserStore is where imported guests are added
+type UserStore interface {
+	FindUser(ctx context.Context, email string) (*domain.User, error)
+	AddUser(ctx context.Context, user *domain.User) error
+}
+
+// Result summarizes a single sync run
+type Result struct {
+	Imported  int // New guests added
+	Duplicate int // Emails already in the repository or seen earlier in the sheet
+	Invalid   int // Rows without a valid email
+	LastRow   int // Last processed row, 1-based including the header
+}
+
+// bookmark is the persisted sync position
+type bookmark struct {
+	Row      int       `json:"row"`
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+// Syncer periodically imports new rows of an RSVP sheet into the repository
+type Syncer struct {
+	cfg    config.RSVPImportConfig
+	source RowSource
+	store  UserStore
+	logger *logger.Logger
+
+	mu        sync.Mutex // Serializes sync runs
+	stopCh    chan struct{}
+	waitGroup sync.WaitGroup
+}
+
+// NewSyncer creates a syncer for the configured sheet
+func NewSyncer(cfg config.RSVPImportConfig, source RowSource, store UserStore, logger *logger.Logger) (*Syncer, error) {
+	if source == nil || store == nil {
+		return nil, errors.New("row source and user store are required")
+	}
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+	if cfg.EmailColumn == "" {
+		return nil, errors.New("email column cannot be empty")
+	}
+
+	return &Syncer{
+		cfg:    cfg,
+		source: source,
+		store:  store,
+		logger: logger,
+		stopCh: make(chan struct{}),
+	}, nil
+}
+
+// emailColumnIndex finds the email column by header name, or by column letter
+func emailColumnIndex(header []string, column string) (int, error) {
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(column)) {
+			return i, nil
+		}
+	}
+
+	letter := strings.ToUpper(strings.TrimSpace(column))
+	if len(letter) == 1 && letter[0] >= 'A' && letter[0] <= 'Z' {
+		return int(letter[0] - 'A'), nil
+	}
+
+	return 0, fmt.Errorf("email column %q not found in sheet header", column)
+}
+
+// loadBookmark returns the last processed row, 0 if none
+func (s *Syncer) loadBookmark() (int, error) {
+	if s.cfg.BookmarkFile == "" {
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(s.cfg.BookmarkFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read bookmark: %w", err)
+	}
+
+	var b bookmark
+	if err := json.Unmarshal(data, &b); err != nil {
+		return 0, fmt.Errorf("failed to parse bookmark: %w", err)
+	}
+	return b.Row, nil
+}
+
+// saveBookmark persists the last processed row
+func (s *Syncer) saveBookmark(row int) error {
+	if s.cfg.BookmarkFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(bookmark{Row: row, SyncedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.cfg.BookmarkFile), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.cfg.BookmarkFile, data, 0644)
+}
+
+// SyncOnce imports rows added since the last run. If the repository fails,
+// the bookmark stays at the last imported row so the rest is retried later.
+func (s *Syncer) SyncOnce(ctx context.Context) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start, err := s.loadBookmark()
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{LastRow: start}
+
+	rows, err := s.source.Rows(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to read RSVP sheet: %w", err)
+	}
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	column, err := emailColumnIndex(rows[0], s.cfg.EmailColumn)
+	if err != nil {
+		return result, err
+	}
+
+	// The header is row 1
+	if result.LastRow < 1 {
+		result.LastRow = 1
+	}
+
+	seen := make(map[string]bool)
+	for i := result.LastRow; i < len(rows); i++ {
+		row := rows[i]
+
+		var email string
+		if column < len(row) {
+			email = utils.NormalizeEmail(row[column])
+		}
+
+		switch {
+		case !utils.IsValidEmail(email):
+			result.Invalid++
+			s.logger.Debug("Skipping RSVP row without valid email", "row", i+1)
+		case seen[email]:
+			result.Duplicate++
+		default:
+			seen[email] = true
+
+			_, err := s.store.FindUser(ctx, email)
+			if err == nil {
+				result.Duplicate++
+				break
+			}
+			if !errors.Is(err, domain.ErrUserNotFound) {
+				return result, s.stopAt(result, err)
+			}
+
+			user := &domain.User{
+				ID:        fmt.Sprintf("rsvp_%d", time.Now().UnixNano()),
+				Email:     email,
+				DateAdded: time.Now(),
+				CreatedBy: "rsvp_import",
+			}
+			if err := s.store.AddUser(ctx, user); err != nil {
+				if errors.Is(err, domain.ErrEmailDenied) {
+					result.Invalid++
+					break
+				}
+				if domain.IsDuplicateUser(err) {
+					// Added by someone else since the lookup
+					result.Duplicate++
+					break
+				}
+				return result, s.stopAt(result, err)
+			}
+			result.Imported++
+		}
+
+		result.LastRow = i + 1
+	}
+
+	if err := s.saveBookmark(result.LastRow); err != nil {
+		return result, fmt.Errorf("failed to save bookmark: %w", err)
+	}
+
+	s.logger.Info("RSVP sheet synced", "imported", result.Imported, "duplicate", result.Duplicate,
+		"invalid", result.Invalid, "last_row", result.LastRow)
+	return result, nil
+}
+
+// stopAt saves the bookmark after a repository failure and returns the cause
+func (s *Syncer) stopAt(result Result, cause error) error {
+	if err := s.saveBookmark(result.LastRow); err != nil {
+		s.logger.Error("Failed to save RSVP bookmark", "error", err)
+	}
+	s.logger.Error("RSVP sync stopped", "row", result.LastRow+1, "imported", result.Imported, "error", cause)
+	return cause
+}
+
+// Start runs a sync immediately and then on the configured interval
+func (s *Syncer) Start() {
+	interval := time.Duration(s.cfg.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	s.waitGroup.Add(1)
+	go func() {
+		defer s.waitGroup.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.SyncOnce(context.Background()); err != nil {
+				s.logger.Error("RSVP sync failed", "error", err)
+			}
+
+			select {
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	s.logger.Info("RSVP import started", "interval", interval)
+}
+
+// Stop waits for a running sync to finish and stops the schedule
+func (s *Syncer) Stop() {
+	close(s.stopCh)
+	s.waitGroup.Wait()
+}
@@ -0,0 +1,108 @@
+package rsvp_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/rsvp"
+)
+
+// staticSource returns fixed rows
+type staticSource struct {
+	rows [][]string
+}
+
+func (s *staticSource) Rows(ctx context.Context) ([][]string, error) {
+	return s.rows, nil
+}
+
+// memoryStore keeps users in a map and can simulate an outage
+type memoryStore struct {
+	users map[string]*domain.User
+	down  bool
+}
+
+func (s *memoryStore) FindUser(ctx context.Context, email string) (*domain.User, error) {
+	if s.down {
+		return nil, domain.ErrDatabaseUnavailable
+	}
+	user, ok := s.users[email]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *memoryStore) AddUser(ctx context.Context, user *domain.User) error {
+	if s.down {
+		return domain.ErrDatabaseUnavailable
+	}
+	s.users[user.Email] = user
+	return nil
+}
+
+func TestSyncer(t *testing.T) {
+	source := &staticSource{rows: [][]string{
+		{"Timestamp", "Email Address", "Name"},
+		{"2025/06/01", "Guest1@Example.com", "One"},
+		{"2025/06/01", "not an email", "Two"},
+		{"2025/06/02", "existing@example.com", "Three"},
+		{"2025/06/02", "guest1@example.com", "Four"},
+	}}
+	store := &memoryStore{users: map[string]*domain.User{
+		"existing@example.com": {ID: "1", Email: "existing@example.com"},
+	}}
+	cfg := config.RSVPImportConfig{
+		EmailColumn:  "email address",
+		BookmarkFile: filepath.Join(t.TempDir(), "bookmark.json"),
+	}
+
+	syncer, err := rsvp.NewSyncer(cfg, source, store, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create syncer: %v", err)
+	}
+	ctx := context.Background()
+
+	result, err := syncer.SyncOnce(ctx)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Imported != 1 || result.Duplicate != 2 || result.Invalid != 1 || result.LastRow != 5 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if _, ok := store.users["guest1@example.com"]; !ok {
+		t.Error("Expected normalized email to be imported")
+	}
+
+	// Rows before the bookmark are not processed again
+	source.rows = append(source.rows, []string{"2025/06/03", "guest2@example.com", "Five"})
+	result, err = syncer.SyncOnce(ctx)
+	if err != nil || result.Imported != 1 || result.Duplicate != 0 || result.LastRow != 6 {
+		t.Errorf("Unexpected second result: %+v (%v)", result, err)
+	}
+
+	// An outage keeps the bookmark so the row is retried
+	source.rows = append(source.rows, []string{"2025/06/04", "guest3@example.com", "Six"})
+	store.down = true
+	if _, err := syncer.SyncOnce(ctx); !errors.Is(err, domain.ErrDatabaseUnavailable) {
+		t.Errorf("Expected database error, got %v", err)
+	}
+	store.down = false
+	result, err = syncer.SyncOnce(ctx)
+	if err != nil || result.Imported != 1 || result.LastRow != 7 {
+		t.Errorf("Expected retried row to be imported, got %+v (%v)", result, err)
+	}
+
+	// Columns can also be given as a letter
+	cfg.EmailColumn = "B"
+	cfg.BookmarkFile = ""
+	syncer, _ = rsvp.NewSyncer(cfg, source, store, logger.New("error"))
+	if result, err := syncer.SyncOnce(ctx); err != nil || result.Duplicate != 5 {
+		t.Errorf("Unexpected result with column letter: %+v (%v)", result, err)
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/analytics"
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// archiveStore keeps the records of archived events
+type archiveStore struct {
+	path    string // JSON file the records are saved to, empty keeps them in memory only
+	mu      sync.Mutex
+	entries []domain.EventArchive
+}
+
+// loadArchives reads the events archived by a previous run
+func loadArchives(path string) (*archiveStore, error) {
+	store := &archiveStore{path: path}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event archive file: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse event archive file: %w", err)
+	}
+	return store, nil
+}
+
+// find returns the archive record of the event, or nil if it is active
+func (a *archiveStore) find(event string) *domain.EventArchive {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := range a.entries {
+		if a.entries[i].Event == event {
+			entry := a.entries[i]
+			return &entry
+		}
+	}
+	return nil
+}
+
+// list returns all archive records, oldest first
+func (a *archiveStore) list() []domain.EventArchive {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]domain.EventArchive(nil), a.entries...)
+}
+
+// add stores an archive record unless the event is already archived
+func (a *archiveStore) add(entry domain.EventArchive) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, existing := range a.entries {
+		if existing.Event == entry.Event {
+			return domain.ErrEventArchived
+		}
+	}
+	a.entries = append(a.entries, entry)
+	if err := a.save(); err != nil {
+		a.entries = a.entries[:len(a.entries)-1]
+		return err
+	}
+	return nil
+}
+
+// save writes all records to the file. The caller must hold mu.
+func (a *archiveStore) save() error {
+	if a.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(a.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash cannot leave a truncated file
+	tmp := a.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, a.path)
+}
+
+// EventArchived returns the archive record of the current event, or nil
+// while it is active
+func (s *Service) EventArchived() *domain.EventArchive {
+	return s.archives.find(s.event)
+}
+
+// EventArchives lists all archived events, oldest first
+func (s *Service) EventArchives() []domain.EventArchive {
+	return s.archives.list()
+}
+
+// ArchiveEvent freezes the records of the current event: guests can no
+// longer be added or redeem. With export, a final report bundle is written
+// to the archive directory first.
+func (s *Service) ArchiveEvent(ctx context.Context, actor string, export bool) (domain.EventArchive, error) {
+	if s.EventArchived() != nil {
+		return domain.EventArchive{}, domain.ErrEventArchived
+	}
+
+	entry := domain.EventArchive{
+		Event:      s.event,
+		ArchivedAt: time.Now(),
+		Actor:      actor,
+	}
+
+	if export {
+		bundle, err := s.exportBundle(ctx, entry)
+		if err != nil {
+			s.log(ctx).Error("Error exporting event bundle", "event", s.event, "error", err)
+			return domain.EventArchive{}, err
+		}
+		entry.Bundle = bundle
+	}
+
+	if err := s.archives.add(entry); err != nil {
+		return domain.EventArchive{}, err
+	}
+
+	s.log(ctx).Info("Audit: event archived", "actor", actor, "event", s.event, "bundle", entry.Bundle)
+	s.recordAudit(ctx, actor, audit.ActionArchiveEvent, "", s.event)
+	return entry, nil
+}
+
+// bundleStats is the content of stats.json in a report bundle
+type bundleStats struct {
+	Event      string               `json:"event"`
+	ArchivedAt time.Time            `json:"archived_at"`
+	Guests     int                  `json:"guests"`
+	Redeemed   int                  `json:"redeemed"`
+	Consented  int                  `json:"consented"`
+	Engagement analytics.Engagement `json:"engagement"`
+}
+
+// exportBundle writes the guest list and statistics of the event to a new
+// directory and returns its path
+func (s *Service) exportBundle(ctx any, entry domain.EventArchive) (string, error) {
+	users, err := s.repo.GetReport(ctx, domain.ReportParams{Type: domain.ReportTypeAll, To: entry.ArchivedAt})
+	if err != nil {
+		return "", err
+	}
+
+	name := utils.Slug(entry.Event) + "-" + entry.ArchivedAt.Format("20060102-150405")
+	dir := filepath.Join(s.archiveDir, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+
+	stats := bundleStats{
+		Event:      entry.Event,
+		ArchivedAt: entry.ArchivedAt,
+		Guests:     len(users),
+		Engagement: s.analytics.Snapshot(),
+	}
+
+	file, err := os.Create(filepath.Join(dir, "guests.csv"))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"ID", "Email", "DateAdded", "Redeemed", "MarketingConsent", "UpdatedAt", "CreatedBy", "Bar", "FirstName", "LastName"})
+	for _, user := range users {
+		redeemed, consent := "", ""
+		if user.Redeemed != nil {
+			redeemed = user.Redeemed.Format(time.RFC3339)
+			stats.Redeemed++
+		}
+		if user.MarketingConsent != nil {
+			consent = user.MarketingConsent.Format(time.RFC3339)
+			stats.Consented++
+		}
+		writer.Write([]string{user.ID, user.Email, user.DateAdded.Format(time.RFC3339), redeemed, consent,
+			user.UpdatedAt.Format(time.RFC3339Nano), user.CreatedBy, user.RedeemedAt, user.FirstName, user.LastName})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", fmt.Errorf("failed to write guest list: %w", err)
+	}
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "stats.json"), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write stats: %w", err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,114 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestArchiveEvent(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.New()
+	cfg.Database.Type = "csv"
+	cfg.Database.ConnectionString = filepath.Join(dir, "users.csv")
+	cfg.Database.DeadLetterFile = ""
+	cfg.Event.Name = "Summer Launch 2025"
+	cfg.Event.ArchiveFile = filepath.Join(dir, "archives.json")
+	cfg.Event.ArchiveDir = filepath.Join(dir, "bundles")
+	cfg.Event.AuditFile = filepath.Join(dir, "audit.jsonl")
+
+	ctx := context.Background()
+	svc, err := service.New(ctx, cfg, logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	for _, email := range []string{"first@example.com", "second@example.com"} {
+		if err := svc.AddUser(ctx, &domain.User{ID: email[:5], Email: email, DateAdded: time.Now()}); err != nil {
+			t.Fatalf("Failed to add %s: %v", email, err)
+		}
+	}
+	if _, err := svc.RedeemCocktail(ctx, 1, "first@example.com"); err != nil {
+		t.Fatalf("Failed to redeem: %v", err)
+	}
+
+	if svc.EventArchived() != nil {
+		t.Fatal("Expected the event to be active")
+	}
+	archive, err := svc.ArchiveEvent(ctx, "token:abc", true)
+	if err != nil {
+		t.Fatalf("Failed to archive event: %v", err)
+	}
+	if archive.Event != "Summer Launch 2025" || archive.Actor != "token:abc" {
+		t.Errorf("Unexpected archive record: %+v", archive)
+	}
+	if _, err := svc.ArchiveEvent(ctx, "token:abc", false); err != domain.ErrEventArchived {
+		t.Errorf("Expected ErrEventArchived when archiving twice, got %v", err)
+	}
+
+	// The bundle holds the guest list and statistics
+	if !strings.HasPrefix(filepath.Base(archive.Bundle), "summer-launch-2025-") {
+		t.Errorf("Unexpected bundle directory %q", archive.Bundle)
+	}
+	guests, err := os.ReadFile(filepath.Join(archive.Bundle, "guests.csv"))
+	if err != nil {
+		t.Fatalf("Failed to read guest list: %v", err)
+	}
+	if lines := strings.Count(string(guests), "\n"); lines != 3 {
+		t.Errorf("Expected a header and 2 guests, got %d lines", lines)
+	}
+	data, err := os.ReadFile(filepath.Join(archive.Bundle, "stats.json"))
+	if err != nil {
+		t.Fatalf("Failed to read stats: %v", err)
+	}
+	var stats struct {
+		Guests   int `json:"guests"`
+		Redeemed int `json:"redeemed"`
+	}
+	if err := json.Unmarshal(data, &stats); err != nil || stats.Guests != 2 || stats.Redeemed != 1 {
+		t.Errorf("Unexpected stats %s: %v", data, err)
+	}
+
+	// The records are frozen
+	if status, _, _ := svc.CheckEmailStatus(ctx, 2, "second@example.com"); status != "archived" {
+		t.Errorf("Expected status archived, got %s", status)
+	}
+	if _, err := svc.RedeemCocktail(ctx, 2, "second@example.com"); err != domain.ErrEventArchived {
+		t.Errorf("Expected ErrEventArchived on redemption, got %v", err)
+	}
+	if err := svc.AddUser(ctx, &domain.User{ID: "third", Email: "third@example.com", DateAdded: time.Now()}); err != domain.ErrEventArchived {
+		t.Errorf("Expected ErrEventArchived when adding a guest, got %v", err)
+	}
+	if err := svc.SetMarketingConsent(ctx, 2, "second@example.com", true); err != domain.ErrEventArchived {
+		t.Errorf("Expected ErrEventArchived on consent, got %v", err)
+	}
+	svc.Close()
+
+	// The archive survives a restart, and a new event is active again
+	reopened, err := service.New(ctx, cfg, logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to reopen service: %v", err)
+	}
+	defer reopened.Close()
+	if reopened.EventArchived() == nil || len(reopened.EventArchives()) != 1 {
+		t.Error("Expected the archive to be loaded")
+	}
+
+	cfg.Event.Name = "Winter Launch 2025"
+	next, err := service.New(ctx, cfg, logger.New("info"))
+	if err != nil {
+		t.Fatalf("Failed to open next event: %v", err)
+	}
+	defer next.Close()
+	if next.EventArchived() != nil {
+		t.Error("Expected the next event to be active")
+	}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// recordAudit appends an action to the audit log. The actor is taken from
+// the context, such as the API token of a request, or else fallback. A
+// failed write is logged but does not undo the action.
+func (s *Service) recordAudit(ctx any, fallback, action, email, details string) {
+	s.writeAudit(ctx, fallback, audit.Entry{Action: action, Email: email, Details: details})
+}
+
+// recordUserAudit appends a change of a guest record to the audit log,
+// keeping a copy of the record as it is after the change
+func (s *Service) recordUserAudit(ctx any, fallback, action string, user *domain.User, details string) {
+	snapshot := *user
+	s.writeAudit(ctx, fallback, audit.Entry{Action: action, Email: user.Email, Details: details, User: &snapshot})
+}
+
+// writeAudit sets the actor of an entry and appends it to the audit log
+func (s *Service) writeAudit(ctx any, fallback string, entry audit.Entry) {
+	entry.Actor = audit.ActorFromContext(ctx, fallback)
+	if entry.Operator == "" {
+		entry.Operator = audit.OperatorFromContext(ctx)
+	}
+	if err := s.audit.Record(entry); err != nil {
+		s.log(ctx).Error("Error writing audit log", "action", entry.Action, "email", entry.Email, "error", err)
+	}
+}
+
+// AuditLog returns the recorded actions matching the filter, newest first,
+// and the number of matching actions before paging
+func (s *Service) AuditLog(ctx context.Context, filter audit.Filter) ([]audit.Entry, int, error) {
+	entries, total, err := s.audit.Query(filter)
+	if err != nil {
+		s.log(ctx).Error("Error reading audit log", "error", err)
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// SetBars replaces the bars staff can choose from when redeeming
+func (s *Service) SetBars(bars []string) {
+	s.bars = bars
+}
+
+// knownBar returns true if the bar is one of the configured bars
+func (s *Service) knownBar(bar string) bool {
+	for _, known := range s.bars {
+		if known == bar {
+			return true
+		}
+	}
+	return false
+}
+
+// RedemptionsByBar counts the redeemed guests of a report per bar. Every
+// configured bar is listed in the configured order, followed by bars no
+// longer configured and, last, redemptions recorded without a bar.
+func (s *Service) RedemptionsByBar(ctx context.Context, fromDate, toDate time.Time, filter domain.ReportFilter) ([]domain.BarRedemptions, error) {
+	users, err := s.GenerateReport(ctx, string(domain.ReportTypeRedeemed), fromDate, toDate, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, user := range users {
+		counts[user.RedeemedAt]++
+	}
+
+	breakdown := make([]domain.BarRedemptions, 0, len(s.bars)+len(counts))
+	for _, bar := range s.bars {
+		breakdown = append(breakdown, domain.BarRedemptions{Bar: bar, Redeemed: counts[bar]})
+		delete(counts, bar)
+	}
+	var others []string
+	for bar := range counts {
+		if bar != "" {
+			others = append(others, bar)
+		}
+	}
+	sort.Strings(others)
+	for _, bar := range others {
+		breakdown = append(breakdown, domain.BarRedemptions{Bar: bar, Redeemed: counts[bar]})
+	}
+	if counts[""] > 0 {
+		breakdown = append(breakdown, domain.BarRedemptions{Redeemed: counts[""]})
+	}
+	return breakdown, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// blocklist holds Telegram users the bot does not serve and emails that
+// cannot be added or redeemed. Changes made at runtime last until restart.
+type blocklist struct {
+	mu     sync.RWMutex
+	users  map[int64]bool
+	emails map[string]bool // Normalized addresses, or domains starting with "@"
+}
+
+// newBlocklist creates a block list with the configured entries
+func newBlocklist(users []int64, emails []string) *blocklist {
+	b := &blocklist{
+		users:  make(map[int64]bool),
+		emails: make(map[string]bool),
+	}
+	for _, userID := range users {
+		b.users[userID] = true
+	}
+	for _, email := range emails {
+		b.emails[utils.NormalizeEmail(email)] = true
+	}
+	return b
+}
+
+// emailDenied returns true if the address or its domain is denied
+func (b *blocklist) emailDenied(email string) bool {
+	email = utils.NormalizeEmail(email)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.emails[email] {
+		return true
+	}
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		return b.emails[email[at:]]
+	}
+	return false
+}
+
+// SetBlocklist replaces the blocked Telegram users and denied emails
+func (s *Service) SetBlocklist(users []int64, emails []string) {
+	s.blocklist = newBlocklist(users, emails)
+}
+
+// IsUserBlocked returns true if the Telegram user is on the block list
+func (s *Service) IsUserBlocked(userID int64) bool {
+	s.blocklist.mu.RLock()
+	defer s.blocklist.mu.RUnlock()
+	return s.blocklist.users[userID]
+}
+
+// BlockUser adds a Telegram user to the block list
+func (s *Service) BlockUser(userID int64) {
+	s.blocklist.mu.Lock()
+	s.blocklist.users[userID] = true
+	s.blocklist.mu.Unlock()
+
+	s.logger.Info("Telegram user blocked", "user_id", userID)
+}
+
+// UnblockUser removes a Telegram user from the block list
+func (s *Service) UnblockUser(userID int64) {
+	s.blocklist.mu.Lock()
+	delete(s.blocklist.users, userID)
+	s.blocklist.mu.Unlock()
+
+	s.logger.Info("Telegram user unblocked", "user_id", userID)
+}
+
+// DenyEmail adds an address, or a domain written as "@example.com", to the deny list
+func (s *Service) DenyEmail(email string) {
+	email = utils.NormalizeEmail(email)
+
+	s.blocklist.mu.Lock()
+	s.blocklist.emails[email] = true
+	s.blocklist.mu.Unlock()
+
+	s.logger.Info("Email denied", "email", email)
+}
+
+// AllowEmail removes an address or domain from the deny list
+func (s *Service) AllowEmail(email string) {
+	email = utils.NormalizeEmail(email)
+
+	s.blocklist.mu.Lock()
+	delete(s.blocklist.emails, email)
+	s.blocklist.mu.Unlock()
+
+	s.logger.Info("Email allowed", "email", email)
+}
@@ -0,0 +1,52 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestBlocklist(t *testing.T) {
+	mockRepo := newMockRepository()
+	mockRepo.users["guest@example.com"] = &domain.User{ID: "1", Email: "guest@example.com", DateAdded: time.Now()}
+	mockRepo.users["vip@spam.example"] = &domain.User{ID: "2", Email: "vip@spam.example", DateAdded: time.Now()}
+
+	svc := service.NewForTest(mockRepo, ratelimit.New(100, 1000), logger.New("error"))
+	svc.SetBlocklist([]int64{42}, []string{"Guest@Example.com", "@spam.example"})
+	ctx := context.Background()
+
+	if !svc.IsUserBlocked(42) || svc.IsUserBlocked(43) {
+		t.Error("Unexpected blocked users")
+	}
+	svc.UnblockUser(42)
+	svc.BlockUser(43)
+	if svc.IsUserBlocked(42) || !svc.IsUserBlocked(43) {
+		t.Error("Block list changes not applied")
+	}
+
+	// Denied addresses and domains are never eligible
+	for _, email := range []string{"guest@example.com", "vip@spam.example"} {
+		if status, _, _ := svc.CheckEmailStatus(ctx, 1, email); status != "denied" {
+			t.Errorf("Expected %s to be denied, got %s", email, status)
+		}
+		if _, err := svc.RedeemCocktail(ctx, 1, email); !errors.Is(err, domain.ErrEmailDenied) {
+			t.Errorf("Expected redemption of %s to be denied, got %v", email, err)
+		}
+	}
+	err := svc.AddUser(ctx, &domain.User{ID: "3", Email: "new@spam.example", DateAdded: time.Now()})
+	if !errors.Is(err, domain.ErrEmailDenied) {
+		t.Errorf("Expected adding a denied email to fail, got %v", err)
+	}
+
+	// Allowed again after removal from the deny list
+	svc.AllowEmail("guest@example.com")
+	if status, _, _ := svc.CheckEmailStatus(ctx, 1, "guest@example.com"); status != "eligible" {
+		t.Errorf("Expected guest to be eligible again, got %s", status)
+	}
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/notify"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// alertTimeout bounds how long a single alerter may take
+const alertTimeout = 10 * time.Second
+
+// deadLetterStore keeps failed redemptions until staff retry or resolve them
+type deadLetterStore struct {
+	path    string // JSON file the entries are saved to, empty keeps them in memory only
+	mu      sync.Mutex
+	entries []domain.FailedRedemption
+	nextID  int
+}
+
+// loadDeadLetters reads failed redemptions saved by a previous run
+func loadDeadLetters(path string) (*deadLetterStore, error) {
+	store := &deadLetterStore{path: path, nextID: 1}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter file: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse dead letter file: %w", err)
+	}
+
+	for _, entry := range store.entries {
+		if id, err := strconv.Atoi(entry.ID); err == nil && id >= store.nextID {
+			store.nextID = id + 1
+		}
+	}
+	return store, nil
+}
+
+// save writes all entries to the file. The caller must hold mu.
+func (d *deadLetterStore) save() error {
+	if d.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(d.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash cannot leave a truncated file
+	tmp := d.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.path)
+}
+
+// add stores a new failed redemption and returns it with its ID set
+func (d *deadLetterStore) add(entry domain.FailedRedemption) (domain.FailedRedemption, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry.ID = strconv.Itoa(d.nextID)
+	d.nextID++
+	d.entries = append(d.entries, entry)
+	return entry, d.save()
+}
+
+// list returns a copy of all entries, oldest first
+func (d *deadLetterStore) list() []domain.FailedRedemption {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries := make([]domain.FailedRedemption, len(d.entries))
+	copy(entries, d.entries)
+	return entries
+}
+
+// get returns the entry with the given ID
+func (d *deadLetterStore) get(id string) (domain.FailedRedemption, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, entry := range d.entries {
+		if entry.ID == id {
+			return entry, true
+		}
+	}
+	return domain.FailedRedemption{}, false
+}
+
+// put replaces the entry with the same ID
+func (d *deadLetterStore) put(entry domain.FailedRedemption) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := range d.entries {
+		if d.entries[i].ID == entry.ID {
+			d.entries[i] = entry
+			return d.save()
+		}
+	}
+	return domain.ErrFailedRedemptionNotFound
+}
+
+// remove deletes the entry with the given ID
+func (d *deadLetterStore) remove(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := range d.entries {
+		if d.entries[i].ID == id {
+			d.entries = append(d.entries[:i], d.entries[i+1:]...)
+			return d.save()
+		}
+	}
+	return domain.ErrFailedRedemptionNotFound
+}
+
+// AddAlerter registers a sink that is told about failed redemptions
+func (s *Service) AddAlerter(alerter notify.Alerter) {
+	if alerter != nil {
+		s.alerters = append(s.alerters, alerter)
+	}
+}
+
+// alert sends the text to all alerters. Failures are logged only.
+func (s *Service) alert(text string) {
+	for _, alerter := range s.alerters {
+		ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+		if err := alerter.Alert(ctx, text); err != nil {
+			s.logger.Error("Error sending alert", "error", err)
+		}
+		cancel()
+	}
+}
+
+// recordFailedRedemption keeps a redemption that could not be saved and alerts staff
+func (s *Service) recordFailedRedemption(userID int64, email, bar string, cause error) {
+	entry, err := s.deadLetters.add(domain.FailedRedemption{
+		Email:       email,
+		UserID:      userID,
+		AttemptedAt: time.Now(),
+		Error:       cause.Error(),
+		Bar:         bar,
+	})
+	if err != nil {
+		s.logger.Error("Error saving failed redemption", "email", email, "error", err)
+	}
+
+	s.logger.Error("Redemption recorded as failed", "id", entry.ID, "email", email, "user_id", userID)
+	go s.alert(fmt.Sprintf("Redemption #%s for %s (Telegram user %d) could not be saved: %s. Use /failed to retry or resolve it.",
+		entry.ID, email, userID, cause))
+}
+
+// recordSpoolConflict keeps a redemption spooled while the database was
+// down that the database contradicts once it is back, such as a guest
+// redeemed at another bar in the meantime, and alerts staff. The database
+// keeps its redemption until staff resolve the entry.
+func (s *Service) recordSpoolConflict(update, current *domain.User) {
+	cause := fmt.Sprintf("database has a redemption at %s", current.Redeemed.Format(time.RFC3339))
+	if current.RedeemedAt != "" {
+		cause += " at " + current.RedeemedAt
+	}
+	entry, err := s.deadLetters.add(domain.FailedRedemption{
+		Email:       update.Email,
+		AttemptedAt: *update.Redeemed,
+		Error:       cause,
+		Bar:         update.RedeemedAt,
+	})
+	if err != nil {
+		s.logger.Error("Error saving failed redemption", "email", update.Email, "error", err)
+	}
+
+	s.logger.Error("Spooled redemption recorded as failed", "id", entry.ID, "email", update.Email, "cause", cause)
+	go s.alert(fmt.Sprintf("Redemption #%s for %s made while the database was down was not written: the %s. Use /failed to resolve it.",
+		entry.ID, update.Email, cause))
+}
+
+// FailedRedemptions returns the redemptions waiting to be retried or resolved
+func (s *Service) FailedRedemptions() []domain.FailedRedemption {
+	return s.deadLetters.list()
+}
+
+// RetryFailedRedemption writes a failed redemption to the database again
+func (s *Service) RetryFailedRedemption(ctx context.Context, id string) (time.Time, error) {
+	entry, ok := s.deadLetters.get(id)
+	if !ok {
+		return time.Time{}, domain.ErrFailedRedemptionNotFound
+	}
+	if s.EventArchived() != nil {
+		return time.Time{}, domain.ErrEventArchived
+	}
+
+	user, err := s.findGuest(ctx, utils.NormalizeEmail(entry.Email))
+	if err == nil && !user.IsRedeemed() {
+		redeemed := entry.AttemptedAt
+		user.Redeemed = &redeemed
+		user.RedeemedAt = entry.Bar
+		err = s.updateUser(ctx, user)
+	}
+	if err != nil {
+		entry.Retries++
+		if saveErr := s.deadLetters.put(entry); saveErr != nil {
+			s.log(ctx).Error("Error saving failed redemption", "id", id, "error", saveErr)
+		}
+		s.log(ctx).Error("Retry of failed redemption failed", "id", id, "email", entry.Email, "retries", entry.Retries, "error", err)
+		return time.Time{}, err
+	}
+
+	if err := s.deadLetters.remove(id); err != nil {
+		s.log(ctx).Error("Error removing failed redemption", "id", id, "error", err)
+	}
+	s.log(ctx).Info("Failed redemption written", "id", id, "email", entry.Email, "time", *user.Redeemed)
+	s.analytics.RecordRedemption()
+	s.publishRedeemed(ctx, entry.UserID, "user:"+strconv.FormatInt(entry.UserID, 10), user, "")
+	return *user.Redeemed, nil
+}
+
+// ResolveFailedRedemption drops a failed redemption that staff handled manually
+func (s *Service) ResolveFailedRedemption(id string) error {
+	if err := s.deadLetters.remove(id); err != nil {
+		return err
+	}
+	s.logger.Info("Failed redemption resolved manually", "id", id)
+	return nil
+}
@@ -0,0 +1,161 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+// mockAlerter passes alerts to a channel
+type mockAlerter struct {
+	alerts chan string
+}
+
+func (a *mockAlerter) Alert(ctx context.Context, text string) error {
+	a.alerts <- text
+	return nil
+}
+
+func TestFailedRedemptions(t *testing.T) {
+	mockRepo := newMockRepository()
+	mockRepo.users["guest@example.com"] = &domain.User{ID: "1", Email: "guest@example.com", DateAdded: time.Now()}
+	mockRepo.users["other@example.com"] = &domain.User{ID: "2", Email: "other@example.com", DateAdded: time.Now()}
+	mockRepo.updateErr = errors.New("connection reset")
+
+	alerter := &mockAlerter{alerts: make(chan string, 2)}
+	svc := service.NewForTest(mockRepo, ratelimit.New(10, 100), logger.New("error"))
+	svc.AddAlerter(alerter)
+
+	ctx := context.Background()
+	if _, err := svc.RedeemCocktail(ctx, 42, "guest@example.com"); err == nil {
+		t.Fatal("Expected redemption to fail")
+	}
+	if _, err := svc.RedeemCocktail(ctx, 43, "other@example.com"); err == nil {
+		t.Fatal("Expected redemption to fail")
+	}
+
+	select {
+	case text := <-alerter.alerts:
+		if !strings.Contains(text, "@example.com") {
+			t.Errorf("Alert does not name the guest: %q", text)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an alert")
+	}
+
+	failed := svc.FailedRedemptions()
+	if len(failed) != 2 || failed[0].Email != "guest@example.com" || failed[0].UserID != 42 {
+		t.Fatalf("Unexpected failed redemptions: %+v", failed)
+	}
+
+	// A retry that fails again is counted and kept
+	if _, err := svc.RetryFailedRedemption(ctx, failed[0].ID); err == nil {
+		t.Error("Expected retry to fail while the database is down")
+	}
+	if retries := svc.FailedRedemptions()[0].Retries; retries != 1 {
+		t.Errorf("Expected 1 retry, got %d", retries)
+	}
+
+	// A successful retry stores the original redemption time
+	mockRepo.updateErr = nil
+	redeemed, err := svc.RetryFailedRedemption(ctx, failed[0].ID)
+	if err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if !redeemed.Equal(failed[0].AttemptedAt) || !mockRepo.users["guest@example.com"].IsRedeemed() {
+		t.Errorf("Expected redemption at %v, got %v", failed[0].AttemptedAt, redeemed)
+	}
+
+	// Resolving drops the entry without touching the database
+	if err := svc.ResolveFailedRedemption(failed[1].ID); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if mockRepo.users["other@example.com"].IsRedeemed() {
+		t.Error("Resolving should not redeem the user")
+	}
+	if len(svc.FailedRedemptions()) != 0 {
+		t.Errorf("Expected no failed redemptions left, got %+v", svc.FailedRedemptions())
+	}
+	if err := svc.ResolveFailedRedemption(failed[1].ID); !errors.Is(err, domain.ErrFailedRedemptionNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	mockRepo := newMockRepository()
+	mockRepo.users["guest@example.com"] = &domain.User{ID: "1", Email: "guest@example.com", DateAdded: time.Now()}
+	mockRepo.updateErr = errors.New("connection reset")
+	svc := service.NewForTest(mockRepo, ratelimit.New(10, 100), logger.New("error"))
+
+	ctx := context.Background()
+	if _, err := svc.RedeemCocktail(ctx, 42, "guest@example.com"); err == nil {
+		t.Fatal("Expected redemption to fail")
+	}
+
+	status := svc.Status()
+	if status.PendingRedemptions != 1 {
+		t.Errorf("Expected 1 pending redemption, got %d", status.PendingRedemptions)
+	}
+	if len(status.LastErrors) == 0 || !strings.Contains(status.LastErrors[0].Message, "connection reset") {
+		t.Errorf("Expected the database error first, got %+v", status.LastErrors)
+	}
+	if status.Started.IsZero() || status.Started.After(time.Now()) {
+		t.Errorf("Unexpected start time %v", status.Started)
+	}
+}
+
+func TestSpooledRedemptionConflict(t *testing.T) {
+	primary := newMockRepository()
+	primary.users["guest@example.com"] = &domain.User{ID: "1", Email: "guest@example.com", DateAdded: time.Now()}
+	fallback := newMockRepository()
+	fallback.users["guest@example.com"] = &domain.User{ID: "1", Email: "guest@example.com", DateAdded: time.Now()}
+	repo, err := repository.NewFailoverRepository(primary, fallback, 0, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create failover repository: %v", err)
+	}
+
+	alerter := &mockAlerter{alerts: make(chan string, 1)}
+	svc := service.NewForTest(repo, ratelimit.New(10, 100), logger.New("error"))
+	svc.AddAlerter(alerter)
+	ctx := context.Background()
+
+	// The redemption is spooled while the database fails writes
+	primary.updateErr = errors.New("connection reset")
+	redeemed, err := svc.RedeemCocktail(ctx, 42, "guest@example.com")
+	if err != nil {
+		t.Fatalf("Expected the redemption to be spooled, got %v", err)
+	}
+
+	// Another instance redeemed the guest in the meantime
+	other := redeemed.Add(-time.Hour)
+	primary.users["guest@example.com"].Redeemed = &other
+	primary.updateErr = nil
+
+	// The replay keeps the database's redemption and records the spooled one
+	if _, _, err := svc.CheckEmailStatus(ctx, 42, "guest@example.com"); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	failed := svc.FailedRedemptions()
+	if len(failed) != 1 || failed[0].Email != "guest@example.com" || !failed[0].AttemptedAt.Equal(redeemed) {
+		t.Fatalf("Expected the spooled redemption to be recorded as failed, got %+v", failed)
+	}
+	if !primary.users["guest@example.com"].Redeemed.Equal(other) {
+		t.Errorf("Expected the database to keep its redemption, got %v", primary.users["guest@example.com"].Redeemed)
+	}
+	select {
+	case text := <-alerter.alerts:
+		if !strings.Contains(text, "guest@example.com") {
+			t.Errorf("Alert does not name the guest: %q", text)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an alert")
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// enumerationSource is a user or API client that checked unknown emails
+type enumerationSource struct {
+	misses       map[string]time.Time // Unknown emails by the time they were last checked
+	alarmed      time.Time            // Time of the last alarm, zero if none
+	blockedUntil time.Time
+}
+
+// enumerationGuard notices sources checking many distinct emails that are
+// not on the guest list, which suggests they are guessing it
+type enumerationGuard struct {
+	cfg config.EnumerationConfig
+	now func() time.Time
+
+	mu         sync.Mutex
+	sources    map[string]*enumerationSource // By rate limiting key
+	alarms     int
+	lastAlert  time.Time
+	suppressed int // Alarms since the last alert
+}
+
+// newEnumerationGuard creates a guard with the configured thresholds
+func newEnumerationGuard(cfg config.EnumerationConfig) *enumerationGuard {
+	return &enumerationGuard{cfg: cfg, now: time.Now, sources: make(map[string]*enumerationSource)}
+}
+
+// blocked returns how long the source is still blocked, 0 if it is not
+func (g *enumerationGuard) blocked(key string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if source := g.sources[key]; source != nil {
+		return max(source.blockedUntil.Sub(g.now()), 0)
+	}
+	return 0
+}
+
+// miss records an unknown email checked by the source. It returns the
+// number of distinct unknown emails in the window when they reach the
+// threshold and the source was not alarmed about within the cooldown,
+// otherwise 0.
+func (g *enumerationGuard) miss(key, email string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	window := g.cfg.Window.Duration()
+	source := g.sources[key]
+	if source == nil {
+		g.forgetIdle(now)
+		source = &enumerationSource{misses: make(map[string]time.Time)}
+		g.sources[key] = source
+	}
+	source.misses[email] = now
+	for other, seen := range source.misses {
+		if now.Sub(seen) > window {
+			delete(source.misses, other)
+		}
+	}
+
+	count := len(source.misses)
+	if count < g.cfg.Threshold || (!source.alarmed.IsZero() && now.Sub(source.alarmed) < g.cfg.Cooldown.Duration()) {
+		return 0
+	}
+	source.alarmed = now
+	if g.cfg.Block {
+		source.blockedUntil = now.Add(g.cfg.Cooldown.Duration())
+	}
+	g.alarms++
+	return count
+}
+
+// forgetIdle drops sources without recent unknown emails, alarm or block.
+// The caller must hold mu.
+func (g *enumerationGuard) forgetIdle(now time.Time) {
+	for key, source := range g.sources {
+		recent := false
+		for _, seen := range source.misses {
+			if now.Sub(seen) <= g.cfg.Window.Duration() {
+				recent = true
+				break
+			}
+		}
+		if !recent && now.After(source.blockedUntil) && now.Sub(source.alarmed) >= g.cfg.Cooldown.Duration() {
+			delete(g.sources, key)
+		}
+	}
+}
+
+// throttle reports whether staff may be alerted now, and how many alarms
+// were held back since the last alert. Held back alarms are counted.
+func (g *enumerationGuard) throttle() (bool, int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	if !g.lastAlert.IsZero() && now.Sub(g.lastAlert) < g.cfg.AlertInterval.Duration() {
+		g.suppressed++
+		return false, 0
+	}
+	suppressed := g.suppressed
+	g.lastAlert, g.suppressed = now, 0
+	return true, suppressed
+}
+
+// stats counts the tracked and blocked sources
+func (g *enumerationGuard) stats() domain.EnumerationStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stats := domain.EnumerationStats{Alarms: g.alarms, Tracked: len(g.sources)}
+	now := g.now()
+	for _, source := range g.sources {
+		if now.Before(source.blockedUntil) {
+			stats.Blocked++
+		}
+	}
+	return stats
+}
+
+// SetEnumerationGuard raises alarms about sources checking many unknown
+// emails, and blocks them if configured
+func (s *Service) SetEnumerationGuard(cfg config.EnumerationConfig) {
+	s.enumeration = newEnumerationGuard(cfg)
+}
+
+// EnumerationStats returns the alarms raised and the sources tracked
+func (s *Service) EnumerationStats() domain.EnumerationStats {
+	if s.enumeration == nil {
+		return domain.EnumerationStats{}
+	}
+	return s.enumeration.stats()
+}
+
+// noteUnknownEmail feeds an email that is not on the guest list to the
+// enumeration guard, and logs and alerts staff when it raises an alarm
+func (s *Service) noteUnknownEmail(ctx context.Context, key string, userID int64, email string) {
+	if s.enumeration == nil {
+		return
+	}
+	count := s.enumeration.miss(key, email)
+	if count == 0 {
+		return
+	}
+
+	cfg := s.enumeration.cfg
+	s.log(ctx).Warn("Possible email enumeration", "source", key, "user_id", userID, "unknown_emails", count,
+		"window", cfg.Window.Duration(), "blocked", cfg.Block)
+
+	ok, suppressed := s.enumeration.throttle()
+	if !ok {
+		return
+	}
+	text := fmt.Sprintf("Possible guest list guessing: %d unknown emails checked within %s from source %s.", count, cfg.Window.Duration(), key)
+	if cfg.Block {
+		text += fmt.Sprintf(" Checks from it are refused for %s.", cfg.Cooldown.Duration())
+	}
+	if suppressed > 0 {
+		text += fmt.Sprintf(" %d more alarms since the last alert, see the log.", suppressed)
+	}
+	go s.alert(text)
+}
@@ -0,0 +1,78 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestEnumerationAlarm(t *testing.T) {
+	mockRepo := newMockRepository()
+	mockRepo.users["guest@example.com"] = &domain.User{ID: "1", Email: "guest@example.com", DateAdded: time.Now()}
+
+	alerter := &mockAlerter{alerts: make(chan string, 4)}
+	svc := service.NewForTest(mockRepo, ratelimit.New(100, 1000), logger.New("error"))
+	svc.AddAlerter(alerter)
+	svc.SetEnumerationGuard(config.EnumerationConfig{
+		Threshold:     3,
+		Window:        config.Duration(time.Minute),
+		Block:         true,
+		Cooldown:      config.Duration(time.Hour),
+		AlertInterval: config.Duration(time.Hour),
+	})
+	ctx := context.Background()
+
+	// Checking the same unknown email again is not guessing
+	for range 5 {
+		svc.CheckEmailStatus(ctx, 42, "typo@example.com")
+	}
+	if stats := svc.EnumerationStats(); stats.Alarms != 0 {
+		t.Fatalf("Expected no alarm for one email, got %+v", stats)
+	}
+
+	for i := range 2 {
+		if status, _, _ := svc.CheckEmailStatus(ctx, 42, fmt.Sprintf("guess%d@example.com", i)); status != "not_found" {
+			t.Fatalf("Expected not_found before the alarm, got %s", status)
+		}
+	}
+	select {
+	case text := <-alerter.alerts:
+		if !strings.Contains(text, "3 unknown emails") || !strings.Contains(text, "refused for 1h0m0s") {
+			t.Errorf("Unexpected alert: %q", text)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an alert")
+	}
+
+	// The source is blocked, even for emails on the list, and told how long to wait
+	if status, _, _ := svc.CheckEmailStatus(ctx, 42, "guest@example.com"); status != "rate_limited" {
+		t.Errorf("Expected the source to be blocked, got %s", status)
+	}
+	if wait := svc.RetryAfter(ctx, 42); wait < 59*time.Minute {
+		t.Errorf("Expected to wait for the cooldown, got %v", wait)
+	}
+	if status, _, _ := svc.CheckEmailStatus(ctx, 43, "guest@example.com"); status != "eligible" {
+		t.Errorf("Expected other users to be served, got %s", status)
+	}
+
+	// A second source raises an alarm that is held back for the next alert
+	for i := range 3 {
+		svc.CheckEmailStatus(ctx, 44, fmt.Sprintf("other%d@example.com", i))
+	}
+	select {
+	case text := <-alerter.alerts:
+		t.Errorf("Expected the second alarm to be held back, got %q", text)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if stats := svc.EnumerationStats(); stats.Alarms != 2 || stats.Blocked != 2 || stats.Tracked != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
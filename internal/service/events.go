@@ -0,0 +1,35 @@
+package service
+
+import (
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/events"
+)
+
+// Events returns the hub the service publishes guest events to, such as
+// redemptions, whichever channel they came through
+func (s *Service) Events() *events.Hub {
+	return s.events
+}
+
+// publishRedeemed tells the subscribers that the cocktail of a guest was
+// redeemed. userID is the Telegram user or hashed API client that
+// redeemed it, 0 if none, and operator the staff member who collected an
+// offline redemption, or else the one named by the context.
+func (s *Service) publishRedeemed(ctx any, userID int64, fallback string, user *domain.User, operator string) {
+	if operator == "" {
+		operator = audit.OperatorFromContext(ctx)
+	}
+	event := events.Event{
+		Type:     events.Redeemed,
+		Email:    user.Email,
+		UserID:   userID,
+		Actor:    audit.ActorFromContext(ctx, fallback),
+		Operator: operator,
+		Bar:      user.RedeemedAt,
+		Time:     *user.Redeemed,
+	}
+	if missed := s.events.Publish(event); missed > 0 {
+		s.log(ctx).Warn("Redemption event missed by slow subscribers", "email", user.Email, "subscribers", missed)
+	}
+}
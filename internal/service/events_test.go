@@ -0,0 +1,46 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/events"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestRedemptionEvents(t *testing.T) {
+	mockRepo := newMockRepository()
+	mockRepo.users["guest@example.com"] = &domain.User{ID: "1", Email: "guest@example.com", DateAdded: time.Now()}
+	mockRepo.users["door@example.com"] = &domain.User{ID: "2", Email: "door@example.com", DateAdded: time.Now()}
+	svc := service.NewForTest(mockRepo, ratelimit.New(10, 100), logger.New("error"))
+	ch, unsubscribe := svc.Events().Subscribe()
+	defer unsubscribe()
+
+	redeemed, err := svc.RedeemCocktail(context.Background(), 42, "Guest@Example.com")
+	if err != nil {
+		t.Fatalf("Failed to redeem: %v", err)
+	}
+	if event := <-ch; event.Type != events.Redeemed || event.Email != "guest@example.com" || event.UserID != 42 || !event.Time.Equal(redeemed) {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	// Offline redemptions are published too
+	svc.ApplyOfflineRedemptions(context.Background(), []domain.OfflineRedemption{{Email: "door@example.com", Redeemed: time.Now().Add(-time.Minute)}})
+	if event := <-ch; event.Email != "door@example.com" || event.UserID != 0 || event.Actor != "system" {
+		t.Errorf("Unexpected offline event: %+v", event)
+	}
+
+	// Failed attempts publish nothing
+	if _, err := svc.RedeemCocktail(context.Background(), 42, "missing@example.com"); err == nil {
+		t.Fatal("Expected an unknown email to fail")
+	}
+	select {
+	case event := <-ch:
+		t.Errorf("Expected no event, got %+v", event)
+	default:
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/events"
+	"github.com/ceesaxp/cocktail-bot/internal/featureflags"
+)
+
+// SetFeatures replaces the feature flags. A nil set has every flag on.
+func (s *Service) SetFeatures(flags *featureflags.Set) {
+	s.features = flags
+}
+
+// FeatureEnabled returns whether a feature flag is on
+func (s *Service) FeatureEnabled(name featureflags.Name) bool {
+	return s.features.Enabled(name)
+}
+
+// Features returns the state of every feature flag
+func (s *Service) Features() []featureflags.Flag {
+	return s.features.List()
+}
+
+// ToggleFeature turns a feature flag on or off until the next restart, or
+// back to its configured state if enabled is nil. It fails with
+// featureflags.ErrToggleDisabled unless runtime toggling is configured.
+func (s *Service) ToggleFeature(ctx context.Context, name featureflags.Name, enabled *bool, actor string) (featureflags.Flag, error) {
+	var (
+		flag featureflags.Flag
+		err  error
+	)
+	if enabled == nil {
+		flag, err = s.features.Reset(name)
+	} else {
+		flag, err = s.features.Toggle(name, *enabled)
+	}
+	if err != nil {
+		return featureflags.Flag{}, err
+	}
+
+	details := fmt.Sprintf("%s=%t", name, flag.Enabled)
+	if enabled == nil {
+		details += " (configured)"
+	}
+	s.log(ctx).Info("Feature flag toggled", "flag", name, "enabled", flag.Enabled, "overridden", flag.Overridden, "actor", actor)
+	s.recordAudit(ctx, actor, audit.ActionToggleFeature, "", details)
+	s.events.Publish(events.Event{Type: events.FeatureToggled, Actor: actor})
+	return flag, nil
+}
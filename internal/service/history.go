@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// Sources of a past user state
+const (
+	StateSourceCurrent = "current" // The record as stored now
+	StateSourceAudit   = "audit"   // A copy kept in the audit log
+	StateSourceDerived = "derived" // The stored record with later timestamps removed
+)
+
+// UserAsOf returns the record of a guest, named by ID or email, as it was
+// at asOf, and where that state comes from. A zero asOf returns the
+// current record. Past states are read from the copies kept in the audit
+// log. Without one, such as for changes logged by older versions, the
+// current record is rolled back by its timestamps, which cannot restore
+// values that were overwritten later. ErrUserNotFound is returned if the
+// guest did not exist at that time.
+func (s *Service) UserAsOf(ctx context.Context, id string, asOf time.Time) (*domain.User, string, error) {
+	user, err := s.findUserByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if asOf.IsZero() {
+		return user, StateSourceCurrent, nil
+	}
+
+	entries, _, err := s.audit.Query(audit.Filter{Email: user.Email, To: asOf})
+	if err != nil {
+		s.log(ctx).Error("Error reading audit log", "error", err)
+		return nil, "", err
+	}
+	for _, entry := range entries {
+		if entry.User != nil && utils.NormalizeEmail(entry.Email) == user.Email {
+			past := *entry.User
+			return &past, StateSourceAudit, nil
+		}
+	}
+
+	if user.DateAdded.After(asOf) {
+		return nil, "", domain.ErrUserNotFound
+	}
+	past := *user
+	if past.Redeemed != nil && past.Redeemed.After(asOf) {
+		past.Redeemed, past.RedeemedAt = nil, ""
+	}
+	if past.MarketingConsent != nil && past.MarketingConsent.After(asOf) {
+		past.MarketingConsent = nil
+	}
+	if past.UpdatedAt.After(asOf) {
+		// Fall back to the latest timestamp left
+		past.UpdatedAt = time.Time{}
+		past.UpdatedAt = past.LastChange()
+	}
+	return &past, StateSourceDerived, nil
+}
+
+// findUserByID looks up a guest by email, or else by record ID
+func (s *Service) findUserByID(ctx any, id string) (*domain.User, error) {
+	if strings.Contains(id, "@") {
+		return s.repo.FindByEmail(ctx, utils.NormalizeEmail(id))
+	}
+
+	// Repositories are keyed by email, so IDs are found among all guests
+	users, err := s.repo.GetReport(ctx, domain.ReportParams{
+		Type: domain.ReportTypeAll,
+		From: time.Unix(0, 0),
+		To:   time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
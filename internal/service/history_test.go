@@ -0,0 +1,57 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestUserAsOf(t *testing.T) {
+	mockRepo := newMockRepository()
+	svc := service.NewForTest(mockRepo, ratelimit.New(100, 1000), logger.New("error"))
+	ctx := context.Background()
+
+	before := time.Now()
+	time.Sleep(time.Millisecond)
+	if err := svc.AddUser(ctx, &domain.User{ID: "7", Email: "guest@example.com", DateAdded: time.Now()}); err != nil {
+		t.Fatalf("Failed to add user: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	added := time.Now()
+	time.Sleep(time.Millisecond)
+	if _, err := svc.RedeemCocktail(ctx, 1, "guest@example.com"); err != nil {
+		t.Fatalf("Failed to redeem: %v", err)
+	}
+
+	// The state between adding and redeeming comes from the audit log
+	user, source, err := svc.UserAsOf(ctx, "7", added)
+	if err != nil || source != service.StateSourceAudit || user.Redeemed != nil {
+		t.Errorf("Expected the unredeemed guest from the audit log, got %+v, %s, %v", user, source, err)
+	}
+	if user, source, err := svc.UserAsOf(ctx, "Guest@Example.com", time.Time{}); err != nil || source != service.StateSourceCurrent || user.Redeemed == nil {
+		t.Errorf("Expected the current redeemed guest, got %+v, %s, %v", user, source, err)
+	}
+	if _, _, err := svc.UserAsOf(ctx, "guest@example.com", before); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("Expected the guest not to exist yet, got %v", err)
+	}
+
+	// Records changed before the audit log kept copies are rolled back
+	redeemed := time.Date(2025, 6, 1, 22, 10, 0, 0, time.UTC)
+	mockRepo.users["old@example.com"] = &domain.User{
+		ID: "8", Email: "old@example.com", DateAdded: redeemed.Add(-2 * time.Hour),
+		Redeemed: &redeemed, RedeemedAt: "Rooftop", UpdatedAt: redeemed,
+	}
+	user, source, err = svc.UserAsOf(ctx, "old@example.com", redeemed.Add(-7*time.Minute))
+	if err != nil || source != service.StateSourceDerived || user.Redeemed != nil || user.RedeemedAt != "" {
+		t.Errorf("Expected the guest before redemption, got %+v, %s, %v", user, source, err)
+	}
+	if _, _, err := svc.UserAsOf(ctx, "99", time.Time{}); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("Expected an unknown ID to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/importer"
+	"github.com/ceesaxp/cocktail-bot/internal/jobs"
+	"github.com/ceesaxp/cocktail-bot/internal/queue"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// importMessage is a batch of emails of an import job, as published to the broker
+type importMessage struct {
+	Job     string    `json:"job"`
+	Total   int       `json:"total"` // Emails of the whole job
+	Actor   string    `json:"actor"`
+	Created time.Time `json:"created"`
+	Emails  []string  `json:"emails"`
+}
+
+// importQueue publishes imported emails to a broker for workers to add.
+// Jobs are tracked by the service, so an instance only knows the jobs it
+// started or whose messages it received.
+type importQueue struct {
+	broker    queue.Broker
+	batchSize int
+	maxEmails int
+	workers   sync.WaitGroup
+}
+
+// SetImportQueue lets the API queue imports to the configured broker and
+// starts the workers adding the queued emails
+func (s *Service) SetImportQueue(cfg config.ImportQueueConfig) error {
+	broker, err := queue.New(cfg, s.logger)
+	if err != nil {
+		return err
+	}
+
+	q := &importQueue{
+		broker:    broker,
+		batchSize: max(cfg.BatchSize, 1),
+		maxEmails: cfg.MaxEmails,
+	}
+	workers := max(cfg.Workers, 1)
+	for i := 0; i < workers; i++ {
+		q.workers.Add(1)
+		go s.importWorker(q)
+	}
+	s.imports = q
+
+	s.logger.Info("Import queue enabled", "broker", cfg.Broker, "queue", cfg.Queue, "workers", workers)
+	return nil
+}
+
+// ImportQueueEnabled returns whether imports can be queued
+func (s *Service) ImportQueueEnabled() bool {
+	return s.imports != nil
+}
+
+// MaxImportEmails returns the number of emails accepted per queued import
+func (s *Service) MaxImportEmails() int {
+	if s.imports == nil {
+		return 0
+	}
+	return s.imports.maxEmails
+}
+
+// EnqueueImport publishes emails for the workers to add and returns the
+// job tracking them. If not all batches can be published, the job fails
+// and the error is returned with it; batches already published are still
+// processed.
+func (s *Service) EnqueueImport(ctx context.Context, actor string, emails []string) (jobs.Job, error) {
+	if s.imports == nil {
+		return jobs.Job{}, domain.ErrImportQueueDisabled
+	}
+	q := s.imports
+
+	h := s.jobs.Create(jobs.Job{Type: jobs.TypeImport, Actor: actor, Total: len(emails)})
+	created := h.Job().Created
+	for start := 0; start < len(emails); start += q.batchSize {
+		end := min(start+q.batchSize, len(emails))
+		data, err := json.Marshal(importMessage{Job: h.ID(), Total: len(emails), Actor: actor, Created: created, Emails: emails[start:end]})
+		if err == nil {
+			err = q.broker.Publish(ctx, data)
+		}
+		if err != nil {
+			s.log(ctx).Error("Failed to queue import", "job", h.ID(), "published", start, "total", len(emails), "error", err)
+			h.Advance("failed", len(emails)-start)
+			h.Finish(fmt.Errorf("%d emails not queued: %w", len(emails)-start, err))
+			return h.Job(), err
+		}
+	}
+
+	s.log(ctx).Info("Import queued", "job", h.ID(), "emails", len(emails), "actor", actor)
+	return h.Job(), nil
+}
+
+// importRetryDelay is how long a worker waits before handing a message
+// back to the broker while the database is unavailable
+const importRetryDelay = time.Second
+
+// importWorker adds the emails of queued messages until the broker is
+// closed, acknowledging each message once its emails are added. Brokers
+// that deliver messages again get them back while the database is
+// unavailable, instead of their emails being counted as failed.
+func (s *Service) importWorker(q *importQueue) {
+	defer q.workers.Done()
+	for message := range q.broker.Messages() {
+		var msg importMessage
+		if err := json.Unmarshal(message.Data, &msg); err != nil {
+			s.logger.Error("Dropping invalid import message", "error", err)
+			message.Nack(false)
+			continue
+		}
+
+		if message.CanRequeue() {
+			if err := s.DatabaseHealth(context.Background()); err != nil {
+				s.logger.Warn("Database unavailable, requeueing import batch", "job", msg.Job, "error", err)
+				time.Sleep(importRetryDelay)
+				if err := message.Nack(true); err != nil {
+					s.logger.Warn("Failed to requeue import batch", "job", msg.Job, "error", err)
+				}
+				continue
+			}
+		}
+
+		s.importBatch(msg)
+		if err := message.Ack(); err != nil {
+			s.logger.Warn("Failed to acknowledge import batch", "job", msg.Job, "error", err)
+		}
+	}
+}
+
+// importBatch adds the emails of one message, counting the outcomes in
+// its job. Jobs started by another instance are tracked from their first
+// message. The emails of a canceled job are skipped.
+func (s *Service) importBatch(msg importMessage) {
+	h := s.jobs.Create(jobs.Job{ID: msg.Job, Type: jobs.TypeImport, Actor: msg.Actor, Total: msg.Total, Created: msg.Created})
+	ctx := context.Background()
+	for i, raw := range msg.Emails {
+		if h.Context().Err() != nil {
+			h.Advance("canceled", len(msg.Emails)-i)
+			break
+		}
+
+		outcome, reason := "added", ""
+		if !utils.IsValidEmail(raw) {
+			outcome, reason = "failed", "invalid format"
+		} else {
+			user := &domain.User{Email: raw, DateAdded: time.Now(), CreatedBy: msg.Actor}
+			switch err := s.AddUser(ctx, user); {
+			case err == nil:
+			case domain.IsDuplicateUser(err):
+				outcome = "duplicate"
+			case err == domain.ErrEmailDenied:
+				outcome, reason = "failed", "denied"
+			case err == domain.ErrEventArchived:
+				outcome, reason = "failed", "archived"
+			default:
+				outcome, reason = "failed", "storage error"
+			}
+		}
+		h.Advance(outcome, 1)
+		if reason != "" {
+			h.Error(raw + ": " + reason)
+		}
+	}
+
+	if job := h.Job(); job.Done >= job.Total {
+		h.Finish(nil)
+	}
+}
+
+// close stops receiving messages and waits for the workers to finish the
+// messages already received
+func (q *importQueue) close() error {
+	err := q.broker.Close()
+	q.workers.Wait()
+	return err
+}
+
+// StartImport imports the guests of an uploaded CSV file in the background
+// and returns the job tracking it, named after the file. Rows are counted
+// as they are read, with the imported, updated, duplicate and invalid
+// outcomes. Invalid options are reported as ValidationErrors.
+func (s *Service) StartImport(ctx context.Context, actor, name string, data []byte, opts importer.Options) (jobs.Job, error) {
+	if err := opts.Validate(); err != nil {
+		return jobs.Job{}, domain.NewValidationError("mapping", err.Error())
+	}
+	if s.EventArchived() != nil {
+		return jobs.Job{}, domain.ErrEventArchived
+	}
+	opts.CreatedBy = actor
+
+	job := s.jobs.Run(jobs.Job{Type: jobs.TypeImport, Name: name, Actor: actor, Total: countRows(data)}, func(jobCtx context.Context, h *jobs.Handle) error {
+		var last importer.Result
+		advance := func(outcome string, n int) {
+			if n > 0 {
+				h.Advance(outcome, n)
+			}
+		}
+		progress := func(r importer.Result) {
+			advance("imported", r.Imported-last.Imported)
+			advance("updated", r.Updated-last.Updated)
+			advance("duplicate", r.Duplicate-last.Duplicate)
+			advance("invalid", r.Invalid-last.Invalid)
+			// The header and rows without an email only count as done
+			counted := (r.Imported + r.Updated + r.Duplicate + r.Invalid) - (last.Imported + last.Updated + last.Duplicate + last.Invalid)
+			advance("", r.Rows-last.Rows-counted)
+			last = r
+		}
+		opts.ProgressEvery = 100
+		opts.Progress = progress
+		opts.OnInvalid = func(row int, email string) {
+			h.Error(fmt.Sprintf("%s: invalid email or tags at row %d", email, row))
+		}
+
+		result, err := importer.Run(audit.NewContext(jobCtx, actor), bytes.NewReader(data), s, opts)
+		progress(result)
+		if err != nil {
+			s.logger.Error("Import failed", "job", h.ID(), "file", name, "rows", result.Rows, "error", err)
+			return err
+		}
+		s.logger.Info("Import finished", "job", h.ID(), "file", name, "counts", h.Job().Counts)
+		return nil
+	})
+	s.log(ctx).Info("Import started", "job", job.ID, "file", name, "rows", job.Total, "actor", actor)
+	return job, nil
+}
+
+// countRows returns the number of CSV records in data, or 0 if it cannot
+// be parsed, in which case the import reports the error
+func countRows(data []byte) int {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	rows := 0
+	for {
+		if _, err := reader.Read(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return rows
+			}
+			return 0
+		}
+		rows++
+	}
+}
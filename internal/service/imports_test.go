@@ -0,0 +1,99 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/importer"
+	"github.com/ceesaxp/cocktail-bot/internal/jobs"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestImportQueue(t *testing.T) {
+	mockRepo := newMockRepository()
+	svc := service.NewForTest(mockRepo, ratelimit.New(10, 100), logger.New("error"))
+	ctx := context.Background()
+
+	if _, err := svc.EnqueueImport(ctx, "token:abc", []string{"guest@example.com"}); !errors.Is(err, domain.ErrImportQueueDisabled) {
+		t.Fatalf("Expected the import queue to be disabled, got %v", err)
+	}
+
+	// One worker, as the mock repository is not safe for concurrent use
+	if err := svc.SetImportQueue(config.ImportQueueConfig{Broker: "memory", Workers: 1, BatchSize: 2, MaxEmails: 10}); err != nil {
+		t.Fatalf("Failed to enable the import queue: %v", err)
+	}
+	svc.DenyEmail("crasher@example.com")
+
+	job, err := svc.EnqueueImport(ctx, "token:abc", []string{"one@example.com", "not-an-email", "Two@Example.com", "crasher@example.com", "three@example.com"})
+	if err != nil || job.Total != 5 || job.ID == "" {
+		t.Fatalf("Expected a queued job of 5 emails, got %+v, %v", job, err)
+	}
+
+	// Workers add the emails in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Active() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if job, err = svc.Job(job.ID); err != nil {
+			t.Fatalf("Failed to get the job: %v", err)
+		}
+	}
+	if job.Status != jobs.StatusDone || job.Type != jobs.TypeImport || job.Done != 5 || job.Counts["added"] != 3 || job.Counts["failed"] != 2 {
+		t.Fatalf("Expected 3 added and 2 failed emails, got %+v", job)
+	}
+	if len(job.Errors) != 2 || job.Errors[0] != "not-an-email: invalid format" || job.Errors[1] != "crasher@example.com: denied" {
+		t.Errorf("Unexpected errors: %v", job.Errors)
+	}
+	if user, ok := mockRepo.users["two@example.com"]; !ok || user.CreatedBy != "token:abc" {
+		t.Errorf("Expected the normalized email added by the token, got %+v", user)
+	}
+
+	if err := svc.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+func TestStartImport(t *testing.T) {
+	mockRepo := newMockRepository()
+	svc := service.NewForTest(mockRepo, ratelimit.New(10, 100), logger.New("error"))
+	defer svc.Close()
+	ctx := context.Background()
+	mockRepo.users["existing@example.com"] = &domain.User{ID: "1", Email: "existing@example.com"}
+
+	if _, err := svc.StartImport(ctx, "token:abc", "guests.csv", nil, importer.Options{}); !domain.IsValidationError(err) {
+		t.Errorf("Expected the missing email column to be refused, got %v", err)
+	}
+
+	data := "Name,Email,Tags\nAnna,Anna@Example.com,vip\nBad,not-an-email,\nOld,existing@example.com,press\n,,\n"
+	job, err := svc.StartImport(ctx, "token:abc", "guests.csv", []byte(data), importer.Options{
+		Column: 2, NameColumn: 1, TagsColumn: 3, Header: true, Existing: importer.ExistingUpdate,
+	})
+	if err != nil || job.Type != jobs.TypeImport || job.Name != "guests.csv" || job.Total != 5 {
+		t.Fatalf("Expected an import job of 5 rows, got %+v, %v", job, err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Active() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if job, err = svc.Job(job.ID); err != nil {
+			t.Fatalf("Failed to get the job: %v", err)
+		}
+	}
+	if job.Status != jobs.StatusDone || job.Done != 5 || job.Counts["imported"] != 1 || job.Counts["updated"] != 1 || job.Counts["invalid"] != 1 {
+		t.Fatalf("Expected one imported, updated and invalid row each, got %+v", job)
+	}
+	if len(job.Errors) != 1 || job.Errors[0] != "not-an-email: invalid email or tags at row 3" {
+		t.Errorf("Unexpected errors: %v", job.Errors)
+	}
+	if user := mockRepo.users["anna@example.com"]; user == nil || user.CreatedBy != "token:abc" || user.FirstName != "Anna" {
+		t.Errorf("Expected the guest added by the token with the name, got %+v", user)
+	}
+	if user := mockRepo.users["existing@example.com"]; len(user.Tags) != 1 || user.Tags[0] != "press" {
+		t.Errorf("Expected the tag added to the existing guest, got %+v", user)
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/jobs"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+)
+
+// Migrations that can be started as jobs
+const (
+	MigrationNormalizeEmails = "normalize-emails" // Rewrite stored emails to their normalized form
+)
+
+// Migrations returns the names of the migrations that can be started
+func Migrations() []string {
+	return []string{MigrationNormalizeEmails}
+}
+
+// Job returns a long-running operation, such as an import or a migration
+func (s *Service) Job(id string) (jobs.Job, error) {
+	return s.jobs.Get(id)
+}
+
+// Jobs returns the recent long-running operations, newest first
+func (s *Service) Jobs() []jobs.Job {
+	return s.jobs.List()
+}
+
+// CancelJob stops a queued or running operation
+func (s *Service) CancelJob(ctx context.Context, actor, id string) (jobs.Job, error) {
+	job, err := s.jobs.Cancel(id)
+	if err != nil {
+		return job, err
+	}
+	s.log(ctx).Info("Job canceled", "job", id, "type", job.Type, "done", job.Done, "total", job.Total, "actor", actor)
+	return job, nil
+}
+
+// StartMigration runs a migration of the stored records in the background
+// and returns the job tracking it
+func (s *Service) StartMigration(ctx context.Context, actor, name string) (jobs.Job, error) {
+	var run func(ctx context.Context, h *jobs.Handle) error
+	switch name {
+	case MigrationNormalizeEmails:
+		normalizer, ok := s.repo.(repository.EmailNormalizer)
+		if !ok {
+			return jobs.Job{}, fmt.Errorf("%w: the database does not support email normalization", domain.ErrMigrationUnsupported)
+		}
+		run = func(ctx context.Context, h *jobs.Handle) error {
+			changed, err := normalizer.NormalizeEmails(ctx)
+			if err != nil {
+				return err
+			}
+			h.Advance("normalized", changed)
+			return nil
+		}
+	default:
+		return jobs.Job{}, fmt.Errorf("%w: %s", domain.ErrMigrationUnknown, name)
+	}
+
+	job := s.jobs.Run(jobs.Job{Type: jobs.TypeMigration, Name: name, Actor: actor}, func(jobCtx context.Context, h *jobs.Handle) error {
+		err := run(jobCtx, h)
+		if err != nil {
+			s.logger.Error("Migration failed", "job", h.ID(), "migration", name, "error", err)
+		} else {
+			s.logger.Info("Migration finished", "job", h.ID(), "migration", name, "counts", h.Job().Counts)
+		}
+		return err
+	})
+	s.log(ctx).Info("Migration started", "job", job.ID, "migration", name, "actor", actor)
+	return job, nil
+}
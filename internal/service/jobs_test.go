@@ -0,0 +1,64 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/jobs"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestMigrationJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	data := "ID,Email,DateAdded,Redeemed\n1, Guest@Example.COM ,2025-06-01T20:00:00Z,\n2,other@example.com,2025-06-01T20:00:00Z,\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	repo, err := repository.NewCSVRepository(path, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to open CSV: %v", err)
+	}
+	svc := service.NewForTest(repo, ratelimit.New(10, 100), logger.New("error"))
+	defer svc.Close()
+	ctx := context.Background()
+
+	if _, err := svc.StartMigration(ctx, "token:abc", "drop-everything"); !errors.Is(err, domain.ErrMigrationUnknown) {
+		t.Errorf("Expected an unknown migration, got %v", err)
+	}
+
+	job, err := svc.StartMigration(ctx, "token:abc", service.MigrationNormalizeEmails)
+	if err != nil || job.Type != jobs.TypeMigration || job.Name != service.MigrationNormalizeEmails {
+		t.Fatalf("Expected a migration job, got %+v, %v", job, err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Active() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if job, err = svc.Job(job.ID); err != nil {
+			t.Fatalf("Failed to get the job: %v", err)
+		}
+	}
+	if job.Status != jobs.StatusDone || job.Counts["normalized"] != 1 || job.Actor != "token:abc" {
+		t.Errorf("Expected one normalized email, got %+v", job)
+	}
+	if list := svc.Jobs(); len(list) != 1 || list[0].ID != job.ID {
+		t.Errorf("Expected the migration in the job list, got %+v", list)
+	}
+	if _, err := svc.CancelJob(ctx, "token:abc", job.ID); !errors.Is(err, jobs.ErrFinished) {
+		t.Errorf("Expected a finished job not to be canceled, got %v", err)
+	}
+
+	// The mock repository cannot normalize emails
+	mockSvc := service.NewForTest(newMockRepository(), ratelimit.New(10, 100), logger.New("error"))
+	if _, err := mockSvc.StartMigration(ctx, "token:abc", service.MigrationNormalizeEmails); !errors.Is(err, domain.ErrMigrationUnsupported) {
+		t.Errorf("Expected the migration to be unsupported, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// latencyBuckets are the ranges of the redemption latency histogram,
+// from guests redeeming right after being added to invitations sent weeks
+// ahead
+var latencyBuckets = []struct {
+	label string
+	upTo  time.Duration // Exclusive, 0 for the last bucket
+}{
+	{"<1h", time.Hour},
+	{"1-6h", 6 * time.Hour},
+	{"6-24h", 24 * time.Hour},
+	{"1-3d", 3 * 24 * time.Hour},
+	{"3-7d", 7 * 24 * time.Hour},
+	{"1-2w", 14 * 24 * time.Hour},
+	{"2-4w", 28 * 24 * time.Hour},
+	{">4w", 0},
+}
+
+// RedemptionLatency measures how long the redeemed guests of a report took
+// from being added to redeeming. Redemptions recorded before the guest was
+// added, as offline or merged records can be, count as immediate.
+func (s *Service) RedemptionLatency(ctx context.Context, fromDate, toDate time.Time, filter domain.ReportFilter) (domain.RedemptionLatency, error) {
+	users, err := s.GenerateReport(ctx, string(domain.ReportTypeRedeemed), fromDate, toDate, filter)
+	if err != nil {
+		return domain.RedemptionLatency{}, err
+	}
+
+	latencies := make([]time.Duration, 0, len(users))
+	for _, user := range users {
+		if user.Redeemed == nil || user.DateAdded.IsZero() {
+			continue
+		}
+		latencies = append(latencies, max(user.Redeemed.Sub(user.DateAdded), 0))
+	}
+	return summarizeLatencies(s.event, latencies), nil
+}
+
+// summarizeLatencies returns the distribution of the latencies
+func summarizeLatencies(event string, latencies []time.Duration) domain.RedemptionLatency {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	summary := domain.RedemptionLatency{Event: event, Count: len(latencies)}
+	for _, bucket := range latencyBuckets {
+		summary.Buckets = append(summary.Buckets, domain.LatencyBucket{Label: bucket.label, UpToSeconds: int64(bucket.upTo / time.Second)})
+	}
+	if len(latencies) == 0 {
+		return summary
+	}
+
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+		i := 0
+		for latencyBuckets[i].upTo != 0 && latency >= latencyBuckets[i].upTo {
+			i++
+		}
+		summary.Buckets[i].Count++
+	}
+	summary.MinSeconds = seconds(latencies[0])
+	summary.MaxSeconds = seconds(latencies[len(latencies)-1])
+	summary.MeanSeconds = seconds(total / time.Duration(len(latencies)))
+	summary.P50Seconds = seconds(percentile(latencies, 50))
+	summary.P90Seconds = seconds(percentile(latencies, 90))
+	summary.P99Seconds = seconds(percentile(latencies, 99))
+	return summary
+}
+
+// percentile returns the nearest-rank percentile p, between 0 and 100, of
+// sorted durations: the smallest value that at least p percent of them do
+// not exceed
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// seconds returns a duration in whole seconds
+func seconds(d time.Duration) int64 {
+	return int64(d / time.Second)
+}
@@ -0,0 +1,63 @@
+package service_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestRedemptionLatency(t *testing.T) {
+	mockRepo := newMockRepository()
+	added := time.Now().Add(-30 * 24 * time.Hour)
+
+	// Ten guests redeeming 1 to 10 days after being added, one who has not
+	// redeemed yet and one whose redemption was recorded before being added
+	for day := 1; day <= 10; day++ {
+		redeemed := added.Add(time.Duration(day) * 24 * time.Hour)
+		email := "guest" + strconv.Itoa(day) + "@example.com"
+		mockRepo.users[email] = &domain.User{ID: strconv.Itoa(day), Email: email, DateAdded: added, Redeemed: &redeemed}
+	}
+	mockRepo.users["waiting@example.com"] = &domain.User{ID: "11", Email: "waiting@example.com", DateAdded: added}
+	early := added.Add(-time.Minute)
+	mockRepo.users["offline@example.com"] = &domain.User{ID: "12", Email: "offline@example.com", DateAdded: added, Redeemed: &early}
+
+	svc := service.NewForTest(mockRepo, ratelimit.New(100, 1000), logger.New("error"))
+	latency, err := svc.RedemptionLatency(context.Background(), added.Add(-time.Hour), time.Now(), domain.ReportFilter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	const day = int64(24 * 60 * 60)
+	if latency.Count != 11 || latency.MinSeconds != 0 || latency.MaxSeconds != 10*day {
+		t.Errorf("Unexpected range: %+v", latency)
+	}
+	// Nearest rank: the 6th of 11 sorted latencies is 5 days, the 10th 9 days
+	if latency.P50Seconds != 5*day || latency.P90Seconds != 9*day || latency.P99Seconds != 10*day {
+		t.Errorf("Unexpected percentiles: p50 %d, p90 %d, p99 %d", latency.P50Seconds, latency.P90Seconds, latency.P99Seconds)
+	}
+	if latency.MeanSeconds != 55*day/11 {
+		t.Errorf("Unexpected mean: %d", latency.MeanSeconds)
+	}
+
+	counts := make(map[string]int)
+	total := 0
+	for _, bucket := range latency.Buckets {
+		counts[bucket.Label] = bucket.Count
+		total += bucket.Count
+	}
+	if total != 11 || counts["<1h"] != 1 || counts["1-3d"] != 2 || counts["3-7d"] != 4 || counts["1-2w"] != 4 {
+		t.Errorf("Unexpected histogram: %+v", latency.Buckets)
+	}
+
+	// An empty report has empty buckets
+	latency, err = svc.RedemptionLatency(context.Background(), time.Now(), time.Now(), domain.ReportFilter{})
+	if err != nil || latency.Count != 0 || len(latency.Buckets) == 0 || latency.P50Seconds != 0 {
+		t.Errorf("Unexpected empty latency: %+v, %v", latency, err)
+	}
+}
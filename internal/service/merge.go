@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+)
+
+// findGuest looks up the guest of an email for the bot and API. Records
+// merged into another guest are reported as not found, so a duplicate
+// cannot be used for a second drink.
+func (s *Service) findGuest(ctx any, email string) (*domain.User, error) {
+	user, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsMerged() {
+		return nil, domain.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// MergeUsers merges the duplicate record of a guest, such as one imported
+// with a misspelled email, into the record that is kept. Both are named by
+// record ID or email. The kept record gets the earliest DateAdded, the
+// earliest redemption with its bar, the earliest marketing consent, the
+// union of the tags and the notes of both. Records cannot be deleted from
+// every backend, so the duplicate is kept with MergedTag and its redemption
+// and consent moved to the kept record. It returns both updated records.
+func (s *Service) MergeUsers(ctx context.Context, keepID, duplicateID, reason string) (keep, duplicate *domain.User, err error) {
+	if s.EventArchived() != nil {
+		return nil, nil, domain.ErrEventArchived
+	}
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > domain.MaxReasonLength {
+		return nil, nil, domain.NewValidationError("reason", "must be at most 200 characters")
+	}
+
+	keep, err = s.findUserByID(ctx, keepID)
+	if err != nil {
+		return nil, nil, err
+	}
+	duplicate, err = s.findUserByID(ctx, duplicateID)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case keep.ID == duplicate.ID:
+		return nil, nil, domain.NewValidationError("duplicate", "cannot merge a guest into itself")
+	case keep.IsMerged():
+		return nil, nil, domain.NewValidationError("keep", "was merged into another guest", keep.Email)
+	case duplicate.IsMerged():
+		return nil, nil, domain.NewValidationError("duplicate", "was already merged", duplicate.Email)
+	}
+
+	if reason != "" {
+		reason = " (" + reason + ")"
+	}
+	droppedRedemption := mergeInto(keep, duplicate)
+
+	// The kept record is written first, so a failure cannot lose the
+	// redemption of the duplicate. Merging again finishes the job.
+	if err := s.updateUser(ctx, keep); err != nil {
+		s.log(ctx).Error("Error updating merged user", "email", keep.Email, "error", err)
+		return nil, nil, err
+	}
+	details := fmt.Sprintf("merged %s (#%s)", duplicate.Email, duplicate.ID)
+	if droppedRedemption != nil {
+		details += ", its later redemption " + droppedRedemption.Format("2006-01-02 15:04") + " was dropped"
+	}
+	s.recordUserAudit(ctx, "system", audit.ActionMergeUser, keep, details+reason)
+
+	retire(duplicate, keep)
+	if err := s.updateUser(ctx, duplicate); err != nil {
+		s.log(ctx).Error("Error retiring merged duplicate", "email", duplicate.Email, "error", err)
+		return nil, nil, err
+	}
+	s.recordUserAudit(ctx, "system", audit.ActionMergeUser, duplicate, fmt.Sprintf("merged into %s (#%s)%s", keep.Email, keep.ID, reason))
+
+	s.log(ctx).Info("Users merged", "id", keep.ID, "email", keep.Email, "duplicate_id", duplicate.ID, "duplicate_email", duplicate.Email)
+	return keep, duplicate, nil
+}
+
+// mergeInto copies the data of the duplicate into keep. If both redeemed,
+// the earliest redemption is kept and the other one is returned.
+func mergeInto(keep, duplicate *domain.User) (dropped *time.Time) {
+	if duplicate.DateAdded.Before(keep.DateAdded) {
+		keep.DateAdded = duplicate.DateAdded
+	}
+
+	switch {
+	case duplicate.Redeemed == nil:
+	case keep.Redeemed == nil:
+		keep.Redeemed, keep.RedeemedAt = duplicate.Redeemed, duplicate.RedeemedAt
+	case duplicate.Redeemed.Before(*keep.Redeemed):
+		dropped = keep.Redeemed
+		keep.Redeemed, keep.RedeemedAt = duplicate.Redeemed, duplicate.RedeemedAt
+	default:
+		dropped = duplicate.Redeemed
+	}
+
+	if duplicate.MarketingConsent != nil && (keep.MarketingConsent == nil || duplicate.MarketingConsent.Before(*keep.MarketingConsent)) {
+		keep.MarketingConsent = duplicate.MarketingConsent
+	}
+
+	for _, tag := range duplicate.Tags {
+		if !slices.Contains(keep.Tags, tag) {
+			keep.Tags = append(keep.Tags, tag)
+		}
+	}
+	if duplicate.Notes != "" && duplicate.Notes != keep.Notes {
+		if keep.Notes != "" {
+			keep.Notes += "\n"
+		}
+		keep.Notes += duplicate.Notes
+	}
+	if keep.FullName() == "" {
+		keep.FirstName, keep.LastName = duplicate.FirstName, duplicate.LastName
+	}
+	return dropped
+}
+
+// retire marks a duplicate as merged into keep. Its redemption and consent
+// now belong to keep, so they are cleared to keep the counts right.
+func retire(duplicate, keep *domain.User) {
+	duplicate.Redeemed, duplicate.RedeemedAt = nil, ""
+	duplicate.MarketingConsent = nil
+	duplicate.Tags = []string{domain.MergedTag}
+	duplicate.Notes = fmt.Sprintf("Merged into %s (#%s)", keep.Email, keep.ID)
+}
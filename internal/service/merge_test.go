@@ -0,0 +1,66 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestMergeUsers(t *testing.T) {
+	mockRepo := newMockRepository()
+	svc := service.NewForTest(mockRepo, ratelimit.New(100, 1000), logger.New("error"))
+	ctx := audit.NewContext(context.Background(), "admin_cli")
+
+	added := time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)
+	redeemed := added.Add(4 * time.Hour)
+	consented := added.Add(time.Hour)
+	mockRepo.users["guest@example.com"] = &domain.User{ID: "7", Email: "guest@example.com", DateAdded: added, Tags: []string{"vip"}, Notes: "Table 4"}
+	mockRepo.users["gest@example.com"] = &domain.User{ID: "8", Email: "gest@example.com", DateAdded: added.Add(-24 * time.Hour),
+		Redeemed: &redeemed, RedeemedAt: "Rooftop", MarketingConsent: &consented, Tags: []string{"press", "vip"}}
+
+	keep, duplicate, err := svc.MergeUsers(ctx, "7", "gest@example.com", "typo in the import")
+	if err != nil {
+		t.Fatalf("Failed to merge users: %v", err)
+	}
+	if !keep.DateAdded.Equal(added.Add(-24*time.Hour)) || keep.Redeemed == nil || !keep.Redeemed.Equal(redeemed) || keep.RedeemedAt != "Rooftop" {
+		t.Errorf("Expected the earliest date and the redemption to be kept, got %+v", keep)
+	}
+	if keep.MarketingConsent == nil || strings.Join(keep.Tags, ",") != "vip,press" || keep.Notes != "Table 4" {
+		t.Errorf("Expected the consent, tags and notes to be merged, got %+v", keep)
+	}
+	if !duplicate.IsMerged() || duplicate.IsRedeemed() || duplicate.HasMarketingConsent() {
+		t.Errorf("Expected the duplicate to be retired, got %+v", duplicate)
+	}
+
+	// The duplicate can no longer be used
+	status, _, err := svc.CheckEmailStatus(ctx, 1, "gest@example.com")
+	if err != nil || status != "not_found" {
+		t.Errorf("Expected the duplicate to be unknown, got %q, %v", status, err)
+	}
+	if _, err := svc.RedeemCocktail(ctx, 1, "gest@example.com"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("Expected a redemption of the duplicate to be refused, got %v", err)
+	}
+
+	entries, _, _ := svc.AuditLog(ctx, audit.Filter{Action: audit.ActionMergeUser})
+	if len(entries) != 2 || !strings.Contains(entries[0].Details+entries[1].Details, "(typo in the import)") {
+		t.Errorf("Expected an audit entry for each record, got %+v", entries)
+	}
+
+	// Merged records and the same record cannot be merged again
+	for _, ids := range [][2]string{{"7", "8"}, {"8", "7"}, {"7", "guest@example.com"}} {
+		if _, _, err := svc.MergeUsers(ctx, ids[0], ids[1], ""); !domain.IsValidationError(err) {
+			t.Errorf("Expected merging %v to be refused, got %v", ids, err)
+		}
+	}
+	if _, _, err := svc.MergeUsers(ctx, "7", "99", ""); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("Expected an unknown guest to be reported, got %v", err)
+	}
+}
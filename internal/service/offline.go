@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// offlineClockSkew is how far in the future an offline redemption may lie,
+// allowing for tablets whose clock runs ahead
+const offlineClockSkew = 5 * time.Minute
+
+// ApplyOfflineRedemptions records redemptions collected offline with the
+// time they happened. Entries are applied in order and independently.
+// Applying the same entry again reports a duplicate, and an email already
+// redeemed at another time is reported as a conflict and left unchanged.
+func (s *Service) ApplyOfflineRedemptions(ctx context.Context, entries []domain.OfflineRedemption) []domain.OfflineRedemptionResult {
+	results := make([]domain.OfflineRedemptionResult, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, s.applyOfflineRedemption(ctx, entry))
+	}
+	return results
+}
+
+// applyOfflineRedemption records a single offline redemption
+func (s *Service) applyOfflineRedemption(ctx any, entry domain.OfflineRedemption) domain.OfflineRedemptionResult {
+	email := utils.NormalizeEmail(entry.Email)
+	result := domain.OfflineRedemptionResult{Email: email}
+
+	// Stored times have a resolution of a second in some backends
+	redeemed := entry.Redeemed.Truncate(time.Second)
+	switch {
+	case !utils.IsValidEmail(email):
+		result.Status, result.Error = "invalid", "invalid email"
+		return result
+	case redeemed.IsZero():
+		result.Status, result.Error = "invalid", "missing redemption time"
+		return result
+	case redeemed.After(time.Now().Add(offlineClockSkew)):
+		result.Status, result.Error = "invalid", "redemption time is in the future"
+		return result
+	case s.EventArchived() != nil:
+		result.Status = "archived"
+		return result
+	case s.blocklist.emailDenied(email):
+		result.Status = "denied"
+		return result
+	case s.sandbox.isTest(email):
+		s.sandbox.redeem(email, "", redeemed)
+		s.log(ctx).Info("Offline test redemption recorded in the sandbox", "email", email, "operator", entry.Operator)
+		result.Status, result.Redeemed = "redeemed", &redeemed
+		return result
+	}
+
+	user, err := s.findGuest(ctx, email)
+	switch {
+	case errors.Is(err, domain.ErrUserNotFound):
+		result.Status = "not_found"
+		return result
+	case errors.Is(err, domain.ErrDatabaseUnavailable):
+		result.Status = "unavailable"
+		return result
+	case err != nil:
+		s.log(ctx).Error("Error finding user for offline redemption", "email", email, "error", err)
+		result.Status, result.Error = "error", "database error"
+		return result
+	}
+
+	if user.IsRedeemed() {
+		result.Redeemed = user.Redeemed
+		if user.Redeemed.Truncate(time.Second).Equal(redeemed) {
+			result.Status = "duplicate"
+		} else {
+			s.log(ctx).Warn("Offline redemption conflicts with earlier redemption", "email", email,
+				"offline", redeemed, "redeemed", *user.Redeemed, "operator", entry.Operator)
+			result.Status = "conflict"
+		}
+		return result
+	}
+
+	user.Redeemed = &redeemed
+	if err := s.updateUser(ctx, user); err != nil {
+		if errors.Is(err, domain.ErrAlreadyRedeemed) {
+			// Redeemed by the bot since the lookup
+			result.Status = "conflict"
+			return result
+		}
+		s.log(ctx).Error("Error applying offline redemption", "email", email, "error", err)
+		result.Status, result.Error = "error", "database error"
+		return result
+	}
+
+	s.log(ctx).Info("Offline redemption applied", "email", email, "time", redeemed, "operator", entry.Operator)
+	details := "offline at " + redeemed.Format(time.RFC3339)
+	if entry.Operator != "" {
+		details += ", operator: " + entry.Operator
+	}
+	s.recordUserAudit(ctx, "system", audit.ActionRedeem, user, details)
+	s.analytics.RecordRedemption()
+	s.publishRedeemed(ctx, 0, "system", user, entry.Operator)
+
+	result.Status, result.Redeemed = "redeemed", &redeemed
+	return result
+}
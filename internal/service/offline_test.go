@@ -0,0 +1,56 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestApplyOfflineRedemptions(t *testing.T) {
+	earlier := time.Date(2025, 6, 1, 19, 0, 0, 0, time.UTC)
+	mockRepo := newMockRepository()
+	mockRepo.users["guest@example.com"] = &domain.User{ID: "1", Email: "guest@example.com", DateAdded: earlier}
+	mockRepo.users["redeemed@example.com"] = &domain.User{ID: "2", Email: "redeemed@example.com", DateAdded: earlier, Redeemed: &earlier}
+
+	// Offline redemptions are not rate limited
+	svc := service.NewForTest(mockRepo, ratelimit.New(1, 1), logger.New("error"))
+	ctx := context.Background()
+
+	door := time.Date(2025, 6, 1, 20, 15, 30, 0, time.UTC)
+	entries := []domain.OfflineRedemption{
+		{Email: "Guest@Example.com", Redeemed: door, Operator: "anna"},
+		{Email: "guest@example.com", Redeemed: door},
+		{Email: "redeemed@example.com", Redeemed: door},
+		{Email: "missing@example.com", Redeemed: door},
+		{Email: "not an email", Redeemed: door},
+		{Email: "guest@example.com"},
+		{Email: "guest@example.com", Redeemed: time.Now().Add(time.Hour)},
+	}
+	want := []string{"redeemed", "duplicate", "conflict", "not_found", "invalid", "invalid", "invalid"}
+
+	results := svc.ApplyOfflineRedemptions(ctx, entries)
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d results, got %d", len(want), len(results))
+	}
+	for i, result := range results {
+		if result.Status != want[i] {
+			t.Errorf("Entry %d: expected %s, got %s (%s)", i, want[i], result.Status, result.Error)
+		}
+	}
+	if results[2].Redeemed == nil || !results[2].Redeemed.Equal(earlier) {
+		t.Errorf("Expected the conflict to report the earlier redemption, got %v", results[2].Redeemed)
+	}
+
+	user := mockRepo.users["guest@example.com"]
+	if user.Redeemed == nil || !user.Redeemed.Equal(door) {
+		t.Errorf("Expected the offline time to be stored, got %v", user.Redeemed)
+	}
+	if !mockRepo.users["redeemed@example.com"].Redeemed.Equal(earlier) {
+		t.Error("Expected the earlier redemption to be kept")
+	}
+}
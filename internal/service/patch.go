@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// fieldChange is one field changed by a patch, as recorded in the audit log
+type fieldChange struct {
+	action  string
+	details string
+}
+
+// PatchUser applies a partial update to the guest named by ID or email
+// and returns the updated record. Fields set to their current value are
+// skipped. Each changed field gets its own audit entry, with the reason
+// of the patch. Invalid fields are reported as ValidationErrors.
+func (s *Service) PatchUser(ctx context.Context, id string, patch domain.UserPatch) (*domain.User, error) {
+	patch, err := domain.NormalizeUserPatch(patch)
+	if err != nil {
+		return nil, err
+	}
+	var newEmail string
+	if patch.Email != nil {
+		if !utils.IsValidEmail(*patch.Email) {
+			return nil, domain.NewValidationError("email", "invalid email format")
+		}
+		newEmail = utils.NormalizeEmail(*patch.Email)
+		if s.blocklist.emailDenied(newEmail) || s.sandbox.isTest(newEmail) {
+			return nil, domain.ErrEmailDenied
+		}
+	}
+
+	if s.EventArchived() != nil {
+		return nil, domain.ErrEventArchived
+	}
+
+	user, err := s.findUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsMerged() {
+		return nil, domain.NewValidationError("id", "the guest was merged into another record", user.Email)
+	}
+	if patch.Unredeem && !user.IsRedeemed() {
+		return nil, domain.NewValidationError("unredeem", "the guest has not redeemed")
+	}
+
+	reason := ""
+	if patch.Reason != "" {
+		reason = " (" + patch.Reason + ")"
+	}
+
+	// The email is changed first, since the record is found by it
+	emailChanged := newEmail != "" && newEmail != user.Email
+	if emailChanged {
+		changer, ok := s.repo.(repository.EmailChanger)
+		if !ok {
+			return nil, domain.ErrEmailChangeUnsupported
+		}
+		if err := changer.ChangeEmail(ctx, user.Email, newEmail); err != nil {
+			if !domain.IsDuplicateUser(err) {
+				s.log(ctx).Error("Error changing email", "email", user.Email, "error", err)
+			}
+			return nil, err
+		}
+		oldEmail := user.Email
+		user.Email = newEmail
+		s.recordUserAudit(ctx, "system", audit.ActionChangeEmail, user, "from "+oldEmail+reason)
+		s.indexSuggestion(newEmail)
+	}
+
+	var changes []fieldChange
+	if patch.Notes != nil && *patch.Notes != user.Notes {
+		user.Notes = *patch.Notes
+		changes = append(changes, fieldChange{audit.ActionUpdateUser, "notes" + reason})
+	}
+	if patch.FirstName != nil && *patch.FirstName != user.FirstName {
+		user.FirstName = *patch.FirstName
+		changes = append(changes, fieldChange{audit.ActionUpdateUser, "first name" + reason})
+	}
+	if patch.LastName != nil && *patch.LastName != user.LastName {
+		user.LastName = *patch.LastName
+		changes = append(changes, fieldChange{audit.ActionUpdateUser, "last name" + reason})
+	}
+	if patch.Tags != nil && !slices.Equal(*patch.Tags, user.Tags) {
+		user.Tags = *patch.Tags
+		changes = append(changes, fieldChange{audit.ActionUpdateUser, "tags: " + domain.JoinTags(user.Tags) + reason})
+	}
+	if patch.Unredeem {
+		details := fmt.Sprintf("was redeemed %s", user.Redeemed.Format("2006-01-02 15:04"))
+		if user.RedeemedAt != "" {
+			details += " at " + user.RedeemedAt
+		}
+		user.Redeemed, user.RedeemedAt = nil, ""
+		changes = append(changes, fieldChange{audit.ActionUnredeem, details + reason})
+	}
+	if len(changes) == 0 && !emailChanged {
+		return user, nil
+	}
+
+	if err := s.updateUser(ctx, user); err != nil {
+		s.log(ctx).Error("Error updating user", "email", user.Email, "error", err)
+		return nil, err
+	}
+	for _, change := range changes {
+		s.recordUserAudit(ctx, "system", change.action, user, change.details)
+	}
+	s.log(ctx).Info("User patched", "id", user.ID, "email", user.Email, "email_changed", emailChanged, "changes", len(changes))
+	return user, nil
+}
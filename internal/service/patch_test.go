@@ -0,0 +1,110 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+// ChangeEmail moves a user of the mock to another email
+func (r *mockRepository) ChangeEmail(ctx any, oldEmail, newEmail string) error {
+	if existing, ok := r.users[newEmail]; ok {
+		return domain.NewDuplicateUserError(newEmail, existing.ID)
+	}
+	user, ok := r.users[oldEmail]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	delete(r.users, oldEmail)
+	user.Email = newEmail
+	r.users[newEmail] = user
+	return nil
+}
+
+func TestPatchUser(t *testing.T) {
+	mockRepo := newMockRepository()
+	svc := service.NewForTest(mockRepo, ratelimit.New(100, 1000), logger.New("error"))
+	ctx := audit.NewContext(context.Background(), "admin_cli")
+
+	redeemed := time.Date(2025, 6, 1, 22, 10, 0, 0, time.UTC)
+	mockRepo.users["gest@example.com"] = &domain.User{ID: "7", Email: "gest@example.com", DateAdded: redeemed.Add(-time.Hour), Redeemed: &redeemed, RedeemedAt: "Rooftop"}
+	mockRepo.users["other@example.com"] = &domain.User{ID: "8", Email: "other@example.com", DateAdded: redeemed}
+
+	email, notes := " Guest@Example.com ", "Table 4"
+	tags := []string{"VIP", "press", "vip"}
+	user, err := svc.PatchUser(ctx, "7", domain.UserPatch{Email: &email, Notes: &notes, Tags: &tags, Reason: "typo at the door"})
+	if err != nil {
+		t.Fatalf("Failed to patch user: %v", err)
+	}
+	if user.Email != "guest@example.com" || user.Notes != "Table 4" || len(user.Tags) != 2 || user.Tags[0] != "vip" || user.Redeemed == nil {
+		t.Errorf("Unexpected patched user %+v", user)
+	}
+	if _, ok := mockRepo.users["gest@example.com"]; ok {
+		t.Error("Expected the old email to be gone")
+	}
+	if stored := mockRepo.users["guest@example.com"]; stored == nil || stored.ID != "7" || stored.Notes != "Table 4" {
+		t.Errorf("Expected the record to be stored under the new email, got %+v", stored)
+	}
+
+	// Each changed field has its own audit entry
+	entries, _, _ := svc.AuditLog(ctx, audit.Filter{Email: "guest@example.com"})
+	actions := map[string]int{}
+	for _, entry := range entries {
+		actions[entry.Action]++
+		if entry.Actor != "admin_cli" {
+			t.Errorf("Unexpected actor %q", entry.Actor)
+		}
+	}
+	if actions[audit.ActionChangeEmail] != 1 || actions[audit.ActionUpdateUser] != 2 {
+		t.Errorf("Expected an email change and two field updates, got %v", actions)
+	}
+
+	// Undoing a redemption needs a reason and clears the bar
+	if _, err := svc.PatchUser(ctx, "guest@example.com", domain.UserPatch{Unredeem: true}); !domain.IsValidationError(err) {
+		t.Errorf("Expected a missing reason to be refused, got %v", err)
+	}
+	user, err = svc.PatchUser(ctx, "guest@example.com", domain.UserPatch{Unredeem: true, Reason: "pressed by mistake"})
+	if err != nil || user.Redeemed != nil || user.RedeemedAt != "" {
+		t.Errorf("Expected the redemption to be undone, got %+v, %v", user, err)
+	}
+	if _, err := svc.PatchUser(ctx, "7", domain.UserPatch{Unredeem: true, Reason: "again"}); !domain.IsValidationError(err) {
+		t.Errorf("Expected undoing a missing redemption to be refused, got %v", err)
+	}
+
+	// Names have their spaces collapsed
+	user, err = svc.PatchUser(ctx, "7", domain.UserPatch{FirstName: ptr("  Anna "), LastName: ptr("Karenina")})
+	if err != nil || user.FirstName != "Anna" || user.FullName() != "Anna Karenina" {
+		t.Errorf("Expected the names to be set, got %+v, %v", user, err)
+	}
+
+	// Invalid and conflicting values change nothing
+	for _, patch := range []domain.UserPatch{
+		{},
+		{Email: ptr("not-an-email")},
+		{Tags: &[]string{"front row"}},
+		{Notes: ptr(string(make([]byte, domain.MaxNotesLength+1)))},
+		{FirstName: ptr(string(make([]byte, domain.MaxNameLength+1)))},
+	} {
+		if _, err := svc.PatchUser(ctx, "7", patch); !domain.IsValidationError(err) {
+			t.Errorf("Expected %+v to be refused, got %v", patch, err)
+		}
+	}
+	if _, err := svc.PatchUser(ctx, "7", domain.UserPatch{Email: ptr("other@example.com")}); !domain.IsDuplicateUser(err) {
+		t.Errorf("Expected the email of another guest to be refused, got %v", err)
+	}
+	if _, err := svc.PatchUser(ctx, "99", domain.UserPatch{Notes: ptr("x")}); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("Expected an unknown guest to be reported, got %v", err)
+	}
+}
+
+// ptr returns a pointer to a copy of v
+func ptr[T any](v T) *T {
+	return &v
+}
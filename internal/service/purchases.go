@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/payments"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// SetPayments enables selling extra drinks through the given manager
+func (s *Service) SetPayments(manager *payments.Manager) {
+	s.payments = manager
+}
+
+// PaymentsEnabled returns true if guests can buy extra drinks
+func (s *Service) PaymentsEnabled() bool {
+	return s.payments != nil
+}
+
+// CreateCheckout returns a payment link for an extra drink
+func (s *Service) CreateCheckout(ctx context.Context, userID int64, email string) (string, error) {
+	if s.payments == nil {
+		return "", domain.ErrPaymentsDisabled
+	}
+	return s.payments.Checkout(context.Background(), userID, utils.NormalizeEmail(email))
+}
+
+// HandlePaymentWebhook processes a payment confirmation from Stripe
+func (s *Service) HandlePaymentWebhook(payload []byte, signature string) error {
+	if s.payments == nil {
+		return domain.ErrPaymentsDisabled
+	}
+	return s.payments.HandleWebhook(payload, signature)
+}
+
+// PurchaseReport returns the drink purchases started in the given period
+func (s *Service) PurchaseReport(ctx context.Context, fromDate, toDate time.Time) ([]domain.Purchase, error) {
+	if s.payments == nil {
+		return nil, domain.ErrPaymentsDisabled
+	}
+
+	// Same defaults as the user reports
+	if fromDate.IsZero() {
+		fromDate = time.Now().AddDate(0, 0, -7)
+	}
+	if toDate.IsZero() {
+		toDate = time.Now()
+	}
+
+	purchases := s.payments.Purchases(fromDate, toDate)
+	s.log(ctx).Info("Purchase report generated", "count", len(purchases))
+	return purchases, nil
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/featureflags"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// registrationStore keeps the access requests of guests whose email is
+// not on the guest list, with the decisions of admins
+type registrationStore struct {
+	path    string // JSON file the requests are saved to, empty keeps them in memory only
+	mu      sync.Mutex
+	entries []domain.Registration
+	nextID  int
+}
+
+// loadRegistrations reads access requests saved by a previous run
+func loadRegistrations(path string) (*registrationStore, error) {
+	store := &registrationStore{path: path, nextID: 1}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registration file: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse registration file: %w", err)
+	}
+
+	for _, entry := range store.entries {
+		if id, err := strconv.Atoi(entry.ID); err == nil && id >= store.nextID {
+			store.nextID = id + 1
+		}
+	}
+	return store, nil
+}
+
+// save writes all entries to the file. The caller must hold mu.
+func (r *registrationStore) save() error {
+	if r.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(r.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash cannot leave a truncated file
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.path)
+}
+
+// request stores a new pending request for the email, unless one is
+// pending or was rejected. It returns the stored request and whether it is new.
+func (r *registrationStore) request(entry domain.Registration) (domain.Registration, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.entries {
+		if existing.Email == entry.Email && existing.Status != domain.RegistrationApproved {
+			return existing, false, nil
+		}
+	}
+
+	entry.ID = strconv.Itoa(r.nextID)
+	entry.Status = domain.RegistrationPending
+	r.nextID++
+	r.entries = append(r.entries, entry)
+	return entry, true, r.save()
+}
+
+// pending returns the requests waiting for an admin, oldest first
+func (r *registrationStore) pending() []domain.Registration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var entries []domain.Registration
+	for _, entry := range r.entries {
+		if entry.Status == domain.RegistrationPending {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// claim returns the pending request with the given ID and marks it
+// decided, so that two admins answering at once cannot both decide it.
+// The change is saved by the next call to flush.
+func (r *registrationStore) claim(id string, status domain.RegistrationStatus, actor string) (domain.Registration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.entries {
+		if r.entries[i].ID != id {
+			continue
+		}
+		if r.entries[i].Status != domain.RegistrationPending {
+			return r.entries[i], domain.ErrRegistrationDecided
+		}
+		now := time.Now()
+		r.entries[i].Status = status
+		r.entries[i].DecidedAt = &now
+		r.entries[i].DecidedBy = actor
+		return r.entries[i], nil
+	}
+	return domain.Registration{}, domain.ErrRegistrationNotFound
+}
+
+// release returns a claimed request to pending, after its email could not be added
+func (r *registrationStore) release(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.entries {
+		if r.entries[i].ID == id {
+			r.entries[i].Status = domain.RegistrationPending
+			r.entries[i].DecidedAt = nil
+			r.entries[i].DecidedBy = ""
+		}
+	}
+}
+
+// flush writes all entries to the file
+func (r *registrationStore) flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.save()
+}
+
+// SetSelfRegistration lets guests whose email is not found ask for access.
+// Requests are kept in the file at path, or in memory if it is empty.
+func (s *Service) SetSelfRegistration(path string) error {
+	store, err := loadRegistrations(path)
+	if err != nil {
+		return err
+	}
+	s.registrations = store
+	return nil
+}
+
+// SelfRegistrationEnabled returns true if guests can ask for access. The
+// self_registration feature flag turns it off, pending requests can still
+// be approved or rejected.
+func (s *Service) SelfRegistrationEnabled() bool {
+	return s.registrations != nil && s.features.Enabled(featureflags.SelfRegistration)
+}
+
+// RequestRegistration records a request for access to the guest list by
+// the Telegram user in chatID. If a request for the email is already
+// pending or was rejected, that request is returned and created is false.
+func (s *Service) RequestRegistration(ctx context.Context, userID, chatID int64, email, lang string) (reg domain.Registration, created bool, err error) {
+	if !s.SelfRegistrationEnabled() {
+		return domain.Registration{}, false, domain.ErrRegistrationDisabled
+	}
+	if !s.allow(ctx, userID) {
+		return domain.Registration{}, false, domain.ErrRateLimitExceeded
+	}
+
+	email = utils.NormalizeEmail(email)
+	if s.EventArchived() != nil {
+		return domain.Registration{}, false, domain.ErrEventArchived
+	}
+	if s.blocklist.emailDenied(email) {
+		s.log(ctx).Warn("Registration requested for denied email", "email", email, "user_id", userID)
+		return domain.Registration{}, false, domain.ErrEmailDenied
+	}
+
+	// The email may have been added since the guest checked it
+	if _, err := s.repo.FindByEmail(ctx, email); err == nil {
+		return domain.Registration{}, false, domain.ErrUserExists
+	} else if !domain.IsNotFound(err) {
+		s.log(ctx).Error("Error finding user for registration", "email", email, "error", err)
+		return domain.Registration{}, false, err
+	}
+
+	reg, created, err = s.registrations.request(domain.Registration{
+		Email:       email,
+		UserID:      userID,
+		ChatID:      chatID,
+		Language:    lang,
+		RequestedAt: time.Now(),
+	})
+	if err != nil {
+		s.log(ctx).Error("Error saving registration", "email", email, "error", err)
+	}
+	if created {
+		s.log(ctx).Info("Registration requested", "id", reg.ID, "email", email, "user_id", userID)
+	}
+	return reg, created, nil
+}
+
+// PendingRegistrations returns the access requests waiting for an admin
+func (s *Service) PendingRegistrations() []domain.Registration {
+	if s.registrations == nil {
+		return nil
+	}
+	return s.registrations.pending()
+}
+
+// ApproveRegistration adds the email of a pending request to the guest list
+func (s *Service) ApproveRegistration(ctx context.Context, id, actor string) (domain.Registration, error) {
+	if s.registrations == nil {
+		return domain.Registration{}, domain.ErrRegistrationDisabled
+	}
+	reg, err := s.registrations.claim(id, domain.RegistrationApproved, actor)
+	if err != nil {
+		return reg, err
+	}
+
+	user := &domain.User{
+		Email:     reg.Email,
+		DateAdded: time.Now(),
+		CreatedBy: "registration:" + strconv.FormatInt(reg.UserID, 10),
+	}
+	if err := s.AddUser(ctx, user); err != nil && !domain.IsDuplicateUser(err) {
+		s.registrations.release(id)
+		return reg, err
+	}
+	if err := s.registrations.flush(); err != nil {
+		s.log(ctx).Error("Error saving registration", "id", id, "error", err)
+	}
+
+	s.log(ctx).Info("Registration approved", "id", id, "email", reg.Email, "actor", actor)
+	return reg, nil
+}
+
+// RejectRegistration turns down a pending request. Later requests for the
+// email are answered with the rejection.
+func (s *Service) RejectRegistration(ctx context.Context, id, actor string) (domain.Registration, error) {
+	if s.registrations == nil {
+		return domain.Registration{}, domain.ErrRegistrationDisabled
+	}
+	reg, err := s.registrations.claim(id, domain.RegistrationRejected, actor)
+	if err != nil {
+		return reg, err
+	}
+	if err := s.registrations.flush(); err != nil {
+		s.log(ctx).Error("Error saving registration", "id", id, "error", err)
+	}
+
+	s.log(ctx).Info("Registration rejected", "id", id, "email", reg.Email, "actor", actor)
+	s.recordAudit(ctx, actor, audit.ActionRejectRegistration, reg.Email, "")
+	return reg, nil
+}
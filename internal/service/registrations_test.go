@@ -0,0 +1,81 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/featureflags"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestSelfRegistration(t *testing.T) {
+	mockRepo := newMockRepository()
+	svc := service.NewForTest(mockRepo, ratelimit.New(10, 100), logger.New("error"))
+	ctx := context.Background()
+
+	if _, _, err := svc.RequestRegistration(ctx, 42, 42, "walkin@example.com", "en"); !errors.Is(err, domain.ErrRegistrationDisabled) {
+		t.Fatalf("Expected registration to be disabled, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "registrations.json")
+	if err := svc.SetSelfRegistration(path); err != nil {
+		t.Fatalf("Failed to enable registration: %v", err)
+	}
+
+	reg, created, err := svc.RequestRegistration(ctx, 42, 42, "Walkin@Example.com", "de")
+	if err != nil || !created || reg.Status != domain.RegistrationPending || reg.Email != "walkin@example.com" {
+		t.Fatalf("Expected a new pending request, got %+v, %v, %v", reg, created, err)
+	}
+	if again, created, _ := svc.RequestRegistration(ctx, 42, 42, "walkin@example.com", "de"); created || again.ID != reg.ID {
+		t.Errorf("Expected the pending request to be returned, got %+v", again)
+	}
+
+	// Approval adds the guest, and the request cannot be decided twice
+	if _, err := svc.ApproveRegistration(ctx, reg.ID, "telegram:1"); err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	user, ok := mockRepo.users["walkin@example.com"]
+	if !ok || user.CreatedBy != "registration:42" {
+		t.Fatalf("Expected the guest to be added, got %+v", user)
+	}
+	if _, err := svc.RejectRegistration(ctx, reg.ID, "telegram:1"); !errors.Is(err, domain.ErrRegistrationDecided) {
+		t.Errorf("Expected the request to be decided already, got %v", err)
+	}
+	if _, _, err := svc.RequestRegistration(ctx, 42, 42, "walkin@example.com", "de"); !errors.Is(err, domain.ErrUserExists) {
+		t.Errorf("Expected an added guest not to register again, got %v", err)
+	}
+
+	// A rejected request is kept across restarts and answers later requests
+	other, _, _ := svc.RequestRegistration(ctx, 43, 43, "crasher@example.com", "en")
+	if _, err := svc.RejectRegistration(ctx, other.ID, "telegram:1"); err != nil {
+		t.Fatalf("Reject failed: %v", err)
+	}
+	if err := svc.SetSelfRegistration(path); err != nil {
+		t.Fatalf("Failed to reload registrations: %v", err)
+	}
+	again, created, err := svc.RequestRegistration(ctx, 43, 43, "crasher@example.com", "en")
+	if err != nil || created || again.Status != domain.RegistrationRejected {
+		t.Errorf("Expected the rejection to be kept, got %+v, %v", again, err)
+	}
+	if _, ok := mockRepo.users["crasher@example.com"]; ok {
+		t.Error("Rejected guest should not be added")
+	}
+	if pending := svc.PendingRegistrations(); len(pending) != 0 {
+		t.Errorf("Expected no pending requests, got %+v", pending)
+	}
+
+	// The feature flag turns requests off without dropping the kept ones
+	flags, _ := featureflags.New(map[string]bool{"self_registration": false}, false)
+	svc.SetFeatures(flags)
+	if svc.SelfRegistrationEnabled() {
+		t.Error("Expected registration to be off with its flag")
+	}
+	if _, _, err := svc.RequestRegistration(ctx, 44, 44, "late@example.com", "en"); !errors.Is(err, domain.ErrRegistrationDisabled) {
+		t.Errorf("Expected registration to be disabled by its flag, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// testCreatedBy is the source of the sandbox records of test emails
+const testCreatedBy = "test"
+
+// sandbox holds the test emails staff train with on the live bot. They
+// are always eligible, and their redemptions are kept in memory apart from
+// the guest list, so they never reach the database, stats or events.
+type sandbox struct {
+	mu      sync.Mutex
+	emails  map[string]bool
+	records map[string]*domain.User // Record of each test email used since start
+}
+
+// newSandbox creates a sandbox for the configured test emails
+func newSandbox(emails []string) *sandbox {
+	b := &sandbox{
+		emails:  make(map[string]bool),
+		records: make(map[string]*domain.User),
+	}
+	for _, email := range emails {
+		if email = utils.NormalizeEmail(email); email != "" {
+			b.emails[email] = true
+		}
+	}
+	return b
+}
+
+// isTest returns true if the normalized email is a test email
+func (b *sandbox) isTest(email string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.emails[email]
+}
+
+// record returns the sandbox record of a test email, created on first use
+// and reported as eligible whether or not it was redeemed
+func (b *sandbox) record(email string) *domain.User {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	user := *b.recordLocked(email)
+	user.Redeemed = nil
+	user.RedeemedAt = ""
+	return &user
+}
+
+// redeem records a redemption of a test email and returns its time
+func (b *sandbox) redeem(email, bar string, redeemed time.Time) time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	user := b.recordLocked(email)
+	user.Redeemed = &redeemed
+	user.RedeemedAt = bar
+	user.UpdatedAt = redeemed
+	return redeemed
+}
+
+// recordLocked returns the stored record of a test email, creating it if
+// needed. b.mu must be held.
+func (b *sandbox) recordLocked(email string) *domain.User {
+	user, ok := b.records[email]
+	if !ok {
+		now := time.Now()
+		user = &domain.User{ID: "test:" + email, Email: email, DateAdded: now, UpdatedAt: now, CreatedBy: testCreatedBy}
+		b.records[email] = user
+	}
+	return user
+}
+
+// report returns copies of the sandbox records in a report, selected the
+// way repositories select guests
+func (b *sandbox) report(params domain.ReportParams) []*domain.User {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var users []*domain.User
+	for _, record := range b.records {
+		if !params.Matches(record) {
+			continue
+		}
+		selected := record.DateAdded
+		if params.Type == domain.ReportTypeChanged {
+			selected = record.UpdatedAt
+		}
+		if selected.Before(params.From) || selected.After(params.To) {
+			continue
+		}
+		if params.Type == domain.ReportTypeRedeemed && record.Redeemed == nil ||
+			params.Type == domain.ReportTypeConsented && record.MarketingConsent == nil {
+			continue
+		}
+		user := *record
+		users = append(users, &user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+	return users
+}
+
+// SetTestEmails replaces the test emails, which are always eligible and
+// redeem into a sandbox instead of the guest list
+func (s *Service) SetTestEmails(emails []string) {
+	s.sandbox = newSandbox(emails)
+}
@@ -0,0 +1,62 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestTestEmails(t *testing.T) {
+	mockRepo := newMockRepository()
+	svc := service.NewForTest(mockRepo, ratelimit.New(100, 1000), logger.New("error"))
+	svc.SetBars([]string{"Rooftop"})
+	svc.SetTestEmails([]string{"Test+Bot@example.com"})
+	ctx := context.Background()
+
+	// Always eligible, even after redeeming
+	for i := 0; i < 2; i++ {
+		status, user, err := svc.CheckEmailStatus(ctx, 1, "test+bot@example.com")
+		if err != nil || status != "eligible" || user == nil || user.IsRedeemed() {
+			t.Fatalf("Expected the test email to be eligible, got %s %+v %v", status, user, err)
+		}
+		if _, err := svc.RedeemCocktailAt(ctx, 1, "test+bot@example.com", "Rooftop"); err != nil {
+			t.Fatalf("Unexpected error redeeming the test email: %v", err)
+		}
+	}
+
+	// Nothing reached the guest list, the stats or the audit log
+	if len(mockRepo.users) != 0 {
+		t.Errorf("Expected no guests, got %d", len(mockRepo.users))
+	}
+	if stats := svc.EngagementStats(); stats.Checks != 0 || stats.Redemptions != 0 {
+		t.Errorf("Expected no checks or redemptions in the stats, got %+v", stats)
+	}
+	if _, total, _ := svc.AuditLog(ctx, audit.Filter{}); total != 0 {
+		t.Errorf("Expected no audit entries, got %d", total)
+	}
+	err := svc.AddUser(ctx, &domain.User{ID: "1", Email: "test+bot@example.com", DateAdded: time.Now()})
+	if !errors.Is(err, domain.ErrEmailDenied) {
+		t.Errorf("Expected adding a test email to fail, got %v", err)
+	}
+
+	// Reports leave the sandbox out unless asked
+	from, to := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	users, err := svc.GenerateReport(ctx, "redeemed", from, to, domain.ReportFilter{})
+	if err != nil || len(users) != 0 {
+		t.Errorf("Expected an empty report, got %d users, %v", len(users), err)
+	}
+	users, err = svc.GenerateReport(ctx, "redeemed", from, to, domain.ReportFilter{IncludeTest: true})
+	if err != nil || len(users) != 1 || users[0].RedeemedAt != "Rooftop" || users[0].CreatedBy != "test" {
+		t.Fatalf("Expected the sandbox record, got %+v, %v", users, err)
+	}
+	if count, _ := svc.CountReport(ctx, "redeemed", from, to, domain.ReportFilter{IncludeTest: true, Bar: "Elsewhere"}); count != 0 {
+		t.Errorf("Expected filters to apply to the sandbox, got %d", count)
+	}
+}
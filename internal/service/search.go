@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+)
+
+// SearchGuests returns a page of the guests of the current event whose
+// email, name, notes or tags contain every word of the query, best match
+// first. SQL databases select the matches with a query, other backends
+// are searched in memory. A limit of 0 returns all matches.
+func (s *Service) SearchGuests(ctx context.Context, query string, offset, limit int) (domain.SearchResult, error) {
+	terms, err := domain.SearchTerms(query)
+	if err != nil {
+		return domain.SearchResult{}, err
+	}
+
+	result, err := repository.SearchUsers(ctx, s.repo, domain.SearchParams{Terms: terms, Offset: offset, Limit: limit})
+	if err != nil {
+		s.log(ctx).Error("Error searching guests", "terms", len(terms), "error", err)
+		return domain.SearchResult{}, err
+	}
+
+	s.log(ctx).Debug("Guests searched", "terms", len(terms), "total", result.Total)
+	return result, nil
+}
@@ -3,21 +3,57 @@ package service
 import (
 	"context"
 	"errors"
+	"strconv"
 	"time"
 
+	"github.com/ceesaxp/cocktail-bot/internal/analytics"
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
 	"github.com/ceesaxp/cocktail-bot/internal/config"
 	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/events"
+	"github.com/ceesaxp/cocktail-bot/internal/featureflags"
+	"github.com/ceesaxp/cocktail-bot/internal/httpclient"
+	"github.com/ceesaxp/cocktail-bot/internal/jobs"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/notify"
+	"github.com/ceesaxp/cocktail-bot/internal/payments"
+	"github.com/ceesaxp/cocktail-bot/internal/ports"
 	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
 	"github.com/ceesaxp/cocktail-bot/internal/repository"
 	"github.com/ceesaxp/cocktail-bot/internal/utils"
 )
 
+// Service implements every port the channels use
+var _ ports.Service = (*Service)(nil)
+
 // Service handles business logic for the bot
 type Service struct {
-	repo    domain.Repository
-	limiter *ratelimit.Limiter
-	logger  *logger.Logger
+	repo          domain.Repository
+	limiter       *ratelimit.Limiter
+	logger        *logger.Logger
+	verifier      *verifier // nil when email verification is disabled
+	analytics     *analytics.Tracker
+	deadLetters   *deadLetterStore
+	alerters      []notify.Alerter
+	blocklist     *blocklist
+	sandbox       *sandbox          // Test emails and their redemptions
+	enumeration   *enumerationGuard // Alarms about sources guessing the guest list, nil if disabled
+	payments      *payments.Manager // nil when drink purchases are disabled
+	tickets       *ticketIssuer     // nil when redemption tickets are disabled
+	archives      *archiveStore
+	registrations *registrationStore // nil when self-registration is disabled
+	audit         *audit.Log
+	suggestions   suggestIndex  // Guest emails for typo suggestions
+	bars          []string      // Bars that serve drinks, empty for a single bar
+	event         string        // Name of the event being served
+	archiveDir    string        // Where report bundles of archived events are written
+	accessMode    string        // config.AccessModeEmail or config.AccessModeVoucher
+	voucherKey    []byte        // Signs voucher codes, empty when they are disabled
+	retryPrimary  time.Duration // How often a failed primary database is retried, 0 without failover
+	imports       *importQueue  // nil when imports are not queued
+	jobs          *jobs.Manager // Imports, migrations and other long-running operations
+	events        *events.Hub   // Redemptions and other guest events, for other parts of the process
+	features      *featureflags.Set
 }
 
 // New creates a new service instance
@@ -29,28 +65,176 @@ func New(ctx context.Context, cfg *config.Config, logger *logger.Logger) (*Servi
 	}
 
 	// Initialize rate limiter
-	limiter := ratelimit.New(cfg.RateLimiting.RequestsPerMinute, cfg.RateLimiting.RequestsPerHour)
+	limiter := ratelimit.NewWithCleanup(cfg.RateLimiting.RequestsPerMinute, cfg.RateLimiting.RequestsPerHour, cfg.RateLimiting.CleanupInterval.Duration())
 
-	return &Service{
-		repo:    repo,
-		limiter: limiter,
-		logger:  logger,
-	}, nil
+	// Load redemptions that failed in a previous run
+	deadLetters, err := loadDeadLetters(cfg.Database.DeadLetterFile)
+	if err != nil {
+		repo.Close()
+		return nil, err
+	}
+
+	// Load events archived in a previous run
+	archives, err := loadArchives(cfg.Event.ArchiveFile)
+	if err != nil {
+		repo.Close()
+		return nil, err
+	}
+
+	auditLog, err := audit.Open(cfg.Event.AuditFile)
+	if err != nil {
+		repo.Close()
+		return nil, err
+	}
+
+	features, err := featureflags.New(cfg.Features.Flags, cfg.Features.RuntimeToggle)
+	if err != nil {
+		repo.Close()
+		return nil, err
+	}
+
+	svc := &Service{
+		repo:        repo,
+		limiter:     limiter,
+		logger:      logger,
+		analytics:   analytics.New(),
+		deadLetters: deadLetters,
+		blocklist:   newBlocklist(cfg.Telegram.BlockedUsers, cfg.Event.DeniedEmails),
+		sandbox:     newSandbox(cfg.Event.TestEmails),
+		archives:    archives,
+		audit:       auditLog,
+		bars:        cfg.Event.Bars,
+		event:       cfg.Event.Name,
+		archiveDir:  cfg.Event.ArchiveDir,
+		jobs:        jobs.NewManager(0),
+		events:      events.NewHub(),
+		features:    features,
+	}
+	svc.suggestions.ttl = cfg.Database.CacheTTL.Duration()
+	repository.SetConflictHandler(repo, svc.recordSpoolConflict)
+	if cfg.RateLimiting.Enumeration.Enabled {
+		svc.SetEnumerationGuard(cfg.RateLimiting.Enumeration)
+	}
+	switch {
+	case cfg.Database.Fallback.Type != "":
+		svc.retryPrimary = time.Duration(cfg.Database.Fallback.RetrySeconds) * time.Second
+	case cfg.GetDatabaseType() == "googlesheet" && cfg.Database.SheetsBreaker.Enabled:
+		svc.retryPrimary = cfg.Database.SheetsBreaker.Cooldown.Duration()
+	}
+
+	// Buffer added users to write them in batches
+	if cfg.Database.WriteBehind.JournalFile != "" {
+		if err := svc.SetWriteBehind(cfg.Database.WriteBehind); err != nil {
+			repo.Close()
+			return nil, err
+		}
+	}
+
+	// Initialize guest access
+	if err := svc.SetAccess(cfg.Event.Access); err != nil {
+		repo.Close()
+		return nil, err
+	}
+
+	// Queue imports to a message broker
+	if cfg.API.ImportQueue.Enabled {
+		if err := svc.SetImportQueue(cfg.API.ImportQueue); err != nil {
+			repo.Close()
+			return nil, err
+		}
+	}
+
+	// Let guests ask for access
+	if cfg.Telegram.SelfRegistration {
+		if err := svc.SetSelfRegistration(cfg.Telegram.RegistrationFile); err != nil {
+			repo.Close()
+			return nil, err
+		}
+	}
+
+	// Initialize drink purchases
+	if cfg.Payments.Enabled {
+		manager, err := payments.New(cfg.Payments, httpclient.New(httpclient.FromConfig(cfg, "stripe")), logger)
+		if err != nil {
+			repo.Close()
+			return nil, err
+		}
+		svc.SetPayments(manager)
+	}
+
+	// Initialize redemption tickets
+	if cfg.Tickets.Enabled {
+		if err := svc.SetTickets(cfg.Tickets, cfg.Event.Name); err != nil {
+			repo.Close()
+			return nil, err
+		}
+	}
+
+	// Alert staff through Slack in addition to any alerters added later
+	if cfg.Notify.SlackWebhook != "" {
+		opts := httpclient.FromConfig(cfg, "slack")
+		opts.RetryPost = true // Alerts are better sent twice than lost
+		svc.AddAlerter(notify.NewSlackAlerter(cfg.Notify.SlackWebhook, httpclient.New(opts), logger))
+	}
+
+	// Initialize email verification
+	if cfg.Event.Verification.Enabled {
+		notifier, err := notify.New(cfg.Notify, logger)
+		if err != nil {
+			repo.Close()
+			return nil, err
+		}
+		svc.SetVerification(cfg.Event.Verification, notifier)
+	}
+
+	return svc, nil
 }
 
 // NewForTest creates a new service instance for testing
 func NewForTest(repo domain.Repository, limiter *ratelimit.Limiter, logger *logger.Logger) *Service {
-	return &Service{
-		repo:    repo,
-		limiter: limiter,
-		logger:  logger,
+	deadLetters, _ := loadDeadLetters("")
+	archives, _ := loadArchives("")
+	auditLog, _ := audit.Open("")
+	svc := &Service{
+		repo:        repo,
+		limiter:     limiter,
+		logger:      logger,
+		analytics:   analytics.New(),
+		deadLetters: deadLetters,
+		blocklist:   newBlocklist(nil, nil),
+		sandbox:     newSandbox(nil),
+		archives:    archives,
+		audit:       auditLog,
+		jobs:        jobs.NewManager(0),
+		events:      events.NewHub(),
 	}
+	repository.SetConflictHandler(repo, svc.recordSpoolConflict)
+	return svc
+}
+
+// log returns the logger carried by the context, which adds the IDs of
+// the request or chat being handled, or the service logger
+func (s *Service) log(ctx any) *logger.Logger {
+	return logger.FromContext(ctx, s.logger)
+}
+
+// allow applies the rate limit to the client of the context, such as an
+// API client, or else to the user ID
+func (s *Service) allow(ctx any, userID int64) bool {
+	return s.limiter.AllowKey(ratelimit.KeyFromContext(ctx, ratelimit.IDKey(userID)))
 }
 
 // CheckEmailStatus checks if an email exists in the database and if it has been redeemed
-func (s *Service) CheckEmailStatus(ctx any, userID int64, email string) (status string, user *domain.User, err error) {
+func (s *Service) CheckEmailStatus(ctx context.Context, userID int64, email string) (status string, user *domain.User, err error) {
 	// Apply rate limiting
-	if !s.limiter.Allow(userID) {
+	if !s.allow(ctx, userID) {
+		return "rate_limited", nil, nil
+	}
+
+	// Sources blocked for guessing the guest list wait for the cooldown
+	key := ratelimit.KeyFromContext(ctx, ratelimit.IDKey(userID))
+	if s.enumeration != nil && s.enumeration.blocked(key) > 0 {
+		s.log(ctx).Info("Check refused after email enumeration alarm", "source", key, "user_id", userID)
 		return "rate_limited", nil, nil
 	}
 
@@ -58,73 +242,241 @@ func (s *Service) CheckEmailStatus(ctx any, userID int64, email string) (status
 	email = utils.NormalizeEmail(email)
 
 	// Log the lookup
-	s.logger.Info("Checking email status", "email", email, "user_id", userID)
+	s.log(ctx).Info("Checking email status", "email", email, "user_id", userID)
+
+	// The records of an archived event are frozen
+	if s.EventArchived() != nil {
+		return "archived", nil, nil
+	}
+
+	// Test emails are always eligible and never looked up
+	if s.sandbox.isTest(email) {
+		s.log(ctx).Info("Test email checked", "email", email, "user_id", userID)
+		return "eligible", s.sandbox.record(email), nil
+	}
+
+	// Denied emails are never eligible
+	if s.blocklist.emailDenied(email) {
+		s.log(ctx).Info("Email is on the deny list", "email", email, "user_id", userID)
+		s.analytics.RecordCheck(false)
+		return "denied", nil, nil
+	}
 
 	// Find user by email
-	user, err = s.repo.FindByEmail(ctx, email)
+	user, err = s.findGuest(ctx, email)
 	if err != nil {
 		if err == domain.ErrUserNotFound {
-			s.logger.Info("Email not found in database", "email", email)
+			s.log(ctx).Info("Email not found in database", "email", email)
+			s.analytics.RecordCheck(false)
+			s.noteUnknownEmail(ctx, key, userID, email)
 			return "not_found", nil, nil
 		}
 		if err == domain.ErrDatabaseUnavailable {
-			s.logger.Error("Database unavailable", "error", err)
+			s.log(ctx).Sampled("database_unavailable").Error("Database unavailable", "error", err)
 			return "unavailable", nil, err
 		}
-		s.logger.Error("Error finding user", "email", email, "error", err)
+		s.log(ctx).Sampled("find_user").Error("Error finding user", "email", email, "error", err)
 		return "error", nil, err
 	}
 
 	// Check if already redeemed
 	if user.IsRedeemed() {
-		s.logger.Info("Email already redeemed", "email", email, "redeemed_at", user.Redeemed)
+		s.log(ctx).Info("Email already redeemed", "email", email, "redeemed_at", user.Redeemed)
+		s.analytics.RecordCheck(false)
 		return "redeemed", user, nil
 	}
 
-	s.logger.Info("Email eligible for redemption", "email", email)
+	s.log(ctx).Info("Email eligible for redemption", "email", email)
+	s.analytics.RecordCheck(true)
 	return "eligible", user, nil
 }
 
 // RedeemCocktail marks a user as having redeemed their cocktail
-func (s *Service) RedeemCocktail(ctx any, userID int64, email string) (time.Time, error) {
+func (s *Service) RedeemCocktail(ctx context.Context, userID int64, email string) (time.Time, error) {
+	return s.RedeemCocktailAt(ctx, userID, email, "")
+}
+
+// RedeemCocktailAt marks the cocktail of an email as redeemed and records
+// the bar that served it. bar must be one of the configured bars, or empty.
+func (s *Service) RedeemCocktailAt(ctx context.Context, userID int64, email, bar string) (time.Time, error) {
+	if bar != "" && !s.knownBar(bar) {
+		return time.Time{}, domain.ErrUnknownBar
+	}
+
 	// Apply rate limiting (just to be extra safe, though the button should be gone)
-	if !s.limiter.Allow(userID) {
+	if !s.allow(ctx, userID) {
 		return time.Time{}, nil // No error because this is a rare edge case
 	}
 
 	// Normalize email
 	email = utils.NormalizeEmail(email)
 
+	if s.EventArchived() != nil {
+		s.log(ctx).Warn("Redemption attempted after the event was archived", "email", email, "user_id", userID)
+		return time.Time{}, domain.ErrEventArchived
+	}
+
+	if s.blocklist.emailDenied(email) {
+		s.log(ctx).Warn("Redemption attempted for denied email", "email", email, "user_id", userID)
+		return time.Time{}, domain.ErrEmailDenied
+	}
+
+	// Require proof of email ownership when enabled
+	if s.verifier != nil && !s.verifier.isVerified(userID, email) {
+		s.log(ctx).Warn("Redemption attempted without verified email", "email", email, "user_id", userID)
+		return time.Time{}, domain.ErrEmailNotVerified
+	}
+
+	// Test emails redeem into the sandbox, leaving the guest list and stats alone
+	if s.sandbox.isTest(email) {
+		redeemed := s.sandbox.redeem(email, bar, time.Now())
+		s.log(ctx).Info("Test redemption recorded in the sandbox", "email", email, "user_id", userID, "bar", bar)
+		return redeemed, nil
+	}
+
 	// Find user by email
-	user, err := s.repo.FindByEmail(ctx, email)
+	user, err := s.findGuest(ctx, email)
 	if err != nil {
-		s.logger.Error("Error finding user for redemption", "email", email, "error", err)
+		s.log(ctx).Error("Error finding user for redemption", "email", email, "error", err)
 		return time.Time{}, err
 	}
 
 	// Check if already redeemed (double-check, should not happen)
 	if user.IsRedeemed() {
-		s.logger.Warn("Attempted to redeem already redeemed email", "email", email, "user_id", userID)
+		s.log(ctx).Warn("Attempted to redeem already redeemed email", "email", email, "user_id", userID)
 		return *user.Redeemed, nil
 	}
 
 	// Mark as redeemed
 	user.Redeem()
+	user.RedeemedAt = bar
 
 	// Update user in repository
-	if err := s.repo.UpdateUser(ctx, user); err != nil {
-		s.logger.Error("Error updating user for redemption", "email", email, "error", err)
+	if err := s.updateUser(ctx, user); err != nil {
+		s.log(ctx).Error("Error updating user for redemption", "email", email, "error", err)
+		if !errors.Is(err, domain.ErrAlreadyRedeemed) {
+			s.recordFailedRedemption(userID, email, bar, err)
+		}
 		return time.Time{}, err
 	}
 
 	// Log the redemption
-	s.logger.Info("Cocktail redeemed", "email", email, "user_id", userID, "time", *user.Redeemed)
+	s.log(ctx).Info("Cocktail redeemed", "email", email, "user_id", userID, "time", *user.Redeemed, "bar", bar)
+	details := ""
+	if bar != "" {
+		details = "bar: " + bar
+	}
+	s.recordUserAudit(ctx, "user:"+strconv.FormatInt(userID, 10), audit.ActionRedeem, user, details)
+	s.analytics.RecordRedemption()
+	s.issueTicket(email, *user.Redeemed)
+	s.publishRedeemed(ctx, userID, "user:"+strconv.FormatInt(userID, 10), user, "")
 
 	return *user.Redeemed, nil
 }
 
+// SetMarketingConsent records whether a user wants to hear about future events
+func (s *Service) SetMarketingConsent(ctx context.Context, userID int64, email string, consent bool) error {
+	// Normalize email
+	email = utils.NormalizeEmail(email)
+
+	if s.EventArchived() != nil {
+		return domain.ErrEventArchived
+	}
+
+	// Find user by email
+	user, err := s.findGuest(ctx, email)
+	if err != nil {
+		s.log(ctx).Error("Error finding user for marketing consent", "email", email, "error", err)
+		return err
+	}
+
+	// Record or withdraw consent
+	if consent {
+		now := time.Now()
+		user.MarketingConsent = &now
+	} else {
+		user.MarketingConsent = nil
+	}
+
+	// Update user in repository
+	if err := s.updateUser(ctx, user); err != nil {
+		s.log(ctx).Error("Error updating marketing consent", "email", email, "error", err)
+		return err
+	}
+
+	s.log(ctx).Info("Marketing consent updated", "email", email, "user_id", userID, "consent", consent)
+	details := "withdrawn"
+	if consent {
+		details = "granted"
+	}
+	s.recordUserAudit(ctx, "user:"+strconv.FormatInt(userID, 10), audit.ActionConsent, user, details)
+	return nil
+}
+
+// TrackInteraction records a bot message for engagement statistics
+func (s *Service) TrackInteraction(lang, command string) {
+	s.analytics.RecordInteraction(lang, command)
+}
+
+// TrackRedeemButton records that a guest was offered a redeem button, for
+// the redemption funnel
+func (s *Service) TrackRedeemButton() {
+	s.analytics.RecordButtonShown()
+}
+
+// EngagementStats returns bot usage statistics since startup
+func (s *Service) EngagementStats() analytics.Engagement {
+	return s.analytics.Snapshot()
+}
+
+// FunnelStats returns the redemption funnel per day since startup
+func (s *Service) FunnelStats() analytics.Funnel {
+	return s.analytics.Funnel()
+}
+
+// Status returns the totals processed since startup, the redemptions
+// waiting for a retry and the last errors logged
+func (s *Service) Status() domain.RuntimeStatus {
+	engagement := s.analytics.Snapshot()
+	status := domain.RuntimeStatus{
+		Started:            engagement.Since,
+		UptimeSeconds:      int64(time.Since(engagement.Since).Seconds()),
+		Interactions:       engagement.Interactions,
+		Checks:             engagement.Checks,
+		Redemptions:        engagement.Redemptions,
+		PendingRedemptions: len(s.deadLetters.list()),
+		LastErrors:         []domain.LoggedError{},
+	}
+	if s.logger != nil {
+		for _, record := range s.logger.RecentErrors() {
+			status.LastErrors = append(status.LastErrors, domain.LoggedError{Time: record.Time, Message: record.Message})
+		}
+	}
+	return status
+}
+
+// DatabaseHealth checks that the repository is reachable
+func (s *Service) DatabaseHealth(ctx context.Context) error {
+	return s.repo.Health(ctx)
+}
+
+// DatabaseStats returns record counts and backend information of the repository
+func (s *Service) DatabaseStats(ctx context.Context) (domain.RepoStats, error) {
+	stats, err := s.repo.Stats(ctx)
+	if err != nil {
+		s.log(ctx).Error("Error collecting database stats", "error", err)
+		return stats, err
+	}
+	return stats, nil
+}
+
+// FindUser looks up a user by email without rate limiting, for internal jobs
+func (s *Service) FindUser(ctx context.Context, email string) (*domain.User, error) {
+	return s.repo.FindByEmail(ctx, utils.NormalizeEmail(email))
+}
+
 // UpdateUser updates an existing user in the database
-func (s *Service) UpdateUser(ctx any, user *domain.User) error {
+func (s *Service) UpdateUser(ctx context.Context, user *domain.User) error {
 	if user == nil {
 		return errors.New("user cannot be nil")
 	}
@@ -132,20 +484,25 @@ func (s *Service) UpdateUser(ctx any, user *domain.User) error {
 	// Normalize email (in case it wasn't already)
 	user.Email = utils.NormalizeEmail(user.Email)
 
+	if s.EventArchived() != nil {
+		return domain.ErrEventArchived
+	}
+
 	// Log the operation
-	s.logger.Info("Updating user", "email", user.Email, "id", user.ID)
+	s.log(ctx).Info("Updating user", "email", user.Email, "id", user.ID)
 
 	// Update user in repository
-	if err := s.repo.UpdateUser(ctx, user); err != nil {
-		s.logger.Error("Error updating user", "email", user.Email, "error", err)
+	if err := s.updateUser(ctx, user); err != nil {
+		s.log(ctx).Error("Error updating user", "email", user.Email, "error", err)
 		return err
 	}
 
+	s.recordUserAudit(ctx, "system", audit.ActionUpdateUser, user, "")
 	return nil
 }
 
 // AddUser adds a new user to the database
-func (s *Service) AddUser(ctx any, user *domain.User) error {
+func (s *Service) AddUser(ctx context.Context, user *domain.User) error {
 	if user == nil {
 		return errors.New("user cannot be nil")
 	}
@@ -153,29 +510,110 @@ func (s *Service) AddUser(ctx any, user *domain.User) error {
 	// Normalize email (in case it wasn't already)
 	user.Email = utils.NormalizeEmail(user.Email)
 
+	if s.EventArchived() != nil {
+		return domain.ErrEventArchived
+	}
+
+	if s.blocklist.emailDenied(user.Email) {
+		s.log(ctx).Warn("Refusing to add denied email", "email", user.Email)
+		return domain.ErrEmailDenied
+	}
+
+	// Test emails never join the guest list
+	if s.sandbox.isTest(user.Email) {
+		s.log(ctx).Warn("Refusing to add test email", "email", user.Email)
+		return domain.ErrEmailDenied
+	}
+
 	// Log the operation
-	s.logger.Info("Adding new user", "email", user.Email, "id", user.ID)
+	s.log(ctx).Info("Adding new user", "email", user.Email, "id", user.ID, "created_by", user.CreatedBy)
 
 	// Add user to repository
+	user.UpdatedAt = time.Now()
 	if err := s.repo.AddUser(ctx, user); err != nil {
-		s.logger.Error("Error adding user", "email", user.Email, "error", err)
+		if domain.IsDuplicateUser(err) {
+			s.log(ctx).Info("User already exists", "email", user.Email)
+			return err
+		}
+		s.log(ctx).Error("Error adding user", "email", user.Email, "error", err)
 		return err
 	}
 
+	actor := user.CreatedBy
+	if actor == "" {
+		actor = "system"
+	}
+	s.recordUserAudit(ctx, actor, audit.ActionAddUser, user, "")
+	s.indexSuggestion(user.Email)
 	return nil
 }
 
-// GenerateReport retrieves users based on report parameters
-func (s *Service) GenerateReport(ctx any, reportType string, fromDate, toDate time.Time) ([]*domain.User, error) {
-	// Validate report type
-	validReportType, err := domain.ValidateReportType(reportType)
+// updateUser writes a changed user to the repository, recording when it changed
+func (s *Service) updateUser(ctx any, user *domain.User) error {
+	user.UpdatedAt = time.Now()
+	return s.repo.UpdateUser(ctx, user)
+}
+
+// GenerateReport retrieves users based on report parameters, narrowed by
+// the optional filter
+func (s *Service) GenerateReport(ctx context.Context, reportType string, fromDate, toDate time.Time, filter domain.ReportFilter) ([]*domain.User, error) {
+	params, err := s.reportParams(ctx, reportType, fromDate, toDate, filter)
 	if err != nil {
-		s.logger.Error("Invalid report type", "report_type", reportType, "error", err)
 		return nil, err
 	}
 
 	// Log the operation
-	s.logger.Info("Generating report", "type", validReportType, "from", fromDate, "to", toDate)
+	s.log(ctx).Info("Generating report", "type", params.Type, "from", params.From, "to", params.To, "domain", params.Domain, "source", params.Source, "bar", params.Bar)
+
+	// Get report from repository
+	users, err := s.repo.GetReport(ctx, params)
+	if err != nil {
+		s.log(ctx).Error("Error generating report", "type", params.Type, "error", err)
+		return nil, err
+	}
+	if params.IncludeTest {
+		users = append(users, s.sandbox.report(params)...)
+	}
+
+	s.log(ctx).Info("Report generated successfully", "type", params.Type, "count", len(users))
+	return users, nil
+}
+
+// CountReport counts the users of a report without loading them, where
+// the repository can count with a query
+func (s *Service) CountReport(ctx context.Context, reportType string, fromDate, toDate time.Time, filter domain.ReportFilter) (int, error) {
+	params, err := s.reportParams(ctx, reportType, fromDate, toDate, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := repository.CountReport(ctx, s.repo, params)
+	if err != nil {
+		s.log(ctx).Error("Error counting report", "type", params.Type, "error", err)
+		return 0, err
+	}
+	if params.IncludeTest {
+		count += len(s.sandbox.report(params))
+	}
+
+	s.log(ctx).Debug("Report counted", "type", params.Type, "from", params.From, "to", params.To, "count", count)
+	return count, nil
+}
+
+// reportParams checks the type and filter of a report, and defaults the
+// date range to the last 7 days
+func (s *Service) reportParams(ctx context.Context, reportType string, fromDate, toDate time.Time, filter domain.ReportFilter) (domain.ReportParams, error) {
+	// Validate report type
+	validReportType, err := domain.ValidateReportType(reportType)
+	if err != nil {
+		s.log(ctx).Error("Invalid report type", "report_type", reportType, "error", err)
+		return domain.ReportParams{}, err
+	}
+	filter, err = domain.NormalizeReportFilter(filter)
+	if err != nil {
+		s.log(ctx).Error("Invalid report filter", "error", err)
+		return domain.ReportParams{}, err
+	}
 
 	// Set default date range if not provided
 	if fromDate.IsZero() {
@@ -188,25 +626,53 @@ func (s *Service) GenerateReport(ctx any, reportType string, fromDate, toDate ti
 		toDate = time.Now()
 	}
 
-	// Create report parameters
-	params := domain.ReportParams{
-		Type: validReportType,
-		From: fromDate,
-		To:   toDate,
-	}
+	return domain.ReportParams{
+		Type:         validReportType,
+		From:         fromDate,
+		To:           toDate,
+		ReportFilter: filter,
+	}, nil
+}
 
-	// Get report from repository
-	users, err := s.repo.GetReport(ctx, params)
-	if err != nil {
-		s.logger.Error("Error generating report", "type", validReportType, "error", err)
-		return nil, err
+// ResetRateLimit clears the rate limit history for a Telegram user
+func (s *Service) ResetRateLimit(userID int64) {
+	s.limiter.ResetFor(userID)
+	s.logger.Info("Rate limit reset", "user_id", userID)
+}
+
+// RetryAfter returns how long a user has to wait until the rate limit, or
+// a block after an enumeration alarm, allows their next request, or 0 if
+// they are not limited
+func (s *Service) RetryAfter(ctx context.Context, userID int64) time.Duration {
+	key := ratelimit.KeyFromContext(ctx, ratelimit.IDKey(userID))
+	wait := s.limiter.RetryAfterKey(key)
+	if s.enumeration != nil {
+		wait = max(wait, s.enumeration.blocked(key))
 	}
+	return wait
+}
 
-	s.logger.Info("Report generated successfully", "type", validReportType, "count", len(users))
-	return users, nil
+// UnavailableRetryAfter returns how long guests should wait before trying
+// again while the database is unavailable. With failover configured it is
+// the interval at which the primary database is retried, otherwise 0
+// because there is no estimate.
+func (s *Service) UnavailableRetryAfter() time.Duration {
+	return s.retryPrimary
+}
+
+// RateLimitStats returns the state of the rate limiter of bot users and
+// API clients, with the top consumers of the last hour
+func (s *Service) RateLimitStats(top int) ratelimit.Stats {
+	return s.limiter.Stats(top)
 }
 
 // Close closes the service and its dependencies
 func (s *Service) Close() error {
+	// Workers finish the received emails before the database is closed
+	if s.imports != nil {
+		if err := s.imports.close(); err != nil {
+			s.logger.Error("Error closing import queue", "error", err)
+		}
+	}
 	return s.repo.Close()
 }
@@ -2,9 +2,12 @@ package service_test
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
 	"github.com/ceesaxp/cocktail-bot/internal/domain"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
 	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
@@ -13,7 +16,8 @@ import (
 
 // mockRepository is a mock implementation of domain.Repository
 type mockRepository struct {
-	users map[string]*domain.User
+	users     map[string]*domain.User
+	updateErr error // Returned by UpdateUser when set
 }
 
 func newMockRepository() *mockRepository {
@@ -37,6 +41,9 @@ func (r *mockRepository) FindByEmail(ctx any, email string) (*domain.User, error
 }
 
 func (r *mockRepository) UpdateUser(ctx any, user *domain.User) error {
+	if r.updateErr != nil {
+		return r.updateErr
+	}
 	_, exists := r.users[user.Email]
 	if !exists {
 		return domain.ErrUserNotFound
@@ -70,6 +77,9 @@ func (r *mockRepository) GetReport(ctx any, params domain.ReportParams) ([]*doma
 	var results []*domain.User
 	
 	for _, user := range r.users {
+		if !params.Matches(user) {
+			continue
+		}
 		// Apply date filter
 		if !user.DateAdded.Before(params.From) && !user.DateAdded.After(params.To) {
 			// Apply report type filter
@@ -84,6 +94,11 @@ func (r *mockRepository) GetReport(ctx any, params domain.ReportParams) ([]*doma
 					}
 					results = append(results, &userCopy)
 				}
+			case domain.ReportTypeConsented:
+				if user.MarketingConsent != nil {
+					userCopy := *user
+					results = append(results, &userCopy)
+				}
 			case domain.ReportTypeAdded:
 				// Make a deep copy to prevent mutation
 				userCopy := *user
@@ -107,6 +122,20 @@ func (r *mockRepository) GetReport(ctx any, params domain.ReportParams) ([]*doma
 	return results, nil
 }
 
+func (r *mockRepository) Health(ctx any) error {
+	return nil
+}
+
+func (r *mockRepository) Stats(ctx any) (domain.RepoStats, error) {
+	stats := domain.RepoStats{Backend: "mock", Users: len(r.users)}
+	for _, user := range r.users {
+		if user.Redeemed != nil {
+			stats.Redeemed++
+		}
+	}
+	return stats, nil
+}
+
 func (r *mockRepository) Close() error {
 	return nil
 }
@@ -235,6 +264,9 @@ func TestRedeemCocktail(t *testing.T) {
 	if updatedUser == nil || updatedUser.Redeemed == nil {
 		t.Errorf("User should have been updated with redemption time")
 	}
+	if updatedUser != nil && updatedUser.UpdatedAt.Before(now) {
+		t.Errorf("Expected the change time to be recorded, got %v", updatedUser.UpdatedAt)
+	}
 
 	// Test redeeming already redeemed user
 	oldRedeemTime, err := svc.RedeemCocktail(ctx, 12345, "redeemed@example.com")
@@ -256,6 +288,54 @@ func TestRedeemCocktail(t *testing.T) {
 	}
 }
 
+func TestSetMarketingConsent(t *testing.T) {
+	// Create mock repository
+	mockRepo := newMockRepository()
+
+	now := time.Now()
+	mockRepo.users["guest@example.com"] = &domain.User{
+		ID:        "1",
+		Email:     "guest@example.com",
+		DateAdded: now,
+		Redeemed:  &now,
+	}
+
+	// Create a test service
+	svc := service.NewForTest(mockRepo, ratelimit.New(10, 100), logger.New("info"))
+
+	ctx := context.Background()
+
+	// Opt in
+	if err := svc.SetMarketingConsent(ctx, 12345, "Guest@Example.com", true); err != nil {
+		t.Fatalf("Failed to record consent: %v", err)
+	}
+	if !mockRepo.users["guest@example.com"].HasMarketingConsent() {
+		t.Errorf("Expected marketing consent to be recorded")
+	}
+
+	// Consented users show up in the consent report
+	users, err := svc.GenerateReport(ctx, "consented", now.Add(-time.Hour), now.Add(time.Hour), domain.ReportFilter{})
+	if err != nil {
+		t.Fatalf("Failed to generate consent report: %v", err)
+	}
+	if len(users) != 1 {
+		t.Errorf("Expected 1 consented user, got %d", len(users))
+	}
+
+	// Opt out again
+	if err := svc.SetMarketingConsent(ctx, 12345, "guest@example.com", false); err != nil {
+		t.Fatalf("Failed to withdraw consent: %v", err)
+	}
+	if mockRepo.users["guest@example.com"].HasMarketingConsent() {
+		t.Errorf("Expected marketing consent to be withdrawn")
+	}
+
+	// Unknown users are reported
+	if err := svc.SetMarketingConsent(ctx, 12345, "nobody@example.com", true); err != domain.ErrUserNotFound {
+		t.Errorf("Expected ErrUserNotFound, got: %v", err)
+	}
+}
+
 func TestAddUser(t *testing.T) {
 	// Create mock repository
 	mockRepo := newMockRepository()
@@ -275,6 +355,7 @@ func TestAddUser(t *testing.T) {
 		Email:     "newuser@example.com",
 		DateAdded: time.Now(),
 		Redeemed:  nil,
+		CreatedBy: "token:abcd",
 	}
 
 	// Test adding a new user
@@ -292,6 +373,9 @@ func TestAddUser(t *testing.T) {
 	if addedUser == nil || addedUser.ID != "test-add-user" {
 		t.Errorf("User should have been added correctly")
 	}
+	if addedUser != nil && (addedUser.UpdatedAt.IsZero() || addedUser.CreatedBy != "token:abcd") {
+		t.Errorf("Expected the change time and creator to be recorded, got %+v", addedUser)
+	}
 
 	// Test adding a user with nil value
 	err = svc.AddUser(ctx, nil)
@@ -410,7 +494,7 @@ func TestGenerateReport(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			users, err := svc.GenerateReport(ctx, tc.reportType, tc.from, tc.to)
+			users, err := svc.GenerateReport(ctx, tc.reportType, tc.from, tc.to, domain.ReportFilter{})
 			if err != nil {
 				t.Errorf("Unexpected error: %v", err)
 			}
@@ -434,8 +518,82 @@ func TestGenerateReport(t *testing.T) {
 	}
 
 	// Test invalid report type
-	_, err := svc.GenerateReport(ctx, "invalid", now.AddDate(0, 0, -7), now)
+	_, err := svc.GenerateReport(ctx, "invalid", now.AddDate(0, 0, -7), now, domain.ReportFilter{})
 	if err == nil {
 		t.Errorf("Expected error for invalid report type, got nil")
 	}
 }
+
+func TestAuditLog(t *testing.T) {
+	mockRepo := newMockRepository()
+	mockRepo.users["guest@example.com"] = &domain.User{ID: "1", Email: "guest@example.com", DateAdded: time.Now()}
+	svc := service.NewForTest(mockRepo, ratelimit.New(10, 100), logger.New("info"))
+
+	// The actor and operator of the context are recorded, the user ID is the fallback
+	ctx := audit.WithOperator(audit.NewContext(context.Background(), "token:abc"), "Anna")
+	if _, err := svc.RedeemCocktail(ctx, 7, "guest@example.com"); err != nil {
+		t.Fatalf("Redeem failed: %v", err)
+	}
+	if err := svc.SetMarketingConsent(nil, 7, "guest@example.com", true); err != nil {
+		t.Fatalf("Consent failed: %v", err)
+	}
+
+	entries, total, err := svc.AuditLog(nil, audit.Filter{Email: "guest@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	if total != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", entries)
+	}
+	if entries[0].Action != audit.ActionConsent || entries[0].Actor != "user:7" || entries[0].Details != "granted" {
+		t.Errorf("Unexpected consent entry: %+v", entries[0])
+	}
+	if entries[1].Action != audit.ActionRedeem || entries[1].Actor != "token:abc" || entries[1].Operator != "Anna" {
+		t.Errorf("Unexpected redeem entry: %+v", entries[1])
+	}
+	if entries[0].Operator != "" {
+		t.Errorf("Expected no operator without one in the context, got %q", entries[0].Operator)
+	}
+	if operated, _, _ := svc.AuditLog(nil, audit.Filter{Operator: "ann"}); len(operated) != 1 {
+		t.Errorf("Expected 1 entry by the operator, got %+v", operated)
+	}
+}
+
+func TestRedemptionsByBar(t *testing.T) {
+	mockRepo := newMockRepository()
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"} {
+		mockRepo.users[email] = &domain.User{ID: email, Email: email, DateAdded: time.Now()}
+	}
+	svc := service.NewForTest(mockRepo, ratelimit.New(10, 100), logger.New("info"))
+	svc.SetBars([]string{"Main Bar", "Rooftop", "Garden"})
+
+	redeem := func(userID int64, email, bar string) {
+		if _, err := svc.RedeemCocktailAt(nil, userID, email, bar); err != nil {
+			t.Fatalf("Redeem at %q failed: %v", bar, err)
+		}
+	}
+	redeem(1, "a@example.com", "Rooftop")
+	redeem(2, "b@example.com", "Rooftop")
+	redeem(3, "c@example.com", "")
+
+	if _, err := svc.RedeemCocktailAt(nil, 4, "d@example.com", "Basement"); !errors.Is(err, domain.ErrUnknownBar) {
+		t.Errorf("Expected ErrUnknownBar, got %v", err)
+	}
+	if user := mockRepo.users["a@example.com"]; user.RedeemedAt != "Rooftop" {
+		t.Errorf("Expected the bar stored with the redemption, got %q", user.RedeemedAt)
+	}
+
+	breakdown, err := svc.RedemptionsByBar(nil, time.Now().Add(-time.Hour), time.Now(), domain.ReportFilter{})
+	if err != nil {
+		t.Fatalf("Failed to break down redemptions: %v", err)
+	}
+	want := []domain.BarRedemptions{
+		{Bar: "Main Bar"},
+		{Bar: "Rooftop", Redeemed: 2},
+		{Bar: "Garden"},
+		{Redeemed: 1},
+	}
+	if !reflect.DeepEqual(breakdown, want) {
+		t.Errorf("Expected %+v, got %+v", want, breakdown)
+	}
+}
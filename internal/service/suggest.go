@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+const (
+	// maxSuggestionDistance is the largest edit distance between the local
+	// parts of a checked email and a suggested one
+	maxSuggestionDistance = 2
+	// maxSuggestions is the number of emails suggested for a typo
+	maxSuggestions = 3
+	// defaultSuggestIndexTTL is how long the index is used before it is
+	// rebuilt from the repository, picking up guests added by other
+	// processes, unless database.cache_ttl is set
+	defaultSuggestIndexTTL = time.Minute
+)
+
+// domainClasses maps domains to the provider they are an alias of
+var domainClasses = map[string]string{
+	"googlemail.com": "gmail.com",
+	"me.com":         "icloud.com",
+	"mac.com":        "icloud.com",
+}
+
+// domainClass returns the provider of a domain, the domain itself if it has no aliases
+func domainClass(domain string) string {
+	if class, ok := domainClasses[domain]; ok {
+		return class
+	}
+	return domain
+}
+
+// suggestIndex keeps the guest emails grouped by domain class and length
+// of the local part, so a lookup only compares emails that can be close
+type suggestIndex struct {
+	mu     sync.Mutex
+	built  time.Time
+	ttl    time.Duration               // Zero uses defaultSuggestIndexTTL
+	emails map[string]map[int][]string // Domain class, then local part length in runes
+}
+
+// add indexes an email
+func (i *suggestIndex) add(email string) {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return
+	}
+	class := domainClass(domain)
+	if i.emails[class] == nil {
+		i.emails[class] = make(map[int][]string)
+	}
+	length := len([]rune(local))
+	i.emails[class][length] = append(i.emails[class][length], email)
+}
+
+// rebuild replaces the index with the emails of all guests
+func (i *suggestIndex) rebuild(users []*domain.User) {
+	i.emails = make(map[string]map[int][]string)
+	for _, user := range users {
+		i.add(utils.NormalizeEmail(user.Email))
+	}
+	i.built = time.Now()
+}
+
+// search returns the indexed emails closest to the email, nearest first
+func (i *suggestIndex) search(email string) []string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return nil
+	}
+	byLength := i.emails[domainClass(domain)]
+	length := len([]rune(local))
+
+	type match struct {
+		email    string
+		distance int
+	}
+	var matches []match
+	for l := length - maxSuggestionDistance; l <= length+maxSuggestionDistance; l++ {
+		for _, candidate := range byLength[l] {
+			if candidate == email {
+				continue
+			}
+			candidateLocal, _, _ := strings.Cut(candidate, "@")
+			if d := levenshtein(local, candidateLocal, maxSuggestionDistance); d <= maxSuggestionDistance {
+				matches = append(matches, match{candidate, d})
+			}
+		}
+	}
+
+	sort.Slice(matches, func(a, b int) bool {
+		if matches[a].distance != matches[b].distance {
+			return matches[a].distance < matches[b].distance
+		}
+		return matches[a].email < matches[b].email
+	})
+	var emails []string
+	for _, m := range matches {
+		if len(emails) == maxSuggestions {
+			break
+		}
+		emails = append(emails, m.email)
+	}
+	return emails
+}
+
+// levenshtein returns the edit distance between a and b, or limit+1 once
+// it is known to exceed limit
+func levenshtein(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// SuggestEmails returns guest emails that differ from an email that was
+// not found by a typo in the local part, nearest first. Only emails of the
+// same provider are suggested. Callers must not show them unmasked, as
+// they belong to other guests.
+func (s *Service) SuggestEmails(ctx context.Context, email string) []string {
+	email = utils.NormalizeEmail(email)
+	if !utils.IsValidEmail(email) {
+		return nil
+	}
+
+	s.suggestions.mu.Lock()
+	defer s.suggestions.mu.Unlock()
+
+	ttl := s.suggestions.ttl
+	if ttl <= 0 {
+		ttl = defaultSuggestIndexTTL
+	}
+	if time.Since(s.suggestions.built) > ttl {
+		users, err := s.repo.GetReport(ctx, domain.ReportParams{
+			Type: domain.ReportTypeAll,
+			From: time.Unix(0, 0),
+			To:   time.Now().Add(time.Hour),
+		})
+		if err != nil {
+			s.log(ctx).Warn("Cannot build email suggestion index", "error", err)
+			return nil
+		}
+		s.suggestions.rebuild(users)
+	}
+	return s.suggestions.search(email)
+}
+
+// indexSuggestion adds a new guest to a built suggestion index
+func (s *Service) indexSuggestion(email string) {
+	s.suggestions.mu.Lock()
+	defer s.suggestions.mu.Unlock()
+	if s.suggestions.emails != nil {
+		s.suggestions.add(email)
+	}
+}
@@ -0,0 +1,46 @@
+package service_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestSuggestEmails(t *testing.T) {
+	mockRepo := newMockRepository()
+	for i, email := range []string{"j.smith@gmail.com", "j.smyth@gmail.com", "j.smith@example.com", "john.smithson@gmail.com"} {
+		mockRepo.users[email] = &domain.User{ID: string(rune('1' + i)), Email: email, DateAdded: time.Now()}
+	}
+	svc := service.NewForTest(mockRepo, ratelimit.New(100, 1000), logger.New("error"))
+	ctx := context.Background()
+
+	tests := []struct {
+		email string
+		want  []string
+	}{
+		{"j.smtih@gmail.com", []string{"j.smith@gmail.com", "j.smyth@gmail.com"}},
+		{"jsmith@googlemail.com", []string{"j.smith@gmail.com", "j.smyth@gmail.com"}},
+		{"j.smith@exmaple.com", nil}, // Typos in the domain are not matched
+		{"someone@gmail.com", nil},
+		{"not an email", nil},
+	}
+	for _, tt := range tests {
+		if got := svc.SuggestEmails(ctx, tt.email); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SuggestEmails(%s) = %v, want %v", tt.email, got, tt.want)
+		}
+	}
+
+	// Guests added through the service are suggested right away
+	if err := svc.AddUser(ctx, &domain.User{ID: "9", Email: "someone1@gmail.com", DateAdded: time.Now()}); err != nil {
+		t.Fatalf("Failed to add user: %v", err)
+	}
+	if got := svc.SuggestEmails(ctx, "someone@gmail.com"); !reflect.DeepEqual(got, []string{"someone1@gmail.com"}) {
+		t.Errorf("Expected the new guest to be suggested, got %v", got)
+	}
+}
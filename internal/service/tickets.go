@@ -0,0 +1,166 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/pdf"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// ticketPathPrefix is the API path tickets are downloaded from
+const ticketPathPrefix = "/api/v1/tickets/"
+
+// ticketIDLength is the number of hex characters in a ticket ID
+const ticketIDLength = 16
+
+// ticketIssuer renders redemption tickets and signs links to download them
+type ticketIssuer struct {
+	dir       string
+	key       []byte
+	baseURL   string
+	linkTTL   time.Duration
+	eventName string
+}
+
+// SetTickets enables printable tickets issued on redemption
+func (s *Service) SetTickets(cfg config.TicketsConfig, eventName string) error {
+	if cfg.SigningKey == "" {
+		return errors.New("tickets require a signing key")
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create ticket directory: %w", err)
+	}
+
+	s.tickets = &ticketIssuer{
+		dir:       cfg.Dir,
+		key:       []byte(cfg.SigningKey),
+		baseURL:   strings.TrimSuffix(cfg.BaseURL, "/"),
+		linkTTL:   time.Duration(cfg.LinkTTLHours) * time.Hour,
+		eventName: eventName,
+	}
+	return nil
+}
+
+// sign returns the hex HMAC of the parts
+func (t *ticketIssuer) sign(parts ...string) string {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ticketID derives the ID of the ticket of a redemption. It is keyed so
+// that IDs cannot be guessed from an email address.
+func (t *ticketIssuer) ticketID(email string, redeemed time.Time) string {
+	return t.sign("ticket", email, strconv.FormatInt(redeemed.Unix(), 10))[:ticketIDLength]
+}
+
+// path returns where the ticket with the ID is stored
+func (t *ticketIssuer) path(id string) string {
+	return filepath.Join(t.dir, id+".pdf")
+}
+
+// issue renders and stores the ticket of a redemption
+func (t *ticketIssuer) issue(email string, redeemed time.Time) (string, error) {
+	id := t.ticketID(email, redeemed)
+	emailHash := sha256.Sum256([]byte(email))
+	guest := hex.EncodeToString(emailHash[:])[:ticketIDLength]
+
+	data, err := pdf.RenderTicket(pdf.Ticket{
+		ID:         id,
+		EventName:  t.eventName,
+		EmailHash:  guest,
+		RedeemedAt: redeemed,
+		Record:     fmt.Sprintf("redemption ticket=%s guest=%s at=%s", id, guest, redeemed.UTC().Format(time.RFC3339)),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file first so a crash cannot leave a partial ticket
+	tmp := t.path(id) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write ticket: %w", err)
+	}
+	if err := os.Rename(tmp, t.path(id)); err != nil {
+		return "", fmt.Errorf("failed to store ticket: %w", err)
+	}
+	return id, nil
+}
+
+// link returns a signed download link valid until now plus the link TTL
+func (t *ticketIssuer) link(id string, now time.Time) string {
+	expires := strconv.FormatInt(now.Add(t.linkTTL).Unix(), 10)
+	query := url.Values{"expires": {expires}, "sig": {t.sign("link", id, expires)}}
+	return t.baseURL + ticketPathPrefix + id + ".pdf?" + query.Encode()
+}
+
+// open checks a signed link and returns the ticket
+func (t *ticketIssuer) open(id string, expires int64, signature string, now time.Time) ([]byte, error) {
+	expected := t.sign("link", id, strconv.FormatInt(expires, 10))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, domain.ErrInvalidSignature
+	}
+	if now.Unix() > expires {
+		return nil, domain.ErrLinkExpired
+	}
+
+	// The signature covers the ID, but check it before touching the filesystem anyway
+	if len(id) != ticketIDLength || strings.Trim(id, "0123456789abcdef") != "" {
+		return nil, domain.ErrTicketNotFound
+	}
+
+	data, err := os.ReadFile(t.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, domain.ErrTicketNotFound
+	}
+	return data, err
+}
+
+// issueTicket stores a ticket for a redemption. Failures are logged and
+// do not affect the redemption.
+func (s *Service) issueTicket(email string, redeemed time.Time) {
+	if s.tickets == nil {
+		return
+	}
+
+	id, err := s.tickets.issue(email, redeemed)
+	if err != nil {
+		s.logger.Error("Error issuing redemption ticket", "email", email, "error", err)
+		return
+	}
+	s.logger.Info("Audit: ticket issued", "ticket", id, "email", email, "redeemed", redeemed)
+}
+
+// TicketURL returns a signed link to the ticket of a redemption, or an
+// empty string if tickets are disabled or the ticket was not issued
+func (s *Service) TicketURL(email string, redeemed time.Time) string {
+	if s.tickets == nil {
+		return ""
+	}
+
+	id := s.tickets.ticketID(utils.NormalizeEmail(email), redeemed)
+	if _, err := os.Stat(s.tickets.path(id)); err != nil {
+		return ""
+	}
+	return s.tickets.link(id, time.Now())
+}
+
+// TicketPDF returns the ticket behind a signed download link
+func (s *Service) TicketPDF(id string, expires int64, signature string) ([]byte, error) {
+	if s.tickets == nil {
+		return nil, domain.ErrTicketNotFound
+	}
+	return s.tickets.open(id, expires, signature, time.Now())
+}
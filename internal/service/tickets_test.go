@@ -0,0 +1,77 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestRedemptionTickets(t *testing.T) {
+	mockRepo := newMockRepository()
+	mockRepo.users["guest@example.com"] = &domain.User{
+		ID:        "1",
+		Email:     "guest@example.com",
+		DateAdded: time.Now(),
+	}
+
+	svc := service.NewForTest(mockRepo, ratelimit.New(10, 100), logger.New("info"))
+	if err := svc.SetTickets(config.TicketsConfig{Dir: t.TempDir()}, "Launch"); err == nil {
+		t.Fatal("Expected tickets without a signing key to be refused")
+	}
+	if err := svc.SetTickets(config.TicketsConfig{
+		Enabled:      true,
+		Dir:          t.TempDir(),
+		SigningKey:   "secret",
+		BaseURL:      "https://bar.example.com/",
+		LinkTTLHours: 1,
+	}, "Launch"); err != nil {
+		t.Fatalf("Failed to enable tickets: %v", err)
+	}
+
+	redeemed, err := svc.RedeemCocktail(context.Background(), 12345, "guest@example.com")
+	if err != nil {
+		t.Fatalf("Failed to redeem: %v", err)
+	}
+
+	link := svc.TicketURL("Guest@Example.com", redeemed)
+	if !strings.HasPrefix(link, "https://bar.example.com/api/v1/tickets/") {
+		t.Fatalf("Unexpected ticket link %q", link)
+	}
+	if svc.TicketURL("other@example.com", redeemed) != "" {
+		t.Error("Expected no ticket link for an email without a redemption")
+	}
+
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("Invalid ticket link: %v", err)
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(parsed.Path, "/api/v1/tickets/"), ".pdf")
+	expires, _ := strconv.ParseInt(parsed.Query().Get("expires"), 10, 64)
+	sig := parsed.Query().Get("sig")
+
+	data, err := svc.TicketPDF(id, expires, sig)
+	if err != nil {
+		t.Fatalf("Failed to open ticket: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Error("Ticket is not a PDF document")
+	}
+
+	// Links cannot be altered
+	if _, err := svc.TicketPDF(id, expires+3600, sig); err != domain.ErrInvalidSignature {
+		t.Errorf("Expected ErrInvalidSignature for an extended link, got %v", err)
+	}
+	if _, err := svc.TicketPDF("../../etc/passwd", expires, sig); err != domain.ErrInvalidSignature {
+		t.Errorf("Expected ErrInvalidSignature for another ticket, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/notify"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// pendingCode is a verification code waiting to be entered
+type pendingCode struct {
+	email    string
+	code     string
+	expires  time.Time
+	attempts int
+}
+
+// verifier tracks email ownership verification per Telegram user
+type verifier struct {
+	cfg      config.VerificationConfig
+	notifier notify.Notifier
+	mu       sync.Mutex
+	pending  map[int64]*pendingCode // Map of userID -> code waiting to be entered
+	verified map[int64]string       // Map of userID -> verified email
+}
+
+// newVerifier creates a verifier with the given settings
+func newVerifier(cfg config.VerificationConfig, notifier notify.Notifier) *verifier {
+	return &verifier{
+		cfg:      cfg,
+		notifier: notifier,
+		pending:  make(map[int64]*pendingCode),
+		verified: make(map[int64]string),
+	}
+}
+
+// isVerified returns true if the user proved ownership of the email
+func (v *verifier) isVerified(userID int64, email string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.verified[userID] == email
+}
+
+// generateCode returns a random 6-digit code
+func generateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// SetVerification enables email ownership verification using the given notifier
+func (s *Service) SetVerification(cfg config.VerificationConfig, notifier notify.Notifier) {
+	if !cfg.Enabled || notifier == nil {
+		s.verifier = nil
+		return
+	}
+	s.verifier = newVerifier(cfg, notifier)
+}
+
+// VerificationRequired returns true if guests must verify their email before redemption
+func (s *Service) VerificationRequired() bool {
+	return s.verifier != nil
+}
+
+// SendVerificationCode emails a one-time code to prove ownership of the email
+func (s *Service) SendVerificationCode(ctx context.Context, userID int64, email string) error {
+	if s.verifier == nil {
+		return nil
+	}
+
+	// Normalize email
+	email = utils.NormalizeEmail(email)
+
+	code, err := generateCode()
+	if err != nil {
+		s.log(ctx).Error("Error generating verification code", "error", err)
+		return err
+	}
+
+	// Replace any previous code for this user
+	s.verifier.mu.Lock()
+	s.verifier.pending[userID] = &pendingCode{
+		email:   email,
+		code:    code,
+		expires: time.Now().Add(time.Duration(s.verifier.cfg.CodeTTLMinutes) * time.Minute),
+	}
+	delete(s.verifier.verified, userID)
+	s.verifier.mu.Unlock()
+
+	body := fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", code, s.verifier.cfg.CodeTTLMinutes)
+	if err := s.verifier.notifier.Send(context.Background(), email, "Your verification code", body); err != nil {
+		s.log(ctx).Error("Error sending verification code", "email", email, "error", err)
+		return err
+	}
+
+	s.log(ctx).Info("Verification code sent", "email", email, "user_id", userID)
+	return nil
+}
+
+// VerifyEmailCode checks a code entered by the user and marks the email as verified
+func (s *Service) VerifyEmailCode(ctx context.Context, userID int64, code string) (string, error) {
+	if s.verifier == nil {
+		return "", domain.ErrNoVerificationPending
+	}
+
+	s.verifier.mu.Lock()
+	defer s.verifier.mu.Unlock()
+
+	pending, ok := s.verifier.pending[userID]
+	if !ok {
+		return "", domain.ErrNoVerificationPending
+	}
+
+	// Check expiry
+	if time.Now().After(pending.expires) {
+		delete(s.verifier.pending, userID)
+		s.log(ctx).Info("Verification code expired", "email", pending.email, "user_id", userID)
+		return "", domain.ErrVerificationExpired
+	}
+
+	// Check code
+	if subtle.ConstantTimeCompare([]byte(code), []byte(pending.code)) != 1 {
+		pending.attempts++
+		if pending.attempts >= s.verifier.cfg.MaxAttempts {
+			delete(s.verifier.pending, userID)
+			s.log(ctx).Warn("Too many verification attempts", "email", pending.email, "user_id", userID)
+			return "", domain.ErrTooManyAttempts
+		}
+		s.log(ctx).Info("Invalid verification code", "email", pending.email, "user_id", userID, "attempts", pending.attempts)
+		return "", domain.ErrInvalidVerificationCode
+	}
+
+	// Mark as verified
+	delete(s.verifier.pending, userID)
+	s.verifier.verified[userID] = pending.email
+
+	s.log(ctx).Info("Email ownership verified", "email", pending.email, "user_id", userID)
+	return pending.email, nil
+}
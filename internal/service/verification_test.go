@@ -0,0 +1,94 @@
+package service_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+// mockNotifier records the last message sent
+type mockNotifier struct {
+	to   string
+	body string
+}
+
+func (n *mockNotifier) Send(ctx context.Context, to, subject, body string) error {
+	n.to = to
+	n.body = body
+	return nil
+}
+
+func TestEmailVerification(t *testing.T) {
+	mockRepo := newMockRepository()
+	mockRepo.users["guest@example.com"] = &domain.User{
+		ID:        "1",
+		Email:     "guest@example.com",
+		DateAdded: time.Now(),
+	}
+
+	notifier := &mockNotifier{}
+	svc := service.NewForTest(mockRepo, ratelimit.New(10, 100), logger.New("info"))
+	svc.SetVerification(config.VerificationConfig{
+		Enabled:        true,
+		CodeTTLMinutes: 10,
+		MaxAttempts:    2,
+	}, notifier)
+
+	ctx := context.Background()
+
+	// Redemption is refused until the email is verified
+	if _, err := svc.RedeemCocktail(ctx, 12345, "guest@example.com"); err != domain.ErrEmailNotVerified {
+		t.Fatalf("Expected ErrEmailNotVerified, got: %v", err)
+	}
+
+	// Send a code and extract it from the message
+	if err := svc.SendVerificationCode(ctx, 12345, "guest@example.com"); err != nil {
+		t.Fatalf("Failed to send verification code: %v", err)
+	}
+	if notifier.to != "guest@example.com" {
+		t.Errorf("Expected code to be sent to guest@example.com, got %q", notifier.to)
+	}
+	code := regexp.MustCompile(`\d{6}`).FindString(notifier.body)
+	if code == "" {
+		t.Fatalf("No code found in message: %q", notifier.body)
+	}
+
+	// Wrong codes count against the attempt limit
+	if _, err := svc.VerifyEmailCode(ctx, 12345, "xxxxxx"); err != domain.ErrInvalidVerificationCode {
+		t.Errorf("Expected ErrInvalidVerificationCode, got: %v", err)
+	}
+	if _, err := svc.VerifyEmailCode(ctx, 12345, "xxxxxx"); err != domain.ErrTooManyAttempts {
+		t.Errorf("Expected ErrTooManyAttempts, got: %v", err)
+	}
+	if _, err := svc.VerifyEmailCode(ctx, 12345, code); err != domain.ErrNoVerificationPending {
+		t.Errorf("Expected ErrNoVerificationPending after too many attempts, got: %v", err)
+	}
+
+	// A fresh code verifies the email
+	if err := svc.SendVerificationCode(ctx, 12345, "guest@example.com"); err != nil {
+		t.Fatalf("Failed to send verification code: %v", err)
+	}
+	code = regexp.MustCompile(`\d{6}`).FindString(notifier.body)
+	email, err := svc.VerifyEmailCode(ctx, 12345, code)
+	if err != nil {
+		t.Fatalf("Failed to verify code: %v", err)
+	}
+	if email != "guest@example.com" {
+		t.Errorf("Expected verified email guest@example.com, got %q", email)
+	}
+
+	// Redemption now succeeds for the verified user only
+	if _, err := svc.RedeemCocktail(ctx, 99999, "guest@example.com"); err != domain.ErrEmailNotVerified {
+		t.Errorf("Expected ErrEmailNotVerified for another user, got: %v", err)
+	}
+	if _, err := svc.RedeemCocktail(ctx, 12345, "guest@example.com"); err != nil {
+		t.Errorf("Failed to redeem verified email: %v", err)
+	}
+}
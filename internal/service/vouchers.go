@@ -0,0 +1,89 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/featureflags"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// voucherSignatureLength is the number of characters of the signature
+// that ends every voucher code
+const voucherSignatureLength = 12
+
+// voucherEncoding encodes voucher codes with the characters Telegram
+// allows in /start deep link payloads
+var voucherEncoding = base64.RawURLEncoding
+
+// SetAccess sets how guests identify themselves. A signing key enables
+// voucher codes in either mode and is required in voucher mode.
+func (s *Service) SetAccess(cfg config.AccessConfig) error {
+	mode := strings.ToLower(cfg.Mode)
+	switch mode {
+	case "", config.AccessModeEmail:
+		mode = config.AccessModeEmail
+	case config.AccessModeVoucher:
+		if cfg.SigningKey == "" {
+			return fmt.Errorf("access mode %q requires a signing key", mode)
+		}
+	default:
+		return fmt.Errorf("unknown access mode %q", cfg.Mode)
+	}
+
+	s.accessMode = mode
+	s.voucherKey = []byte(cfg.SigningKey)
+	return nil
+}
+
+// FreeFormEmailAllowed returns false if guests must use a voucher code or
+// signed link instead of typing their email. Guests type their email in
+// voucher mode too while the vouchers feature flag is off.
+func (s *Service) FreeFormEmailAllowed() bool {
+	return s.accessMode != config.AccessModeVoucher || !s.features.Enabled(featureflags.Vouchers)
+}
+
+// signVoucher returns the truncated signature of a voucher payload
+func (s *Service) signVoucher(payload string) string {
+	mac := hmac.New(sha256.New, s.voucherKey)
+	mac.Write([]byte("voucher|" + payload))
+	return voucherEncoding.EncodeToString(mac.Sum(nil))[:voucherSignatureLength]
+}
+
+// VoucherCode returns the voucher code of an email. The code carries the
+// email and its signature, so it can be checked without a lookup.
+func (s *Service) VoucherCode(email string) (string, error) {
+	if len(s.voucherKey) == 0 {
+		return "", domain.ErrVouchersDisabled
+	}
+	payload := voucherEncoding.EncodeToString([]byte(utils.NormalizeEmail(email)))
+	return payload + s.signVoucher(payload), nil
+}
+
+// VoucherEmail returns the email of a voucher code. Codes are refused while
+// the vouchers feature flag is off, but can still be created ahead of it.
+func (s *Service) VoucherEmail(code string) (string, error) {
+	if len(s.voucherKey) == 0 || !s.features.Enabled(featureflags.Vouchers) {
+		return "", domain.ErrVouchersDisabled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) <= voucherSignatureLength {
+		return "", domain.ErrInvalidVoucher
+	}
+	payload, signature := code[:len(code)-voucherSignatureLength], code[len(code)-voucherSignatureLength:]
+	if !hmac.Equal([]byte(signature), []byte(s.signVoucher(payload))) {
+		return "", domain.ErrInvalidVoucher
+	}
+
+	email, err := voucherEncoding.DecodeString(payload)
+	if err != nil || !utils.IsValidEmail(string(email)) {
+		return "", domain.ErrInvalidVoucher
+	}
+	return string(email), nil
+}
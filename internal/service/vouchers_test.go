@@ -0,0 +1,64 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/featureflags"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+func TestVouchers(t *testing.T) {
+	svc := service.NewForTest(newMockRepository(), ratelimit.New(10, 100), logger.New("error"))
+	if !svc.FreeFormEmailAllowed() {
+		t.Error("Expected typed emails to be allowed by default")
+	}
+	if _, err := svc.VoucherCode("guest@example.com"); !errors.Is(err, domain.ErrVouchersDisabled) {
+		t.Errorf("Expected vouchers to be disabled without a key, got %v", err)
+	}
+	if err := svc.SetAccess(config.AccessConfig{Mode: config.AccessModeVoucher}); err == nil {
+		t.Fatal("Expected voucher mode without a signing key to be refused")
+	}
+
+	if err := svc.SetAccess(config.AccessConfig{Mode: config.AccessModeVoucher, SigningKey: "secret"}); err != nil {
+		t.Fatalf("Failed to enable voucher mode: %v", err)
+	}
+	if svc.FreeFormEmailAllowed() {
+		t.Error("Expected typed emails to be refused in voucher mode")
+	}
+
+	code, err := svc.VoucherCode(" Guest@Example.com ")
+	if err != nil {
+		t.Fatalf("Failed to create voucher: %v", err)
+	}
+	// Codes must fit a Telegram deep link payload
+	if len(code) > 64 {
+		t.Errorf("Expected a code of at most 64 characters, got %d", len(code))
+	}
+	if email, err := svc.VoucherEmail(code); err != nil || email != "guest@example.com" {
+		t.Errorf("Expected the voucher email, got %q, %v", email, err)
+	}
+
+	for _, forged := range []string{"", "short", code[:len(code)-1] + "x", "b3RoZXJAZXhhbXBsZS5jb20" + code[len(code)-12:]} {
+		if _, err := svc.VoucherEmail(forged); !errors.Is(err, domain.ErrInvalidVoucher) {
+			t.Errorf("Expected %q to be rejected, got %v", forged, err)
+		}
+	}
+
+	// With the vouchers flag off, codes are refused and guests type their email
+	flags, _ := featureflags.New(map[string]bool{"vouchers": false}, false)
+	svc.SetFeatures(flags)
+	if !svc.FreeFormEmailAllowed() {
+		t.Error("Expected typed emails to be allowed while vouchers are off")
+	}
+	if _, err := svc.VoucherEmail(code); !errors.Is(err, domain.ErrVouchersDisabled) {
+		t.Errorf("Expected codes to be refused while vouchers are off, got %v", err)
+	}
+	if _, err := svc.VoucherCode("guest@example.com"); err != nil {
+		t.Errorf("Expected codes to be created ahead of the rollout, got %v", err)
+	}
+}
@@ -0,0 +1,496 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/repository"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// Defaults of the write-behind buffer when the config leaves them out
+const (
+	defaultWriteBehindBatch    = 100
+	defaultWriteBehindInterval = time.Second
+)
+
+// writeBehind buffers added users and writes them to the repository in
+// batches. Each buffered user is appended to a journal first, so users
+// accepted before a crash are written once the service starts again.
+// Lookups and updates of buffered users are served from the buffer.
+type writeBehind struct {
+	domain.Repository // Where users are written, serves everything else
+
+	path     string
+	maxBatch int
+	interval time.Duration
+	logger   *logger.Logger
+
+	mu      sync.Mutex
+	journal *os.File
+	pending []*pendingUser // Oldest first
+	byEmail map[string]*pendingUser
+
+	flushMu sync.Mutex    // Serializes flushes
+	flushCh chan struct{} // Asks for a flush before the interval is up
+	stopCh  chan struct{}
+	done    chan struct{}
+}
+
+// pendingUser is a buffered user, as kept in the journal
+type pendingUser struct {
+	User    domain.User `json:"user"`
+	Stored  bool        `json:"stored,omitempty"`  // Added already, only a later update is pending
+	Changed bool        `json:"changed,omitempty"` // Updated while buffered, such as redeemed
+	version int         // Bumped on every change, so a flush only drops what it wrote
+}
+
+// batchEntry is a copy of a pending user taken for a flush
+type batchEntry struct {
+	entry   *pendingUser
+	user    domain.User
+	stored  bool
+	changed bool
+	version int
+}
+
+// newWriteBehind wraps a repository with a write-behind buffer, replaying
+// users left in the journal by a previous run
+func newWriteBehind(repo domain.Repository, cfg config.WriteBehindConfig, logger *logger.Logger) (*writeBehind, error) {
+	if cfg.JournalFile == "" {
+		return nil, errors.New("write-behind journal file is required")
+	}
+
+	w := &writeBehind{
+		Repository: repo,
+		path:       cfg.JournalFile,
+		maxBatch:   cfg.MaxBatch,
+		interval:   time.Duration(cfg.FlushMs) * time.Millisecond,
+		logger:     logger,
+		byEmail:    make(map[string]*pendingUser),
+		flushCh:    make(chan struct{}, 1),
+		stopCh:     make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if w.maxBatch <= 0 {
+		w.maxBatch = defaultWriteBehindBatch
+	}
+	if w.interval <= 0 {
+		w.interval = defaultWriteBehindInterval
+	}
+
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	if err := w.rewriteJournal(); err != nil {
+		return nil, fmt.Errorf("failed to write journal: %w", err)
+	}
+
+	logger.Info("Write-behind enabled", "journal", w.path, "max_batch", w.maxBatch, "interval", w.interval, "replayed", len(w.pending))
+	go w.run()
+	return w, nil
+}
+
+// SetWriteBehind buffers added users and writes them to the database in
+// batches, keeping them in the journal file until they are written
+func (s *Service) SetWriteBehind(cfg config.WriteBehindConfig) error {
+	buffer, err := newWriteBehind(s.repo, cfg, s.logger)
+	if err != nil {
+		return err
+	}
+	s.repo = buffer
+	return nil
+}
+
+// load reads the users buffered by a previous run. Later records of an
+// email replace earlier ones. A record cut short by a crash is skipped.
+func (w *writeBehind) load() error {
+	data, err := os.ReadFile(w.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record pendingUser
+		if err := json.Unmarshal(line, &record); err != nil {
+			w.logger.Warn("Skipping unreadable journal record", "journal", w.path, "error", err)
+			continue
+		}
+		key := utils.NormalizeEmail(record.User.Email)
+		if existing, ok := w.byEmail[key]; ok {
+			*existing = record
+			continue
+		}
+		entry := record
+		w.pending = append(w.pending, &entry)
+		w.byEmail[key] = &entry
+	}
+	return scanner.Err()
+}
+
+// appendJournal adds a record to the journal and syncs it to disk. The
+// caller must hold mu.
+func (w *writeBehind) appendJournal(entry *pendingUser) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := w.journal.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return w.journal.Sync()
+}
+
+// rewriteJournal replaces the journal with the users still buffered. The
+// caller must hold mu.
+func (w *writeBehind) rewriteJournal() error {
+	var buf bytes.Buffer
+	for _, entry := range w.pending {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	}
+
+	// Write to a temporary file first so a crash cannot leave a truncated journal
+	tmp := w.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	if w.journal != nil {
+		w.journal.Close()
+		w.journal = nil
+	}
+	if err := os.Rename(tmp, w.path); err != nil {
+		return err
+	}
+
+	journal, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	w.journal = journal
+	return nil
+}
+
+// FindByEmail returns a buffered user, or looks the email up in the repository
+func (w *writeBehind) FindByEmail(ctx any, email string) (*domain.User, error) {
+	w.mu.Lock()
+	entry, ok := w.byEmail[utils.NormalizeEmail(email)]
+	if ok {
+		user := entry.User
+		w.mu.Unlock()
+		return &user, nil
+	}
+	w.mu.Unlock()
+
+	return w.Repository.FindByEmail(ctx, email)
+}
+
+// AddUser buffers the user once it is in the journal. Emails stored by
+// another process in the meantime are found when the batch is written.
+func (w *writeBehind) AddUser(ctx any, user *domain.User) error {
+	if user == nil {
+		return errors.New("user cannot be nil")
+	}
+	key := utils.NormalizeEmail(user.Email)
+
+	w.mu.Lock()
+	if existing, ok := w.byEmail[key]; ok {
+		w.mu.Unlock()
+		return domain.NewDuplicateUserError(key, existing.User.ID)
+	}
+	if w.journal == nil {
+		w.mu.Unlock()
+		return errors.New("write-behind journal is closed")
+	}
+
+	entry := &pendingUser{User: *user}
+	if err := w.appendJournal(entry); err != nil {
+		w.mu.Unlock()
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	w.pending = append(w.pending, entry)
+	w.byEmail[key] = entry
+	full := len(w.pending) >= w.maxBatch
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// UpdateUser changes a buffered user in place, or updates the repository
+func (w *writeBehind) UpdateUser(ctx any, user *domain.User) error {
+	w.mu.Lock()
+	entry, ok := w.byEmail[utils.NormalizeEmail(user.Email)]
+	if !ok {
+		w.mu.Unlock()
+		return w.Repository.UpdateUser(ctx, user)
+	}
+	defer w.mu.Unlock()
+
+	entry.User = *user
+	entry.Changed = true
+	entry.version++
+	if err := w.appendJournal(entry); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// ChangeEmail writes buffered users first, so the email is corrected in
+// the repository
+func (w *writeBehind) ChangeEmail(ctx any, oldEmail, newEmail string) error {
+	changer, ok := w.Repository.(repository.EmailChanger)
+	if !ok {
+		return errors.New("repository does not support changing emails")
+	}
+	w.flush(ctx)
+
+	w.mu.Lock()
+	_, unwritten := w.byEmail[utils.NormalizeEmail(oldEmail)]
+	w.mu.Unlock()
+	if unwritten {
+		// The buffered user could not be written, the flush logged why
+		return domain.ErrDatabaseUnavailable
+	}
+	return changer.ChangeEmail(ctx, oldEmail, newEmail)
+}
+
+// GetReport writes buffered users first, so reports include them
+func (w *writeBehind) GetReport(ctx any, params domain.ReportParams) ([]*domain.User, error) {
+	w.flush(ctx)
+	return w.Repository.GetReport(ctx, params)
+}
+
+// CountReport writes buffered users first, so counts include them
+func (w *writeBehind) CountReport(ctx any, params domain.ReportParams) (int, error) {
+	w.flush(ctx)
+	return repository.CountReport(ctx, w.Repository, params)
+}
+
+// SearchUsers writes buffered users first, so searches find them
+func (w *writeBehind) SearchUsers(ctx any, params domain.SearchParams) (domain.SearchResult, error) {
+	w.flush(ctx)
+	return repository.SearchUsers(ctx, w.Repository, params)
+}
+
+// Stats adds the number of buffered users to the repository statistics
+func (w *writeBehind) Stats(ctx any) (domain.RepoStats, error) {
+	stats, err := w.Repository.Stats(ctx)
+	if err != nil {
+		return stats, err
+	}
+	if stats.Details == nil {
+		stats.Details = make(map[string]string)
+	}
+
+	w.mu.Lock()
+	stats.Details["buffered_users"] = fmt.Sprint(len(w.pending))
+	w.mu.Unlock()
+
+	return stats, nil
+}
+
+// Close writes the buffered users and closes the repository. Users that
+// could not be written stay in the journal for the next start.
+func (w *writeBehind) Close() error {
+	close(w.stopCh)
+	<-w.done
+
+	w.mu.Lock()
+	if len(w.pending) > 0 {
+		w.logger.Warn("Closing with buffered users, they are written on the next start", "pending", len(w.pending), "journal", w.path)
+	}
+	if w.journal != nil {
+		w.journal.Close()
+		w.journal = nil
+	}
+	w.mu.Unlock()
+
+	return w.Repository.Close()
+}
+
+// run flushes the buffer every interval, when a batch is full and on stop
+func (w *writeBehind) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.flushCh:
+		case <-w.stopCh:
+			w.flush(context.Background())
+			return
+		}
+		w.flush(context.Background())
+	}
+}
+
+// flush writes buffered users in batches until the buffer is empty or the
+// repository fails. Users that could not be written stay buffered.
+func (w *writeBehind) flush(ctx any) {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	for {
+		batch := w.nextBatch()
+		if len(batch) == 0 {
+			return
+		}
+
+		written, ok := w.write(ctx, batch)
+		w.finish(written)
+		if !ok {
+			return
+		}
+	}
+}
+
+// nextBatch copies the oldest buffered users, up to a batch
+func (w *writeBehind) nextBatch() []batchEntry {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	batch := make([]batchEntry, 0, min(len(w.pending), w.maxBatch))
+	for _, entry := range w.pending[:min(len(w.pending), w.maxBatch)] {
+		batch = append(batch, batchEntry{entry: entry, user: entry.User, stored: entry.Stored, changed: entry.Changed, version: entry.version})
+	}
+	return batch
+}
+
+// write stores a batch, all new users in one write if the repository
+// supports it. It returns the entries that are done with, and false if the
+// repository failed and the rest must wait for the next flush.
+func (w *writeBehind) write(ctx any, batch []batchEntry) ([]batchEntry, bool) {
+	var adds []*domain.User
+	for i := range batch {
+		if !batch[i].stored {
+			adds = append(adds, &batch[i].user)
+		}
+	}
+
+	batched := false
+	if adder, ok := w.Repository.(repository.BatchAdder); ok && len(adds) > 1 {
+		err := adder.AddUsers(ctx, adds)
+		if err == nil {
+			batched = true
+			w.logger.Debug("Buffered users written", "count", len(adds))
+		} else if !errors.Is(err, repository.ErrBatchUnsupported) {
+			w.logger.Debug("Batch add failed, adding users one by one", "count", len(adds), "error", err)
+		}
+	}
+
+	written := make([]batchEntry, 0, len(batch))
+	for _, item := range batch {
+		var err error
+		switch {
+		case item.stored:
+			err = w.Repository.UpdateUser(ctx, &item.user)
+		case !batched:
+			err = w.Repository.AddUser(ctx, &item.user)
+		}
+		if domain.IsDuplicateUser(err) && !item.stored && item.changed {
+			// Stored by another process while buffered. Changes made here,
+			// such as a redemption, still apply to the stored user.
+			err = w.updateStored(ctx, &item.user)
+		}
+
+		switch {
+		case err == nil:
+		case domain.IsDuplicateUser(err):
+			w.logger.Warn("Dropping buffered user already stored, it was not changed since", "email", item.user.Email, "id", item.user.ID)
+		case domain.IsValidationError(err):
+			w.logger.Error("Dropping buffered user rejected by database", "email", item.user.Email, "error", err)
+		default:
+			w.logger.Sampled("write_behind").Error("Error writing buffered users, retrying later", "error", err)
+			return written, false
+		}
+		written = append(written, item)
+	}
+	return written, true
+}
+
+// updateStored writes a buffered user over the user stored with its email,
+// taking the ID and date added of the stored one
+func (w *writeBehind) updateStored(ctx any, user *domain.User) error {
+	stored, err := w.Repository.FindByEmail(ctx, user.Email)
+	if err != nil {
+		return err
+	}
+	user.ID = stored.ID
+	user.DateAdded = stored.DateAdded
+	if err := w.Repository.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	w.logger.Info("Buffered user already stored, applied its changes", "email", user.Email, "id", user.ID)
+	return nil
+}
+
+// finish removes written users from the buffer, unless they changed while
+// being written. Those are kept and written as an update next time.
+func (w *writeBehind) finish(written []batchEntry) {
+	if len(written) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	done := make(map[*pendingUser]bool, len(written))
+	for _, item := range written {
+		if item.entry.version == item.version {
+			done[item.entry] = true
+		} else {
+			// Later updates apply to the user as stored
+			item.entry.Stored = true
+			item.entry.User.ID, item.entry.User.DateAdded = item.user.ID, item.user.DateAdded
+		}
+	}
+
+	pending := w.pending[:0]
+	for _, entry := range w.pending {
+		if done[entry] {
+			delete(w.byEmail, utils.NormalizeEmail(entry.User.Email))
+			continue
+		}
+		pending = append(pending, entry)
+	}
+	w.pending = pending
+
+	if err := w.rewriteJournal(); err != nil {
+		w.logger.Error("Error rewriting write-behind journal", "journal", w.path, "error", err)
+	}
+}
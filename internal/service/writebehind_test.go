@@ -0,0 +1,154 @@
+package service_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+)
+
+// batchRepository is a mock repository that adds users in batches
+type batchRepository struct {
+	*mockRepository
+	batches    int
+	down       bool // Fails all adds when set
+	duplicates bool // Refuses to add stored emails when set
+}
+
+func (r *batchRepository) AddUser(ctx any, user *domain.User) error {
+	if r.down {
+		return domain.ErrDatabaseUnavailable
+	}
+	if stored, ok := r.users[user.Email]; ok && r.duplicates {
+		return domain.NewDuplicateUserError(user.Email, stored.ID)
+	}
+	return r.mockRepository.AddUser(ctx, user)
+}
+
+func (r *batchRepository) AddUsers(ctx any, users []*domain.User) error {
+	if r.down {
+		return domain.ErrDatabaseUnavailable
+	}
+	for _, user := range users {
+		if stored, ok := r.users[user.Email]; ok && r.duplicates {
+			return domain.NewDuplicateUserError(user.Email, stored.ID)
+		}
+	}
+	r.batches++
+	for _, user := range users {
+		r.mockRepository.AddUser(ctx, user)
+	}
+	return nil
+}
+
+func TestWriteBehind(t *testing.T) {
+	cfg := config.WriteBehindConfig{
+		JournalFile: filepath.Join(t.TempDir(), "write_behind.jsonl"),
+		MaxBatch:    1000,
+		FlushMs:     int(time.Hour / time.Millisecond), // Only flushed on close
+	}
+	ctx := context.Background()
+	newService := func(repo *batchRepository) *service.Service {
+		t.Helper()
+		svc := service.NewForTest(repo, ratelimit.New(100, 1000), logger.New("error"))
+		if err := svc.SetWriteBehind(cfg); err != nil {
+			t.Fatalf("Failed to enable write-behind: %v", err)
+		}
+		return svc
+	}
+
+	repo := &batchRepository{mockRepository: newMockRepository()}
+	svc := newService(repo)
+	for _, email := range []string{"first@example.com", "second@example.com"} {
+		if err := svc.AddUser(ctx, &domain.User{ID: email, Email: email, DateAdded: time.Now()}); err != nil {
+			t.Fatalf("Failed to add user: %v", err)
+		}
+	}
+	if len(repo.users) != 0 {
+		t.Fatalf("Expected users to be buffered, got %d stored", len(repo.users))
+	}
+	if err := svc.AddUser(ctx, &domain.User{ID: "again", Email: "first@example.com"}); !domain.IsDuplicateUser(err) {
+		t.Errorf("Expected a duplicate of a buffered user, got %v", err)
+	}
+
+	// Buffered users can be looked up and redeemed
+	if status, _, _ := svc.CheckEmailStatus(ctx, 1, "first@example.com"); status != "eligible" {
+		t.Errorf("Expected a buffered user to be eligible, got %s", status)
+	}
+	if _, err := svc.RedeemCocktail(ctx, 1, "first@example.com"); err != nil {
+		t.Fatalf("Failed to redeem a buffered user: %v", err)
+	}
+
+	if err := svc.Close(); err != nil {
+		t.Fatalf("Failed to close service: %v", err)
+	}
+	if repo.batches != 1 || len(repo.users) != 2 {
+		t.Fatalf("Expected 2 users written in 1 batch, got %d users in %d batches", len(repo.users), repo.batches)
+	}
+	if repo.users["first@example.com"].Redeemed == nil {
+		t.Error("Expected the redemption of a buffered user to be written")
+	}
+
+	// Users that could not be written are kept in the journal for the next start
+	down := &batchRepository{mockRepository: newMockRepository(), down: true}
+	svc = newService(down)
+	if err := svc.AddUser(ctx, &domain.User{ID: "3", Email: "third@example.com", DateAdded: time.Now()}); err != nil {
+		t.Fatalf("Failed to add user: %v", err)
+	}
+	svc.Close()
+
+	up := &batchRepository{mockRepository: newMockRepository()}
+	svc = newService(up)
+	if status, _, _ := svc.CheckEmailStatus(ctx, 1, "third@example.com"); status != "eligible" {
+		t.Errorf("Expected a replayed user to be eligible, got %s", status)
+	}
+	svc.Close()
+	if _, ok := up.users["third@example.com"]; !ok {
+		t.Error("Expected the journaled user to be written after a restart")
+	}
+}
+
+func TestWriteBehindDuplicate(t *testing.T) {
+	cfg := config.WriteBehindConfig{
+		JournalFile: filepath.Join(t.TempDir(), "write_behind.jsonl"),
+		MaxBatch:    1000,
+		FlushMs:     int(time.Hour / time.Millisecond), // Only flushed on close
+	}
+	ctx := context.Background()
+	repo := &batchRepository{mockRepository: newMockRepository(), duplicates: true}
+	svc := service.NewForTest(repo, ratelimit.New(100, 1000), logger.New("error"))
+	if err := svc.SetWriteBehind(cfg); err != nil {
+		t.Fatalf("Failed to enable write-behind: %v", err)
+	}
+
+	added := time.Now().Add(-time.Hour)
+	for _, email := range []string{"redeemed@example.com", "untouched@example.com"} {
+		if err := svc.AddUser(ctx, &domain.User{ID: "buffered", Email: email, DateAdded: time.Now()}); err != nil {
+			t.Fatalf("Failed to add user: %v", err)
+		}
+	}
+	if _, err := svc.RedeemCocktail(ctx, 1, "redeemed@example.com"); err != nil {
+		t.Fatalf("Failed to redeem a buffered user: %v", err)
+	}
+
+	// Another process stores both emails before the buffer is written
+	redeemedAt := time.Now().Add(-time.Minute)
+	repo.users["redeemed@example.com"] = &domain.User{ID: "stored", Email: "redeemed@example.com", DateAdded: added}
+	repo.users["untouched@example.com"] = &domain.User{ID: "stored", Email: "untouched@example.com", DateAdded: added, Redeemed: &redeemedAt}
+
+	if err := svc.Close(); err != nil {
+		t.Fatalf("Failed to close service: %v", err)
+	}
+	if user := repo.users["redeemed@example.com"]; user.Redeemed == nil || user.ID != "stored" || !user.DateAdded.Equal(added) {
+		t.Errorf("Expected the redemption applied to the stored user, got %+v", user)
+	}
+	if user := repo.users["untouched@example.com"]; user.Redeemed == nil || !user.Redeemed.Equal(redeemedAt) {
+		t.Errorf("Expected an unchanged buffered user to leave the stored one alone, got %+v", user)
+	}
+}
@@ -1,14 +1,23 @@
 package telegram
 
 import (
+	"context"
 	"fmt"
+	"runtime/debug"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
 	"github.com/ceesaxp/cocktail-bot/internal/config"
-	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/crashreport"
+	"github.com/ceesaxp/cocktail-bot/internal/events"
 	"github.com/ceesaxp/cocktail-bot/internal/i18n"
+	"github.com/ceesaxp/cocktail-bot/internal/kvstore"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ports"
+	"github.com/ceesaxp/cocktail-bot/internal/richtext"
 	"github.com/ceesaxp/cocktail-bot/internal/service"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -21,32 +30,35 @@ type BotAPI interface {
 	StopReceivingUpdates()
 }
 
-// ServiceInterface defines the methods expected from a service
-type ServiceInterface interface {
-	CheckEmailStatus(ctx any, userID int64, email string) (string, *domain.User, error)
-	RedeemCocktail(ctx any, userID int64, email string) (time.Time, error)
-	Close() error
-}
-
 // TranslatorInterface defines the methods expected from a translator
 type TranslatorInterface interface {
 	T(lang, key string, args ...string) string
 	DetectLanguage(langCode string) string
 	GetAvailableLanguages() []string
 	GetFallbackLanguage() string
+	RetryIn(lang string, wait time.Duration) string
 }
 
 // Bot represents a Telegram bot
 type Bot struct {
 	api        BotAPI
-	service    ServiceInterface
+	config     *config.Config
+	service    ports.Service
 	logger     *logger.Logger
 	running    bool
 	waitGroup  sync.WaitGroup
 	stopCh     chan struct{}
-	emailCache map[int64]string     // Map of userID -> last email checked
+	state      kvstore.Store        // Where operational state is kept across restarts, nil keeps it in memory
+	emailCache *userState           // Last email checked by each user, only kept in memory
 	translator TranslatorInterface  // Translator for multi-language support
-	userLangs  map[int64]string     // Map of userID -> preferred language
+	userLangs  *userState           // Preferred language of each user
+	consentPending *userState       // Redeemed email of each user awaiting a marketing consent answer
+	purchasePending *userState      // Redeemed email of each user offered an extra drink
+	callbacks  *callbackStore        // Emails behind sent buttons, kept across restarts
+	parseMode  richtext.Mode        // Formatting of outgoing messages
+	sender     *sendLimiter         // Paces messages sent through api
+	debounce   *debouncer           // Drops buttons pressed twice in a row
+	reloaded   atomic.Pointer[config.Config] // Configuration last reloaded, whose disabled commands apply
 }
 
 // New creates a new Telegram bot with the provided API and service
@@ -55,14 +67,24 @@ func New(api any, service any, logger *logger.Logger, cfg *config.Config) *Bot {
 	translator := i18n.NewWithConfig(cfg)
 	i18n.LoadDefaultTranslations(translator)
 
+	stopCh := make(chan struct{})
+	state := openStateStore(cfg, logger)
 	return &Bot{
 		api:        api.(BotAPI),
-		service:    service.(ServiceInterface),
+		config:     cfg,
+		service:    service.(ports.Service),
 		logger:     logger,
-		stopCh:     make(chan struct{}),
-		emailCache: make(map[int64]string),
+		stopCh:     stopCh,
+		state:      state,
+		emailCache: newUserState("email", nil, logger),
 		translator: translator,
-		userLangs:  make(map[int64]string),
+		userLangs:  newUserState("lang", state, logger),
+		consentPending: newUserState("consent", state, logger),
+		purchasePending: newUserState("purchase", state, logger),
+		callbacks:  openCallbackStore(cfg, logger, state),
+		parseMode:  parseMode(cfg),
+		sender:     newSendLimiter(api.(BotAPI), cfg, stopCh),
+		debounce:   newDebouncer(cfg),
 	}
 }
 
@@ -74,21 +96,45 @@ func NewFromToken(token string, service *service.Service, logger *logger.Logger,
 		return nil, fmt.Errorf("failed to create Telegram bot: %w", err)
 	}
 
+	mode, err := richtext.ParseMode(cfg.Telegram.ParseMode)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create translator with config settings
 	translator := i18n.NewWithConfig(cfg)
 	i18n.LoadDefaultTranslations(translator)
 
+	stopCh := make(chan struct{})
+	state := openStateStore(cfg, logger)
 	return &Bot{
 		api:        api,
+		config:     cfg,
 		service:    service,
 		logger:     logger,
-		stopCh:     make(chan struct{}),
-		emailCache: make(map[int64]string),
+		stopCh:     stopCh,
+		state:      state,
+		emailCache: newUserState("email", nil, logger),
 		translator: translator,
-		userLangs:  make(map[int64]string),
+		userLangs:  newUserState("lang", state, logger),
+		consentPending: newUserState("consent", state, logger),
+		purchasePending: newUserState("purchase", state, logger),
+		callbacks:  openCallbackStore(cfg, logger, state),
+		parseMode:  mode,
+		sender:     newSendLimiter(api, cfg, stopCh),
+		debounce:   newDebouncer(cfg),
 	}, nil
 }
 
+// parseMode returns the configured parse mode, plain text if it is invalid
+func parseMode(cfg *config.Config) richtext.Mode {
+	if cfg == nil {
+		return richtext.Plain
+	}
+	mode, _ := richtext.ParseMode(cfg.Telegram.ParseMode)
+	return mode
+}
+
 // Start starts the bot
 func (b *Bot) Start() error {
 	if b.running {
@@ -97,14 +143,18 @@ func (b *Bot) Start() error {
 
 	b.running = true
 
+	// Continue after the last update handled before a restart. Without
+	// one, updates sent while the bot was down are dropped.
+	offset := b.updateOffset()
+
 	// Get bot info
 	botAPI, ok := b.api.(*tgbotapi.BotAPI)
 	if ok {
-		b.logger.Info("Bot started", "username", botAPI.Self.UserName)
+		b.logger.Info("Bot started", "username", botAPI.Self.UserName, "offset", offset)
 		
 		// Delete any existing webhook to avoid conflicts with polling mode
 		_, err := botAPI.Request(tgbotapi.DeleteWebhookConfig{
-			DropPendingUpdates: true,
+			DropPendingUpdates: offset == 0,
 		})
 		if err != nil {
 			b.logger.Error("Failed to delete webhook", "error", err)
@@ -116,8 +166,13 @@ func (b *Bot) Start() error {
 		b.logger.Info("Bot started")
 	}
 
+	// Publish the command menu, the bot still works without it
+	if err := b.RegisterCommands(); err != nil {
+		b.logger.Warn("Continuing without command menu", "error", err)
+	}
+
 	// Get updates
-	u := tgbotapi.NewUpdate(0)
+	u := tgbotapi.NewUpdate(offset)
 	u.Timeout = 60
 	updates := b.api.GetUpdatesChan(u)
 
@@ -127,6 +182,17 @@ func (b *Bot) Start() error {
 		b.processUpdates(updates)
 	}()
 
+	// Update redeem buttons and post receipts when emails are redeemed
+	if hub := b.service.Events(); hub != nil {
+		ch, unsubscribe := hub.Subscribe()
+		b.waitGroup.Add(1)
+		go func() {
+			defer b.waitGroup.Done()
+			defer unsubscribe()
+			b.watchEvents(ch)
+		}()
+	}
+
 	return nil
 }
 
@@ -140,21 +206,38 @@ func (b *Bot) Stop() {
 	close(b.stopCh)
 	b.api.StopReceivingUpdates()
 	b.waitGroup.Wait()
+	if b.state != nil {
+		if err := b.state.Close(); err != nil {
+			b.logger.Error("Failed to close state store", "error", err)
+		}
+	}
 
 	b.logger.Info("Bot stopped")
 }
 
 // processUpdates processes updates from Telegram
 func (b *Bot) processUpdates(updates tgbotapi.UpdatesChannel) {
+	// Offsets are saved in batches, and once more when stopping
+	offsets := newOffsetBatch(b)
+	defer offsets.flush()
+	ticker := time.NewTicker(offsetSaveInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-b.stopCh:
 			return
+		case <-ticker.C:
+			offsets.flush()
 		case update, ok := <-updates:
 			if !ok {
 				return
 			}
 
+			// Record the update as handled first, so a restart
+			// does not handle it again
+			offsets.add(update.UpdateID + 1)
+
 			// Process the update
 			go b.handleUpdate(update)
 		}
@@ -165,7 +248,7 @@ func (b *Bot) processUpdates(updates tgbotapi.UpdatesChannel) {
 func (b *Bot) handleUpdate(update tgbotapi.Update) {
 	defer func() {
 		if r := recover(); r != nil {
-			b.logger.Error("Recovered from panic in handleUpdate", "panic", r)
+			crashreport.Record(b.logger, crashreport.New(crashreport.SourceTelegram, r, debug.Stack()))
 		}
 	}()
 
@@ -177,24 +260,87 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 	}
 
 	if update.Message != nil {
-		b.handleMessage(update.Message)
+		ctx := b.chatContext(update.Message.Chat.ID, update.Message.From.ID, "update_id", update.UpdateID)
+		b.handleMessage(ctx, update.Message)
 	} else if update.CallbackQuery != nil {
-		b.handleCallbackQuery(update.CallbackQuery)
+		query := update.CallbackQuery
+		ctx := b.chatContext(query.Message.Chat.ID, query.From.ID, "update_id", update.UpdateID)
+		b.handleCallbackQuery(ctx, query)
 	}
 }
 
-// sendMessage sends a text message to a chat
-func (b *Bot) sendMessage(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
-	_, err := b.api.Send(msg)
-	if err != nil {
+// chatContext returns the context of one interaction with a user. It
+// carries a logger adding the chat and user IDs, and any further
+// key-value pairs, to every line logged while handling it, and the user
+// as the actor of audited changes.
+func (b *Bot) chatContext(chatID, userID int64, args ...any) context.Context {
+	log := b.logger.With(append([]any{"chat_id", chatID, "user_id", userID}, args...)...)
+	ctx := audit.NewContext(context.Background(), "telegram:"+strconv.FormatInt(userID, 10))
+	return logger.NewContext(ctx, log)
+}
+
+// log returns the logger of the interaction carried by the context
+func (b *Bot) log(ctx context.Context) *logger.Logger {
+	return logger.FromContext(ctx, b.logger)
+}
+
+// isAdmin checks if a Telegram user is configured as a bot admin
+func (b *Bot) isAdmin(userID int64) bool {
+	return b.config != nil && b.config.IsTelegramAdmin(userID)
+}
+
+// isBlocked checks if the user is on the block list. Blocked users are
+// ignored, or get a polite refusal when configured. Admins are never blocked.
+func (b *Bot) isBlocked(chatID int64, userID int64) bool {
+	if b.isAdmin(userID) || !b.service.IsUserBlocked(userID) {
+		return false
+	}
+
+	b.logger.Debug("Ignoring blocked user", "user_id", userID)
+	if b.config != nil && b.config.Telegram.RefuseBlocked {
+		b.sendTranslated(chatID, userID, "user_blocked")
+	}
+	return true
+}
+
+// newMessage creates a message with text formatted in the parse mode of the bot
+func (b *Bot) newMessage(chatID int64, formatted string) tgbotapi.MessageConfig {
+	msg := tgbotapi.NewMessage(chatID, formatted)
+	msg.ParseMode = string(b.parseMode)
+	return msg
+}
+
+// sendFormatted sends a message formatted in the parse mode of the bot
+func (b *Bot) sendFormatted(chatID int64, formatted string) {
+	if _, err := b.sender.Send(b.newMessage(chatID, formatted)); err != nil {
 		b.logger.Error("Error sending message", "chat_id", chatID, "error", err)
 	}
 }
 
+// sendMessage sends plain text to a chat, escaped for the parse mode
+func (b *Bot) sendMessage(chatID int64, text string) {
+	b.sendFormatted(chatID, richtext.Escape(b.parseMode, text))
+}
+
+// Alert sends a staff alert to all admin users
+func (b *Bot) Alert(ctx context.Context, text string) error {
+	if b.config == nil || len(b.config.Telegram.AdminUsers) == 0 {
+		return nil
+	}
+
+	var lastErr error
+	for _, adminID := range b.config.Telegram.AdminUsers {
+		if _, err := b.sender.SendBackground(b.newMessage(adminID, richtext.Escape(b.parseMode, text))); err != nil {
+			b.logger.Error("Error sending alert to admin", "admin_id", adminID, "error", err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
 // getUserLanguage gets the user's preferred language
 func (b *Bot) getUserLanguage(userID int64) string {
-	if lang, ok := b.userLangs[userID]; ok {
+	if lang, ok := b.userLangs.get(userID); ok {
 		return lang
 	}
 	// Return the default language from translator's fallback
@@ -209,7 +355,7 @@ func (b *Bot) detectUserLanguage(user *tgbotapi.User) {
 	}
 
 	// Only detect if language not already set
-	if _, exists := b.userLangs[user.ID]; !exists {
+	if _, exists := b.userLangs.get(user.ID); !exists {
 		detectedLang := b.detectLanguage(user.LanguageCode)
 		b.setUserLanguage(user.ID, detectedLang)
 	}
@@ -217,7 +363,7 @@ func (b *Bot) detectUserLanguage(user *tgbotapi.User) {
 
 // setUserLanguage sets the user's preferred language
 func (b *Bot) setUserLanguage(userID int64, lang string) {
-	b.userLangs[userID] = lang
+	b.userLangs.set(userID, lang)
 }
 
 // detectLanguage attempts to detect the user's language from Telegram
@@ -231,10 +377,26 @@ func (b *Bot) translate(userID int64, key string, args ...string) string {
 	return b.translator.T(lang, key, args...)
 }
 
+// format translates a message key for a specific user and fills in the
+// arguments, escaping the text for the parse mode. Values may be strings
+// or richtext.Fragments.
+func (b *Bot) format(userID int64, key string, args ...any) string {
+	return richtext.Format(b.parseMode, b.translate(userID, key), args...)
+}
+
 // sendTranslated sends a translated message to a chat
-func (b *Bot) sendTranslated(chatID int64, userID int64, key string, args ...string) {
-	text := b.translate(userID, key, args...)
-	b.sendMessage(chatID, text)
+func (b *Bot) sendTranslated(chatID int64, userID int64, key string, args ...any) {
+	b.sendFormatted(chatID, b.format(userID, key, args...))
+}
+
+// sendRetry sends a message asking the user to try again later, naming
+// the wait if it is known
+func (b *Bot) sendRetry(chatID int64, userID int64, key string, wait time.Duration) {
+	if wait <= 0 {
+		b.sendTranslated(chatID, userID, key)
+		return
+	}
+	b.sendTranslated(chatID, userID, key+"_retry", "retry", b.translator.RetryIn(b.getUserLanguage(userID), wait))
 }
 
 // SetTranslations overrides translations with fixed values for testing
@@ -267,17 +429,26 @@ func (t *mockTranslator) GetFallbackLanguage() string {
 	return "en"
 }
 
+func (t *mockTranslator) RetryIn(lang string, wait time.Duration) string {
+	return wait.String()
+}
+
 // HandleMessage exposes the handleMessage method for testing
 func (b *Bot) HandleMessage(message *tgbotapi.Message) {
-	b.handleMessage(message)
+	b.handleMessage(b.chatContext(message.Chat.ID, message.From.ID), message)
 }
 
 // HandleCommand exposes the handleCommand method for testing
 func (b *Bot) HandleCommand(message *tgbotapi.Message) {
-	b.handleCommand(message)
+	b.handleCommand(b.chatContext(message.Chat.ID, message.From.ID), message)
+}
+
+// HandleEvent exposes the handleEvent method for testing
+func (b *Bot) HandleEvent(event events.Event) {
+	b.handleEvent(event)
 }
 
 // HandleCallbackQuery exposes the handleCallbackQuery method for testing
 func (b *Bot) HandleCallbackQuery(query *tgbotapi.CallbackQuery) {
-	b.handleCallbackQuery(query)
+	b.handleCallbackQuery(b.chatContext(query.Message.Chat.ID, query.From.ID), query)
 }
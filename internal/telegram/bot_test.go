@@ -1,47 +1,269 @@
 package telegram_test
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/ceesaxp/cocktail-bot/internal/analytics"
 	"github.com/ceesaxp/cocktail-bot/internal/config"
 	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/events"
+	"github.com/ceesaxp/cocktail-bot/internal/kvstore"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ports"
 	"github.com/ceesaxp/cocktail-bot/internal/telegram"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-// mockService is a mock implementation of the service
+// mockService is a mock implementation of the service. Methods the bot
+// does not use are left to the embedded interface and panic if called.
 type mockService struct {
+	ports.Service
+
 	status      string
 	user        *domain.User
 	redeemError error
+	consent     map[string]bool
+	verify      bool
+	codeSentTo  string
+	verifyCode  string
+	delay       time.Duration
+	failed      []domain.FailedRedemption
+	blocked     map[int64]bool
+	denied      map[string]bool
+	payments    bool
+	checkoutFor string
+	ticketURL   string
+	redeemedAt  string
+	voucherOnly bool
+	vouchers    map[string]string // Emails of valid voucher codes, nil when vouchers are disabled
+	checked     string            // Last email looked up
+	suggestions []string          // Emails suggested for any email that is not found
+	retryAfter  time.Duration     // Wait until the rate limit allows the next request
+	buttons     int               // Redeem buttons shown
+
+	// Access requests of guests whose email is not found
+	selfRegistration bool
+	registrations    []domain.Registration
+	approved         string // Email of the last approved registration
 }
 
-func (s *mockService) CheckEmailStatus(ctx any, userID int64, email string) (string, *domain.User, error) {
+func (s *mockService) CheckEmailStatus(ctx context.Context, userID int64, email string) (string, *domain.User, error) {
+	time.Sleep(s.delay)
+	s.checked = email
+	for _, suggestion := range s.suggestions {
+		if email == suggestion {
+			return "eligible", &domain.User{ID: "2", Email: email, DateAdded: time.Now()}, nil
+		}
+	}
 	return s.status, s.user, nil
 }
 
-func (s *mockService) RedeemCocktail(ctx any, userID int64, email string) (time.Time, error) {
+func (s *mockService) SuggestEmails(ctx context.Context, email string) []string {
+	return s.suggestions
+}
+
+func (s *mockService) RedeemCocktail(ctx context.Context, userID int64, email string) (time.Time, error) {
+	return s.RedeemCocktailAt(ctx, userID, email, "")
+}
+
+func (s *mockService) RedeemCocktailAt(ctx context.Context, userID int64, email, bar string) (time.Time, error) {
 	if s.redeemError != nil {
 		return time.Time{}, s.redeemError
 	}
+	s.redeemedAt = bar
 	return time.Now(), nil
 }
 
+func (s *mockService) SetMarketingConsent(ctx context.Context, userID int64, email string, consent bool) error {
+	if s.consent == nil {
+		s.consent = make(map[string]bool)
+	}
+	s.consent[email] = consent
+	return nil
+}
+
+func (s *mockService) VerificationRequired() bool {
+	return s.verify
+}
+
+func (s *mockService) SendVerificationCode(ctx context.Context, userID int64, email string) error {
+	s.codeSentTo = email
+	return nil
+}
+
+func (s *mockService) VerifyEmailCode(ctx context.Context, userID int64, code string) (string, error) {
+	if code != s.verifyCode {
+		return "", domain.ErrInvalidVerificationCode
+	}
+	return s.codeSentTo, nil
+}
+
+func (s *mockService) TrackInteraction(lang, command string) {}
+func (s *mockService) TrackRedeemButton()                    { s.buttons++ }
+
+func (s *mockService) EngagementStats() analytics.Engagement {
+	return analytics.Engagement{Interactions: 7, Checks: 4, Redemptions: 2, ConversionRate: 0.5}
+}
+
+func (s *mockService) ResetRateLimit(userID int64) {}
+
+func (s *mockService) RetryAfter(ctx context.Context, userID int64) time.Duration {
+	return s.retryAfter
+}
+
+func (s *mockService) UnavailableRetryAfter() time.Duration {
+	return 0
+}
+
+func (s *mockService) FailedRedemptions() []domain.FailedRedemption {
+	return s.failed
+}
+
+func (s *mockService) RetryFailedRedemption(ctx context.Context, id string) (time.Time, error) {
+	for i, entry := range s.failed {
+		if entry.ID == id {
+			s.failed = append(s.failed[:i], s.failed[i+1:]...)
+			return entry.AttemptedAt, nil
+		}
+	}
+	return time.Time{}, domain.ErrFailedRedemptionNotFound
+}
+
+func (s *mockService) ResolveFailedRedemption(id string) error {
+	_, err := s.RetryFailedRedemption(nil, id)
+	return err
+}
+
+func (s *mockService) IsUserBlocked(userID int64) bool {
+	return s.blocked[userID]
+}
+
+func (s *mockService) BlockUser(userID int64) {
+	if s.blocked == nil {
+		s.blocked = make(map[int64]bool)
+	}
+	s.blocked[userID] = true
+}
+
+func (s *mockService) UnblockUser(userID int64) {
+	delete(s.blocked, userID)
+}
+
+func (s *mockService) DenyEmail(email string) {
+	if s.denied == nil {
+		s.denied = make(map[string]bool)
+	}
+	s.denied[email] = true
+}
+
+func (s *mockService) AllowEmail(email string) {
+	delete(s.denied, email)
+}
+
+func (s *mockService) PaymentsEnabled() bool {
+	return s.payments
+}
+
+func (s *mockService) CreateCheckout(ctx context.Context, userID int64, email string) (string, error) {
+	s.checkoutFor = email
+	return "https://checkout.example.com/cs_1", nil
+}
+
+func (s *mockService) TicketURL(email string, redeemed time.Time) string {
+	return s.ticketURL
+}
+
+func (s *mockService) FreeFormEmailAllowed() bool {
+	return !s.voucherOnly
+}
+
+func (s *mockService) VoucherEmail(code string) (string, error) {
+	if s.vouchers == nil {
+		return "", domain.ErrVouchersDisabled
+	}
+	email, ok := s.vouchers[code]
+	if !ok {
+		return "", domain.ErrInvalidVoucher
+	}
+	return email, nil
+}
+
+func (s *mockService) SelfRegistrationEnabled() bool {
+	return s.selfRegistration
+}
+
+func (s *mockService) RequestRegistration(ctx context.Context, userID, chatID int64, email, lang string) (domain.Registration, bool, error) {
+	for _, reg := range s.registrations {
+		if reg.Email == email {
+			return reg, false, nil
+		}
+	}
+	reg := domain.Registration{ID: strconv.Itoa(len(s.registrations) + 1), Email: email, UserID: userID, ChatID: chatID, Language: lang, Status: domain.RegistrationPending}
+	s.registrations = append(s.registrations, reg)
+	return reg, true, nil
+}
+
+func (s *mockService) decideRegistration(id string, status domain.RegistrationStatus) (domain.Registration, error) {
+	for i, reg := range s.registrations {
+		if reg.ID != id {
+			continue
+		}
+		if reg.Status != domain.RegistrationPending {
+			return reg, domain.ErrRegistrationDecided
+		}
+		s.registrations[i].Status = status
+		return s.registrations[i], nil
+	}
+	return domain.Registration{}, domain.ErrRegistrationNotFound
+}
+
+func (s *mockService) ApproveRegistration(ctx context.Context, id, actor string) (domain.Registration, error) {
+	reg, err := s.decideRegistration(id, domain.RegistrationApproved)
+	if err == nil {
+		s.approved = reg.Email
+	}
+	return reg, err
+}
+
+func (s *mockService) RejectRegistration(ctx context.Context, id, actor string) (domain.Registration, error) {
+	return s.decideRegistration(id, domain.RegistrationRejected)
+}
+
+func (s *mockService) Events() *events.Hub {
+	return nil
+}
+
 func (s *mockService) Close() error {
 	return nil
 }
 
-// Ensure mockService implements the ServiceInterface
-var _ telegram.ServiceInterface = &mockService{}
+// Ensure mockService implements ports.Service
+var _ ports.Service = &mockService{}
+
+// newTestConfig returns the default configuration with button state kept in memory
+func newTestConfig() *config.Config {
+	cfg := config.New()
+	cfg.Telegram.CallbackStateFile = ""
+	// Replies are not paced, so tests do not wait for the send limits
+	cfg.Telegram.SendPerSecond = 0
+	cfg.Telegram.ChatSendInterval = 0
+	return cfg
+}
 
 // mockBotAPI is a mock of the Telegram Bot API
 type mockBotAPI struct {
 	messagesSent     []tgbotapi.MessageConfig
 	callbackAnswers  []tgbotapi.CallbackConfig
 	messagesEdited   []tgbotapi.EditMessageReplyMarkupConfig
+	textsEdited      []tgbotapi.EditMessageTextConfig
+	commandsSet      []tgbotapi.SetMyCommandsConfig
+	chatActions      []tgbotapi.ChatActionConfig
 	updateConfig     tgbotapi.UpdateConfig
 	selfUser         tgbotapi.User
 	updatesChannel   chan tgbotapi.Update
@@ -73,6 +295,9 @@ func (m *mockBotAPI) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
 	case tgbotapi.EditMessageReplyMarkupConfig:
 		m.messagesEdited = append(m.messagesEdited, v)
 		return tgbotapi.Message{}, nil
+	case tgbotapi.EditMessageTextConfig:
+		m.textsEdited = append(m.textsEdited, v)
+		return tgbotapi.Message{}, nil
 	default:
 		return tgbotapi.Message{}, nil
 	}
@@ -83,6 +308,14 @@ func (m *mockBotAPI) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error
 	if callback, ok := c.(tgbotapi.CallbackConfig); ok {
 		m.callbackAnswers = append(m.callbackAnswers, callback)
 	}
+	// For command menu registration
+	if commands, ok := c.(tgbotapi.SetMyCommandsConfig); ok {
+		m.commandsSet = append(m.commandsSet, commands)
+	}
+	// For typing indicators
+	if action, ok := c.(tgbotapi.ChatActionConfig); ok {
+		m.chatActions = append(m.chatActions, action)
+	}
 	return &tgbotapi.APIResponse{Ok: true}, nil
 }
 
@@ -184,6 +417,9 @@ func TestBot(t *testing.T) {
 	if mockAPI.messagesSent[0].ReplyMarkup == nil {
 		t.Errorf("Expected reply markup with buttons")
 	}
+	if mockSvc.buttons != 1 {
+		t.Errorf("Expected the redeem button to be counted once, got %d", mockSvc.buttons)
+	}
 
 	// Test already redeemed email
 	mockSvc.status = "redeemed"
@@ -284,3 +520,936 @@ func TestBot(t *testing.T) {
 		t.Errorf("Expected message to be edited to remove buttons")
 	}
 }
+
+func TestMarketingConsent(t *testing.T) {
+	mockSvc := &mockService{
+		status: "eligible",
+		user:   &domain.User{ID: "1", Email: "eligible@example.com", DateAdded: time.Now()},
+	}
+	mockAPI := newMockBotAPI()
+
+	// Enable the opt-in question
+	cfg := &config.Config{}
+	cfg.Telegram.AskMarketingConsent = true
+
+	bot := telegram.New(mockAPI, mockSvc, logger.New("info"), cfg)
+	bot.SetTranslations(map[string]string{
+		"consent_question": "Want to hear about future events?",
+		"consent_thanks":   "Thanks!",
+	})
+
+	from := &tgbotapi.User{ID: 456, UserName: "testuser"}
+	chat := &tgbotapi.Chat{ID: 789, Type: "private"}
+
+	// Check and redeem an email
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: from, Chat: chat, Text: "eligible@example.com"})
+	bot.HandleCallbackQuery(&tgbotapi.CallbackQuery{
+		ID:      "callback1",
+		From:    from,
+		Message: &tgbotapi.Message{MessageID: 1, Chat: chat},
+		Data:    "redeem",
+	})
+
+	// The consent question follows the redemption message
+	last := mockAPI.messagesSent[len(mockAPI.messagesSent)-1]
+	if last.Text != "Want to hear about future events?" {
+		t.Fatalf("Expected consent question, got: %s", last.Text)
+	}
+	if last.ReplyMarkup == nil {
+		t.Errorf("Expected consent buttons")
+	}
+
+	// Answer the question
+	bot.HandleCallbackQuery(&tgbotapi.CallbackQuery{
+		ID:      "callback2",
+		From:    from,
+		Message: &tgbotapi.Message{MessageID: 2, Chat: chat},
+		Data:    "consent_yes",
+	})
+
+	if consent, ok := mockSvc.consent["eligible@example.com"]; !ok || !consent {
+		t.Errorf("Expected marketing consent to be recorded, got %v", mockSvc.consent)
+	}
+
+	last = mockAPI.messagesSent[len(mockAPI.messagesSent)-1]
+	if last.Text != "Thanks!" {
+		t.Errorf("Unexpected consent response: %s", last.Text)
+	}
+}
+
+func TestEmailVerification(t *testing.T) {
+	mockSvc := &mockService{
+		status:     "eligible",
+		user:       &domain.User{ID: "1", Email: "eligible@example.com", DateAdded: time.Now()},
+		verify:     true,
+		verifyCode: "123456",
+	}
+	mockAPI := newMockBotAPI()
+
+	bot := telegram.New(mockAPI, mockSvc, logger.New("info"), &config.Config{})
+	bot.SetTranslations(map[string]string{
+		"eligible":             "Email found! You're eligible for a free cocktail.",
+		"verification_sent":    "Code sent.",
+		"verification_invalid": "Wrong code.",
+	})
+
+	from := &tgbotapi.User{ID: 456, UserName: "testuser"}
+	chat := &tgbotapi.Chat{ID: 789, Type: "private"}
+
+	// Eligible email triggers a code instead of the redeem buttons
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: from, Chat: chat, Text: "eligible@example.com"})
+
+	if mockSvc.codeSentTo != "eligible@example.com" {
+		t.Errorf("Expected code to be sent to eligible@example.com, got %q", mockSvc.codeSentTo)
+	}
+	if len(mockAPI.messagesSent) != 1 || mockAPI.messagesSent[0].Text != "Code sent." {
+		t.Fatalf("Expected verification prompt, got %+v", mockAPI.messagesSent)
+	}
+	if mockAPI.messagesSent[0].ReplyMarkup != nil {
+		t.Errorf("Redeem buttons should not be shown before verification")
+	}
+
+	// Wrong code
+	mockAPI.messagesSent = nil
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 2, From: from, Chat: chat, Text: "654321"})
+
+	if len(mockAPI.messagesSent) != 1 || mockAPI.messagesSent[0].Text != "Wrong code." {
+		t.Fatalf("Expected wrong code response, got %+v", mockAPI.messagesSent)
+	}
+
+	// Correct code shows the redeem buttons
+	mockAPI.messagesSent = nil
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 3, From: from, Chat: chat, Text: "123456"})
+
+	if len(mockAPI.messagesSent) != 1 {
+		t.Fatalf("Expected 1 message sent, got %d", len(mockAPI.messagesSent))
+	}
+	if mockAPI.messagesSent[0].Text != "Email found! You're eligible for a free cocktail." {
+		t.Errorf("Unexpected response after verification: %s", mockAPI.messagesSent[0].Text)
+	}
+	if mockAPI.messagesSent[0].ReplyMarkup == nil {
+		t.Errorf("Expected redeem buttons after verification")
+	}
+}
+
+// commandMessage builds a message containing a bot command
+func commandMessage(userID int64, text string) *tgbotapi.Message {
+	command := strings.Fields(text)[0]
+	return &tgbotapi.Message{
+		MessageID: 1,
+		From:      &tgbotapi.User{ID: userID},
+		Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+		Text:      text,
+		Entities:  []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}},
+	}
+}
+
+func TestCommandMenu(t *testing.T) {
+	mockSvc := &mockService{
+		status: "eligible",
+		user:   &domain.User{ID: "1", Email: "eligible@example.com", DateAdded: time.Now()},
+	}
+	mockAPI := newMockBotAPI()
+
+	cfg := newTestConfig()
+	cfg.Language.Enabled = []string{"en", "de"}
+	cfg.Telegram.AdminUsers = []int64{99}
+	cfg.Telegram.DisabledCommands = []string{"mystatus"}
+
+	bot := telegram.New(mockAPI, mockSvc, logger.New("info"), cfg)
+	if err := bot.RegisterCommands(); err != nil {
+		t.Fatalf("Failed to register commands: %v", err)
+	}
+
+	// Default menu, one per enabled language and one for the admin
+	if len(mockAPI.commandsSet) != 4 {
+		t.Fatalf("Expected 4 command menus, got %d", len(mockAPI.commandsSet))
+	}
+
+	names := func(menu tgbotapi.SetMyCommandsConfig) string {
+		var list []string
+		for _, cmd := range menu.Commands {
+			list = append(list, cmd.Command)
+		}
+		return strings.Join(list, ",")
+	}
+
+	for _, menu := range mockAPI.commandsSet {
+		if menu.Scope != nil && menu.Scope.Type == "chat" {
+			if names(menu) != "start,help,language,stats,resetlimit,failed,block,unblock" {
+				t.Errorf("Unexpected admin menu: %s", names(menu))
+			}
+			continue
+		}
+		if names(menu) != "start,help,language" {
+			t.Errorf("Unexpected guest menu for %q: %s", menu.LanguageCode, names(menu))
+		}
+		if menu.LanguageCode == "de" && menu.Commands[1].Description != "Hilfe anzeigen" {
+			t.Errorf("Expected German description, got %q", menu.Commands[1].Description)
+		}
+	}
+
+	// Disabled commands are rejected
+	bot.HandleCommand(commandMessage(456, "/mystatus"))
+	if len(mockAPI.messagesSent) != 1 || !strings.Contains(mockAPI.messagesSent[0].Text, "Unknown command") {
+		t.Errorf("Expected unknown command reply, got %+v", mockAPI.messagesSent)
+	}
+
+	// Stats are only shown to admins
+	bot.HandleCommand(commandMessage(456, "/stats"))
+	if text := mockAPI.messagesSent[1].Text; !strings.Contains(text, "administrators") {
+		t.Errorf("Expected admin only reply, got %q", text)
+	}
+	bot.HandleCommand(commandMessage(99, "/stats"))
+	if text := mockAPI.messagesSent[2].Text; !strings.Contains(text, "Redemptions: 2") || !strings.Contains(text, "Conversion: 50.0%") {
+		t.Errorf("Unexpected stats reply: %q", text)
+	}
+
+	// Toggling a feature flag publishes the menu again
+	bot.HandleEvent(events.Event{Type: events.FeatureToggled, Actor: "token:abcd"})
+	if len(mockAPI.commandsSet) != 8 {
+		t.Fatalf("Expected the 4 menus to be published again, got %d", len(mockAPI.commandsSet))
+	}
+
+	// A reloaded configuration updates the menu and the accepted commands
+	reloaded := newTestConfig()
+	reloaded.Language.Enabled = []string{"en", "de"}
+	reloaded.Telegram.AdminUsers = []int64{99}
+	reloaded.Telegram.DisabledCommands = []string{"/stats"}
+	if err := bot.Reload(reloaded); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if len(mockAPI.commandsSet) != 12 {
+		t.Fatalf("Expected the 4 menus to be published again, got %d", len(mockAPI.commandsSet))
+	}
+	for _, menu := range mockAPI.commandsSet[8:] {
+		if menu.Scope != nil && menu.Scope.Type == "chat" {
+			if names(menu) != "start,help,language,mystatus,resetlimit,failed,block,unblock" {
+				t.Errorf("Unexpected reloaded admin menu: %s", names(menu))
+			}
+		} else if names(menu) != "start,help,language,mystatus" {
+			t.Errorf("Unexpected reloaded guest menu for %q: %s", menu.LanguageCode, names(menu))
+		}
+	}
+	bot.HandleCommand(commandMessage(99, "/stats"))
+	if text := mockAPI.messagesSent[3].Text; !strings.Contains(text, "Unknown command") {
+		t.Errorf("Expected unknown command reply after the reload, got %q", text)
+	}
+}
+
+func TestMyStatus(t *testing.T) {
+	mockSvc := &mockService{
+		status: "eligible",
+		user:   &domain.User{ID: "1", Email: "eligible@example.com", DateAdded: time.Now()},
+	}
+	mockAPI := newMockBotAPI()
+	bot := telegram.New(mockAPI, mockSvc, logger.New("info"), newTestConfig())
+
+	// Nothing checked yet
+	bot.HandleCommand(commandMessage(456, "/mystatus"))
+	if text := mockAPI.messagesSent[0].Text; !strings.Contains(text, "haven't checked an email") {
+		t.Errorf("Unexpected reply: %q", text)
+	}
+
+	// After a check the status is repeated
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 2, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "eligible@example.com"})
+	mockSvc.status = "redeemed"
+	redeemed := time.Date(2025, 6, 1, 20, 0, 0, 0, time.UTC)
+	mockSvc.user = &domain.User{ID: "1", Email: "eligible@example.com", Redeemed: &redeemed}
+
+	bot.HandleCommand(commandMessage(456, "/mystatus"))
+	last := mockAPI.messagesSent[len(mockAPI.messagesSent)-1].Text
+	if !strings.Contains(last, "June 1, 2025") {
+		t.Errorf("Expected redeemed status, got %q", last)
+	}
+}
+
+func TestStateAfterRestart(t *testing.T) {
+	mockSvc := &mockService{
+		status: "eligible",
+		user:   &domain.User{ID: "1", Email: "eligible@example.com", DateAdded: time.Now()},
+	}
+	path := filepath.Join(t.TempDir(), "telegram_state.json")
+	cfg := newTestConfig()
+	cfg.Telegram.State = config.StateConfig{Store: "file", Path: path}
+
+	// One guest picks Spanish, another checks an email
+	mockAPI := newMockBotAPI()
+	bot := telegram.New(mockAPI, mockSvc, logger.New("error"), cfg)
+	chat := &tgbotapi.Chat{ID: 789}
+	bot.HandleCallbackQuery(&tgbotapi.CallbackQuery{ID: "1", From: &tgbotapi.User{ID: 789}, Message: &tgbotapi.Message{MessageID: 1, Chat: chat}, Data: "lang_es"})
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 2, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "eligible@example.com"})
+
+	// Updates are recorded as handled when received
+	if err := bot.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	mockAPI.updatesChannel <- tgbotapi.Update{UpdateID: 41}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		store, err := kvstore.NewFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read state: %v", err)
+		}
+		if offset, _ := store.Get(context.Background(), "telegram:offset"); string(offset) == "42" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the update offset")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	bot.Stop()
+
+	// A new bot continues after the last update and remembers the
+	// language, but not the email checked
+	mockAPI = newMockBotAPI()
+	restarted := telegram.New(mockAPI, mockSvc, logger.New("error"), cfg)
+	restarted.HandleCommand(commandMessage(789, "/mystatus"))
+	if text := mockAPI.messagesSent[0].Text; !strings.Contains(text, "Aún no has consultado") {
+		t.Errorf("Expected the chosen language after a restart, got %q", text)
+	}
+	restarted.HandleCommand(commandMessage(456, "/mystatus"))
+	if text := mockAPI.messagesSent[1].Text; !strings.Contains(text, "haven't checked an email") {
+		t.Errorf("Expected the checked email to be forgotten after a restart, got %q", text)
+	}
+	if err := restarted.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	restarted.Stop()
+	if mockAPI.updateConfig.Offset != 42 {
+		t.Errorf("Expected to continue after update 41, got offset %d", mockAPI.updateConfig.Offset)
+	}
+}
+
+func TestGreetingByName(t *testing.T) {
+	mockSvc := &mockService{
+		status: "eligible",
+		user:   &domain.User{ID: "1", Email: "anna@example.com", DateAdded: time.Now(), FirstName: "Anna", LastName: "Karenina"},
+	}
+	mockAPI := newMockBotAPI()
+	cfg := newTestConfig()
+	bot := telegram.New(mockAPI, mockSvc, logger.New("info"), cfg)
+
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "anna@example.com"})
+	if text := mockAPI.messagesSent[0].Text; !strings.Contains(text, "Email found, Anna!") || strings.Contains(text, "Karenina") {
+		t.Errorf("Expected the guest to be greeted by first name, got %q", text)
+	}
+
+	// Anyone can send the email, so privacy mode keeps the name
+	cfg.Privacy.MaskEmails = true
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 2, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "anna@example.com"})
+	if text := mockAPI.messagesSent[1].Text; strings.Contains(text, "Anna") {
+		t.Errorf("Expected no name in privacy mode, got %q", text)
+	}
+}
+
+func TestRetryMessages(t *testing.T) {
+	mockSvc := &mockService{status: "rate_limited", retryAfter: 90 * time.Second}
+	mockAPI := newMockBotAPI()
+	bot := telegram.New(mockAPI, mockSvc, logger.New("info"), newTestConfig())
+
+	// The wait is rounded up to whole minutes
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "guest@example.com"})
+	if text := mockAPI.messagesSent[0].Text; !strings.Contains(text, "try again in 2 minutes.") {
+		t.Errorf("Expected the wait in minutes, got %q", text)
+	}
+
+	mockSvc.retryAfter = 20 * time.Second
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 2, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "guest@example.com"})
+	if text := mockAPI.messagesSent[1].Text; !strings.Contains(text, "try again in 20 seconds.") {
+		t.Errorf("Expected the wait in seconds, got %q", text)
+	}
+
+	// Without an estimate the generic text is sent
+	mockSvc.status = "unavailable"
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 3, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "guest@example.com"})
+	if text := mockAPI.messagesSent[2].Text; !strings.Contains(text, "try again later") {
+		t.Errorf("Expected the generic text, got %q", text)
+	}
+}
+
+func TestSlowLookupProgress(t *testing.T) {
+	mockSvc := &mockService{
+		status: "eligible",
+		user:   &domain.User{ID: "1", Email: "eligible@example.com", DateAdded: time.Now()},
+		delay:  100 * time.Millisecond,
+	}
+	mockAPI := newMockBotAPI()
+	cfg := newTestConfig()
+	cfg.Telegram.TypingDelayMs = 10
+	cfg.Telegram.SlowLookupMs = 50
+	bot := telegram.New(mockAPI, mockSvc, logger.New("error"), cfg)
+
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "eligible@example.com"})
+
+	if len(mockAPI.chatActions) != 1 || mockAPI.chatActions[0].Action != tgbotapi.ChatTyping {
+		t.Errorf("Expected one typing indicator, got %+v", mockAPI.chatActions)
+	}
+	if len(mockAPI.messagesSent) != 2 {
+		t.Fatalf("Expected interim and result messages, got %d", len(mockAPI.messagesSent))
+	}
+	if text := mockAPI.messagesSent[0].Text; !strings.Contains(text, "Still checking") {
+		t.Errorf("Expected interim message first, got %q", text)
+	}
+
+	// Fast lookups get no progress feedback
+	mockSvc.delay = 0
+	mockAPI = newMockBotAPI()
+	bot = telegram.New(mockAPI, mockSvc, logger.New("error"), cfg)
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 2, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "eligible@example.com"})
+	if len(mockAPI.chatActions) != 0 || len(mockAPI.messagesSent) != 1 {
+		t.Errorf("Expected no progress feedback, got %d actions and %d messages", len(mockAPI.chatActions), len(mockAPI.messagesSent))
+	}
+}
+
+func TestFailedCommand(t *testing.T) {
+	mockSvc := &mockService{
+		failed: []domain.FailedRedemption{
+			{ID: "1", Email: "guest@example.com", AttemptedAt: time.Now(), Error: "connection reset"},
+		},
+	}
+	mockAPI := newMockBotAPI()
+	cfg := newTestConfig()
+	cfg.Telegram.AdminUsers = []int64{99}
+	bot := telegram.New(mockAPI, mockSvc, logger.New("error"), cfg)
+
+	bot.HandleCommand(commandMessage(99, "/failed"))
+	if text := mockAPI.messagesSent[0].Text; !strings.Contains(text, "#1 guest@example.com") {
+		t.Errorf("Expected failed redemption listed, got %q", text)
+	}
+
+	bot.HandleCommand(commandMessage(99, "/failed retry 1"))
+	if text := mockAPI.messagesSent[1].Text; !strings.Contains(text, "#1 saved") {
+		t.Errorf("Expected retry confirmation, got %q", text)
+	}
+
+	bot.HandleCommand(commandMessage(99, "/failed resolve 1"))
+	if text := mockAPI.messagesSent[2].Text; !strings.Contains(text, "no failed redemption #1") {
+		t.Errorf("Expected not found reply, got %q", text)
+	}
+
+	// Alerts go to every admin
+	if err := bot.Alert(context.Background(), "Redemption failed"); err != nil {
+		t.Fatalf("Alert failed: %v", err)
+	}
+	if last := mockAPI.messagesSent[len(mockAPI.messagesSent)-1]; last.ChatID != 99 || last.Text != "Redemption failed" {
+		t.Errorf("Unexpected alert message: %+v", last)
+	}
+}
+
+func TestBlockedUsers(t *testing.T) {
+	mockSvc := &mockService{
+		status: "eligible",
+		user:   &domain.User{ID: "1", Email: "eligible@example.com", DateAdded: time.Now()},
+	}
+	mockAPI := newMockBotAPI()
+	cfg := newTestConfig()
+	cfg.Telegram.AdminUsers = []int64{99}
+	bot := telegram.New(mockAPI, mockSvc, logger.New("error"), cfg)
+
+	// Admins block users and deny emails
+	bot.HandleCommand(commandMessage(99, "/block 456"))
+	bot.HandleCommand(commandMessage(99, "/block @spam.example"))
+	if !mockSvc.blocked[456] || !mockSvc.denied["@spam.example"] {
+		t.Fatalf("Expected user blocked and domain denied, got %v %v", mockSvc.blocked, mockSvc.denied)
+	}
+	if len(mockAPI.messagesSent) != 2 {
+		t.Fatalf("Expected 2 confirmations, got %d", len(mockAPI.messagesSent))
+	}
+
+	// Blocked users are ignored by default
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "eligible@example.com"})
+	if len(mockAPI.messagesSent) != 2 {
+		t.Errorf("Expected blocked user to be ignored, got %q", mockAPI.messagesSent[len(mockAPI.messagesSent)-1].Text)
+	}
+
+	// or refused politely when configured
+	cfg.Telegram.RefuseBlocked = true
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 2, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "eligible@example.com"})
+	if text := mockAPI.messagesSent[len(mockAPI.messagesSent)-1].Text; !strings.Contains(text, "not available to you") {
+		t.Errorf("Expected polite refusal, got %q", text)
+	}
+
+	// Admins cannot block themselves out
+	bot.HandleCommand(commandMessage(99, "/block 99"))
+	bot.HandleCommand(commandMessage(99, "/unblock 456"))
+	if mockSvc.blocked[456] {
+		t.Error("Expected user to be unblocked")
+	}
+	if text := mockAPI.messagesSent[len(mockAPI.messagesSent)-1].Text; !strings.Contains(text, "456 unblocked") {
+		t.Errorf("Expected unblock confirmation, got %q", text)
+	}
+
+	// Denied emails get an explanation instead of the redeem button
+	mockSvc.status = "denied"
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 3, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "guest@spam.example"})
+	if text := mockAPI.messagesSent[len(mockAPI.messagesSent)-1].Text; !strings.Contains(text, "cannot be used") {
+		t.Errorf("Expected denied email reply, got %q", text)
+	}
+}
+
+func TestBuyAnotherDrink(t *testing.T) {
+	mockSvc := &mockService{
+		status:   "eligible",
+		user:     &domain.User{ID: "1", Email: "eligible@example.com", DateAdded: time.Now()},
+		payments: true,
+	}
+	mockAPI := newMockBotAPI()
+	bot := telegram.New(mockAPI, mockSvc, logger.New("error"), newTestConfig())
+
+	chat := &tgbotapi.Chat{ID: 456}
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 456}, Chat: chat, Text: "eligible@example.com"})
+	bot.HandleCallbackQuery(&tgbotapi.CallbackQuery{ID: "1", From: &tgbotapi.User{ID: 456}, Message: &tgbotapi.Message{MessageID: 2, Chat: chat}, Data: "redeem"})
+
+	// The offer follows the redemption
+	offer := mockAPI.messagesSent[len(mockAPI.messagesSent)-1]
+	markup, ok := offer.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	if !ok || !strings.HasPrefix(*markup.InlineKeyboard[0][0].CallbackData, "buy:") {
+		t.Fatalf("Expected buy button, got %+v", offer)
+	}
+
+	bot.HandleCallbackQuery(&tgbotapi.CallbackQuery{ID: "2", From: &tgbotapi.User{ID: 456}, Message: &tgbotapi.Message{MessageID: 3, Chat: chat}, Data: *markup.InlineKeyboard[0][0].CallbackData})
+	if mockSvc.checkoutFor != "eligible@example.com" {
+		t.Errorf("Expected checkout for redeemed email, got %q", mockSvc.checkoutFor)
+	}
+	link := mockAPI.messagesSent[len(mockAPI.messagesSent)-1]
+	markup, ok = link.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	if !ok || *markup.InlineKeyboard[0][0].URL != "https://checkout.example.com/cs_1" {
+		t.Errorf("Expected payment link button, got %+v", link)
+	}
+}
+
+func TestRedemptionTicket(t *testing.T) {
+	mockSvc := &mockService{
+		status:    "eligible",
+		user:      &domain.User{ID: "1", Email: "eligible@example.com", DateAdded: time.Now()},
+		ticketURL: "https://bar.example.com/api/v1/tickets/0123456789abcdef.pdf",
+	}
+	mockAPI := newMockBotAPI()
+	bot := telegram.New(mockAPI, mockSvc, logger.New("error"), newTestConfig())
+
+	chat := &tgbotapi.Chat{ID: 456}
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 456}, Chat: chat, Text: "eligible@example.com"})
+	bot.HandleCallbackQuery(&tgbotapi.CallbackQuery{ID: "1", From: &tgbotapi.User{ID: 456}, Message: &tgbotapi.Message{MessageID: 2, Chat: chat}, Data: "redeem"})
+
+	// The ticket link follows the redemption
+	last := mockAPI.messagesSent[len(mockAPI.messagesSent)-1]
+	if !strings.Contains(last.Text, mockSvc.ticketURL) {
+		t.Errorf("Expected ticket link, got %q", last.Text)
+	}
+}
+
+func TestRedemptionAtBar(t *testing.T) {
+	mockSvc := &mockService{
+		status: "eligible",
+		user:   &domain.User{ID: "1", Email: "eligible@example.com", DateAdded: time.Now()},
+	}
+	mockAPI := newMockBotAPI()
+	cfg := newTestConfig()
+	cfg.Event.Bars = []string{"Main Bar", "Rooftop"}
+	bot := telegram.New(mockAPI, mockSvc, logger.New("error"), cfg)
+
+	chat := &tgbotapi.Chat{ID: 456}
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 456}, Chat: chat, Text: "eligible@example.com"})
+	bot.HandleCallbackQuery(&tgbotapi.CallbackQuery{ID: "1", From: &tgbotapi.User{ID: 456}, Message: &tgbotapi.Message{MessageID: 2, Chat: chat}, Data: "redeem"})
+
+	// Redeem asks for the bar instead of redeeming right away
+	question := mockAPI.messagesSent[len(mockAPI.messagesSent)-1]
+	markup, ok := question.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	if !ok || len(markup.InlineKeyboard) != 2 || markup.InlineKeyboard[1][0].Text != "Rooftop" {
+		t.Fatalf("Expected a button per bar, got %+v", question)
+	}
+
+	bot.HandleCallbackQuery(&tgbotapi.CallbackQuery{ID: "2", From: &tgbotapi.User{ID: 456}, Message: &tgbotapi.Message{MessageID: 3, Chat: chat}, Data: *markup.InlineKeyboard[1][0].CallbackData})
+	if mockSvc.redeemedAt != "Rooftop" {
+		t.Errorf("Expected the drink redeemed at Rooftop, got %q", mockSvc.redeemedAt)
+	}
+	last := mockAPI.messagesSent[len(mockAPI.messagesSent)-1]
+	if !strings.Contains(last.Text, "Enjoy your free cocktail") {
+		t.Errorf("Expected the redemption confirmed, got %q", last.Text)
+	}
+}
+
+func TestVoucherOnlyAccess(t *testing.T) {
+	mockSvc := &mockService{
+		status:      "eligible",
+		user:        &domain.User{ID: "1", Email: "eligible@example.com", DateAdded: time.Now()},
+		voucherOnly: true,
+		vouchers:    map[string]string{"ZWxpZ2libGVAZXhhbXBsZS5jb20abcdefghijkl": "eligible@example.com"},
+	}
+	mockAPI := newMockBotAPI()
+	bot := telegram.New(mockAPI, mockSvc, logger.New("error"), newTestConfig())
+
+	// Typed emails are refused without a lookup
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "eligible@example.com"})
+	if mockSvc.checked != "" {
+		t.Errorf("Expected no lookup of a typed email, got %s", mockSvc.checked)
+	}
+	if last := mockAPI.messagesSent[len(mockAPI.messagesSent)-1]; !strings.Contains(last.Text, "voucher code") {
+		t.Errorf("Expected a request for a voucher, got %q", last.Text)
+	}
+
+	// Invalid codes are rejected
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 2, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "ZWxpZ2libGVAZXhhbXBsZS5jb20forgedsigxx"})
+	if last := mockAPI.messagesSent[len(mockAPI.messagesSent)-1]; !strings.Contains(last.Text, "not valid") {
+		t.Errorf("Expected an invalid voucher message, got %q", last.Text)
+	}
+
+	// A deep link carries the voucher in the /start payload
+	bot.HandleCommand(commandMessage(456, "/start ZWxpZ2libGVAZXhhbXBsZS5jb20abcdefghijkl"))
+	if mockSvc.checked != "eligible@example.com" {
+		t.Errorf("Expected the voucher email looked up, got %q", mockSvc.checked)
+	}
+	if last := mockAPI.messagesSent[len(mockAPI.messagesSent)-1]; !strings.Contains(last.Text, "eligible") {
+		t.Errorf("Expected the eligible message, got %q", last.Text)
+	}
+}
+
+func TestMessageFormatting(t *testing.T) {
+	entry := domain.FailedRedemption{ID: "1", Email: "first_guest@example.com", AttemptedAt: time.Now(), Error: "<timeout> & retry"}
+	for mode, want := range map[string]string{
+		"html":       "#1 first_guest@example.com at",
+		"markdownv2": "\\#1 first\\_guest@example\\.com at",
+		"plain":      "#1 first_guest@example.com at",
+	} {
+		mockAPI := newMockBotAPI()
+		cfg := newTestConfig()
+		cfg.Telegram.AdminUsers = []int64{99}
+		cfg.Telegram.ParseMode = mode
+		bot := telegram.New(mockAPI, &mockService{failed: []domain.FailedRedemption{entry}}, logger.New("error"), cfg)
+
+		// Values from guests and errors are escaped for the parse mode
+		bot.HandleCommand(commandMessage(99, "/failed"))
+		msg := mockAPI.messagesSent[0]
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("%s: expected %q in %q", mode, want, msg.Text)
+		}
+		if mode == "html" && (msg.ParseMode != "HTML" || !strings.Contains(msg.Text, "&lt;timeout&gt; &amp; retry")) {
+			t.Errorf("html: expected an escaped error, got %q with mode %q", msg.Text, msg.ParseMode)
+		}
+		if mode == "plain" && msg.ParseMode != "" {
+			t.Errorf("plain: expected no parse mode, got %q", msg.ParseMode)
+		}
+	}
+}
+
+func TestCallbacksAfterRestart(t *testing.T) {
+	mockSvc := &mockService{
+		status: "eligible",
+		user:   &domain.User{ID: "1", Email: "eligible@example.com", DateAdded: time.Now()},
+	}
+	cfg := newTestConfig()
+	cfg.Telegram.CallbackStateFile = filepath.Join(t.TempDir(), "callbacks.json")
+	cfg.Telegram.CallbackDebounce = 0 // The button is pressed again as if after a while
+
+	mockAPI := newMockBotAPI()
+	bot := telegram.New(mockAPI, mockSvc, logger.New("error"), cfg)
+	chat := &tgbotapi.Chat{ID: 456}
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 456}, Chat: chat, Text: "eligible@example.com"})
+	markup, ok := mockAPI.messagesSent[len(mockAPI.messagesSent)-1].ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	if !ok {
+		t.Fatal("Expected redemption buttons")
+	}
+	redeem := *markup.InlineKeyboard[0][0].CallbackData
+
+	// A new bot has no email cache but finds the state behind the button
+	mockAPI = newMockBotAPI()
+	restarted := telegram.New(mockAPI, mockSvc, logger.New("error"), cfg)
+	press := func(userID int64) string {
+		restarted.HandleCallbackQuery(&tgbotapi.CallbackQuery{ID: "1", From: &tgbotapi.User{ID: userID}, Message: &tgbotapi.Message{MessageID: 2, Chat: chat}, Data: redeem})
+		return mockAPI.messagesSent[len(mockAPI.messagesSent)-1].Text
+	}
+	if text := press(456); !strings.Contains(text, "Enjoy your free cocktail") {
+		t.Errorf("Expected the redemption confirmed after a restart, got %q", text)
+	}
+
+	// Pressing the button again reports the redemption instead of repeating it
+	redeemed := time.Now()
+	mockSvc.status = "redeemed"
+	mockSvc.user.Redeemed = &redeemed
+	mockSvc.redeemError = errors.New("should not redeem again")
+	if text := press(456); !strings.Contains(text, "already") {
+		t.Errorf("Expected the earlier redemption reported, got %q", text)
+	}
+
+	// Buttons only work for the user they were sent to
+	if text := press(789); !strings.Contains(text, "can't find your email") {
+		t.Errorf("Expected another user to be refused, got %q", text)
+	}
+}
+
+func TestEmailSuggestions(t *testing.T) {
+	mockSvc := &mockService{status: "not_found", suggestions: []string{"j.smith@gmail.com"}}
+	mockAPI := newMockBotAPI()
+	bot := telegram.New(mockAPI, mockSvc, logger.New("error"), newTestConfig())
+	chat := &tgbotapi.Chat{ID: 456}
+	last := func() tgbotapi.MessageConfig {
+		return mockAPI.messagesSent[len(mockAPI.messagesSent)-1]
+	}
+	press := func(data string) {
+		bot.HandleCallbackQuery(&tgbotapi.CallbackQuery{ID: "1", From: &tgbotapi.User{ID: 456}, Message: &tgbotapi.Message{MessageID: 2, Chat: chat}, Data: data})
+	}
+
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 456}, Chat: chat, Text: "j.smtih@gmail.com"})
+	markup, ok := last().ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	if !ok || len(markup.InlineKeyboard) != 1 {
+		t.Fatalf("Expected a suggestion button, got %+v", last())
+	}
+	suggestion := markup.InlineKeyboard[0][0]
+	if suggestion.Text != "j***h@gmail.com" {
+		t.Errorf("Expected the suggestion to be masked, got %q", suggestion.Text)
+	}
+
+	// Picking a suggestion asks for confirmation before checking it
+	press(*suggestion.CallbackData)
+	if mockSvc.checked != "j.smtih@gmail.com" || !strings.Contains(last().Text, "j***h@gmail.com") {
+		t.Fatalf("Expected a confirmation, got %q after checking %s", last().Text, mockSvc.checked)
+	}
+	markup = last().ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	press(*markup.InlineKeyboard[0][1].CallbackData)
+	if !strings.Contains(last().Text, "not in database") {
+		t.Errorf("Expected a rejected suggestion to leave the email not found, got %q", last().Text)
+	}
+
+	press(*markup.InlineKeyboard[0][0].CallbackData)
+	if mockSvc.checked != "j.smith@gmail.com" || !strings.Contains(last().Text, "eligible") {
+		t.Errorf("Expected the confirmed suggestion to be checked, got %q", last().Text)
+	}
+
+	// Without suggestions the email is reported as not found
+	mockSvc.suggestions = nil
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 3, From: &tgbotapi.User{ID: 456}, Chat: chat, Text: "nobody@example.com"})
+	if !strings.Contains(last().Text, "not in database") {
+		t.Errorf("Expected not found, got %q", last().Text)
+	}
+}
+
+func TestDoubleTapRedeemsOnce(t *testing.T) {
+	mockSvc := &mockService{
+		status: "eligible",
+		user:   &domain.User{ID: "1", Email: "eligible@example.com", DateAdded: time.Now()},
+	}
+	mockAPI := newMockBotAPI()
+	bot := telegram.New(mockAPI, mockSvc, logger.New("error"), newTestConfig())
+	chat := &tgbotapi.Chat{ID: 456}
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 456}, Chat: chat, Text: "eligible@example.com"})
+	markup, ok := mockAPI.messagesSent[len(mockAPI.messagesSent)-1].ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	if !ok {
+		t.Fatal("Expected redemption buttons")
+	}
+	redeem := *markup.InlineKeyboard[0][0].CallbackData
+	press := func(id string, messageID int) {
+		bot.HandleCallbackQuery(&tgbotapi.CallbackQuery{ID: id, From: &tgbotapi.User{ID: 456}, Message: &tgbotapi.Message{MessageID: messageID, Chat: chat}, Data: redeem})
+	}
+
+	// Both presses are acknowledged, only the first is answered
+	mockAPI.messagesSent = nil
+	press("1", 1)
+	press("2", 1)
+	if len(mockAPI.callbackAnswers) != 2 {
+		t.Errorf("Expected both presses to be acknowledged, got %d", len(mockAPI.callbackAnswers))
+	}
+	successes := 0
+	for _, msg := range mockAPI.messagesSent {
+		if strings.Contains(msg.Text, "Enjoy your free cocktail") {
+			successes++
+		}
+	}
+	if successes != 1 || len(mockAPI.messagesSent) != 1 {
+		t.Errorf("Expected a single success message, got %+v", mockAPI.messagesSent)
+	}
+
+	// A press that loses the race to another one stays quiet
+	mockAPI.messagesSent = nil
+	mockSvc.redeemError = domain.ErrAlreadyRedeemed
+	press("3", 2)
+	if len(mockAPI.messagesSent) != 0 {
+		t.Errorf("Expected no reply to a redemption completed by another press, got %+v", mockAPI.messagesSent)
+	}
+}
+
+func TestSelfRegistration(t *testing.T) {
+	mockSvc := &mockService{status: "not_found", selfRegistration: true}
+	mockAPI := newMockBotAPI()
+	cfg := newTestConfig()
+	cfg.Telegram.AdminUsers = []int64{100}
+	cfg.Telegram.CallbackDebounce = 0 // Buttons are pressed again as if after a while
+	bot := telegram.New(mockAPI, mockSvc, logger.New("error"), cfg)
+	guestChat := &tgbotapi.Chat{ID: 456}
+	adminChat := &tgbotapi.Chat{ID: 100}
+	last := func() tgbotapi.MessageConfig {
+		return mockAPI.messagesSent[len(mockAPI.messagesSent)-1]
+	}
+	button := func(message tgbotapi.MessageConfig, i int) string {
+		markup, ok := message.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+		if !ok || len(markup.InlineKeyboard[0]) <= i {
+			t.Fatalf("Expected buttons, got %+v", message)
+		}
+		return *markup.InlineKeyboard[0][i].CallbackData
+	}
+	press := func(userID int64, chat *tgbotapi.Chat, data string) {
+		bot.HandleCallbackQuery(&tgbotapi.CallbackQuery{ID: "1", From: &tgbotapi.User{ID: userID}, Message: &tgbotapi.Message{MessageID: 2, Chat: chat}, Data: data})
+	}
+
+	// An unknown email is offered a request for access
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 456}, Chat: guestChat, Text: "walkin@example.com"})
+	offer := last()
+	if !strings.Contains(offer.Text, "ask the organizers") {
+		t.Fatalf("Expected a registration offer, got %q", offer.Text)
+	}
+
+	// Requesting access notifies the admins with approve and reject buttons
+	press(456, guestChat, button(offer, 0))
+	if len(mockSvc.registrations) != 1 || mockSvc.registrations[0].ChatID != 456 {
+		t.Fatalf("Expected a pending registration, got %+v", mockSvc.registrations)
+	}
+	request := last()
+	if request.ChatID != 100 || !strings.Contains(request.Text, "walkin@example.com") {
+		t.Fatalf("Expected the admin to be asked, got %+v", request)
+	}
+	if text := mockAPI.messagesSent[len(mockAPI.messagesSent)-2].Text; !strings.Contains(text, "sent to the organizers") {
+		t.Errorf("Expected the guest to be told, got %q", text)
+	}
+
+	// Asking again does not notify the admins twice
+	sent := len(mockAPI.messagesSent)
+	press(456, guestChat, button(offer, 0))
+	if len(mockAPI.messagesSent) != sent+1 || !strings.Contains(last().Text, "still waiting") {
+		t.Errorf("Expected the guest to be told the request is pending, got %q", last().Text)
+	}
+
+	// Only admins can decide
+	press(456, guestChat, button(request, 0))
+	if mockSvc.approved != "" || !strings.Contains(last().Text, "only available to administrators") {
+		t.Fatalf("Expected a guest to be refused, got %q", last().Text)
+	}
+
+	// Approval adds the email and offers the guest the drink
+	press(100, adminChat, button(request, 0))
+	if mockSvc.approved != "walkin@example.com" {
+		t.Fatalf("Expected the registration to be approved, got %q", mockSvc.approved)
+	}
+	if last().ChatID != 456 || !strings.Contains(last().Text, "eligible") {
+		t.Errorf("Expected the guest to be offered the drink, got %+v", last())
+	}
+	if text := mockAPI.messagesSent[len(mockAPI.messagesSent)-2].Text; !strings.Contains(text, "added to the guest list") {
+		t.Errorf("Expected the guest to be told about the approval, got %q", text)
+	}
+
+	// A second answer reports the first decision
+	press(100, adminChat, button(request, 1))
+	if !strings.Contains(last().Text, "already approved") {
+		t.Errorf("Expected the request to be decided already, got %q", last().Text)
+	}
+}
+
+func TestSharedEmailRedemption(t *testing.T) {
+	mockSvc := &mockService{status: "eligible", user: &domain.User{Email: "couple@example.com"}}
+	mockAPI := newMockBotAPI()
+	bot := telegram.New(mockAPI, mockSvc, logger.New("error"), newTestConfig())
+	firstChat, secondChat := &tgbotapi.Chat{ID: 456}, &tgbotapi.Chat{ID: 789}
+
+	// Both partners check the same email and get redeem buttons
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 456}, Chat: firstChat, Text: "couple@example.com"})
+	first := mockAPI.messagesSent[len(mockAPI.messagesSent)-1]
+	firstID := len(mockAPI.messagesSent)
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 789}, Chat: secondChat, Text: "couple@example.com"})
+	secondID := len(mockAPI.messagesSent)
+
+	// One of them redeems
+	markup := first.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	bot.HandleCallbackQuery(&tgbotapi.CallbackQuery{ID: "1", From: &tgbotapi.User{ID: 456}, Message: &tgbotapi.Message{MessageID: firstID, Chat: firstChat}, Data: *markup.InlineKeyboard[0][0].CallbackData})
+	if text := mockAPI.messagesSent[len(mockAPI.messagesSent)-1].Text; !strings.Contains(text, "Enjoy your free cocktail") {
+		t.Fatalf("Expected the email to be redeemed, got %q", text)
+	}
+
+	// The other chat's message loses its buttons and says so
+	if len(mockAPI.textsEdited) != 1 {
+		t.Fatalf("Expected the other chat's message to be updated, got %+v", mockAPI.textsEdited)
+	}
+	edit := mockAPI.textsEdited[0]
+	if edit.ChatID != 789 || edit.MessageID != secondID || !strings.Contains(edit.Text, "just redeemed") {
+		t.Errorf("Unexpected update of the other chat: %+v", edit)
+	}
+	if edit.ReplyMarkup == nil || len(edit.ReplyMarkup.InlineKeyboard) != 0 {
+		t.Errorf("Expected the buttons to be removed, got %+v", edit.ReplyMarkup)
+	}
+
+	// A later redemption of the same email updates nothing more
+	bot.HandleCallbackQuery(&tgbotapi.CallbackQuery{ID: "2", From: &tgbotapi.User{ID: 456}, Message: &tgbotapi.Message{MessageID: firstID, Chat: firstChat}, Data: *markup.InlineKeyboard[0][0].CallbackData})
+	if len(mockAPI.textsEdited) != 1 {
+		t.Errorf("Expected no further updates, got %d", len(mockAPI.textsEdited))
+	}
+}
+
+func TestRedeemedThroughAPI(t *testing.T) {
+	mockSvc := &mockService{status: "eligible", user: &domain.User{Email: "guest@example.com"}}
+	cfg := newTestConfig()
+	cfg.Telegram.CallbackStateFile = filepath.Join(t.TempDir(), "callbacks.json")
+	mockAPI := newMockBotAPI()
+	bot := telegram.New(mockAPI, mockSvc, logger.New("error"), cfg)
+	bot.HandleMessage(&tgbotapi.Message{MessageID: 1, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "guest@example.com"})
+	messageID := len(mockAPI.messagesSent)
+
+	// The message reference is kept across restarts
+	mockAPI = newMockBotAPI()
+	restarted := telegram.New(mockAPI, mockSvc, logger.New("error"), cfg)
+
+	// Redemptions of other emails leave the message alone
+	restarted.HandleEvent(events.Event{Type: events.Redeemed, Email: "other@example.com", UserID: 99, Time: time.Now()})
+	if len(mockAPI.textsEdited) != 0 {
+		t.Fatalf("Expected no update for another email, got %+v", mockAPI.textsEdited)
+	}
+
+	// A redemption through the API removes the buttons and says so
+	restarted.HandleEvent(events.Event{Type: events.Redeemed, Email: "guest@example.com", UserID: 99, Actor: "token:abcd", Time: time.Now()})
+	if len(mockAPI.textsEdited) != 1 {
+		t.Fatalf("Expected the message to be updated, got %+v", mockAPI.textsEdited)
+	}
+	if edit := mockAPI.textsEdited[0]; edit.ChatID != 456 || edit.MessageID != messageID || !strings.Contains(edit.Text, "just redeemed") {
+		t.Errorf("Unexpected update: %+v", edit)
+	}
+
+	// Skipped redemptions are not updated later
+	restarted.HandleMessage(&tgbotapi.Message{MessageID: 2, From: &tgbotapi.User{ID: 456}, Chat: &tgbotapi.Chat{ID: 456}, Text: "guest@example.com"})
+	markup := mockAPI.messagesSent[len(mockAPI.messagesSent)-1].ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	restarted.HandleCallbackQuery(&tgbotapi.CallbackQuery{ID: "1", From: &tgbotapi.User{ID: 456}, Message: &tgbotapi.Message{MessageID: len(mockAPI.messagesSent), Chat: &tgbotapi.Chat{ID: 456}}, Data: *markup.InlineKeyboard[0][1].CallbackData})
+	restarted.HandleEvent(events.Event{Type: events.Redeemed, Email: "guest@example.com", UserID: 99, Time: time.Now()})
+	if len(mockAPI.textsEdited) != 1 {
+		t.Errorf("Expected a skipped message not to be updated, got %+v", mockAPI.textsEdited)
+	}
+}
+
+func TestRedemptionReceipts(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Telegram.ReceiptsChannel = -1001234
+	mockAPI := newMockBotAPI()
+	bot := telegram.New(mockAPI, &mockService{}, logger.New("error"), cfg)
+
+	// Every redemption is posted to the channel with a masked email
+	redeemed := time.Date(2025, 6, 14, 21, 30, 0, 0, time.UTC)
+	bot.HandleEvent(events.Event{Type: events.Redeemed, Email: "guest@example.com", Actor: "token:abcd", Bar: "Rooftop", Time: redeemed})
+	if len(mockAPI.messagesSent) != 1 {
+		t.Fatalf("Expected a receipt, got %+v", mockAPI.messagesSent)
+	}
+	receipt := mockAPI.messagesSent[0]
+	if receipt.ChatID != -1001234 || strings.Contains(receipt.Text, "guest@example.com") ||
+		!strings.Contains(receipt.Text, "g***t@example.com") || !strings.Contains(receipt.Text, "2025-06-14 21:30:00 UTC") ||
+		!strings.Contains(receipt.Text, "token:abcd") || !strings.Contains(receipt.Text, "Rooftop") {
+		t.Errorf("Unexpected receipt: %+v", receipt)
+	}
+
+	// Offline redemptions name the operator
+	bot.HandleEvent(events.Event{Type: events.Redeemed, Email: "other@example.com", Actor: "system", Operator: "anna", Time: redeemed})
+	if text := mockAPI.messagesSent[1].Text; !strings.Contains(text, "anna (system)") || strings.Contains(text, "Bar:") {
+		t.Errorf("Unexpected offline receipt: %s", text)
+	}
+
+	// Without a channel nothing is posted
+	cfg.Telegram.ReceiptsChannel = 0
+	bot.HandleEvent(events.Event{Type: events.Redeemed, Email: "guest@example.com", Time: redeemed})
+	if len(mockAPI.messagesSent) != 2 {
+		t.Errorf("Expected no receipt without a channel, got %+v", mockAPI.messagesSent)
+	}
+}
@@ -0,0 +1,231 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/kvstore"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// callbackStateTTL is how long the buttons of a message keep working
+const callbackStateTTL = 48 * time.Hour
+
+// callbackState is the email behind the buttons of one message. Redeem
+// buttons also record their message, so it can be updated once the email
+// is redeemed elsewhere.
+type callbackState struct {
+	UserID    int64     `json:"user_id"`
+	Email     string    `json:"email"`
+	Created   time.Time `json:"created"`
+	ChatID    int64     `json:"chat_id,omitempty"`
+	MessageID int       `json:"message_id,omitempty"`
+}
+
+// callbackStore keeps the state of sent buttons by reference. Buttons carry
+// the reference in their callback data, and the store is saved to the state
+// store or a file so that buttons pressed after a restart still find their
+// email.
+type callbackStore struct {
+	state   kvstore.Store // Where the state is saved, nil saves it to path
+	path    string        // JSON file the state is saved to, empty keeps it in memory only
+	mu      sync.Mutex
+	entries map[string]callbackState
+}
+
+// loadCallbackStore reads the button state saved by a previous run
+func loadCallbackStore(path string) (*callbackStore, error) {
+	store := &callbackStore{path: path, entries: make(map[string]callbackState)}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read callback state file: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse callback state file: %w", err)
+	}
+	return store, nil
+}
+
+// loadCallbackState reads the button state kept in the state store
+func loadCallbackState(state kvstore.Store) (*callbackStore, error) {
+	store := &callbackStore{state: state, entries: make(map[string]callbackState)}
+	data, err := state.Get(context.Background(), callbacksKey)
+	if errors.Is(err, kvstore.ErrNotFound) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read callback state: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse callback state: %w", err)
+	}
+	return store, nil
+}
+
+// openCallbackStore loads the callback state from the state store, or the
+// configured file without one. Lost state only makes old buttons ask for
+// the email again, so state that cannot be read is logged and replaced.
+func openCallbackStore(cfg *config.Config, l *logger.Logger, state kvstore.Store) *callbackStore {
+	if state != nil {
+		store, err := loadCallbackState(state)
+		if err != nil {
+			l.Warn("Starting with empty callback state", "error", err)
+			store = &callbackStore{state: state, entries: make(map[string]callbackState)}
+		}
+		return store
+	}
+
+	var path string
+	if cfg != nil {
+		path = cfg.Telegram.CallbackStateFile
+	}
+	store, err := loadCallbackStore(path)
+	if err != nil {
+		l.Warn("Starting with empty callback state", "error", err)
+		store = &callbackStore{path: path, entries: make(map[string]callbackState)}
+	}
+	return store
+}
+
+// issue stores the email behind a new message and returns its reference
+func (c *callbackStore) issue(userID int64, email string) (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	ref := base64.RawURLEncoding.EncodeToString(buf)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, state := range c.entries {
+		if now.Sub(state.Created) > callbackStateTTL {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[ref] = callbackState{UserID: userID, Email: email, Created: now}
+	return ref, c.save()
+}
+
+// lookup returns the email behind a reference. Buttons only work for the
+// user they were sent to and until they expire.
+func (c *callbackStore) lookup(ref string, userID int64) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state, ok := c.entries[ref]
+	if !ok || state.UserID != userID || time.Since(state.Created) > callbackStateTTL {
+		return "", false
+	}
+	return state.Email, true
+}
+
+// attach records the message carrying the buttons behind ref
+func (c *callbackStore) attach(ref string, chatID int64, messageID int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state, ok := c.entries[ref]
+	if !ok {
+		return nil
+	}
+	state.ChatID, state.MessageID = chatID, messageID
+	c.entries[ref] = state
+	return c.save()
+}
+
+// takeMessages returns the unexpired messages with buttons for the email
+// and forgets them, so each message is updated only once. The buttons
+// themselves keep their email.
+func (c *callbackStore) takeMessages(email string) ([]callbackState, error) {
+	return c.detach(func(state callbackState) bool { return state.Email == email })
+}
+
+// release forgets the messages with buttons for the email sent to one
+// user, such as after the user skipped the redemption
+func (c *callbackStore) release(email string, userID int64) error {
+	_, err := c.detach(func(state callbackState) bool { return state.Email == email && state.UserID == userID })
+	return err
+}
+
+// detach forgets the messages of the entries matching the filter and
+// returns the unexpired ones
+func (c *callbackStore) detach(match func(state callbackState) bool) ([]callbackState, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var taken []callbackState
+	for ref, state := range c.entries {
+		if state.MessageID == 0 || !match(state) {
+			continue
+		}
+		if time.Since(state.Created) <= callbackStateTTL {
+			taken = append(taken, state)
+		}
+		state.ChatID, state.MessageID = 0, 0
+		c.entries[ref] = state
+	}
+	if len(taken) == 0 {
+		return nil, nil
+	}
+	return taken, c.save()
+}
+
+// save writes all entries to the state store or file. The caller must hold mu.
+func (c *callbackStore) save() error {
+	if c.state == nil && c.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if c.state != nil {
+		return c.state.Set(context.Background(), callbacksKey, data)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash cannot leave a truncated file
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// callbackData returns the callback data of a button acting on the state
+// behind ref. Without a reference the button falls back to the last email
+// the user checked.
+func callbackData(action, ref string) string {
+	if ref == "" {
+		return action
+	}
+	return action + ":" + ref
+}
+
+// splitCallbackData splits callback data into its action and state reference
+func splitCallbackData(data string) (action, ref string) {
+	action, ref, _ = strings.Cut(data, ":")
+	return action, ref
+}
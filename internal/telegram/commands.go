@@ -0,0 +1,94 @@
+package telegram
+
+import (
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// botCommand describes a command shown in the Telegram command menu
+type botCommand struct {
+	name  string // Command without the leading slash
+	key   string // Translation key of the description
+	admin bool   // Only shown to and usable by admins
+}
+
+// botCommands lists the commands in the order they appear in the menu
+var botCommands = []botCommand{
+	{name: "start", key: "cmd_start"},
+	{name: "help", key: "cmd_help"},
+	{name: "language", key: "cmd_language"},
+	{name: "mystatus", key: "cmd_mystatus"},
+	{name: "stats", key: "cmd_stats", admin: true},
+	{name: "resetlimit", key: "cmd_resetlimit", admin: true},
+	{name: "failed", key: "cmd_failed", admin: true},
+	{name: "block", key: "cmd_block", admin: true},
+	{name: "unblock", key: "cmd_unblock", admin: true},
+}
+
+// commandEnabled checks if a command is not disabled in the configuration,
+// or in the configuration last reloaded
+func (b *Bot) commandEnabled(name string) bool {
+	if reloaded := b.reloaded.Load(); reloaded != nil {
+		return reloaded.IsCommandEnabled(name)
+	}
+	return b.config == nil || b.config.IsCommandEnabled(name)
+}
+
+// Reload applies a reloaded configuration, such as after SIGHUP: commands
+// disabled or enabled since take effect, and the command menu is published
+// again with the current translations.
+func (b *Bot) Reload(cfg *config.Config) error {
+	b.reloaded.Store(cfg)
+	return b.RegisterCommands()
+}
+
+// menuCommands returns the enabled commands with descriptions in the given language
+func (b *Bot) menuCommands(lang string, includeAdmin bool) []tgbotapi.BotCommand {
+	var commands []tgbotapi.BotCommand
+	for _, cmd := range botCommands {
+		if cmd.admin && !includeAdmin {
+			continue
+		}
+		if !b.commandEnabled(cmd.name) {
+			continue
+		}
+		commands = append(commands, tgbotapi.BotCommand{
+			Command:     cmd.name,
+			Description: b.translator.T(lang, cmd.key),
+		})
+	}
+	return commands
+}
+
+// RegisterCommands publishes the command menu to Telegram. Guests get a
+// localized menu for every enabled language, admins additionally see the
+// admin commands. It is called again when the configuration is reloaded
+// and when a feature flag is toggled.
+func (b *Bot) RegisterCommands() error {
+	fallback := b.translator.GetFallbackLanguage()
+
+	requests := []tgbotapi.SetMyCommandsConfig{
+		// Default menu for users whose language is not enabled
+		tgbotapi.NewSetMyCommandsWithScope(tgbotapi.NewBotCommandScopeDefault(), b.menuCommands(fallback, false)...),
+	}
+	for _, lang := range b.translator.GetAvailableLanguages() {
+		requests = append(requests, tgbotapi.NewSetMyCommandsWithScopeAndLanguage(
+			tgbotapi.NewBotCommandScopeDefault(), lang, b.menuCommands(lang, false)...))
+	}
+	if b.config != nil {
+		for _, adminID := range b.config.Telegram.AdminUsers {
+			requests = append(requests, tgbotapi.NewSetMyCommandsWithScope(
+				tgbotapi.NewBotCommandScopeChat(adminID), b.menuCommands(fallback, true)...))
+		}
+	}
+
+	for _, req := range requests {
+		if _, err := b.api.Request(req); err != nil {
+			b.logger.Error("Failed to register bot commands", "language", req.LanguageCode, "error", err)
+			return err
+		}
+	}
+
+	b.logger.Info("Bot commands registered", "menus", len(requests))
+	return nil
+}
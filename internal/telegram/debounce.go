@@ -0,0 +1,66 @@
+package telegram
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+)
+
+// maxDebounceKeys is the number of presses tracked before expired ones are forgotten
+const maxDebounceKeys = 10000
+
+// pressKey identifies a button press: the same button of the same message
+// pressed by the same user
+type pressKey struct {
+	userID    int64
+	messageID int
+	data      string
+}
+
+// debouncer drops repeated presses of a button. A fast double tap can
+// reach the bot as two callback queries, which are handled concurrently
+// and would otherwise both redeem and both answer.
+type debouncer struct {
+	window time.Duration // 0 disables
+	now    func() time.Time
+
+	mu      sync.Mutex
+	presses map[pressKey]time.Time
+}
+
+// newDebouncer returns a debouncer with the window configured in cfg
+func newDebouncer(cfg *config.Config) *debouncer {
+	d := &debouncer{now: time.Now, presses: make(map[pressKey]time.Time)}
+	if cfg != nil {
+		d.window = cfg.Telegram.CallbackDebounce.Duration()
+	}
+	return d
+}
+
+// allow returns true for the first press of a button and false for
+// identical presses within the window after it
+func (d *debouncer) allow(userID int64, messageID int, data string) bool {
+	if d.window <= 0 {
+		return true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	key := pressKey{userID: userID, messageID: messageID, data: data}
+	if last, ok := d.presses[key]; ok && now.Sub(last) < d.window {
+		return false
+	}
+
+	if len(d.presses) >= maxDebounceKeys {
+		for k, last := range d.presses {
+			if now.Sub(last) >= d.window {
+				delete(d.presses, k)
+			}
+		}
+	}
+	d.presses[key] = now
+	return true
+}
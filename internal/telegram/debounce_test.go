@@ -0,0 +1,41 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+)
+
+func TestDebouncer(t *testing.T) {
+	cfg := config.New()
+	cfg.Telegram.CallbackDebounce = config.Duration(2 * time.Second)
+
+	now := time.Date(2025, 6, 14, 21, 0, 0, 0, time.UTC)
+	d := newDebouncer(cfg)
+	d.now = func() time.Time { return now }
+
+	if !d.allow(42, 1, "redeem:abc") {
+		t.Fatal("Expected the first press to be allowed")
+	}
+	if d.allow(42, 1, "redeem:abc") {
+		t.Error("Expected a double tap to be dropped")
+	}
+
+	// Other buttons, messages and users are not affected
+	if !d.allow(42, 1, "skip:abc") || !d.allow(42, 2, "redeem:abc") || !d.allow(43, 1, "redeem:abc") {
+		t.Error("Expected presses of other buttons to be allowed")
+	}
+
+	now = now.Add(2 * time.Second)
+	if !d.allow(42, 1, "redeem:abc") {
+		t.Error("Expected a press after the window to be allowed")
+	}
+
+	// A zero window disables debouncing
+	cfg.Telegram.CallbackDebounce = 0
+	d = newDebouncer(cfg)
+	if !d.allow(42, 1, "redeem:abc") || !d.allow(42, 1, "redeem:abc") {
+		t.Error("Expected every press to be allowed without a window")
+	}
+}
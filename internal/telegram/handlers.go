@@ -2,7 +2,11 @@ package telegram
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ceesaxp/cocktail-bot/internal/domain"
 	"github.com/ceesaxp/cocktail-bot/internal/utils"
@@ -10,115 +14,632 @@ import (
 )
 
 // handleMessage processes incoming messages
-func (b *Bot) handleMessage(message *tgbotapi.Message) {
+func (b *Bot) handleMessage(ctx context.Context, message *tgbotapi.Message) {
+	if b.isBlocked(message.Chat.ID, message.From.ID) {
+		return
+	}
+
+	// Record engagement statistics
+	b.service.TrackInteraction(b.getUserLanguage(message.From.ID), message.Command())
 
 	if message.IsCommand() {
-		b.handleCommand(message)
+		b.handleCommand(ctx, message)
 		return
 	}
 
 	// Check if the message text looks like an email
 	if utils.IsValidEmail(message.Text) {
-		b.handleEmailCheck(message)
+		if !b.service.FreeFormEmailAllowed() {
+			b.sendTranslated(message.Chat.ID, message.From.ID, "voucher_required")
+			return
+		}
+		b.handleEmailCheck(ctx, message)
+		return
+	}
+
+	// Check if the message text looks like a verification code
+	if b.service.VerificationRequired() && isVerificationCode(message.Text) {
+		b.handleVerificationCode(ctx, message)
+		return
+	}
+
+	// Check if the message text looks like a voucher code
+	if isVoucherCode(message.Text) {
+		b.handleVoucher(ctx, message, message.Text)
 		return
 	}
 
 	// Respond with help message
+	if !b.service.FreeFormEmailAllowed() {
+		b.sendTranslated(message.Chat.ID, message.From.ID, "voucher_required")
+		return
+	}
 	b.sendTranslated(message.Chat.ID, message.From.ID, "invalid_email")
 }
 
 // handleCommand handles bot commands
-func (b *Bot) handleCommand(message *tgbotapi.Message) {
+func (b *Bot) handleCommand(ctx context.Context, message *tgbotapi.Message) {
+	if !b.commandEnabled(message.Command()) {
+		b.sendTranslated(message.Chat.ID, message.From.ID, "unknown_command")
+		return
+	}
+
 	switch message.Command() {
 	case "start":
+		// Deep links such as t.me/<bot>?start=<voucher> carry a voucher code
+		if code := strings.TrimSpace(message.CommandArguments()); code != "" {
+			b.handleVoucher(ctx, message, code)
+			return
+		}
+		if !b.service.FreeFormEmailAllowed() {
+			b.sendTranslated(message.Chat.ID, message.From.ID, "voucher_required")
+			return
+		}
 		b.sendTranslated(message.Chat.ID, message.From.ID, "welcome")
 	case "help":
 		b.sendHelpMessage(message.Chat.ID, message.From.ID)
 	case "language":
 		b.sendLanguageOptions(message.Chat.ID)
+	case "mystatus":
+		b.handleMyStatus(ctx, message)
+	case "stats":
+		b.handleStats(ctx, message)
+	case "resetlimit":
+		b.handleResetLimit(ctx, message)
+	case "failed":
+		b.handleFailed(ctx, message)
+	case "block", "unblock":
+		b.handleBlock(ctx, message)
 	default:
 		b.sendTranslated(message.Chat.ID, message.From.ID, "unknown_command")
 	}
 }
 
+// handleResetLimit handles the admin command to reset a user's rate limit
+func (b *Bot) handleResetLimit(ctx context.Context, message *tgbotapi.Message) {
+	if !b.isAdmin(message.From.ID) {
+		b.log(ctx).Warn("Non-admin attempted admin command", "command", message.Command(), "user_id", message.From.ID)
+		b.sendTranslated(message.Chat.ID, message.From.ID, "admin_only")
+		return
+	}
+
+	targetID, err := strconv.ParseInt(strings.TrimSpace(message.CommandArguments()), 10, 64)
+	if err != nil || targetID == 0 {
+		b.sendTranslated(message.Chat.ID, message.From.ID, "resetlimit_usage")
+		return
+	}
+
+	b.service.ResetRateLimit(targetID)
+	b.log(ctx).Info("Audit: rate limit reset", "actor", "telegram:"+strconv.FormatInt(message.From.ID, 10), "target_user_id", targetID)
+	b.sendTranslated(message.Chat.ID, message.From.ID, "resetlimit_done", "user_id", strconv.FormatInt(targetID, 10))
+}
+
+// handleFailed handles the admin command to list, retry and resolve failed redemptions
+func (b *Bot) handleFailed(ctx context.Context, message *tgbotapi.Message) {
+	if !b.isAdmin(message.From.ID) {
+		b.log(ctx).Warn("Non-admin attempted admin command", "command", message.Command(), "user_id", message.From.ID)
+		b.sendTranslated(message.Chat.ID, message.From.ID, "admin_only")
+		return
+	}
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		entries := b.service.FailedRedemptions()
+		if len(entries) == 0 {
+			b.sendTranslated(message.Chat.ID, message.From.ID, "failed_none")
+			return
+		}
+
+		lines := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			// Entries are retried by ID, so the full email is not needed
+			email := entry.Email
+			if b.config.Privacy.MaskEmails {
+				email = utils.MaskEmail(email)
+			}
+			lines = append(lines, b.format(message.From.ID, "failed_entry",
+				"id", entry.ID,
+				"email", email,
+				"time", entry.AttemptedAt.Format("2006-01-02 15:04"),
+				"error", entry.Error,
+				"retries", strconv.Itoa(entry.Retries),
+			))
+		}
+		b.sendFormatted(message.Chat.ID, strings.Join(lines, "\n"))
+		return
+	}
+
+	if len(args) != 2 || (args[0] != "retry" && args[0] != "resolve") {
+		b.sendTranslated(message.Chat.ID, message.From.ID, "failed_usage")
+		return
+	}
+
+	action, id := args[0], args[1]
+	actor := "telegram:" + strconv.FormatInt(message.From.ID, 10)
+	var err error
+	if action == "retry" {
+		var redeemed time.Time
+		redeemed, err = b.service.RetryFailedRedemption(ctx, id)
+		if err == nil {
+			b.log(ctx).Info("Audit: failed redemption retried", "actor", actor, "id", id)
+			b.sendTranslated(message.Chat.ID, message.From.ID, "failed_retried", "id", id, "time", redeemed.Format("2006-01-02 15:04"))
+			return
+		}
+	} else {
+		err = b.service.ResolveFailedRedemption(id)
+		if err == nil {
+			b.log(ctx).Info("Audit: failed redemption resolved", "actor", actor, "id", id)
+			b.sendTranslated(message.Chat.ID, message.From.ID, "failed_resolved", "id", id)
+			return
+		}
+	}
+
+	if errors.Is(err, domain.ErrFailedRedemptionNotFound) {
+		b.sendTranslated(message.Chat.ID, message.From.ID, "failed_not_found", "id", id)
+		return
+	}
+	b.sendTranslated(message.Chat.ID, message.From.ID, "failed_error", "id", id, "error", err.Error())
+}
+
+// handleBlock handles the admin commands to block and unblock a Telegram user or an email
+func (b *Bot) handleBlock(ctx context.Context, message *tgbotapi.Message) {
+	if !b.isAdmin(message.From.ID) {
+		b.log(ctx).Warn("Non-admin attempted admin command", "command", message.Command(), "user_id", message.From.ID)
+		b.sendTranslated(message.Chat.ID, message.From.ID, "admin_only")
+		return
+	}
+
+	block := message.Command() == "block"
+	target := strings.TrimSpace(message.CommandArguments())
+	actor := "telegram:" + strconv.FormatInt(message.From.ID, 10)
+
+	// Email addresses and domains go to the deny list
+	if strings.Contains(target, "@") {
+		if block {
+			b.service.DenyEmail(target)
+			b.sendTranslated(message.Chat.ID, message.From.ID, "deny_done", "email", target)
+		} else {
+			b.service.AllowEmail(target)
+			b.sendTranslated(message.Chat.ID, message.From.ID, "allow_done", "email", target)
+		}
+		b.log(ctx).Info("Audit: email deny list changed", "actor", actor, "email", target, "denied", block)
+		return
+	}
+
+	targetID, err := strconv.ParseInt(target, 10, 64)
+	if err != nil || targetID == 0 {
+		b.sendTranslated(message.Chat.ID, message.From.ID, "block_usage")
+		return
+	}
+
+	if block {
+		b.service.BlockUser(targetID)
+		b.sendTranslated(message.Chat.ID, message.From.ID, "block_done", "user_id", target)
+	} else {
+		b.service.UnblockUser(targetID)
+		b.sendTranslated(message.Chat.ID, message.From.ID, "unblock_done", "user_id", target)
+	}
+	b.log(ctx).Info("Audit: user block list changed", "actor", actor, "target_user_id", targetID, "blocked", block)
+}
+
+// handleMyStatus repeats the lookup for the last email the user checked
+func (b *Bot) handleMyStatus(ctx context.Context, message *tgbotapi.Message) {
+	email, ok := b.emailCache.get(message.From.ID)
+	if !ok {
+		b.sendTranslated(message.Chat.ID, message.From.ID, "mystatus_none")
+		return
+	}
+
+	b.checkEmail(ctx, message, email)
+}
+
+// handleStats handles the admin command to show engagement statistics
+func (b *Bot) handleStats(ctx context.Context, message *tgbotapi.Message) {
+	if !b.isAdmin(message.From.ID) {
+		b.log(ctx).Warn("Non-admin attempted admin command", "command", message.Command(), "user_id", message.From.ID)
+		b.sendTranslated(message.Chat.ID, message.From.ID, "admin_only")
+		return
+	}
+
+	stats := b.service.EngagementStats()
+	b.sendTranslated(message.Chat.ID, message.From.ID, "stats_summary",
+		"since", stats.Since.Format("2006-01-02 15:04"),
+		"interactions", strconv.Itoa(stats.Interactions),
+		"checks", strconv.Itoa(stats.Checks),
+		"redemptions", strconv.Itoa(stats.Redemptions),
+		"conversion", fmt.Sprintf("%.1f%%", stats.ConversionRate*100),
+		"hour", fmt.Sprintf("%02d", stats.BusiestHour),
+	)
+}
+
 // handleEmailCheck processes email validation and database lookup
-func (b *Bot) handleEmailCheck(message *tgbotapi.Message) {
-	email := utils.NormalizeEmail(message.Text)
+func (b *Bot) handleEmailCheck(ctx context.Context, message *tgbotapi.Message) {
+	b.checkEmail(ctx, message, utils.NormalizeEmail(message.Text))
+}
 
+// checkEmail looks up the email and replies with its status
+func (b *Bot) checkEmail(ctx context.Context, message *tgbotapi.Message, email string) {
 	// Store email in cache for callback handling
-	b.emailCache[message.From.ID] = email
+	b.emailCache.set(message.From.ID, email)
 
 	// Check email status
-	ctx := context.Background()
-	status, user, err := b.service.CheckEmailStatus(ctx, int64(message.From.ID), email)
+	var (
+		status string
+		user   *domain.User
+		err    error
+	)
+	b.withProgress(message.Chat.ID, message.From.ID, "check", func() {
+		status, user, err = b.service.CheckEmailStatus(ctx, int64(message.From.ID), email)
+	})
 	if err != nil {
-		b.logger.Error("Error checking email status", "email", email, "error", err)
+		b.log(ctx).Error("Error checking email status", "email", email, "error", err)
 		b.sendTranslated(message.Chat.ID, message.From.ID, "error_occurred")
 		return
 	}
 
+	if status == "not_found" && b.sendSuggestions(ctx, message.Chat.ID, message.From.ID, email) {
+		return
+	}
+	if status == "not_found" && b.service.SelfRegistrationEnabled() {
+		b.sendRegistrationOffer(message.Chat.ID, message.From.ID, email)
+		return
+	}
+	if status != "eligible" {
+		b.sendStatus(ctx, message.Chat.ID, message.From.ID, status, user)
+		return
+	}
+	if b.service.VerificationRequired() {
+		b.startVerification(ctx, message, email)
+		return
+	}
+	b.sendEligibleMessage(message.Chat.ID, message.From.ID, email, b.greetingName(user))
+}
+
+// greetingName returns the first name the guest is greeted with. Anyone
+// can send any email, so guests are not greeted by name in privacy mode.
+func (b *Bot) greetingName(user *domain.User) string {
+	if user == nil || (b.config != nil && b.config.Privacy.MaskEmails) {
+		return ""
+	}
+	return user.FirstName
+}
+
+// sendStatus replies to a lookup of an email that cannot be redeemed
+func (b *Bot) sendStatus(ctx context.Context, chatID int64, userID int64, status string, user *domain.User) {
 	switch status {
 	case "rate_limited":
-		b.sendTranslated(message.Chat.ID, message.From.ID, "rate_limited")
+		b.sendRetry(chatID, userID, "rate_limited", b.service.RetryAfter(ctx, userID))
 	case "not_found":
-		b.sendTranslated(message.Chat.ID, message.From.ID, "email_not_found")
+		b.sendTranslated(chatID, userID, "email_not_found")
 	case "unavailable":
-		b.sendTranslated(message.Chat.ID, message.From.ID, "system_unavailable")
+		b.sendRetry(chatID, userID, "system_unavailable", b.service.UnavailableRetryAfter())
+	case "denied":
+		b.sendTranslated(chatID, userID, "email_denied")
+	case "archived":
+		b.sendTranslated(chatID, userID, "event_archived")
 	case "redeemed":
 		dateStr := user.Redeemed.Format("January 2, 2006")
-		b.sendTranslated(message.Chat.ID, message.From.ID, "already_redeemed", "date", dateStr)
-	case "eligible":
-		b.sendEligibleMessage(message.Chat.ID, message.From.ID)
+		b.sendTranslated(chatID, userID, "already_redeemed", "date", dateStr)
+	default:
+		b.sendTranslated(chatID, userID, "error_occurred")
+	}
+}
+
+// sendSuggestions offers guest emails close to one that was not found, as
+// buttons showing them masked. It returns false if there are none.
+func (b *Bot) sendSuggestions(ctx context.Context, chatID int64, userID int64, email string) bool {
+	if b.config == nil || !b.config.Telegram.SuggestEmails {
+		return false
+	}
+	suggestions := b.service.SuggestEmails(ctx, email)
+	if len(suggestions) == 0 {
+		return false
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, suggestion := range suggestions {
+		ref := b.callbackRef(userID, suggestion)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(utils.MaskEmail(suggestion), callbackData("suggest", ref)),
+		))
+	}
+
+	msg := b.newMessage(chatID, b.format(userID, "email_suggestions"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	if _, err := b.sender.Send(msg); err != nil {
+		b.logger.Error("Failed to send email suggestions", "error", err)
+	}
+	return true
+}
+
+// sendSuggestionConfirm asks whether a picked suggestion is the email of
+// the guest before checking it
+func (b *Bot) sendSuggestionConfirm(chatID int64, userID int64, email, ref string) {
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.translate(userID, "button_suggestion_yes"), callbackData("suggest_yes", ref)),
+			tgbotapi.NewInlineKeyboardButtonData(b.translate(userID, "button_suggestion_no"), "suggest_no"),
+		),
+	)
+
+	msg := b.newMessage(chatID, b.format(userID, "suggestion_confirm", "email", utils.MaskEmail(email)))
+	msg.ReplyMarkup = keyboard
+	if _, err := b.sender.Send(msg); err != nil {
+		b.logger.Error("Failed to send suggestion confirmation", "error", err)
+	}
+}
+
+// handleVoucher checks the email carried by a voucher code, sent as a
+// message or through a deep link
+func (b *Bot) handleVoucher(ctx context.Context, message *tgbotapi.Message, code string) {
+	email, err := b.service.VoucherEmail(code)
+	if errors.Is(err, domain.ErrVouchersDisabled) {
+		b.sendTranslated(message.Chat.ID, message.From.ID, "invalid_email")
+		return
+	}
+	if err != nil {
+		b.log(ctx).Info("Invalid voucher code", "error", err)
+		b.sendTranslated(message.Chat.ID, message.From.ID, "voucher_invalid")
+		return
+	}
+	b.checkEmail(ctx, message, email)
+}
+
+// isVoucherCode returns true if the text could be a voucher code: a
+// single word of URL-safe base64 longer than a verification code
+func isVoucherCode(text string) bool {
+	text = strings.TrimSpace(text)
+	if len(text) <= 16 {
+		return false
+	}
+	for _, r := range text {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// startVerification emails a code the user must enter before redeeming
+func (b *Bot) startVerification(ctx context.Context, message *tgbotapi.Message, email string) {
+	if err := b.service.SendVerificationCode(ctx, message.From.ID, email); err != nil {
+		b.log(ctx).Error("Error sending verification code", "email", email, "error", err)
+		b.sendTranslated(message.Chat.ID, message.From.ID, "error_occurred")
+		return
+	}
+
+	b.sendTranslated(message.Chat.ID, message.From.ID, "verification_sent", "email", email)
+}
+
+// handleVerificationCode checks a verification code entered by the user
+func (b *Bot) handleVerificationCode(ctx context.Context, message *tgbotapi.Message) {
+	email, err := b.service.VerifyEmailCode(ctx, message.From.ID, strings.TrimSpace(message.Text))
+
+	switch err {
+	case nil:
+		// Email verified, offer redemption
+		b.emailCache.set(message.From.ID, email)
+		b.sendEligibleMessage(message.Chat.ID, message.From.ID, email, "")
+	case domain.ErrInvalidVerificationCode:
+		b.sendTranslated(message.Chat.ID, message.From.ID, "verification_invalid")
+	case domain.ErrVerificationExpired:
+		b.sendTranslated(message.Chat.ID, message.From.ID, "verification_expired")
+	case domain.ErrTooManyAttempts:
+		b.sendTranslated(message.Chat.ID, message.From.ID, "verification_too_many")
+	case domain.ErrNoVerificationPending:
+		b.sendTranslated(message.Chat.ID, message.From.ID, "invalid_email")
 	default:
+		b.log(ctx).Error("Error verifying code", "error", err)
 		b.sendTranslated(message.Chat.ID, message.From.ID, "error_occurred")
 	}
 }
 
+// isVerificationCode returns true if the text is a 6-digit code
+func isVerificationCode(text string) bool {
+	text = strings.TrimSpace(text)
+	if len(text) != 6 {
+		return false
+	}
+	for _, r := range text {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // handleCallbackQuery handles button press responses
-func (b *Bot) handleCallbackQuery(query *tgbotapi.CallbackQuery) {
+func (b *Bot) handleCallbackQuery(ctx context.Context, query *tgbotapi.CallbackQuery) {
 	// Acknowledge the callback query
 	callback := tgbotapi.NewCallback(query.ID, "")
 	if _, err := b.api.Request(callback); err != nil {
-		b.logger.Error("Error acknowledging callback query", "error", err)
+		b.log(ctx).Error("Error acknowledging callback query", "error", err)
+	}
+
+	if b.isBlocked(query.Message.Chat.ID, query.From.ID) {
+		return
+	}
+
+	// A double tap answers once, the second press is only acknowledged
+	if !b.debounce.allow(query.From.ID, query.Message.MessageID, query.Data) {
+		b.log(ctx).Debug("Ignoring repeated button press", "data", query.Data)
+		return
 	}
 
 	// Handle language selection
 	if strings.HasPrefix(query.Data, "lang_") {
 		lang := strings.TrimPrefix(query.Data, "lang_")
-		b.handleLanguageSelection(query, lang)
+		b.handleLanguageSelection(ctx, query, lang)
+		return
+	}
+
+	action, ref := splitCallbackData(query.Data)
+
+	// Handle extra drink purchases
+	if action == "buy" {
+		b.handleBuy(ctx, query, ref)
+		b.removeButtons(ctx, query.Message)
+		return
+	}
+
+	// Handle marketing consent answers
+	if strings.HasPrefix(action, "consent_") {
+		b.handleConsent(ctx, query, action == "consent_yes", ref)
+		b.removeButtons(ctx, query.Message)
+		return
+	}
+
+	// Handle admin decisions on access requests, whose buttons carry the
+	// ID of the request
+	if action == "reg_approve" || action == "reg_reject" {
+		b.handleRegistrationDecision(ctx, query, action == "reg_approve", ref)
+		b.removeButtons(ctx, query.Message)
+		return
+	}
+
+	// A rejected suggestion leaves the email not found
+	if action == "suggest_no" {
+		b.sendTranslated(query.Message.Chat.ID, query.From.ID, "email_not_found")
+		b.removeButtons(ctx, query.Message)
 		return
 	}
 
-	// Get cached email
-	email, ok := b.emailCache[query.From.ID]
+	// Get the email behind the buttons
+	email, ok := b.callbackEmail(query.From.ID, ref, b.emailCache)
 	if !ok {
 		b.sendTranslated(query.Message.Chat.ID, query.From.ID, "email_not_cached")
 		return
 	}
 
-	switch query.Data {
+	// Handle the bar picked for a redemption
+	if strings.HasPrefix(action, "bar_") {
+		b.handleBarSelection(ctx, query, email, ref, strings.TrimPrefix(action, "bar_"))
+		b.removeButtons(ctx, query.Message)
+		return
+	}
+
+	switch action {
 	case "redeem":
-		b.handleRedemption(query, email)
+		// At multi-bar events, staff pick the bar that serves the drink first
+		if len(b.bars()) > 0 {
+			b.sendBarOptions(query.Message.Chat.ID, query.From.ID, ref)
+		} else {
+			b.handleRedemption(ctx, query, email, "")
+		}
 	case "skip":
-		b.handleSkip(query)
+		b.handleSkip(ctx, query, email)
+	case "suggest":
+		b.sendSuggestionConfirm(query.Message.Chat.ID, query.From.ID, email, ref)
+	case "register":
+		b.handleRegistration(ctx, query, email)
+	case "suggest_yes":
+		// Check the confirmed suggestion as if the guest had sent it
+		b.checkEmail(ctx, &tgbotapi.Message{Chat: query.Message.Chat, From: query.From}, email)
 	default:
 		b.sendTranslated(query.Message.Chat.ID, query.From.ID, "error_occurred")
 	}
 
 	// Remove buttons from the original message
-	b.removeButtons(query.Message)
+	b.removeButtons(ctx, query.Message)
 }
 
-// handleRedemption processes the cocktail redemption
-func (b *Bot) handleRedemption(query *tgbotapi.CallbackQuery, email string) {
-	ctx := context.Background()
-	redemptionTime, err := b.service.RedeemCocktail(ctx, int64(query.From.ID), email)
+// callbackEmail returns the email behind pressed buttons: the state their
+// reference points to, which survives restarts, or the pending email of
+// the user for buttons sent without a reference
+func (b *Bot) callbackEmail(userID int64, ref string, pending *userState) (string, bool) {
+	if ref != "" {
+		return b.callbacks.lookup(ref, userID)
+	}
+	return pending.get(userID)
+}
+
+// callbackRef stores the email behind the buttons of a new message and
+// returns the reference they carry
+func (b *Bot) callbackRef(userID int64, email string) string {
+	ref, err := b.callbacks.issue(userID, email)
+	if err != nil {
+		// The state is still kept in memory, it is only lost on restart
+		b.logger.Error("Failed to save callback state", "error", err)
+	}
+	return ref
+}
+
+// bars returns the bars of a multi-bar event, empty for a single bar
+func (b *Bot) bars() []string {
+	if b.config == nil {
+		return nil
+	}
+	return b.config.Event.Bars
+}
+
+// sendBarOptions asks which bar serves the drink, one button per bar.
+// Buttons carry the index of the bar, since callback data is limited to 64 bytes.
+func (b *Bot) sendBarOptions(chatID int64, userID int64, ref string) {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, bar := range b.bars() {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(bar, callbackData("bar_"+strconv.Itoa(i), ref)),
+		))
+	}
+
+	msg := b.newMessage(chatID, b.format(userID, "bar_question"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	if _, err := b.sender.Send(msg); err != nil {
+		b.logger.Error("Failed to send bar options", "error", err)
+	}
+}
+
+// handleBarSelection redeems the drink at the bar picked from sendBarOptions
+func (b *Bot) handleBarSelection(ctx context.Context, query *tgbotapi.CallbackQuery, email, ref, index string) {
+	bars := b.bars()
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || i >= len(bars) {
+		// The bar list changed since the buttons were sent
+		b.log(ctx).Warn("Unknown bar selected", "index", index)
+		b.sendBarOptions(query.Message.Chat.ID, query.From.ID, ref)
+		return
+	}
+	b.handleRedemption(ctx, query, email, bars[i])
+}
+
+// handleRedemption processes the cocktail redemption, served by bar if the
+// event has several
+func (b *Bot) handleRedemption(ctx context.Context, query *tgbotapi.CallbackQuery, email, bar string) {
+	// Buttons may be pressed long after they were sent, after a restart or
+	// a second time, so only an email that is still eligible is redeemed
+	var (
+		status string
+		user   *domain.User
+		err    error
+	)
+	b.withProgress(query.Message.Chat.ID, query.From.ID, "check", func() {
+		status, user, err = b.service.CheckEmailStatus(ctx, int64(query.From.ID), email)
+	})
+	if err == nil && status != "eligible" {
+		b.log(ctx).Info("Redeem button pressed for an email that is not eligible", "email", email, "status", status)
+		b.sendStatus(ctx, query.Message.Chat.ID, query.From.ID, status, user)
+		return
+	}
+
+	var redemptionTime time.Time
+	b.withProgress(query.Message.Chat.ID, query.From.ID, "redeem", func() {
+		redemptionTime, err = b.service.RedeemCocktailAt(ctx, int64(query.From.ID), email, bar)
+	})
 
 	if err != nil {
 		if err == domain.ErrDatabaseUnavailable {
-			b.sendTranslated(query.Message.Chat.ID, query.From.ID, "system_unavailable")
+			b.sendRetry(query.Message.Chat.ID, query.From.ID, "system_unavailable", b.service.UnavailableRetryAfter())
+		} else if err == domain.ErrEmailNotVerified {
+			b.sendTranslated(query.Message.Chat.ID, query.From.ID, "verification_required")
+		} else if err == domain.ErrEmailDenied {
+			b.sendTranslated(query.Message.Chat.ID, query.From.ID, "email_denied")
+		} else if err == domain.ErrEventArchived {
+			b.sendTranslated(query.Message.Chat.ID, query.From.ID, "event_archived")
+		} else if errors.Is(err, domain.ErrAlreadyRedeemed) {
+			// Another press redeemed the drink in the meantime and sent the success message
+			b.log(ctx).Info("Redemption already completed by another press", "email", email)
 		} else {
-			b.logger.Error("Error redeeming cocktail", "email", email, "error", err)
+			b.log(ctx).Error("Error redeeming cocktail", "email", email, "error", err)
 			b.sendTranslated(query.Message.Chat.ID, query.From.ID, "error_occurred")
 		}
 		return
@@ -127,42 +648,163 @@ func (b *Bot) handleRedemption(query *tgbotapi.CallbackQuery, email string) {
 	dateStr := redemptionTime.Format("January 2, 2006")
 	b.sendTranslated(query.Message.Chat.ID, query.From.ID, "redemption_success", "date", dateStr)
 
+	// Link the printable ticket for venues that need a paper trail
+	if ticketURL := b.service.TicketURL(email, redemptionTime); ticketURL != "" {
+		b.sendTranslated(query.Message.Chat.ID, query.From.ID, "ticket_ready", "url", ticketURL)
+	}
+
 	// Remove cached email
-	delete(b.emailCache, query.From.ID)
+	b.emailCache.delete(query.From.ID)
+
+	// Invalidate the buttons other chats got for the same email
+	b.invalidateEligible(ctx, email, query.From.ID, redemptionTime)
+
+	// Offer an extra drink
+	if b.service.PaymentsEnabled() {
+		b.purchasePending.set(query.From.ID, email)
+		b.sendUpgradeOffer(query.Message.Chat.ID, query.From.ID, email)
+	}
+
+	// Ask whether the guest wants to hear about future events
+	if b.config != nil && b.config.Telegram.AskMarketingConsent {
+		b.consentPending.set(query.From.ID, email)
+		b.sendConsentQuestion(query.Message.Chat.ID, query.From.ID, email)
+	}
+}
+
+// sendUpgradeOffer offers the guest to buy another drink
+func (b *Bot) sendUpgradeOffer(chatID int64, userID int64, email string) {
+	ref := b.callbackRef(userID, email)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.translate(userID, "button_buy"), callbackData("buy", ref)),
+		),
+	)
+
+	msg := b.newMessage(chatID, b.format(userID, "upgrade_offer"))
+	msg.ReplyMarkup = keyboard
+	if _, err := b.sender.Send(msg); err != nil {
+		b.logger.Error("Failed to send upgrade offer", "error", err)
+	}
+}
+
+// handleBuy creates a payment link for an extra drink
+func (b *Bot) handleBuy(ctx context.Context, query *tgbotapi.CallbackQuery, ref string) {
+	email, ok := b.callbackEmail(query.From.ID, ref, b.purchasePending)
+	if !ok {
+		b.sendTranslated(query.Message.Chat.ID, query.From.ID, "email_not_cached")
+		return
+	}
+
+	paymentURL, err := b.service.CreateCheckout(ctx, query.From.ID, email)
+	if err != nil {
+		b.log(ctx).Error("Error creating checkout", "email", email, "error", err)
+		b.sendTranslated(query.Message.Chat.ID, query.From.ID, "checkout_failed")
+		return
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonURL(b.translate(query.From.ID, "button_pay"), paymentURL),
+		),
+	)
+
+	msg := b.newMessage(query.Message.Chat.ID, b.format(query.From.ID, "checkout_ready"))
+	msg.ReplyMarkup = keyboard
+	if _, err := b.sender.Send(msg); err != nil {
+		b.log(ctx).Error("Failed to send payment link", "error", err)
+	}
+}
+
+// handleConsent records the guest's answer to the marketing opt-in question
+func (b *Bot) handleConsent(ctx context.Context, query *tgbotapi.CallbackQuery, consent bool, ref string) {
+	email, ok := b.callbackEmail(query.From.ID, ref, b.consentPending)
+	if !ok {
+		b.sendTranslated(query.Message.Chat.ID, query.From.ID, "email_not_cached")
+		return
+	}
+
+	if err := b.service.SetMarketingConsent(ctx, query.From.ID, email, consent); err != nil {
+		b.log(ctx).Error("Error recording marketing consent", "email", email, "error", err)
+		b.sendTranslated(query.Message.Chat.ID, query.From.ID, "error_occurred")
+		return
+	}
+
+	// Remove pending question
+	b.consentPending.delete(query.From.ID)
+
+	if consent {
+		b.sendTranslated(query.Message.Chat.ID, query.From.ID, "consent_thanks")
+	} else {
+		b.sendTranslated(query.Message.Chat.ID, query.From.ID, "consent_declined")
+	}
+}
+
+// sendConsentQuestion asks the guest to opt in to marketing messages
+func (b *Bot) sendConsentQuestion(chatID int64, userID int64, email string) {
+	yesText := b.translate(userID, "button_consent_yes")
+	noText := b.translate(userID, "button_consent_no")
+
+	ref := b.callbackRef(userID, email)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(yesText, callbackData("consent_yes", ref)),
+			tgbotapi.NewInlineKeyboardButtonData(noText, callbackData("consent_no", ref)),
+		),
+	)
+
+	msg := b.newMessage(chatID, b.format(userID, "consent_question"))
+	msg.ReplyMarkup = keyboard
+	if _, err := b.sender.Send(msg); err != nil {
+		b.logger.Error("Failed to send consent question", "error", err)
+	}
 }
 
 // handleSkip processes skipping the cocktail redemption
-func (b *Bot) handleSkip(query *tgbotapi.CallbackQuery) {
+func (b *Bot) handleSkip(ctx context.Context, query *tgbotapi.CallbackQuery, email string) {
 	b.sendTranslated(query.Message.Chat.ID, query.From.ID, "skip_redemption")
 
 	// Remove cached email
-	delete(b.emailCache, query.From.ID)
+	b.emailCache.delete(query.From.ID)
+	if err := b.callbacks.release(email, query.From.ID); err != nil {
+		b.log(ctx).Error("Failed to save callback state", "error", err)
+	}
 }
 
-// sendEligibleMessage sends a message with redemption buttons
-func (b *Bot) sendEligibleMessage(chatID int64, userID int64) {
+// sendEligibleMessage sends a message with redemption buttons for the
+// email, greeting the guest by name if it is given
+func (b *Bot) sendEligibleMessage(chatID int64, userID int64, email, name string) {
 	redeemText := b.translate(userID, "button_redeem")
 	skipText := b.translate(userID, "button_skip")
 
+	ref := b.callbackRef(userID, email)
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(redeemText, "redeem"),
-			tgbotapi.NewInlineKeyboardButtonData(skipText, "skip"),
+			tgbotapi.NewInlineKeyboardButtonData(redeemText, callbackData("redeem", ref)),
+			tgbotapi.NewInlineKeyboardButtonData(skipText, callbackData("skip", ref)),
 		),
 	)
 
-	text := b.translate(userID, "eligible")
-	msg := tgbotapi.NewMessage(chatID, text)
+	text := b.format(userID, "eligible")
+	if name != "" {
+		text = b.format(userID, "eligible_named", "name", name)
+	}
+	msg := b.newMessage(chatID, text)
 	msg.ReplyMarkup = keyboard
-	if _, err := b.api.Send(msg); err != nil {
+	sent, err := b.sender.Send(msg)
+	if err != nil {
 		b.logger.Error("Failed to send message with keyboard", "error", err)
+		return
 	}
+	b.service.TrackRedeemButton()
+
+	// Other users who checked the same email are told once it is redeemed
+	b.trackEligible(ref, chatID, sent.MessageID)
 }
 
 // sendHelpMessage sends help information
 func (b *Bot) sendHelpMessage(chatID int64, userID int64) {
-	helpText := b.translate(userID, "help_message")
-	b.sendMessage(chatID, helpText)
+	b.sendTranslated(chatID, userID, "help_message")
 }
 
 // sendLanguageOptions sends a message with language selection buttons
@@ -202,15 +844,15 @@ func (b *Bot) sendLanguageOptions(chatID int64) {
 	}
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
-	msg := tgbotapi.NewMessage(chatID, b.translate(0, "language_command"))
+	msg := b.newMessage(chatID, b.format(0, "language_command"))
 	msg.ReplyMarkup = keyboard
-	if _, err := b.api.Send(msg); err != nil {
+	if _, err := b.sender.Send(msg); err != nil {
 		b.logger.Error("Failed to send language options message", "error", err)
 	}
 }
 
 // handleLanguageSelection processes language selection from the user
-func (b *Bot) handleLanguageSelection(query *tgbotapi.CallbackQuery, lang string) {
+func (b *Bot) handleLanguageSelection(ctx context.Context, query *tgbotapi.CallbackQuery, lang string) {
 	// Check if language is supported
 	supported := false
 	for _, l := range b.translator.GetAvailableLanguages() {
@@ -234,11 +876,11 @@ func (b *Bot) handleLanguageSelection(query *tgbotapi.CallbackQuery, lang string
 	}
 
 	// Remove buttons from the original message
-	b.removeButtons(query.Message)
+	b.removeButtons(ctx, query.Message)
 }
 
 // removeButtons removes the inline keyboard from a message
-func (b *Bot) removeButtons(message *tgbotapi.Message) {
+func (b *Bot) removeButtons(ctx context.Context, message *tgbotapi.Message) {
 	edit := tgbotapi.NewEditMessageReplyMarkup(
 		message.Chat.ID,
 		message.MessageID,
@@ -246,7 +888,7 @@ func (b *Bot) removeButtons(message *tgbotapi.Message) {
 			InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
 		},
 	)
-	if _, err := b.api.Send(edit); err != nil {
-		b.logger.Error("Failed to remove buttons from message", "error", err)
+	if _, err := b.sender.Send(edit); err != nil {
+		b.log(ctx).Error("Failed to remove buttons from message", "error", err)
 	}
 }
@@ -0,0 +1,200 @@
+package telegram
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// errBotStopped is returned for messages still waiting when the bot stops
+var errBotStopped = errors.New("bot stopped before the message was sent")
+
+// maxChatBuckets is the number of chats tracked before idle ones are forgotten
+const maxChatBuckets = 10000
+
+// bucket is a token bucket refilled at rate tokens per second up to burst
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned since the last refill
+func (b *bucket) refill(now time.Time, rate, burst float64) {
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+}
+
+// wait returns how long until the bucket holds n tokens
+func (b *bucket) wait(n, rate float64) time.Duration {
+	if b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / rate * float64(time.Second))
+}
+
+// sendLimiter paces messages to stay within the limits of the Bot API:
+// about 30 messages per second across all chats and one per second to the
+// same chat. Interactive replies go first: background messages, such as
+// alerts to admins and updates of other chats, wait while replies are
+// waiting and leave a third of the global burst to them. Send blocks
+// until the message may go out. Other requests, such as answers to button
+// presses and typing indicators, are not paced.
+type sendLimiter struct {
+	BotAPI
+	globalRate float64 // Messages per second across all chats, 0 disables
+	chatRate   float64 // Messages per second to one chat, 0 disables
+	chatBurst  float64
+	reserve    float64 // Global tokens background messages leave to replies
+	stop       <-chan struct{}
+	now        func() time.Time
+
+	mu          sync.Mutex
+	global      bucket
+	chats       map[int64]*bucket
+	interactive int // Replies waiting for their turn
+}
+
+// newSendLimiter wraps api with the pacing configured in cfg. Messages
+// waiting when stop is closed fail with errBotStopped.
+func newSendLimiter(api BotAPI, cfg *config.Config, stop <-chan struct{}) *sendLimiter {
+	l := &sendLimiter{BotAPI: api, stop: stop, now: time.Now, chats: make(map[int64]*bucket)}
+	if cfg == nil {
+		return l
+	}
+	l.globalRate = float64(cfg.Telegram.SendPerSecond)
+	l.reserve = max(0, min(l.globalRate/3, l.globalRate-1))
+	if interval := cfg.Telegram.ChatSendInterval.Duration(); interval > 0 {
+		l.chatRate = float64(time.Second) / float64(interval)
+		l.chatBurst = float64(max(cfg.Telegram.ChatSendBurst, 1))
+	}
+	l.global = bucket{tokens: l.globalRate, last: l.now()}
+	return l
+}
+
+// Send sends an interactive reply once the limits allow it
+func (l *sendLimiter) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	if err := l.wait(chatOf(c), false); err != nil {
+		return tgbotapi.Message{}, err
+	}
+	return l.BotAPI.Send(c)
+}
+
+// SendBackground sends a message after the waiting interactive replies
+func (l *sendLimiter) SendBackground(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	if err := l.wait(chatOf(c), true); err != nil {
+		return tgbotapi.Message{}, err
+	}
+	return l.BotAPI.Send(c)
+}
+
+// wait blocks until a message to chatID may be sent, taking its tokens
+func (l *sendLimiter) wait(chatID int64, background bool) error {
+	if l.globalRate == 0 && l.chatRate == 0 {
+		return nil
+	}
+
+	if !background {
+		l.mu.Lock()
+		l.interactive++
+		l.mu.Unlock()
+		defer func() {
+			l.mu.Lock()
+			l.interactive--
+			l.mu.Unlock()
+		}()
+	}
+
+	for {
+		delay := l.take(chatID, background)
+		if delay == 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-l.stop:
+			timer.Stop()
+			return errBotStopped
+		case <-timer.C:
+		}
+	}
+}
+
+// take takes the tokens of a message to chatID and returns 0, or returns
+// how long to wait before trying again
+func (l *sendLimiter) take(chatID int64, background bool) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var delay time.Duration
+
+	// Background messages wait for the replies, and leave them a reserve
+	needed := 1.0
+	if l.globalRate > 0 {
+		l.global.refill(now, l.globalRate, l.globalRate)
+		if background {
+			needed += l.reserve
+			if l.interactive > 0 {
+				delay = time.Duration(float64(time.Second) / l.globalRate)
+			}
+		}
+		delay = max(delay, l.global.wait(needed, l.globalRate))
+	}
+
+	var chat *bucket
+	if l.chatRate > 0 && chatID != 0 {
+		chat = l.chats[chatID]
+		if chat == nil {
+			l.forgetIdleChats(now)
+			chat = &bucket{tokens: l.chatBurst, last: now}
+			l.chats[chatID] = chat
+		}
+		chat.refill(now, l.chatRate, l.chatBurst)
+		delay = max(delay, chat.wait(1, l.chatRate))
+	}
+
+	if delay > 0 {
+		return delay
+	}
+	if l.globalRate > 0 {
+		l.global.tokens--
+	}
+	if chat != nil {
+		chat.tokens--
+	}
+	return 0
+}
+
+// forgetIdleChats drops the buckets of chats that could send a full burst
+// again, once too many chats are tracked
+func (l *sendLimiter) forgetIdleChats(now time.Time) {
+	if len(l.chats) < maxChatBuckets {
+		return
+	}
+	for id, chat := range l.chats {
+		chat.refill(now, l.chatRate, l.chatBurst)
+		if chat.tokens >= l.chatBurst {
+			delete(l.chats, id)
+		}
+	}
+}
+
+// chatOf returns the chat a message goes to, 0 if it is not known
+func chatOf(c tgbotapi.Chattable) int64 {
+	switch v := c.(type) {
+	case tgbotapi.MessageConfig:
+		return v.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return v.ChatID
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return v.ChatID
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,93 @@
+package telegram
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+)
+
+// newTestLimiter returns a limiter on a fake clock, which the returned
+// function advances
+func newTestLimiter(perSecond int, interval time.Duration, burst int, stop chan struct{}) (*sendLimiter, func(time.Duration)) {
+	cfg := config.New()
+	cfg.Telegram.SendPerSecond = perSecond
+	cfg.Telegram.ChatSendInterval = config.Duration(interval)
+	cfg.Telegram.ChatSendBurst = burst
+
+	now := time.Date(2025, 6, 14, 21, 0, 0, 0, time.UTC)
+	l := newSendLimiter(nil, cfg, stop)
+	l.now = func() time.Time { return now }
+	l.global.last = now
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestSendLimiter_Chat(t *testing.T) {
+	l, advance := newTestLimiter(0, time.Second, 2, nil)
+
+	// A burst goes out right away, then one message per interval
+	for i := 0; i < 2; i++ {
+		if delay := l.take(42, false); delay != 0 {
+			t.Fatalf("Expected message %d of the burst to go out, got a delay of %v", i+1, delay)
+		}
+	}
+	if delay := l.take(42, false); delay != time.Second {
+		t.Errorf("Expected to wait a second, got %v", delay)
+	}
+	if delay := l.take(43, false); delay != 0 {
+		t.Errorf("Expected other chats not to wait, got %v", delay)
+	}
+	advance(time.Second)
+	if delay := l.take(42, false); delay != 0 {
+		t.Errorf("Expected the next message after a second, got %v", delay)
+	}
+}
+
+func TestSendLimiter_Priority(t *testing.T) {
+	l, advance := newTestLimiter(3, 0, 0, nil)
+
+	// Background messages leave a third of the burst to replies
+	if delay := l.take(1, true); delay != 0 {
+		t.Fatalf("Expected a background message to go out, got %v", delay)
+	}
+	if delay := l.take(2, true); delay != 0 {
+		t.Fatalf("Expected a second background message to go out, got %v", delay)
+	}
+	if delay := l.take(3, true); delay == 0 {
+		t.Error("Expected the reserve to be kept for replies")
+	}
+	if delay := l.take(4, false); delay != 0 {
+		t.Errorf("Expected a reply to use the reserve, got %v", delay)
+	}
+	if delay := l.take(5, false); delay == 0 {
+		t.Error("Expected replies to wait once the burst is used")
+	}
+
+	// Waiting replies go before background messages
+	advance(10 * time.Second)
+	l.interactive = 1
+	if delay := l.take(6, true); delay == 0 {
+		t.Error("Expected a background message to wait for replies")
+	}
+	l.interactive = 0
+	if delay := l.take(6, true); delay != 0 {
+		t.Errorf("Expected the background message to go out, got %v", delay)
+	}
+}
+
+func TestSendLimiter_Stop(t *testing.T) {
+	stop := make(chan struct{})
+	l, _ := newTestLimiter(0, time.Hour, 1, stop)
+
+	if err := l.wait(42, false); err != nil {
+		t.Fatalf("Expected the first message to go out, got %v", err)
+	}
+	close(stop)
+	if err := l.wait(42, false); !errors.Is(err, errBotStopped) {
+		t.Errorf("Expected waiting messages to fail when the bot stops, got %v", err)
+	}
+	if l.interactive != 0 {
+		t.Errorf("Expected no waiting replies, got %d", l.interactive)
+	}
+}
@@ -0,0 +1,79 @@
+package telegram
+
+import (
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// typingRefresh is how often the typing indicator is resent. Telegram
+// clears it after about five seconds.
+const typingRefresh = 4 * time.Second
+
+// progressDelays returns when to show the typing indicator and when to send
+// the "still checking" message. Zero disables the respective feedback.
+func (b *Bot) progressDelays() (typing, slow time.Duration) {
+	if b.config == nil {
+		return 0, 0
+	}
+	return time.Duration(b.config.Telegram.TypingDelayMs) * time.Millisecond,
+		time.Duration(b.config.Telegram.SlowLookupMs) * time.Millisecond
+}
+
+// withProgress runs a potentially slow service call and keeps the user
+// informed while it runs. It returns how long the call took.
+func (b *Bot) withProgress(chatID, userID int64, op string, call func()) time.Duration {
+	start := time.Now()
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+		b.showProgress(chatID, userID, done)
+	}()
+
+	call()
+	close(done)
+	<-finished
+
+	elapsed := time.Since(start)
+	if _, slow := b.progressDelays(); slow > 0 && elapsed >= slow {
+		b.logger.Warn("Slow lookup", "op", op, "duration_ms", elapsed.Milliseconds(), "user_id", userID)
+	} else {
+		b.logger.Debug("Lookup finished", "op", op, "duration_ms", elapsed.Milliseconds(), "user_id", userID)
+	}
+
+	return elapsed
+}
+
+// showProgress sends a typing indicator and an interim message until done is closed
+func (b *Bot) showProgress(chatID, userID int64, done <-chan struct{}) {
+	typingDelay, slowDelay := b.progressDelays()
+
+	var typing, slow <-chan time.Time
+	if typingDelay > 0 {
+		timer := time.NewTimer(typingDelay)
+		defer timer.Stop()
+		typing = timer.C
+	}
+	if slowDelay > 0 {
+		timer := time.NewTimer(slowDelay)
+		defer timer.Stop()
+		slow = timer.C
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-typing:
+			if _, err := b.api.Request(tgbotapi.NewChatAction(chatID, tgbotapi.ChatTyping)); err != nil {
+				b.logger.Debug("Failed to send typing indicator", "chat_id", chatID, "error", err)
+			}
+			typing = time.After(typingRefresh)
+		case <-slow:
+			b.sendTranslated(chatID, userID, "still_checking")
+			slow = nil
+		}
+	}
+}
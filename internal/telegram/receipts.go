@@ -0,0 +1,51 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ceesaxp/cocktail-bot/internal/events"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// receiptTimeLayout is the format of redemption times in receipts
+const receiptTimeLayout = "2006-01-02 15:04:05 MST"
+
+// postReceipt posts a short record of a redemption to the receipts
+// channel, whichever channel the redemption came through. Organizers get
+// a live ledger that only the bot writes to. Emails are always masked,
+// since the channel may be shared with people who are not staff.
+func (b *Bot) postReceipt(ctx context.Context, event events.Event) {
+	if b.config == nil || b.config.Telegram.ReceiptsChannel == 0 {
+		return
+	}
+
+	source := receiptSource(event)
+	key, args := "receipt", []any{
+		"email", utils.MaskEmail(event.Email),
+		"time", event.Time.Format(receiptTimeLayout),
+		"source", source,
+	}
+	if event.Bar != "" {
+		key, args = "receipt_bar", append(args, "bar", event.Bar)
+	}
+
+	channelID := b.config.Telegram.ReceiptsChannel
+	if _, err := b.sender.SendBackground(b.newMessage(channelID, b.format(0, key, args...))); err != nil {
+		b.log(ctx).Error("Error posting redemption receipt", "channel_id", channelID, "error", err)
+	}
+}
+
+// receiptSource names who redeemed: the actor of the audit log, such as
+// telegram:<user_id> or token:<fingerprint>, and the staff member who
+// collected an offline redemption
+func receiptSource(event events.Event) string {
+	source := event.Actor
+	if source == "" {
+		source = "unknown"
+	}
+	if operator := strings.TrimSpace(event.Operator); operator != "" {
+		source = operator + " (" + source + ")"
+	}
+	return source
+}
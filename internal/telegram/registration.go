@@ -0,0 +1,154 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sendRegistrationOffer tells the guest the email was not found and offers
+// to ask the admins for access
+func (b *Bot) sendRegistrationOffer(chatID int64, userID int64, email string) {
+	ref := b.callbackRef(userID, email)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.translate(userID, "button_register"), callbackData("register", ref)),
+		),
+	)
+
+	msg := b.newMessage(chatID, b.format(userID, "registration_offer"))
+	msg.ReplyMarkup = keyboard
+	if _, err := b.sender.Send(msg); err != nil {
+		b.logger.Error("Failed to send registration offer", "error", err)
+	}
+}
+
+// handleRegistration asks the admins to give the guest access
+func (b *Bot) handleRegistration(ctx context.Context, query *tgbotapi.CallbackQuery, email string) {
+	chatID, userID := query.Message.Chat.ID, query.From.ID
+	reg, created, err := b.service.RequestRegistration(ctx, userID, chatID, email, b.getUserLanguage(userID))
+	switch {
+	case errors.Is(err, domain.ErrUserExists):
+		// Added in the meantime, so the guest can redeem right away
+		b.checkEmail(ctx, &tgbotapi.Message{Chat: query.Message.Chat, From: query.From}, email)
+		return
+	case errors.Is(err, domain.ErrRateLimitExceeded):
+		b.sendRetry(chatID, userID, "rate_limited", b.service.RetryAfter(ctx, userID))
+		return
+	case errors.Is(err, domain.ErrEmailDenied):
+		b.sendTranslated(chatID, userID, "email_denied")
+		return
+	case errors.Is(err, domain.ErrEventArchived):
+		b.sendTranslated(chatID, userID, "event_archived")
+		return
+	case err != nil:
+		b.log(ctx).Error("Error requesting registration", "email", email, "error", err)
+		b.sendTranslated(chatID, userID, "error_occurred")
+		return
+	}
+
+	switch {
+	case created:
+		b.sendTranslated(chatID, userID, "registration_requested", "email", email)
+		b.notifyRegistration(ctx, reg)
+	case reg.Status == domain.RegistrationRejected:
+		b.sendTranslated(chatID, userID, "registration_rejected", "email", email)
+	default:
+		b.sendTranslated(chatID, userID, "registration_pending", "email", email)
+	}
+}
+
+// notifyRegistration sends a new access request to all admins with
+// buttons to approve or reject it
+func (b *Bot) notifyRegistration(ctx context.Context, reg domain.Registration) {
+	if b.config == nil {
+		return
+	}
+
+	email := reg.Email
+	if b.config.Privacy.MaskEmails {
+		email = utils.MaskEmail(email)
+	}
+	for _, adminID := range b.config.Telegram.AdminUsers {
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(b.translate(adminID, "button_approve"), callbackData("reg_approve", reg.ID)),
+				tgbotapi.NewInlineKeyboardButtonData(b.translate(adminID, "button_reject"), callbackData("reg_reject", reg.ID)),
+			),
+		)
+
+		msg := b.newMessage(adminID, b.format(adminID, "registration_request",
+			"id", reg.ID,
+			"email", email,
+			"user_id", strconv.FormatInt(reg.UserID, 10),
+		))
+		msg.ReplyMarkup = keyboard
+		if _, err := b.sender.SendBackground(msg); err != nil {
+			b.log(ctx).Error("Error sending registration request to admin", "admin_id", adminID, "error", err)
+		}
+	}
+}
+
+// handleRegistrationDecision approves or rejects the access request with
+// the given ID and tells the guest
+func (b *Bot) handleRegistrationDecision(ctx context.Context, query *tgbotapi.CallbackQuery, approve bool, id string) {
+	chatID, adminID := query.Message.Chat.ID, query.From.ID
+	if !b.isAdmin(adminID) {
+		b.log(ctx).Warn("Non-admin attempted to decide a registration", "id", id)
+		b.sendTranslated(chatID, adminID, "admin_only")
+		return
+	}
+
+	actor := "telegram:" + strconv.FormatInt(adminID, 10)
+	var (
+		reg domain.Registration
+		err error
+	)
+	if approve {
+		reg, err = b.service.ApproveRegistration(ctx, id, actor)
+	} else {
+		reg, err = b.service.RejectRegistration(ctx, id, actor)
+	}
+	switch {
+	case errors.Is(err, domain.ErrRegistrationNotFound), errors.Is(err, domain.ErrRegistrationDisabled):
+		b.sendTranslated(chatID, adminID, "registration_not_found", "id", id)
+		return
+	case errors.Is(err, domain.ErrRegistrationDecided):
+		b.sendTranslated(chatID, adminID, "registration_decided", "id", id, "status", string(reg.Status))
+		return
+	case err != nil:
+		b.log(ctx).Error("Error deciding registration", "id", id, "approve", approve, "error", err)
+		b.sendTranslated(chatID, adminID, "registration_error", "id", id, "error", err.Error())
+		return
+	}
+
+	email := reg.Email
+	if b.config.Privacy.MaskEmails {
+		email = utils.MaskEmail(email)
+	}
+	b.log(ctx).Info("Audit: registration decided", "actor", actor, "id", id, "approved", approve)
+
+	// The guest may not have written since a restart, so their language
+	// is taken from the request
+	if _, ok := b.userLangs.get(reg.UserID); !ok && reg.Language != "" {
+		b.setUserLanguage(reg.UserID, reg.Language)
+	}
+	if !approve {
+		b.sendTranslated(chatID, adminID, "registration_rejected_admin", "id", id, "email", email)
+		b.sendTranslated(reg.ChatID, reg.UserID, "registration_rejected", "email", reg.Email)
+		return
+	}
+
+	b.sendTranslated(chatID, adminID, "registration_approved_admin", "id", id, "email", email)
+	b.sendTranslated(reg.ChatID, reg.UserID, "registration_approved", "email", reg.Email)
+	if b.service.VerificationRequired() {
+		b.sendTranslated(reg.ChatID, reg.UserID, "verification_required")
+		return
+	}
+	b.emailCache.set(reg.UserID, reg.Email)
+	b.sendEligibleMessage(reg.ChatID, reg.UserID, reg.Email, "")
+}
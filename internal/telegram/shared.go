@@ -0,0 +1,80 @@
+package telegram
+
+import (
+	"context"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/ceesaxp/cocktail-bot/internal/events"
+)
+
+// trackEligible records the message with the redeem buttons behind ref, so
+// it can be updated once the email is redeemed elsewhere: by another user
+// who checked the same email, as couples sometimes do, or through the API
+// or the WebUI
+func (b *Bot) trackEligible(ref string, chatID int64, messageID int) {
+	if ref == "" {
+		return
+	}
+	if err := b.callbacks.attach(ref, chatID, messageID); err != nil {
+		// The reference is still kept in memory, it is only lost on restart
+		b.logger.Error("Failed to save callback state", "error", err)
+	}
+}
+
+// invalidateEligible tells the users who got redeem buttons for an email
+// that was just redeemed, other than userID who redeemed it, and removes
+// their buttons. The redeeming user's own buttons are left to the caller;
+// pressing an older one only repeats that the email was redeemed.
+func (b *Bot) invalidateEligible(ctx context.Context, email string, userID int64, redeemed time.Time) {
+	messages, err := b.callbacks.takeMessages(email)
+	if err != nil {
+		b.log(ctx).Error("Failed to save callback state", "error", err)
+	}
+
+	for _, m := range messages {
+		if m.UserID == userID {
+			continue
+		}
+
+		edit := tgbotapi.NewEditMessageText(m.ChatID, m.MessageID, b.format(m.UserID, "redeemed_elsewhere", "date", redeemed.Format("January 2, 2006")))
+		edit.ParseMode = string(b.parseMode)
+		edit.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+		if _, err := b.sender.SendBackground(edit); err != nil {
+			b.log(ctx).Error("Failed to update message of a redeemed email", "other_chat_id", m.ChatID, "error", err)
+			continue
+		}
+		b.log(ctx).Info("Invalidated redeem buttons of another user", "email", email, "other_user_id", m.UserID)
+	}
+}
+
+// watchEvents invalidates redeem buttons and posts receipts when emails
+// are redeemed through any channel, until the bot stops
+func (b *Bot) watchEvents(ch <-chan events.Event) {
+	for {
+		select {
+		case <-b.stopCh:
+			return
+		case event := <-ch:
+			b.handleEvent(event)
+		}
+	}
+}
+
+// handleEvent acts on an event published by the service
+func (b *Bot) handleEvent(event events.Event) {
+	if event.Type == events.FeatureToggled {
+		// The menu follows the current flags
+		if err := b.RegisterCommands(); err != nil {
+			b.logger.Warn("Keeping the previous command menu", "error", err)
+		}
+		return
+	}
+	if event.Type != events.Redeemed {
+		return
+	}
+	ctx := b.chatContext(0, event.UserID, "event", string(event.Type), "actor", event.Actor)
+	b.invalidateEligible(ctx, event.Email, event.UserID, event.Time)
+	b.postReceipt(ctx, event)
+}
@@ -0,0 +1,184 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/kvstore"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// Keys of the bot state in the state store
+const (
+	offsetKey    = "telegram:offset"
+	callbacksKey = "telegram:callbacks"
+)
+
+// openStateStore opens the configured state store. It returns nil for the
+// memory store, and when the store cannot be opened, so state is then only
+// kept in memory.
+func openStateStore(cfg *config.Config, l *logger.Logger) kvstore.Store {
+	if cfg == nil {
+		return nil
+	}
+	switch strings.ToLower(cfg.Telegram.State.Store) {
+	case "", "memory":
+		return nil
+	}
+	store, err := kvstore.New(cfg.Telegram.State, l)
+	if err != nil {
+		l.Error("Keeping bot state in memory, it is lost on restart", "store", cfg.Telegram.State.Store, "error", err)
+		return nil
+	}
+	return store
+}
+
+// updateOffset returns the ID of the first Telegram update not handled yet,
+// 0 if unknown
+func (b *Bot) updateOffset() int {
+	if b.state == nil {
+		return 0
+	}
+	data, err := b.state.Get(context.Background(), offsetKey)
+	if err != nil {
+		if !errors.Is(err, kvstore.ErrNotFound) {
+			b.logger.Error("Failed to read update offset", "error", err)
+		}
+		return 0
+	}
+	offset, _ := strconv.Atoi(string(data))
+	return offset
+}
+
+// saveOffset records that the updates before offset were handled
+func (b *Bot) saveOffset(offset int) {
+	if b.state == nil {
+		return
+	}
+	if err := b.state.Set(context.Background(), offsetKey, []byte(strconv.Itoa(offset))); err != nil {
+		b.logger.Error("Failed to save update offset", "offset", offset, "error", err)
+	}
+}
+
+// Offsets are saved every offsetSaveUpdates updates and otherwise at most
+// every offsetSaveInterval, rather than for every update: the file store
+// rewrites the whole file on each write. A crash may handle the
+// updates of the last interval again.
+const (
+	offsetSaveUpdates  = 100
+	offsetSaveInterval = time.Second
+)
+
+// offsetBatch holds the offset of the last updates received until it is
+// saved. It is only used by the goroutine receiving updates.
+type offsetBatch struct {
+	bot     *Bot
+	offset  int
+	pending int // Updates received since the offset was last saved
+}
+
+// newOffsetBatch creates an empty batch saving through the bot
+func newOffsetBatch(b *Bot) *offsetBatch {
+	return &offsetBatch{bot: b}
+}
+
+// add records that the updates before offset were received, saving them
+// once the batch is full
+func (o *offsetBatch) add(offset int) {
+	o.offset = offset
+	o.pending++
+	if o.pending >= offsetSaveUpdates {
+		o.flush()
+	}
+}
+
+// flush saves the offset if updates were received since the last save
+func (o *offsetBatch) flush() {
+	if o.pending == 0 {
+		return
+	}
+	o.bot.saveOffset(o.offset)
+	o.pending = 0
+}
+
+// userState is one kind of state kept per Telegram user, such as the
+// language they chose. Values are cached in memory and, with a state
+// store, saved so they survive restarts.
+type userState struct {
+	kind   string        // Part of the key, such as lang
+	store  kvstore.Store // nil keeps values in memory only
+	logger *logger.Logger
+
+	mu     sync.Mutex
+	values map[int64]string
+}
+
+// newUserState creates the state of one kind, kept in store if not nil
+func newUserState(kind string, store kvstore.Store, logger *logger.Logger) *userState {
+	return &userState{kind: kind, store: store, logger: logger, values: make(map[int64]string)}
+}
+
+// key returns the store key of a user's value
+func (s *userState) key(userID int64) string {
+	return "telegram:" + s.kind + ":" + strconv.FormatInt(userID, 10)
+}
+
+// get returns the value of a user, loading it from the store after a restart
+func (s *userState) get(userID int64) (string, bool) {
+	s.mu.Lock()
+	value, ok := s.values[userID]
+	s.mu.Unlock()
+	if ok || s.store == nil {
+		return value, ok
+	}
+
+	data, err := s.store.Get(context.Background(), s.key(userID))
+	if err != nil {
+		if !errors.Is(err, kvstore.ErrNotFound) {
+			s.logger.Error("Failed to read bot state", "kind", s.kind, "user_id", userID, "error", err)
+		}
+		return "", false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value, ok := s.values[userID]; ok {
+		return value, true // Set in the meantime
+	}
+	s.values[userID] = string(data)
+	return string(data), true
+}
+
+// set stores the value of a user. Failing to save it is logged, the value
+// is still kept in memory.
+func (s *userState) set(userID int64, value string) {
+	s.mu.Lock()
+	s.values[userID] = value
+	s.mu.Unlock()
+
+	if s.store == nil {
+		return
+	}
+	if err := s.store.Set(context.Background(), s.key(userID), []byte(value)); err != nil {
+		s.logger.Error("Failed to save bot state", "kind", s.kind, "user_id", userID, "error", err)
+	}
+}
+
+// delete forgets the value of a user
+func (s *userState) delete(userID int64) {
+	s.mu.Lock()
+	delete(s.values, userID)
+	s.mu.Unlock()
+
+	if s.store == nil {
+		return
+	}
+	if err := s.store.Delete(context.Background(), s.key(userID)); err != nil {
+		s.logger.Error("Failed to save bot state", "kind", s.kind, "user_id", userID, "error", err)
+	}
+}
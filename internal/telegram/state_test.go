@@ -0,0 +1,65 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ceesaxp/cocktail-bot/internal/kvstore"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// countingStore keeps values in a map and counts the writes
+type countingStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	sets   int
+}
+
+func (s *countingStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	if !ok {
+		return nil, kvstore.ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *countingStore) Set(ctx context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.sets++
+	return nil
+}
+
+func (s *countingStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+func (s *countingStore) Close() error { return nil }
+
+func TestOffsetSavedInBatches(t *testing.T) {
+	store := &countingStore{values: make(map[string][]byte)}
+	b := &Bot{state: store, logger: logger.New("error"), stopCh: make(chan struct{})}
+
+	updates := make(chan tgbotapi.Update, 250)
+	for id := 1; id <= 250; id++ {
+		updates <- tgbotapi.Update{UpdateID: id}
+	}
+	close(updates)
+	b.processUpdates(updates)
+
+	// Two full batches, the rest when updates stop, and perhaps a tick
+	if store.sets < 3 || store.sets > 5 {
+		t.Errorf("Expected the offset to be saved in batches, got %d writes", store.sets)
+	}
+	if offset := b.updateOffset(); offset != 251 {
+		t.Errorf("Expected offset 251 after stopping, got %d", offset)
+	}
+}
@@ -2,9 +2,13 @@ package utils
 
 import (
 	"net/mail"
+	"regexp"
 	"strings"
 )
 
+// emailPattern matches email addresses within text
+var emailPattern = regexp.MustCompile(`[\p{L}\p{N}._%+\-]+@[\p{L}\p{N}\-]+(?:\.[\p{L}\p{N}\-]+)*\.\p{L}{2,}`)
+
 // IsValidEmail checks if a string is a valid email address
 func IsValidEmail(email string) bool {
 	// Check using standard library first
@@ -42,3 +46,30 @@ func NormalizeEmail(email string) string {
 	// Trim spaces and convert to lowercase
 	return strings.ToLower(strings.TrimSpace(email))
 }
+
+// MaskEmail hides most of the local part of an email address, keeping its
+// first and last character and the domain, e.g. j***h@gmail.com. An empty
+// string stays empty.
+func MaskEmail(email string) string {
+	if email == "" {
+		return ""
+	}
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return "***"
+	}
+	runes := []rune(local)
+	if len(runes) <= 2 {
+		return string(runes[:min(len(runes), 1)]) + "***@" + domain
+	}
+	return string(runes[0]) + "***" + string(runes[len(runes)-1]) + "@" + domain
+}
+
+// MaskEmails masks every email address found in text, such as in an error
+// message, with MaskEmail
+func MaskEmails(text string) string {
+	if !strings.Contains(text, "@") {
+		return text
+	}
+	return emailPattern.ReplaceAllStringFunc(text, MaskEmail)
+}
@@ -0,0 +1,24 @@
+package utils
+
+import "strings"
+
+// Slug turns a name, such as the event name, into lowercase letters,
+// digits and dashes for use in paths and file names. Empty names become
+// "event".
+func Slug(name string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		default:
+			return '-'
+		}
+	}, strings.TrimSpace(name))
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		return "event"
+	}
+	return slug
+}
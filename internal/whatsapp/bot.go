@@ -0,0 +1,420 @@
+// Package whatsapp serves the guest flows over the WhatsApp Business Cloud API.
+// Messages arrive through a webhook and replies are sent with the Graph API.
+package whatsapp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/httpclient"
+	"github.com/ceesaxp/cocktail-bot/internal/i18n"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/ports"
+	"github.com/ceesaxp/cocktail-bot/internal/service"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+)
+
+// maxWebhookBody limits the size of webhook payloads
+const maxWebhookBody = 1 << 20
+
+// ServiceInterface defines the methods the WhatsApp bot uses from the service
+type ServiceInterface interface {
+	ports.Guests
+	ports.Verification
+}
+
+// Bot answers guests on WhatsApp
+type Bot struct {
+	sender     Sender
+	service    ServiceInterface
+	config     *config.Config
+	logger     *logger.Logger
+	translator *i18n.Translator
+	server     *http.Server
+
+	mu         sync.Mutex
+	emailCache map[string]string // Map of phone number -> email awaiting redemption
+}
+
+// New creates a WhatsApp bot sending replies through the sender
+func New(sender Sender, svc ServiceInterface, cfg *config.Config, logger *logger.Logger) *Bot {
+	translator := i18n.NewWithConfig(cfg)
+	i18n.LoadDefaultTranslations(translator)
+
+	return &Bot{
+		sender:     sender,
+		service:    svc,
+		config:     cfg,
+		logger:     logger,
+		translator: translator,
+		emailCache: make(map[string]string),
+	}
+}
+
+// NewFromConfig creates a WhatsApp bot using the Cloud API settings
+func NewFromConfig(cfg *config.Config, svc *service.Service, logger *logger.Logger) (*Bot, error) {
+	client, err := NewClient(cfg.WhatsApp.BaseURL, cfg.WhatsApp.AccessToken, cfg.WhatsApp.PhoneNumberID, httpclient.New(httpclient.FromConfig(cfg, "whatsapp")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WhatsApp client: %w", err)
+	}
+	if cfg.WhatsApp.VerifyToken == "" {
+		return nil, errors.New("whatsapp verify token cannot be empty")
+	}
+	if cfg.WhatsApp.AppSecret == "" {
+		return nil, errors.New("whatsapp app secret cannot be empty")
+	}
+
+	return New(client, svc, cfg, logger), nil
+}
+
+// Start starts the webhook server receiving messages
+func (b *Bot) Start() error {
+	if b.server != nil {
+		return fmt.Errorf("bot is already running")
+	}
+
+	addr := fmt.Sprintf("%s:%d", b.config.WhatsApp.Host, b.config.WhatsApp.Port)
+	b.server = &http.Server{
+		Addr:         addr,
+		Handler:      b,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	go func() {
+		if err := b.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			b.logger.Error("WhatsApp webhook server error", "error", err)
+		}
+	}()
+
+	b.logger.Info("WhatsApp bot started", "addr", addr, "phone_number_id", b.config.WhatsApp.PhoneNumberID)
+	return nil
+}
+
+// Stop stops the webhook server and waits for running handlers
+func (b *Bot) Stop() {
+	if b.server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.Timeouts.Shutdown.Duration())
+	defer cancel()
+	if err := b.server.Shutdown(ctx); err != nil {
+		b.logger.Error("Error stopping WhatsApp webhook server", "error", err)
+	}
+	b.server = nil
+
+	b.logger.Info("Bot stopped")
+}
+
+// webhookPayload is the part of a Cloud API webhook call the bot reads
+type webhookPayload struct {
+	Entry []struct {
+		Changes []struct {
+			Value struct {
+				Messages []incomingMessage `json:"messages"`
+			} `json:"value"`
+		} `json:"changes"`
+	} `json:"entry"`
+}
+
+// incomingMessage is a message sent by a guest
+type incomingMessage struct {
+	From string `json:"from"`
+	Type string `json:"type"`
+	Text struct {
+		Body string `json:"body"`
+	} `json:"text"`
+	Interactive struct {
+		ButtonReply struct {
+			ID string `json:"id"`
+		} `json:"button_reply"`
+	} `json:"interactive"`
+}
+
+// ServeHTTP answers the webhook verification and handles incoming messages
+func (b *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		b.handleVerify(w, r)
+	case http.MethodPost:
+		b.handleWebhook(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleVerify echoes the challenge when Meta subscribes the webhook
+func (b *Bot) handleVerify(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("hub.mode") != "subscribe" || query.Get("hub.verify_token") != b.config.WhatsApp.VerifyToken {
+		b.logger.Warn("Rejected WhatsApp webhook verification")
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	w.Write([]byte(query.Get("hub.challenge")))
+}
+
+// handleWebhook checks the signature and handles every message in the payload
+func (b *Bot) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	// Without an app secret no call can be verified, so none is accepted
+	if secret := b.config.WhatsApp.AppSecret; secret == "" || !validSignature(body, r.Header.Get("X-Hub-Signature-256"), secret) {
+		b.logger.Warn("Rejected WhatsApp webhook with invalid signature")
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	for _, entry := range payload.Entry {
+		for _, change := range entry.Changes {
+			for _, message := range change.Value.Messages {
+				b.handleMessage(r.Context(), message)
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// validSignature checks the HMAC-SHA256 of the payload sent in X-Hub-Signature-256
+func validSignature(payload []byte, header, secret string) bool {
+	signature, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// handleMessage handles a text message or a button reply from a guest
+func (b *Bot) handleMessage(ctx context.Context, message incomingMessage) {
+	// WhatsApp identifies users by phone number, which the service uses as user ID
+	userID, err := strconv.ParseInt(message.From, 10, 64)
+	if err != nil {
+		b.logger.Warn("Ignoring WhatsApp message with invalid sender", "from", message.From)
+		return
+	}
+	if b.service.IsUserBlocked(userID) {
+		b.logger.Debug("Ignoring blocked user", "user_id", userID)
+		return
+	}
+
+	switch message.Type {
+	case "interactive":
+		b.service.TrackInteraction(b.language(), "")
+		b.handleButton(ctx, message.From, userID, message.Interactive.ButtonReply.ID)
+	case "text":
+		b.service.TrackInteraction(b.language(), "")
+		b.handleText(ctx, message.From, userID, strings.TrimSpace(message.Text.Body))
+	default:
+		b.send(ctx, message.From, "invalid_email")
+	}
+}
+
+// handleText checks an email or a verification code
+func (b *Bot) handleText(ctx context.Context, to string, userID int64, text string) {
+	if utils.IsValidEmail(text) {
+		b.checkEmail(ctx, to, userID, utils.NormalizeEmail(text))
+		return
+	}
+
+	if b.service.VerificationRequired() && isVerificationCode(text) {
+		b.handleVerificationCode(ctx, to, userID, text)
+		return
+	}
+
+	b.send(ctx, to, "invalid_email")
+}
+
+// checkEmail looks up the email and replies with its status
+func (b *Bot) checkEmail(ctx context.Context, to string, userID int64, email string) {
+	b.mu.Lock()
+	b.emailCache[to] = email
+	b.mu.Unlock()
+
+	status, user, err := b.service.CheckEmailStatus(ctx, userID, email)
+	if err != nil {
+		b.logger.Error("Error checking email status", "email", email, "error", err)
+		b.send(ctx, to, "error_occurred")
+		return
+	}
+
+	switch status {
+	case "rate_limited":
+		b.sendRetry(ctx, to, "rate_limited", b.service.RetryAfter(ctx, userID))
+	case "not_found":
+		b.send(ctx, to, "email_not_found")
+	case "unavailable":
+		b.sendRetry(ctx, to, "system_unavailable", b.service.UnavailableRetryAfter())
+	case "denied":
+		b.send(ctx, to, "email_denied")
+	case "archived":
+		b.send(ctx, to, "event_archived")
+	case "redeemed":
+		b.send(ctx, to, "already_redeemed", "date", user.Redeemed.Format("January 2, 2006"))
+	case "eligible":
+		if b.service.VerificationRequired() {
+			if err := b.service.SendVerificationCode(ctx, userID, email); err != nil {
+				b.logger.Error("Error sending verification code", "email", email, "error", err)
+				b.send(ctx, to, "error_occurred")
+				return
+			}
+			b.send(ctx, to, "verification_sent", "email", email)
+			return
+		}
+		b.sendEligibleMessage(ctx, to)
+	default:
+		b.send(ctx, to, "error_occurred")
+	}
+}
+
+// handleVerificationCode checks a verification code entered by the guest
+func (b *Bot) handleVerificationCode(ctx context.Context, to string, userID int64, code string) {
+	email, err := b.service.VerifyEmailCode(ctx, userID, code)
+
+	switch err {
+	case nil:
+		b.mu.Lock()
+		b.emailCache[to] = email
+		b.mu.Unlock()
+		b.sendEligibleMessage(ctx, to)
+	case domain.ErrInvalidVerificationCode:
+		b.send(ctx, to, "verification_invalid")
+	case domain.ErrVerificationExpired:
+		b.send(ctx, to, "verification_expired")
+	case domain.ErrTooManyAttempts:
+		b.send(ctx, to, "verification_too_many")
+	case domain.ErrNoVerificationPending:
+		b.send(ctx, to, "invalid_email")
+	default:
+		b.logger.Error("Error verifying code", "error", err)
+		b.send(ctx, to, "error_occurred")
+	}
+}
+
+// handleButton handles the redeem and skip buttons
+func (b *Bot) handleButton(ctx context.Context, to string, userID int64, id string) {
+	b.mu.Lock()
+	email, ok := b.emailCache[to]
+	delete(b.emailCache, to)
+	b.mu.Unlock()
+
+	if !ok {
+		b.send(ctx, to, "email_not_cached")
+		return
+	}
+
+	switch id {
+	case "redeem":
+		b.handleRedemption(ctx, to, userID, email)
+	case "skip":
+		b.send(ctx, to, "skip_redemption")
+	default:
+		b.send(ctx, to, "error_occurred")
+	}
+}
+
+// handleRedemption processes the cocktail redemption
+func (b *Bot) handleRedemption(ctx context.Context, to string, userID int64, email string) {
+	redemptionTime, err := b.service.RedeemCocktail(ctx, userID, email)
+	if err != nil {
+		if err == domain.ErrDatabaseUnavailable {
+			b.sendRetry(ctx, to, "system_unavailable", b.service.UnavailableRetryAfter())
+		} else if err == domain.ErrEmailNotVerified {
+			b.send(ctx, to, "verification_required")
+		} else if err == domain.ErrEmailDenied {
+			b.send(ctx, to, "email_denied")
+		} else if err == domain.ErrEventArchived {
+			b.send(ctx, to, "event_archived")
+		} else {
+			b.logger.Error("Error redeeming cocktail", "email", email, "error", err)
+			b.send(ctx, to, "error_occurred")
+		}
+		return
+	}
+
+	b.send(ctx, to, "redemption_success", "date", redemptionTime.Format("January 2, 2006"))
+}
+
+// sendEligibleMessage sends a message with redemption buttons
+func (b *Bot) sendEligibleMessage(ctx context.Context, to string) {
+	buttons := []Button{
+		{ID: "redeem", Title: b.translate("button_redeem")},
+		{ID: "skip", Title: b.translate("button_skip")},
+	}
+	if err := b.sender.SendButtons(ctx, to, b.translate("eligible"), buttons); err != nil {
+		b.logger.Error("Failed to send message with buttons", "error", err)
+		return
+	}
+	b.service.TrackRedeemButton()
+}
+
+// send sends a translated message
+func (b *Bot) send(ctx context.Context, to, key string, args ...string) {
+	if err := b.sender.SendText(ctx, to, b.translate(key, args...)); err != nil {
+		b.logger.Error("Error sending message", "to", to, "error", err)
+	}
+}
+
+// sendRetry sends a message asking to try again later, naming the wait if
+// it is known
+func (b *Bot) sendRetry(ctx context.Context, to, key string, wait time.Duration) {
+	if err := b.sender.SendText(ctx, to, b.translator.TRetry(b.language(), key, wait)); err != nil {
+		b.logger.Error("Error sending message", "to", to, "error", err)
+	}
+}
+
+// language returns the language replies are sent in. WhatsApp does not
+// share the user's language, so the configured default is used.
+func (b *Bot) language() string {
+	return b.translator.GetFallbackLanguage()
+}
+
+// translate translates a message key
+func (b *Bot) translate(key string, args ...string) string {
+	return b.translator.T(b.language(), key, args...)
+}
+
+// isVerificationCode returns true if the text is a 6-digit code
+func isVerificationCode(text string) bool {
+	if len(text) != 6 {
+		return false
+	}
+	for _, r := range text {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,205 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+)
+
+// mockSender records sent messages
+type mockSender struct {
+	texts   []string
+	buttons [][]Button
+}
+
+func (s *mockSender) SendText(ctx context.Context, to, text string) error {
+	s.texts = append(s.texts, text)
+	return nil
+}
+
+func (s *mockSender) SendButtons(ctx context.Context, to, text string, buttons []Button) error {
+	s.texts = append(s.texts, text)
+	s.buttons = append(s.buttons, buttons)
+	return nil
+}
+
+// mockService answers with a fixed status and records redemptions
+type mockService struct {
+	status   string
+	redeemed []string
+	blocked  bool
+}
+
+func (s *mockService) CheckEmailStatus(ctx context.Context, userID int64, email string) (string, *domain.User, error) {
+	return s.status, &domain.User{Email: email}, nil
+}
+
+func (s *mockService) RedeemCocktail(ctx context.Context, userID int64, email string) (time.Time, error) {
+	s.redeemed = append(s.redeemed, email)
+	return time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), nil
+}
+
+func (s *mockService) VerificationRequired() bool { return false }
+
+func (s *mockService) SendVerificationCode(ctx context.Context, userID int64, email string) error {
+	return nil
+}
+
+func (s *mockService) VerifyEmailCode(ctx context.Context, userID int64, code string) (string, error) {
+	return "", domain.ErrNoVerificationPending
+}
+
+func (s *mockService) TrackInteraction(lang, command string) {}
+func (s *mockService) TrackRedeemButton()                    {}
+
+func (s *mockService) IsUserBlocked(userID int64) bool { return s.blocked }
+
+func (s *mockService) RetryAfter(ctx context.Context, userID int64) time.Duration { return time.Minute }
+
+func (s *mockService) UnavailableRetryAfter() time.Duration { return 0 }
+
+func newTestBot(svc *mockService) (*Bot, *mockSender) {
+	cfg := config.New()
+	cfg.WhatsApp.VerifyToken = "verify-me"
+	cfg.WhatsApp.AppSecret = "app-secret"
+	sender := &mockSender{}
+	return New(sender, svc, cfg, logger.New("error")), sender
+}
+
+// post sends a signed webhook call with a single message
+func post(t *testing.T, bot *Bot, message map[string]any) int {
+	t.Helper()
+
+	payload, _ := json.Marshal(map[string]any{
+		"object": "whatsapp_business_account",
+		"entry": []any{map[string]any{
+			"changes": []any{map[string]any{
+				"field": "messages",
+				"value": map[string]any{"messages": []any{message}},
+			}},
+		}},
+	})
+
+	mac := hmac.New(sha256.New, []byte("app-secret"))
+	mac.Write(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(payload)))
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	bot.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestWebhookVerification(t *testing.T) {
+	bot, _ := newTestBot(&mockService{})
+
+	rec := httptest.NewRecorder()
+	bot.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?hub.mode=subscribe&hub.verify_token=verify-me&hub.challenge=42", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "42" {
+		t.Errorf("Expected challenge echoed, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	bot.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?hub.mode=subscribe&hub.verify_token=wrong&hub.challenge=42", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for wrong verify token, got %d", rec.Code)
+	}
+
+	// Unsigned calls are rejected when an app secret is configured
+	rec = httptest.NewRecorder()
+	bot.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"entry":[]}`)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for unsigned webhook, got %d", rec.Code)
+	}
+
+	// Without an app secret, no call is accepted
+	bot.config.WhatsApp.AppSecret = ""
+	rec = httptest.NewRecorder()
+	bot.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"entry":[]}`)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for unsigned webhook without app secret, got %d", rec.Code)
+	}
+}
+
+func TestRedemptionFlow(t *testing.T) {
+	svc := &mockService{status: "eligible"}
+	bot, sender := newTestBot(svc)
+
+	// An eligible email is answered with redemption buttons
+	code := post(t, bot, map[string]any{"from": "15551234567", "type": "text", "text": map[string]any{"body": "Guest@Example.com"}})
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(sender.buttons) != 1 || sender.buttons[0][0].ID != "redeem" || sender.buttons[0][1].ID != "skip" {
+		t.Fatalf("Expected redeem and skip buttons, got %+v", sender.buttons)
+	}
+
+	// Pressing redeem redeems the checked email
+	post(t, bot, map[string]any{"from": "15551234567", "type": "interactive", "interactive": map[string]any{
+		"type": "button_reply", "button_reply": map[string]any{"id": "redeem", "title": "Redeem"},
+	}})
+	if len(svc.redeemed) != 1 || svc.redeemed[0] != "guest@example.com" {
+		t.Fatalf("Expected guest@example.com redeemed, got %v", svc.redeemed)
+	}
+	if last := sender.texts[len(sender.texts)-1]; !strings.Contains(last, "June 1, 2025") {
+		t.Errorf("Expected redemption confirmation, got %q", last)
+	}
+
+	// The email is forgotten after redemption
+	post(t, bot, map[string]any{"from": "15551234567", "type": "interactive", "interactive": map[string]any{
+		"type": "button_reply", "button_reply": map[string]any{"id": "redeem"},
+	}})
+	if len(svc.redeemed) != 1 {
+		t.Errorf("Expected no second redemption, got %v", svc.redeemed)
+	}
+}
+
+func TestBlockedSender(t *testing.T) {
+	bot, sender := newTestBot(&mockService{status: "eligible", blocked: true})
+
+	post(t, bot, map[string]any{"from": "15551234567", "type": "text", "text": map[string]any{"body": "guest@example.com"}})
+	if len(sender.texts) != 0 {
+		t.Errorf("Expected blocked user to be ignored, got %v", sender.texts)
+	}
+}
+
+func TestClientSendButtons(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/123/messages" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Unexpected request %s with auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &got)
+		w.Write([]byte(`{"messages":[{"id":"wamid.1"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "token", "123", server.Client())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	err = client.SendButtons(context.Background(), "15551234567", "Redeem now?", []Button{{ID: "redeem", Title: "A very long button title"}})
+	if err != nil {
+		t.Fatalf("Failed to send buttons: %v", err)
+	}
+
+	buttons := got["interactive"].(map[string]any)["action"].(map[string]any)["buttons"].([]any)
+	title := buttons[0].(map[string]any)["reply"].(map[string]any)["title"].(string)
+	if len([]rune(title)) != maxButtonTitle {
+		t.Errorf("Expected button title truncated to %d characters, got %q", maxButtonTitle, title)
+	}
+}
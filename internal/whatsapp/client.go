@@ -0,0 +1,123 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ceesaxp/cocktail-bot/internal/httpclient"
+)
+
+// maxButtonTitle is the longest reply button title WhatsApp accepts
+const maxButtonTitle = 20
+
+// Button is a quick reply button shown below a message
+type Button struct {
+	ID    string
+	Title string
+}
+
+// Sender sends messages to WhatsApp users
+type Sender interface {
+	SendText(ctx context.Context, to, text string) error
+	SendButtons(ctx context.Context, to, text string, buttons []Button) error
+}
+
+// Client sends messages through the WhatsApp Business Cloud API
+type Client struct {
+	baseURL       string
+	accessToken   string
+	phoneNumberID string
+	httpClient    *http.Client
+}
+
+// NewClient creates a client sending from the given business phone number
+func NewClient(baseURL, accessToken, phoneNumberID string, httpClient *http.Client) (*Client, error) {
+	if accessToken == "" {
+		return nil, errors.New("whatsapp access token cannot be empty")
+	}
+	if phoneNumberID == "" {
+		return nil, errors.New("whatsapp phone number ID cannot be empty")
+	}
+	if baseURL == "" {
+		baseURL = "https://graph.facebook.com/v19.0"
+	}
+	if httpClient == nil {
+		httpClient = httpclient.New(httpclient.Options{Name: "whatsapp"})
+	}
+
+	return &Client{
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		accessToken:   accessToken,
+		phoneNumberID: phoneNumberID,
+		httpClient:    httpClient,
+	}, nil
+}
+
+// SendText sends a plain text message
+func (c *Client) SendText(ctx context.Context, to, text string) error {
+	return c.send(ctx, map[string]any{
+		"messaging_product": "whatsapp",
+		"to":                to,
+		"type":              "text",
+		"text":              map[string]any{"body": text},
+	})
+}
+
+// SendButtons sends a message with up to three reply buttons
+func (c *Client) SendButtons(ctx context.Context, to, text string, buttons []Button) error {
+	replies := make([]map[string]any, 0, len(buttons))
+	for _, button := range buttons {
+		title := button.Title
+		if runes := []rune(title); len(runes) > maxButtonTitle {
+			title = string(runes[:maxButtonTitle])
+		}
+		replies = append(replies, map[string]any{
+			"type":  "reply",
+			"reply": map[string]any{"id": button.ID, "title": title},
+		})
+	}
+
+	return c.send(ctx, map[string]any{
+		"messaging_product": "whatsapp",
+		"to":                to,
+		"type":              "interactive",
+		"interactive": map[string]any{
+			"type":   "button",
+			"body":   map[string]any{"text": text},
+			"action": map[string]any{"buttons": replies},
+		},
+	})
+}
+
+// send posts a message to the Cloud API
+func (c *Client) send(ctx context.Context, message map[string]any) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/"+c.phoneNumberID+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("whatsapp request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("whatsapp returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
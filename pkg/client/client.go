@@ -0,0 +1,525 @@
+// Package client is a typed Go client for the cocktail bot REST API. It
+// is used by the WebUI and can be used by other integrations, such as
+// door apps or ticketing systems.
+//
+//	c := client.New("http://localhost:8080", token)
+//	status, err := c.CheckEmail(ctx, "guest@example.com")
+//
+// Errors returned by the API are *Error values carrying the HTTP status.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultTimeout limits requests of clients created without an HTTP client
+const defaultTimeout = 30 * time.Second
+
+// Client calls the API with one token. It is safe for concurrent use, and
+// the With methods return copies that share the HTTP client.
+type Client struct {
+	baseURL    string
+	token      string
+	clientIP   string // Sent as X-Forwarded-For, so rate limits apply to the end client behind a trusted proxy
+	language   string // Sent as Accept-Language, for error details in that language
+	operator   string // Sent as X-Operator, naming the staff member in the audit log
+	httpClient *http.Client
+}
+
+// New creates a client for the API at baseURL, such as
+// http://localhost:8080, authenticating with token
+func New(baseURL, token string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// WithHTTPClient returns a copy of the client sending requests through httpClient
+func (c *Client) WithHTTPClient(httpClient *http.Client) *Client {
+	copied := *c
+	copied.httpClient = httpClient
+	return &copied
+}
+
+// WithToken returns a copy of the client authenticating with another token
+func (c *Client) WithToken(token string) *Client {
+	copied := *c
+	copied.token = token
+	return &copied
+}
+
+// ForClient returns a copy of the client acting on behalf of the end
+// client at ip, sent as X-Forwarded-For. The API applies its rate limits
+// to that IP only if the caller's address is in api.trusted_proxies of
+// the server and its connection does not use the PROXY protocol; other
+// callers are limited under their own address.
+func (c *Client) ForClient(ip string) *Client {
+	copied := *c
+	copied.clientIP = ip
+	return &copied
+}
+
+// WithLanguage returns a copy of the client asking for error details in
+// lang, such as "de". Details the API has no translation for stay English.
+func (c *Client) WithLanguage(lang string) *Client {
+	copied := *c
+	copied.language = lang
+	return &copied
+}
+
+// WithOperator returns a copy of the client naming the staff member using
+// it, such as a bartender at a shared POS. The name is recorded with every
+// change in the audit log, and must be on the roster in api.operators if
+// the server has one.
+func (c *Client) WithOperator(name string) *Client {
+	copied := *c
+	copied.operator = name
+	return &copied
+}
+
+// Error is an error response of the API
+type Error struct {
+	StatusCode int
+	Message    string
+	Details    string
+	Limit      string        // Rate limit that was exceeded: ip or token
+	RetryAfter time.Duration // Wait before retrying from the Retry-After header, 0 if not given
+}
+
+// Error returns the message and details of the response
+func (e *Error) Error() string {
+	if e.Details == "" {
+		return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("API error %d: %s: %s", e.StatusCode, e.Message, e.Details)
+}
+
+// StatusCode returns the HTTP status of an API error, or 0 if the request
+// failed before the API answered
+func StatusCode(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// RetryAfter returns how long the API asked to wait before retrying a
+// failed request, or 0 if it gave no estimate
+func RetryAfter(err error) time.Duration {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// do sends a request with an optional JSON payload and decodes a
+// successful JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, payload, out any) error {
+	resp, err := c.send(ctx, method, path, query, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse API response: %w", err)
+	}
+	return nil
+}
+
+// send sends a request and returns the response if its status is 2xx.
+// The caller must close the body.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, payload any) (*http.Response, error) {
+	var body io.Reader
+	contentType := "application/json"
+	switch p := payload.(type) {
+	case nil:
+	case csvPayload:
+		body, contentType = p.Reader, "text/csv"
+	default:
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.clientIP != "" {
+		req.Header.Set("X-Forwarded-For", c.clientIP)
+	}
+	if c.language != "" {
+		req.Header.Set("Accept-Language", c.language)
+	}
+	if c.operator != "" {
+		req.Header.Set("X-Operator", c.operator)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	// Error responses are JSON, except from proxies in front of the API
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var parsed struct {
+		Error   string `json:"error"`
+		Message string `json:"message"` // Set instead of error when adding an existing email
+		Details string `json:"details"`
+		Limit   string `json:"limit"`
+		Title   string `json:"title"`  // Problem details, sent when the API fails unexpectedly
+		Detail  string `json:"detail"` // Problem details
+	}
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	if json.Unmarshal(data, &parsed) == nil {
+		apiErr.Message, apiErr.Details, apiErr.Limit = parsed.Error, parsed.Details, parsed.Limit
+		if apiErr.Message == "" {
+			apiErr.Message = parsed.Message
+		}
+		if apiErr.Message == "" {
+			apiErr.Message, apiErr.Details = parsed.Title, parsed.Detail
+		}
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return nil, apiErr
+}
+
+// Introspect describes the client's token: its scopes and the events it
+// works for. An invalid token fails with status 401 Unauthorized.
+func (c *Client) Introspect(ctx context.Context) (*TokenInfo, error) {
+	var info TokenInfo
+	if err := c.do(ctx, http.MethodGet, "/api/v1/auth/introspect", nil, nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// IntrospectToken describes another token, such as one a user pasted in.
+// The client needs an admin token to check a token other than its own,
+// and fails with status 403 Forbidden otherwise. An invalid or removed
+// token is returned with Valid false.
+func (c *Client) IntrospectToken(ctx context.Context, token string) (*TokenInfo, error) {
+	var info TokenInfo
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/introspect", nil, map[string]string{"token": token}, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// CheckEmail returns whether the guest with the email may redeem a cocktail
+func (c *Client) CheckEmail(ctx context.Context, email string) (*EmailStatus, error) {
+	var status EmailStatus
+	if err := c.do(ctx, http.MethodGet, "/api/v1/email/status", url.Values{"email": {email}}, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Redeem redeems the cocktail of the guest with the email. An email that
+// was redeemed before fails with status 409 Conflict, an unknown one with
+// 404 Not Found.
+func (c *Client) Redeem(ctx context.Context, email string) (*Redemption, error) {
+	var redemption Redemption
+	if err := c.do(ctx, http.MethodPost, "/api/v1/email/redeem", nil, map[string]string{"email": email}, &redemption); err != nil {
+		return nil, err
+	}
+	return &redemption, nil
+}
+
+// AddEmail adds a guest. An email that is already on the guest list fails
+// with status 409 Conflict.
+func (c *Client) AddEmail(ctx context.Context, email string) (*AddedEmail, error) {
+	var added AddedEmail
+	if err := c.do(ctx, http.MethodPost, "/api/v1/email", nil, map[string]string{"email": email}, &added); err != nil {
+		return nil, err
+	}
+	return &added, nil
+}
+
+// PatchUser changes fields of the guest with the ID or email, and returns
+// the updated guest. It needs an admin token. Invalid fields fail with
+// status 400 Bad Request, an email of another guest with 409 Conflict.
+func (c *Client) PatchUser(ctx context.Context, id string, patch UserPatch) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodPatch, "/api/v1/users/"+url.PathEscape(id), nil, patch, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// MergeUsers merges the duplicate record of a guest into the record that
+// is kept, both named by ID or email. It needs an admin token. The
+// duplicate stays tagged merged and can no longer redeem.
+func (c *Client) MergeUsers(ctx context.Context, keep, duplicate, reason string) (*MergedUsers, error) {
+	body := map[string]string{"keep": keep, "duplicate": duplicate, "reason": reason}
+	var merged MergedUsers
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/users/merge", nil, body, &merged); err != nil {
+		return nil, err
+	}
+	return &merged, nil
+}
+
+// Report returns one page of a report, or the whole report if the query
+// has no limit
+func (c *Client) Report(ctx context.Context, query ReportQuery) (*Report, error) {
+	if query.Type == "" {
+		query.Type = ReportAll
+	}
+	var report Report
+	if err := c.do(ctx, http.MethodGet, "/api/v1/report/"+url.PathEscape(string(query.Type)), query.values(), nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Dataset returns a report as an anonymized dataset. Reversible datasets,
+// whose guest tokens can be turned back into emails, need an admin token.
+// Paging and comparisons of the query are ignored.
+func (c *Client) Dataset(ctx context.Context, query ReportQuery, reversible bool) (*Dataset, error) {
+	values := query.values()
+	if query.Type != "" {
+		values.Set("type", string(query.Type))
+	}
+	if reversible {
+		values.Set("mode", "reversible")
+	}
+	var dataset Dataset
+	if err := c.do(ctx, http.MethodGet, "/api/v1/report/dataset", values, nil, &dataset); err != nil {
+		return nil, err
+	}
+	return &dataset, nil
+}
+
+// ReportCount returns how many users a report holds, without fetching them
+func (c *Client) ReportCount(ctx context.Context, query ReportQuery) (int, error) {
+	report, err := c.ReportSummary(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return report.Total, nil
+}
+
+// ReportSummary returns the total of a report and of the range it is
+// compared with, without fetching users
+func (c *Client) ReportSummary(ctx context.Context, query ReportQuery) (*Report, error) {
+	if query.Type == "" {
+		query.Type = ReportAll
+	}
+	values := query.values()
+	values.Del("offset")
+	values.Del("limit")
+	values.Set("count_only", "true")
+	var report Report
+	if err := c.do(ctx, http.MethodGet, "/api/v1/report/"+url.PathEscape(string(query.Type)), values, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ReportUsers fetches all users of a report in pages of pageSize users
+func (c *Client) ReportUsers(ctx context.Context, query ReportQuery, pageSize int) ([]*User, error) {
+	if pageSize < 1 {
+		return nil, fmt.Errorf("page size must be 1 or greater, got %d", pageSize)
+	}
+
+	var users []*User
+	query.Limit = pageSize
+	for offset := query.Offset; ; offset += pageSize {
+		query.Offset = offset
+		page, err := c.Report(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, page.Users...)
+		if page.Count < pageSize || offset+page.Count >= page.Total {
+			return users, nil
+		}
+	}
+}
+
+// SearchUsers returns a page of the guests of the event whose email, name,
+// notes or tags contain every word of the query, best match first
+func (c *Client) SearchUsers(ctx context.Context, query SearchQuery) (*SearchPage, error) {
+	var page SearchPage
+	if err := c.do(ctx, http.MethodGet, "/api/v1/users/search", query.values(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Engagement returns the bot usage statistics since its start
+func (c *Client) Engagement(ctx context.Context) (*Engagement, error) {
+	var engagement Engagement
+	if err := c.do(ctx, http.MethodGet, "/api/v1/stats/engagement", nil, nil, &engagement); err != nil {
+		return nil, err
+	}
+	return &engagement, nil
+}
+
+// Funnel returns the redemption funnel per day since the bot started: email
+// checks, eligible checks, redeem buttons shown and redemptions
+func (c *Client) Funnel(ctx context.Context) (*Funnel, error) {
+	var funnel Funnel
+	if err := c.do(ctx, http.MethodGet, "/api/v1/stats/funnel", nil, nil, &funnel); err != nil {
+		return nil, err
+	}
+	return &funnel, nil
+}
+
+// Features returns the feature flags of the bot and whether each is on
+func (c *Client) Features(ctx context.Context) (*Features, error) {
+	var features Features
+	if err := c.do(ctx, http.MethodGet, "/api/v1/features", nil, nil, &features); err != nil {
+		return nil, err
+	}
+	return &features, nil
+}
+
+// RedemptionLatency returns how long the redeemed guests of the query's
+// range took from being added to redeeming. The type, paging and
+// comparisons of the query are ignored.
+func (c *Client) RedemptionLatency(ctx context.Context, query ReportQuery) (*RedemptionLatency, error) {
+	values := query.values()
+	values.Del("offset")
+	values.Del("limit")
+	var latency RedemptionLatency
+	if err := c.do(ctx, http.MethodGet, "/api/v1/stats/latency", values, nil, &latency); err != nil {
+		return nil, err
+	}
+	return &latency, nil
+}
+
+// DatabaseStatus returns the diagnostics of the database. It requires an admin token.
+func (c *Client) DatabaseStatus(ctx context.Context) (*DatabaseStatus, error) {
+	var status DatabaseStatus
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/db", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// AuditLog returns one page of the audit log. It requires an admin token.
+func (c *Client) AuditLog(ctx context.Context, query AuditQuery) (*AuditPage, error) {
+	var page AuditPage
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/audit", query.values(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ExportAuditLog streams all audit log entries matching the query as CSV.
+// The caller must close the reader. It requires an admin token.
+func (c *Client) ExportAuditLog(ctx context.Context, query AuditQuery) (io.ReadCloser, error) {
+	values := query.values()
+	values.Del("limit")
+	values.Del("offset")
+	values.Set("format", "csv")
+
+	resp, err := c.send(ctx, http.MethodGet, "/api/v1/admin/audit", values, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ExportAuditJournal streams the whole audit log as a hash-chained journal
+// signed with event.journal_key, which cocktail-admin verify-journal
+// checks. The caller must close the reader. It requires an admin token.
+func (c *Client) ExportAuditJournal(ctx context.Context) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/api/v1/admin/audit", url.Values{"format": {"journal"}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Jobs returns the recent jobs, newest first: all jobs for admin tokens,
+// the jobs the token started for others
+func (c *Client) Jobs(ctx context.Context) (*JobList, error) {
+	var list JobList
+	if err := c.do(ctx, http.MethodGet, "/api/v1/jobs", nil, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Job returns the progress of a job
+func (c *Client) Job(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodGet, "/api/v1/jobs/"+url.PathEscape(id), nil, nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CancelJob stops a queued or running job. A job that already finished
+// fails with status 409 Conflict.
+func (c *Client) CancelJob(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodPost, "/api/v1/jobs/"+url.PathEscape(id)+"/cancel", nil, nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// csvPayload is a request body sent as text/csv instead of JSON
+type csvPayload struct {
+	io.Reader
+}
+
+// ImportCSV imports the guests of a CSV file in the background, with the
+// columns named in opts, and returns the job tracking it
+func (c *Client) ImportCSV(ctx context.Context, data io.Reader, opts ImportOptions) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodPost, "/api/v1/import/csv", opts.values(), csvPayload{data}, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// setInt sets a query parameter to a number, unless it is 0
+func setInt(values url.Values, key string, n int) {
+	if n != 0 {
+		values.Set(key, strconv.Itoa(n))
+	}
+}
@@ -0,0 +1,263 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/pkg/client"
+)
+
+func TestClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/email/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer door" || r.Header.Get("X-Forwarded-For") != "203.0.113.7" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]any{"error": "Unauthorized", "code": 401})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"email": r.URL.Query().Get("email"), "status": "eligible"})
+	})
+	mux.HandleFunc("/api/v1/auth/introspect", func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if r.Method == http.MethodPost {
+			var req struct{ Token string }
+			json.NewDecoder(r.Body).Decode(&req)
+			token = req.Token
+		}
+		if token == "revoked" {
+			json.NewEncoder(w).Encode(map[string]any{"valid": false, "active": false, "scopes": []string{}, "expires": nil, "events": []string{}})
+			return
+		}
+		scopes := []string{"api"}
+		if token == "admin" {
+			scopes = append(scopes, "admin")
+		}
+		json.NewEncoder(w).Encode(map[string]any{"valid": true, "fingerprint": "3f2a9c1b", "scopes": scopes, "expires": nil, "events": []string{"Launch"}})
+	})
+	mux.HandleFunc("/api/v1/email/redeem", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Language") == "de" {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]any{"error": "Conflict", "code": 409, "details": "Cocktail bereits eingelöst"})
+			return
+		}
+		w.Header().Set("Retry-After", "42")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]any{"error": "Too Many Requests", "code": 429, "details": "Rate limit exceeded", "limit": "ip"})
+	})
+	mux.HandleFunc("/api/v1/email", func(w http.ResponseWriter, r *http.Request) {
+		if operator := r.Header.Get("X-Operator"); operator != "" && operator != "Anna" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{"error": "Forbidden", "code": 403, "details": "Unknown operator"})
+			return
+		}
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]any{"id": "7", "status": "exists", "message": "Email already exists in database"})
+	})
+	mux.HandleFunc("/api/v1/report/all", func(w http.ResponseWriter, r *http.Request) {
+		const total = 5
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if r.URL.Query().Get("count_only") == "true" {
+			report := map[string]any{"type": "all", "total": total}
+			if r.URL.Query().Get("compare") == "previous" {
+				report["compare"] = map[string]any{"total": 3, "change": total - 3}
+			}
+			json.NewEncoder(w).Encode(report)
+			return
+		}
+		var users []map[string]any
+		for i := offset; i < total && (limit == 0 || i < offset+limit); i++ {
+			users = append(users, map[string]any{"id": strconv.Itoa(i), "email": fmt.Sprintf("guest%d@example.com", i)})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"type": "all", "total": total, "offset": offset, "limit": limit, "count": len(users), "users": users})
+	})
+	mux.HandleFunc("/api/v1/report/dataset", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		json.NewEncoder(w).Encode(map[string]any{"type": query.Get("type"), "mode": query.Get("mode"), "count": 1,
+			"records": []map[string]any{{"guest": "r1.abc", "source": "import"}}})
+	})
+	mux.HandleFunc("/api/v1/users/search", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		hit := map[string]any{"user": map[string]any{"id": "1", "email": "anna@example.com", "notes": "VIP table"}, "score": 2, "fields": []string{"notes"}}
+		json.NewEncoder(w).Encode(map[string]any{"query": query.Get("q"), "event": "Launch", "total": 3, "offset": 0, "limit": limit, "count": 1, "results": []any{hit}})
+	})
+	mux.HandleFunc("/api/v1/features", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"features": []map[string]any{{"name": "kiosk", "enabled": false}, {"name": "vouchers", "enabled": true}}})
+	})
+	mux.HandleFunc("/api/v1/stats/funnel", func(w http.ResponseWriter, r *http.Request) {
+		day := map[string]any{"checks": 10, "eligible": 6, "buttons_shown": 5, "redeemed": 4, "unclaimed": 2}
+		json.NewEncoder(w).Encode(map[string]any{"days": []map[string]any{day}, "total": day})
+	})
+	mux.HandleFunc("/api/v1/stats/latency", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"count": 2, "p50_seconds": 3600, "from": r.URL.Query().Get("period"),
+			"buckets": []map[string]any{{"label": "<1h", "up_to_seconds": 3600, "count": 1}, {"label": "1-6h", "up_to_seconds": 21600, "count": 1}}})
+	})
+	mux.HandleFunc("/api/v1/jobs/job1/cancel", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]any{"error": "Conflict", "code": 409, "details": "Job already finished as done"})
+	})
+	mux.HandleFunc("/api/v1/jobs", func(w http.ResponseWriter, r *http.Request) {
+		jobs := []map[string]any{{"id": "job1", "type": "import", "status": "running", "total": 4, "done": 1, "counts": map[string]int{"added": 1}}}
+		json.NewEncoder(w).Encode(map[string]any{"jobs": jobs, "count": 1})
+	})
+	mux.HandleFunc("/api/v1/import/csv", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		query := r.URL.Query()
+		if r.Header.Get("Content-Type") != "text/csv" || query.Get("email_column") != "2" || query.Get("header") != "false" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{"id": "job2", "type": "import", "name": query.Get("name"), "status": "queued", "total": bytes.Count(data, []byte("\n"))})
+	})
+	mux.HandleFunc("/api/v1/users/7", func(w http.ResponseWriter, r *http.Request) {
+		var patch map[string]any
+		if r.Method != http.MethodPatch || json.NewDecoder(r.Body).Decode(&patch) != nil || len(patch) != 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"id": "7", "email": "guest@example.com", "tags": patch["tags"]})
+	})
+	mux.HandleFunc("/api/v1/admin/users/merge", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"user":      map[string]any{"id": "7", "email": req["keep"]},
+			"duplicate": map[string]any{"id": "8", "email": req["duplicate"], "tags": []string{"merged"}},
+		})
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	ctx := context.Background()
+	c := client.New(ts.URL+"/", "door")
+
+	// The token and the end client's IP are sent with every request
+	if _, err := c.CheckEmail(ctx, "guest@example.com"); client.StatusCode(err) != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without the client IP, got %v", err)
+	}
+	status, err := c.ForClient("203.0.113.7").CheckEmail(ctx, "guest@example.com")
+	if err != nil || status.Status != client.StatusEligible || status.Email != "guest@example.com" {
+		t.Errorf("Expected an eligible guest, got %+v (%v)", status, err)
+	}
+
+	// Introspection tells which endpoints the token may use
+	info, err := c.Introspect(ctx)
+	if err != nil || !info.Valid || info.HasScope(client.ScopeAdmin) || info.Expires != nil || info.Events[0] != "Launch" {
+		t.Errorf("Expected a non-admin token of the event, got %+v (%v)", info, err)
+	}
+	if info, err := c.WithToken("admin").Introspect(ctx); err != nil || !info.HasScope(client.ScopeAdmin) {
+		t.Errorf("Expected an admin token, got %+v (%v)", info, err)
+	}
+	if info, err := c.IntrospectToken(ctx, "admin"); err != nil || !info.Valid || !info.HasScope(client.ScopeAdmin) {
+		t.Errorf("Expected the posted admin token to be described, got %+v (%v)", info, err)
+	}
+	if info, err := c.IntrospectToken(ctx, "revoked"); err != nil || info.Valid || info.HasScope(client.ScopeAPI) {
+		t.Errorf("Expected a revoked token to be described as not valid, got %+v (%v)", info, err)
+	}
+
+	// Error responses carry their status and details
+	_, err = c.Redeem(ctx, "guest@example.com")
+	apiErr, ok := err.(*client.Error)
+	if !ok || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Limit != "ip" || apiErr.Details != "Rate limit exceeded" {
+		t.Errorf("Expected a rate limit error, got %#v", err)
+	}
+	if wait := client.RetryAfter(err); wait != 42*time.Second {
+		t.Errorf("Expected to retry after 42s, got %v", wait)
+	}
+	_, err = c.WithLanguage("de").Redeem(ctx, "guest@example.com")
+	if apiErr, ok := err.(*client.Error); !ok || apiErr.Details != "Cocktail bereits eingelöst" {
+		t.Errorf("Expected German details, got %v", err)
+	}
+	_, err = c.AddEmail(ctx, "guest@example.com")
+	if client.StatusCode(err) != http.StatusConflict || err.(*client.Error).Message != "Email already exists in database" {
+		t.Errorf("Expected a conflict, got %v", err)
+	}
+	_, err = c.WithOperator("Anna").AddEmail(ctx, "guest@example.com")
+	if client.StatusCode(err) != http.StatusConflict {
+		t.Errorf("Expected an operator on the roster to be accepted, got %v", err)
+	}
+	_, err = c.WithOperator("Mallory").AddEmail(ctx, "guest@example.com")
+	if client.StatusCode(err) != http.StatusForbidden {
+		t.Errorf("Expected an unknown operator to be refused, got %v", err)
+	}
+
+	// Reports are fetched page by page
+	users, err := c.ReportUsers(ctx, client.ReportQuery{}, 2)
+	if err != nil || len(users) != 5 || users[4].Email != "guest4@example.com" {
+		t.Errorf("Expected 5 users across pages, got %d (%v)", len(users), err)
+	}
+	page, err := c.Report(ctx, client.ReportQuery{Offset: 4, Limit: 2})
+	if err != nil || page.Total != 5 || page.Count != 1 {
+		t.Errorf("Expected the last page, got %+v (%v)", page, err)
+	}
+	if count, err := c.ReportCount(ctx, client.ReportQuery{Limit: 2}); err != nil || count != 5 {
+		t.Errorf("Expected a count of 5, got %d (%v)", count, err)
+	}
+	summary, err := c.ReportSummary(ctx, client.ReportQuery{Period: "week", Compare: client.ComparePrevious})
+	if err != nil || summary.Total != 5 || summary.Compare == nil || summary.Compare.Change != 2 {
+		t.Errorf("Expected 5 against 3, got %+v (%v)", summary, err)
+	}
+
+	dataset, err := c.Dataset(ctx, client.ReportQuery{Type: client.ReportRedeemed}, true)
+	if err != nil || dataset.Type != "redeemed" || dataset.Mode != "reversible" || dataset.Records[0].Guest != "r1.abc" {
+		t.Errorf("Expected a reversible dataset of redeemed guests, got %+v (%v)", dataset, err)
+	}
+	features, err := c.Features(ctx)
+	if err != nil || features.Enabled("kiosk") || !features.Enabled("vouchers") || features.Enabled("waitlist") {
+		t.Errorf("Expected vouchers on and the kiosk off, got %+v (%v)", features, err)
+	}
+
+	funnel, err := c.Funnel(ctx)
+	if err != nil || len(funnel.Days) != 1 || funnel.Total.ButtonsShown != 5 || funnel.Total.Unclaimed != 2 {
+		t.Errorf("Expected the funnel of one day, got %+v (%v)", funnel, err)
+	}
+	search, err := c.SearchUsers(ctx, client.SearchQuery{Query: "vip table", Limit: 1})
+	if err != nil || search.Query != "vip table" || search.Total != 3 || search.Limit != 1 || len(search.Results) != 1 || search.Results[0].User.Notes != "VIP table" || search.Results[0].Fields[0] != "notes" {
+		t.Errorf("Expected a page of the search, got %+v (%v)", search, err)
+	}
+	latency, err := c.RedemptionLatency(ctx, client.ReportQuery{Period: "week"})
+	if err != nil || latency.Count != 2 || latency.P50Seconds != 3600 || len(latency.Buckets) != 2 || latency.From != "week" {
+		t.Errorf("Expected the latency of the week, got %+v (%v)", latency, err)
+	}
+
+	// Jobs report their progress
+	list, err := c.Jobs(ctx)
+	if err != nil || list.Count != 1 || !list.Jobs[0].Active() || list.Jobs[0].Percent() != 25 || list.Jobs[0].Counts["added"] != 1 {
+		t.Errorf("Expected a running job at 25%%, got %+v (%v)", list, err)
+	}
+	if _, err := c.CancelJob(ctx, "job1"); client.StatusCode(err) != http.StatusConflict {
+		t.Errorf("Expected a conflict canceling a finished job, got %v", err)
+	}
+
+	// Imports send the file as CSV with the columns in the query
+	job, err := c.ImportCSV(ctx, strings.NewReader("Anna,anna@example.com\nBen,ben@example.com\n"), client.ImportOptions{Name: "guests.csv", EmailColumn: 2, NoHeader: true})
+	if err != nil || job.Name != "guests.csv" || job.Total != 2 {
+		t.Errorf("Expected an import job of 2 rows, got %+v (%v)", job, err)
+	}
+
+	// Patches only send the fields to change
+	tags := []string{"vip"}
+	user, err := c.PatchUser(ctx, "7", client.UserPatch{Tags: &tags, Reason: "sponsor"})
+	if err != nil || len(user.Tags) != 1 || user.Tags[0] != "vip" {
+		t.Errorf("Expected the tagged guest, got %+v (%v)", user, err)
+	}
+
+	merged, err := c.MergeUsers(ctx, "guest@example.com", "gest@example.com", "typo")
+	if err != nil || merged.User.Email != "guest@example.com" || merged.Duplicate.Tags[0] != "merged" {
+		t.Errorf("Expected the merged guests, got %+v (%v)", merged, err)
+	}
+}
@@ -0,0 +1,473 @@
+package client
+
+import (
+	"net/url"
+	"time"
+)
+
+// Statuses of an email check
+const (
+	StatusEligible = "eligible"  // On the guest list and not yet redeemed
+	StatusRedeemed = "redeemed"  // Redeemed before
+	StatusNotFound = "not_found" // Not on the guest list
+	StatusDenied   = "denied"    // Blocked by the operators
+	StatusArchived = "archived"  // The event is over
+)
+
+// User is a guest record
+type User struct {
+	ID               string     `json:"id"`
+	Email            string     `json:"email"` // Masked in privacy mode unless revealed
+	FirstName        string     `json:"first_name,omitempty"`
+	LastName         string     `json:"last_name,omitempty"` // Only the initial in privacy mode unless revealed
+	DateAdded        time.Time  `json:"date_added"`
+	Redeemed         *time.Time `json:"redeemed,omitempty"`
+	MarketingConsent *time.Time `json:"marketing_consent,omitempty"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	CreatedBy        string     `json:"created_by,omitempty"`
+	Bar              string     `json:"bar,omitempty"` // Bar that served the drink
+	Notes            string     `json:"notes,omitempty"`
+	Tags             []string   `json:"tags,omitempty"`
+}
+
+// UserPatch is a partial update of a guest. Nil fields are not changed.
+type UserPatch struct {
+	Email     *string   `json:"email,omitempty"`      // Corrected email
+	FirstName *string   `json:"first_name,omitempty"` // Empty to clear the first name
+	LastName  *string   `json:"last_name,omitempty"`  // Empty to clear the last name
+	Notes     *string   `json:"notes,omitempty"`      // Empty to clear the notes
+	Tags      *[]string `json:"tags,omitempty"`       // Replaces all tags, empty to clear them
+	Unredeem  bool      `json:"unredeem,omitempty"`   // Lets the guest redeem again, needs a reason
+	Reason    string    `json:"reason,omitempty"`     // Recorded in the audit log
+}
+
+// MergedUsers holds both records after a merge
+type MergedUsers struct {
+	User      User `json:"user"`      // The kept record with the data of both
+	Duplicate User `json:"duplicate"` // The duplicate, tagged merged
+}
+
+// Scopes of API tokens
+const (
+	ScopeAPI   = "api"   // Guest lookups, redemptions and reports
+	ScopeAdmin = "admin" // Admin endpoints, such as the audit log
+)
+
+// TokenInfo describes an API token
+type TokenInfo struct {
+	Valid       bool       `json:"valid"`
+	Fingerprint string     `json:"fingerprint"` // As recorded in the audit log, empty if not valid
+	Scopes      []string   `json:"scopes"`
+	Expires     *time.Time `json:"expires"` // nil if the token does not expire
+	Events      []string   `json:"events"`
+	Operator    string     `json:"operator,omitempty"`
+}
+
+// HasScope reports whether the token has the scope, such as ScopeAdmin
+func (t TokenInfo) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// EmailStatus is the result of an email check
+type EmailStatus struct {
+	Email    string     `json:"email"`
+	Status   string     `json:"status"` // One of the Status constants
+	Redeemed *time.Time `json:"redeemed,omitempty"`
+}
+
+// Redemption is the result of a redemption
+type Redemption struct {
+	Email     string    `json:"email"`
+	Status    string    `json:"status"`
+	Redeemed  time.Time `json:"redeemed"`
+	TicketURL string    `json:"ticket_url,omitempty"` // Signed link to a printable ticket
+}
+
+// AddedEmail is the result of adding a guest
+type AddedEmail struct {
+	ID      string `json:"id,omitempty"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// ReportType selects the guests of a report
+type ReportType string
+
+// Report types
+const (
+	ReportAll       ReportType = "all"
+	ReportRedeemed  ReportType = "redeemed"
+	ReportAdded     ReportType = "added"
+	ReportConsented ReportType = "consented"
+)
+
+// ReportQuery selects a report and its page. Empty fields are left to the
+// API, which covers its default period and returns all users.
+type ReportQuery struct {
+	Type   ReportType
+	From   string // YYYY-MM-DD
+	To     string // YYYY-MM-DD
+	Period string // Named period instead of From and To, such as today or event
+	Domain string // Only emails at this domain
+	Source string // Only guests added this way, such as api or import
+	Bar    string // Only guests served at this bar
+	Reveal bool   // Full emails in privacy mode, for admin tokens
+	Offset int
+	Limit  int // Page size, 0 returns all users
+
+	IncludeTest bool // Add the sandbox records of test emails
+
+	// Range the total is compared with: CompareFrom and CompareTo dates,
+	// or Compare set to ComparePrevious for the range before
+	Compare     string
+	CompareFrom string // YYYY-MM-DD
+	CompareTo   string // YYYY-MM-DD
+}
+
+// ComparePrevious compares a report with the range before it, such as
+// yesterday until this time for today
+const ComparePrevious = "previous"
+
+// values returns the query as API parameters
+func (q ReportQuery) values() url.Values {
+	values := url.Values{}
+	for key, value := range map[string]string{
+		"from":   q.From,
+		"to":     q.To,
+		"period": q.Period,
+		"domain": q.Domain,
+		"source": q.Source,
+		"bar":    q.Bar,
+
+		"compare":      q.Compare,
+		"compare_from": q.CompareFrom,
+		"compare_to":   q.CompareTo,
+	} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	if q.Reveal {
+		values.Set("reveal", "true")
+	}
+	if q.IncludeTest {
+		values.Set("include_test", "true")
+	}
+	setInt(values, "offset", q.Offset)
+	setInt(values, "limit", q.Limit)
+	return values
+}
+
+// Report is a page of a report
+type Report struct {
+	Type      string            `json:"type"`
+	Archived  bool              `json:"archived"` // Whether the report covers an archived event
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Total     int               `json:"total"`  // Matching users across all pages
+	Offset    int               `json:"offset"` // Users skipped before this page
+	Limit     int               `json:"limit"`  // Page size, 0 if all users are returned
+	Count     int               `json:"count"`  // Users in this page
+	Users     []*User           `json:"users"`
+	Compare   *ReportComparison `json:"compare"` // Total of the compared range, nil unless requested
+	Generated time.Time         `json:"generated"`
+}
+
+// Dataset is a report with emails replaced by pseudonyms and times rounded
+// down, for sharing with sponsors or analysis
+type Dataset struct {
+	Type      string          `json:"type"`
+	Mode      string          `json:"mode"`   // hashed or reversible
+	Bucket    string          `json:"bucket"` // Times are rounded down to this, in UTC
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Count     int             `json:"count"`
+	Records   []DatasetRecord `json:"records"`
+	Generated time.Time       `json:"generated"`
+}
+
+// DatasetRecord is an anonymized guest
+type DatasetRecord struct {
+	Guest     string     `json:"guest"`  // Pseudonym, or token that cocktail-admin reveal-guests opens
+	Source    string     `json:"source"` // Kind of creator, such as token, telegram or import
+	Added     time.Time  `json:"added"`
+	Redeemed  *time.Time `json:"redeemed"`
+	Bar       string     `json:"bar"`
+	Consented bool       `json:"consented"`
+}
+
+// ReportComparison is the total of a report over the range it is compared with
+type ReportComparison struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Total  int    `json:"total"`
+	Change int    `json:"change"` // Total of the report minus this total
+}
+
+// Engagement holds the bot usage statistics since its start
+type Engagement struct {
+	Since          time.Time      `json:"since"`
+	Interactions   int            `json:"interactions"`
+	Languages      map[string]int `json:"languages"`
+	Commands       map[string]int `json:"commands"`
+	Hours          [24]int        `json:"hours"`
+	BusiestHour    int            `json:"busiest_hour"`
+	Checks         int            `json:"checks"`
+	EligibleChecks int            `json:"eligible_checks"`
+	ButtonsShown   int            `json:"buttons_shown"`
+	Redemptions    int            `json:"redemptions"`
+	ConversionRate float64        `json:"conversion_rate"` // Redemptions per email check
+}
+
+// Funnel holds the redemption funnel per day since the bot started
+type Funnel struct {
+	Since time.Time   `json:"since"`
+	Days  []FunnelDay `json:"days"` // Oldest first
+	Total FunnelDay   `json:"total"`
+}
+
+// FunnelDay holds the funnel counts of one day
+type FunnelDay struct {
+	Date         string `json:"date,omitempty"` // YYYY-MM-DD, empty for totals
+	Checks       int    `json:"checks"`
+	Eligible     int    `json:"eligible"`
+	ButtonsShown int    `json:"buttons_shown"`
+	Redeemed     int    `json:"redeemed"`
+	Unclaimed    int    `json:"unclaimed"` // Eligible checks beyond the redemptions
+}
+
+// Feature is the state of a feature flag
+type Feature struct {
+	Name        string `json:"name"` // Such as kiosk, self_registration or vouchers
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	Configured  bool   `json:"configured"` // State read from the configuration
+	Overridden  bool   `json:"overridden"` // Toggled at runtime by an admin token
+}
+
+// Features holds the feature flags of the bot
+type Features struct {
+	Features      []Feature `json:"features"`
+	RuntimeToggle bool      `json:"runtime_toggle"` // Whether admin tokens may toggle flags
+}
+
+// Enabled returns whether the named flag is on. Flags the bot does not
+// know are off.
+func (f *Features) Enabled(name string) bool {
+	for _, feature := range f.Features {
+		if feature.Name == name {
+			return feature.Enabled
+		}
+	}
+	return false
+}
+
+// RedemptionLatency is the distribution of the time guests took from being
+// added to redeeming, in seconds
+type RedemptionLatency struct {
+	Event       string          `json:"event,omitempty"`
+	Count       int             `json:"count"` // Redeemed guests measured
+	MinSeconds  int64           `json:"min_seconds"`
+	MeanSeconds int64           `json:"mean_seconds"`
+	P50Seconds  int64           `json:"p50_seconds"`
+	P90Seconds  int64           `json:"p90_seconds"`
+	P99Seconds  int64           `json:"p99_seconds"`
+	MaxSeconds  int64           `json:"max_seconds"`
+	Buckets     []LatencyBucket `json:"buckets"`
+	From        string          `json:"from"`
+	To          string          `json:"to"`
+	Generated   time.Time       `json:"generated"`
+}
+
+// LatencyBucket is a bar of the redemption latency histogram
+type LatencyBucket struct {
+	Label       string `json:"label"`
+	UpToSeconds int64  `json:"up_to_seconds,omitempty"` // Exclusive upper bound, 0 for the last bucket
+	Count       int    `json:"count"`
+}
+
+// DatabaseStatus holds the diagnostics of the database
+type DatabaseStatus struct {
+	Status  string         `json:"status"` // ok or unavailable
+	Error   string         `json:"error,omitempty"`
+	Stats   *DatabaseStats `json:"stats,omitempty"`
+	Checked time.Time      `json:"checked"`
+}
+
+// DatabaseStats holds the size of the guest list and backend details
+type DatabaseStats struct {
+	Backend   string            `json:"backend"`
+	Users     int               `json:"users"`
+	Redeemed  int               `json:"redeemed"`
+	Consented int               `json:"consented"`
+	LastWrite *time.Time        `json:"last_write,omitempty"` // Most recent change, nil if the guest list is empty
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// AuditQuery selects audit log entries. Empty fields match every entry.
+type AuditQuery struct {
+	Actor    string
+	Operator string
+	Action   string
+	Email    string
+	From     string // YYYY-MM-DD
+	To       string // YYYY-MM-DD
+	Reveal   bool   // Full emails in privacy mode
+	Offset   int
+	Limit    int // Page size, 0 uses the API default
+}
+
+// values returns the query as API parameters
+func (q AuditQuery) values() url.Values {
+	values := url.Values{}
+	for key, value := range map[string]string{
+		"actor":    q.Actor,
+		"operator": q.Operator,
+		"action":   q.Action,
+		"email":    q.Email,
+		"from":     q.From,
+		"to":       q.To,
+	} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	if q.Reveal {
+		values.Set("reveal", "true")
+	}
+	setInt(values, "offset", q.Offset)
+	setInt(values, "limit", q.Limit)
+	return values
+}
+
+// SearchQuery selects a page of the guests matching a search
+type SearchQuery struct {
+	Query  string // Words that must all be found in the email, name, notes or tags
+	Reveal bool   // Full emails in privacy mode
+	Offset int
+	Limit  int // Page size, 0 uses the API default
+}
+
+// values returns the query as API parameters
+func (q SearchQuery) values() url.Values {
+	values := url.Values{"q": {q.Query}}
+	if q.Reveal {
+		values.Set("reveal", "true")
+	}
+	setInt(values, "offset", q.Offset)
+	setInt(values, "limit", q.Limit)
+	return values
+}
+
+// SearchHit is a guest matching a search
+type SearchHit struct {
+	User   *User    `json:"user"`
+	Score  int      `json:"score"`  // Higher is a better match
+	Fields []string `json:"fields"` // Fields a word matched: email, name, notes or tags
+}
+
+// SearchPage is a page of the guests matching a search, best match first
+type SearchPage struct {
+	Query     string      `json:"query"`
+	Event     string      `json:"event,omitempty"` // Event the guests belong to
+	Total     int         `json:"total"`           // Matching guests across all pages
+	Offset    int         `json:"offset"`
+	Limit     int         `json:"limit"`
+	Count     int         `json:"count"`
+	Results   []SearchHit `json:"results"`
+	Generated time.Time   `json:"generated"`
+}
+
+// AuditEntry is an action recorded in the audit log
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor"` // Who acted, such as token:<fingerprint> or telegram:<user ID>
+	Action   string    `json:"action"`
+	Email    string    `json:"email,omitempty"`
+	Details  string    `json:"details,omitempty"`
+	Operator string    `json:"operator,omitempty"` // Staff member named with X-Operator
+	User     *User     `json:"user,omitempty"`     // Guest record after the action
+}
+
+// AuditPage is a page of the audit log
+type AuditPage struct {
+	Total     int          `json:"total"` // Matching entries across all pages
+	Offset    int          `json:"offset"`
+	Limit     int          `json:"limit"`
+	Count     int          `json:"count"`
+	Entries   []AuditEntry `json:"entries"`
+	Generated time.Time    `json:"generated"`
+}
+
+// Job is a long-running operation, such as a queued import or a migration
+type Job struct {
+	ID       string         `json:"id"`
+	Type     string         `json:"type"`           // import or migration
+	Name     string         `json:"name,omitempty"` // Migration name, such as normalize-emails, or the imported file
+	Status   string         `json:"status"`         // queued, running, done, failed or canceled
+	Actor    string         `json:"actor,omitempty"`
+	Total    int            `json:"total"` // Items to process, 0 while unknown
+	Done     int            `json:"done"`
+	Counts   map[string]int `json:"counts,omitempty"` // Processed items by outcome, such as added or duplicate
+	Errors   []string       `json:"errors"`
+	Created  time.Time      `json:"created"`
+	Updated  time.Time      `json:"updated"`
+	Finished *time.Time     `json:"finished,omitempty"`
+}
+
+// Active returns whether the job is still queued or running
+func (j Job) Active() bool {
+	return j.Status == "queued" || j.Status == "running"
+}
+
+// Percent returns the share of items processed, from 0 to 100. Finished
+// jobs are at 100, jobs of unknown size at 0 until they finish.
+func (j Job) Percent() int {
+	switch {
+	case !j.Active():
+		return 100
+	case j.Total <= 0:
+		return 0
+	default:
+		return min(j.Done*100/j.Total, 100)
+	}
+}
+
+// ImportOptions name the columns of a CSV import, numbered from 1
+type ImportOptions struct {
+	Name        string // File name shown with the job
+	EmailColumn int
+	NameColumn  int    // 0 for none
+	TagsColumn  int    // 0 for none, tags are separated by commas
+	NoHeader    bool   // The first row is a guest, not a header
+	Existing    string // skip or update guests already on the list, skip if empty
+}
+
+// values returns the options as API parameters
+func (o ImportOptions) values() url.Values {
+	values := url.Values{}
+	if o.Name != "" {
+		values.Set("name", o.Name)
+	}
+	setInt(values, "email_column", o.EmailColumn)
+	setInt(values, "name_column", o.NameColumn)
+	setInt(values, "tags_column", o.TagsColumn)
+	if o.NoHeader {
+		values.Set("header", "false")
+	}
+	if o.Existing != "" {
+		values.Set("existing", o.Existing)
+	}
+	return values
+}
+
+// JobList is the list of recent jobs
+type JobList struct {
+	Jobs  []Job `json:"jobs"`
+	Count int   `json:"count"`
+}
@@ -23,7 +23,7 @@ fi
 # Build the bot
 echo "Building Cocktail Bot..."
 go build -o cocktail-bot ./cmd/bot
-go build -o importcsv ./cmd/importcsv
+go build -o cocktail-admin ./cmd/admin
 
 # Create sample CSV file if it doesn't exist
 if [ ! -f data/users.csv ]; then
@@ -42,5 +42,5 @@ echo "2. Run ./cocktail-bot to start the bot"
 echo "3. Or use Docker: docker-compose up -d"
 echo ""
 echo "For importing emails from a CSV file, use:"
-echo "./importcsv -input your_emails.csv -output data/users.csv -column 1"
+echo "./cocktail-admin import csv your_emails.csv --column 1"
 echo ""
@@ -0,0 +1,177 @@
+package webui
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/audit"
+	"github.com/ceesaxp/cocktail-bot/pkg/client"
+)
+
+// auditPageSize is the number of entries shown per audit log page
+const auditPageSize = 50
+
+// auditView holds the data shown on the audit log page
+type auditView struct {
+	Title        string
+	User         string
+	Lang         string        // Language of the page
+	LanguageMenu template.HTML // Language switcher
+	Filter       url.Values    // Filters of the current page
+	Actions      []string      // Choices of the action filter
+	Entries      []client.AuditEntry
+	Total        int
+	Page         int
+	PrevURL      string // Empty on the first page
+	NextURL      string // Empty on the last page
+	Export       string // CSV export of all matching entries
+	Masked       bool   // Emails are masked unless revealed
+	Error        string
+}
+
+// auditFilterParams returns the filters of an audit log request, as
+// kept in the links to other pages
+func auditFilterParams(r *http.Request) map[string]string {
+	params := make(map[string]string)
+	for _, key := range []string{"actor", "operator", "action", "email", "from", "to", "reveal"} {
+		if value := r.URL.Query().Get(key); value != "" {
+			params[key] = value
+		}
+	}
+	return params
+}
+
+// auditQuery returns the API query of the filters of an audit log request
+func auditQuery(params map[string]string) client.AuditQuery {
+	return client.AuditQuery{
+		Actor:    params["actor"],
+		Operator: params["operator"],
+		Action:   params["action"],
+		Email:    params["email"],
+		From:     params["from"],
+		To:       params["to"],
+		Reveal:   params["reveal"] == "true",
+	}
+}
+
+// auditPageURL returns the link to a page of the audit log with the same filters
+func auditPageURL(path string, params map[string]string, page int) string {
+	values := url.Values{}
+	for key, value := range params {
+		values.Set(key, value)
+	}
+	if page > 1 {
+		values.Set("page", strconv.Itoa(page))
+	}
+	if len(values) == 0 {
+		return path
+	}
+	return path + "?" + values.Encode()
+}
+
+// handleAudit shows the audit log, filtered by actor, action, email and
+// date. The audit log requires an admin token.
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	params := auditFilterParams(r)
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	lang := s.pageLanguage(w, r)
+	view := &auditView{
+		Title:        s.translator.T(lang, "webui_nav_audit"),
+		User:         getUserFromCookie(r),
+		Lang:         lang,
+		LanguageMenu: s.languageSwitcher(r, lang),
+		Filter:       r.URL.Query(),
+		Actions:      []string{audit.ActionRedeem, audit.ActionAddUser, audit.ActionUpdateUser, audit.ActionConsent, audit.ActionArchiveEvent, audit.ActionRejectRegistration},
+		Page:         page,
+		Export:       auditPageURL("/audit/export", params, 1),
+		Masked:       s.config.Privacy.MaskEmails,
+	}
+
+	token := sessionToken(r)
+	if !s.authProvider.IsAdmin(token) {
+		view.Error = s.translator.T(lang, "webui_audit_admin")
+		s.renderAudit(w, view)
+		return
+	}
+
+	query := auditQuery(params)
+	query.Limit = auditPageSize
+	query.Offset = (page - 1) * auditPageSize
+
+	result, err := s.apiClient.WithToken(token).AuditLog(r.Context(), query)
+	if err != nil {
+		s.logger.Error("Error getting audit log", "error", err)
+		view.Error = s.translator.T(lang, "webui_audit_error")
+		s.renderAudit(w, view)
+		return
+	}
+
+	view.Entries = result.Entries
+	view.Total = result.Total
+
+	if page > 1 {
+		view.PrevURL = auditPageURL("/audit", params, page-1)
+	}
+	if page*auditPageSize < result.Total {
+		view.NextURL = auditPageURL("/audit", params, page+1)
+	}
+
+	s.renderAudit(w, view)
+}
+
+// handleAuditExport downloads all audit log entries matching the filters as CSV
+func (s *Server) handleAuditExport(w http.ResponseWriter, r *http.Request) {
+	token := sessionToken(r)
+	if !s.authProvider.IsAdmin(token) {
+		http.Error(w, "Admin token required", http.StatusForbidden)
+		return
+	}
+
+	body, err := s.apiClient.WithToken(token).ExportAuditLog(r.Context(), auditQuery(auditFilterParams(r)))
+	if err != nil {
+		s.logger.Error("Error exporting audit log", "error", err)
+		status := client.StatusCode(err)
+		if status == 0 {
+			status = http.StatusBadGateway
+		}
+		http.Error(w, "Error exporting audit log", status)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"audit-%s.csv\"", time.Now().Format("2006-01-02")))
+	io.Copy(w, body)
+}
+
+// renderAudit renders the audit log template
+func (s *Server) renderAudit(w http.ResponseWriter, view *auditView) {
+	var buf bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&buf, "audit.html", view); err != nil {
+		s.logger.Error("Error rendering audit page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// sessionToken returns the API token of the logged in user, kept on the
+// server with their session
+func sessionToken(r *http.Request) string {
+	if sess := requestSession(r); sess != nil {
+		return sess.token
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package webui
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+)
+
+// defaultBrandName is shown when no event is named
+const defaultBrandName = "Cocktail Bot"
+
+// defaultFavicon is the cocktail emoji as an inline SVG icon
+const defaultFavicon = `data:image/svg+xml,<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><text y=".9em" font-size="90">🍹</text></svg>`
+
+// Paths local branding files are served at
+const (
+	brandingLogoPath    = "/branding/logo"
+	brandingFaviconPath = "/branding/favicon"
+)
+
+// cssColor matches the colors accepted for branding: hex codes and names
+var cssColor = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|[a-zA-Z]+)$`)
+
+// branding holds what tells the WebUI of one event apart from another
+type branding struct {
+	Name        string       // Event name, shown in page titles and the navbar
+	LogoURL     template.URL // Empty without a logo
+	FaviconURL  template.URL
+	AccentColor string // Empty keeps the default colors
+	NavbarColor string
+	logoFile    string // Local files served at brandingLogoPath and brandingFaviconPath
+	faviconFile string
+}
+
+// brandingHead is added to the head of every page
+var brandingHead = template.Must(template.New("head").Parse(`<link rel="icon" href="{{.FaviconURL}}">
+{{- if or .AccentColor .NavbarColor}}
+    <style>
+    {{- with .AccentColor}}
+        .btn-primary, .bg-primary { background-color: {{.}} !important; border-color: {{.}} !important; }
+        .btn-outline-primary { color: {{.}} !important; border-color: {{.}} !important; }
+        .border-primary { border-color: {{.}} !important; }
+    {{- end}}
+    {{- with .NavbarColor}}
+        .navbar.bg-dark { background-color: {{.}} !important; }
+    {{- end}}
+    </style>
+{{- end}}`))
+
+// brandingNav is the brand link of the navigation bar
+var brandingNav = template.Must(template.New("nav").Parse(`<a class="navbar-brand d-flex align-items-center" href="/">
+{{- if .LogoURL}}<img src="{{.LogoURL}}" alt="" height="30" class="me-2">{{else}}🍹 {{end}}{{.Name}}</a>`))
+
+// newBranding returns the branding of the configured event
+func newBranding(cfg *config.Config) (*branding, error) {
+	b := &branding{
+		Name:        cfg.Event.Name,
+		FaviconURL:  defaultFavicon,
+		AccentColor: cfg.WebUI.Branding.AccentColor,
+		NavbarColor: cfg.WebUI.Branding.NavbarColor,
+	}
+	if b.Name == "" {
+		b.Name = defaultBrandName
+	}
+
+	for _, color := range []string{b.AccentColor, b.NavbarColor} {
+		if color != "" && !cssColor.MatchString(color) {
+			return nil, fmt.Errorf("invalid branding color %q, use a hex code or a color name", color)
+		}
+	}
+
+	// Logos come from the configuration, so data URLs are trusted
+	if logo := cfg.WebUI.Branding.Logo; isURL(logo) {
+		b.LogoURL = template.URL(logo)
+	} else if logo != "" {
+		b.LogoURL, b.logoFile = brandingLogoPath, logo
+	}
+	if favicon := cfg.WebUI.Branding.Favicon; isURL(favicon) {
+		b.FaviconURL = template.URL(favicon)
+	} else if favicon != "" {
+		b.FaviconURL, b.faviconFile = brandingFaviconPath, favicon
+	}
+	return b, nil
+}
+
+// isURL returns true if the logo or favicon is a URL rather than a local file
+func isURL(value string) bool {
+	return strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "data:")
+}
+
+// title returns the page title for a page of the event
+func (b *branding) title(page string) string {
+	if page == "" {
+		return b.Name
+	}
+	return b.Name + " - " + page
+}
+
+// head returns the favicon and color overrides for the head of a page
+func (b *branding) head() template.HTML {
+	return b.render(brandingHead)
+}
+
+// nav returns the brand link of the navigation bar
+func (b *branding) nav() template.HTML {
+	return b.render(brandingNav)
+}
+
+// render executes a branding template, which only fails on a broken template
+func (b *branding) render(tmpl *template.Template) template.HTML {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, b); err != nil {
+		return ""
+	}
+	return template.HTML(buf.String())
+}
+
+// funcs returns the template functions pages use to show the branding
+func (b *branding) funcs() template.FuncMap {
+	return template.FuncMap{
+		"brandTitle": b.title,
+		"brandHead":  b.head,
+		"brandNav":   b.nav,
+	}
+}
+
+// register serves the local logo and favicon files, if any
+func (b *branding) register(mux *http.ServeMux) {
+	if b.logoFile != "" {
+		mux.HandleFunc(brandingLogoPath, func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFile(w, r, b.logoFile)
+		})
+	}
+	if b.faviconFile != "" {
+		mux.HandleFunc(brandingFaviconPath, func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFile(w, r, b.faviconFile)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package webui
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/api"
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+	"github.com/ceesaxp/cocktail-bot/pkg/client"
+)
+
+// consoleView holds the data shown on the redeem console
+type consoleView struct {
+	Title        string
+	Lang         string
+	LanguageMenu template.HTML
+}
+
+// consoleResult is the answer to a check or redemption on the console
+type consoleResult struct {
+	Status  string `json:"status"`          // Email status, or "redeemed" after a redemption
+	Email   string `json:"email,omitempty"` // Set when the email can be redeemed
+	Message string `json:"message"`
+	Class   string `json:"class"`           // Bootstrap alert class: success, info, warning or danger
+	Retry   bool   `json:"retry,omitempty"` // A queued redemption should be sent again later
+}
+
+// handleConsole shows the redeem console for bar staff on phones
+func (s *Server) handleConsole(w http.ResponseWriter, r *http.Request) {
+	lang := s.pageLanguage(w, r)
+	view := &consoleView{
+		Title:        s.translator.T(lang, "webui_nav_console"),
+		Lang:         lang,
+		LanguageMenu: s.languageSwitcher(r, lang),
+	}
+
+	var buf bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&buf, "console.html", view); err != nil {
+		s.logger.Error("Error rendering console page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// handleConsoleStatus checks the status of an email for the console
+func (s *Server) handleConsoleStatus(w http.ResponseWriter, r *http.Request) {
+	lang := s.pageLanguage(w, r)
+
+	// The console takes typed emails, which voucher mode refuses
+	if s.config.Event.Access.Mode == config.AccessModeVoucher {
+		s.writeConsoleResult(w, lang, consoleResult{Status: "invalid", Class: "warning"}, "voucher_required")
+		return
+	}
+
+	email := strings.TrimSpace(r.URL.Query().Get("email"))
+	if !utils.IsValidEmail(email) {
+		s.writeConsoleResult(w, lang, consoleResult{Status: "invalid", Class: "warning"}, "invalid_email")
+		return
+	}
+
+	status, err := s.consoleClient(r).CheckEmail(r.Context(), email)
+	switch code := client.StatusCode(err); {
+	case err == nil:
+	case code == http.StatusTooManyRequests:
+		s.writeConsoleRetry(w, lang, consoleResult{Status: "error", Class: "danger"}, "rate_limited", client.RetryAfter(err))
+		return
+	case code == http.StatusServiceUnavailable:
+		s.writeConsoleRetry(w, lang, consoleResult{Status: "error", Class: "danger"}, "system_unavailable", client.RetryAfter(err))
+		return
+	default:
+		s.logger.Error("Console email check failed", "error", err)
+		s.writeConsoleResult(w, lang, consoleResult{Status: "error", Class: "danger"}, "error_occurred")
+		return
+	}
+
+	switch status.Status {
+	case client.StatusEligible:
+		s.writeConsoleResult(w, lang, consoleResult{Status: status.Status, Email: email, Class: "success"}, "eligible")
+	case client.StatusRedeemed:
+		s.writeConsoleResult(w, lang, consoleResult{Status: status.Status, Class: "info"}, "already_redeemed", "date", formatRedeemed(status.Redeemed))
+	case client.StatusNotFound:
+		s.writeConsoleResult(w, lang, consoleResult{Status: status.Status, Class: "warning"}, "email_not_found")
+	case client.StatusDenied:
+		s.writeConsoleResult(w, lang, consoleResult{Status: status.Status, Class: "danger"}, "email_denied")
+	case client.StatusArchived:
+		s.writeConsoleResult(w, lang, consoleResult{Status: status.Status, Class: "warning"}, "event_archived")
+	default:
+		s.writeConsoleResult(w, lang, consoleResult{Status: "error", Class: "danger"}, "error_occurred")
+	}
+}
+
+// handleConsoleRedeem redeems a cocktail confirmed on the console. The
+// console queues redemptions while offline and sends them again until the
+// result is final, so a repeated redemption is reported as already redeemed.
+func (s *Server) handleConsoleRedeem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	lang := s.pageLanguage(w, r)
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !utils.IsValidEmail(req.Email) {
+		s.writeConsoleResult(w, lang, consoleResult{Status: "invalid", Class: "warning"}, "invalid_email")
+		return
+	}
+
+	apiClient := s.consoleClient(r)
+	redemption, err := apiClient.Redeem(r.Context(), req.Email)
+	switch code := client.StatusCode(err); code {
+	case 0:
+		if err != nil {
+			s.logger.Warn("Console redemption failed, the console will retry", "email", req.Email, "error", err)
+			s.writeConsoleResult(w, lang, consoleResult{Status: "error", Class: "danger", Retry: true}, "system_unavailable")
+			return
+		}
+		s.writeConsoleResult(w, lang, consoleResult{Status: "redeemed", Class: "success"}, "redemption_success", "date", formatRedeemed(&redemption.Redeemed))
+	case http.StatusConflict:
+		status, err := apiClient.CheckEmail(r.Context(), req.Email)
+		if err != nil {
+			s.writeConsoleResult(w, lang, consoleResult{Status: "redeemed", Class: "info"}, "already_redeemed", "date", "")
+			return
+		}
+		if status.Status == client.StatusArchived {
+			s.writeConsoleResult(w, lang, consoleResult{Status: "archived", Class: "warning"}, "event_archived")
+			return
+		}
+		s.writeConsoleResult(w, lang, consoleResult{Status: "redeemed", Class: "info"}, "already_redeemed", "date", formatRedeemed(status.Redeemed))
+	case http.StatusNotFound:
+		s.writeConsoleResult(w, lang, consoleResult{Status: "not_found", Class: "warning"}, "email_not_found")
+	case http.StatusForbidden:
+		s.writeConsoleResult(w, lang, consoleResult{Status: "denied", Class: "danger"}, "email_denied")
+	case http.StatusTooManyRequests:
+		s.writeConsoleRetry(w, lang, consoleResult{Status: "error", Class: "danger", Retry: true}, "rate_limited", client.RetryAfter(err))
+	case http.StatusServiceUnavailable:
+		s.writeConsoleRetry(w, lang, consoleResult{Status: "error", Class: "danger", Retry: true}, "system_unavailable", client.RetryAfter(err))
+	default:
+		s.logger.Error("Console redemption failed", "email", req.Email, "status", code)
+		s.writeConsoleResult(w, lang, consoleResult{Status: "error", Class: "danger", Retry: code >= http.StatusInternalServerError}, "error_occurred")
+	}
+}
+
+// consoleClient returns an API client acting with the session's token on
+// behalf of the console's IP
+func (s *Server) consoleClient(r *http.Request) *client.Client {
+	return s.apiClient.WithToken(sessionToken(r)).ForClient(api.ClientIP(r))
+}
+
+// writeConsoleResult writes a console result with its translated message
+func (s *Server) writeConsoleResult(w http.ResponseWriter, lang string, result consoleResult, key string, args ...string) {
+	result.Message = s.translator.T(lang, key, args...)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// writeConsoleRetry writes a result asking to try again later, naming the
+// wait if the API gave one
+func (s *Server) writeConsoleRetry(w http.ResponseWriter, lang string, result consoleResult, key string, wait time.Duration) {
+	result.Message = s.translator.TRetry(lang, key, wait)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
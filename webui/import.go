@@ -0,0 +1,297 @@
+package webui
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/ceesaxp/cocktail-bot/pkg/client"
+)
+
+// Limits of uploads waiting for their column mapping
+const (
+	maxImportBytes    = 10 << 20  // Largest file, as accepted by the API
+	maxImportUploads  = 20        // Uploads kept at once, the oldest is dropped
+	importUploadTTL   = time.Hour // How long an upload waits for its mapping
+	importPreviewRows = 5         // Rows shown below the header
+)
+
+// importUpload is a CSV file waiting for its columns to be mapped
+type importUpload struct {
+	name    string
+	data    []byte
+	token   string // Session token that uploaded the file, the only one that may import it
+	created time.Time
+}
+
+// importUploads keeps uploaded files between the preview and the import
+type importUploads struct {
+	mu      sync.Mutex
+	uploads map[string]*importUpload
+}
+
+func newImportUploads() *importUploads {
+	return &importUploads{uploads: make(map[string]*importUpload)}
+}
+
+// add keeps an upload and returns its ID. Expired uploads are dropped, and
+// the oldest one if too many are waiting.
+func (u *importUploads) add(upload *importUpload) string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	id := hex.EncodeToString(buf)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var oldest string
+	for key, other := range u.uploads {
+		if time.Since(other.created) > importUploadTTL {
+			delete(u.uploads, key)
+		} else if oldest == "" || other.created.Before(u.uploads[oldest].created) {
+			oldest = key
+		}
+	}
+	if len(u.uploads) >= maxImportUploads {
+		delete(u.uploads, oldest)
+	}
+	u.uploads[id] = upload
+	return id
+}
+
+// get returns the upload of a session, nil if it expired or belongs to
+// another session
+func (u *importUploads) get(id, token string) *importUpload {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	upload := u.uploads[id]
+	if upload == nil || upload.token != token || time.Since(upload.created) > importUploadTTL {
+		return nil
+	}
+	return upload
+}
+
+// remove drops an upload once it is imported
+func (u *importUploads) remove(id string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.uploads, id)
+}
+
+// importColumn is a choice of the column mapping
+type importColumn struct {
+	Number int
+	Label  string // Header cell, empty without a header
+}
+
+// importMapping is what the columns of an upload hold
+type importMapping struct {
+	Email    int // Column numbers from 1, 0 for none
+	Name     int
+	Tags     int
+	Header   bool
+	Existing string // skip or update
+}
+
+// importView holds the data shown on the import page
+type importView struct {
+	Title        string
+	User         string
+	Lang         string        // Language of the page
+	LanguageMenu template.HTML // Language switcher
+	Upload       string        // ID of the uploaded file, empty before a file is uploaded
+	File         string
+	Columns      []importColumn
+	Rows         [][]string // First rows of the file, the header first
+	Mapping      importMapping
+	Error        string
+}
+
+// handleImport shows the upload form, and the first rows of an uploaded
+// CSV file with the choices of its column mapping
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	lang := s.pageLanguage(w, r)
+	view := &importView{
+		Title:        s.translator.T(lang, "webui_nav_import"),
+		User:         getUserFromCookie(r),
+		Lang:         lang,
+		LanguageMenu: s.languageSwitcher(r, lang),
+	}
+
+	if r.Method == http.MethodPost {
+		upload, err := readImportUpload(w, r)
+		if err == nil {
+			err = previewImport(view, upload.data)
+		}
+		if err != nil {
+			s.logger.Warn("Error reading import upload", "error", err)
+			view.Error = s.translator.T(lang, "webui_import_bad_file")
+		} else {
+			upload.token = sessionToken(r)
+			view.Upload = s.imports.add(upload)
+			view.File = upload.name
+		}
+	}
+	s.renderImport(w, view)
+}
+
+// handleImportStart starts the import of an upload with the chosen column
+// mapping and shows its progress on the jobs page
+func (s *Server) handleImportStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	lang := s.pageLanguage(w, r)
+	view := &importView{
+		Title:        s.translator.T(lang, "webui_nav_import"),
+		User:         getUserFromCookie(r),
+		Lang:         lang,
+		LanguageMenu: s.languageSwitcher(r, lang),
+	}
+	id := r.FormValue("upload")
+	upload := s.imports.get(id, sessionToken(r))
+	if upload == nil {
+		view.Error = s.translator.T(lang, "webui_import_expired")
+		s.renderImport(w, view)
+		return
+	}
+
+	mapping := importMapping{Header: r.FormValue("header") == "true", Existing: r.FormValue("existing")}
+	mapping.Email, _ = strconv.Atoi(r.FormValue("email"))
+	mapping.Name, _ = strconv.Atoi(r.FormValue("name"))
+	mapping.Tags, _ = strconv.Atoi(r.FormValue("tags"))
+
+	job, err := s.apiClient.WithToken(sessionToken(r)).ImportCSV(r.Context(), bytes.NewReader(upload.data), client.ImportOptions{
+		Name:        upload.name,
+		EmailColumn: mapping.Email,
+		NameColumn:  mapping.Name,
+		TagsColumn:  mapping.Tags,
+		NoHeader:    !mapping.Header,
+		Existing:    mapping.Existing,
+	})
+	if err != nil {
+		s.logger.Warn("Error starting import", "file", upload.name, "error", err)
+		previewImport(view, upload.data)
+		view.Upload, view.File, view.Mapping = id, upload.name, mapping
+		view.Error = s.translator.T(lang, "webui_import_failed", "error", err.Error())
+		s.renderImport(w, view)
+		return
+	}
+
+	s.imports.remove(id)
+	http.Redirect(w, r, "/jobs?"+url.Values{"started": {job.ID}}.Encode(), http.StatusSeeOther)
+}
+
+// renderImport writes the import page
+func (s *Server) renderImport(w http.ResponseWriter, view *importView) {
+	var buf bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&buf, "import.html", view); err != nil {
+		s.logger.Error("Error rendering import page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// readImportUpload reads the CSV file of the upload form
+func readImportUpload(w http.ResponseWriter, r *http.Request) (*importUpload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImportBytes))
+	if err != nil {
+		return nil, err
+	}
+	return &importUpload{name: header.Filename, data: data, created: time.Now()}, nil
+}
+
+// previewImport fills the view with the first rows of a CSV file and a
+// mapping guessed from its header
+func previewImport(view *importView, data []byte) error {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	view.Rows = nil
+	for len(view.Rows) <= importPreviewRows {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		view.Rows = append(view.Rows, record)
+	}
+	if len(view.Rows) == 0 {
+		return io.ErrUnexpectedEOF
+	}
+
+	columns := 0
+	for _, row := range view.Rows {
+		columns = max(columns, len(row))
+	}
+	view.Columns = make([]importColumn, columns)
+	for i := range view.Columns {
+		view.Columns[i] = importColumn{Number: i + 1}
+		if i < len(view.Rows[0]) {
+			view.Columns[i].Label = strings.TrimSpace(view.Rows[0][i])
+		}
+	}
+	view.Mapping = guessMapping(view.Rows)
+	return nil
+}
+
+// guessMapping picks the columns whose header names an email, a name and
+// tags. Without an email header the first row is taken for a guest, and the
+// email column is the first one holding an @.
+func guessMapping(rows [][]string) importMapping {
+	mapping := importMapping{Existing: "skip"}
+	for i, cell := range rows[0] {
+		switch strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, cell) {
+		case "email", "emailaddress", "mail":
+			mapping.Email = i + 1
+		case "name", "fullname", "guest", "guestname", "firstname":
+			mapping.Name = i + 1
+		case "tags", "tag", "labels":
+			mapping.Tags = i + 1
+		}
+	}
+	if mapping.Email > 0 {
+		mapping.Header = true
+		return mapping
+	}
+
+	mapping.Name, mapping.Tags = 0, 0
+	for i, cell := range rows[0] {
+		if strings.Contains(cell, "@") {
+			mapping.Email = i + 1
+			break
+		}
+	}
+	mapping.Header = mapping.Email == 0
+	mapping.Email = max(mapping.Email, 1)
+	return mapping
+}
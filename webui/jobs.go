@@ -0,0 +1,82 @@
+package webui
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/ceesaxp/cocktail-bot/pkg/client"
+)
+
+// jobsView holds the data shown on the jobs page
+type jobsView struct {
+	Title        string
+	User         string
+	Lang         string        // Language of the page
+	LanguageMenu template.HTML // Language switcher
+	Jobs         []client.Job
+	Refresh      bool   // Reload the page while jobs are running
+	Notice       string // Outcome of a cancellation or a started import
+	Error        string
+}
+
+// handleJobs shows the recent imports, migrations and other long-running
+// jobs the session token may see, with their progress
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	lang := s.pageLanguage(w, r)
+	view := &jobsView{
+		Title:        s.translator.T(lang, "webui_nav_jobs"),
+		User:         getUserFromCookie(r),
+		Lang:         lang,
+		LanguageMenu: s.languageSwitcher(r, lang),
+	}
+	if id := r.URL.Query().Get("started"); id != "" {
+		view.Notice = s.translator.T(lang, "webui_jobs_started", "id", id)
+	}
+	if id := r.URL.Query().Get("canceled"); id != "" {
+		view.Notice = s.translator.T(lang, "webui_jobs_canceled", "id", id)
+	}
+	if id := r.URL.Query().Get("not_canceled"); id != "" {
+		view.Error = s.translator.T(lang, "webui_jobs_not_canceled", "id", id)
+	}
+
+	list, err := s.apiClient.WithToken(sessionToken(r)).Jobs(r.Context())
+	if err != nil {
+		s.logger.Error("Error getting jobs", "error", err)
+		view.Error = s.translator.T(lang, "webui_jobs_error")
+	} else {
+		view.Jobs = list.Jobs
+	}
+	for _, job := range view.Jobs {
+		if job.Active() {
+			view.Refresh = true
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&buf, "jobs.html", view); err != nil {
+		s.logger.Error("Error rendering jobs page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// handleJobCancel cancels a job and returns to the jobs page
+func (s *Server) handleJobCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.FormValue("id")
+	result := "canceled"
+	if _, err := s.apiClient.WithToken(sessionToken(r)).CancelJob(r.Context(), id); err != nil {
+		s.logger.Warn("Error canceling job", "job", id, "error", err)
+		result = "not_canceled"
+	}
+	http.Redirect(w, r, "/jobs?"+url.Values{result: {id}}.Encode(), http.StatusSeeOther)
+}
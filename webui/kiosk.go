@@ -0,0 +1,297 @@
+package webui
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/api"
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/featureflags"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+	"github.com/ceesaxp/cocktail-bot/pkg/client"
+)
+
+// kioskView holds the data shown on the kiosk page
+type kioskView struct {
+	Lang           string
+	Prompt         string
+	CheckLabel     string
+	Message        string
+	MessageClass   string // Bootstrap alert class: success, info, warning or danger
+	Email          string // Set when the email is eligible and waits for staff confirmation
+	RedeemLabel    string
+	TicketURL      string // Signed link to the printable ticket of a redemption
+	TicketLabel    string
+	PINError       bool
+	CaptchaSiteKey string
+}
+
+// handleKiosk shows the email form and checks submitted emails
+func (s *Server) handleKiosk(w http.ResponseWriter, r *http.Request) {
+	if !s.featureEnabled(r.Context(), featureflags.Kiosk) {
+		http.NotFound(w, r)
+		return
+	}
+
+	lang := s.kioskLanguage(r)
+	view := s.newKioskView(lang)
+
+	if r.Method == http.MethodGet {
+		s.renderKiosk(w, view)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	clientIP := api.ClientIP(r)
+	limitKey := "ip:" + api.ClientPrefix(clientIP, s.config.API.IPv6Prefix)
+	if !s.kioskLimiter.AllowKey(limitKey) {
+		s.kioskRetry(w, view, "rate_limited", s.kioskLimiter.RetryAfterKey(limitKey))
+		return
+	}
+
+	if !s.verifyCaptcha(r.FormValue("cf-turnstile-response"), clientIP) {
+		s.logger.Warn("Kiosk CAPTCHA failed", "remote", clientIP)
+		s.kioskMessage(w, view, "danger", "error_occurred")
+		return
+	}
+
+	// The kiosk takes typed emails, which voucher mode refuses while vouchers are on
+	if s.config.Event.Access.Mode == config.AccessModeVoucher && s.featureEnabled(r.Context(), featureflags.Vouchers) {
+		s.kioskMessage(w, view, "warning", "voucher_required")
+		return
+	}
+
+	email := strings.TrimSpace(r.FormValue("email"))
+	if !utils.IsValidEmail(email) {
+		s.kioskMessage(w, view, "warning", "invalid_email")
+		return
+	}
+
+	status, err := s.apiClient.ForClient(clientIP).WithLanguage(lang).CheckEmail(r.Context(), email)
+	switch code := client.StatusCode(err); {
+	case err == nil:
+	case code == http.StatusTooManyRequests:
+		s.kioskRetry(w, view, "rate_limited", client.RetryAfter(err))
+		return
+	case code == http.StatusServiceUnavailable:
+		s.kioskRetry(w, view, "system_unavailable", client.RetryAfter(err))
+		return
+	default:
+		s.logger.Error("Kiosk email check failed", "error", err)
+		s.kioskError(w, view, err)
+		return
+	}
+
+	switch status.Status {
+	case client.StatusEligible:
+		view.Email = status.Email
+		s.kioskMessage(w, view, "success", "eligible")
+	case client.StatusRedeemed:
+		s.kioskMessage(w, view, "info", "already_redeemed", "date", formatRedeemed(status.Redeemed))
+	case client.StatusNotFound:
+		s.kioskMessage(w, view, "warning", "email_not_found")
+	case client.StatusDenied:
+		s.kioskMessage(w, view, "danger", "email_denied")
+	case client.StatusArchived:
+		s.kioskMessage(w, view, "warning", "event_archived")
+	default:
+		s.kioskMessage(w, view, "danger", "error_occurred")
+	}
+}
+
+// handleKioskRedeem redeems the cocktail once staff entered the PIN
+func (s *Server) handleKioskRedeem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/kiosk", http.StatusSeeOther)
+		return
+	}
+	if !s.featureEnabled(r.Context(), featureflags.Kiosk) {
+		http.NotFound(w, r)
+		return
+	}
+
+	lang := s.kioskLanguage(r)
+	view := s.newKioskView(lang)
+	clientIP := api.ClientIP(r)
+
+	// PIN attempts count against the limit, which stops guessing
+	limitKey := "ip:" + api.ClientPrefix(clientIP, s.config.API.IPv6Prefix)
+	if !s.kioskLimiter.AllowKey(limitKey) {
+		s.kioskRetry(w, view, "rate_limited", s.kioskLimiter.RetryAfterKey(limitKey))
+		return
+	}
+
+	email := strings.TrimSpace(r.FormValue("email"))
+	if !utils.IsValidEmail(email) {
+		s.kioskMessage(w, view, "warning", "invalid_email")
+		return
+	}
+
+	pin := r.FormValue("pin")
+	if subtle.ConstantTimeCompare([]byte(pin), []byte(s.config.WebUI.Kiosk.PIN)) != 1 {
+		s.logger.Warn("Kiosk redemption with wrong PIN", "email", email, "remote", clientIP)
+		view.Email = email
+		view.PINError = true
+		s.kioskMessage(w, view, "success", "eligible")
+		return
+	}
+
+	apiClient := s.apiClient.ForClient(clientIP).WithLanguage(lang)
+	redemption, err := apiClient.Redeem(r.Context(), email)
+	switch code := client.StatusCode(err); code {
+	case 0:
+		if err != nil {
+			s.logger.Error("Kiosk redemption failed", "email", email, "error", err)
+			s.kioskMessage(w, view, "danger", "error_occurred")
+			return
+		}
+		s.logger.Info("Audit: cocktail redeemed at kiosk", "actor", "kiosk", "email", email, "remote", clientIP)
+		view.TicketURL = redemption.TicketURL
+		s.kioskMessage(w, view, "success", "redemption_success", "date", formatRedeemed(&redemption.Redeemed))
+	case http.StatusConflict:
+		date := ""
+		if status, err := apiClient.CheckEmail(r.Context(), email); err == nil {
+			if status.Status == client.StatusArchived {
+				s.kioskMessage(w, view, "warning", "event_archived")
+				return
+			}
+			date = formatRedeemed(status.Redeemed)
+		}
+		s.kioskMessage(w, view, "info", "already_redeemed", "date", date)
+	case http.StatusNotFound:
+		s.kioskMessage(w, view, "warning", "email_not_found")
+	case http.StatusForbidden:
+		s.kioskMessage(w, view, "danger", "email_denied")
+	case http.StatusTooManyRequests:
+		s.kioskRetry(w, view, "rate_limited", client.RetryAfter(err))
+	case http.StatusServiceUnavailable:
+		s.kioskRetry(w, view, "system_unavailable", client.RetryAfter(err))
+	default:
+		s.kioskError(w, view, err)
+	}
+}
+
+// newKioskView creates the view of an empty kiosk page
+func (s *Server) newKioskView(lang string) *kioskView {
+	return &kioskView{
+		Lang:           lang,
+		Prompt:         s.translator.T(lang, "kiosk_prompt"),
+		CheckLabel:     s.translator.T(lang, "kiosk_check"),
+		RedeemLabel:    s.translator.T(lang, "button_redeem"),
+		TicketLabel:    s.translator.T(lang, "button_ticket"),
+		CaptchaSiteKey: s.config.WebUI.Kiosk.CaptchaSiteKey,
+	}
+}
+
+// kioskMessage renders the kiosk page with a translated message
+func (s *Server) kioskMessage(w http.ResponseWriter, view *kioskView, class, key string, args ...string) {
+	view.MessageClass = class
+	view.Message = s.translator.T(view.Lang, key, args...)
+	s.renderKiosk(w, view)
+}
+
+// kioskError renders the kiosk with the details of a rejected request,
+// which the API translated to the page language, or a generic message for
+// server errors
+func (s *Server) kioskError(w http.ResponseWriter, view *kioskView, err error) {
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError && apiErr.Details != "" {
+		view.MessageClass = "danger"
+		view.Message = apiErr.Details
+		s.renderKiosk(w, view)
+		return
+	}
+	s.kioskMessage(w, view, "danger", "error_occurred")
+}
+
+// kioskRetry renders the kiosk with a message asking to try again later,
+// naming the wait if it is known
+func (s *Server) kioskRetry(w http.ResponseWriter, view *kioskView, key string, wait time.Duration) {
+	view.MessageClass = "danger"
+	view.Message = s.translator.TRetry(view.Lang, key, wait)
+	s.renderKiosk(w, view)
+}
+
+// renderKiosk renders the kiosk template
+func (s *Server) renderKiosk(w http.ResponseWriter, view *kioskView) {
+	var buf bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&buf, "kiosk.html", view); err != nil {
+		s.logger.Error("Error rendering kiosk page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// kioskLanguage picks the page language from the browser's preferred language
+func (s *Server) kioskLanguage(r *http.Request) string {
+	accept := r.Header.Get("Accept-Language")
+	if i := strings.IndexAny(accept, ",;"); i >= 0 {
+		accept = accept[:i]
+	}
+	return s.translator.DetectLanguage(strings.TrimSpace(accept))
+}
+
+// featureEnabled returns whether a feature flag is on, asking the API so
+// flags toggled at runtime are seen. The configured state is used when the
+// API cannot be reached.
+func (s *Server) featureEnabled(ctx context.Context, name featureflags.Name) bool {
+	features, err := s.apiClient.Features(ctx)
+	if err == nil {
+		return features.Enabled(string(name))
+	}
+	s.logger.Warn("Error reading feature flags, using the configured ones", "flag", name, "error", err)
+	enabled, ok := s.config.Features.Flags[string(name)]
+	return enabled || !ok
+}
+
+// formatRedeemed formats a redemption time returned by the API
+func formatRedeemed(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format("January 2, 2006")
+}
+
+// verifyCaptcha checks a Turnstile answer. It passes when no secret is configured.
+func (s *Server) verifyCaptcha(response, clientIP string) bool {
+	kiosk := s.config.WebUI.Kiosk
+	if kiosk.CaptchaSecret == "" {
+		return true
+	}
+	if response == "" {
+		return false
+	}
+
+	resp, err := s.httpClient.PostForm(kiosk.CaptchaVerifyURL, url.Values{
+		"secret":   {kiosk.CaptchaSecret},
+		"response": {response},
+		"remoteip": {clientIP},
+	})
+	if err != nil {
+		s.logger.Error("Error verifying CAPTCHA", "error", err)
+		return false
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		s.logger.Error("Error decoding CAPTCHA verification", "error", err)
+		return false
+	}
+	return result.Success
+}
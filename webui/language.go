@@ -0,0 +1,80 @@
+package webui
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ceesaxp/cocktail-bot/internal/i18n"
+)
+
+// languageCookie remembers the language picked with the language switcher
+const languageCookie = "webui_lang"
+
+// languageCookieMaxAge keeps the picked language for a year
+const languageCookieMaxAge = 365 * 24 * 60 * 60
+
+// translateFuncs returns the template functions translating WebUI texts.
+// Arguments are placeholder name and value pairs, as for Translator.T.
+func translateFuncs(translator *i18n.Translator) template.FuncMap {
+	return template.FuncMap{
+		"t": func(lang, key string, args ...any) string {
+			values := make([]string, len(args))
+			for i, arg := range args {
+				values[i] = fmt.Sprint(arg)
+			}
+			return translator.T(lang, key, values...)
+		},
+	}
+}
+
+// pageLanguage returns the language of a staff page. A lang query
+// parameter picks the language and stores it in a cookie, otherwise the
+// cookie or else the configured default language is used.
+func (s *Server) pageLanguage(w http.ResponseWriter, r *http.Request) string {
+	available := s.translator.GetAvailableLanguages()
+
+	if lang := strings.ToLower(r.URL.Query().Get("lang")); slices.Contains(available, lang) {
+		http.SetCookie(w, &http.Cookie{
+			Name:     languageCookie,
+			Value:    lang,
+			Path:     "/",
+			MaxAge:   languageCookieMaxAge,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		return lang
+	}
+
+	if cookie, err := r.Cookie(languageCookie); err == nil && slices.Contains(available, cookie.Value) {
+		return cookie.Value
+	}
+	return s.translator.GetFallbackLanguage()
+}
+
+// languageSwitcher returns a menu reloading the page in another language.
+// It is empty when only one language is enabled.
+func (s *Server) languageSwitcher(r *http.Request, lang string) template.HTML {
+	available := s.translator.GetAvailableLanguages()
+	if len(available) < 2 {
+		return ""
+	}
+
+	var options strings.Builder
+	for _, code := range available {
+		query := r.URL.Query()
+		query.Set("lang", code)
+		selected := ""
+		if code == lang {
+			selected = " selected"
+		}
+		fmt.Fprintf(&options, `<option value="%s"%s>%s</option>`,
+			template.HTMLEscapeString(r.URL.Path+"?"+query.Encode()), selected,
+			template.HTMLEscapeString(s.translator.T(code, "webui_language_name")))
+	}
+
+	return template.HTML(fmt.Sprintf(`<select class="form-select form-select-sm w-auto me-3" aria-label="%s" onchange="window.location.href = this.value">%s</select>`,
+		template.HTMLEscapeString(s.translator.T(lang, "webui_language")), options.String()))
+}
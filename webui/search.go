@@ -0,0 +1,118 @@
+package webui
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ceesaxp/cocktail-bot/pkg/client"
+)
+
+// searchPageSize is the number of guests shown per search page
+const searchPageSize = 25
+
+// searchView holds the data shown on the guest search page
+type searchView struct {
+	Title        string
+	User         string
+	Lang         string        // Language of the page
+	LanguageMenu template.HTML // Language switcher
+	Query        string
+	Event        string // Event the guests belong to
+	Results      []client.SearchHit
+	Total        int
+	Page         int
+	PrevURL      string // Empty on the first page
+	NextURL      string // Empty on the last page
+	Masked       bool   // Emails are masked unless revealed
+	Reveal       bool
+	Error        string
+}
+
+// searchPageURL returns the link to a page of a search
+func searchPageURL(query string, reveal bool, page int) string {
+	values := url.Values{"q": {query}}
+	if reveal {
+		values.Set("reveal", "true")
+	}
+	if page > 1 {
+		values.Set("page", strconv.Itoa(page))
+	}
+	return "/search?" + values.Encode()
+}
+
+// handleSearch searches the guests of the event by email, name, notes and
+// tags. Full emails are asked for with the session's own admin token, as
+// on the users pages.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	lang := s.pageLanguage(w, r)
+	view := &searchView{
+		Title:        s.translator.T(lang, "webui_search"),
+		User:         getUserFromCookie(r),
+		Lang:         lang,
+		LanguageMenu: s.languageSwitcher(r, lang),
+		Query:        strings.TrimSpace(r.URL.Query().Get("q")),
+		Page:         page,
+		Masked:       s.config.Privacy.MaskEmails,
+		Reveal:       s.emailsRevealed(r),
+	}
+	if view.Query == "" {
+		s.renderSearch(w, view)
+		return
+	}
+
+	apiClient := s.apiClient
+	if view.Reveal {
+		apiClient = apiClient.WithToken(sessionToken(r))
+	}
+	result, err := apiClient.SearchUsers(r.Context(), client.SearchQuery{
+		Query:  view.Query,
+		Reveal: view.Reveal,
+		Offset: (page - 1) * searchPageSize,
+		Limit:  searchPageSize,
+	})
+	switch {
+	case client.StatusCode(err) == http.StatusBadRequest:
+		view.Error = s.translator.T(lang, "webui_search_invalid")
+		s.renderSearch(w, view)
+		return
+	case err != nil:
+		s.logger.Error("Error searching guests", "error", err)
+		view.Error = s.translator.T(lang, "webui_search_error")
+		s.renderSearch(w, view)
+		return
+	}
+
+	view.Event = result.Event
+	view.Results = result.Results
+	view.Total = result.Total
+	if page > 1 {
+		view.PrevURL = searchPageURL(view.Query, view.Reveal, page-1)
+	}
+	if page*searchPageSize < result.Total {
+		view.NextURL = searchPageURL(view.Query, view.Reveal, page+1)
+	}
+
+	s.renderSearch(w, view)
+}
+
+// renderSearch renders the search template
+func (s *Server) renderSearch(w http.ResponseWriter, view *searchView) {
+	var buf bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&buf, "search.html", view); err != nil {
+		s.logger.Error("Error rendering search page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,214 @@
+package webui
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/api"
+)
+
+// sessionCookie holds the signed session ID. The API token the staff
+// member logged in with stays on the server.
+const sessionCookie = "webui_session"
+
+// legacyTokenCookie held the API token itself in earlier versions, it is
+// cleared when seen
+const legacyTokenCookie = "auth_token"
+
+// Default session lifetimes, used when not configured
+const (
+	defaultSessionIdle   = 2 * time.Hour
+	defaultSessionMaxAge = 24 * time.Hour
+)
+
+// session is a logged in staff member
+type session struct {
+	id          string
+	token       string // API token the session calls the API with
+	fingerprint string
+	created     time.Time
+	lastSeen    time.Time
+}
+
+// sessions keeps the sessions of logged in staff. Session IDs are sent as
+// cookies signed with a key that changes every max age, and the previous
+// key is still accepted so sessions last their full lifetime.
+type sessions struct {
+	secret []byte
+	idle   time.Duration // Ends a session without requests for this long
+	maxAge time.Duration // Ends a session this long after login
+	secure bool          // Cookies are only sent over HTTPS
+	now    func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// newSessions creates the session store. Without a configured secret the
+// keys are random, which only logs staff out on restart since sessions are
+// kept in memory. Secure cookies are only sent over HTTPS.
+func newSessions(secret string, idle, maxAge time.Duration, secure bool) *sessions {
+	if idle <= 0 {
+		idle = defaultSessionIdle
+	}
+	if maxAge <= 0 {
+		maxAge = defaultSessionMaxAge
+	}
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &sessions{secret: key, idle: idle, maxAge: maxAge, secure: secure, now: time.Now, sessions: make(map[string]*session)}
+}
+
+// key returns the signing key of the rotation period at t
+func (s *sessions) key(t time.Time) []byte {
+	return s.periodKey(t.UnixNano() / int64(s.maxAge))
+}
+
+// periodKey derives the key of a rotation period from the secret
+func (s *sessions) periodKey(period int64) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("webui-session"))
+	binary.Write(mac, binary.BigEndian, period)
+	return mac.Sum(nil)
+}
+
+// sign returns the cookie value of a session ID
+func sign(key []byte, id string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// create starts a session for an API token and returns its cookie value.
+// Expired sessions are dropped.
+func (s *sessions) create(token string) string {
+	buf := make([]byte, 24)
+	rand.Read(buf)
+	now := s.now()
+	sess := &session{
+		id:          base64.RawURLEncoding.EncodeToString(buf),
+		token:       token,
+		fingerprint: api.TokenFingerprint(token),
+		created:     now,
+		lastSeen:    now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, other := range s.sessions {
+		if s.expired(other, now) {
+			delete(s.sessions, id)
+		}
+	}
+	s.sessions[sess.id] = sess
+	return sign(s.key(now), sess.id)
+}
+
+// expired reports whether a session has ended at now
+func (s *sessions) expired(sess *session, now time.Time) bool {
+	return now.Sub(sess.lastSeen) > s.idle || now.Sub(sess.created) > s.maxAge
+}
+
+// lookup returns the session of a cookie value and records the request,
+// nil if the signature is wrong or the session ended
+func (s *sessions) lookup(value string) *session {
+	id, _, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil
+	}
+	now := s.now()
+	period := now.UnixNano() / int64(s.maxAge)
+	if !hmac.Equal([]byte(value), []byte(sign(s.periodKey(period), id))) &&
+		!hmac.Equal([]byte(value), []byte(sign(s.periodKey(period-1), id))) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.sessions[id]
+	if sess == nil {
+		return nil
+	}
+	if s.expired(sess, now) {
+		delete(s.sessions, id)
+		return nil
+	}
+	sess.lastSeen = now
+	return sess
+}
+
+// end removes the session of a cookie value
+func (s *sessions) end(value string) {
+	if sess := s.lookup(value); sess != nil {
+		s.mu.Lock()
+		delete(s.sessions, sess.id)
+		s.mu.Unlock()
+	}
+}
+
+// sessionKey is the context key of the session of a request
+type sessionKey struct{}
+
+// withSession returns a context carrying the session
+func withSession(ctx context.Context, sess *session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sess)
+}
+
+// requestSession returns the session of a request that passed the auth
+// middleware, nil otherwise
+func requestSession(r *http.Request) *session {
+	sess, _ := r.Context().Value(sessionKey{}).(*session)
+	return sess
+}
+
+// currentSession returns the valid session of the request's cookie, nil if
+// there is none or its API token was removed
+func (s *Server) currentSession(r *http.Request) *session {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+	sess := s.sessions.lookup(cookie.Value)
+	if sess == nil || !s.authProvider.Authenticate(sess.token) {
+		return nil
+	}
+	return sess
+}
+
+// setSessionCookie sends the session cookie, or clears it if value is
+// empty. The cookie of earlier versions holding the API token is cleared
+// either way.
+func (s *Server) setSessionCookie(w http.ResponseWriter, value string) {
+	maxAge := int(s.sessions.maxAge / time.Second)
+	if value == "" {
+		maxAge = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   s.sessions.secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     legacyTokenCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   s.sessions.secure,
+	})
+}
@@ -0,0 +1,154 @@
+package webui
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSessions returns a session store whose clock is set by the returned
+// function, with a 1h idle timeout and a 24h max age
+func testSessions(secret string) (*sessions, func(time.Time)) {
+	s := newSessions(secret, time.Hour, 24*time.Hour, true)
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, func(t time.Time) { now = t }
+}
+
+func TestSessionExpiry(t *testing.T) {
+	login := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		requests []time.Duration // Times of the requests after login
+		want     bool            // Whether the last request has a session
+	}{
+		{"fresh", []time.Duration{time.Minute}, true},
+		{"idle", []time.Duration{61 * time.Minute}, false},
+		{"requests keep it alive", []time.Duration{50 * time.Minute, 100 * time.Minute, 150 * time.Minute}, true},
+		{"idle after requests", []time.Duration{50 * time.Minute, 111 * time.Minute}, false},
+		{"max age", hourly(24*time.Hour + time.Minute), false},
+		{"just before max age", hourly(24 * time.Hour), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, setNow := testSessions("secret")
+			setNow(login)
+			value := s.create("token")
+
+			var sess *session
+			for _, after := range tt.requests {
+				setNow(login.Add(after))
+				sess = s.lookup(value)
+			}
+			if (sess != nil) != tt.want {
+				t.Errorf("Expected session %v, got %v", tt.want, sess != nil)
+			}
+			if sess != nil && sess.token != "token" {
+				t.Errorf("Expected the token of the login, got %q", sess.token)
+			}
+		})
+	}
+}
+
+// hourly returns request times every 50 minutes up to last, which keeps a
+// session from idling out
+func hourly(last time.Duration) []time.Duration {
+	var times []time.Duration
+	for d := 50 * time.Minute; d < last; d += 50 * time.Minute {
+		times = append(times, d)
+	}
+	return append(times, last)
+}
+
+func TestSessionKeyRotation(t *testing.T) {
+	s, setNow := testSessions("secret")
+
+	// Log in a minute before the signing key changes
+	period := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).UnixNano() / int64(s.maxAge)
+	boundary := time.Unix(0, (period+1)*int64(s.maxAge))
+	setNow(boundary.Add(-time.Minute))
+	old := s.create("token")
+	id, _, _ := strings.Cut(old, ".")
+	if old != sign(s.periodKey(period), id) {
+		t.Fatalf("Expected the cookie to be signed with the current key")
+	}
+
+	// The previous key is still accepted, and new sessions get the new key
+	setNow(boundary.Add(time.Minute))
+	if s.lookup(old) == nil {
+		t.Errorf("Expected a cookie signed with the previous key to be accepted")
+	}
+	fresh := s.create("token")
+	freshID, _, _ := strings.Cut(fresh, ".")
+	if fresh != sign(s.periodKey(period+1), freshID) {
+		t.Errorf("Expected a new session to be signed with the new key")
+	}
+
+	// A key two periods old is rejected, even for a live session
+	setNow(boundary.Add(2 * time.Minute))
+	if s.lookup(sign(s.periodKey(period-1), freshID)) != nil {
+		t.Errorf("Expected a cookie signed with an expired key to be rejected")
+	}
+	if s.lookup(fresh) == nil {
+		t.Errorf("Expected the new session to stay valid")
+	}
+}
+
+func TestSessionTampered(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(value string) string
+	}{
+		{"unsigned", func(value string) string {
+			id, _, _ := strings.Cut(value, ".")
+			return id
+		}},
+		{"empty signature", func(value string) string {
+			id, _, _ := strings.Cut(value, ".")
+			return id + "."
+		}},
+		{"changed signature", func(value string) string {
+			return value[:len(value)-2] + "AA"
+		}},
+		{"other session ID", func(value string) string {
+			_, mac, _ := strings.Cut(value, ".")
+			return "guessed-session-id." + mac
+		}},
+		{"other secret", func(value string) string {
+			id, _, _ := strings.Cut(value, ".")
+			other, _ := testSessions("other secret")
+			return sign(other.key(other.now()), id)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := testSessions("secret")
+			value := s.create("token")
+			if s.lookup(tt.tamper(value)) != nil {
+				t.Errorf("Expected the tampered cookie to be rejected")
+			}
+			if s.lookup(value) == nil {
+				t.Errorf("Expected the original cookie to stay valid")
+			}
+		})
+	}
+}
+
+func TestSessionCookieSecure(t *testing.T) {
+	for _, secure := range []bool{true, false} {
+		srv := &Server{sessions: newSessions("secret", 0, 0, secure)}
+		rec := httptest.NewRecorder()
+		srv.setSessionCookie(rec, srv.sessions.create("token"))
+
+		cookies := rec.Result().Cookies()
+		if len(cookies) == 0 {
+			t.Fatalf("Expected cookies to be set")
+		}
+		for _, cookie := range cookies {
+			if cookie.Secure != secure {
+				t.Errorf("Expected cookie %s to have Secure %v", cookie.Name, secure)
+			}
+		}
+	}
+}
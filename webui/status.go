@@ -0,0 +1,212 @@
+package webui
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/ceesaxp/cocktail-bot/internal/api"
+	"github.com/ceesaxp/cocktail-bot/internal/config"
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/period"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/internal/utils"
+	"github.com/ceesaxp/cocktail-bot/pkg/client"
+)
+
+// statusPathPrefix is followed by the event slug
+const statusPathPrefix = "/public/"
+
+// statusRetryDelay is how long the status page waits after a failed fetch
+// before asking the API again, so screens don't hammer an API that is down
+const statusRetryDelay = 10 * time.Second
+
+// statusCounter counts redemptions, as the API client does
+type statusCounter interface {
+	ReportCount(ctx context.Context, query client.ReportQuery) (int, error)
+}
+
+// statusPage serves the public counts of the event. Counts are fetched
+// from the API at most once per TTL, however many screens show the page.
+type statusPage struct {
+	path    string // /public/{event-slug}
+	sig     string // Signature the link must carry, empty if unsigned
+	ttl     time.Duration
+	event   bool // Whether the event has a start date to count from
+	limiter *ratelimit.Limiter
+	now     func() time.Time
+
+	fetches singleflight.Group // One fetch at a time for all screens
+
+	mu      sync.Mutex
+	today   int
+	total   int
+	fetched time.Time // Zero until counts were fetched once
+	failed  time.Time // Last failed fetch
+}
+
+// statusView holds the data shown on the status page
+type statusView struct {
+	Lang    string
+	Refresh int // Seconds between reloads
+	Counts  []statusCount
+	Updated string
+	Message string // Shown instead of the counts when none could be fetched
+}
+
+// statusCount is one number on the status page
+type statusCount struct {
+	Label string
+	Value int
+}
+
+// newStatusPage sets up the status page of the configured event
+func newStatusPage(cfg *config.Config, log *logger.Logger) *statusPage {
+	page := &statusPage{
+		path:    statusPathPrefix + utils.Slug(cfg.Event.Name),
+		ttl:     cfg.WebUI.StatusPage.CacheTTL.Duration(),
+		event:   cfg.Event.StartDate != "",
+		limiter: ratelimit.NewWithCleanup(cfg.WebUI.StatusPage.RequestsPerMinute, cfg.WebUI.StatusPage.RequestsPerHour, cfg.RateLimiting.CleanupInterval.Duration()),
+		now:     time.Now,
+	}
+	if page.ttl <= 0 {
+		page.ttl = config.DefaultStatusPageConfig().CacheTTL.Duration()
+	}
+
+	link := page.path
+	if secret := cfg.WebUI.StatusPage.Secret; secret != "" {
+		page.sig = statusSignature(secret, page.path)
+		link += "?" + url.Values{"sig": {page.sig}}.Encode()
+	} else {
+		log.Warn("Status page link is not signed, set webui.status_page.secret to sign it")
+	}
+	log.Info("Public status page enabled", "path", link)
+	return page
+}
+
+// statusSignature signs the path of the status page, so the link cannot be
+// guessed from the event name
+func statusSignature(secret, path string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(path))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// handleStatus shows how many cocktails were served, without any guest data
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	page := s.statusPage
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Other events and unsigned links are not told apart from missing pages
+	if r.URL.Path != page.path || (page.sig != "" && subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("sig")), []byte(page.sig)) != 1) {
+		http.NotFound(w, r)
+		return
+	}
+
+	limitKey := "ip:" + api.ClientPrefix(api.ClientIP(r), s.config.API.IPv6Prefix)
+	if !page.limiter.AllowKey(limitKey) {
+		wait := page.limiter.RetryAfterKey(limitKey)
+		w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+		return
+	}
+
+	lang := s.kioskLanguage(r)
+	view := &statusView{Lang: lang, Refresh: int(page.ttl / time.Second)}
+	today, total, fetched, ok := page.counts(r.Context(), s.apiClient, s.logger)
+	if !ok {
+		view.Message = s.translator.T(lang, "status_unavailable")
+		w.Header().Set("Cache-Control", "no-store")
+		s.renderStatus(w, view, http.StatusServiceUnavailable)
+		return
+	}
+
+	view.Counts = []statusCount{{Label: s.translator.T(lang, "status_today"), Value: today}}
+	if page.event {
+		view.Counts = append(view.Counts, statusCount{Label: s.translator.T(lang, "status_event"), Value: total})
+	}
+	view.Updated = s.translator.T(lang, "status_updated", "time", fetched.Format("15:04"))
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", view.Refresh))
+	s.renderStatus(w, view, http.StatusOK)
+}
+
+// counts returns the cached counts, fetching them again once they are
+// older than the TTL. Requests arriving during a fetch wait for it instead
+// of starting their own, and the lock is not held while the API is asked.
+// Stale counts are returned if the API fails, and it is not asked again
+// for statusRetryDelay; ok is false only if counts were never fetched.
+func (p *statusPage) counts(ctx context.Context, counter statusCounter, log *logger.Logger) (today, total int, fetched time.Time, ok bool) {
+	if p.due() {
+		// The fetch outlives a screen that gives up, as others wait for it
+		p.fetches.Do("counts", func() (any, error) {
+			if !p.due() {
+				return nil, nil // Fetched by the previous flight
+			}
+			err := p.fetch(context.WithoutCancel(ctx), counter)
+			if err != nil {
+				log.Warn("Error getting status page counts", "error", err)
+			}
+			return nil, err
+		})
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.today, p.total, p.fetched, !p.fetched.IsZero()
+}
+
+// due reports whether the counts are older than the TTL and the last
+// failure is older than the retry delay
+func (p *statusPage) due() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	return now.Sub(p.fetched) >= p.ttl && now.Sub(p.failed) >= statusRetryDelay
+}
+
+// fetch gets the redemption counts from the API, without fetching users
+func (p *statusPage) fetch(ctx context.Context, counter statusCounter) error {
+	today, err := counter.ReportCount(ctx, client.ReportQuery{Type: client.ReportRedeemed, Period: period.Today})
+	total := 0
+	if err == nil && p.event {
+		total, err = counter.ReportCount(ctx, client.ReportQuery{Type: client.ReportRedeemed, Period: period.Event})
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.failed = p.now()
+		return err
+	}
+	p.today, p.total, p.fetched = today, total, p.now()
+	return nil
+}
+
+// renderStatus renders the status template with the given status code
+func (s *Server) renderStatus(w http.ResponseWriter, view *statusView, code int) {
+	var buf bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&buf, "status.html", view); err != nil {
+		s.logger.Error("Error rendering status page", "error", err)
+		w.Header().Set("Cache-Control", "no-store")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write(buf.Bytes())
+}
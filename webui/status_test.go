@@ -0,0 +1,168 @@
+package webui
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/period"
+	"github.com/ceesaxp/cocktail-bot/pkg/client"
+)
+
+// fakeCounter answers report counts, blocking until release is closed if
+// it is set
+type fakeCounter struct {
+	today, total int
+	err          error
+	release      chan struct{}
+	calls        atomic.Int32 // Calls for today's count, one per fetch
+}
+
+func (f *fakeCounter) ReportCount(ctx context.Context, query client.ReportQuery) (int, error) {
+	if query.Period == period.Event {
+		return f.total, f.err
+	}
+	f.calls.Add(1)
+	if f.release != nil {
+		<-f.release
+	}
+	return f.today, f.err
+}
+
+// testStatusPage returns a status page with a 30s TTL counting from the
+// event start, and the function setting its clock
+func testStatusPage() (*statusPage, func(time.Duration)) {
+	start := time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+	now := start
+	page := &statusPage{ttl: 30 * time.Second, event: true}
+	var mu sync.Mutex
+	page.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	return page, func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = start.Add(d)
+	}
+}
+
+func TestStatusCountsCache(t *testing.T) {
+	log := logger.New("error")
+	boom := errors.New("API down")
+	tests := []struct {
+		name      string
+		fail      []bool          // Whether the API fails at each request
+		at        []time.Duration // Time of each request
+		wantCalls int32
+		wantOK    bool // Of the last request
+		wantToday int  // Of the last request
+	}{
+		{"cached within TTL", []bool{false, false}, []time.Duration{0, 29 * time.Second}, 1, true, 7},
+		{"fetched after TTL", []bool{false, false}, []time.Duration{0, 30 * time.Second}, 2, true, 7},
+		{"error without counts", []bool{true}, []time.Duration{0}, 1, false, 0},
+		{"error cached", []bool{true, false}, []time.Duration{0, statusRetryDelay - time.Second}, 1, false, 0},
+		{"retried after delay", []bool{true, false}, []time.Duration{0, statusRetryDelay}, 2, true, 7},
+		{"stale counts on error", []bool{false, true}, []time.Duration{0, time.Minute}, 2, true, 7},
+		{"stale counts during delay", []bool{false, true, false}, []time.Duration{0, time.Minute, time.Minute + time.Second}, 2, true, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, setNow := testStatusPage()
+			counter := &fakeCounter{today: 7, total: 40}
+
+			var today int
+			var ok bool
+			for i, at := range tt.at {
+				setNow(at)
+				counter.err = nil
+				if tt.fail[i] {
+					counter.err = boom
+				}
+				today, _, _, ok = page.counts(context.Background(), counter, log)
+			}
+			if got := counter.calls.Load(); got != tt.wantCalls {
+				t.Errorf("Expected %d fetches, got %d", tt.wantCalls, got)
+			}
+			if ok != tt.wantOK || today != tt.wantToday {
+				t.Errorf("Expected ok %v and %d today, got %v and %d", tt.wantOK, tt.wantToday, ok, today)
+			}
+		})
+	}
+}
+
+func TestStatusCountsSingleFetch(t *testing.T) {
+	page, _ := testStatusPage()
+	counter := &fakeCounter{today: 7, total: 40, release: make(chan struct{})}
+	log := logger.New("error")
+
+	// Screens arriving while the API is slow wait for the same fetch
+	const screens = 10
+	var wg sync.WaitGroup
+	results := make(chan int, screens)
+	for i := 0; i < screens; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, total, _, _ := page.counts(context.Background(), counter, log)
+			results <- total
+		}()
+	}
+
+	// The lock is free while the fetch waits for the API
+	for counter.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	locked := make(chan struct{})
+	go func() {
+		page.mu.Lock()
+		page.mu.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the lock to be released during the fetch")
+	}
+
+	close(counter.release)
+	wg.Wait()
+	close(results)
+	for total := range results {
+		if total != 40 {
+			t.Errorf("Expected every screen to get the fetched count, got %d", total)
+		}
+	}
+	if got := counter.calls.Load(); got != 1 {
+		t.Errorf("Expected one fetch for all screens, got %d", got)
+	}
+}
+
+func TestStatusCountsCanceledScreen(t *testing.T) {
+	page, _ := testStatusPage()
+	counter := &fakeCounter{today: 7, total: 40}
+
+	// A screen that already gave up still fetches for the others
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, _, ok := page.counts(ctx, &cancelAwareCounter{counter}, logger.New("error")); !ok {
+		t.Errorf("Expected the fetch to ignore the canceled request")
+	}
+}
+
+// cancelAwareCounter fails like the API client when the context is done
+type cancelAwareCounter struct {
+	*fakeCounter
+}
+
+func (c *cancelAwareCounter) ReportCount(ctx context.Context, query client.ReportQuery) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.fakeCounter.ReportCount(ctx, query)
+}
@@ -4,17 +4,24 @@ import (
 	"bytes"
 	"context"
 	"embed"
-	"encoding/json"
 	"fmt"
 	"html/template"
-	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ceesaxp/cocktail-bot/internal/api"
 	"github.com/ceesaxp/cocktail-bot/internal/config"
-	"github.com/ceesaxp/cocktail-bot/internal/domain"
+	"github.com/ceesaxp/cocktail-bot/internal/httpclient"
+	"github.com/ceesaxp/cocktail-bot/internal/i18n"
 	"github.com/ceesaxp/cocktail-bot/internal/logger"
+	"github.com/ceesaxp/cocktail-bot/internal/period"
+	"github.com/ceesaxp/cocktail-bot/internal/proxyproto"
+	"github.com/ceesaxp/cocktail-bot/internal/ratelimit"
+	"github.com/ceesaxp/cocktail-bot/pkg/client"
 )
 
 //go:embed templates/*
@@ -28,15 +35,38 @@ type Server struct {
 	logger       *logger.Logger
 	httpServer   *http.Server
 	authProvider *api.AuthProvider
+	sessions     *sessions // Logged in staff, referenced by signed cookies
 	templates    *template.Template
-	apiURL       string
-	apiToken     string // Store first available token for API calls
+	apiClient    *client.Client // Calls the API with the first token
+	adminToken   string         // First admin token, used for admin-only API calls
+	translator   *i18n.Translator
+	kioskLimiter *ratelimit.Limiter // Limits kiosk requests per client IP, nil when the kiosk is disabled
+	httpClient   *http.Client       // Calls the API and verifies CAPTCHAs
+	brand        *branding          // Event name, logo and colors shown on every page
+	statusPage   *statusPage        // Public counts of the event, nil when the status page is disabled
+	imports      *importUploads     // CSV files waiting for their column mapping
 	running      bool
 }
 
 func New(cfg *config.Config, log *logger.Logger) (*Server, error) {
+	// Pages show the event they belong to
+	brand, err := newBranding(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Forwarding headers name the client only behind trusted proxies
+	if err := api.SetTrustedProxies(cfg.API.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+	}
+
+	// Guest facing pages are translated like the bot, staff pages with
+	// the WebUI texts
+	translator := i18n.NewWithConfig(cfg)
+	i18n.LoadDefaultTranslations(translator)
+
 	// Initialize templates
-	tmpl, err := template.ParseFS(templatesFS, "templates/*.html")
+	tmpl, err := template.New("").Funcs(brand.funcs()).Funcs(translateFuncs(translator)).ParseFS(templatesFS, "templates/*.html")
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse templates: %w", err)
 	}
@@ -54,12 +84,20 @@ func New(cfg *config.Config, log *logger.Logger) (*Server, error) {
 	}
 
 	// Create auth provider with tokens from config (shared with API)
-	authProvider := api.NewAuthProvider(cfg.API.AuthTokens)
+	authProvider := api.NewAuthProviderWithAdmins(cfg.API.AuthTokens, cfg.API.AdminTokens)
 
-	// Store first token for API calls
-	var apiToken string
-	if len(cfg.API.AuthTokens) > 0 {
-		apiToken = cfg.API.AuthTokens[0]
+	// Tokens for the WebUI's own API calls. Hashed tokens cannot be sent, so
+	// the first plain token is used unless one is configured.
+	apiToken := cfg.WebUI.APIToken
+	if apiToken == "" {
+		apiToken = firstPlainToken(cfg.API.AuthTokens)
+	}
+	adminToken := cfg.WebUI.AdminToken
+	if adminToken == "" {
+		adminToken = firstPlainToken(cfg.API.AdminTokens)
+	}
+	if apiToken == "" && len(cfg.API.AuthTokens) > 0 {
+		log.Warn("API tokens are hashed, set webui.api_token for the dashboard and kiosk")
 	}
 
 	// Create HTTP server
@@ -75,22 +113,30 @@ func New(cfg *config.Config, log *logger.Logger) (*Server, error) {
 		apiURL = fmt.Sprintf("http://localhost:%d", cfg.API.Port)
 	}
 
+	httpClient := httpclient.New(httpclient.FromConfig(cfg, "webui_api"))
 	server := &Server{
 		config:       cfg,
 		logger:       log,
 		templates:    tmpl,
 		authProvider: authProvider,
-		apiURL:       apiURL,
-		apiToken:     apiToken,
+		sessions:     newSessions(cfg.WebUI.SessionSecret, cfg.WebUI.SessionIdleTimeout.Duration(), cfg.WebUI.SessionMaxAge.Duration(), !cfg.WebUI.InsecureCookies),
+		apiClient:    client.New(apiURL, apiToken).WithHTTPClient(httpClient),
+		adminToken:   adminToken,
+		translator:   translator,
+		brand:        brand,
+		httpClient:   httpClient,
+		imports:      newImportUploads(),
 		httpServer: &http.Server{
-			Addr:    bindAddr,
-			Handler: mux,
+			Addr:        bindAddr,
+			Handler:     mux,
+			ConnContext: proxyproto.ConnContext,
 		},
 	}
 
 	// Register routes
 	// Static files
 	mux.Handle("/static/", http.FileServer(http.FS(staticFS)))
+	brand.register(mux)
 
 	// Test endpoint to debug
 	mux.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
@@ -103,11 +149,40 @@ func New(cfg *config.Config, log *logger.Logger) (*Server, error) {
 	mux.HandleFunc("/", server.authMiddleware(server.handleDashboard))
 	mux.HandleFunc("/users", server.authMiddleware(server.handleAllUsers))
 	mux.HandleFunc("/redeemed", server.authMiddleware(server.handleRedeemedUsers))
+	mux.HandleFunc("/search", server.authMiddleware(server.handleSearch))
+	mux.HandleFunc("/audit", server.authMiddleware(server.handleAudit))
+	mux.HandleFunc("/audit/export", server.authMiddleware(server.handleAuditExport))
+	mux.HandleFunc("/jobs", server.authMiddleware(server.handleJobs))
+	mux.HandleFunc("/jobs/cancel", server.authMiddleware(server.handleJobCancel))
+	mux.HandleFunc("/import", server.authMiddleware(server.handleImport))
+	mux.HandleFunc("/import/start", server.authMiddleware(server.handleImportStart))
+	mux.HandleFunc("/console", server.authMiddleware(server.handleConsole))
+	mux.HandleFunc("/console/status", server.authMiddleware(server.handleConsoleStatus))
+	mux.HandleFunc("/console/redeem", server.authMiddleware(server.handleConsoleRedeem))
 
 	// Authentication
 	mux.HandleFunc("/login", server.handleLogin)
 	mux.HandleFunc("/logout", server.handleLogout)
 
+	// Public kiosk page for guests without Telegram
+	if cfg.WebUI.Kiosk.Enabled {
+		if cfg.WebUI.Kiosk.PIN == "" {
+			return nil, fmt.Errorf("kiosk requires a staff PIN")
+		}
+		if cfg.WebUI.Kiosk.CaptchaSecret == "" {
+			log.Warn("Kiosk CAPTCHA is disabled, set webui.kiosk.captcha_secret to enable it")
+		}
+		server.kioskLimiter = ratelimit.NewWithCleanup(cfg.WebUI.Kiosk.RequestsPerMinute, cfg.WebUI.Kiosk.RequestsPerHour, cfg.RateLimiting.CleanupInterval.Duration())
+		mux.HandleFunc("/kiosk", server.handleKiosk)
+		mux.HandleFunc("/kiosk/redeem", server.handleKioskRedeem)
+	}
+
+	// Public status page with the counts of the event and no guest data
+	if cfg.WebUI.StatusPage.Enabled {
+		server.statusPage = newStatusPage(cfg, log)
+		mux.HandleFunc(statusPathPrefix, server.handleStatus)
+	}
+
 	return server, nil
 }
 
@@ -125,9 +200,20 @@ func (s *Server) Start() error {
 	s.running = true
 	s.logger.Info("Starting Web UI server", "port", s.config.WebUI.Port)
 
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		s.running = false
+		return fmt.Errorf("failed to listen on %s: %w", s.httpServer.Addr, err)
+	}
+	// Behind HAProxy and similar balancers, clients are read from the PROXY protocol
+	if s.config.WebUI.ProxyProtocol {
+		listener = proxyproto.NewListener(listener)
+		s.logger.Info("Web UI server expects the PROXY protocol")
+	}
+
 	// Start server in a separate goroutine
 	go func() {
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
 			s.logger.Error("Web UI server error", "error", err)
 		}
 	}()
@@ -142,13 +228,20 @@ func (s *Server) Stop() error {
 
 	s.logger.Info("Stopping Web UI server")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeouts.Shutdown.Duration())
 	defer cancel()
 
 	if err := s.httpServer.Shutdown(ctx); err != nil {
 		return fmt.Errorf("error shutting down server: %w", err)
 	}
 
+	if s.kioskLimiter != nil {
+		s.kioskLimiter.Close()
+	}
+	if s.statusPage != nil {
+		s.statusPage.limiter.Close()
+	}
+
 	s.running = false
 	return nil
 }
@@ -156,42 +249,33 @@ func (s *Server) Stop() error {
 // Middleware for authentication
 func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Check if user is authenticated via cookie
-		cookie, err := r.Cookie("auth_token")
-		if err != nil || cookie.Value == "" {
-			// Redirect to login page
-			s.logger.Debug("No auth token cookie, redirecting to login", "path", r.URL.Path)
-			http.Redirect(w, r, "/login?redirect="+r.URL.Path, http.StatusSeeOther)
-			return
-		}
-
-		// Validate the token
-		if !s.authProvider.Authenticate(cookie.Value) {
-			// Clear invalid cookie and redirect to login
-			http.SetCookie(w, &http.Cookie{
-				Name:     "auth_token",
-				Value:    "",
-				Path:     "/",
-				MaxAge:   -1,
-				HttpOnly: true,
-			})
+		// Check if user has a valid session
+		sess := s.currentSession(r)
+		if sess == nil {
+			// Clear a stale cookie and redirect to login page
+			s.logger.Debug("No valid session, redirecting to login", "path", r.URL.Path)
+			if _, err := r.Cookie(sessionCookie); err == nil {
+				s.setSessionCookie(w, "")
+			}
 			http.Redirect(w, r, "/login?redirect="+r.URL.Path, http.StatusSeeOther)
 			return
 		}
 
-		// Pass request to next handler
-		next(w, r)
+		// Pass request to next handler with the session
+		next(w, r.WithContext(withSession(r.Context(), sess)))
 	}
 }
 
 // handleLogin handles the login page and form submission
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	// If already logged in, redirect to dashboard
-	if cookie, err := r.Cookie("auth_token"); err == nil && cookie.Value != "" && s.authProvider.Authenticate(cookie.Value) {
+	if s.currentSession(r) != nil {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 
+	lang := s.pageLanguage(w, r)
+
 	// Get redirect URL from query parameter
 	redirect := r.URL.Query().Get("redirect")
 	if redirect == "" {
@@ -210,16 +294,9 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 
 		// Authenticate token
 		if s.authProvider.Authenticate(token) {
-			// Set auth cookie with the token
-			cookie := &http.Cookie{
-				Name:     "auth_token",
-				Value:    token,
-				Path:     "/",
-				MaxAge:   86400, // 24 hours
-				HttpOnly: true,
-				SameSite: http.SameSiteStrictMode,
-			}
-			http.SetCookie(w, cookie)
+			// Start a session, the browser only gets its signed ID
+			s.setSessionCookie(w, s.sessions.create(token))
+			s.logger.Info("WebUI login", "token", api.TokenFingerprint(token), "client_ip", api.ClientIP(r))
 
 			// Redirect to requested page
 			http.Redirect(w, r, redirect, http.StatusSeeOther)
@@ -227,25 +304,21 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Authentication failed
-		s.renderLoginPage(w, "Invalid authentication token", redirect)
+		s.renderLoginPage(w, r, lang, s.translator.T(lang, "webui_login_invalid"), redirect)
 		return
 	}
 
 	// Display login page
-	s.renderLoginPage(w, "", redirect)
+	s.renderLoginPage(w, r, lang, "", redirect)
 }
 
 // handleLogout handles user logout
 func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
-	// Clear auth token cookie
-	cookie := &http.Cookie{
-		Name:     "auth_token",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1, // Delete cookie
-		HttpOnly: true,
+	// End the session and clear its cookie
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		s.sessions.end(cookie.Value)
 	}
-	http.SetCookie(w, cookie)
+	s.setSessionCookie(w, "")
 
 	// Redirect to login page
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
@@ -257,55 +330,77 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
 	oneWeekAgo := now.AddDate(0, 0, -7).Format("2006-01-02")
 	oneMonthAgo := now.AddDate(0, -1, 0).Format("2006-01-02")
-	oneYearAgo := now.AddDate(-1, 0, 0).Format("2006-01-02")
 	today := now.Format("2006-01-02")
 
 	// Fetch data from API endpoints in parallel
 	type result struct {
-		name string
-		data any
-		err  error
+		name       string
+		count      int
+		compare    *client.ReportComparison
+		engagement *client.Engagement
+		latency    *client.RedemptionLatency
+		err        error
 	}
 
-	results := make(chan result, 4)
+	results := make(chan result, 8)
+	ctx := r.Context()
 
-	// Fetch all users (last year)
-	go func() {
-		resp, err := s.callAPI("/api/v1/report/all", map[string]string{"from": oneYearAgo, "to": today})
-		results <- result{"all", resp, err}
-	}()
+	// Only the totals of the reports are needed, so no users are fetched
+	countUsers := func(name string, query client.ReportQuery) {
+		report, err := s.apiClient.ReportSummary(ctx, query)
+		if err != nil {
+			results <- result{name: name, err: err}
+			return
+		}
+		results <- result{name: name, count: report.Total, compare: report.Compare}
+	}
 
-	// Fetch redeemed users (last year)
-	go func() {
-		resp, err := s.callAPI("/api/v1/report/redeemed", map[string]string{"from": oneYearAgo, "to": today})
-		results <- result{"redeemed", resp, err}
-	}()
+	// Totals cover the default period of the user lists
+	go countUsers("all", s.reportQuery(client.ReportAll, nil))
+	go countUsers("redeemed", s.reportQuery(client.ReportRedeemed, nil))
 
-	// Fetch recently added users (last month)
+	// Recently added users (last month and last week), against the span before
+	go countUsers("last_month", client.ReportQuery{Type: client.ReportAdded, From: oneMonthAgo, To: today, Compare: client.ComparePrevious})
+	go countUsers("last_week", client.ReportQuery{Type: client.ReportAdded, From: oneWeekAgo, To: today, Compare: client.ComparePrevious})
+
+	// Redemptions tonight and this week, against the same time before
+	go countUsers("tonight", client.ReportQuery{Type: client.ReportRedeemed, Period: period.Today, Compare: client.ComparePrevious})
+	go countUsers("this_week", client.ReportQuery{Type: client.ReportRedeemed, Period: period.Week, Compare: client.ComparePrevious})
+
+	// Fetch bot engagement statistics
 	go func() {
-		resp, err := s.callAPI("/api/v1/report/added", map[string]string{"from": oneMonthAgo, "to": today})
-		results <- result{"last_month", resp, err}
+		engagement, err := s.apiClient.Engagement(ctx)
+		results <- result{name: "engagement", engagement: engagement, err: err}
 	}()
 
-	// Fetch recently added users (last week)
+	// Fetch how long guests of the default period took to redeem
 	go func() {
-		resp, err := s.callAPI("/api/v1/report/added", map[string]string{"from": oneWeekAgo, "to": today})
-		results <- result{"last_week", resp, err}
+		latency, err := s.apiClient.RedemptionLatency(ctx, s.reportQuery(client.ReportRedeemed, nil))
+		results <- result{name: "latency", latency: latency, err: err}
 	}()
 
 	// Collect results
 	stats := make(map[string]int)
-	for range 4 {
+	deltas := make(map[string]statDelta)
+	var engagement, latency map[string]any
+	for range 8 {
 		r := <-results
 		if r.err != nil {
 			s.logger.Error("Error fetching data", "endpoint", r.name, "error", r.err)
 			continue
 		}
 
-		if reportResp, ok := r.data.(map[string]any); ok {
-			if count, ok := reportResp["count"].(float64); ok {
-				stats[r.name] = int(count)
-			}
+		if r.name == "engagement" {
+			engagement = engagementView(r.engagement)
+			continue
+		}
+		if r.name == "latency" {
+			latency = latencyView(r.latency)
+			continue
+		}
+		stats[r.name] = r.count
+		if r.compare != nil {
+			deltas[r.name] = newStatDelta(r.compare)
 		}
 	}
 
@@ -321,10 +416,16 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	delete(stats, "all")
 
 	// Render dashboard template
+	lang := s.pageLanguage(w, r)
 	data := map[string]any{
-		"Stats": stats,
-		"Title": "Dashboard",
-		"User":  getUserFromCookie(r),
+		"Stats":        stats,
+		"Deltas":       deltas,
+		"Engagement":   engagement,
+		"Latency":      latency,
+		"Title":        s.translator.T(lang, "webui_nav_dashboard"),
+		"User":         getUserFromCookie(r),
+		"Lang":         lang,
+		"LanguageMenu": s.languageSwitcher(r, lang),
 	}
 	
 	// Debug: log the data being passed
@@ -338,171 +439,270 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleAllUsers displays all users
-func (s *Server) handleAllUsers(w http.ResponseWriter, r *http.Request) {
-	// Get date range from query params or use defaults
-	from := r.URL.Query().Get("from")
-	to := r.URL.Query().Get("to")
+// statDelta is the change of a dashboard total against the range it is
+// compared with
+type statDelta struct {
+	Change    string // Signed, such as +4
+	Previous  int
+	Direction string // up, down or flat
+}
+
+// newStatDelta describes the change of a report comparison
+func newStatDelta(compare *client.ReportComparison) statDelta {
+	delta := statDelta{Change: fmt.Sprintf("%+d", compare.Change), Previous: compare.Total, Direction: "flat"}
+	switch {
+	case compare.Change > 0:
+		delta.Direction = "up"
+	case compare.Change < 0:
+		delta.Direction = "down"
+	default:
+		delta.Change = "±0"
+	}
+	return delta
+}
 
-	if from == "" {
-		from = time.Now().AddDate(-1, 0, 0).Format("2006-01-02") // Last year
+// engagementView converts the engagement statistics into template data
+func engagementView(stats *client.Engagement) map[string]any {
+	return map[string]any{
+		"Languages":   stats.Languages,
+		"Hours":       stats.Hours[:],
+		"BusiestHour": stats.BusiestHour,
+		"Checks":      stats.Checks,
+		"Redemptions": stats.Redemptions,
+		"Conversion":  fmt.Sprintf("%.1f%%", stats.ConversionRate*100),
 	}
-	if to == "" {
-		to = time.Now().Format("2006-01-02")
+}
+
+// latencyView converts the redemption latency into template data, nil when
+// no guest redeemed yet
+func latencyView(latency *client.RedemptionLatency) map[string]any {
+	if latency.Count == 0 {
+		return nil
 	}
+	labels := make([]string, len(latency.Buckets))
+	counts := make([]int, len(latency.Buckets))
+	for i, bucket := range latency.Buckets {
+		labels[i] = bucket.Label
+		counts[i] = bucket.Count
+	}
+	return map[string]any{
+		"Labels": labels,
+		"Counts": counts,
+		"Count":  latency.Count,
+		"P50":    formatLatency(latency.P50Seconds),
+		"P90":    formatLatency(latency.P90Seconds),
+	}
+}
 
+// formatLatency shortens a latency in seconds to its two largest units,
+// such as 2d 4h or 35m
+func formatLatency(seconds int64) string {
+	d := time.Duration(seconds) * time.Second
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd %dh", d/(24*time.Hour), d%(24*time.Hour)/time.Hour)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %dm", d/time.Hour, d%time.Hour/time.Minute)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
+
+// handleAllUsers displays all users
+func (s *Server) handleAllUsers(w http.ResponseWriter, r *http.Request) {
 	// Fetch all users from API
-	resp, err := s.callAPI("/api/v1/report/all", map[string]string{"from": from, "to": to})
+	users, err := s.reportUsers(r, client.ReportAll)
 	if err != nil {
 		s.logger.Error("Error getting all users", "error", err)
 		http.Error(w, "Error loading user data", http.StatusInternalServerError)
 		return
 	}
 
-	// Extract users from response
-	var users []*domain.User
-	if reportResp, ok := resp.(map[string]any); ok {
-		if usersData, ok := reportResp["users"].([]any); ok {
-			for _, u := range usersData {
-				if userMap, ok := u.(map[string]any); ok {
-					user := &domain.User{}
-					if id, ok := userMap["id"].(string); ok {
-						user.ID = id
-					}
-					if email, ok := userMap["email"].(string); ok {
-						user.Email = email
-					}
-					if dateAdded, ok := userMap["date_added"].(string); ok {
-						if t, err := time.Parse(time.RFC3339, dateAdded); err == nil {
-							user.DateAdded = t
-						}
-					}
-					if redeemed, ok := userMap["redeemed"].(string); ok && redeemed != "" {
-						if t, err := time.Parse(time.RFC3339, redeemed); err == nil {
-							user.Redeemed = &t
-						}
-					}
-					users = append(users, user)
-				}
-			}
-		}
-	}
-
 	// Render users page
-	s.renderUsersPage(w, users, "All Users")
+	s.renderUsersPage(w, r, users, "webui_nav_users")
 }
 
 // handleRedeemedUsers displays users who have redeemed their cocktails
 func (s *Server) handleRedeemedUsers(w http.ResponseWriter, r *http.Request) {
-	// Get date range from query params or use defaults
-	from := r.URL.Query().Get("from")
-	to := r.URL.Query().Get("to")
-
-	if from == "" {
-		from = time.Now().AddDate(-1, 0, 0).Format("2006-01-02") // Last year
-	}
-	if to == "" {
-		to = time.Now().Format("2006-01-02")
-	}
-
 	// Fetch redeemed users from API
-	resp, err := s.callAPI("/api/v1/report/redeemed", map[string]string{"from": from, "to": to})
+	users, err := s.reportUsers(r, client.ReportRedeemed)
 	if err != nil {
 		s.logger.Error("Error getting redeemed users", "error", err)
 		http.Error(w, "Error loading redeemed user data", http.StatusInternalServerError)
 		return
 	}
 
-	// Extract users from response
-	var users []*domain.User
-	if reportResp, ok := resp.(map[string]any); ok {
-		if usersData, ok := reportResp["users"].([]any); ok {
-			for _, u := range usersData {
-				if userMap, ok := u.(map[string]any); ok {
-					user := &domain.User{}
-					if id, ok := userMap["id"].(string); ok {
-						user.ID = id
-					}
-					if email, ok := userMap["email"].(string); ok {
-						user.Email = email
-					}
-					if dateAdded, ok := userMap["date_added"].(string); ok {
-						if t, err := time.Parse(time.RFC3339, dateAdded); err == nil {
-							user.DateAdded = t
-						}
-					}
-					if redeemed, ok := userMap["redeemed"].(string); ok && redeemed != "" {
-						if t, err := time.Parse(time.RFC3339, redeemed); err == nil {
-							user.Redeemed = &t
-						}
-					}
-					users = append(users, user)
-				}
-			}
-		}
+	// Render redeemed users page
+	s.renderUsersPage(w, r, users, "webui_nav_redeemed")
+}
+
+// reportQuery returns a report query with the dates or period of a users
+// page. Pages without them show the configured default period.
+func (s *Server) reportQuery(reportType client.ReportType, query url.Values) client.ReportQuery {
+	report := client.ReportQuery{
+		Type:   reportType,
+		From:   query.Get("from"),
+		To:     query.Get("to"),
+		Period: query.Get("period"),
 	}
+	if report.From == "" && report.To == "" && report.Period == "" {
+		report.Period = s.config.WebUI.DefaultPeriod
+	}
+	return report
+}
 
-	// Render redeemed users page
-	s.renderUsersPage(w, users, "Redeemed Cocktails")
+// usersPageSize is the number of users fetched from the API per request
+const usersPageSize = 500
+
+// reportUsers fetches all users of a report for a users page. Full emails
+// are asked for with the session's own admin token, so the API checks and
+// logs the reveal.
+func (s *Server) reportUsers(r *http.Request, reportType client.ReportType) ([]*client.User, error) {
+	query := s.reportQuery(reportType, r.URL.Query())
+	apiClient := s.apiClient
+	if s.emailsRevealed(r) {
+		query.Reveal = true
+		apiClient = apiClient.WithToken(sessionToken(r))
+	}
+	return apiClient.ReportUsers(r.Context(), query, usersPageSize)
+}
+
+// emailsRevealed returns true if a page of a privacy mode WebUI asks for
+// full guest emails and the logged in token is allowed to see them
+func (s *Server) emailsRevealed(r *http.Request) bool {
+	return s.config.Privacy.MaskEmails && r.URL.Query().Get("reveal") == "true" && s.authProvider.IsAdmin(sessionToken(r))
+}
+
+// revealToggle returns a link switching a users page between masked and full
+// emails. It is only shown to admin tokens in privacy mode.
+func (s *Server) revealToggle(r *http.Request, lang string) string {
+	if !s.config.Privacy.MaskEmails || !s.authProvider.IsAdmin(sessionToken(r)) {
+		return ""
+	}
+	query := r.URL.Query()
+	label := s.translator.T(lang, "webui_hide_emails")
+	if s.emailsRevealed(r) {
+		query.Del("reveal")
+	} else {
+		query.Set("reveal", "true")
+		label = s.translator.T(lang, "webui_show_emails")
+	}
+	link := r.URL.Path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return fmt.Sprintf(`<a href="%s" class="float-end">%s</a>`, template.HTMLEscapeString(link), template.HTMLEscapeString(label))
+}
+
+// firstPlainToken returns the first token not configured as a hash, if any
+func firstPlainToken(tokens []string) string {
+	for _, token := range tokens {
+		if token != "" && !api.IsTokenHash(token) {
+			return token
+		}
+	}
+	return ""
 }
 
-// getUserFromCookie gets the token identifier from the auth cookie
+// getUserFromCookie gets the user of the session of the request
 // Since we're using tokens, we'll return a generic "Admin" identifier
 func getUserFromCookie(r *http.Request) string {
-	_, err := r.Cookie("auth_token")
-	if err != nil {
+	if requestSession(r) == nil {
 		return ""
 	}
 	// For display purposes, return "Admin" when authenticated
 	return "Admin"
 }
 
+// databaseStatus fetches repository diagnostics for the page footer.
+// It returns nil when no admin token is configured.
+func (s *Server) databaseStatus() map[string]any {
+	if s.adminToken == "" {
+		return nil
+	}
+
+	status, err := s.apiClient.WithToken(s.adminToken).DatabaseStatus(context.Background())
+	if err != nil {
+		s.logger.Debug("Error fetching database status", "error", err)
+		return map[string]any{"Status": "unavailable"}
+	}
+
+	view := map[string]any{"Status": status.Status}
+	if stats := status.Stats; stats != nil {
+		view["Backend"] = stats.Backend
+		view["Users"] = stats.Users
+		if stats.LastWrite != nil {
+			view["LastWrite"] = stats.LastWrite.Format("2006-01-02 15:04")
+		}
+	}
+
+	return view
+}
+
 // renderTemplate renders a template with the layout
 func (s *Server) renderTemplate(w http.ResponseWriter, templateName string, data map[string]any) error {
 	// Create a buffer to capture any template errors
 	var buf bytes.Buffer
+
+	// Show repository diagnostics in the footer
+	if _, ok := data["DB"]; !ok {
+		data["DB"] = s.databaseStatus()
+	}
 	
 	// The layout expects a "content" template to be defined
 	// We need to parse both the layout and the specific template together
 	
 	// Parse layout first
 	layoutContent := `<!DOCTYPE html>
-<html lang="en">
+<html lang="{{.Lang}}">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Cocktail Bot - {{.Title}}</title>
+    <title>{{brandTitle .Title}}</title>
     <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/bootstrap@5.2.3/dist/css/bootstrap.min.css">
     <link rel="stylesheet" href="/static/css/styles.css">
+    {{brandHead}}
     <script src="https://cdn.jsdelivr.net/npm/chart.js@4.3.0/dist/chart.umd.min.js"></script>
 </head>
 <body>
     <nav class="navbar navbar-expand-lg navbar-dark bg-dark">
         <div class="container">
-            <a class="navbar-brand" href="/">🍹 Cocktail Bot</a>
+            {{brandNav}}
             <button class="navbar-toggler" type="button" data-bs-toggle="collapse" data-bs-target="#navbarNav">
                 <span class="navbar-toggler-icon"></span>
             </button>
             <div class="collapse navbar-collapse" id="navbarNav">
                 <ul class="navbar-nav me-auto">
                     <li class="nav-item">
-                        <a class="nav-link" href="/">Dashboard</a>
+                        <a class="nav-link" href="/">{{t .Lang "webui_nav_dashboard"}}</a>
+                    </li>
+                    <li class="nav-item">
+                        <a class="nav-link" href="/users">{{t .Lang "webui_nav_users"}}</a>
+                    </li>
+                    <li class="nav-item">
+                        <a class="nav-link" href="/redeemed">{{t .Lang "webui_nav_redeemed"}}</a>
+                    </li>
+                    <li class="nav-item">
+                        <a class="nav-link" href="/audit">{{t .Lang "webui_nav_audit"}}</a>
                     </li>
                     <li class="nav-item">
-                        <a class="nav-link" href="/users">All Users</a>
+                        <a class="nav-link" href="/jobs">{{t .Lang "webui_nav_jobs"}}</a>
                     </li>
                     <li class="nav-item">
-                        <a class="nav-link" href="/redeemed">Redeemed Cocktails</a>
+                        <a class="nav-link" href="/console">{{t .Lang "webui_nav_console"}}</a>
                     </li>
                 </ul>
-                {{if .User}}
-                <div class="d-flex">
+                <div class="d-flex align-items-center">
+                    {{.LanguageMenu}}
+                    {{if .User}}
                     <span class="navbar-text me-3">
-                        Welcome, {{.User}}
+                        {{t .Lang "webui_welcome" "user" .User}}
                     </span>
-                    <a href="/logout" class="btn btn-outline-light btn-sm">Logout</a>
+                    <a href="/logout" class="btn btn-outline-light btn-sm">{{t .Lang "webui_logout"}}</a>
+                    {{end}}
                 </div>
-                {{end}}
             </div>
         </div>
     </nav>
@@ -514,7 +714,13 @@ func (s *Server) renderTemplate(w http.ResponseWriter, templateName string, data
 
     <footer class="footer mt-auto py-3 bg-light">
         <div class="container text-center">
-            <span class="text-muted">Cocktail Bot Admin Interface</span>
+            <span class="text-muted">{{t .Lang "webui_footer"}}</span>
+            {{with .DB}}
+            <br>
+            <small class="{{if eq .Status "ok"}}text-muted{{else}}text-danger{{end}}">
+                {{t $.Lang "webui_database"}}: {{if .Backend}}{{.Backend}}{{else}}{{t $.Lang "webui_database_unknown"}}{{end}} ({{.Status}}){{if .Users}} &middot; {{t $.Lang "webui_database_users" "count" .Users}}{{end}}{{if .LastWrite}} &middot; {{t $.Lang "webui_database_last_write" "time" .LastWrite}}{{end}}
+            </small>
+            {{end}}
         </div>
     </footer>
 
@@ -527,7 +733,7 @@ func (s *Server) renderTemplate(w http.ResponseWriter, templateName string, data
 		fullTemplate := layoutContent + `
 {{define "content"}}` + getDashboardContent() + `{{end}}`
 		
-		tmpl, err := template.New("layout").Parse(fullTemplate)
+		tmpl, err := template.New("layout").Funcs(s.brand.funcs()).Funcs(translateFuncs(s.translator)).Parse(fullTemplate)
 		if err != nil {
 			return fmt.Errorf("error parsing template: %w", err)
 		}
@@ -557,11 +763,11 @@ func getDashboardContent() string {
     <div class="col-md-3 mb-4">
         <div class="card text-center h-100 border-primary">
             <div class="card-header bg-primary text-white">
-                Total Users
+                {{t .Lang "webui_total_users"}}
             </div>
             <div class="card-body">
                 <h2 class="card-title">{{.Stats.total}}</h2>
-                <p class="card-text">Total registered users</p>
+                <p class="card-text">{{t .Lang "webui_total_users_text"}}</p>
             </div>
         </div>
     </div>
@@ -569,11 +775,11 @@ func getDashboardContent() string {
     <div class="col-md-3 mb-4">
         <div class="card text-center h-100 border-success">
             <div class="card-header bg-success text-white">
-                Redeemed Cocktails
+                {{t .Lang "webui_nav_redeemed"}}
             </div>
             <div class="card-body">
                 <h2 class="card-title">{{.Stats.redeemed}}</h2>
-                <p class="card-text">Users who redeemed their cocktails</p>
+                <p class="card-text">{{t .Lang "webui_redeemed_text"}}</p>
             </div>
         </div>
     </div>
@@ -581,11 +787,12 @@ func getDashboardContent() string {
     <div class="col-md-3 mb-4">
         <div class="card text-center h-100 border-info">
             <div class="card-header bg-info text-white">
-                Last Month
+                {{t .Lang "webui_last_month"}}
             </div>
             <div class="card-body">
                 <h2 class="card-title">{{.Stats.last_month}}</h2>
-                <p class="card-text">New users in the last 30 days</p>
+                <p class="card-text">{{t .Lang "webui_last_month_text"}}</p>
+                {{with index .Deltas "last_month"}}<p class="card-text small {{if eq .Direction "up"}}text-success{{else if eq .Direction "down"}}text-danger{{else}}text-muted{{end}}">{{if eq .Direction "up"}}&#9650;{{else if eq .Direction "down"}}&#9660;{{else}}&#9644;{{end}} {{t $.Lang "webui_vs_before" "change" .Change "previous" .Previous}}</p>{{end}}
             </div>
         </div>
     </div>
@@ -593,27 +800,143 @@ func getDashboardContent() string {
     <div class="col-md-3 mb-4">
         <div class="card text-center h-100 border-warning">
             <div class="card-header bg-warning text-dark">
-                Last Week
+                {{t .Lang "webui_last_week"}}
             </div>
             <div class="card-body">
                 <h2 class="card-title">{{.Stats.last_week}}</h2>
-                <p class="card-text">New users in the last 7 days</p>
+                <p class="card-text">{{t .Lang "webui_last_week_text"}}</p>
+                {{with index .Deltas "last_week"}}<p class="card-text small {{if eq .Direction "up"}}text-success{{else if eq .Direction "down"}}text-danger{{else}}text-muted{{end}}">{{if eq .Direction "up"}}&#9650;{{else if eq .Direction "down"}}&#9660;{{else}}&#9644;{{end}} {{t $.Lang "webui_vs_before" "change" .Change "previous" .Previous}}</p>{{end}}
             </div>
         </div>
     </div>
 </div>
 
+<!-- Redemptions against the same time before -->
+<div class="row">
+    <div class="col-md-6 mb-4">
+        <div class="card text-center h-100 border-success">
+            <div class="card-header">
+                {{t .Lang "webui_tonight"}}
+            </div>
+            <div class="card-body">
+                <h2 class="card-title">{{.Stats.tonight}}</h2>
+                <p class="card-text">{{t .Lang "webui_tonight_text"}}</p>
+                {{with index .Deltas "tonight"}}<p class="card-text small {{if eq .Direction "up"}}text-success{{else if eq .Direction "down"}}text-danger{{else}}text-muted{{end}}">{{if eq .Direction "up"}}&#9650;{{else if eq .Direction "down"}}&#9660;{{else}}&#9644;{{end}} {{t $.Lang "webui_vs_yesterday" "change" .Change "previous" .Previous}}</p>{{end}}
+            </div>
+        </div>
+    </div>
+
+    <div class="col-md-6 mb-4">
+        <div class="card text-center h-100 border-success">
+            <div class="card-header">
+                {{t .Lang "webui_this_week"}}
+            </div>
+            <div class="card-body">
+                <h2 class="card-title">{{.Stats.this_week}}</h2>
+                <p class="card-text">{{t .Lang "webui_this_week_text"}}</p>
+                {{with index .Deltas "this_week"}}<p class="card-text small {{if eq .Direction "up"}}text-success{{else if eq .Direction "down"}}text-danger{{else}}text-muted{{end}}">{{if eq .Direction "up"}}&#9650;{{else if eq .Direction "down"}}&#9660;{{else}}&#9644;{{end}} {{t $.Lang "webui_vs_last_week" "change" .Change "previous" .Previous}}</p>{{end}}
+            </div>
+        </div>
+    </div>
+</div>
+
+{{with .Engagement}}
+<!-- Engagement -->
+<div class="row">
+    <div class="col-md-4 mb-4">
+        <div class="card h-100">
+            <div class="card-header">
+                {{t $.Lang "webui_language_split"}}
+            </div>
+            <ul class="list-group list-group-flush">
+                {{range $lang, $count := .Languages}}
+                <li class="list-group-item d-flex justify-content-between">
+                    <span>{{$lang}}</span>
+                    <span class="badge bg-secondary">{{$count}}</span>
+                </li>
+                {{else}}
+                <li class="list-group-item text-muted">{{t $.Lang "webui_no_interactions"}}</li>
+                {{end}}
+            </ul>
+        </div>
+    </div>
+
+    <div class="col-md-4 mb-4">
+        <div class="card h-100">
+            <div class="card-header">
+                {{t $.Lang "webui_busiest_hours"}}
+            </div>
+            <div class="card-body">
+                <canvas id="hoursChart" height="180"></canvas>
+                <p class="card-text mt-2">{{t $.Lang "webui_busiest_hour" "hour" .BusiestHour}}</p>
+            </div>
+        </div>
+    </div>
+
+    <div class="col-md-4 mb-4">
+        <div class="card text-center h-100 border-secondary">
+            <div class="card-header">
+                {{t $.Lang "webui_conversion"}}
+            </div>
+            <div class="card-body">
+                <h2 class="card-title">{{.Conversion}}</h2>
+                <p class="card-text">{{t $.Lang "webui_conversion_text" "redemptions" .Redemptions "checks" .Checks}}</p>
+            </div>
+        </div>
+    </div>
+</div>
+
+<script>
+    new Chart(document.getElementById('hoursChart'), {
+        type: 'bar',
+        data: {
+            labels: Array.from({length: 24}, (_, i) => i),
+            datasets: [{label: {{t $.Lang "webui_chart_messages"}}, data: {{.Hours}}}]
+        },
+        options: {plugins: {legend: {display: false}}}
+    });
+</script>
+{{end}}
+
+{{with .Latency}}
+<!-- Redemption latency -->
+<div class="row">
+    <div class="col-12 mb-4">
+        <div class="card h-100">
+            <div class="card-header">
+                {{t $.Lang "webui_latency"}}
+            </div>
+            <div class="card-body">
+                <canvas id="latencyChart" height="90"></canvas>
+                <p class="card-text mt-2">{{t $.Lang "webui_latency_text" "p50" .P50 "p90" .P90 "count" .Count}}</p>
+            </div>
+        </div>
+    </div>
+</div>
+
+<script>
+    new Chart(document.getElementById('latencyChart'), {
+        type: 'bar',
+        data: {
+            labels: {{.Labels}},
+            datasets: [{label: {{t $.Lang "webui_chart_guests"}}, data: {{.Counts}}}]
+        },
+        options: {plugins: {legend: {display: false}}}
+    });
+</script>
+{{end}}
+
 <!-- Quick Actions -->
 <div class="row mt-2">
     <div class="col-12">
         <div class="card">
             <div class="card-header">
-                Quick Actions
+                {{t .Lang "webui_quick_actions"}}
             </div>
             <div class="card-body">
                 <div class="d-flex gap-2 flex-wrap">
-                    <a href="/users" class="btn btn-primary">View All Users</a>
-                    <a href="/redeemed" class="btn btn-success">View Redeemed Cocktails</a>
+                    <a href="/users" class="btn btn-primary">{{t .Lang "webui_view_users"}}</a>
+                    <a href="/redeemed" class="btn btn-success">{{t .Lang "webui_view_redeemed"}}</a>
                 </div>
             </div>
         </div>
@@ -622,46 +945,53 @@ func getDashboardContent() string {
 }
 
 // renderLoginPage renders the login page with optional error message
-func (s *Server) renderLoginPage(w http.ResponseWriter, errorMsg string, redirect string) {
+func (s *Server) renderLoginPage(w http.ResponseWriter, r *http.Request, lang, errorMsg, redirect string) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := func(key string, args ...string) string {
+		return template.HTMLEscapeString(s.translator.T(lang, key, args...))
+	}
 
 	errorHTML := ""
 	if errorMsg != "" {
-		errorHTML = `<div class="alert alert-danger" role="alert">` + errorMsg + `</div>`
+		errorHTML = `<div class="alert alert-danger" role="alert">` + template.HTMLEscapeString(errorMsg) + `</div>`
 	}
 
 	loginHTML := `<!DOCTYPE html>
-<html lang="en">
+<html lang="` + lang + `">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Cocktail Bot - Login</title>
+    <title>` + template.HTMLEscapeString(s.brand.title(s.translator.T(lang, "webui_login"))) + `</title>
     <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/bootstrap@5.2.3/dist/css/bootstrap.min.css">
+    ` + string(s.brand.head()) + `
 </head>
 <body class="bg-light d-flex align-items-center min-vh-100">
     <div class="container">
         <div class="row justify-content-center">
             <div class="col-md-6 col-lg-4">
+                <div class="d-flex justify-content-end mb-2">
+                    ` + string(s.languageSwitcher(r, lang)) + `
+                </div>
                 <div class="card shadow">
                     <div class="card-header bg-primary text-white">
-                        <h3 class="card-title text-center mb-0">🍹 Cocktail Bot Login</h3>
+                        <h3 class="card-title text-center mb-0">🍹 ` + t("webui_login_title", "name", s.brand.Name) + `</h3>
                     </div>
                     <div class="card-body">
                         ` + errorHTML + `
                         <form method="POST" action="/login">
                             <input type="hidden" name="redirect" value="` + redirect + `">
                             <div class="mb-3">
-                                <label for="token" class="form-label">Authentication Token</label>
-                                <input type="password" class="form-control" id="token" name="token" placeholder="Enter your API token" required autofocus>
-                                <small class="form-text text-muted">Use the same token as configured for API access</small>
+                                <label for="token" class="form-label">` + t("webui_login_token") + `</label>
+                                <input type="password" class="form-control" id="token" name="token" placeholder="` + t("webui_login_placeholder") + `" required autofocus>
+                                <small class="form-text text-muted">` + t("webui_login_help") + `</small>
                             </div>
                             <div class="d-grid">
-                                <button type="submit" class="btn btn-primary">Login</button>
+                                <button type="submit" class="btn btn-primary">` + t("webui_login") + `</button>
                             </div>
                         </form>
                     </div>
                     <div class="card-footer text-center">
-                        <small class="text-muted">Enter your authentication token to access the dashboard</small>
+                        <small class="text-muted">` + t("webui_login_footer") + `</small>
                     </div>
                 </div>
             </div>
@@ -674,56 +1004,87 @@ func (s *Server) renderLoginPage(w http.ResponseWriter, errorMsg string, redirec
 }
 
 // renderUsersPage renders a page with a list of users
-func (s *Server) renderUsersPage(w http.ResponseWriter, users []*domain.User, title string) {
+func (s *Server) renderUsersPage(w http.ResponseWriter, r *http.Request, users []*client.User, titleKey string) {
+	lang := s.pageLanguage(w, r)
+	t := func(key string, args ...string) string {
+		return template.HTMLEscapeString(s.translator.T(lang, key, args...))
+	}
+	title := s.translator.T(lang, titleKey)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	
 	// Build user rows HTML
 	var userRows string
 	for _, user := range users {
-		redeemedText := "No"
+		redeemedText := t("webui_not_redeemed")
 		redeemedClass := ""
 		if user.Redeemed != nil {
 			redeemedText = user.Redeemed.Format("Jan 02, 2006 15:04")
 			redeemedClass = "text-success"
 		}
+		createdBy := user.CreatedBy
+		if createdBy == "" {
+			createdBy = "-"
+		}
+		name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+		if name == "" {
+			name = "-"
+		}
 		
 		userRows += fmt.Sprintf(`
 		<tr>
 			<td>%s</td>
 			<td>%s</td>
 			<td>%s</td>
+			<td>%s</td>
 			<td class="%s">%s</td>
-		</tr>`, user.ID, user.Email, user.DateAdded.Format("Jan 02, 2006 15:04"), redeemedClass, redeemedText)
+			<td>%s</td>
+			<td>%s</td>
+		</tr>`, user.ID, template.HTMLEscapeString(user.Email), template.HTMLEscapeString(name), user.DateAdded.Format("Jan 02, 2006 15:04"), redeemedClass, redeemedText,
+			user.UpdatedAt.Format("Jan 02, 2006 15:04"), template.HTMLEscapeString(createdBy))
 	}
 	
 	html := fmt.Sprintf(`<!DOCTYPE html>
-<html lang="en">
+<html lang="%s">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Cocktail Bot - %s</title>
+    <title>%s</title>
     <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/bootstrap@5.2.3/dist/css/bootstrap.min.css">
     <link rel="stylesheet" href="/static/css/styles.css">
+    %s
 </head>
 <body>
     <nav class="navbar navbar-expand-lg navbar-dark bg-dark">
         <div class="container">
-            <a class="navbar-brand" href="/">🍹 Cocktail Bot</a>
+            %s
             <div class="collapse navbar-collapse" id="navbarNav">
                 <ul class="navbar-nav me-auto">
                     <li class="nav-item">
-                        <a class="nav-link" href="/">Dashboard</a>
+                        <a class="nav-link" href="/">%s</a>
+                    </li>
+                    <li class="nav-item">
+                        <a class="nav-link" href="/users">%s</a>
+                    </li>
+                    <li class="nav-item">
+                        <a class="nav-link" href="/redeemed">%s</a>
                     </li>
                     <li class="nav-item">
-                        <a class="nav-link" href="/users">All Users</a>
+                        <a class="nav-link" href="/audit">%s</a>
                     </li>
                     <li class="nav-item">
-                        <a class="nav-link" href="/redeemed">Redeemed Cocktails</a>
+                        <a class="nav-link" href="/jobs">%s</a>
+                    </li>
+                    <li class="nav-item">
+                        <a class="nav-link" href="/import">%s</a>
+                    </li>
+                    <li class="nav-item">
+                        <a class="nav-link" href="/console">%s</a>
                     </li>
                 </ul>
-                <div class="d-flex">
-                    <span class="navbar-text me-3">Welcome, Admin</span>
-                    <a href="/logout" class="btn btn-outline-light btn-sm">Logout</a>
+                <div class="d-flex align-items-center">
+                    %s
+                    <span class="navbar-text me-3">%s</span>
+                    <a href="/logout" class="btn btn-outline-light btn-sm">%s</a>
                 </div>
             </div>
         </div>
@@ -731,19 +1092,27 @@ func (s *Server) renderUsersPage(w http.ResponseWriter, users []*domain.User, ti
 
     <div class="container mt-4">
         <h1 class="mb-4">%s</h1>
+        <form method="GET" action="/search" class="d-flex mb-3">
+            <input type="search" class="form-control me-2" name="q" placeholder="%s" minlength="2" maxlength="100">
+            <button type="submit" class="btn btn-outline-primary">%s</button>
+        </form>
         <div class="card">
             <div class="card-header">
-                Total: %d users
+                %s
+                %s
             </div>
             <div class="card-body">
                 <div class="table-responsive">
                     <table class="table table-striped">
                         <thead>
                             <tr>
-                                <th>ID</th>
-                                <th>Email</th>
-                                <th>Date Added</th>
-                                <th>Redeemed</th>
+                                <th>%s</th>
+                                <th>%s</th>
+                                <th>%s</th>
+                                <th>%s</th>
+                                <th>%s</th>
+                                <th>%s</th>
+                                <th>%s</th>
                             </tr>
                         </thead>
                         <tbody>
@@ -757,60 +1126,19 @@ func (s *Server) renderUsersPage(w http.ResponseWriter, users []*domain.User, ti
 
     <footer class="footer mt-auto py-3 bg-light">
         <div class="container text-center">
-            <span class="text-muted">Cocktail Bot Admin Interface</span>
+            <span class="text-muted">%s</span>
         </div>
     </footer>
 
     <script src="https://cdn.jsdelivr.net/npm/bootstrap@5.2.3/dist/js/bootstrap.bundle.min.js"></script>
 </body>
-</html>`, title, title, len(users), userRows)
+</html>`, lang, template.HTMLEscapeString(s.brand.title(title)), s.brand.head(), s.brand.nav(),
+		t("webui_nav_dashboard"), t("webui_nav_users"), t("webui_nav_redeemed"), t("webui_nav_audit"), t("webui_nav_jobs"), t("webui_nav_import"), t("webui_nav_console"),
+		s.languageSwitcher(r, lang), t("webui_welcome", "user", "Admin"), t("webui_logout"),
+		template.HTMLEscapeString(title), t("webui_search_placeholder"), t("webui_search"), t("webui_users_total", "count", strconv.Itoa(len(users))), s.revealToggle(r, lang),
+		t("webui_column_id"), t("webui_column_email"), t("webui_column_name"), t("webui_column_added"), t("webui_column_redeemed"), t("webui_column_updated"), t("webui_column_added_by"),
+		userRows, t("webui_footer"))
 	
 	w.Write([]byte(html))
 }
 
-// callAPI makes a request to the API and returns the parsed JSON response
-func (s *Server) callAPI(endpoint string, params map[string]string) (any, error) {
-	// Build URL with query parameters
-	req, err := http.NewRequest("GET", s.apiURL+endpoint, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// Add query parameters
-	q := req.URL.Query()
-	for k, v := range params {
-		q.Add(k, v)
-	}
-	req.URL.RawQuery = q.Encode()
-
-	// Add authentication header
-	req.Header.Set("Authorization", "Bearer "+s.apiToken)
-	req.Header.Set("Accept", "application/json")
-
-	// Make the request
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse JSON response
-	var result any
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse API response: %w", err)
-	}
-
-	// Check for error response
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-	return result, nil
-}